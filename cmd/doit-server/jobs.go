@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/agents"
+	"github.com/Actual-Outcomes/doit/internal/compact"
+	"github.com/Actual-Outcomes/doit/internal/config"
+	"github.com/Actual-Outcomes/doit/internal/gates"
+	"github.com/Actual-Outcomes/doit/internal/gc"
+	"github.com/Actual-Outcomes/doit/internal/jobs"
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+// backgroundJobs is the maintenance the server runs for every tenant.
+func backgroundJobs(cfg *config.Config) []jobs.Job {
+	return []jobs.Job{
+		{
+			Name:        "reap_leases",
+			Description: "Release expired claim leases, so issues held by crashed agents go back to the ready queue.",
+			Interval:    cfg.LeaseReapInterval,
+			Run:         reapExpiredLeases,
+		},
+		{
+			Name:        "purge_trash",
+			Description: fmt.Sprintf("Permanently delete issues in the trash for longer than %s.", cfg.TrashRetention),
+			Interval:    cfg.TrashPurgeInterval,
+			Run: func(ctx context.Context, st store.Store, now time.Time) (any, error) {
+				return purgeTrash(ctx, st, now.Add(-cfg.TrashRetention))
+			},
+		},
+		{
+			Name:        "compact",
+			Description: fmt.Sprintf("Summarize issues closed for longer than %s, or their project's compaction age.", cfg.CompactAge),
+			Interval:    cfg.CompactInterval,
+			Run: func(ctx context.Context, st store.Store, _ time.Time) (any, error) {
+				return compactClosed(ctx, st, cfg.CompactAge)
+			},
+		},
+		{
+			Name:        "gc_ephemeral",
+			Description: "Delete ephemeral issues and wisps kept longer than the tenant's gc policy allows.",
+			Interval:    cfg.EphemeralGCInterval,
+			Run:         collectEphemeral,
+		},
+		{
+			Name:        "agent_monitor",
+			Description: fmt.Sprintf("Mark agents that have not reported in for %s stuck and raise a flag on each.", cfg.AgentStuckAfter),
+			Interval:    cfg.AgentMonitorInterval,
+			Run: func(ctx context.Context, st store.Store, now time.Time) (any, error) {
+				return markStuckAgents(ctx, st, now, cfg.AgentStuckAfter)
+			},
+		},
+		{
+			Name:        "check_gates",
+			Description: "Open gates whose timer ran out or whose issue or flag closed, and flag gates past their timeout.",
+			Interval:    cfg.GateCheckInterval,
+			Run:         checkGates,
+		},
+	}
+}
+
+func reapExpiredLeases(ctx context.Context, st store.Store, now time.Time) (any, error) {
+	released, err := st.ReleaseExpiredLeases(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(released))
+	for i, issue := range released {
+		ids[i] = issue.ID
+		slog.Info("released expired claim", "issue", issue.ID)
+	}
+	return map[string][]string{"released": ids}, nil
+}
+
+func purgeTrash(ctx context.Context, st store.Store, deletedBefore time.Time) (any, error) {
+	purged, err := st.PurgeTrash(ctx, deletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range purged {
+		slog.Info("purged deleted issue", "issue", id)
+	}
+	return map[string][]string{"purged": purged}, nil
+}
+
+// compactClosed records counts only; the compacted issues carry their own
+// compacted events.
+func compactClosed(ctx context.Context, st store.Store, age time.Duration) (any, error) {
+	report, err := compact.New(st).Run(ctx, compact.Options{Age: age})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int{
+		"scanned":   report.Scanned,
+		"excluded":  report.Excluded,
+		"compacted": len(report.Compacted),
+	}, nil
+}
+
+// collectEphemeral records counts only, like compactClosed; a tenant that
+// wants the deleted issues listed turns on gc reports.
+func collectEphemeral(ctx context.Context, st store.Store, now time.Time) (any, error) {
+	report, err := gc.New(st).Run(ctx, now, gc.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"scanned":   report.Scanned,
+		"collected": report.ByType,
+		"report_id": report.ReportID,
+	}, nil
+}
+
+func markStuckAgents(ctx context.Context, st store.Store, now time.Time, after time.Duration) (any, error) {
+	stuck, err := agents.New(st).MarkStuck(ctx, now, after)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range stuck {
+		slog.Warn("agent stuck", "agent", a.Name, "idle", a.Idle, "flag", a.FlagID)
+	}
+	return map[string][]agents.Stuck{"stuck": stuck}, nil
+}
+
+// checkGates records the gates it opened or escalated; a gate that could
+// not be checked fails the run without holding back the others.
+func checkGates(ctx context.Context, st store.Store, now time.Time) (any, error) {
+	report, err := gates.New(st).Check(ctx, now)
+	if report == nil {
+		return nil, err
+	}
+	for _, g := range report.Escalated {
+		slog.Warn("gate timed out", "gate", g.ID, "flag", g.FlagID)
+	}
+	return report, err
+}
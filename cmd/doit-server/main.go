@@ -12,6 +12,7 @@ import (
 	"github.com/Actual-Outcomes/doit/internal/api"
 	"github.com/Actual-Outcomes/doit/internal/auth"
 	"github.com/Actual-Outcomes/doit/internal/config"
+	"github.com/Actual-Outcomes/doit/internal/jobs"
 	"github.com/Actual-Outcomes/doit/internal/store"
 	"github.com/Actual-Outcomes/doit/internal/ui"
 	"github.com/Actual-Outcomes/doit/internal/version"
@@ -48,12 +49,24 @@ func main() {
 
 	ctx := context.Background()
 
-	pgStore, err := store.NewPgStore(ctx, cfg.DatabaseURL, cfg.DBQueryTimeout, cfg.IDPrefix)
+	st, err := store.Open(ctx, cfg.DatabaseURL, cfg.DBQueryTimeout, cfg.IDPrefix)
 	if err != nil {
 		slog.Error("failed to connect to database", "error", err)
 		os.Exit(1)
 	}
-	defer pgStore.Close()
+	defer st.Close()
+
+	// The in-memory store starts empty; seed the admin tenant so the
+	// admin key has somewhere to land.
+	if store.IsMemoryURL(cfg.DatabaseURL) {
+		slog.Warn("using in-memory store, data will not survive a restart")
+		if cfg.AdminTenantSlug != "" {
+			if _, err := st.CreateTenant(ctx, cfg.AdminTenantSlug, cfg.AdminTenantSlug); err != nil {
+				slog.Error("failed to seed admin tenant", "error", err)
+				os.Exit(1)
+			}
+		}
+	}
 
 	// MCP servers: agent (20 tools) + admin (8 tools)
 	agentMCP := mcp.NewServer(&mcp.Implementation{
@@ -65,7 +78,11 @@ func main() {
 		Version: version.Number,
 	}, nil)
 
-	handlers := api.NewHandlers(pgStore)
+	scheduler := jobs.New(st, backgroundJobs(cfg)...)
+
+	handlers := api.NewHandlers(st)
+	handlers.SetClaimLease(cfg.ClaimLease)
+	handlers.SetJobs(scheduler)
 	api.RegisterAgentTools(agentMCP, handlers)
 	api.RegisterAdminTools(adminMCP, handlers)
 
@@ -79,11 +96,11 @@ func main() {
 	// Auth config
 	authCfg := auth.MiddlewareConfig{
 		AdminKey:          cfg.AdminAPIKey,
-		Resolver:          pgStore,
-		AdminKeyHashStore: pgStore,
+		Resolver:          st,
+		AdminKeyHashStore: st,
 	}
 	if cfg.AdminTenantSlug != "" {
-		tenants, err := pgStore.ListTenants(ctx)
+		tenants, err := st.ListTenants(ctx)
 		if err != nil {
 			slog.Error("failed to list tenants", "error", err)
 			os.Exit(1)
@@ -117,7 +134,7 @@ func main() {
 
 	r.Get("/documentation", api.DocumentationHandler())
 
-	ui.RegisterUIRoutes(r, pgStore, cfg.AdminAPIKey, authCfg.AdminTenantID)
+	ui.RegisterUIRoutes(r, st, cfg.AdminAPIKey, authCfg.AdminTenantID)
 
 	// Start server
 	srv := &http.Server{
@@ -134,11 +151,17 @@ func main() {
 		}
 	}()
 
+	// Background work
+	bgCtx, stopBackground := context.WithCancel(ctx)
+	defer stopBackground()
+	go scheduler.Run(bgCtx)
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	slog.Info("shutting down server...")
+	stopBackground()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
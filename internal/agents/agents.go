@@ -0,0 +1,382 @@
+// Package agents keeps the registry of agents working in a tenant.
+//
+// Each agent is an issue of type agent (its bead) titled with the agent's
+// name, which the store keeps unique within the tenant. The bead carries
+// the agent's lifecycle state (model.AgentState), role, rig, the issue it
+// is hooked to and when it last reported in. A live agent's bead is in
+// progress and one that has ended is closed, so beads never show up as
+// ready work.
+//
+// Agents report in with SetStatus, which doubles as their heartbeat. The
+// monitor marks agents that stop heartbeating stuck and raises a flag on
+// their bead; the flag is resolved when the agent reports in again.
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+// monitorActor is who the monitor's state changes and flags are from.
+const monitorActor = "agent_monitor"
+
+// Registry registers agents and tracks their state.
+type Registry struct {
+	store store.Store
+}
+
+func New(s store.Store) *Registry {
+	return &Registry{store: s}
+}
+
+// Agent is an agent with the work it holds.
+type Agent struct {
+	ID           string           `json:"id"` // the agent's bead
+	Name         string           `json:"name"`
+	State        model.AgentState `json:"state"`
+	Role         string           `json:"role,omitempty"`
+	Rig          string           `json:"rig,omitempty"`
+	RoleBead     string           `json:"role_bead,omitempty"`
+	HookBead     string           `json:"hook_bead,omitempty"` // the issue it is hooked to
+	HookTitle    string           `json:"hook_title,omitempty"`
+	LastActivity *time.Time       `json:"last_activity,omitempty"`
+	// Holding are the in-progress issues assigned to the agent.
+	Holding []model.CompactIssue `json:"holding"`
+}
+
+// RegisterInput describes an agent to register.
+type RegisterInput struct {
+	Name      string
+	Role      string
+	Rig       string
+	RoleBead  string
+	ProjectID string
+}
+
+// Register creates the agent's bead, or finds it if the name is taken,
+// and starts (or restarts) its lifecycle at spawning.
+func (r *Registry) Register(ctx context.Context, input RegisterInput) (*Agent, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("agent name is required")
+	}
+	now := time.Now().UTC()
+	spawning := model.AgentSpawning
+
+	bead, err := r.find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if bead == nil {
+		id, err := r.store.GenerateID(ctx, "agent")
+		if err != nil {
+			return nil, fmt.Errorf("generating agent ID: %w", err)
+		}
+		created, err := r.store.CreateIssue(ctx, store.CreateIssueInput{
+			ID:           id,
+			Title:        name,
+			Status:       model.StatusInProgress,
+			Priority:     2,
+			IssueType:    model.TypeAgent,
+			CreatedBy:    name,
+			ProjectID:    input.ProjectID,
+			AgentState:   spawning,
+			LastActivity: &now,
+			RoleType:     input.Role,
+			Rig:          input.Rig,
+			RoleBead:     input.RoleBead,
+		})
+		if err == nil {
+			return r.agent(ctx, created)
+		}
+		// The store keeps names unique: if the name was registered
+		// since we looked, this registers that agent again.
+		if bead, _ = r.find(ctx, name); bead == nil {
+			return nil, fmt.Errorf("registering agent %s: %w", name, err)
+		}
+	}
+
+	live, noHook := model.StatusInProgress, ""
+	update := store.UpdateIssueInput{AgentState: &spawning, LastActivity: &now}
+	if bead.Status != live {
+		update.Status = &live
+		update.HookBead = &noHook
+	}
+	if input.Role != "" {
+		update.RoleType = &input.Role
+	}
+	if input.Rig != "" {
+		update.Rig = &input.Rig
+	}
+	if input.RoleBead != "" {
+		update.RoleBead = &input.RoleBead
+	}
+	if bead, err = r.store.UpdateIssue(ctx, bead.ID, update); err != nil {
+		return nil, fmt.Errorf("registering agent %s: %w", name, err)
+	}
+	if err := r.resolveStuckFlags(ctx, bead.ID, name, "agent registered again"); err != nil {
+		return nil, err
+	}
+	return r.agent(ctx, bead)
+}
+
+// StatusInput is an agent reporting in. Nil fields stay as they are.
+type StatusInput struct {
+	Name  string
+	State *model.AgentState
+	Hook  *string // the issue the agent is working on; "" clears it
+}
+
+// SetStatus records that the agent is alive and, if given, its new state
+// and hook. A stuck agent that reports in without a state goes back to
+// working if it is hooked to an issue, and to running otherwise. An agent
+// that ends (done, stopped or dead) drops its hook and its bead closes.
+func (r *Registry) SetStatus(ctx context.Context, input StatusInput) (*Agent, error) {
+	bead, err := r.get(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	update := store.UpdateIssueInput{LastActivity: &now}
+
+	hook := bead.HookBead
+	if input.Hook != nil {
+		hook = strings.TrimSpace(*input.Hook)
+		if hook != "" {
+			if _, err := r.store.GetIssue(ctx, hook); err != nil {
+				return nil, fmt.Errorf("hooking %s: %w", hook, err)
+			}
+		}
+		update.HookBead = &hook
+	}
+
+	state := bead.AgentState
+	switch {
+	case input.State != nil:
+		state = *input.State
+	case bead.AgentState == model.AgentStuck && hook != "":
+		state = model.AgentWorking
+	case bead.AgentState == model.AgentStuck:
+		state = model.AgentRunning
+	}
+	if err := model.CheckAgentTransition(bead.AgentState, state); err != nil {
+		return nil, fmt.Errorf("agent %s: %w", bead.Title, err)
+	}
+	if state != bead.AgentState {
+		update.AgentState = &state
+	}
+	if state.Ended() {
+		closed, reason, noHook := model.StatusClosed, string(state), ""
+		update.Status, update.CloseReason, update.HookBead = &closed, &reason, &noHook
+	}
+
+	if bead, err = r.store.UpdateIssue(ctx, bead.ID, update); err != nil {
+		return nil, fmt.Errorf("updating agent %s: %w", input.Name, err)
+	}
+	if state != model.AgentStuck {
+		if err := r.resolveStuckFlags(ctx, bead.ID, bead.Title, "agent reported in as "+string(state)); err != nil {
+			return nil, err
+		}
+	}
+	return r.agent(ctx, bead)
+}
+
+// Get returns the agent called name.
+func (r *Registry) Get(ctx context.Context, name string) (*Agent, error) {
+	bead, err := r.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return r.agent(ctx, bead)
+}
+
+// List returns the tenant's agents by name, leaving out those that have
+// ended unless all is set.
+func (r *Registry) List(ctx context.Context, all bool) ([]Agent, error) {
+	beads, err := r.beads(ctx)
+	if err != nil {
+		return nil, err
+	}
+	agents := make([]Agent, 0, len(beads))
+	for i := range beads {
+		if !all && beads[i].AgentState.Ended() {
+			continue
+		}
+		a, err := r.agent(ctx, &beads[i])
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, *a)
+	}
+	return agents, nil
+}
+
+// Stuck is an agent the monitor marked stuck.
+type Stuck struct {
+	Name   string `json:"name"`
+	Idle   string `json:"idle"` // how long since it last reported in
+	FlagID string `json:"flag_id"`
+}
+
+// MarkStuck marks agents that should be heartbeating but have not for
+// longer than after as stuck, raising a flag on each agent's bead.
+func (r *Registry) MarkStuck(ctx context.Context, now time.Time, after time.Duration) ([]Stuck, error) {
+	if after <= 0 {
+		return nil, fmt.Errorf("stuck threshold must be positive, got %s", after)
+	}
+	ctx = auth.WithActor(ctx, monitorActor)
+	beads, err := r.beads(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stuck := []Stuck{}
+	for i := range beads {
+		bead := &beads[i]
+		if !bead.AgentState.Monitored() {
+			continue
+		}
+		last := bead.UpdatedAt
+		if bead.LastActivity != nil {
+			last = *bead.LastActivity
+		}
+		idle := now.Sub(last)
+		if idle <= after {
+			continue
+		}
+
+		state := model.AgentStuck
+		if _, err := r.store.UpdateIssue(ctx, bead.ID, store.UpdateIssueInput{
+			AgentState:        &state,
+			ExpectedUpdatedAt: &bead.UpdatedAt, // it may have reported in since
+		}); err != nil {
+			if errors.Is(err, store.ErrConflict) {
+				continue
+			}
+			return nil, fmt.Errorf("marking agent %s stuck: %w", bead.Title, err)
+		}
+		a, err := r.agent(ctx, bead)
+		if err != nil {
+			return nil, err
+		}
+		flag, err := r.raiseStuckFlag(ctx, a, bead.ProjectID, idle)
+		if err != nil {
+			return nil, err
+		}
+		stuck = append(stuck, Stuck{Name: a.Name, Idle: idle.Round(time.Second).String(), FlagID: flag.ID})
+	}
+	return stuck, nil
+}
+
+func (r *Registry) raiseStuckFlag(ctx context.Context, a *Agent, projectID string, idle time.Duration) (*model.Flag, error) {
+	holding := make([]string, len(a.Holding))
+	for i, h := range a.Holding {
+		holding[i] = h.ID
+	}
+	details, err := json.Marshal(map[string]any{
+		"agent":         a.Name,
+		"was":           a.State,
+		"last_activity": a.LastActivity,
+		"hook_bead":     a.HookBead,
+		"holding":       holding,
+	})
+	if err != nil {
+		return nil, err
+	}
+	flag, err := r.store.RaiseFlag(ctx, store.RaiseFlagInput{
+		IssueID:   a.ID,
+		ProjectID: projectID,
+		Type:      string(model.FlagAgentStuck),
+		Severity:  2,
+		Summary:   fmt.Sprintf("Agent %s has not reported in for %s", a.Name, idle.Round(time.Second)),
+		Context:   details,
+		CreatedBy: monitorActor,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("flagging stuck agent %s: %w", a.Name, err)
+	}
+	return flag, nil
+}
+
+// resolveStuckFlags resolves the open stuck flags on an agent's bead.
+func (r *Registry) resolveStuckFlags(ctx context.Context, beadID, name, resolution string) error {
+	open := model.FlagStatusOpen
+	flags, err := r.store.ListFlags(ctx, model.FlagFilter{IssueID: &beadID, Status: &open})
+	if err != nil {
+		return fmt.Errorf("listing flags of agent %s: %w", name, err)
+	}
+	for _, f := range flags {
+		if f.Type != model.FlagAgentStuck {
+			continue
+		}
+		if _, err := r.store.ResolveFlag(ctx, f.ID, resolution, name); err != nil {
+			return fmt.Errorf("resolving flag %s: %w", f.ID, err)
+		}
+	}
+	return nil
+}
+
+// beads returns every agent bead of the tenant, by name.
+func (r *Registry) beads(ctx context.Context) ([]model.Issue, error) {
+	agentType := model.TypeAgent
+	beads, err := r.store.ListIssues(ctx, model.IssueFilter{IssueType: &agentType})
+	if err != nil {
+		return nil, fmt.Errorf("listing agents: %w", err)
+	}
+	slices.SortFunc(beads, func(a, b model.Issue) int { return strings.Compare(a.Title, b.Title) })
+	return beads, nil
+}
+
+// find returns the bead of the agent called name, or nil.
+func (r *Registry) find(ctx context.Context, name string) (*model.Issue, error) {
+	return r.store.FindAgentBead(ctx, name)
+}
+
+func (r *Registry) get(ctx context.Context, name string) (*model.Issue, error) {
+	bead, err := r.find(ctx, strings.TrimSpace(name))
+	if err != nil {
+		return nil, err
+	}
+	if bead == nil {
+		return nil, fmt.Errorf("agent %q is not registered", name)
+	}
+	return bead, nil
+}
+
+// agent fills in what bead's agent is hooked to and holds.
+func (r *Registry) agent(ctx context.Context, bead *model.Issue) (*Agent, error) {
+	a := &Agent{
+		ID:           bead.ID,
+		Name:         bead.Title,
+		State:        bead.AgentState,
+		Role:         bead.RoleType,
+		Rig:          bead.Rig,
+		RoleBead:     bead.RoleBead,
+		HookBead:     bead.HookBead,
+		LastActivity: bead.LastActivity,
+		Holding:      []model.CompactIssue{},
+	}
+	if a.HookBead != "" {
+		// The hooked issue may since have been deleted.
+		if hooked, err := r.store.GetIssue(ctx, a.HookBead); err == nil {
+			a.HookTitle = hooked.Title
+		}
+	}
+	inProgress := model.StatusInProgress
+	held, err := r.store.ListIssues(ctx, model.IssueFilter{Status: &inProgress, Assignee: &a.Name})
+	if err != nil {
+		return nil, fmt.Errorf("listing work held by %s: %w", a.Name, err)
+	}
+	for i := range held {
+		a.Holding = append(a.Holding, held[i].ToCompact())
+	}
+	return a, nil
+}
@@ -0,0 +1,253 @@
+package agents
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/Actual-Outcomes/doit/internal/store/storetest"
+)
+
+func state(s model.AgentState) *model.AgentState { return &s }
+
+func TestLifecycle(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	r := New(s)
+
+	a, err := r.Register(ctx, RegisterInput{Name: "builder-1", Role: "builder", Rig: "ci"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if a.State != model.AgentSpawning || a.Role != "builder" || a.LastActivity == nil || !strings.HasPrefix(a.ID, "agent-") {
+		t.Fatalf("registered %+v, want a spawning builder", a)
+	}
+	if again, err := r.Register(ctx, RegisterInput{Name: "builder-1"}); err != nil || again.ID != a.ID || again.Role != "builder" {
+		t.Fatalf("registering again = %+v, %v; want the same bead", again, err)
+	}
+	if ready, _ := s.ListReady(ctx, model.IssueFilter{}); len(ready) != 0 {
+		t.Errorf("agent beads should never be ready, got %d", len(ready))
+	}
+
+	id := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "fix build"}).ID
+	if _, err := s.ClaimNextReady(ctx, store.ClaimNextReadyInput{Claimant: "builder-1"}); err != nil {
+		t.Fatalf("ClaimNextReady: %v", err)
+	}
+
+	if _, err := r.SetStatus(ctx, StatusInput{Name: "builder-1", State: state(model.AgentSpawning)}); err != nil {
+		t.Errorf("staying in spawning should be allowed: %v", err)
+	}
+	a, err = r.SetStatus(ctx, StatusInput{Name: "builder-1", State: state(model.AgentWorking), Hook: &id})
+	if err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if a.State != model.AgentWorking || a.HookBead != id || a.HookTitle != "fix build" ||
+		len(a.Holding) != 1 || a.Holding[0].ID != id {
+		t.Errorf("after hooking: %+v, want builder-1 working on and holding %s", a, id)
+	}
+	for _, bad := range []StatusInput{
+		{Name: "builder-1", State: state("sleeping")},
+		{Name: "builder-1", State: state(model.AgentSpawning)},
+		{Name: "builder-1", Hook: strPtr("doit-nope")},
+		{Name: "nobody"},
+	} {
+		if _, err := r.SetStatus(ctx, bad); err == nil {
+			t.Errorf("SetStatus(%+v) should fail", bad)
+		}
+	}
+
+	a, err = r.SetStatus(ctx, StatusInput{Name: "builder-1", State: state(model.AgentDone)})
+	if err != nil {
+		t.Fatalf("SetStatus(done): %v", err)
+	}
+	bead, _ := s.GetIssue(ctx, a.ID)
+	if a.HookBead != "" || bead.Status != model.StatusClosed || bead.CloseReason != "done" {
+		t.Errorf("done agent = %+v, bead %s; want its hook dropped and bead closed", a, bead.Status)
+	}
+	if _, err := r.SetStatus(ctx, StatusInput{Name: "builder-1", State: state(model.AgentRunning)}); err == nil {
+		t.Error("an agent that is done should need registering again")
+	}
+	if live, _ := r.List(ctx, false); len(live) != 0 {
+		t.Errorf("List = %+v, want agents that ended left out", live)
+	}
+	if a, err = r.Register(ctx, RegisterInput{Name: "builder-1"}); err != nil || a.State != model.AgentSpawning {
+		t.Fatalf("restarting = %+v, %v; want it spawning again", a, err)
+	}
+	if bead, _ = s.GetIssue(ctx, a.ID); bead.Status != model.StatusInProgress {
+		t.Errorf("restarted bead is %s, want in_progress", bead.Status)
+	}
+
+	events, _ := s.ListEvents(ctx, a.ID, 0)
+	var states []string
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Field == "agent_state" {
+			states = append(states, events[i].NewValue)
+		}
+	}
+	if strings.Join(states, ",") != "working,done,spawning" {
+		t.Errorf("state history = %v, want working, done, spawning", states)
+	}
+}
+
+func TestMarkStuck(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	r := New(s)
+	for _, name := range []string{"quiet", "idle", "gone"} {
+		if _, err := r.Register(ctx, RegisterInput{Name: name}); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	if _, err := r.SetStatus(ctx, StatusInput{Name: "idle", State: state(model.AgentIdle)}); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if _, err := r.SetStatus(ctx, StatusInput{Name: "gone", State: state(model.AgentStopped)}); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+
+	if stuck, err := r.MarkStuck(ctx, time.Now(), 10*time.Minute); err != nil || len(stuck) != 0 {
+		t.Fatalf("MarkStuck right away = %+v, %v; want nothing", stuck, err)
+	}
+	// Idle agents and those that ended are not expected to heartbeat.
+	later := time.Now().Add(20 * time.Minute)
+	stuck, err := r.MarkStuck(ctx, later, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("MarkStuck: %v", err)
+	}
+	if len(stuck) != 1 || stuck[0].Name != "quiet" || stuck[0].FlagID == "" {
+		t.Fatalf("stuck = %+v, want quiet alone, flagged", stuck)
+	}
+	if again, _ := r.MarkStuck(ctx, later, 10*time.Minute); len(again) != 0 {
+		t.Errorf("second run = %+v, want nothing new", again)
+	}
+
+	quiet, _ := r.Get(ctx, "quiet")
+	if quiet.State != model.AgentStuck {
+		t.Errorf("quiet is %s, want stuck", quiet.State)
+	}
+	open := model.FlagStatusOpen
+	flags, err := s.ListFlags(ctx, model.FlagFilter{IssueID: &quiet.ID, Status: &open})
+	if err != nil {
+		t.Fatalf("ListFlags: %v", err)
+	}
+	if len(flags) != 1 || flags[0].Type != model.FlagAgentStuck || flags[0].CreatedBy != monitorActor ||
+		!strings.Contains(flags[0].Summary, "quiet") {
+		t.Fatalf("flags = %+v, want one agent_stuck flag on quiet", flags)
+	}
+
+	// Reporting in again unsticks the agent and resolves its flag.
+	if quiet, err = r.SetStatus(ctx, StatusInput{Name: "quiet"}); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if quiet.State != model.AgentRunning {
+		t.Errorf("quiet is %s after reporting in, want running", quiet.State)
+	}
+	if flags, _ = s.ListFlags(ctx, model.FlagFilter{IssueID: &quiet.ID, Status: &open}); len(flags) != 0 {
+		t.Errorf("open flags after reporting in = %+v, want none", flags)
+	}
+
+	if _, err := r.MarkStuck(ctx, later, 0); err == nil {
+		t.Error("a zero threshold should be rejected")
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+// TestEveryStore takes an agent from registration through stuck to done on each store backend.
+func TestEveryStore(t *testing.T) {
+	storetest.EachStore(t, testEveryStore)
+}
+
+func testEveryStore(t *testing.T, s store.Store) {
+	ctx := storetest.NewTenant(t, s)
+	r := New(s)
+
+	a, err := r.Register(ctx, RegisterInput{Name: "builder-1", Role: "builder", Rig: "ci", RoleBead: "doit-role"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	work := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "fix build"}).ID
+	working := model.AgentWorking
+	if _, err := r.SetStatus(ctx, StatusInput{Name: "builder-1", State: &working, Hook: &work}); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+
+	bead, err := s.GetIssue(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if bead.IssueType != model.TypeAgent || bead.Status != model.StatusInProgress ||
+		bead.AgentState != model.AgentWorking || bead.RoleType != "builder" || bead.Rig != "ci" ||
+		bead.RoleBead != "doit-role" || bead.HookBead != work || bead.LastActivity == nil {
+		t.Errorf("bead = %+v, want a working builder on ci hooked to %s", bead, work)
+	}
+	if ready := storetest.ReadyIDs(t, ctx, s); len(ready) != 1 || ready[0] != work {
+		t.Errorf("ready = %v, want only %s", ready, work)
+	}
+
+	stuck, err := r.MarkStuck(ctx, time.Now().Add(time.Hour), 10*time.Minute)
+	if err != nil {
+		t.Fatalf("MarkStuck: %v", err)
+	}
+	if len(stuck) != 1 || stuck[0].Name != "builder-1" {
+		t.Fatalf("stuck = %+v, want builder-1", stuck)
+	}
+	if bead, _ = s.GetIssue(ctx, a.ID); bead.AgentState != model.AgentStuck {
+		t.Errorf("agent state = %s, want stuck", bead.AgentState)
+	}
+
+	done := model.AgentDone
+	if _, err := r.SetStatus(ctx, StatusInput{Name: "builder-1", State: &done}); err != nil {
+		t.Fatalf("SetStatus(done): %v", err)
+	}
+	if bead, _ = s.GetIssue(ctx, a.ID); bead.Status != model.StatusClosed || bead.HookBead != "" {
+		t.Errorf("bead = %s hooked to %q, want closed with no hook", bead.Status, bead.HookBead)
+	}
+	open := model.FlagStatusOpen
+	if flags, _ := s.ListFlags(ctx, model.FlagFilter{IssueID: &a.ID, Status: &open}); len(flags) != 0 {
+		t.Errorf("open flags = %+v, want the stuck flag resolved", flags)
+	}
+}
+
+// TestEveryStoreConcurrentRegister registers one name from several workers
+// at once on each store backend: they all end up with the same agent.
+func TestEveryStoreConcurrentRegister(t *testing.T) {
+	storetest.EachStore(t, func(t *testing.T, s store.Store) {
+		ctx := storetest.NewTenant(t, s)
+		r := New(s)
+
+		const workers = 8
+		ids := make([]string, workers)
+		errs := make([]error, workers)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				a, err := r.Register(ctx, RegisterInput{Name: "builder-1"})
+				if err == nil {
+					ids[w] = a.ID
+				}
+				errs[w] = err
+			}(w)
+		}
+		wg.Wait()
+
+		for i := 0; i < workers; i++ {
+			if errs[i] != nil {
+				t.Fatalf("Register: %v", errs[i])
+			}
+			if ids[i] != ids[0] {
+				t.Errorf("registrations got agents %s and %s, want one", ids[0], ids[i])
+			}
+		}
+		agents, err := r.List(ctx, true)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(agents) != 1 {
+			t.Errorf("agents = %+v, want one builder-1", agents)
+		}
+	})
+}
@@ -118,39 +118,52 @@ This project uses Doit for persistent work tracking via MCP.
 
 ### Workflow
 - Call doit_list_projects to find your project slug
-- Call doit_ready with project slug to find available work
+- Call doit_claim_next with project slug to claim the next available issue
 - Call doit_get_issue for full details before starting
-- Call doit_update_issue with claim=true to start work
+- Call doit_heartbeat periodically while working to keep your claim
 - Call doit_update_issue with status=closed when done
 - Call doit_create_issue with project slug for new work items
-- Call doit_add_dependency to track blockers</code></pre>
+- Call doit_add_dependency to track blockers
+- Call doit_batch to create an epic with its children and dependencies at once</code></pre>
 
-<h2>Agent Tools (25)</h2>
+<h2>Agent Tools (54)</h2>
 <p>Available on <code>POST /mcp</code> — authenticated with any API key (tenant or admin).</p>
 
 <h3>Issue CRUD</h3>
 <table>
   <tr><th>Tool</th><th>Description</th></tr>
-  <tr><td><code>doit_create_issue</code></td><td>Create a new work item (task, bug, feature, epic, etc). Returns the created issue with its hash-based ID. Use <code>parent_id</code> to create a hierarchical child (e.g. epic.1). Use <code>project</code> (slug) to assign to a project.</td></tr>
+  <tr><td><code>doit_create_issue</code></td><td>Create a new work item (task, bug, feature, epic, etc). Returns the created issue with its hash-based ID. Use <code>parent_id</code> to create a hierarchical child (e.g. epic.1). Use <code>project</code> (slug) to assign to a project. Optional <code>estimated_minutes</code> feeds <code>doit_critical_path</code>.</td></tr>
   <tr><td><code>doit_get_issue</code></td><td>Get full details of an issue including labels, dependencies, and parent.</td></tr>
-  <tr><td><code>doit_update_issue</code></td><td>Update fields on an existing issue. Only specified fields are changed. Use claim=true to atomically set assignee and status to in_progress.</td></tr>
-  <tr><td><code>doit_list_issues</code></td><td>List issues with filtering by status, type, priority, assignee, and labels. Supports sorting by priority, oldest, updated, or hybrid. Use <code>project</code> (slug) to scope results. Set <code>pinned=true</code> to retrieve only pinned issues. Returns <code>{count, has_more, items}</code> envelope. Defaults: <code>compact=true</code>, <code>limit=50</code>. Without project filter and <code>compact=false</code>, hard cap at 20 items. Oversized responses auto-compact.</td></tr>
-  <tr><td><code>doit_delete_issue</code></td><td>Delete an issue. Cascades to dependencies, labels, comments, and events.</td></tr>
+  <tr><td><code>doit_update_issue</code></td><td>Update fields on an existing issue. Only specified fields are changed; <code>estimated_minutes=0</code> clears an estimate. Use claim=true to atomically set assignee and status to in_progress with a renewable lease (see <code>doit_heartbeat</code>). Pass expected_content_hash or expected_updated_at to fail with a conflict (returning the current issue) if it changed since you read it. Closing an issue opens the gates waiting on it.</td></tr>
+  <tr><td><code>doit_list_issues</code></td><td>List issues with filtering by status, type, priority, assignee, and labels. Supports sorting by priority, oldest, updated, or hybrid. Use <code>project</code> (slug) to scope results. Set <code>pinned=true</code> to retrieve only pinned issues. Set <code>search</code> to filter by a substring of title or description. Returns <code>{count, has_more, next_cursor, items}</code> envelope; pass <code>next_cursor</code> as <code>cursor</code> for the next page. Defaults: <code>compact=true</code>, <code>limit=50</code>. Without project filter and <code>compact=false</code>, hard cap at 20 items. Oversized responses auto-compact.</td></tr>
+  <tr><td><code>doit_delete_issue</code></td><td>Move an issue to the trash. See Trash below.</td></tr>
+  <tr><td><code>doit_restore_issue</code></td><td>Restore a deleted issue from the trash with its dependencies, labels, comments and events.</td></tr>
+  <tr><td><code>doit_list_trash</code></td><td>List deleted issues awaiting purge, most recently deleted first. Optional <code>limit</code> (default 50).</td></tr>
+</table>
+
+<h3>Search</h3>
+<table>
+  <tr><th>Tool</th><th>Description</th></tr>
+  <tr><td><code>doit_search</code></td><td>Full-text search across title, description, design, acceptance criteria, notes, and comments. Web-search syntax: words are ANDed, <code>"quoted phrases"</code> match in order, <code>-word</code> excludes. Results are ranked by relevance (title matches rank highest) and carry a <code>snippet</code> with matches wrapped in <code>**</code>. Filter by <code>status</code>, <code>issue_type</code>, or <code>project</code> (slug). Returns <code>{count, has_more, items}</code> envelope. Defaults: <code>compact=true</code>, <code>limit=50</code>.</td></tr>
 </table>
 
 <h3>Ready Detection</h3>
 <table>
   <tr><th>Tool</th><th>Description</th></tr>
-  <tr><td><code>doit_ready</code></td><td>List issues ready for work — open, not blocked, not deferred. Call this to find the next task to work on. Use <code>project</code> (slug) to scope results. Returns <code>{count, has_more, items}</code> envelope. Defaults: <code>compact=true</code>, <code>limit=50</code>. Without project filter and <code>compact=false</code>, hard cap at 20 items.</td></tr>
+  <tr><td><code>doit_ready</code></td><td>List issues ready for work — open, not blocked, not deferred, and not held back by a gating dependency (see Ready Detection below). Call this to find the next task to work on. Use <code>project</code> (slug) to scope results. Returns <code>{count, has_more, next_cursor, items}</code> envelope; pass <code>next_cursor</code> as <code>cursor</code> for the next page. Defaults: <code>compact=true</code>, <code>limit=50</code>. Without project filter and <code>compact=false</code>, hard cap at 20 items.</td></tr>
+  <tr><td><code>doit_explain_ready</code></td><td>Explain why an issue is or is not ready. Returns <code>{issue_id, status, ready, reasons}</code>; each reason has a <code>kind</code> (<code>status</code>, <code>ephemeral</code>, <code>deferred</code>, <code>flag</code>, <code>dependency</code>, <code>ancestor</code>) and a <code>message</code>. Dependency and ancestor reasons carry <code>blockers</code>: the unfinished issues in the way, with status and assignee, each with its own <code>blocked_by</code> chain.</td></tr>
+  <tr><td><code>doit_claim_next</code></td><td>Atomically claim the next ready issue (highest priority, then oldest): assigns it to the caller and sets <code>in_progress</code> in one step, so competing agents never receive the same issue. Filters: <code>project</code> (slug), <code>issue_type</code>, <code>labels</code> (all must match). <code>agent</code> names the claimant; it defaults to the MCP client name. Returns the issue, or <code>{claimed: false}</code> when nothing is ready.</td></tr>
+  <tr><td><code>doit_heartbeat</code></td><td>Renew your claim lease on an <code>in_progress</code> issue. Pass the same <code>agent</code> used to claim and optionally <code>lease_seconds</code>. Fails with <code>claim lease lost</code> if the issue was released or reassigned.</td></tr>
 </table>
 
 <h3>Dependencies</h3>
 <table>
   <tr><th>Tool</th><th>Description</th></tr>
-  <tr><td><code>doit_add_dependency</code></td><td>Add a dependency between two issues. The 'blocks' type prevents the dependent from appearing in ready work.</td></tr>
+  <tr><td><code>doit_add_dependency</code></td><td>Add a dependency between two issues. Gating types (<code>blocks</code>, <code>conditional-blocks</code>, <code>waits-for</code>, <code>until</code>, <code>parent-child</code>) keep the dependent out of ready work; see Ready Detection below. Edges that would close a cycle among blocking types (<code>blocks</code>, <code>conditional-blocks</code>, <code>waits-for</code>) or among <code>parent-child</code> links are rejected, and the error shows the cycle.</td></tr>
   <tr><td><code>doit_remove_dependency</code></td><td>Remove a dependency between two issues.</td></tr>
   <tr><td><code>doit_list_dependencies</code></td><td>List dependencies for an issue. Direction: upstream, downstream, or both.</td></tr>
   <tr><td><code>doit_dependency_tree</code></td><td>Walk the parent-child hierarchy tree from a root issue.</td></tr>
+  <tr><td><code>doit_critical_path</code></td><td>Schedule an epic's unfinished work by <code>estimated_minutes</code>. Returns <code>{critical_path, total_minutes, earliest_finish, tasks, unestimated}</code>; each task has its earliest and latest start and finish (minutes from <code>start</code>) and its <code>slack</code>. See Critical Path below.</td></tr>
 </table>
 
 <h3>Comments</h3>
@@ -158,6 +171,9 @@ This project uses Doit for persistent work tracking via MCP.
   <tr><th>Tool</th><th>Description</th></tr>
   <tr><td><code>doit_add_comment</code></td><td>Add a comment to an issue.</td></tr>
   <tr><td><code>doit_list_comments</code></td><td>List comments on an issue, ordered by creation time.</td></tr>
+  <tr><td><code>doit_list_events</code></td><td>List an issue's audit trail, newest first. Optional <code>event_type</code> filter and <code>limit</code> (default 50). See Audit Trail below.</td></tr>
+  <tr><td><code>doit_issue_at</code></td><td>Show an issue as it stood at a past <code>at</code> (RFC 3339 or <code>YYYY-MM-DD</code>): fields, labels and its own dependencies, rebuilt from the audit trail. Returns <code>{at, issue, dependencies, undone}</code>.</td></tr>
+  <tr><td><code>doit_diff_issue</code></td><td>List the fields that changed between <code>from</code> and <code>to</code> (default now), each as <code>{field, from, to}</code>, and the <code>actors</code> who changed them.</td></tr>
 </table>
 
 <h3>Labels</h3>
@@ -167,25 +183,74 @@ This project uses Doit for persistent work tracking via MCP.
   <tr><td><code>doit_remove_label</code></td><td>Remove a label from an issue.</td></tr>
 </table>
 
+<h3>Batches</h3>
+<table>
+  <tr><th>Tool</th><th>Description</th></tr>
+  <tr><td><code>doit_batch</code></td><td>Apply an ordered list of <code>ops</code> (<code>create</code>, <code>update</code>, <code>dependency</code>, <code>label</code>, <code>comment</code>) in one transaction. A create with <code>ref</code> can be referenced by later ops as <code>$ref</code> in any issue ID field. All or nothing: a failure names the failing op's index and nothing is applied. Returns <code>{applied, results}</code>, one result per op.</td></tr>
+</table>
+
 <h3>Projects</h3>
 <table>
   <tr><th>Tool</th><th>Description</th></tr>
   <tr><td><code>doit_create_project</code></td><td>Create a project within your tenant for organizing issues.</td></tr>
   <tr><td><code>doit_list_projects</code></td><td>List projects in your tenant. Returns project slugs for use with <code>project</code> filters.</td></tr>
+  <tr><td><code>doit_ready_policy</code></td><td>Show or set which dependency types gate ready work in a project (see Ready Detection below). Pass <code>project</code> (slug) alone to show the policy, <code>gates</code> to replace it (an empty list disables every gate), or <code>reset=true</code> to restore the default of all five.</td></tr>
+  <tr><td><code>doit_compaction_policy</code></td><td>Show or set when a project's closed issues are compacted (see Semantic Compaction below). Required: <code>project</code> (slug). <code>age</code> (a duration such as <code>720h</code>) replaces the age <code>doit_compact</code> is given; <code>exclude_labels</code> lists labels whose issues are never compacted. Only the fields given change; <code>reset=true</code> removes the policy.</td></tr>
 </table>
 
 <h3>Compaction</h3>
 <table>
   <tr><th>Tool</th><th>Description</th></tr>
-  <tr><td><code>doit_compact</code></td><td>Run semantic compaction on old closed issues. Summarizes issues to save context window tokens. Default threshold: 7 days.</td></tr>
+  <tr><td><code>doit_compact</code></td><td>Run semantic compaction on old closed issues. Summarizes issues to save context window tokens. Default threshold (<code>age</code>): 7 days to level 1, twice that to level 2. Optional: <code>project</code> (slug), <code>exclude_labels</code>, <code>dry_run=true</code> to preview. Returns <code>{dry_run, scanned, excluded, compacted}</code>.</td></tr>
+  <tr><td><code>doit_uncompact</code></td><td>Restore a compacted issue's content from its snapshots. Required: <code>id</code>. Optional <code>level</code> (default 0, full detail). Returns the restored issue.</td></tr>
+  <tr><td><code>doit_list_compaction_snapshots</code></td><td>List an issue's compaction snapshots by level, each with <code>from_level</code>, <code>summary</code> and the <code>original</code> content.</td></tr>
+</table>
+
+<h3>Garbage Collection</h3>
+<table>
+  <tr><th>Tool</th><th>Description</th></tr>
+  <tr><td><code>doit_gc</code></td><td>Permanently delete ephemeral issues and wisps past their retention (see Garbage Collection below). Optional: <code>dry_run=true</code> to preview, <code>report</code> to write a <code>gc_report</code> wisp or not, overriding the policy. Returns <code>{dry_run, scanned, collected, by_type, report_id}</code>.</td></tr>
+  <tr><td><code>doit_gc_policy</code></td><td>Show or set the tenant's retention. <code>retention</code> maps wisp types to durations (e.g. <code>{"heartbeat": "2h"}</code>); <code>ephemeral</code> covers closed ephemeral issues without a wisp type; <code>report=true</code> makes every run write a <code>gc_report</code>. Only the fields given change; <code>reset=true</code> restores the defaults.</td></tr>
+</table>
+
+<h3>Molecules</h3>
+<table>
+  <tr><th>Tool</th><th>Description</th></tr>
+  <tr><td><code>doit_create_molecule</code></td><td>Create a molecule and its steps together (see Molecules below). Required: <code>title</code>, <code>mol_type</code> (<code>work</code>, <code>swarm</code> or <code>patrol</code>), <code>steps</code> (each with a <code>title</code>, and optionally <code>description</code>, <code>acceptance_criteria</code>, <code>estimated_minutes</code>). Optional: <code>work_type</code> (<code>mutex</code>, the default, or <code>open_competition</code>), <code>crystallizes</code>, <code>ephemeral</code>, <code>description</code>, <code>priority</code>, <code>project</code> (slug), <code>labels</code>.</td></tr>
+  <tr><td><code>doit_molecule</code></td><td>Show a molecule with its <code>steps</code> in order (status, assignee, and under open competition the <code>competitors</code> and <code>winner</code>) and its <code>progress</code>. Required: <code>id</code>.</td></tr>
+  <tr><td><code>doit_claim_step</code></td><td>Claim a step, or join it under open competition. Required: <code>id</code>. Optional: <code>agent</code>, <code>lease_seconds</code>. Returns the molecule.</td></tr>
+  <tr><td><code>doit_complete_step</code></td><td>Close a step; under open competition the first to complete it wins. Required: <code>id</code>. Optional: <code>agent</code>, <code>reason</code>. Returns the molecule.</td></tr>
+</table>
+
+<h3>Agents</h3>
+<table>
+  <tr><th>Tool</th><th>Description</th></tr>
+  <tr><td><code>doit_register_agent</code></td><td>Register as an agent, or restart one that ended; its state becomes <code>spawning</code>. Optional: <code>agent</code>, <code>role</code>, <code>rig</code>, <code>role_bead</code>, <code>project</code> (slug).</td></tr>
+  <tr><td><code>doit_agent_status</code></td><td>Heartbeat, optionally moving to a new <code>state</code> or hooking an issue with <code>hook</code> (empty clears it). Optional: <code>agent</code>. Returns the agent.</td></tr>
+  <tr><td><code>doit_list_agents</code></td><td>List agents with <code>state</code>, <code>last_activity</code>, <code>hook_bead</code> and the in-progress issues they are <code>holding</code>. Optional: <code>all=true</code> to include agents that have ended.</td></tr>
+</table>
+
+<h3>Templates</h3>
+<table>
+  <tr><th>Tool</th><th>Description</th></tr>
+  <tr><td><code>doit_mark_template</code></td><td>Make an issue tree a template. Required: <code>id</code> (the root). Optional: <code>unmark=true</code> to undo it. Returns the template and the <code>variables</code> it uses.</td></tr>
+  <tr><td><code>doit_list_templates</code></td><td>List template roots with their issue count and <code>variables</code>.</td></tr>
+  <tr><td><code>doit_instantiate_template</code></td><td>Copy a template with its variables filled in. Required: <code>id</code>, <code>variables</code> (object of name to value). Optional: <code>project</code> (slug), <code>parent</code>. Returns the new <code>root_id</code> and a copy per template issue.</td></tr>
+</table>
+
+<h3>Gates</h3>
+<table>
+  <tr><th>Tool</th><th>Description</th></tr>
+  <tr><td><code>doit_create_gate</code></td><td>Create a gate holding back the issues in <code>blocks</code>. Required: <code>title</code>, <code>await_type</code> (<code>timer</code>, <code>issue</code>, <code>flag</code>, <code>signal</code>). Optional: <code>await_id</code>, <code>timeout</code> (e.g. <code>30m</code>), <code>description</code>, <code>priority</code>, <code>project</code> (slug).</td></tr>
+  <tr><td><code>doit_signal_gate</code></td><td>Send a named <code>signal</code>, opening every gate waiting on it. Optional: <code>note</code>. Returns the gates opened.</td></tr>
 </table>
 
 <h3>Lessons Learned</h3>
 <table>
   <tr><th>Tool</th><th>Description</th></tr>
   <tr><td><code>doit_record_lesson</code></td><td>Record a lesson learned — a mistake and its correction. Required: <code>title</code>, <code>mistake</code>, <code>correction</code>. Optional: <code>project</code> (slug), <code>issue_id</code>, <code>expert</code>, <code>components</code>, <code>severity</code>, <code>created_by</code>.</td></tr>
-  <tr><td><code>doit_list_lessons</code></td><td>List lessons learned. All filters optional: <code>project</code> (slug), <code>status</code>, <code>expert</code>, <code>component</code>, <code>severity</code>, <code>limit</code>, <code>compact</code>. Returns <code>{count, has_more, items}</code> envelope. Defaults: <code>compact=true</code>, <code>limit=50</code>.</td></tr>
-  <tr><td><code>doit_resolve_lesson</code></td><td>Mark a lesson as resolved. Required: <code>id</code>. Optional: <code>resolved_by</code>.</td></tr>
+  <tr><td><code>doit_list_lessons</code></td><td>List lessons learned. All filters optional: <code>project</code> (slug), <code>status</code>, <code>expert</code>, <code>component</code>, <code>severity</code>, <code>limit</code>, <code>compact</code>. Returns <code>{count, has_more, next_cursor, items}</code> envelope; pass <code>next_cursor</code> as <code>cursor</code> for the next page. Defaults: <code>compact=true</code>, <code>limit=50</code>.</td></tr>
+  <tr><td><code>doit_resolve_lesson</code></td><td>Mark a lesson as resolved. Required: <code>id</code>. Optional: <code>resolved_by</code>. Opens the gates waiting on the flag.</td></tr>
 </table>
 
 <h3>Retries (Operational Memory)</h3>
@@ -203,11 +268,11 @@ Flags survive session reset and are queryable across agent sessions.</p>
 <table>
   <tr><th>Tool</th><th>Description</th></tr>
   <tr><td><code>doit_raise_flag</code></td><td>Raise an escalation flag. Required: <code>issue_id</code>, <code>type</code>, <code>severity</code> (1=critical, 2=blocking, 3=warning), <code>summary</code>. Optional: <code>context</code> (JSON), <code>project</code> (slug), <code>created_by</code>.</td></tr>
-  <tr><td><code>doit_list_flags</code></td><td>List escalation flags. All filters optional: <code>project</code>, <code>status</code> (open/acknowledged/resolved), <code>severity</code>, <code>issue_id</code>, <code>limit</code>.</td></tr>
+  <tr><td><code>doit_list_flags</code></td><td>List escalation flags. All filters optional: <code>project</code>, <code>status</code> (open/acknowledged/resolved), <code>severity</code>, <code>issue_id</code>, <code>limit</code>. Returns an array of flags; set <code>paged=true</code> for the <code>{count, has_more, next_cursor, items}</code> envelope and <code>cursor</code> paging (default <code>limit=50</code>).</td></tr>
   <tr><td><code>doit_resolve_flag</code></td><td>Resolve a flag with a decision. Required: <code>id</code>, <code>resolution</code>. Optional: <code>resolved_by</code>.</td></tr>
 </table>
 
-<h2>Admin Tools (10)</h2>
+<h2>Admin Tools (13)</h2>
 <p>Available on <code>POST /admin/mcp</code> — requires admin API key. Tenant keys receive 403.</p>
 
 <h3>Tenant Management</h3>
@@ -229,6 +294,19 @@ Flags survive session reset and are queryable across agent sessions.</p>
   <tr><td><code>doit_delete_project</code></td><td>Delete a project. Rejects if issues still reference the project. Accepts project slug.</td></tr>
 </table>
 
+<h3>Graph Validation</h3>
+<table>
+  <tr><th>Tool</th><th>Description</th></tr>
+  <tr><td><code>doit_validate_graph</code></td><td>Report dependency graph problems per tenant: <code>cycles</code> among blocking or parent-child edges (each with its <code>path</code>), <code>orphaned_children</code> (IDs like <code>parent.3</code> whose parent-child link is gone) and <code>dangling_references</code> (edges to issues outside the tenant). Pass <code>tenant</code> (slug) to check one tenant; defaults to all. <code>valid</code> is true when nothing was found.</td></tr>
+  <tr><td><code>doit_compaction_settings</code></td><td>Show or change how a tenant's issues are summarized when compacted. Required: <code>tenant</code> (slug). <code>summarizer</code>: <code>heuristic</code> (default), <code>extractive</code> or <code>endpoint</code>. <code>level1_budget</code> and <code>level2_budget</code> cap summary length in characters (default 400 and 120). The endpoint summarizer needs <code>endpoint_url</code> and <code>endpoint_model</code>; <code>endpoint_api_key_env</code> names the server environment variable holding its bearer token, which must start with <code>DOIT_SUMMARIZER_</code> so no other server secret can be sent to the endpoint. Only the fields given change; <code>reset=true</code> restores the defaults.</td></tr>
+</table>
+
+<h3>Background Jobs</h3>
+<table>
+  <tr><th>Tool</th><th>Description</th></tr>
+  <tr><td><code>doit_jobs</code></td><td>Show a tenant's background jobs: each one's <code>interval</code>, whether it is the server <code>default</code>, its <code>last_run</code> (with its <code>result</code> or <code>error</code>) and <code>next_run</code>. Required: <code>tenant</code> (slug). Pass <code>job</code> for its run history too (newest first, <code>limit</code> runs, default 20). With <code>job</code>, <code>interval</code> (e.g. <code>"6h"</code>, or <code>"0s"</code> to turn it off) overrides how often it runs for the tenant, and <code>reset=true</code> goes back to the default.</td></tr>
+</table>
+
 <h3>Admin Key Management</h3>
 <table>
   <tr><th>Tool</th><th>Description</th></tr>
@@ -267,7 +345,7 @@ Flags survive session reset and are queryable across agent sessions.</p>
 </p>
 
 <h3>Flag Types</h3>
-<p><code>structural_concern</code> &middot; <code>feature_concern</code> &middot; <code>red_flag</code> &middot; <code>human_decision</code> &middot; <code>security_concern</code></p>
+<p><code>structural_concern</code> &middot; <code>feature_concern</code> &middot; <code>red_flag</code> &middot; <code>human_decision</code> &middot; <code>security_concern</code> &middot; <code>agent_stuck</code> and <code>gate_timeout</code> (raised by the server)</p>
 
 <h3>Flag Statuses</h3>
 <p>
@@ -298,13 +376,18 @@ Flags survive session reset and are queryable across agent sessions.</p>
 <h2>Response Format</h2>
 
 <h3>List Response Envelope</h3>
-<p>All list endpoints (<code>doit_list_issues</code>, <code>doit_ready</code>, <code>doit_list_lessons</code>, <code>doit_list_comments</code>) return a response envelope:</p>
+<p>All list endpoints (<code>doit_list_issues</code>, <code>doit_search</code>, <code>doit_ready</code>, <code>doit_list_lessons</code>, <code>doit_list_comments</code>, and <code>doit_list_flags</code> with <code>paged=true</code>) return a response envelope:</p>
 <pre><code>{
-  "count": 12,        // Number of items in this response
-  "has_more": true,   // True if more items exist beyond the limit
-  "items": [...]      // The actual items (compact or full)
+  "count": 12,          // Number of items in this response
+  "has_more": true,     // True if more items exist beyond the limit
+  "next_cursor": "...", // Opaque; pass as cursor to fetch the next page
+  "items": [...]        // The actual items (compact or full)
 }</code></pre>
 
+<h3>Pagination</h3>
+<p><code>doit_list_issues</code>, <code>doit_ready</code>, <code>doit_list_lessons</code> and <code>doit_list_flags</code> (with <code>paged=true</code>) page with keyset cursors. When <code>has_more</code> is true, call again with the same filters and <code>cursor</code> set to <code>next_cursor</code>. Cursors mark a position in the sort order rather than an offset, so issues created between calls never shift or repeat earlier pages. A cursor only works with the <code>sort_by</code> it was issued for.</p>
+<p>Sorts on fields that change are only as stable as those fields. <code>sort_by=updated</code> and <code>hybrid</code> order by last update, so an issue edited while you page moves ahead of the cursor and is skipped if you had not reached it yet. An issue whose priority changes between pages (<code>priority</code>, <code>hybrid</code> and <code>doit_ready</code>) can be skipped or returned twice. Page with <code>sort_by=oldest</code> to see every issue exactly once.</p>
+
 <h3>Response Size Protection</h3>
 <p>List endpoints include server-side protection against oversized responses that can crash agent sessions:</p>
 <ul>
@@ -317,13 +400,58 @@ Flags survive session reset and are queryable across agent sessions.</p>
 <h2>Key Concepts</h2>
 
 <h3>Ready Detection</h3>
-<p>An issue is "ready" when it is <code>open</code>, has no unresolved <code>blocks</code> dependencies, and is not deferred to the future. Use <code>doit_ready</code> to find work.</p>
+<p>An issue is "ready" when it is <code>open</code>, not deferred to the future, has no open severity 1&ndash;2 flag, and none of its gating dependencies hold it back. For an edge from the issue to its target:</p>
+<ul>
+  <li><strong>blocks</strong> &mdash; gated until the target is closed.</li>
+  <li><strong>conditional-blocks</strong> &mdash; the issue is the target's fallback path: gated until the target closes with a failure reason (one starting with <code>failed</code>, <code>rejected</code>, <code>aborted</code>, <code>abandoned</code>, <code>canceled</code>, <code>wontfix</code>, <code>won't fix</code>, <code>timed out</code> or <code>timeout</code>). A successful close keeps it gated.</li>
+  <li><strong>waits-for</strong> &mdash; gated until the target and all of its children are closed.</li>
+  <li><strong>until</strong> &mdash; ready only while the target is still open; once it closes, the issue drops out of ready work.</li>
+  <li><strong>parent-child</strong> &mdash; gated while any ancestor is <code>blocked</code>, <code>deferred</code> (by status or <code>defer_until</code>) or itself gated, so the children of a deferred epic wait with it.</li>
+</ul>
+<p>All five gates apply by default. <code>doit_ready_policy</code> switches individual gates off for one project, e.g. <code>{"project": "web", "gates": ["blocks", "parent-child"]}</code>. Use <code>doit_ready</code> to browse work and <code>doit_claim_next</code> to take it; claiming records a <code>status_changed</code> event with the claimant as actor.</p>
+
+<h3>Claim Leases</h3>
+<p>Claims made with <code>doit_claim_next</code> or <code>claim=true</code> expire after <code>lease_seconds</code> (default 30 minutes) unless renewed with <code>doit_heartbeat</code>. The server checks every minute and returns expired issues to <code>open</code>, recording an <code>abandoned</code> retry and a <code>status_changed</code> event. Moving an issue out of <code>in_progress</code> ends its lease. <code>last_activity</code> holds the time of the latest claim or heartbeat.</p>
+
+<h3>Batches</h3>
+<p><code>doit_batch</code> builds a whole plan at once. For example, <code>{"op": "create", "ref": "epic", "title": "Billing", "issue_type": "epic"}</code> followed by two children <code>{"op": "create", "ref": "api", "parent_id": "$epic", "title": "API"}</code> and <code>{"op": "create", "ref": "ui", "parent_id": "$epic", "title": "UI"}</code>, then <code>{"op": "dependency", "issue_id": "$ui", "depends_on_id": "$api"}</code> so the UI waits on the API. A batch holds at most 500 ops.</p>
 
 <h3>Hierarchical Tasks</h3>
 <p>Issues can be nested: epic &rarr; task &rarr; subtask. Use <code>parent</code> when creating an issue to make it a child. Children get auto-numbered IDs like <code>parent.1</code>, <code>parent.2</code>.</p>
 
+<h3>Critical Path</h3>
+<p>Give tasks an <code>estimated_minutes</code> when creating or updating them, then call <code>doit_critical_path</code> on their epic. A task can start once every other task in the epic it depends on through <code>blocks</code>, <code>conditional-blocks</code> or <code>waits-for</code> has finished, and a parent finishes after its children. The longest such chain is the critical path: any delay on it delays the epic, while other tasks can slip by their <code>slack</code>. Closed tasks are done and drop out; open blockers outside the epic are listed per task as <code>external_blockers</code> but not scheduled. The CLI shows the same plan with <code>doit plan &lt;epic&gt;</code>.</p>
+
+<h3>Audit Trail</h3>
+<p>Every change to an issue is recorded as an event in the same transaction as the change: creation, each updated field (<code>field</code>, <code>old_value</code>, <code>new_value</code>), status changes, closes and reopens, labels, dependencies, comments, compaction, deletion and restore. The <code>actor</code> is whoever the call names (<code>created_by</code>, <code>claimant</code>, a comment's <code>author</code>, <code>agent</code>), else the MCP client, and <code>system</code> for background jobs. Read it with <code>doit_list_events</code> or on the issue page of the web UI.</p>
+<p>Because every event keeps the old value, doit can replay the trail backwards. <code>doit_issue_at</code> rebuilds an issue at any past time by undoing, newest first, each change made since; compaction snapshots bring back content compacted away. Fields the trail does not track, such as the issue type and lease, keep their current values, and dependencies are the issue's own edges. <code>doit_diff_issue</code> compares two such versions field by field. The CLI offers the same with <code>doit show &lt;id&gt; --at &lt;time&gt;</code> and <code>--since &lt;time&gt;</code>.</p>
+
+<h3>Trash</h3>
+<p>Deleting an issue records <code>deleted_at</code> and <code>deleted_by</code> instead of removing it. A deleted issue drops out of lists, search, ready detection and the dependency graph: it no longer blocks anything, and edges to it are hidden but kept. <code>doit_restore_issue</code> brings it back exactly as it was. The server purges issues that have been in the trash longer than <code>TRASH_RETENTION</code> (default 30 days), and only then are their dependencies, labels, comments and events removed.</p>
+
+<h3>Background Jobs</h3>
+<p>The server runs maintenance for every tenant as scheduled jobs: <code>reap_leases</code> (every <code>LEASE_REAP_INTERVAL</code>, default 1 minute), <code>purge_trash</code> (<code>TRASH_PURGE_INTERVAL</code>, 1 hour), <code>compact</code> (<code>COMPACT_INTERVAL</code>, 24 hours, compacting issues closed longer than <code>COMPACT_AGE</code>, default 7 days), <code>gc_ephemeral</code> (<code>EPHEMERAL_GC_INTERVAL</code>, 1 hour; see Garbage Collection), <code>agent_monitor</code> (<code>AGENT_MONITOR_INTERVAL</code>, 1 minute; see Agents) and <code>check_gates</code> (<code>GATE_CHECK_INTERVAL</code>, 1 minute; see Gates). An interval of 0 turns a job off. Each tenant can override these with <code>doit_jobs</code>. With Postgres, a job takes an advisory lock per tenant before it runs, so however many replicas share the database only one runs it. Every run is recorded with its result or error; the newest 100 runs of each job are kept.</p>
+
+<h3>Garbage Collection</h3>
+<p>Ephemeral issues and wisps are throwaway: heartbeats, pings, patrol notes, scratch work. Garbage collection deletes them for good once they outlive their tenant's retention, set with <code>doit_gc_policy</code>. By default heartbeats and pings are kept 1 hour, patrols 24 hours, recovery and error wisps 7 days, escalations and gc reports 30 days, and closed ephemeral issues without a wisp type 24 hours. A wisp's age counts from when it was closed or, while it is open, last updated; other ephemeral issues, such as unread messages, are kept while open. Pinned and in-progress issues are never collected, nor are the steps of molecules that have not closed yet. Unlike deletion, collection skips the trash. With <code>report</code> on, each run leaves one closed <code>gc_report</code> wisp listing what it deleted by type. The server runs <code>gc_ephemeral</code> every <code>EPHEMERAL_GC_INTERVAL</code>; <code>doit_gc</code> runs it on demand.</p>
+
+<h3>Molecules</h3>
+<p>A molecule is an issue of type <code>molecule</code> whose children are steps that agents share. Its <code>mol_type</code> says how the steps run: <code>work</code> steps run in order, each blocked by the one before; <code>swarm</code> and <code>patrol</code> steps all run at once, and patrol steps are ephemeral patrol wisps by default. Its <code>work_type</code> says how agents share a step: under <code>mutex</code> one agent claims a step with <code>doit_claim_step</code> and holds it until it closes; under <code>open_competition</code> any number join it, each recorded as a <code>joined</code> event, and the first to call <code>doit_complete_step</code> wins, becoming its assignee. The molecule's status follows its steps: open until one starts, in progress until all are closed, then closed, whichever tool moves them. A molecule with <code>crystallizes</code> set turns its ephemeral steps into permanent issues when it closes, so the record of the work survives garbage collection.</p>
+
+<h3>Agents</h3>
+<p>Agents register with <code>doit_register_agent</code>, which creates their bead: an issue of type <code>agent</code> titled with their name, in progress while they are alive so it never shows up as ready work. An agent moves through <code>spawning</code>, <code>running</code>, <code>working</code>, <code>idle</code> and <code>stuck</code> by calling <code>doit_agent_status</code>, and ends with <code>done</code>, <code>stopped</code> or <code>dead</code>, which closes its bead until it registers again. Each call is a heartbeat. The server's <code>agent_monitor</code> job (every <code>AGENT_MONITOR_INTERVAL</code>, default 1 minute) marks spawning, running and working agents that have not reported in for <code>AGENT_STUCK_AFTER</code> (default 10 minutes) <code>stuck</code> and raises an <code>agent_stuck</code> flag on their bead; the flag is resolved when the agent reports in again. State and hook changes are recorded as events on the bead. The web UI's Agents page shows who holds which work.</p>
+
+<h3>Templates</h3>
+<p>A template is an issue tree, an issue and its descendants, marked with <code>doit_mark_template</code>: <code>is_template</code> is set on every issue in it, and template issues are never ready. Titles, descriptions, design, acceptance criteria, notes, assignee, owner and labels may hold <code>{{variables}}</code>. <code>doit_instantiate_template</code> (or <code>doit template apply</code> from the CLI) copies the whole tree in one batch as open issues, filling in the variables, with the same types, priorities, estimates and labels and the blocking dependencies between the template's own issues. Every variable the template uses must be given, and only those, so a misspelt name fails instead of leaving <code>{{version}}</code> behind. The copy goes under <code>parent</code> if given, into <code>project</code>, else the parent's project, else the template's.</p>
+
+<h3>Gates</h3>
+<p>A gate is an issue of type <code>gate</code> that holds back the issues it blocks until something happens. Its <code>await_type</code> says what: <code>timer</code> opens <code>timeout</code> after the gate was created; <code>issue</code> opens when the issue <code>await_id</code> closes; <code>flag</code> opens when the flag <code>await_id</code> is resolved, and a flag gate created without one raises a <code>human_decision</code> flag on itself to wait for, which makes it an approval; <code>signal</code> opens when <code>doit_signal_gate</code> sends the signal named <code>await_id</code>. A waiting gate is <code>blocked</code>, so it is never ready work itself. Opening it closes it with the reason as <code>close_reason</code>, and the issues it blocks become ready unless something else holds them back. Closing an issue with <code>doit_update_issue</code>, <code>doit_batch</code>, <code>doit_complete_step</code> (which also covers the molecule its last step closes) or <code>doit update</code>, or resolving a flag with <code>doit_resolve_flag</code>, opens the gates waiting on it at once; the server's <code>check_gates</code> job (every <code>GATE_CHECK_INTERVAL</code>, default 1 minute) opens timers that ran out and catches issues and flags closed any other way, such as by the scheduled jobs. A non-timer gate still waiting <code>timeout</code> after it was created raises a <code>gate_timeout</code> flag, once, and keeps waiting. Closing a gate by hand opens it too.</p>
+
 <h3>Semantic Compaction</h3>
 <p>Old closed issues can be compacted to save context window tokens. The original content is preserved in a snapshot. Use <code>doit_compact</code> to trigger.</p>
+<p>A run pages through every closed issue, so none are missed however many there are. Each compaction raises the issue's <code>compaction_level</code>, stamps <code>compacted_at</code> and records a <code>compacted</code> event, and issues are never compacted twice to the same level. Pinned issues are always left alone. A project's <code>doit_compaction_policy</code> can give it its own age and labels to exclude; a run can exclude more labels or cover one project. Pass <code>dry_run=true</code> to see what a run would compact first.</p>
+<p>Compaction is reversible. <code>doit_uncompact</code> puts back the content an issue had at any earlier level, and reopening a compacted issue restores its full content automatically, so work never resumes from a one-line summary. The CLI equivalent is <code>doit compact restore &lt;id&gt;</code>.</p>
+<p>Each tenant chooses how its summaries are written, with <code>doit_compaction_settings</code>. The <code>heuristic</code> summarizer keeps the type, title, first lines and close reason. The <code>extractive</code> one keeps the description's most telling sentences (those sharing words with the title or recording a cause, decision or outcome) and which checklist items in the acceptance criteria were met. The <code>endpoint</code> one asks an OpenAI-compatible chat completions API, such as a local model server; if the call fails, the issue gets a heuristic summary and the compaction result carries the error. Summaries are cut to the tenant's budget for their level.</p>
 
 <h3>Agent Messaging &rarr; TheHerald</h3>
 <p>Agent-to-agent messaging is handled by <a href="https://herald.aoendpoint.com/documentation"><strong>TheHerald</strong></a>, a dedicated messaging MCP server. Herald provides typed messages (DO, ASK, TELL, HAND), conversation threading, signals (ACK, CLAIM, BLOCK, REJECT), and agent identity. Add Herald to your <code>.mcp.json</code> alongside Doit.</p>
@@ -1,14 +1,67 @@
 package api
 
 import (
+	"context"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/jobs"
 	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// DefaultClaimLease is how long a claim lasts without a heartbeat unless the
+// caller or server configuration says otherwise.
+const DefaultClaimLease = 30 * time.Minute
+
 // Handlers wraps the store for MCP tool implementations.
 type Handlers struct {
-	store store.Store
+	store      store.Store
+	claimLease time.Duration
+	jobs       *jobs.Scheduler // nil when background jobs are not running
 }
 
 func NewHandlers(s store.Store) *Handlers {
-	return &Handlers{store: s}
+	return &Handlers{store: s, claimLease: DefaultClaimLease}
+}
+
+// SetClaimLease changes the default lease given to claims and heartbeats
+// that don't ask for a specific length.
+func (h *Handlers) SetClaimLease(d time.Duration) {
+	if d > 0 {
+		h.claimLease = d
+	}
+}
+
+// SetJobs gives doit_jobs the scheduler running the server's background
+// jobs.
+func (h *Handlers) SetJobs(s *jobs.Scheduler) {
+	h.jobs = s
+}
+
+// leaseFor returns the requested lease, or the default when seconds is 0.
+func (h *Handlers) leaseFor(seconds int) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return h.claimLease
+}
+
+// recordActor is MCP middleware that attributes the events a tool call
+// writes to the calling client, unless the call names an actor itself
+// (created_by, claimant, author). Tools taking an agent argument narrow it
+// further with withAgent.
+func recordActor(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if r, ok := req.(*mcp.CallToolRequest); ok {
+			ctx = auth.WithActor(ctx, claimant(r, ""))
+		}
+		return next(ctx, method, req)
+	}
+}
+
+// withAgent attributes events to the agent named in a tool call, falling
+// back to the calling client.
+func withAgent(ctx context.Context, req *mcp.CallToolRequest, agent string) context.Context {
+	return auth.WithActor(ctx, claimant(req, agent))
 }
@@ -2,15 +2,18 @@ package api
 
 import "github.com/modelcontextprotocol/go-sdk/mcp"
 
-// RegisterAgentTools registers agent-facing MCP tools (25 tools).
+// RegisterAgentTools registers agent-facing MCP tools (54 tools).
 func RegisterAgentTools(server *mcp.Server, h *Handlers) {
+	server.AddReceivingMiddleware(recordActor)
+
 	// --- Issue CRUD ---
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "doit_create_issue",
 		Description: "Create a new work item (task, bug, feature, epic, etc). " +
 			"Returns the created issue with its hash-based ID. " +
-			"Use --parent to create a hierarchical child (e.g. epic.1).",
+			"Use --parent to create a hierarchical child (e.g. epic.1). " +
+			"Set estimated_minutes to feed doit_critical_path.",
 	}, h.CreateIssue)
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -22,7 +25,11 @@ func RegisterAgentTools(server *mcp.Server, h *Handlers) {
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "doit_update_issue",
 		Description: "Update fields on an existing issue. Only specified fields are changed. " +
-			"Use claim=true to atomically set assignee and status to in_progress.",
+			"estimated_minutes sets the estimate (0 clears it). " +
+			"Use claim=true to atomically set assignee and status to in_progress with a renewable lease (see doit_heartbeat). " +
+			"Closing an issue opens any gates waiting on it. " +
+			"Pass expected_content_hash or expected_updated_at (from a previous read) to fail with a conflict " +
+			"instead of overwriting someone else's change; the conflict result includes the current issue.",
 	}, h.UpdateIssue)
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -32,20 +39,50 @@ func RegisterAgentTools(server *mcp.Server, h *Handlers) {
 			"Use project slug to scope results to a single project. " +
 			"Set compact=true for minimal responses that save context window tokens. " +
 			"Set pinned=true to retrieve only pinned issues for fast orientation. " +
-			"Returns {count, has_more, items} envelope. " +
+			"Set search to filter by a substring of title or description; use doit_search for ranked full-text search. " +
+			"Returns {count, has_more, next_cursor, items} envelope; pass next_cursor as cursor to fetch the next page. " +
+			"Issues edited while you page can move past the cursor under updated, hybrid and priority sorts; " +
+			"page with sort_by=oldest to see each issue exactly once. " +
 			"Defaults: compact=true, limit=50. Without project filter and compact=false, hard cap at 20 items.",
 	}, h.ListIssues)
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "doit_delete_issue",
-		Description: "Delete an issue. Cascades to dependencies, labels, comments, and events.",
+		Description: "Delete an issue by moving it to the trash. It keeps its dependencies, labels, comments and events, " +
+			"but disappears from every list, search and ready query until restored with doit_restore_issue. " +
+			"The trash is purged for good after the server's retention window.",
 	}, h.DeleteIssue)
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_restore_issue",
+		Description: "Restore a deleted issue from the trash, with its dependencies, labels, comments and events. " +
+			"Returns the restored issue.",
+	}, h.RestoreIssue)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_list_trash",
+		Description: "List deleted issues awaiting purge, most recently deleted first, with deleted_at and deleted_by. " +
+			"Returns {count, has_more, items} envelope. Default limit 50.",
+	}, h.ListTrash)
+
+	// --- Search ---
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_search",
+		Description: "Full-text search across issue title, description, design, acceptance criteria, notes, and comments. " +
+			"Query uses web-search syntax: words are ANDed, \"quoted phrases\" match in order, -word excludes. " +
+			"Results are ranked by relevance (title matches rank highest) and include a snippet with matches wrapped in **. " +
+			"Filter by status, issue_type, or project slug. " +
+			"Returns {count, has_more, next_cursor, items} envelope; pass next_cursor as cursor to fetch the next page. " +
+			"Defaults: compact=true, limit=50. Without project filter and compact=false, hard cap at 20 items.",
+	}, h.Search)
+
 	// --- Ready detection ---
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "doit_ready",
-		Description: "List issues ready for work — open, not blocked, not deferred. " +
+		Description: "List issues ready for work — open, not blocked, not deferred, and not held back by a gating dependency " +
+			"(blocks, conditional-blocks, waits-for, until, or a held-back parent; see doit_ready_policy). " +
 			"Call this to find the next task to work on. " +
 			"Use project slug to scope results to a single project. " +
 			"Set compact=true for minimal responses that save context window tokens. " +
@@ -53,12 +90,42 @@ func RegisterAgentTools(server *mcp.Server, h *Handlers) {
 			"Defaults: compact=true, limit=50. Without project filter and compact=false, hard cap at 20 items.",
 	}, h.Ready)
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_explain_ready",
+		Description: "Explain why an issue is or is not in doit_ready. Returns {ready, reasons}: each reason has a kind " +
+			"(status, ephemeral, deferred, flag, dependency, ancestor) and a message. Dependency and ancestor reasons " +
+			"list the unfinished issues holding it back as blockers, with their status and assignee, and each " +
+			"blocker's own blocked_by chain.",
+	}, h.ExplainReady)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_claim_next",
+		Description: "Atomically claim the next ready issue: picks the highest-priority, oldest ready issue, " +
+			"assigns it to you and sets status to in_progress in one step, so two agents never get the same issue. " +
+			"Prefer this over doit_ready followed by doit_update_issue with claim=true. " +
+			"Optional filters: project (slug), issue_type, labels (all must match). " +
+			"Pass agent to record who claimed it (defaults to the MCP client name). " +
+			"Returns the claimed issue, or {claimed: false} when nothing is ready. " +
+			"The claim is a lease: call doit_heartbeat before lease_expires_at or the issue returns to open.",
+	}, h.ClaimNext)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_heartbeat",
+		Description: "Renew your claim lease on an in_progress issue. Call it periodically while working " +
+			"(well within lease_seconds, default 30 minutes); if the lease expires the server reopens the issue " +
+			"and records an abandoned retry. Pass the same agent used to claim. " +
+			"Fails with 'claim lease lost' if the issue was released or reassigned — stop work on it.",
+	}, h.Heartbeat)
+
 	// --- Dependencies ---
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "doit_add_dependency",
 		Description: "Add a dependency between two issues. " +
-			"The 'blocks' type prevents the dependent issue from appearing in ready work.",
+			"Gating types (blocks, conditional-blocks, waits-for, until, parent-child) keep the dependent issue " +
+			"out of ready work as described under doit_ready_policy. " +
+			"Rejects edges that would create a cycle among blocking (blocks, conditional-blocks, waits-for) " +
+			"or parent-child dependencies; the error shows the cycle path.",
 	}, h.AddDependency)
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -78,6 +145,14 @@ func RegisterAgentTools(server *mcp.Server, h *Handlers) {
 			"Shows nested tasks at each depth level.",
 	}, h.DependencyTree)
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_critical_path",
+		Description: "Schedule an epic's unfinished work by estimated_minutes. Returns the critical path (the longest " +
+			"chain of blocking dependencies and children), total_minutes and earliest_finish, and per task its " +
+			"earliest and latest start and finish and its slack. Tasks without an estimate count as zero and are " +
+			"listed in unestimated. Optional start (RFC 3339) defaults to now.",
+	}, h.CriticalPath)
+
 	// --- Comments ---
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -90,6 +165,27 @@ func RegisterAgentTools(server *mcp.Server, h *Handlers) {
 		Description: "List comments on an issue, ordered by creation time.",
 	}, h.ListComments)
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_list_events",
+		Description: "List an issue's audit trail, newest first: every change with its old and new values and who made it. " +
+			"Optional event_type filters to one kind (created, updated, status_changed, closed, reopened, commented, " +
+			"label_added, label_removed, dependency_added, dependency_removed, compacted, deleted, restored). Default limit 50.",
+	}, h.ListEvents)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_issue_at",
+		Description: "Show an issue as it stood at a past time (RFC 3339 or YYYY-MM-DD), rebuilt by undoing its audit " +
+			"trail and compaction: fields, status, labels and its own dependencies. Use to answer \"what did this look " +
+			"like last week?\".",
+	}, h.IssueAt)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_diff_issue",
+		Description: "List the fields of an issue that changed between two times (from, and to which defaults to now), " +
+			"each with its value at both ends, plus the actors who made changes in between. Use to review what an " +
+			"agent changed overnight.",
+	}, h.DiffIssue)
+
 	// --- Labels ---
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -102,14 +198,158 @@ func RegisterAgentTools(server *mcp.Server, h *Handlers) {
 		Description: "Remove a label from an issue.",
 	}, h.RemoveLabel)
 
+	// --- Batches ---
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_batch",
+		Description: "Apply many changes in one transaction: all succeed or none do. " +
+			"Use it to lay out a plan (an epic, its children, labels and blocking dependencies) in a single call. " +
+			"ops is an ordered list; each entry has op = create, update, dependency, label or comment plus that op's fields " +
+			"(create/update: title, description, notes, status, priority, issue_type, assignee, owner, parent_id, project, labels; " +
+			"update also takes id and expected_content_hash/expected_updated_at; dependency: issue_id, depends_on_id, type; " +
+			"label: issue_id, label; comment: issue_id, author, text). " +
+			"Give a create a ref (e.g. \"epic\") and later ops may use \"$epic\" anywhere an issue ID is expected. " +
+			"Returns one result per op with the resulting issue IDs; on failure reports the failing op's index and applies nothing.",
+	}, h.Batch)
+
 	// --- Compaction ---
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "doit_compact",
 		Description: "Run semantic compaction on old closed issues. " +
-			"Summarizes issues to save context window tokens. Default threshold: 7 days.",
+			"Summarizes issues to save context window tokens. Issues closed longer than age (default 168h, 7 days) " +
+			"go to level 1, those closed twice as long to level 2; a project's doit_compaction_policy overrides the age. " +
+			"Pinned issues are never compacted. Optional: project (slug) to compact one project, exclude_labels to skip " +
+			"issues with any of those labels, dry_run=true to list what would be compacted without changing anything.",
 	}, h.Compact)
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_uncompact",
+		Description: "Restore a compacted issue's description, design, acceptance criteria and notes from its compaction " +
+			"snapshots. Optional level (default 0, full detail) restores an intermediate level instead. Reopening a " +
+			"compacted issue does this automatically.",
+	}, h.Uncompact)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_list_compaction_snapshots",
+		Description: "List the snapshots compaction took of an issue, by level: each holds the content the issue had " +
+			"at from_level before it was summarized to level.",
+	}, h.ListCompactionSnapshots)
+
+	// --- Garbage collection ---
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_gc",
+		Description: "Permanently delete ephemeral issues and wisps kept longer than the tenant's doit_gc_policy allows. " +
+			"Wisps count from when they were closed or, while open, last updated; other ephemeral issues only once closed. " +
+			"Pinned and in-progress issues, and the steps of molecules still running, are kept. Optional: dry_run=true to list what would be deleted, " +
+			"report=true or false to roll the run into a single gc_report wisp or not, overriding the policy.",
+	}, h.GC)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_gc_policy",
+		Description: "Show or set how long your tenant keeps ephemeral issues. retention maps wisp types (heartbeat, ping, " +
+			"patrol, gc_report, recovery, error, escalation) to durations such as \"2h\"; ephemeral is the retention of " +
+			"closed ephemeral issues without a wisp type; report=true makes each gc run leave a gc_report wisp. " +
+			"Only the fields given change; reset=true restores the defaults.",
+	}, h.GCPolicy)
+
+	// --- Molecules ---
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_create_molecule",
+		Description: "Create a molecule: a parent issue whose steps are shared between agents, created together. " +
+			"mol_type is work (steps run in order, each blocked by the one before), swarm or patrol (steps run at once). " +
+			"work_type is mutex (default: one agent holds a step) or open_competition (agents race; the first to complete " +
+			"a step wins it). steps is a list of {title, description, acceptance_criteria, estimated_minutes}. " +
+			"Patrol steps are ephemeral patrol wisps unless ephemeral=false; ephemeral=true makes other steps ephemeral. " +
+			"crystallizes=true turns ephemeral steps into permanent issues when the molecule closes. " +
+			"Optional: description, priority, project (slug), labels.",
+	}, h.CreateMolecule)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_molecule",
+		Description: "Show a molecule with its steps in order, who holds or competes for each, and its progress. " +
+			"A molecule's status follows its steps: open until one starts, in_progress until all close, then closed.",
+	}, h.Molecule)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_claim_step",
+		Description: "Claim a molecule step. Under mutex this assigns it to you with a lease (lease_seconds, renewed by " +
+			"claiming again) and fails if another agent holds it; under open_competition you join the agents competing " +
+			"for it. Work steps can only be claimed once the steps before them are closed. Optional: agent.",
+	}, h.ClaimStep)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_complete_step",
+		Description: "Complete a molecule step, closing it with an optional reason. Under mutex only its holder may; " +
+			"under open_competition the first agent to complete it wins, and later ones are told who won. " +
+			"Closing the last step closes the molecule. Optional: agent.",
+	}, h.CompleteStep)
+
+	// --- Agents ---
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_register_agent",
+		Description: "Register yourself as an agent, or restart after ending: your agent bead starts in state spawning. " +
+			"Optional: agent (your name; defaults to your client name), role, rig, role_bead, project (slug).",
+	}, h.RegisterAgent)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_agent_status",
+		Description: "Report in as an agent. Every call is a heartbeat; agents that stop heartbeating are marked stuck " +
+			"and flagged. Optional: state (running, working, idle, stuck, done, stopped, dead), hook (the issue ID you " +
+			"are working on; empty clears it), agent. done, stopped and dead end the agent until it registers again.",
+	}, h.AgentStatus)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_list_agents",
+		Description: "List your tenant's agents with their state, last activity, hooked issue and the in-progress " +
+			"issues they hold. Optional: all=true to include agents that have ended.",
+	}, h.ListAgents)
+
+	// --- Templates ---
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_mark_template",
+		Description: "Make an issue and its descendants a template: a reusable breakdown whose titles, descriptions, " +
+			"design, acceptance criteria, notes, assignee, owner and labels may hold {{variables}}. Template issues are " +
+			"never ready. Required: id (the root). Optional: unmark=true to turn the tree back into ordinary issues. " +
+			"Returns the template with the variables it uses.",
+	}, h.MarkTemplate)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "doit_list_templates",
+		Description: "List your tenant's templates (their roots) with the number of issues and the variables each uses.",
+	}, h.ListTemplates)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_instantiate_template",
+		Description: "Deep-copy a template into ordinary open issues in one batch, filling in its {{variables}} and " +
+			"recreating its labels and the blocking dependencies between its issues. Required: id (the template root), " +
+			"variables (every variable the template uses, and no others). Optional: project (slug; default the parent's, " +
+			"else the template's), parent (issue ID to put the copy under). Returns the new issue IDs.",
+	}, h.InstantiateTemplate)
+
+	// --- Gates ---
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_create_gate",
+		Description: "Create a gate: an issue that holds back the issues in blocks until it opens. " +
+			"Required: title, await_type (timer, issue, flag or signal). " +
+			"A timer gate opens after timeout (e.g. 30m, 24h). An issue gate opens when the issue await_id closes; " +
+			"a flag gate when the flag await_id is resolved, or, without await_id, when the human_decision flag it " +
+			"raises on itself is resolved (an approval); a signal gate when doit_signal_gate sends the signal await_id. " +
+			"For those, timeout is optional and raises a gate_timeout flag if the gate is still waiting by then. " +
+			"Optional: description, blocks (issue IDs), priority, project (slug).",
+	}, h.CreateGate)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_signal_gate",
+		Description: "Send a named signal, opening every gate waiting on it so the issues they block become ready. " +
+			"Required: signal. Optional: note, added to the gates' close reason. Returns the gates opened.",
+	}, h.SignalGate)
+
 	// --- Projects ---
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -122,6 +362,26 @@ func RegisterAgentTools(server *mcp.Server, h *Handlers) {
 		Description: "List projects in your tenant.",
 	}, h.ListProjects)
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_ready_policy",
+		Description: "Show or set which dependency types keep a project's issues out of ready work. " +
+			"Gates: blocks (until the target closes), conditional-blocks (until the target closes with a failure " +
+			"reason such as \"failed: ...\"; the issue is the fallback path), waits-for (until the target and all its " +
+			"children close), until (only while the target is still open), parent-child (while an ancestor is " +
+			"blocked, deferred or itself gated). All apply by default. " +
+			"Pass project (slug) alone to show the policy, gates to replace it (an empty list disables every gate), " +
+			"or reset=true to restore the default.",
+	}, h.ReadyPolicy)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_compaction_policy",
+		Description: "Show or set when a project's closed issues are compacted by doit_compact. " +
+			"age (a duration such as 720h) replaces the age doit_compact is given: issues go to level 1 after it " +
+			"and to level 2 after twice it. exclude_labels lists labels whose issues are never compacted. " +
+			"Pass project (slug) alone to show the policy, age or exclude_labels to change just that field " +
+			"(an empty age restores doit_compact's), or reset=true to remove the policy.",
+	}, h.CompactionPolicy)
+
 	// --- Lessons ---
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -134,7 +394,7 @@ func RegisterAgentTools(server *mcp.Server, h *Handlers) {
 		Name: "doit_list_lessons",
 		Description: "List lessons learned, filtered by project, status, expert, component, or severity. " +
 			"Review before starting work to avoid repeating mistakes. " +
-			"Returns {count, has_more, items} envelope. " +
+			"Returns {count, has_more, next_cursor, items} envelope; pass next_cursor as cursor to fetch the next page. " +
 			"Defaults: compact=true, limit=50.",
 	}, h.ListLessons)
 
@@ -172,19 +432,23 @@ func RegisterAgentTools(server *mcp.Server, h *Handlers) {
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "doit_list_flags",
 		Description: "List escalation flags. Filter by project, status (open/acknowledged/resolved), " +
-			"severity, or issue_id. Call at session start to check for unresolved escalations.",
+			"severity, or issue_id. Call at session start to check for unresolved escalations. " +
+			"Returns an array of flags. Set paged=true for a {count, has_more, next_cursor, items} envelope " +
+			"instead; pass next_cursor as cursor to fetch the next page. Paged defaults: limit=50.",
 	}, h.ListFlags)
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "doit_resolve_flag",
 		Description: "Resolve an escalation flag with a resolution message. " +
-			"Records who resolved it and when.",
+			"Records who resolved it and when, and opens any gates waiting on the flag.",
 	}, h.ResolveFlag)
 
 }
 
-// RegisterAdminTools registers admin-only MCP tools (10 tools).
+// RegisterAdminTools registers admin-only MCP tools (13 tools).
 func RegisterAdminTools(server *mcp.Server, h *Handlers) {
+	server.AddReceivingMiddleware(recordActor)
+
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "doit_create_tenant",
 		Description: "Create a new tenant. Requires admin API key. " +
@@ -236,6 +500,36 @@ func RegisterAdminTools(server *mcp.Server, h *Handlers) {
 			"Rejects if projects still exist (delete them first). Accepts tenant slug.",
 	}, h.DeleteTenant)
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_validate_graph",
+		Description: "Check dependency graphs for problems. Requires admin API key. " +
+			"Reports blocking and parent-child cycles (with their paths), orphaned children " +
+			"(hierarchical IDs whose parent link is gone) and dangling references (edges to issues " +
+			"outside the tenant). Pass tenant (slug) to check one tenant; defaults to all.",
+	}, h.ValidateGraph)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_compaction_settings",
+		Description: "Show or change how a tenant's closed issues are summarized by doit_compact. Requires admin API key. " +
+			"Summarizers: heuristic (type, title, first lines and close reason; the default), extractive (the " +
+			"sentences sharing most with the title or recording decisions and outcomes, plus which checklist " +
+			"acceptance criteria were met) and endpoint (an OpenAI-compatible chat completions API at endpoint_url, " +
+			"using endpoint_model, with a bearer token read from the server environment variable endpoint_api_key_env, whose name must start with DOIT_SUMMARIZER_; when it fails the heuristic summary is used " +
+			"and the error reported). " +
+			"level1_budget and level2_budget cap summary length in characters (default 400 and 120). " +
+			"Pass tenant (slug) alone to show the settings, any other field to change just that field, " +
+			"or reset=true to restore the defaults.",
+	}, h.CompactionSettings)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "doit_jobs",
+		Description: "Show a tenant's background jobs (reap_leases, purge_trash, compact, gc_ephemeral, agent_monitor, " +
+			"check_gates) with how often each runs, its last run and result or error, and when it runs next. " +
+			"Requires admin API key. Pass tenant (slug); add job to see its run history (newest first, limit runs, default 20). " +
+			"With job, interval (a duration such as \"6h\", or \"0s\" to turn it off) overrides how often it runs " +
+			"for the tenant and reset=true restores the server default.",
+	}, h.Jobs)
+
 	mcp.AddTool(server, &mcp.Tool{
 		Name: "doit_rotate_admin_key",
 		Description: "Generate a new admin API key and store its hash in the database. " +
@@ -3,7 +3,9 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Actual-Outcomes/doit/internal/model"
 	"github.com/Actual-Outcomes/doit/internal/store"
@@ -57,6 +59,7 @@ const (
 type listResponse struct {
 	Count         int    `json:"count"`
 	HasMore       bool   `json:"has_more,omitempty"`
+	NextCursor    string `json:"next_cursor,omitempty"`
 	Items         any    `json:"items"`
 	AutoCompacted bool   `json:"auto_compacted,omitempty"`
 	Message       string `json:"message,omitempty"`
@@ -64,12 +67,14 @@ type listResponse struct {
 
 // protectedListResult wraps list items in a response envelope with size protection.
 // If the serialized response exceeds maxResponseChars, it auto-compacts using compactFn.
-// Pass compactFn=nil if there is no compact form for this type.
-func protectedListResult(items any, count int, hasMore bool, compactFn func() any) (*mcp.CallToolResult, any, error) {
+// Pass compactFn=nil if there is no compact form for this type, and nextCursor=""
+// if the list cannot be resumed.
+func protectedListResult(items any, count int, hasMore bool, nextCursor string, compactFn func() any) (*mcp.CallToolResult, any, error) {
 	resp := listResponse{
-		Count:   count,
-		HasMore: hasMore,
-		Items:   items,
+		Count:      count,
+		HasMore:    hasMore,
+		NextCursor: nextCursor,
+		Items:      items,
 	}
 	data, _ := json.MarshalIndent(resp, "", "  ")
 	if len(data) > maxResponseChars && compactFn != nil {
@@ -116,6 +121,7 @@ type createIssueArgs struct {
 	Project            string   `json:"project"`
 	Labels             []string `json:"labels"`
 	Ephemeral          bool     `json:"ephemeral"`
+	EstimatedMinutes   *int     `json:"estimated_minutes,omitempty"`
 }
 
 func (h *Handlers) CreateIssue(ctx context.Context, _ *mcp.CallToolRequest, args createIssueArgs) (*mcp.CallToolResult, any, error) {
@@ -168,6 +174,7 @@ func (h *Handlers) CreateIssue(ctx context.Context, _ *mcp.CallToolRequest, args
 		ParentID:           args.ParentID,
 		Labels:             args.Labels,
 		Ephemeral:          args.Ephemeral,
+		EstimatedMinutes:   args.EstimatedMinutes,
 	})
 	if err != nil {
 		return errResult(err)
@@ -199,9 +206,16 @@ type updateIssueArgs struct {
 	Claim       bool    `json:"claim"`
 	Pinned      *bool   `json:"pinned"`
 	Notes       *string `json:"notes"`
+	Estimate    *int    `json:"estimated_minutes,omitempty"` // 0 clears
+	Agent       string  `json:"agent,omitempty"`             // claimant identity for claim=true
+	LeaseSecs   int     `json:"lease_seconds,omitempty"`
+
+	// Optimistic concurrency: reject the update if the issue has changed.
+	ExpectedContentHash *string `json:"expected_content_hash,omitempty"`
+	ExpectedUpdatedAt   *string `json:"expected_updated_at,omitempty"`
 }
 
-func (h *Handlers) UpdateIssue(ctx context.Context, _ *mcp.CallToolRequest, args updateIssueArgs) (*mcp.CallToolResult, any, error) {
+func (h *Handlers) UpdateIssue(ctx context.Context, req *mcp.CallToolRequest, args updateIssueArgs) (*mcp.CallToolResult, any, error) {
 	// Filter out literal "null" strings that arrive from MCP client serialization.
 	// go-sdk/mcp marks all struct fields as required, so clients send null for
 	// fields they don't want to change. The JSON round-trip can turn *string null
@@ -221,6 +235,8 @@ func (h *Handlers) UpdateIssue(ctx context.Context, _ *mcp.CallToolRequest, args
 		Owner:       filterNull(args.Owner),
 		Pinned:      args.Pinned,
 		Notes:       filterNull(args.Notes),
+
+		EstimatedMinutes: args.Estimate,
 	}
 
 	if args.Status != nil && *args.Status != "null" {
@@ -229,19 +245,59 @@ func (h *Handlers) UpdateIssue(ctx context.Context, _ *mcp.CallToolRequest, args
 	}
 
 	if args.Claim {
-		assignee := "agent"
+		ctx = withAgent(ctx, req, args.Agent)
+		assignee := claimant(req, args.Agent)
 		input.Assignee = &assignee
 		s := model.StatusInProgress
 		input.Status = &s
+		lease := h.leaseFor(args.LeaseSecs)
+		input.Lease = &lease
+	}
+
+	if strSet(args.ExpectedContentHash) {
+		input.ExpectedContentHash = args.ExpectedContentHash
+	}
+	if strSet(args.ExpectedUpdatedAt) {
+		t, err := time.Parse(time.RFC3339Nano, *args.ExpectedUpdatedAt)
+		if err != nil {
+			return errResult(fmt.Errorf("invalid expected_updated_at %q: want RFC 3339", *args.ExpectedUpdatedAt))
+		}
+		input.ExpectedUpdatedAt = &t
 	}
 
 	issue, err := h.store.UpdateIssue(ctx, args.ID, input)
 	if err != nil {
+		var conflict *store.ConflictError
+		if errors.As(err, &conflict) {
+			return conflictResult(conflict)
+		}
 		return errResult(err)
 	}
+	if input.Status != nil {
+		if err := h.settle(ctx, issue); err != nil {
+			return errResult(err)
+		}
+	}
 	return jsonResult(issue)
 }
 
+// conflictResult reports a failed optimistic update with the stored issue,
+// so the agent can merge its change and retry against the new version.
+func conflictResult(c *store.ConflictError) (*mcp.CallToolResult, any, error) {
+	data, _ := json.MarshalIndent(map[string]any{
+		"error":    "conflict",
+		"message":  c.Error(),
+		"field":    c.Field,
+		"expected": c.Expected,
+		"actual":   c.Actual,
+		"current":  c.Current,
+	}, "", "  ")
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: string(data)}},
+		IsError: true,
+	}, nil, nil
+}
+
 type listIssuesArgs struct {
 	Status    string  `json:"status"`
 	IssueType string  `json:"issue_type"`
@@ -252,6 +308,8 @@ type listIssuesArgs struct {
 	SortBy    string  `json:"sort_by"`
 	Compact   *bool   `json:"compact,omitempty"`
 	Pinned    bool    `json:"pinned,omitempty"`
+	Search    string  `json:"search,omitempty"`
+	Cursor    string  `json:"cursor,omitempty"`
 }
 
 func (h *Handlers) ListIssues(ctx context.Context, _ *mcp.CallToolRequest, args listIssuesArgs) (*mcp.CallToolResult, any, error) {
@@ -262,6 +320,7 @@ func (h *Handlers) ListIssues(ctx context.Context, _ *mcp.CallToolRequest, args
 	filter := model.IssueFilter{
 		Limit:  limit + 1, // fetch one extra to detect truncation
 		SortBy: args.SortBy,
+		Cursor: args.Cursor,
 	}
 	if args.Status != "" {
 		s := model.Status(args.Status)
@@ -288,6 +347,9 @@ func (h *Handlers) ListIssues(ctx context.Context, _ *mcp.CallToolRequest, args
 		t := true
 		filter.Pinned = &t
 	}
+	if args.Search != "" {
+		filter.Search = &args.Search
+	}
 
 	issues, err := h.store.ListIssues(ctx, filter)
 	if err != nil {
@@ -295,15 +357,17 @@ func (h *Handlers) ListIssues(ctx context.Context, _ *mcp.CallToolRequest, args
 	}
 
 	hasMore := len(issues) > limit
+	var nextCursor string
 	if hasMore {
 		issues = issues[:limit]
+		nextCursor = store.IssueCursor(&issues[limit-1], args.SortBy)
 	}
 
 	if compact {
 		compactItems := model.ToCompactList(issues)
-		return protectedListResult(compactItems, len(compactItems), hasMore, nil)
+		return protectedListResult(compactItems, len(compactItems), hasMore, nextCursor, nil)
 	}
-	return protectedListResult(issues, len(issues), hasMore, func() any {
+	return protectedListResult(issues, len(issues), hasMore, nextCursor, func() any {
 		return model.ToCompactList(issues)
 	})
 }
@@ -316,13 +380,51 @@ func (h *Handlers) DeleteIssue(ctx context.Context, _ *mcp.CallToolRequest, args
 	if err := h.store.DeleteIssue(ctx, args.ID); err != nil {
 		return errResult(err)
 	}
-	return jsonResult(map[string]string{"deleted": args.ID})
+	return jsonResult(map[string]string{
+		"deleted": args.ID,
+		"message": "moved to the trash; restore it with doit_restore_issue",
+	})
+}
+
+type restoreIssueArgs struct {
+	ID string `json:"id"`
+}
+
+func (h *Handlers) RestoreIssue(ctx context.Context, _ *mcp.CallToolRequest, args restoreIssueArgs) (*mcp.CallToolResult, any, error) {
+	issue, err := h.store.RestoreIssue(ctx, args.ID)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(issue)
+}
+
+type listTrashArgs struct {
+	Limit int `json:"limit,omitempty"`
+}
+
+func (h *Handlers) ListTrash(ctx context.Context, _ *mcp.CallToolRequest, args listTrashArgs) (*mcp.CallToolResult, any, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	issues, err := h.store.ListTrash(ctx)
+	if err != nil {
+		return errResult(err)
+	}
+	hasMore := len(issues) > limit
+	if hasMore {
+		issues = issues[:limit]
+	}
+	return protectedListResult(issues, len(issues), hasMore, "", func() any {
+		return model.ToCompactList(issues)
+	})
 }
 
 type readyArgs struct {
 	Limit   int     `json:"limit"`
 	Project *string `json:"project"`
 	Compact *bool   `json:"compact,omitempty"`
+	Cursor  string  `json:"cursor,omitempty"`
 }
 
 func (h *Handlers) Ready(ctx context.Context, _ *mcp.CallToolRequest, args readyArgs) (*mcp.CallToolResult, any, error) {
@@ -330,7 +432,7 @@ func (h *Handlers) Ready(ctx context.Context, _ *mcp.CallToolRequest, args ready
 	hasProject := strSet(args.Project)
 	limit := applyListDefaults(args.Limit, compact, hasProject)
 
-	filter := model.IssueFilter{Limit: limit + 1}
+	filter := model.IssueFilter{Limit: limit + 1, Cursor: args.Cursor}
 	if hasProject {
 		resolved, err := resolveProjectSlug(ctx, h.store, *args.Project)
 		if err != nil {
@@ -344,19 +446,104 @@ func (h *Handlers) Ready(ctx context.Context, _ *mcp.CallToolRequest, args ready
 	}
 
 	hasMore := len(issues) > limit
+	var nextCursor string
 	if hasMore {
 		issues = issues[:limit]
+		nextCursor = store.IssueCursor(&issues[limit-1], store.ReadySortBy)
 	}
 
 	if compact {
 		compactItems := model.ToCompactList(issues)
-		return protectedListResult(compactItems, len(compactItems), hasMore, nil)
+		return protectedListResult(compactItems, len(compactItems), hasMore, nextCursor, nil)
 	}
-	return protectedListResult(issues, len(issues), hasMore, func() any {
+	return protectedListResult(issues, len(issues), hasMore, nextCursor, func() any {
 		return model.ToCompactList(issues)
 	})
 }
 
+type explainReadyArgs struct {
+	ID string `json:"id"`
+}
+
+func (h *Handlers) ExplainReady(ctx context.Context, _ *mcp.CallToolRequest, args explainReadyArgs) (*mcp.CallToolResult, any, error) {
+	if args.ID == "" {
+		return errResult(fmt.Errorf("id is required"))
+	}
+	exp, err := store.ExplainReady(ctx, h.store, args.ID)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(exp)
+}
+
+// claimant identifies who is claiming work: the explicit agent argument,
+// else the name the MCP client gave when it connected, else "agent".
+func claimant(req *mcp.CallToolRequest, agent string) string {
+	if agent != "" && agent != "null" {
+		return agent
+	}
+	if req != nil && req.Session != nil {
+		if p := req.Session.InitializeParams(); p != nil && p.ClientInfo != nil && p.ClientInfo.Name != "" {
+			return p.ClientInfo.Name
+		}
+	}
+	return "agent"
+}
+
+type claimNextArgs struct {
+	Agent     string   `json:"agent,omitempty"`
+	Project   *string  `json:"project,omitempty"`
+	IssueType string   `json:"issue_type,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	LeaseSecs int      `json:"lease_seconds,omitempty"`
+}
+
+func (h *Handlers) ClaimNext(ctx context.Context, req *mcp.CallToolRequest, args claimNextArgs) (*mcp.CallToolResult, any, error) {
+	ctx = withAgent(ctx, req, args.Agent)
+	input := store.ClaimNextReadyInput{
+		Claimant: claimant(req, args.Agent),
+		Labels:   args.Labels,
+		Lease:    h.leaseFor(args.LeaseSecs),
+	}
+	if strSet(args.Project) {
+		resolved, err := resolveProjectSlug(ctx, h.store, *args.Project)
+		if err != nil {
+			return errResult(err)
+		}
+		input.ProjectID = &resolved
+	}
+	if args.IssueType != "" && args.IssueType != "null" {
+		t := model.IssueType(args.IssueType)
+		input.IssueType = &t
+	}
+
+	issue, err := h.store.ClaimNextReady(ctx, input)
+	if err != nil {
+		return errResult(err)
+	}
+	if issue == nil {
+		return jsonResult(map[string]any{"claimed": false, "message": "no ready issues match"})
+	}
+	if _, err := h.syncMolecule(ctx, issue.ID); err != nil {
+		return errResult(err)
+	}
+	return jsonResult(issue)
+}
+
+type heartbeatArgs struct {
+	ID        string `json:"id"`
+	Agent     string `json:"agent,omitempty"`
+	LeaseSecs int    `json:"lease_seconds,omitempty"`
+}
+
+func (h *Handlers) Heartbeat(ctx context.Context, req *mcp.CallToolRequest, args heartbeatArgs) (*mcp.CallToolResult, any, error) {
+	issue, err := h.store.RenewLease(ctx, args.ID, claimant(req, args.Agent), h.leaseFor(args.LeaseSecs))
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(issue)
+}
+
 type addDepArgs struct {
 	IssueID     string `json:"issue_id"`
 	DependsOnID string `json:"depends_on_id"`
@@ -421,6 +608,30 @@ func (h *Handlers) DependencyTree(ctx context.Context, _ *mcp.CallToolRequest, a
 	return jsonResult(nodes)
 }
 
+type criticalPathArgs struct {
+	ID    string `json:"id"`
+	Start string `json:"start,omitempty"` // RFC 3339; now when empty
+}
+
+func (h *Handlers) CriticalPath(ctx context.Context, _ *mcp.CallToolRequest, args criticalPathArgs) (*mcp.CallToolResult, any, error) {
+	if args.ID == "" {
+		return errResult(fmt.Errorf("id is required"))
+	}
+	start := time.Now().UTC()
+	if args.Start != "" {
+		t, err := time.Parse(time.RFC3339, args.Start)
+		if err != nil {
+			return errResult(fmt.Errorf("invalid start (want RFC 3339): %w", err))
+		}
+		start = t.UTC()
+	}
+	sched, err := store.CriticalPath(ctx, h.store, args.ID, start)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(sched)
+}
+
 type addCommentArgs struct {
 	IssueID string `json:"issue_id"`
 	Author  string `json:"author"`
@@ -444,7 +655,90 @@ func (h *Handlers) ListComments(ctx context.Context, _ *mcp.CallToolRequest, arg
 	if err != nil {
 		return errResult(err)
 	}
-	return protectedListResult(comments, len(comments), false, nil)
+	return protectedListResult(comments, len(comments), false, "", nil)
+}
+
+type listEventsArgs struct {
+	IssueID   string `json:"issue_id"`
+	EventType string `json:"event_type,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+}
+
+func (h *Handlers) ListEvents(ctx context.Context, _ *mcp.CallToolRequest, args listEventsArgs) (*mcp.CallToolResult, any, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	filter := args.EventType != "" && args.EventType != "null"
+	fetch := limit + 1
+	if filter {
+		fetch = 0
+	}
+	events, err := h.store.ListEvents(ctx, args.IssueID, fetch)
+	if err != nil {
+		return errResult(err)
+	}
+	if filter {
+		matched := []model.Event{}
+		for _, e := range events {
+			if string(e.EventType) == args.EventType {
+				matched = append(matched, e)
+			}
+		}
+		events = matched
+	}
+	hasMore := len(events) > limit
+	if hasMore {
+		events = events[:limit]
+	}
+	return protectedListResult(events, len(events), hasMore, "", nil)
+}
+
+type issueAtArgs struct {
+	ID string `json:"id"`
+	At string `json:"at"` // RFC 3339 or YYYY-MM-DD
+}
+
+func (h *Handlers) IssueAt(ctx context.Context, _ *mcp.CallToolRequest, args issueAtArgs) (*mcp.CallToolResult, any, error) {
+	if args.ID == "" || args.At == "" {
+		return errResult(fmt.Errorf("id and at are required"))
+	}
+	at, err := model.ParseHistoryTime(args.At)
+	if err != nil {
+		return errResult(err)
+	}
+	version, err := store.IssueAt(ctx, h.store, args.ID, at)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(version)
+}
+
+type diffIssueArgs struct {
+	ID   string `json:"id"`
+	From string `json:"from"`         // RFC 3339 or YYYY-MM-DD
+	To   string `json:"to,omitempty"` // now when empty
+}
+
+func (h *Handlers) DiffIssue(ctx context.Context, _ *mcp.CallToolRequest, args diffIssueArgs) (*mcp.CallToolResult, any, error) {
+	if args.ID == "" || args.From == "" {
+		return errResult(fmt.Errorf("id and from are required"))
+	}
+	from, err := model.ParseHistoryTime(args.From)
+	if err != nil {
+		return errResult(err)
+	}
+	to := time.Now().UTC()
+	if args.To != "" {
+		if to, err = model.ParseHistoryTime(args.To); err != nil {
+			return errResult(err)
+		}
+	}
+	diff, err := store.DiffIssue(ctx, h.store, args.ID, from, to)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(diff)
 }
 
 type labelArgs struct {
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Actual-Outcomes/doit/internal/agents"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type registerAgentArgs struct {
+	Agent    string `json:"agent,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Rig      string `json:"rig,omitempty"`
+	RoleBead string `json:"role_bead,omitempty"`
+	Project  string `json:"project,omitempty"`
+}
+
+// RegisterAgent registers the calling agent, or restarts it.
+func (h *Handlers) RegisterAgent(ctx context.Context, req *mcp.CallToolRequest, args registerAgentArgs) (*mcp.CallToolResult, any, error) {
+	ctx = withAgent(ctx, req, args.Agent)
+	input := agents.RegisterInput{
+		Name:     claimant(req, args.Agent),
+		Role:     args.Role,
+		Rig:      args.Rig,
+		RoleBead: args.RoleBead,
+	}
+	if args.Project != "" {
+		projectID, err := resolveProjectSlug(ctx, h.store, args.Project)
+		if err != nil {
+			return errResult(err)
+		}
+		input.ProjectID = projectID
+	}
+	a, err := agents.New(h.store).Register(ctx, input)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(a)
+}
+
+type agentStatusArgs struct {
+	Agent string  `json:"agent,omitempty"`
+	State *string `json:"state,omitempty"`
+	Hook  *string `json:"hook,omitempty"` // "" clears it
+}
+
+// AgentStatus records that the calling agent is alive, and its new state
+// and hook if given.
+func (h *Handlers) AgentStatus(ctx context.Context, req *mcp.CallToolRequest, args agentStatusArgs) (*mcp.CallToolResult, any, error) {
+	ctx = withAgent(ctx, req, args.Agent)
+	input := agents.StatusInput{Name: claimant(req, args.Agent)}
+	if strSet(args.State) {
+		s := model.AgentState(*args.State)
+		input.State = &s
+	}
+	if args.Hook != nil && *args.Hook != "null" {
+		input.Hook = args.Hook
+	}
+	a, err := agents.New(h.store).SetStatus(ctx, input)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(a)
+}
+
+type listAgentsArgs struct {
+	All bool `json:"all,omitempty"` // include agents that have ended
+}
+
+// ListAgents lists the tenant's agents with the work they hold.
+func (h *Handlers) ListAgents(ctx context.Context, _ *mcp.CallToolRequest, args listAgentsArgs) (*mcp.CallToolResult, any, error) {
+	list, err := agents.New(h.store).List(ctx, args.All)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(map[string]any{"count": len(list), "agents": list})
+}
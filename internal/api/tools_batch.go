@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type batchArgs struct {
+	Ops []batchOpArgs `json:"ops"`
+}
+
+// batchOpArgs is one doit_batch operation. Op selects which of the other
+// fields apply; see the doit_batch description in register.go.
+type batchOpArgs struct {
+	Op  string `json:"op"`            // create, update, dependency, label, comment
+	Ref string `json:"ref,omitempty"` // create: name later ops use as "$ref"
+
+	// create and update
+	ID          string   `json:"id,omitempty"` // update target
+	Title       *string  `json:"title,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Notes       *string  `json:"notes,omitempty"`
+	Status      *string  `json:"status,omitempty"`
+	Priority    *int     `json:"priority,omitempty"`
+	IssueType   string   `json:"issue_type,omitempty"`
+	Assignee    *string  `json:"assignee,omitempty"`
+	Owner       *string  `json:"owner,omitempty"`
+	ParentID    string   `json:"parent_id,omitempty"`
+	Project     string   `json:"project,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Pinned      *bool    `json:"pinned,omitempty"`
+	Estimate    *int     `json:"estimated_minutes,omitempty"`
+
+	ExpectedContentHash *string `json:"expected_content_hash,omitempty"`
+	ExpectedUpdatedAt   *string `json:"expected_updated_at,omitempty"`
+
+	// dependency, label and comment
+	IssueID     string `json:"issue_id,omitempty"`
+	DependsOnID string `json:"depends_on_id,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Label       string `json:"label,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Text        string `json:"text,omitempty"`
+}
+
+func (h *Handlers) Batch(ctx context.Context, _ *mcp.CallToolRequest, args batchArgs) (*mcp.CallToolResult, any, error) {
+	projects := make(map[string]string) // slug → resolved ID
+	ops := make([]store.BatchOp, 0, len(args.Ops))
+	for i, a := range args.Ops {
+		op, err := a.toStoreOp(ctx, h.store, projects)
+		if err != nil {
+			return errResult(fmt.Errorf("ops[%d]: %w", i, err))
+		}
+		ops = append(ops, op)
+	}
+
+	results, err := h.store.ApplyBatch(ctx, ops)
+	if err != nil {
+		return errResult(fmt.Errorf("batch rolled back, nothing was applied: %w", err))
+	}
+	for i, r := range results {
+		if ops[i].Update == nil || ops[i].Update.Input.Status == nil {
+			continue
+		}
+		if err := h.settle(ctx, r.Issue); err != nil {
+			return errResult(err)
+		}
+	}
+	return jsonResult(map[string]any{
+		"applied": len(results),
+		"results": results,
+	})
+}
+
+func (a batchOpArgs) toStoreOp(ctx context.Context, s store.Store, projects map[string]string) (store.BatchOp, error) {
+	op := store.BatchOp{Ref: a.Ref}
+
+	switch a.Op {
+	case store.BatchOpCreate:
+		if !strSet(a.Title) {
+			return op, fmt.Errorf("create: title is required")
+		}
+		input := store.CreateIssueInput{
+			Title:            *a.Title,
+			Status:           model.StatusOpen,
+			IssueType:        model.IssueType(a.IssueType),
+			ParentID:         a.ParentID,
+			Labels:           a.Labels,
+			EstimatedMinutes: a.Estimate,
+		}
+		if strSet(a.Description) {
+			input.Description = *a.Description
+		}
+		if strSet(a.Notes) {
+			input.Notes = *a.Notes
+		}
+		if a.Priority != nil {
+			input.Priority = *a.Priority
+		}
+		if strSet(a.Assignee) {
+			input.Assignee = *a.Assignee
+		}
+		if strSet(a.Owner) {
+			input.Owner = *a.Owner
+		}
+		input.CreatedBy = input.Owner
+		if input.CreatedBy == "" {
+			input.CreatedBy = input.Assignee
+		}
+		if input.CreatedBy == "" {
+			input.CreatedBy = "system"
+		}
+		if a.Project != "" {
+			id, ok := projects[a.Project]
+			if !ok {
+				var err error
+				if id, err = resolveProjectSlug(ctx, s, a.Project); err != nil {
+					return op, err
+				}
+				projects[a.Project] = id
+			}
+			input.ProjectID = id
+		}
+		op.Create = &input
+
+	case store.BatchOpUpdate:
+		// As in UpdateIssue, a literal "null" means "leave unchanged".
+		filterNull := func(s *string) *string {
+			if s != nil && *s == "null" {
+				return nil
+			}
+			return s
+		}
+		input := store.UpdateIssueInput{
+			Title:       filterNull(a.Title),
+			Description: filterNull(a.Description),
+			Notes:       filterNull(a.Notes),
+			Priority:    a.Priority,
+			Assignee:    filterNull(a.Assignee),
+			Owner:       filterNull(a.Owner),
+			Pinned:      a.Pinned,
+
+			EstimatedMinutes: a.Estimate,
+		}
+		if strSet(a.Status) {
+			st := model.Status(*a.Status)
+			input.Status = &st
+		}
+		if strSet(a.ExpectedContentHash) {
+			input.ExpectedContentHash = a.ExpectedContentHash
+		}
+		if strSet(a.ExpectedUpdatedAt) {
+			t, err := time.Parse(time.RFC3339Nano, *a.ExpectedUpdatedAt)
+			if err != nil {
+				return op, fmt.Errorf("invalid expected_updated_at %q: want RFC 3339", *a.ExpectedUpdatedAt)
+			}
+			input.ExpectedUpdatedAt = &t
+		}
+		op.Update = &store.BatchUpdate{ID: a.ID, Input: input}
+
+	case store.BatchOpDependency:
+		op.Dependency = &store.AddDependencyInput{
+			IssueID:     a.IssueID,
+			DependsOnID: a.DependsOnID,
+			Type:        model.DependencyType(a.Type),
+		}
+
+	case store.BatchOpLabel:
+		op.Label = &store.BatchLabel{IssueID: a.IssueID, Label: a.Label}
+
+	case store.BatchOpComment:
+		op.Comment = &store.BatchComment{IssueID: a.IssueID, Author: a.Author, Text: a.Text}
+
+	default:
+		return op, fmt.Errorf("unknown op %q: want create, update, dependency, label or comment", a.Op)
+	}
+	return op, nil
+}
@@ -5,12 +5,18 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Actual-Outcomes/doit/internal/auth"
 	"github.com/Actual-Outcomes/doit/internal/compact"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 type compactArgs struct {
-	Age string `json:"age"`
+	Age           string   `json:"age"`
+	DryRun        bool     `json:"dry_run,omitempty"`
+	Project       string   `json:"project,omitempty"` // slug
+	ExcludeLabels []string `json:"exclude_labels,omitempty"`
 }
 
 func (h *Handlers) Compact(ctx context.Context, _ *mcp.CallToolRequest, args compactArgs) (*mcp.CallToolResult, any, error) {
@@ -24,11 +30,137 @@ func (h *Handlers) Compact(ctx context.Context, _ *mcp.CallToolRequest, args com
 		return errResult(fmt.Errorf("invalid age duration %q: %w", age, err))
 	}
 
-	compactor := compact.New(h.store)
-	results, err := compactor.CompactOld(ctx, threshold)
+	opts := compact.Options{Age: threshold, DryRun: args.DryRun, ExcludeLabels: args.ExcludeLabels}
+	if args.Project != "" {
+		if opts.ProjectID, err = resolveProjectSlug(ctx, h.store, args.Project); err != nil {
+			return errResult(err)
+		}
+	}
+	report, err := compact.New(h.store).Run(ctx, opts)
 	if err != nil {
 		return errResult(err)
 	}
 
-	return jsonResult(results)
+	return jsonResult(report)
+}
+
+type uncompactArgs struct {
+	ID    string `json:"id"`
+	Level int    `json:"level,omitempty"` // 0, full detail, by default
+}
+
+func (h *Handlers) Uncompact(ctx context.Context, _ *mcp.CallToolRequest, args uncompactArgs) (*mcp.CallToolResult, any, error) {
+	if args.ID == "" {
+		return errResult(fmt.Errorf("id is required"))
+	}
+	issue, err := store.UncompactIssue(ctx, h.store, args.ID, args.Level)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(issue)
+}
+
+type listCompactionSnapshotsArgs struct {
+	IssueID string `json:"issue_id"`
+}
+
+func (h *Handlers) ListCompactionSnapshots(ctx context.Context, _ *mcp.CallToolRequest, args listCompactionSnapshotsArgs) (*mcp.CallToolResult, any, error) {
+	snaps, err := h.store.GetCompactionSnapshots(ctx, args.IssueID)
+	if err != nil {
+		return errResult(err)
+	}
+	if snaps == nil {
+		snaps = []model.CompactionSnapshot{}
+	}
+	return jsonResult(snaps)
+}
+
+type compactionSettingsArgs struct {
+	Tenant         string  `json:"tenant"` // slug
+	Summarizer     *string `json:"summarizer,omitempty"`
+	Level1Budget   *int    `json:"level1_budget,omitempty"`
+	Level2Budget   *int    `json:"level2_budget,omitempty"`
+	EndpointURL    *string `json:"endpoint_url,omitempty"`
+	EndpointModel  *string `json:"endpoint_model,omitempty"`
+	EndpointKeyEnv *string `json:"endpoint_api_key_env,omitempty"`
+	Reset          bool    `json:"reset,omitempty"`
+}
+
+// CompactionSettings shows a tenant's compaction settings, or changes the
+// fields given. Unset fields keep their current values.
+func (h *Handlers) CompactionSettings(ctx context.Context, _ *mcp.CallToolRequest, args compactionSettingsArgs) (*mcp.CallToolResult, any, error) {
+	if args.Tenant == "" {
+		return errResult(fmt.Errorf("tenant is required"))
+	}
+	tenants, err := h.store.ListTenants(ctx)
+	if err != nil {
+		return errResult(err)
+	}
+	found := false
+	for _, t := range tenants {
+		if t.Slug == args.Tenant {
+			ctx, found = auth.WithTenant(ctx, t.ID), true
+			break
+		}
+	}
+	if !found {
+		return errResult(fmt.Errorf("tenant %q not found", args.Tenant))
+	}
+
+	changes := args.Summarizer != nil || args.Level1Budget != nil || args.Level2Budget != nil ||
+		args.EndpointURL != nil || args.EndpointModel != nil || args.EndpointKeyEnv != nil
+	if args.Reset {
+		if changes {
+			return errResult(fmt.Errorf("pass settings or reset, not both"))
+		}
+		settings, err := h.store.SetCompactionSettings(ctx, nil)
+		if err != nil {
+			return errResult(err)
+		}
+		return jsonResult(settings)
+	}
+
+	settings, err := h.store.GetCompactionSettings(ctx)
+	if err != nil {
+		return errResult(err)
+	}
+	if !changes {
+		return jsonResult(settings)
+	}
+	if args.Summarizer != nil {
+		settings.Summarizer = *args.Summarizer
+	}
+	if args.Level1Budget != nil {
+		settings.Budgets.Level1 = *args.Level1Budget
+	}
+	if args.Level2Budget != nil {
+		settings.Budgets.Level2 = *args.Level2Budget
+	}
+	if args.EndpointURL != nil || args.EndpointModel != nil || args.EndpointKeyEnv != nil {
+		ep := model.CompactionEndpoint{}
+		if settings.Endpoint != nil {
+			ep = *settings.Endpoint
+		}
+		for _, f := range []struct {
+			dst *string
+			val *string
+		}{
+			{&ep.URL, args.EndpointURL},
+			{&ep.Model, args.EndpointModel},
+			{&ep.APIKeyEnv, args.EndpointKeyEnv},
+		} {
+			if f.val != nil {
+				*f.dst = *f.val
+			}
+		}
+		settings.Endpoint = &ep
+		if ep == (model.CompactionEndpoint{}) {
+			settings.Endpoint = nil
+		}
+	}
+	settings, err = h.store.SetCompactionSettings(ctx, settings)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(settings)
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/Actual-Outcomes/doit/internal/gates"
 	"github.com/Actual-Outcomes/doit/internal/model"
 	"github.com/Actual-Outcomes/doit/internal/store"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -55,6 +56,8 @@ type listFlagsArgs struct {
 	Severity *int    `json:"severity,omitempty"`
 	IssueID  *string `json:"issue_id,omitempty"`
 	Limit    *int    `json:"limit,omitempty"`
+	Paged    bool    `json:"paged,omitempty"`
+	Cursor   *string `json:"cursor,omitempty"`
 }
 
 func (h *Handlers) ListFlags(ctx context.Context, _ *mcp.CallToolRequest, args listFlagsArgs) (*mcp.CallToolResult, any, error) {
@@ -77,15 +80,40 @@ func (h *Handlers) ListFlags(ctx context.Context, _ *mcp.CallToolRequest, args l
 	if strSet(args.IssueID) {
 		filter.IssueID = args.IssueID
 	}
-	if args.Limit != nil {
-		filter.Limit = *args.Limit
+	// Without paged or a cursor the result stays the bare array it has
+	// always been, holding every match unless limit is given.
+	if !args.Paged && !strSet(args.Cursor) {
+		if args.Limit != nil {
+			filter.Limit = *args.Limit
+		}
+		flags, err := h.store.ListFlags(ctx, filter)
+		if err != nil {
+			return errResult(fmt.Errorf("listing flags: %w", err))
+		}
+		return jsonResult(flags)
+	}
+	if strSet(args.Cursor) {
+		filter.Cursor = *args.Cursor
 	}
 
+	limit := defaultLimit
+	if args.Limit != nil && *args.Limit > 0 {
+		limit = *args.Limit
+	}
+	filter.Limit = limit + 1 // fetch one extra to detect truncation
+
 	flags, err := h.store.ListFlags(ctx, filter)
 	if err != nil {
 		return errResult(fmt.Errorf("listing flags: %w", err))
 	}
-	return jsonResult(flags)
+
+	hasMore := len(flags) > limit
+	var nextCursor string
+	if hasMore {
+		flags = flags[:limit]
+		nextCursor = store.FlagCursor(&flags[limit-1])
+	}
+	return protectedListResult(flags, len(flags), hasMore, nextCursor, nil)
 }
 
 type resolveFlagArgs struct {
@@ -104,5 +132,8 @@ func (h *Handlers) ResolveFlag(ctx context.Context, _ *mcp.CallToolRequest, args
 	if err != nil {
 		return errResult(fmt.Errorf("resolving flag: %w", err))
 	}
+	if _, err := gates.New(h.store).FlagResolved(ctx, flag); err != nil {
+		return errResult(fmt.Errorf("flag %s was resolved, but opening the gates waiting on it failed: %w", flag.ID, err))
+	}
 	return jsonResult(flag)
 }
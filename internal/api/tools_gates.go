@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/gates"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type createGateArgs struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	AwaitType   string   `json:"await_type"`
+	AwaitID     string   `json:"await_id,omitempty"`
+	Timeout     string   `json:"timeout,omitempty"` // Go duration, e.g. "30m"
+	Blocks      []string `json:"blocks,omitempty"`
+	Priority    int      `json:"priority,omitempty"`
+	Project     string   `json:"project,omitempty"`
+}
+
+// CreateGate creates a gate blocking the given issues.
+func (h *Handlers) CreateGate(ctx context.Context, req *mcp.CallToolRequest, args createGateArgs) (*mcp.CallToolResult, any, error) {
+	input := gates.CreateInput{
+		Title:       args.Title,
+		Description: args.Description,
+		AwaitType:   args.AwaitType,
+		AwaitID:     args.AwaitID,
+		Blocks:      args.Blocks,
+		Priority:    args.Priority,
+		CreatedBy:   claimant(req, ""),
+	}
+	if args.Timeout != "" {
+		d, err := time.ParseDuration(args.Timeout)
+		if err != nil {
+			return errResult(fmt.Errorf("invalid timeout %q: want a duration like 30m or 24h", args.Timeout))
+		}
+		input.Timeout = d
+	}
+	if args.Project != "" {
+		projectID, err := resolveProjectSlug(ctx, h.store, args.Project)
+		if err != nil {
+			return errResult(err)
+		}
+		input.ProjectID = projectID
+	}
+	gate, err := gates.New(h.store).Create(ctx, input)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(gate)
+}
+
+type signalGateArgs struct {
+	Signal string `json:"signal"`
+	Note   string `json:"note,omitempty"`
+}
+
+// SignalGate sends a named signal, opening the gates waiting on it.
+func (h *Handlers) SignalGate(ctx context.Context, req *mcp.CallToolRequest, args signalGateArgs) (*mcp.CallToolResult, any, error) {
+	ctx = withAgent(ctx, req, "")
+	resolved, err := gates.New(h.store).Signal(ctx, args.Signal, args.Note)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(map[string]any{"signal": args.Signal, "count": len(resolved), "resolved": resolved})
+}
+
+// releaseGates opens the gates waiting on the issues closed, which a tool
+// outside the gate ones just closed, rather than leaving them to the next
+// check_gates run.
+func (h *Handlers) releaseGates(ctx context.Context, closed ...string) error {
+	keeper := gates.New(h.store)
+	for _, id := range closed {
+		if _, err := keeper.IssueClosed(ctx, id); err != nil {
+			return fmt.Errorf("%s was closed, but opening the gates waiting on it failed: %w", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/gc"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type gcArgs struct {
+	DryRun bool  `json:"dry_run,omitempty"`
+	Report *bool `json:"report,omitempty"` // overrides the policy's report setting
+}
+
+// GC deletes the tenant's ephemeral issues and wisps past their retention.
+func (h *Handlers) GC(ctx context.Context, _ *mcp.CallToolRequest, args gcArgs) (*mcp.CallToolResult, any, error) {
+	report, err := gc.New(h.store).Run(ctx, time.Now().UTC(), gc.Options{DryRun: args.DryRun, Report: args.Report})
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(report)
+}
+
+type gcPolicyArgs struct {
+	Retention map[string]string `json:"retention,omitempty"` // wisp type to duration
+	Ephemeral *string           `json:"ephemeral,omitempty"`
+	Report    *bool             `json:"report,omitempty"`
+	Reset     bool              `json:"reset,omitempty"`
+}
+
+// GCPolicy shows the tenant's gc policy, or changes the fields given.
+// Retention entries are merged into the current ones.
+func (h *Handlers) GCPolicy(ctx context.Context, _ *mcp.CallToolRequest, args gcPolicyArgs) (*mcp.CallToolResult, any, error) {
+	changes := args.Retention != nil || args.Ephemeral != nil || args.Report != nil
+	var (
+		policy *model.GCPolicy
+		err    error
+	)
+	switch {
+	case args.Reset && changes:
+		return errResult(fmt.Errorf("pass retention, ephemeral or report, or reset, not both"))
+	case args.Reset:
+		policy, err = h.store.SetGCPolicy(ctx, nil)
+	default:
+		policy, err = h.store.GetGCPolicy(ctx)
+		if err != nil || !changes {
+			break
+		}
+		for t, v := range args.Retention {
+			policy.Retention[model.WispType(t)] = v
+		}
+		if args.Ephemeral != nil {
+			policy.Ephemeral = *args.Ephemeral
+		}
+		if args.Report != nil {
+			policy.Report = *args.Report
+		}
+		policy, err = h.store.SetGCPolicy(ctx, policy)
+	}
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(policy)
+}
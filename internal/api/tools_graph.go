@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type validateGraphArgs struct {
+	Tenant string `json:"tenant,omitempty"` // slug; all tenants when empty
+}
+
+// tenantGraphReport labels a GraphReport with the tenant's slug.
+type tenantGraphReport struct {
+	Tenant string `json:"tenant"`
+	*model.GraphReport
+}
+
+func (h *Handlers) ValidateGraph(ctx context.Context, _ *mcp.CallToolRequest, args validateGraphArgs) (*mcp.CallToolResult, any, error) {
+	tenants, err := h.store.ListTenants(ctx)
+	if err != nil {
+		return errResult(err)
+	}
+	if args.Tenant != "" {
+		var match []model.Tenant
+		for _, t := range tenants {
+			if t.Slug == args.Tenant {
+				match = append(match, t)
+			}
+		}
+		if len(match) == 0 {
+			return errResult(fmt.Errorf("tenant %q not found", args.Tenant))
+		}
+		tenants = match
+	}
+
+	valid := true
+	reports := make([]tenantGraphReport, 0, len(tenants))
+	for _, t := range tenants {
+		report, err := h.store.ValidateGraph(auth.WithTenant(ctx, t.ID))
+		if err != nil {
+			return errResult(fmt.Errorf("validating tenant %s: %w", t.Slug, err))
+		}
+		valid = valid && report.Valid
+		reports = append(reports, tenantGraphReport{Tenant: t.Slug, GraphReport: report})
+	}
+	return jsonResult(map[string]any{
+		"valid":   valid,
+		"tenants": reports,
+	})
+}
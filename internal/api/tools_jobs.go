@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/jobs"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type jobsArgs struct {
+	Tenant   string  `json:"tenant"`             // slug
+	Job      string  `json:"job,omitempty"`      // show this job's history
+	Limit    int     `json:"limit,omitempty"`    // runs of history to show
+	Interval *string `json:"interval,omitempty"` // Go duration; "0s" turns the job off
+	Reset    bool    `json:"reset,omitempty"`
+}
+
+// jobsResult is every job's status for a tenant, plus one job's history
+// when asked for.
+type jobsResult struct {
+	Tenant  string         `json:"tenant"`
+	Jobs    []jobs.Status  `json:"jobs"`
+	History []model.JobRun `json:"history,omitempty"`
+}
+
+// Jobs shows a tenant's background jobs and their last results, or sets
+// or resets how often one runs for the tenant.
+func (h *Handlers) Jobs(ctx context.Context, _ *mcp.CallToolRequest, args jobsArgs) (*mcp.CallToolResult, any, error) {
+	if h.jobs == nil {
+		return errResult(fmt.Errorf("background jobs are not enabled"))
+	}
+	if args.Tenant == "" {
+		return errResult(fmt.Errorf("tenant is required"))
+	}
+	tenants, err := h.store.ListTenants(ctx)
+	if err != nil {
+		return errResult(err)
+	}
+	found := false
+	for _, t := range tenants {
+		if t.Slug == args.Tenant {
+			ctx, found = auth.WithTenant(ctx, t.ID), true
+			break
+		}
+	}
+	if !found {
+		return errResult(fmt.Errorf("tenant %q not found", args.Tenant))
+	}
+
+	if args.Job != "" {
+		if _, ok := h.jobs.Job(args.Job); !ok {
+			return errResult(fmt.Errorf("unknown job %q", args.Job))
+		}
+	}
+	if args.Interval != nil || args.Reset {
+		if args.Job == "" {
+			return errResult(fmt.Errorf("job is required to change an interval"))
+		}
+		if args.Interval != nil && args.Reset {
+			return errResult(fmt.Errorf("pass interval or reset, not both"))
+		}
+		var interval *time.Duration
+		if args.Interval != nil {
+			d, err := time.ParseDuration(*args.Interval)
+			if err != nil {
+				return errResult(fmt.Errorf("invalid interval %q: %w", *args.Interval, err))
+			}
+			interval = &d
+		}
+		if err := h.store.SetJobInterval(ctx, args.Job, interval); err != nil {
+			return errResult(err)
+		}
+	}
+
+	statuses, err := h.jobs.Statuses(ctx)
+	if err != nil {
+		return errResult(err)
+	}
+	result := jobsResult{Tenant: args.Tenant, Jobs: statuses}
+	if args.Job != "" {
+		limit := args.Limit
+		if limit <= 0 {
+			limit = 20
+		}
+		if result.History, err = h.store.ListJobRuns(ctx, args.Job, limit); err != nil {
+			return errResult(err)
+		}
+	}
+	return jsonResult(result)
+}
@@ -64,6 +64,7 @@ type listLessonsArgs struct {
 	Severity  *int    `json:"severity,omitempty"`
 	Limit     *int    `json:"limit,omitempty"`
 	Compact   *bool   `json:"compact,omitempty"`
+	Cursor    *string `json:"cursor,omitempty"`
 }
 
 func (h *Handlers) ListLessons(ctx context.Context, _ *mcp.CallToolRequest, args listLessonsArgs) (*mcp.CallToolResult, any, error) {
@@ -100,6 +101,9 @@ func (h *Handlers) ListLessons(ctx context.Context, _ *mcp.CallToolRequest, args
 	if args.Severity != nil {
 		filter.Severity = args.Severity
 	}
+	if strSet(args.Cursor) {
+		filter.Cursor = *args.Cursor
+	}
 
 	lessons, err := h.store.ListLessons(ctx, filter)
 	if err != nil {
@@ -107,15 +111,17 @@ func (h *Handlers) ListLessons(ctx context.Context, _ *mcp.CallToolRequest, args
 	}
 
 	hasMore := len(lessons) > limit
+	var nextCursor string
 	if hasMore {
 		lessons = lessons[:limit]
+		nextCursor = store.LessonCursor(&lessons[limit-1])
 	}
 
 	if compact {
 		compactItems := model.ToCompactLessonList(lessons)
-		return protectedListResult(compactItems, len(compactItems), hasMore, nil)
+		return protectedListResult(compactItems, len(compactItems), hasMore, nextCursor, nil)
 	}
-	return protectedListResult(lessons, len(lessons), hasMore, func() any {
+	return protectedListResult(lessons, len(lessons), hasMore, nextCursor, func() any {
 		return model.ToCompactLessonList(lessons)
 	})
 }
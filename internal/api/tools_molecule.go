@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/molecule"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type moleculeStepArgs struct {
+	Title              string `json:"title"`
+	Description        string `json:"description,omitempty"`
+	AcceptanceCriteria string `json:"acceptance_criteria,omitempty"`
+	EstimatedMinutes   *int   `json:"estimated_minutes,omitempty"`
+}
+
+type createMoleculeArgs struct {
+	Title        string             `json:"title"`
+	Description  string             `json:"description,omitempty"`
+	MolType      string             `json:"mol_type"`
+	WorkType     string             `json:"work_type,omitempty"`
+	Crystallizes bool               `json:"crystallizes,omitempty"`
+	Ephemeral    *bool              `json:"ephemeral,omitempty"` // steps; default true for patrol molecules only
+	Priority     int                `json:"priority,omitempty"`
+	Project      string             `json:"project,omitempty"`
+	Labels       []string           `json:"labels,omitempty"`
+	Steps        []moleculeStepArgs `json:"steps"`
+}
+
+// CreateMolecule creates a molecule and its steps in one go.
+func (h *Handlers) CreateMolecule(ctx context.Context, req *mcp.CallToolRequest, args createMoleculeArgs) (*mcp.CallToolResult, any, error) {
+	input := molecule.CreateInput{
+		Title:        args.Title,
+		Description:  args.Description,
+		MolType:      model.MolType(args.MolType),
+		WorkType:     model.WorkType(args.WorkType),
+		Crystallizes: args.Crystallizes,
+		Ephemeral:    args.Ephemeral,
+		Priority:     args.Priority,
+		Labels:       args.Labels,
+		CreatedBy:    claimant(req, ""),
+	}
+	if args.Project != "" {
+		projectID, err := resolveProjectSlug(ctx, h.store, args.Project)
+		if err != nil {
+			return errResult(err)
+		}
+		input.ProjectID = projectID
+	}
+	for _, s := range args.Steps {
+		input.Steps = append(input.Steps, molecule.Step{
+			Title:              s.Title,
+			Description:        s.Description,
+			AcceptanceCriteria: s.AcceptanceCriteria,
+			EstimatedMinutes:   s.EstimatedMinutes,
+		})
+	}
+	m, err := molecule.New(h.store).Create(ctx, input)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(m)
+}
+
+type moleculeArgs struct {
+	ID string `json:"id"`
+}
+
+// Molecule shows a molecule with its steps and progress.
+func (h *Handlers) Molecule(ctx context.Context, _ *mcp.CallToolRequest, args moleculeArgs) (*mcp.CallToolResult, any, error) {
+	m, err := molecule.New(h.store).Get(ctx, args.ID)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(m)
+}
+
+type claimStepArgs struct {
+	ID        string `json:"id"`
+	Agent     string `json:"agent,omitempty"`
+	LeaseSecs int    `json:"lease_seconds,omitempty"`
+}
+
+// ClaimStep claims a molecule step, or joins it under open_competition.
+func (h *Handlers) ClaimStep(ctx context.Context, req *mcp.CallToolRequest, args claimStepArgs) (*mcp.CallToolResult, any, error) {
+	ctx = withAgent(ctx, req, args.Agent)
+	m, err := molecule.New(h.store).Claim(ctx, args.ID, claimant(req, args.Agent), h.leaseFor(args.LeaseSecs))
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(m)
+}
+
+type completeStepArgs struct {
+	ID     string `json:"id"`
+	Agent  string `json:"agent,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// CompleteStep closes a molecule step; under open_competition the first to
+// do so wins it.
+func (h *Handlers) CompleteStep(ctx context.Context, req *mcp.CallToolRequest, args completeStepArgs) (*mcp.CallToolResult, any, error) {
+	ctx = withAgent(ctx, req, args.Agent)
+	m, err := molecule.New(h.store).Complete(ctx, args.ID, claimant(req, args.Agent), args.Reason)
+	if err != nil {
+		return errResult(err)
+	}
+	closed := []string{args.ID}
+	if m.Status == model.StatusClosed {
+		closed = append(closed, m.ID)
+	}
+	if err := h.releaseGates(ctx, closed...); err != nil {
+		return errResult(err)
+	}
+	return jsonResult(m)
+}
+
+// syncMolecule keeps the molecule issueID may be a step of in line with
+// it, after a tool outside the molecule ones moved the issue. It returns
+// the molecule, or nil if issueID is not a step.
+func (h *Handlers) syncMolecule(ctx context.Context, issueID string) (*molecule.Molecule, error) {
+	m, err := molecule.New(h.store).SyncParent(ctx, issueID)
+	if err != nil {
+		return nil, fmt.Errorf("%s was updated, but syncing its molecule failed: %w", issueID, err)
+	}
+	return m, nil
+}
+
+// settle brings what hangs on issue in line after a tool outside the
+// molecule and gate ones changed its status: the molecule it may be a
+// step of, and the gates waiting on it or on that molecule once closed.
+func (h *Handlers) settle(ctx context.Context, issue *model.Issue) error {
+	m, err := h.syncMolecule(ctx, issue.ID)
+	if err != nil {
+		return err
+	}
+	var closed []string
+	if issue.Status == model.StatusClosed {
+		closed = append(closed, issue.ID)
+	}
+	if m != nil && m.Status == model.StatusClosed {
+		closed = append(closed, m.ID)
+	}
+	return h.releaseGates(ctx, closed...)
+}
@@ -2,7 +2,9 @@ package api
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/Actual-Outcomes/doit/internal/model"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -28,3 +30,85 @@ func (h *Handlers) ListProjects(ctx context.Context, _ *mcp.CallToolRequest, _ l
 	}
 	return jsonResult(projects)
 }
+
+type readyPolicyArgs struct {
+	Project string   `json:"project"`
+	Gates   []string `json:"gates,omitempty"`
+	Reset   bool     `json:"reset,omitempty"`
+}
+
+// ReadyPolicy shows a project's ready policy, or sets it when gates or
+// reset is given.
+func (h *Handlers) ReadyPolicy(ctx context.Context, _ *mcp.CallToolRequest, args readyPolicyArgs) (*mcp.CallToolResult, any, error) {
+	if args.Project == "" {
+		return errResult(fmt.Errorf("project is required"))
+	}
+	projectID, err := resolveProjectSlug(ctx, h.store, args.Project)
+	if err != nil {
+		return errResult(err)
+	}
+
+	var policy *model.ReadyPolicy
+	switch {
+	case args.Reset && args.Gates != nil:
+		return errResult(fmt.Errorf("pass gates or reset, not both"))
+	case args.Reset:
+		policy, err = h.store.SetReadyPolicy(ctx, projectID, nil)
+	case args.Gates != nil:
+		gates := make([]model.DependencyType, len(args.Gates))
+		for i, g := range args.Gates {
+			gates[i] = model.DependencyType(g)
+		}
+		policy, err = h.store.SetReadyPolicy(ctx, projectID, gates)
+	default:
+		policy, err = h.store.GetReadyPolicy(ctx, projectID)
+	}
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(policy)
+}
+
+type compactionPolicyArgs struct {
+	Project       string   `json:"project"`
+	Age           *string  `json:"age,omitempty"`
+	ExcludeLabels []string `json:"exclude_labels,omitempty"`
+	Reset         bool     `json:"reset,omitempty"`
+}
+
+// CompactionPolicy shows a project's compaction policy, or changes the
+// fields given. Unset fields keep their current values.
+func (h *Handlers) CompactionPolicy(ctx context.Context, _ *mcp.CallToolRequest, args compactionPolicyArgs) (*mcp.CallToolResult, any, error) {
+	if args.Project == "" {
+		return errResult(fmt.Errorf("project is required"))
+	}
+	projectID, err := resolveProjectSlug(ctx, h.store, args.Project)
+	if err != nil {
+		return errResult(err)
+	}
+
+	changes := args.Age != nil || args.ExcludeLabels != nil
+	var policy *model.CompactionPolicy
+	switch {
+	case args.Reset && changes:
+		return errResult(fmt.Errorf("pass age or exclude_labels, or reset, not both"))
+	case args.Reset:
+		policy, err = h.store.SetCompactionPolicy(ctx, projectID, nil)
+	default:
+		policy, err = h.store.GetCompactionPolicy(ctx, projectID)
+		if err != nil || !changes {
+			break
+		}
+		if args.Age != nil {
+			policy.Age = *args.Age
+		}
+		if args.ExcludeLabels != nil {
+			policy.ExcludeLabels = args.ExcludeLabels
+		}
+		policy, err = h.store.SetCompactionPolicy(ctx, projectID, policy)
+	}
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(policy)
+}
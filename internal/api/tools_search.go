@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type searchArgs struct {
+	Query     string  `json:"query"`
+	Status    string  `json:"status,omitempty"`
+	IssueType string  `json:"issue_type,omitempty"`
+	Project   *string `json:"project,omitempty"`
+	Limit     int     `json:"limit,omitempty"`
+	Compact   *bool   `json:"compact,omitempty"`
+}
+
+func (h *Handlers) Search(ctx context.Context, _ *mcp.CallToolRequest, args searchArgs) (*mcp.CallToolResult, any, error) {
+	compact := compactDefault(args.Compact)
+	hasProject := strSet(args.Project)
+	limit := applyListDefaults(args.Limit, compact, hasProject)
+
+	query := model.SearchQuery{
+		Query: args.Query,
+		Limit: limit + 1, // fetch one extra to detect truncation
+	}
+	if args.Status != "" {
+		s := model.Status(args.Status)
+		query.Status = &s
+	}
+	if args.IssueType != "" {
+		t := model.IssueType(args.IssueType)
+		query.IssueType = &t
+	}
+	if hasProject {
+		resolved, err := resolveProjectSlug(ctx, h.store, *args.Project)
+		if err != nil {
+			return errResult(err)
+		}
+		query.ProjectID = &resolved
+	}
+
+	results, err := h.store.SearchIssues(ctx, query)
+	if err != nil {
+		return errResult(err)
+	}
+
+	hasMore := len(results) > limit
+	if hasMore {
+		results = results[:limit]
+	}
+
+	if compact {
+		compactItems := model.ToCompactSearchList(results)
+		return protectedListResult(compactItems, len(compactItems), hasMore, "", nil)
+	}
+	return protectedListResult(results, len(results), hasMore, "", func() any {
+		return model.ToCompactSearchList(results)
+	})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Actual-Outcomes/doit/internal/templates"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type markTemplateArgs struct {
+	ID     string `json:"id"`
+	Unmark bool   `json:"unmark,omitempty"`
+}
+
+// MarkTemplate makes an issue tree a template, or an ordinary tree again.
+func (h *Handlers) MarkTemplate(ctx context.Context, req *mcp.CallToolRequest, args markTemplateArgs) (*mcp.CallToolResult, any, error) {
+	ctx = withAgent(ctx, req, "")
+	t, err := templates.New(h.store).Mark(ctx, args.ID, !args.Unmark)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(t)
+}
+
+type listTemplatesArgs struct{}
+
+// ListTemplates lists the tenant's templates with the variables they use.
+func (h *Handlers) ListTemplates(ctx context.Context, _ *mcp.CallToolRequest, _ listTemplatesArgs) (*mcp.CallToolResult, any, error) {
+	list, err := templates.New(h.store).List(ctx)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(map[string]any{"count": len(list), "templates": list})
+}
+
+type instantiateTemplateArgs struct {
+	ID        string            `json:"id"`
+	Variables map[string]string `json:"variables,omitempty"`
+	Project   string            `json:"project,omitempty"`
+	Parent    string            `json:"parent,omitempty"`
+}
+
+// InstantiateTemplate copies a template with its variables filled in.
+func (h *Handlers) InstantiateTemplate(ctx context.Context, req *mcp.CallToolRequest, args instantiateTemplateArgs) (*mcp.CallToolResult, any, error) {
+	input := templates.InstantiateInput{
+		TemplateID: args.ID,
+		Variables:  args.Variables,
+		ParentID:   args.Parent,
+		CreatedBy:  claimant(req, ""),
+	}
+	if args.Project != "" {
+		projectID, err := resolveProjectSlug(ctx, h.store, args.Project)
+		if err != nil {
+			return errResult(err)
+		}
+		input.ProjectID = projectID
+	}
+	inst, err := templates.New(h.store).Instantiate(ctx, input)
+	if err != nil {
+		return errResult(err)
+	}
+	return jsonResult(inst)
+}
@@ -6,9 +6,16 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/Actual-Outcomes/doit/internal/agents"
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/compact"
+	"github.com/Actual-Outcomes/doit/internal/jobs"
 	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/molecule"
 	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/Actual-Outcomes/doit/internal/store/storetest"
 	"github.com/google/uuid"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -80,11 +87,37 @@ func (m *mockStore) ListIssues(_ context.Context, filter model.IssueFilter) ([]m
 		if filter.Pinned != nil && issue.Pinned != *filter.Pinned {
 			continue
 		}
+		if filter.Search != nil && !strings.Contains(issue.Title, *filter.Search) {
+			continue
+		}
 		out = append(out, *issue)
 	}
 	return out, nil
 }
 
+func (m *mockStore) FindAgentBead(_ context.Context, name string) (*model.Issue, error) {
+	for _, issue := range m.issues {
+		if issue.IssueType == model.TypeAgent && issue.Title == name {
+			return issue, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockStore) SearchIssues(_ context.Context, query model.SearchQuery) ([]model.SearchResult, error) {
+	var out []model.SearchResult
+	for _, issue := range m.issues {
+		if strings.Contains(issue.Title, query.Query) {
+			snippet := strings.ReplaceAll(issue.Title, query.Query, model.SnippetMark+query.Query+model.SnippetMark)
+			out = append(out, model.SearchResult{Issue: *issue, Rank: 1, Snippet: snippet})
+		}
+	}
+	if query.Limit > 0 && len(out) > query.Limit {
+		out = out[:query.Limit]
+	}
+	return out, nil
+}
+
 func (m *mockStore) DeleteIssue(_ context.Context, id string) error {
 	if _, ok := m.issues[id]; !ok {
 		return fmt.Errorf("issue %q not found", id)
@@ -103,6 +136,22 @@ func (m *mockStore) ListReady(_ context.Context, _ model.IssueFilter) ([]model.I
 	return out, nil
 }
 
+func (m *mockStore) ClaimNextReady(_ context.Context, _ store.ClaimNextReadyInput) (*model.Issue, error) {
+	return nil, nil
+}
+
+func (m *mockStore) RenewLease(_ context.Context, issueID, _ string, _ time.Duration) (*model.Issue, error) {
+	issue, ok := m.issues[issueID]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	return issue, nil
+}
+
+func (m *mockStore) ReleaseExpiredLeases(_ context.Context, _ time.Time) ([]model.Issue, error) {
+	return nil, nil
+}
+
 func (m *mockStore) AddDependency(_ context.Context, input store.AddDependencyInput) (*model.Dependency, error) {
 	dep := &model.Dependency{
 		IssueID:     input.IssueID,
@@ -161,6 +210,26 @@ func (m *mockStore) ListComments(_ context.Context, _ string) ([]model.Comment,
 	return nil, nil
 }
 
+func (m *mockStore) ValidateGraph(_ context.Context) (*model.GraphReport, error) {
+	return &model.GraphReport{Valid: true}, nil
+}
+
+func (m *mockStore) ApplyBatch(_ context.Context, _ []store.BatchOp) ([]store.BatchResult, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (m *mockStore) RestoreIssue(_ context.Context, id string) (*model.Issue, error) {
+	return nil, fmt.Errorf("issue %s not found", id)
+}
+
+func (m *mockStore) ListTrash(_ context.Context) ([]model.Issue, error) {
+	return nil, nil
+}
+
+func (m *mockStore) PurgeTrash(_ context.Context, _ time.Time) ([]string, error) {
+	return nil, nil
+}
+
 func (m *mockStore) AddEvent(_ context.Context, _ store.AddEventInput) (*model.Event, error) {
 	return &model.Event{}, nil
 }
@@ -169,7 +238,7 @@ func (m *mockStore) ListEvents(_ context.Context, _ string, _ int) ([]model.Even
 	return nil, nil
 }
 
-func (m *mockStore) SaveCompactionSnapshot(_ context.Context, _ string, _ int, _, _ string) error {
+func (m *mockStore) SaveCompactionSnapshot(_ context.Context, _ string, _ int, _, _ string, _ store.UpdateIssueInput) error {
 	return nil
 }
 
@@ -177,6 +246,22 @@ func (m *mockStore) GetCompactionSnapshots(_ context.Context, _ string) ([]model
 	return nil, nil
 }
 
+func (m *mockStore) GetCompactionSettings(_ context.Context) (*model.CompactionSettings, error) {
+	return &model.CompactionSettings{Summarizer: model.SummarizerHeuristic, Budgets: model.DefaultCompactionBudgets, Default: true}, nil
+}
+
+func (m *mockStore) SetCompactionSettings(_ context.Context, settings *model.CompactionSettings) (*model.CompactionSettings, error) {
+	return settings, nil
+}
+
+func (m *mockStore) GetCompactionPolicy(_ context.Context, projectID string) (*model.CompactionPolicy, error) {
+	return &model.CompactionPolicy{ProjectID: projectID, Default: true}, nil
+}
+
+func (m *mockStore) SetCompactionPolicy(_ context.Context, projectID string, policy *model.CompactionPolicy) (*model.CompactionPolicy, error) {
+	return policy, nil
+}
+
 func (m *mockStore) CountIssuesByStatus(_ context.Context) (map[string]int, error) {
 	return nil, nil
 }
@@ -209,6 +294,10 @@ func (m *mockStore) ListFlags(_ context.Context, _ model.FlagFilter) ([]model.Fl
 	return nil, nil
 }
 
+func (m *mockStore) GetFlag(_ context.Context, id string) (*model.Flag, error) {
+	return &model.Flag{ID: id}, nil
+}
+
 func (m *mockStore) ResolveFlag(_ context.Context, _ string, _, _ string) (*model.Flag, error) {
 	return &model.Flag{}, nil
 }
@@ -299,6 +388,14 @@ func (m *mockStore) DeleteProject(_ context.Context, _ string) error {
 	return nil
 }
 
+func (m *mockStore) GetReadyPolicy(_ context.Context, projectID string) (*model.ReadyPolicy, error) {
+	return &model.ReadyPolicy{ProjectID: projectID, Gates: model.ReadyGates, Default: true}, nil
+}
+
+func (m *mockStore) SetReadyPolicy(_ context.Context, projectID string, gates []model.DependencyType) (*model.ReadyPolicy, error) {
+	return &model.ReadyPolicy{ProjectID: projectID, Gates: gates}, nil
+}
+
 func (m *mockStore) DeleteTenant(_ context.Context, _ string) error {
 	return nil
 }
@@ -311,6 +408,34 @@ func (m *mockStore) SetConfig(_ context.Context, _, _ string) error {
 	return nil
 }
 
+func (m *mockStore) PurgeIssues(_ context.Context, ids []string) ([]string, error) {
+	return ids, nil
+}
+
+func (m *mockStore) GetGCPolicy(_ context.Context) (*model.GCPolicy, error) {
+	return &model.GCPolicy{Default: true}, nil
+}
+
+func (m *mockStore) SetGCPolicy(_ context.Context, policy *model.GCPolicy) (*model.GCPolicy, error) {
+	return policy, nil
+}
+
+func (m *mockStore) RecordJobRun(_ context.Context, _ *model.JobRun) error {
+	return nil
+}
+
+func (m *mockStore) ListJobRuns(_ context.Context, _ string, _ int) ([]model.JobRun, error) {
+	return nil, nil
+}
+
+func (m *mockStore) GetJobIntervals(_ context.Context) (map[string]time.Duration, error) {
+	return nil, nil
+}
+
+func (m *mockStore) SetJobInterval(_ context.Context, _ string, _ *time.Duration) error {
+	return nil
+}
+
 func (m *mockStore) Close() {}
 
 // --- Tests ---
@@ -446,6 +571,150 @@ func TestListIssues_PinnedFilter(t *testing.T) {
 	}
 }
 
+func TestListIssues_Search(t *testing.T) {
+	ms := newMockStore()
+	h := NewHandlers(ms)
+	ms.issues["a"] = &model.Issue{ID: "a", Title: "Fix login timeout", Status: model.StatusOpen}
+	ms.issues["b"] = &model.Issue{ID: "b", Title: "Write docs", Status: model.StatusOpen}
+
+	result, _, err := h.ListIssues(context.Background(), nil, listIssuesArgs{Search: "login"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var resp listResponse
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &resp); err != nil {
+		t.Fatalf("failed to parse response envelope: %v", err)
+	}
+	if resp.Count != 1 {
+		t.Errorf("count = %d, want 1", resp.Count)
+	}
+}
+
+func TestSearch_CompactWithSnippet(t *testing.T) {
+	ms := newMockStore()
+	h := NewHandlers(ms)
+	ms.issues["a"] = &model.Issue{
+		ID:          "a",
+		Title:       "Fix login timeout",
+		Status:      model.StatusOpen,
+		Description: "should not appear in compact",
+	}
+	ms.issues["b"] = &model.Issue{ID: "b", Title: "Write docs", Status: model.StatusOpen}
+
+	result, _, err := h.Search(context.Background(), nil, searchArgs{Query: "login"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	if contains(text, "should not appear in compact") {
+		t.Error("default compact=true should exclude description")
+	}
+	var resp struct {
+		Count int                         `json:"count"`
+		Items []model.CompactSearchResult `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(text), &resp); err != nil {
+		t.Fatalf("failed to parse response envelope: %v", err)
+	}
+	if resp.Count != 1 || resp.Items[0].ID != "a" {
+		t.Fatalf("items = %+v, want only a", resp.Items)
+	}
+	if resp.Items[0].Snippet != "Fix **login** timeout" {
+		t.Errorf("snippet = %q", resp.Items[0].Snippet)
+	}
+}
+
+func TestSearch_HasMore(t *testing.T) {
+	ms := newMockStore()
+	h := NewHandlers(ms)
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("doit-%d", i)
+		ms.issues[id] = &model.Issue{ID: id, Title: "login bug", Status: model.StatusOpen}
+	}
+
+	result, _, err := h.Search(context.Background(), nil, searchArgs{Query: "login", Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var resp listResponse
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &resp); err != nil {
+		t.Fatalf("failed to parse response envelope: %v", err)
+	}
+	if resp.Count != 2 || !resp.HasMore {
+		t.Errorf("count = %d, has_more = %v; want 2, true", resp.Count, resp.HasMore)
+	}
+}
+
+func TestListIssues_NextCursor(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	want := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("doit-%d", i)
+		if _, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: id, Title: id, Status: model.StatusOpen, Priority: 2}); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+		want[id] = true
+	}
+
+	seen := map[string]bool{}
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		result, _, err := h.ListIssues(ctx, nil, listIssuesArgs{Limit: 2, SortBy: "oldest", Cursor: cursor})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text := result.Content[0].(*mcp.TextContent).Text
+		if result.IsError {
+			t.Fatalf("expected success, got error: %s", text)
+		}
+		var resp struct {
+			HasMore    bool                 `json:"has_more"`
+			NextCursor string               `json:"next_cursor"`
+			Items      []model.CompactIssue `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(text), &resp); err != nil {
+			t.Fatalf("failed to parse response envelope: %v", err)
+		}
+		for _, i := range resp.Items {
+			if seen[i.ID] {
+				t.Errorf("%s returned twice", i.ID)
+			}
+			seen[i.ID] = true
+		}
+		if resp.HasMore != (resp.NextCursor != "") {
+			t.Fatalf("has_more = %v but next_cursor = %q", resp.HasMore, resp.NextCursor)
+		}
+		if !resp.HasMore {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+	if len(seen) != len(want) {
+		t.Errorf("paged through %d issues, want %d", len(seen), len(want))
+	}
+}
+
+func TestListIssues_InvalidCursor(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	result, _, err := h.ListIssues(ctx, nil, listIssuesArgs{Cursor: "bogus"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected error result for invalid cursor")
+	}
+}
+
 func TestReady_Compact(t *testing.T) {
 	ms := newMockStore()
 	h := NewHandlers(ms)
@@ -501,6 +770,49 @@ func TestDeleteIssue_NotFound(t *testing.T) {
 	}
 }
 
+func TestTrashRestore(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	ctx = auth.WithActor(ctx, "bob")
+	h := NewHandlers(ms)
+	if _, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-a", Title: "A", Status: model.StatusOpen, IssueType: model.TypeTask, Priority: 2}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	result, _, err := h.DeleteIssue(ctx, nil, deleteIssueArgs{ID: "doit-a"})
+	if err != nil || result.IsError {
+		t.Fatalf("DeleteIssue failed: %v", err)
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; !contains(text, "doit_restore_issue") {
+		t.Errorf("delete response should point at restore, got %s", text)
+	}
+
+	result, _, err = h.ListTrash(ctx, nil, listTrashArgs{})
+	if err != nil || result.IsError {
+		t.Fatalf("ListTrash failed: %v", err)
+	}
+	var resp listResponse
+	var issues []model.Issue
+	resp.Items = &issues
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(issues) != 1 || issues[0].ID != "doit-a" || issues[0].DeletedBy != "bob" {
+		t.Fatalf("trash = %+v, want doit-a deleted by bob", issues)
+	}
+
+	result, _, err = h.RestoreIssue(ctx, nil, restoreIssueArgs{ID: "doit-a"})
+	if err != nil || result.IsError {
+		t.Fatalf("RestoreIssue failed: %v", err)
+	}
+	if _, err := ms.GetIssue(ctx, "doit-a"); err != nil {
+		t.Errorf("restored issue should be retrievable: %v", err)
+	}
+	result, _, _ = h.RestoreIssue(ctx, nil, restoreIssueArgs{ID: "doit-a"})
+	if !result.IsError {
+		t.Error("restoring a live issue should fail")
+	}
+}
+
 func TestAddDependency(t *testing.T) {
 	ms := newMockStore()
 	h := NewHandlers(ms)
@@ -555,6 +867,63 @@ func TestAddComment(t *testing.T) {
 	}
 }
 
+func TestListEvents(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	// Tool calls run behind recordActor, which attributes them to the client.
+	var actor string
+	handler := recordActor(func(ctx context.Context, _ string, _ mcp.Request) (mcp.Result, error) {
+		actor = auth.ActorFromContext(ctx)
+		return nil, nil
+	})
+	if _, err := handler(ctx, "tools/call", &mcp.CallToolRequest{}); err != nil {
+		t.Fatalf("middleware: %v", err)
+	}
+	if actor != "agent" {
+		t.Fatalf("recordActor set actor %q, want agent", actor)
+	}
+	ctx = auth.WithActor(ctx, actor)
+
+	if _, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-a", Title: "A", Status: model.StatusOpen, IssueType: model.TypeTask, Priority: 2}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	for _, label := range []string{"x", "y", "z"} {
+		if err := ms.AddLabel(ctx, "doit-a", label); err != nil {
+			t.Fatalf("AddLabel: %v", err)
+		}
+	}
+
+	list := func(args listEventsArgs) listResponse {
+		t.Helper()
+		result, _, err := h.ListEvents(ctx, nil, args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text := result.Content[0].(*mcp.TextContent).Text
+		if result.IsError {
+			t.Fatalf("expected success, got error: %s", text)
+		}
+		var resp listResponse
+		var events []model.Event
+		resp.Items = &events
+		if err := json.Unmarshal([]byte(text), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		resp.Items = events
+		return resp
+	}
+
+	if resp := list(listEventsArgs{IssueID: "doit-a"}); resp.Count != 4 || resp.HasMore {
+		t.Errorf("all events: count %d has_more %v, want 4 and false", resp.Count, resp.HasMore)
+	}
+	resp := list(listEventsArgs{IssueID: "doit-a", EventType: "label_added", Limit: 2})
+	events := resp.Items.([]model.Event)
+	if resp.Count != 2 || !resp.HasMore || events[0].NewValue != "z" || events[0].Actor != "agent" {
+		t.Errorf("filtered events = %+v (has_more %v), want the newest two label_added by agent", events, resp.HasMore)
+	}
+}
+
 func TestAddLabel(t *testing.T) {
 	ms := newMockStore()
 	h := NewHandlers(ms)
@@ -603,77 +972,317 @@ func TestUpdateIssue_Claim(t *testing.T) {
 // These verify that literal "null" strings (sent by MCP clients for JSON null)
 // are treated as unset and don't cause FK violations or incorrect filters.
 
-func TestUpdateIssue_NullStrings(t *testing.T) {
-	ms := newMockStore()
+func TestClaimNext(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
 	h := NewHandlers(ms)
-	ms.issues["x"] = &model.Issue{ID: "x", Title: "Original", Status: model.StatusOpen, Assignee: "dave"}
 
-	// All optional string fields set to "null" — should be treated as no-op
-	nullStr := "null"
-	result, _, err := h.UpdateIssue(context.Background(), nil, updateIssueArgs{
-		ID:          "x",
-		Title:       &nullStr,
-		Description: &nullStr,
-		Status:      &nullStr,
-		Assignee:    &nullStr,
-		Owner:       &nullStr,
-		Notes:       &nullStr,
-	})
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if result.IsError {
-		t.Fatalf("expected success, got error: %s", result.Content[0].(*mcp.TextContent).Text)
+	for _, in := range []store.CreateIssueInput{
+		{ID: "doit-low", Title: "Low", Priority: 3},
+		{ID: "doit-high", Title: "High", Priority: 1},
+		{ID: "doit-bug", Title: "Bug", Priority: 2, IssueType: model.TypeBug, Labels: []string{"backend"}},
+	} {
+		in.Status = model.StatusOpen
+		if in.IssueType == "" {
+			in.IssueType = model.TypeTask
+		}
+		if _, err := ms.CreateIssue(ctx, in); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
 	}
 
-	// Title should remain unchanged because filterNull converts "null" to nil
-	issue := ms.issues["x"]
-	if issue.Title != "Original" {
-		t.Errorf("title = %q, want %q (null should be no-op)", issue.Title, "Original")
+	claim := func(args claimNextArgs) model.Issue {
+		t.Helper()
+		result, _, err := h.ClaimNext(ctx, nil, args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text := result.Content[0].(*mcp.TextContent).Text
+		if result.IsError {
+			t.Fatalf("expected success, got error: %s", text)
+		}
+		var issue model.Issue
+		if err := json.Unmarshal([]byte(text), &issue); err != nil {
+			t.Fatalf("failed to parse issue: %v", err)
+		}
+		return issue
 	}
-}
 
-func TestListIssues_NullProject(t *testing.T) {
-	ms := newMockStore()
-	h := NewHandlers(ms)
-	ms.issues["a"] = &model.Issue{ID: "a", Title: "Task A", Status: model.StatusOpen}
+	got := claim(claimNextArgs{Labels: []string{"backend"}})
+	if got.ID != "doit-bug" || got.Assignee != "agent" || got.Status != model.StatusInProgress {
+		t.Errorf("label-filtered claim = %s by %q (%s), want doit-bug by agent", got.ID, got.Assignee, got.Status)
+	}
+	got = claim(claimNextArgs{Agent: "worker-1"})
+	if got.ID != "doit-high" || got.Assignee != "worker-1" {
+		t.Errorf("claim = %s by %q, want doit-high by worker-1", got.ID, got.Assignee)
+	}
 
-	// Project set to "null" — should be treated as unset, not trigger slug resolution
-	nullStr := "null"
-	result, _, err := h.ListIssues(context.Background(), nil, listIssuesArgs{
-		Project: &nullStr,
-	})
+	events, err := ms.ListEvents(ctx, "doit-high", 0)
 	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+		t.Fatalf("ListEvents: %v", err)
 	}
-	if result.IsError {
-		t.Fatalf("expected success, got error: %s", result.Content[0].(*mcp.TextContent).Text)
+	if len(events) == 0 || events[0].EventType != model.EventStatusChanged || events[0].Actor != "worker-1" {
+		t.Errorf("claim should record a status_changed event by the claimant, got %+v", events)
+	}
+
+	claim(claimNextArgs{})
+	if got := claim(claimNextArgs{}); got.ID != "" {
+		t.Errorf("claim with nothing ready = %s, want none", got.ID)
 	}
 }
 
-func TestReady_NullProject(t *testing.T) {
-	ms := newMockStore()
+func TestHeartbeat(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
 	h := NewHandlers(ms)
-	ms.issues["a"] = &model.Issue{ID: "a", Title: "Ready", Status: model.StatusOpen}
+	h.SetClaimLease(time.Hour)
 
-	nullStr := "null"
-	result, _, err := h.Ready(context.Background(), nil, readyArgs{
-		Project: &nullStr,
-	})
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if _, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-1", Title: "Job", Status: model.StatusOpen, Priority: 2}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	result, _, _ := h.ClaimNext(ctx, nil, claimNextArgs{Agent: "worker-1", LeaseSecs: 60})
+	var claimed model.Issue
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &claimed); err != nil {
+		t.Fatalf("failed to parse claim: %v", err)
 	}
+	if claimed.LeaseExpiresAt == nil || claimed.LeaseExpiresAt.Sub(*claimed.LastActivity) != time.Minute {
+		t.Fatalf("lease_seconds=60 should give a one-minute lease, got %+v", claimed)
+	}
+
+	result, _, _ = h.Heartbeat(ctx, nil, heartbeatArgs{ID: "doit-1", Agent: "worker-2"})
+	if !result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "claim lease lost") {
+		t.Errorf("heartbeat by another agent should fail, got %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+
+	result, _, _ = h.Heartbeat(ctx, nil, heartbeatArgs{ID: "doit-1", Agent: "worker-1"})
+	text := result.Content[0].(*mcp.TextContent).Text
 	if result.IsError {
-		t.Fatalf("expected success, got error: %s", result.Content[0].(*mcp.TextContent).Text)
+		t.Fatalf("heartbeat failed: %s", text)
+	}
+	var renewed model.Issue
+	if err := json.Unmarshal([]byte(text), &renewed); err != nil {
+		t.Fatalf("failed to parse heartbeat: %v", err)
+	}
+	if d := renewed.LeaseExpiresAt.Sub(*renewed.LastActivity); d != time.Hour {
+		t.Errorf("heartbeat without lease_seconds should use the configured lease, got %v", d)
 	}
 }
 
-func TestRecordLesson_NullOptionalFields(t *testing.T) {
-	ms := newMockStore()
+func TestBatch(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
 	h := NewHandlers(ms)
 
-	// All optional fields set to "null" — should not be passed to store
-	nullStr := "null"
+	title := func(s string) *string { return &s }
+	result, _, _ := h.Batch(ctx, nil, batchArgs{Ops: []batchOpArgs{
+		{Op: "create", Ref: "epic", Title: title("Billing"), IssueType: "epic"},
+		{Op: "create", Ref: "api", Title: title("API"), ParentID: "$epic"},
+		{Op: "create", Ref: "ui", Title: title("UI"), ParentID: "$epic", Labels: []string{"frontend"}},
+		{Op: "dependency", IssueID: "$ui", DependsOnID: "$api"},
+		{Op: "label", IssueID: "$api", Label: "backend"},
+		{Op: "comment", IssueID: "$epic", Author: "planner", Text: "plan laid out"},
+	}})
+	text := result.Content[0].(*mcp.TextContent).Text
+	if result.IsError {
+		t.Fatalf("batch failed: %s", text)
+	}
+	var resp struct {
+		Applied int                 `json:"applied"`
+		Results []store.BatchResult `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(text), &resp); err != nil {
+		t.Fatalf("failed to parse batch result: %v", err)
+	}
+	if resp.Applied != 6 || len(resp.Results) != 6 {
+		t.Fatalf("expected 6 results, got %s", text)
+	}
+	epic, api := resp.Results[0].IssueID, resp.Results[1].IssueID
+	if api != epic+".1" {
+		t.Errorf("child of $epic should be %s.1, got %s", epic, api)
+	}
+	if dep := resp.Results[3].Dependency; dep == nil || dep.IssueID != epic+".2" || dep.DependsOnID != api || dep.Type != model.DepBlocks {
+		t.Errorf("dependency should resolve refs and default to blocks, got %+v", dep)
+	}
+
+	before, _ := ms.ListIssues(ctx, model.IssueFilter{})
+	result, _, _ = h.Batch(ctx, nil, batchArgs{Ops: []batchOpArgs{
+		{Op: "create", Ref: "x", Title: title("Orphan")},
+		{Op: "dependency", IssueID: "$x", DependsOnID: "doit-missing"},
+	}})
+	text = result.Content[0].(*mcp.TextContent).Text
+	if !result.IsError || !strings.Contains(text, "batch op 1 (dependency)") {
+		t.Fatalf("expected failure at op 1, got %s", text)
+	}
+	after, _ := ms.ListIssues(ctx, model.IssueFilter{})
+	if len(after) != len(before) {
+		t.Errorf("failed batch should create nothing: %d issues before, %d after", len(before), len(after))
+	}
+
+	result, _, _ = h.Batch(ctx, nil, batchArgs{Ops: []batchOpArgs{{Op: "rename"}}})
+	if !result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "ops[0]: unknown op") {
+		t.Errorf("unknown op should be rejected, got %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+}
+
+func TestValidateGraph(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+	if _, err := ms.CreateTenant(context.Background(), "globex", "globex"); err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+	if _, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-gone.1", Title: "orphan", Status: model.StatusOpen}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	var resp struct {
+		Valid   bool `json:"valid"`
+		Tenants []struct {
+			Tenant           string                `json:"tenant"`
+			Valid            bool                  `json:"valid"`
+			OrphanedChildren []model.OrphanedChild `json:"orphaned_children"`
+		} `json:"tenants"`
+	}
+	result, _, _ := h.ValidateGraph(context.Background(), nil, validateGraphArgs{})
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &resp); err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+	if resp.Valid || len(resp.Tenants) != 2 {
+		t.Fatalf("expected an invalid report covering both tenants, got %+v", resp)
+	}
+
+	result, _, _ = h.ValidateGraph(context.Background(), nil, validateGraphArgs{Tenant: "globex"})
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &resp); err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+	if !resp.Valid || len(resp.Tenants) != 1 || resp.Tenants[0].Tenant != "globex" {
+		t.Errorf("globex alone should be valid, got %+v", resp)
+	}
+
+	result, _, _ = h.ValidateGraph(context.Background(), nil, validateGraphArgs{Tenant: "initech"})
+	if !result.IsError {
+		t.Error("unknown tenant should be an error")
+	}
+}
+
+func TestUpdateIssue_NullStrings(t *testing.T) {
+	ms := newMockStore()
+	h := NewHandlers(ms)
+	ms.issues["x"] = &model.Issue{ID: "x", Title: "Original", Status: model.StatusOpen, Assignee: "dave"}
+
+	// All optional string fields set to "null" — should be treated as no-op
+	nullStr := "null"
+	result, _, err := h.UpdateIssue(context.Background(), nil, updateIssueArgs{
+		ID:          "x",
+		Title:       &nullStr,
+		Description: &nullStr,
+		Status:      &nullStr,
+		Assignee:    &nullStr,
+		Owner:       &nullStr,
+		Notes:       &nullStr,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+
+	// Title should remain unchanged because filterNull converts "null" to nil
+	issue := ms.issues["x"]
+	if issue.Title != "Original" {
+		t.Errorf("title = %q, want %q (null should be no-op)", issue.Title, "Original")
+	}
+}
+
+func TestUpdateIssue_Conflict(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	issue, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-1", Title: "Original", Status: model.StatusOpen, Priority: 2})
+	if err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	theirs := "Theirs"
+	if _, err := ms.UpdateIssue(ctx, issue.ID, store.UpdateIssueInput{Title: &theirs}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+
+	ours := "Ours"
+	result, _, err := h.UpdateIssue(ctx, nil, updateIssueArgs{
+		ID:                  issue.ID,
+		Title:               &ours,
+		ExpectedContentHash: &issue.ContentHash,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(*mcp.TextContent).Text
+	if !result.IsError {
+		t.Fatalf("expected conflict, got success: %s", text)
+	}
+	var resp struct {
+		Error   string      `json:"error"`
+		Field   string      `json:"field"`
+		Current model.Issue `json:"current"`
+	}
+	if err := json.Unmarshal([]byte(text), &resp); err != nil {
+		t.Fatalf("failed to parse conflict: %v", err)
+	}
+	if resp.Error != "conflict" || resp.Field != "content_hash" || resp.Current.Title != "Theirs" {
+		t.Errorf("conflict = %+v", resp)
+	}
+
+	stamp := issue.UpdatedAt.Format(time.RFC3339Nano)
+	result, _, _ = h.UpdateIssue(ctx, nil, updateIssueArgs{ID: issue.ID, Title: &ours, ExpectedUpdatedAt: &stamp})
+	if !result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "updated_at") {
+		t.Errorf("stale updated_at should conflict, got %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+
+	bad := "yesterday"
+	result, _, _ = h.UpdateIssue(ctx, nil, updateIssueArgs{ID: issue.ID, Title: &ours, ExpectedUpdatedAt: &bad})
+	if !result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "RFC 3339") {
+		t.Errorf("unparseable updated_at should be rejected, got %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+}
+
+func TestListIssues_NullProject(t *testing.T) {
+	ms := newMockStore()
+	h := NewHandlers(ms)
+	ms.issues["a"] = &model.Issue{ID: "a", Title: "Task A", Status: model.StatusOpen}
+
+	// Project set to "null" — should be treated as unset, not trigger slug resolution
+	nullStr := "null"
+	result, _, err := h.ListIssues(context.Background(), nil, listIssuesArgs{
+		Project: &nullStr,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+}
+
+func TestReady_NullProject(t *testing.T) {
+	ms := newMockStore()
+	h := NewHandlers(ms)
+	ms.issues["a"] = &model.Issue{ID: "a", Title: "Ready", Status: model.StatusOpen}
+
+	nullStr := "null"
+	result, _, err := h.Ready(context.Background(), nil, readyArgs{
+		Project: &nullStr,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+}
+
+func TestRecordLesson_NullOptionalFields(t *testing.T) {
+	ms := newMockStore()
+	h := NewHandlers(ms)
+
+	// All optional fields set to "null" — should not be passed to store
+	nullStr := "null"
 	result, _, err := h.RecordLesson(context.Background(), nil, recordLessonArgs{
 		Title:      "Test lesson",
 		Mistake:    "Did something wrong",
@@ -826,6 +1435,63 @@ func TestListFlags_HappyPath(t *testing.T) {
 	}
 }
 
+func TestListFlags_Paged(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+	if _, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-a", Title: "A", Status: model.StatusOpen, Priority: 2}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := ms.RaiseFlag(ctx, store.RaiseFlagInput{IssueID: "doit-a", Type: "blocked", Severity: 3, Summary: fmt.Sprint("flag ", i)}); err != nil {
+			t.Fatalf("RaiseFlag: %v", err)
+		}
+	}
+
+	// Unpaged, the result is the bare array callers have always had.
+	limit := 2
+	result, _, err := h.ListFlags(ctx, nil, listFlagsArgs{Limit: &limit})
+	if err != nil || result.IsError {
+		t.Fatalf("ListFlags: %v %+v", err, result)
+	}
+	var flags []model.Flag
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &flags); err != nil {
+		t.Fatalf("unpaged response should be an array: %v", err)
+	}
+	if len(flags) != 2 {
+		t.Errorf("unpaged flags = %d, want the 2 asked for", len(flags))
+	}
+
+	seen := map[string]bool{}
+	var cursor *string
+	for page := 0; page < 3; page++ {
+		result, _, err := h.ListFlags(ctx, nil, listFlagsArgs{Limit: &limit, Paged: true, Cursor: cursor})
+		if err != nil || result.IsError {
+			t.Fatalf("ListFlags(paged): %v %+v", err, result)
+		}
+		var resp struct {
+			HasMore    bool         `json:"has_more"`
+			NextCursor string       `json:"next_cursor"`
+			Items      []model.Flag `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &resp); err != nil {
+			t.Fatalf("paged response should be an envelope: %v", err)
+		}
+		for _, f := range resp.Items {
+			if seen[f.ID] {
+				t.Errorf("%s returned twice", f.ID)
+			}
+			seen[f.ID] = true
+		}
+		if !resp.HasMore {
+			break
+		}
+		cursor = &resp.NextCursor
+	}
+	if len(seen) != 3 {
+		t.Errorf("paged through %d flags, want 3", len(seen))
+	}
+}
+
 func TestResolveFlag_HappyPath(t *testing.T) {
 	ms := newMockStore()
 	h := NewHandlers(ms)
@@ -1155,7 +1821,7 @@ func TestProtectedListResult_AutoCompact(t *testing.T) {
 		})
 	}
 
-	result, _, err := protectedListResult(issues, len(issues), false, func() any {
+	result, _, err := protectedListResult(issues, len(issues), false, "", func() any {
 		return model.ToCompactList(issues)
 	})
 	if err != nil {
@@ -1224,3 +1890,741 @@ func containsStr(s, sub string) bool {
 	}
 	return false
 }
+
+func TestReadyPolicy(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+	if _, err := ms.CreateProject(ctx, "Web", "web"); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	policy := func(args readyPolicyArgs) model.ReadyPolicy {
+		t.Helper()
+		result, _, _ := h.ReadyPolicy(ctx, nil, args)
+		if result.IsError {
+			t.Fatalf("ReadyPolicy(%+v) failed: %s", args, result.Content[0].(*mcp.TextContent).Text)
+		}
+		var p model.ReadyPolicy
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &p); err != nil {
+			t.Fatalf("failed to parse policy: %v", err)
+		}
+		return p
+	}
+
+	if p := policy(readyPolicyArgs{Project: "web"}); !p.Default {
+		t.Errorf("expected the default policy, got %+v", p)
+	}
+	if p := policy(readyPolicyArgs{Project: "web", Gates: []string{"waits-for", "blocks"}}); p.Default ||
+		len(p.Gates) != 2 || p.Gates[0] != model.DepBlocks {
+		t.Errorf("expected [blocks waits-for], got %+v", p)
+	}
+	if p := policy(readyPolicyArgs{Project: "web", Gates: []string{}}); p.Default || len(p.Gates) != 0 {
+		t.Errorf("an empty list should disable every gate, got %+v", p)
+	}
+	if p := policy(readyPolicyArgs{Project: "web", Reset: true}); !p.Default {
+		t.Errorf("reset should restore the default, got %+v", p)
+	}
+
+	for _, args := range []readyPolicyArgs{
+		{},
+		{Project: "web", Gates: []string{"related"}},
+		{Project: "web", Gates: []string{"blocks"}, Reset: true},
+		{Project: "nope"},
+	} {
+		if result, _, _ := h.ReadyPolicy(ctx, nil, args); !result.IsError {
+			t.Errorf("ReadyPolicy(%+v) should fail", args)
+		}
+	}
+}
+
+func TestExplainReady(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+	for _, in := range []store.CreateIssueInput{
+		{ID: "doit-a", Title: "blocker", Status: model.StatusOpen, Assignee: "bob"},
+		{ID: "doit-b", Title: "blocked", Status: model.StatusOpen},
+	} {
+		if _, err := ms.CreateIssue(ctx, in); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+	}
+	if _, err := ms.AddDependency(ctx, store.AddDependencyInput{IssueID: "doit-b", DependsOnID: "doit-a", Type: model.DepBlocks}); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+
+	result, _, _ := h.ExplainReady(ctx, nil, explainReadyArgs{ID: "doit-b"})
+	var exp model.ReadyExplanation
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &exp); err != nil {
+		t.Fatalf("failed to parse explanation: %v", err)
+	}
+	if exp.Ready || len(exp.Reasons) != 1 || len(exp.Reasons[0].Blockers) != 1 ||
+		exp.Reasons[0].Blockers[0].Assignee != "bob" {
+		t.Errorf("expected one dependency reason naming bob's blocker, got %+v", exp)
+	}
+
+	if result, _, _ := h.ExplainReady(ctx, nil, explainReadyArgs{}); !result.IsError {
+		t.Error("a missing id should be an error")
+	}
+}
+
+func TestCriticalPath(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	if _, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-e", Title: "epic", IssueType: model.TypeEpic}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	thirty, fortyFive := 30, 45
+	for _, args := range []createIssueArgs{
+		{Title: "first", ParentID: "doit-e", EstimatedMinutes: &thirty},
+		{Title: "second", ParentID: "doit-e", EstimatedMinutes: &fortyFive},
+	} {
+		if result, _, _ := h.CreateIssue(ctx, nil, args); result.IsError {
+			t.Fatalf("CreateIssue: %s", result.Content[0].(*mcp.TextContent).Text)
+		}
+	}
+	if _, err := ms.AddDependency(ctx, store.AddDependencyInput{IssueID: "doit-e.2", DependsOnID: "doit-e.1", Type: model.DepBlocks}); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+
+	result, _, _ := h.CriticalPath(ctx, nil, criticalPathArgs{ID: "doit-e", Start: "2026-01-05T09:00:00Z"})
+	var sched model.Schedule
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &sched); err != nil {
+		t.Fatalf("failed to parse schedule: %v", err)
+	}
+	if sched.TotalMinutes != 75 || len(sched.CriticalPath) != 2 ||
+		!sched.EarliestFinish.Equal(time.Date(2026, 1, 5, 10, 15, 0, 0, time.UTC)) {
+		t.Errorf("expected a 75 minute path finishing at 10:15, got %+v", sched)
+	}
+
+	if result, _, _ := h.CriticalPath(ctx, nil, criticalPathArgs{ID: "doit-e", Start: "tomorrow"}); !result.IsError {
+		t.Error("an unparseable start should be an error")
+	}
+}
+
+func TestIssueAt(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+	if _, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-a", Title: "before", Status: model.StatusOpen, IssueType: model.TypeTask, Priority: 2}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	created := time.Now().UTC().Format(time.RFC3339Nano)
+	time.Sleep(5 * time.Millisecond)
+	title := "after"
+	if _, err := ms.UpdateIssue(ctx, "doit-a", store.UpdateIssueInput{Title: &title}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+
+	result, _, err := h.IssueAt(ctx, nil, issueAtArgs{ID: "doit-a", At: created})
+	if err != nil || result.IsError {
+		t.Fatalf("IssueAt failed: %v", err)
+	}
+	var version model.IssueVersion
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &version); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if version.Issue.Title != "before" || version.Undone != 1 {
+		t.Errorf("version = %q with %d undone, want before with 1", version.Issue.Title, version.Undone)
+	}
+
+	result, _, err = h.DiffIssue(ctx, nil, diffIssueArgs{ID: "doit-a", From: created})
+	if err != nil || result.IsError {
+		t.Fatalf("DiffIssue failed: %v", err)
+	}
+	var diff model.IssueDiff
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &diff); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(diff.Changes) != 1 || diff.Changes[0] != (model.FieldChange{Field: "title", From: "before", To: "after"}) {
+		t.Errorf("changes = %+v, want title before → after", diff.Changes)
+	}
+
+	if result, _, _ := h.IssueAt(ctx, nil, issueAtArgs{ID: "doit-a", At: "last tuesday"}); !result.IsError {
+		t.Error("an unparseable time should be rejected")
+	}
+}
+
+func TestUncompact(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+	issue, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-a", Title: "A", Description: "full detail", Status: model.StatusClosed, IssueType: model.TypeTask, Priority: 2})
+	if err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	summary := "summary"
+	if err := ms.SaveCompactionSnapshot(ctx, "doit-a", 1, summary, model.CompactionOriginal(issue), store.UpdateIssueInput{Description: &summary}); err != nil {
+		t.Fatalf("SaveCompactionSnapshot: %v", err)
+	}
+
+	result, _, err := h.ListCompactionSnapshots(ctx, nil, listCompactionSnapshotsArgs{IssueID: "doit-a"})
+	if err != nil || result.IsError {
+		t.Fatalf("ListCompactionSnapshots failed: %v", err)
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; !contains(text, "full detail") {
+		t.Errorf("snapshots should carry the original content, got %s", text)
+	}
+
+	result, _, err = h.Uncompact(ctx, nil, uncompactArgs{ID: "doit-a"})
+	if err != nil || result.IsError {
+		t.Fatalf("Uncompact failed: %v", err)
+	}
+	var restored model.Issue
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &restored); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if restored.Description != "full detail" || restored.CompactionLevel != 0 {
+		t.Errorf("restored = %q at level %d, want full detail at 0", restored.Description, restored.CompactionLevel)
+	}
+	if result, _, _ := h.Uncompact(ctx, nil, uncompactArgs{ID: "doit-a"}); !result.IsError {
+		t.Error("uncompacting a full-detail issue should fail")
+	}
+}
+
+func TestCompactionSettings(t *testing.T) {
+	ms := store.NewMemStore("")
+	h := NewHandlers(ms)
+	if _, err := ms.CreateTenant(context.Background(), "acme", "acme"); err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+
+	settings := func(args compactionSettingsArgs) model.CompactionSettings {
+		t.Helper()
+		result, _, _ := h.CompactionSettings(context.Background(), nil, args)
+		if result.IsError {
+			t.Fatalf("CompactionSettings(%+v) failed: %s", args, result.Content[0].(*mcp.TextContent).Text)
+		}
+		var s model.CompactionSettings
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &s); err != nil {
+			t.Fatalf("failed to parse settings: %v", err)
+		}
+		return s
+	}
+
+	if s := settings(compactionSettingsArgs{Tenant: "acme"}); !s.Default || s.Summarizer != model.SummarizerHeuristic {
+		t.Errorf("expected the default settings, got %+v", s)
+	}
+	budget := 250
+	if s := settings(compactionSettingsArgs{Tenant: "acme", Summarizer: strPtr("extractive"), Level1Budget: &budget}); s.Default ||
+		s.Summarizer != model.SummarizerExtractive || s.Budgets.Level1 != 250 || s.Budgets.Level2 != model.DefaultCompactionBudgets.Level2 {
+		t.Errorf("expected extractive with a 250 level 1 budget, got %+v", s)
+	}
+	// Changing one field keeps the others.
+	s := settings(compactionSettingsArgs{Tenant: "acme", Summarizer: strPtr("endpoint"), EndpointURL: strPtr("http://localhost:11434/v1"), EndpointModel: strPtr("llama3")})
+	if s.Budgets.Level1 != 250 || s.Endpoint == nil || s.Endpoint.Model != "llama3" {
+		t.Errorf("expected the endpoint added to the earlier settings, got %+v", s)
+	}
+	if s := settings(compactionSettingsArgs{Tenant: "acme", Reset: true}); !s.Default || s.Endpoint != nil {
+		t.Errorf("expected reset to restore the defaults, got %+v", s)
+	}
+
+	for _, args := range []compactionSettingsArgs{
+		{},
+		{Tenant: "nope"},
+		{Tenant: "acme", Summarizer: strPtr("endpoint")},
+		{Tenant: "acme", Summarizer: strPtr("extractive"), Reset: true},
+	} {
+		if result, _, _ := h.CompactionSettings(context.Background(), nil, args); !result.IsError {
+			t.Errorf("CompactionSettings(%+v) should fail", args)
+		}
+	}
+}
+
+func TestCompactionPolicy(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+	if _, err := ms.CreateProject(ctx, "Web", "web"); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	policy := func(args compactionPolicyArgs) model.CompactionPolicy {
+		t.Helper()
+		result, _, _ := h.CompactionPolicy(ctx, nil, args)
+		if result.IsError {
+			t.Fatalf("CompactionPolicy(%+v) failed: %s", args, result.Content[0].(*mcp.TextContent).Text)
+		}
+		var p model.CompactionPolicy
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &p); err != nil {
+			t.Fatalf("failed to parse policy: %v", err)
+		}
+		return p
+	}
+
+	if p := policy(compactionPolicyArgs{Project: "web"}); !p.Default {
+		t.Errorf("expected the default policy, got %+v", p)
+	}
+	if p := policy(compactionPolicyArgs{Project: "web", Age: strPtr("720h")}); p.Default || p.Age != "720h0m0s" {
+		t.Errorf("expected a 720h age, got %+v", p)
+	}
+	if p := policy(compactionPolicyArgs{Project: "web", ExcludeLabels: []string{"legal"}}); p.Age != "720h0m0s" ||
+		len(p.ExcludeLabels) != 1 || p.ExcludeLabels[0] != "legal" {
+		t.Errorf("expected the age kept and legal excluded, got %+v", p)
+	}
+	if p := policy(compactionPolicyArgs{Project: "web", Reset: true}); !p.Default {
+		t.Errorf("expected reset to restore the default, got %+v", p)
+	}
+	if result, _, _ := h.CompactionPolicy(ctx, nil, compactionPolicyArgs{Project: "web", Age: strPtr("soon")}); !result.IsError {
+		t.Error("an invalid age should fail")
+	}
+}
+
+func TestCompactDryRun(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+	if _, err := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-a", Title: "A", Description: "full detail", Status: model.StatusOpen, IssueType: model.TypeTask}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	closed := model.StatusClosed
+	if _, err := ms.UpdateIssue(ctx, "doit-a", store.UpdateIssueInput{Status: &closed}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	result, _, _ := h.Compact(ctx, nil, compactArgs{Age: "1ns", DryRun: true})
+	if result.IsError {
+		t.Fatalf("Compact failed: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+	var report compact.Report
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &report); err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+	if !report.DryRun || len(report.Compacted) != 1 || report.Compacted[0].IssueID != "doit-a" {
+		t.Errorf("expected a dry run listing doit-a, got %+v", report)
+	}
+	if issue, _ := ms.GetIssue(ctx, "doit-a"); issue.CompactionLevel != 0 || issue.Description != "full detail" {
+		t.Errorf("dry run changed the issue: level %d, %q", issue.CompactionLevel, issue.Description)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	ms := store.NewMemStore("")
+	h := NewHandlers(ms)
+	tenant, err := ms.CreateTenant(context.Background(), "acme", "acme")
+	if err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+	ctx := auth.WithTenant(context.Background(), tenant.ID)
+
+	if result, _, _ := h.Jobs(context.Background(), nil, jobsArgs{Tenant: "acme"}); !result.IsError {
+		t.Error("expected an error while background jobs are not enabled")
+	}
+	h.SetJobs(jobs.New(ms, jobs.Job{Name: "compact", Interval: 24 * time.Hour}))
+	if err := ms.RecordJobRun(ctx, &model.JobRun{
+		Job: "compact", StartedAt: time.Now(), FinishedAt: time.Now(), Succeeded: true, Result: json.RawMessage(`{"compacted":3}`),
+	}); err != nil {
+		t.Fatalf("RecordJobRun: %v", err)
+	}
+
+	call := func(args jobsArgs) jobsResult {
+		t.Helper()
+		result, _, _ := h.Jobs(context.Background(), nil, args)
+		if result.IsError {
+			t.Fatalf("Jobs(%+v) failed: %s", args, result.Content[0].(*mcp.TextContent).Text)
+		}
+		var r jobsResult
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &r); err != nil {
+			t.Fatalf("failed to parse jobs: %v", err)
+		}
+		return r
+	}
+
+	r := call(jobsArgs{Tenant: "acme"})
+	if len(r.Jobs) != 1 || r.Jobs[0].Interval != "24h0m0s" || !r.Jobs[0].Default || r.Jobs[0].LastRun == nil || r.History != nil {
+		t.Fatalf("expected compact at its default with its last run, got %+v", r)
+	}
+	var last map[string]int
+	if err := json.Unmarshal(r.Jobs[0].LastRun.Result, &last); err != nil || last["compacted"] != 3 {
+		t.Errorf("expected the last run's result, got %s", r.Jobs[0].LastRun.Result)
+	}
+	r = call(jobsArgs{Tenant: "acme", Job: "compact", Interval: strPtr("6h")})
+	if r.Jobs[0].Interval != "6h0m0s" || r.Jobs[0].Default || len(r.History) != 1 {
+		t.Errorf("expected a 6h override and the history, got %+v", r)
+	}
+	if r = call(jobsArgs{Tenant: "acme", Job: "compact", Reset: true}); !r.Jobs[0].Default {
+		t.Errorf("expected reset to restore the default, got %+v", r.Jobs[0])
+	}
+
+	for _, args := range []jobsArgs{
+		{},
+		{Tenant: "nope"},
+		{Tenant: "acme", Job: "nope"},
+		{Tenant: "acme", Interval: strPtr("1h")},
+		{Tenant: "acme", Job: "compact", Interval: strPtr("soon")},
+		{Tenant: "acme", Job: "compact", Interval: strPtr("-1h")},
+		{Tenant: "acme", Job: "compact", Interval: strPtr("1h"), Reset: true},
+	} {
+		if result, _, _ := h.Jobs(context.Background(), nil, args); !result.IsError {
+			t.Errorf("Jobs(%+v) should fail", args)
+		}
+	}
+}
+
+func TestGC(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	policy := func(args gcPolicyArgs) model.GCPolicy {
+		t.Helper()
+		result, _, _ := h.GCPolicy(ctx, nil, args)
+		if result.IsError {
+			t.Fatalf("GCPolicy(%+v) failed: %s", args, result.Content[0].(*mcp.TextContent).Text)
+		}
+		var p model.GCPolicy
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &p); err != nil {
+			t.Fatalf("failed to parse policy: %v", err)
+		}
+		return p
+	}
+	if p := policy(gcPolicyArgs{}); !p.Default || p.Retention["ping"] != "1h0m0s" {
+		t.Errorf("expected the default policy, got %+v", p)
+	}
+	// Retention entries merge into the current ones.
+	policy(gcPolicyArgs{Retention: map[string]string{"ping": "1ns"}})
+	if p := policy(gcPolicyArgs{Retention: map[string]string{"error": "48h"}, Report: boolPtr(true)}); p.Retention["ping"] != "1ns" ||
+		p.Retention["error"] != "48h0m0s" || !p.Report {
+		t.Errorf("expected merged retention with reports on, got %+v", p)
+	}
+
+	id, _ := ms.GenerateID(ctx, "")
+	if _, err := ms.CreateIssue(ctx, store.CreateIssueInput{
+		ID: id, Title: "ping", Status: model.StatusOpen, IssueType: model.TypeTask, Ephemeral: true, WispType: model.WispPing,
+	}); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	run := func(args gcArgs) map[string]any {
+		t.Helper()
+		result, _, _ := h.GC(ctx, nil, args)
+		if result.IsError {
+			t.Fatalf("GC failed: %s", result.Content[0].(*mcp.TextContent).Text)
+		}
+		var r map[string]any
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &r); err != nil {
+			t.Fatalf("failed to parse report: %v", err)
+		}
+		return r
+	}
+	if r := run(gcArgs{DryRun: true}); r["dry_run"] != true || len(r["collected"].([]any)) != 1 {
+		t.Errorf("expected a dry run listing the ping, got %v", r)
+	}
+	if _, err := ms.GetIssue(ctx, id); err != nil {
+		t.Errorf("dry run deleted the ping: %v", err)
+	}
+	if r := run(gcArgs{Report: boolPtr(false)}); len(r["collected"].([]any)) != 1 || r["report_id"] != nil {
+		t.Errorf("expected the ping collected without a report, got %v", r)
+	}
+
+	for _, args := range []gcPolicyArgs{
+		{Retention: map[string]string{"chatter": "1h"}},
+		{Ephemeral: strPtr("-1h")},
+		{Report: boolPtr(true), Reset: true},
+	} {
+		if result, _, _ := h.GCPolicy(ctx, nil, args); !result.IsError {
+			t.Errorf("GCPolicy(%+v) should fail", args)
+		}
+	}
+	if p := policy(gcPolicyArgs{Reset: true}); !p.Default || p.Report {
+		t.Errorf("expected reset to restore the defaults, got %+v", p)
+	}
+}
+
+func TestMolecules(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	parse := func(name string, result *mcp.CallToolResult) molecule.Molecule {
+		t.Helper()
+		if result.IsError {
+			t.Fatalf("%s failed: %s", name, result.Content[0].(*mcp.TextContent).Text)
+		}
+		var m molecule.Molecule
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &m); err != nil {
+			t.Fatalf("failed to parse molecule: %v", err)
+		}
+		return m
+	}
+
+	result, _, _ := h.CreateMolecule(ctx, nil, createMoleculeArgs{
+		Title:   "Ship release",
+		MolType: "work",
+		Steps:   []moleculeStepArgs{{Title: "build"}, {Title: "deploy"}},
+	})
+	m := parse("CreateMolecule", result)
+	if m.IssueType != model.TypeMolecule || m.WorkType != model.WorkMutex || len(m.Steps) != 2 {
+		t.Fatalf("expected a mutex molecule with 2 steps, got %+v", m)
+	}
+	build, deploy := m.Steps[0].ID, m.Steps[1].ID
+
+	result, _, _ = h.ClaimStep(ctx, nil, claimStepArgs{ID: build, Agent: "alice"})
+	if m = parse("ClaimStep", result); m.Status != model.StatusInProgress || m.Steps[0].Assignee != "alice" {
+		t.Errorf("expected alice on the first step, got %+v", m)
+	}
+	if result, _, _ = h.ClaimStep(ctx, nil, claimStepArgs{ID: build, Agent: "bob"}); !result.IsError {
+		t.Error("bob should not be able to claim alice's step")
+	}
+	result, _, _ = h.CompleteStep(ctx, nil, completeStepArgs{ID: build, Agent: "alice", Reason: "built"})
+	if m = parse("CompleteStep", result); m.Steps[0].Status != model.StatusClosed || m.Steps[0].CloseReason != "built" {
+		t.Errorf("expected the first step closed, got %+v", m.Steps[0])
+	}
+
+	// Closing the last step with doit_update_issue still closes the molecule.
+	closed := string(model.StatusClosed)
+	if result, _, _ = h.UpdateIssue(ctx, nil, updateIssueArgs{ID: deploy, Status: &closed}); result.IsError {
+		t.Fatalf("UpdateIssue failed: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+	result, _, _ = h.Molecule(ctx, nil, moleculeArgs{ID: m.ID})
+	if m = parse("Molecule", result); m.Status != model.StatusClosed || m.Progress.Closed != 2 {
+		t.Errorf("expected the molecule closed with its steps, got %+v", m)
+	}
+
+	if result, _, _ = h.Molecule(ctx, nil, moleculeArgs{ID: build}); !result.IsError {
+		t.Error("a step is not a molecule")
+	}
+	if result, _, _ = h.CreateMolecule(ctx, nil, createMoleculeArgs{Title: "empty", MolType: "swarm"}); !result.IsError {
+		t.Error("a molecule without steps should be rejected")
+	}
+}
+
+func TestAgents(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	parse := func(name string, result *mcp.CallToolResult) agents.Agent {
+		t.Helper()
+		if result.IsError {
+			t.Fatalf("%s failed: %s", name, result.Content[0].(*mcp.TextContent).Text)
+		}
+		var a agents.Agent
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &a); err != nil {
+			t.Fatalf("failed to parse agent: %v", err)
+		}
+		return a
+	}
+
+	result, _, _ := h.RegisterAgent(ctx, nil, registerAgentArgs{Agent: "alice", Role: "builder"})
+	a := parse("RegisterAgent", result)
+	if a.Name != "alice" || a.State != model.AgentSpawning || a.Role != "builder" {
+		t.Fatalf("expected alice spawning as a builder, got %+v", a)
+	}
+
+	issue, _ := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-hook", Title: "fix build", Status: model.StatusOpen, IssueType: model.TypeTask})
+	working, hook := "working", issue.ID
+	result, _, _ = h.AgentStatus(ctx, nil, agentStatusArgs{Agent: "alice", State: &working, Hook: &hook})
+	if a = parse("AgentStatus", result); a.State != model.AgentWorking || a.HookBead != issue.ID || a.HookTitle != "fix build" {
+		t.Errorf("expected alice working on %s, got %+v", issue.ID, a)
+	}
+	bogus := "asleep"
+	if result, _, _ = h.AgentStatus(ctx, nil, agentStatusArgs{Agent: "alice", State: &bogus}); !result.IsError {
+		t.Error("an unknown state should be rejected")
+	}
+	if result, _, _ = h.AgentStatus(ctx, nil, agentStatusArgs{Agent: "bob"}); !result.IsError {
+		t.Error("an agent that never registered should be rejected")
+	}
+
+	stopped := "stopped"
+	h.RegisterAgent(ctx, nil, registerAgentArgs{Agent: "bob"})
+	h.AgentStatus(ctx, nil, agentStatusArgs{Agent: "bob", State: &stopped})
+
+	var listed struct {
+		Count  int            `json:"count"`
+		Agents []agents.Agent `json:"agents"`
+	}
+	result, _, _ = h.ListAgents(ctx, nil, listAgentsArgs{})
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &listed); err != nil {
+		t.Fatalf("failed to parse agents: %v", err)
+	}
+	if listed.Count != 1 || listed.Agents[0].Name != "alice" {
+		t.Errorf("expected only alice to be live, got %+v", listed.Agents)
+	}
+	result, _, _ = h.ListAgents(ctx, nil, listAgentsArgs{All: true})
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &listed); err != nil {
+		t.Fatalf("failed to parse agents: %v", err)
+	}
+	if listed.Count != 2 {
+		t.Errorf("expected both agents with all=true, got %d", listed.Count)
+	}
+}
+
+func TestGates(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	parse := func(name string, result *mcp.CallToolResult) model.Issue {
+		t.Helper()
+		if result.IsError {
+			t.Fatalf("%s failed: %s", name, result.Content[0].(*mcp.TextContent).Text)
+		}
+		var issue model.Issue
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &issue); err != nil {
+			t.Fatalf("failed to parse gate: %v", err)
+		}
+		return issue
+	}
+	status := func(id string) model.Status {
+		t.Helper()
+		issue, err := ms.GetIssue(ctx, id)
+		if err != nil {
+			t.Fatalf("GetIssue: %v", err)
+		}
+		return issue.Status
+	}
+
+	deploy, _ := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-deploy", Title: "deploy", Status: model.StatusOpen, IssueType: model.TypeTask})
+	review, _ := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-review", Title: "review", Status: model.StatusOpen, IssueType: model.TypeTask})
+
+	result, _, _ := h.CreateGate(ctx, nil, createGateArgs{Title: "reviewed", AwaitType: "issue", AwaitID: review.ID, Blocks: []string{deploy.ID}})
+	onReview := parse("CreateGate(issue)", result)
+	result, _, _ = h.CreateGate(ctx, nil, createGateArgs{Title: "approved", AwaitType: "flag", Timeout: "24h", Blocks: []string{deploy.ID}})
+	approval := parse("CreateGate(flag)", result)
+	result, _, _ = h.CreateGate(ctx, nil, createGateArgs{Title: "window", AwaitType: "signal", AwaitID: "release", Blocks: []string{deploy.ID}})
+	window := parse("CreateGate(signal)", result)
+	if approval.Status != model.StatusBlocked || approval.AwaitID == "" || approval.Timeout != 24*time.Hour {
+		t.Fatalf("expected a blocked approval gate with its flag and timeout, got %+v", approval)
+	}
+	if result, _, _ = h.CreateGate(ctx, nil, createGateArgs{Title: "soon", AwaitType: "timer", Timeout: "soon"}); !result.IsError {
+		t.Error("an unparseable timeout should be rejected")
+	}
+
+	closed := string(model.StatusClosed)
+	if result, _, _ = h.UpdateIssue(ctx, nil, updateIssueArgs{ID: review.ID, Status: &closed}); result.IsError {
+		t.Fatalf("UpdateIssue failed: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+	if status(onReview.ID) != model.StatusClosed {
+		t.Error("closing the issue should open the gate waiting on it")
+	}
+
+	if result, _, _ = h.ResolveFlag(ctx, nil, resolveFlagArgs{ID: approval.AwaitID, Resolution: "ship it"}); result.IsError {
+		t.Fatalf("ResolveFlag failed: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+	if status(approval.ID) != model.StatusClosed {
+		t.Error("resolving the flag should open the approval gate")
+	}
+
+	ready, _ := ms.ListReady(ctx, model.IssueFilter{})
+	if len(ready) != 0 {
+		t.Errorf("expected deploy held back by the signal gate, got %d ready", len(ready))
+	}
+	result, _, _ = h.SignalGate(ctx, nil, signalGateArgs{Signal: "release", Note: "window open"})
+	if result.IsError {
+		t.Fatalf("SignalGate failed: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+	if !strings.Contains(result.Content[0].(*mcp.TextContent).Text, window.ID) || status(window.ID) != model.StatusClosed {
+		t.Errorf("expected the signal to open %s, got %s", window.ID, result.Content[0].(*mcp.TextContent).Text)
+	}
+	if ready, _ = ms.ListReady(ctx, model.IssueFilter{}); len(ready) != 1 || ready[0].ID != deploy.ID {
+		t.Errorf("expected deploy ready once every gate opened, got %+v", ready)
+	}
+}
+
+// TestGatesOtherClosePaths checks that closing an issue through doit_batch
+// or doit_complete_step opens the gates waiting on it at once, as
+// doit_update_issue does, rather than at the next check_gates run.
+func TestGatesOtherClosePaths(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	gateOn := func(id string) string {
+		t.Helper()
+		result, _, _ := h.CreateGate(ctx, nil, createGateArgs{Title: "after " + id, AwaitType: "issue", AwaitID: id})
+		if result.IsError {
+			t.Fatalf("CreateGate failed: %s", result.Content[0].(*mcp.TextContent).Text)
+		}
+		var gate model.Issue
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &gate); err != nil {
+			t.Fatalf("failed to parse gate: %v", err)
+		}
+		return gate.ID
+	}
+	open := func(gateID string) bool {
+		t.Helper()
+		gate, err := ms.GetIssue(ctx, gateID)
+		if err != nil {
+			t.Fatalf("GetIssue: %v", err)
+		}
+		return gate.Status == model.StatusClosed
+	}
+
+	task, _ := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-task", Title: "task", Status: model.StatusOpen, IssueType: model.TypeTask})
+	onTask := gateOn(task.ID)
+	closed := string(model.StatusClosed)
+	result, _, _ := h.Batch(ctx, nil, batchArgs{Ops: []batchOpArgs{{Op: "update", ID: task.ID, Status: &closed}}})
+	if result.IsError {
+		t.Fatalf("Batch failed: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+	if !open(onTask) {
+		t.Error("closing an issue in a batch should open the gate waiting on it")
+	}
+
+	m, err := molecule.New(ms).Create(ctx, molecule.CreateInput{Title: "release", MolType: model.MolWork, Steps: []molecule.Step{{Title: "ship"}}})
+	if err != nil {
+		t.Fatalf("Create molecule: %v", err)
+	}
+	step := m.Steps[0].ID
+	onStep, onMolecule := gateOn(step), gateOn(m.ID)
+	result, _, _ = h.CompleteStep(ctx, nil, completeStepArgs{ID: step, Agent: "alice"})
+	if result.IsError {
+		t.Fatalf("CompleteStep failed: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+	if !open(onStep) || !open(onMolecule) {
+		t.Error("completing the last step should open the gates waiting on it and on its molecule")
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	ms, ctx := storetest.MemTenant(t)
+	h := NewHandlers(ms)
+
+	root, _ := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-rel", Title: "Release {{version}}", Status: model.StatusOpen, IssueType: model.TypeEpic})
+	build, _ := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-rel.1", Title: "Build {{version}}", Status: model.StatusOpen, IssueType: model.TypeTask, ParentID: root.ID, Labels: []string{"{{team}}"}})
+	ship, _ := ms.CreateIssue(ctx, store.CreateIssueInput{ID: "doit-rel.2", Title: "Ship {{version}}", Status: model.StatusOpen, IssueType: model.TypeTask, ParentID: root.ID})
+	ms.AddDependency(ctx, store.AddDependencyInput{IssueID: ship.ID, DependsOnID: build.ID, Type: model.DepBlocks})
+
+	result, _, _ := h.MarkTemplate(ctx, nil, markTemplateArgs{ID: root.ID})
+	if result.IsError {
+		t.Fatalf("MarkTemplate failed: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+	var tmpl struct {
+		Variables []string `json:"variables"`
+	}
+	json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &tmpl)
+	if strings.Join(tmpl.Variables, ",") != "team,version" {
+		t.Errorf("expected the template to use team and version, got %v", tmpl.Variables)
+	}
+	if ready, _ := ms.ListReady(ctx, model.IssueFilter{}); len(ready) != 0 {
+		t.Errorf("expected template issues to stay out of ready, got %d", len(ready))
+	}
+	result, _, _ = h.ListTemplates(ctx, nil, listTemplatesArgs{})
+	if !strings.Contains(result.Content[0].(*mcp.TextContent).Text, `"count": 1`) {
+		t.Errorf("expected one template, got %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+
+	if result, _, _ = h.InstantiateTemplate(ctx, nil, instantiateTemplateArgs{ID: root.ID, Variables: map[string]string{"version": "2.1"}}); !result.IsError {
+		t.Error("instantiating without every variable should fail")
+	}
+	result, _, _ = h.InstantiateTemplate(ctx, nil, instantiateTemplateArgs{ID: root.ID, Variables: map[string]string{"version": "2.1", "team": "platform"}})
+	if result.IsError {
+		t.Fatalf("InstantiateTemplate failed: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+	var inst struct {
+		RootID string `json:"root_id"`
+		Issues []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"issues"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &inst); err != nil {
+		t.Fatalf("failed to parse instance: %v", err)
+	}
+	if len(inst.Issues) != 3 || inst.Issues[1].Title != "Build 2.1" {
+		t.Fatalf("expected three copies with variables filled in, got %+v", inst.Issues)
+	}
+	ready, _ := ms.ListReady(ctx, model.IssueFilter{})
+	for _, r := range ready {
+		if r.ID == inst.Issues[2].ID {
+			t.Errorf("expected the ship copy held back by the build copy, got it ready")
+		}
+	}
+	if len(ready) != 2 {
+		t.Errorf("expected the root and build copies ready, got %d issues", len(ready))
+	}
+}
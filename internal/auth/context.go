@@ -12,6 +12,7 @@ const (
 	ctxTenantID ctxKey = iota
 	ctxAdmin
 	ctxAllowedProjects
+	ctxActor
 )
 
 // WithTenant stores the tenant ID in the context.
@@ -46,3 +47,14 @@ func AllowedProjectsFromContext(ctx context.Context) []string {
 	ids, _ := ctx.Value(ctxAllowedProjects).([]string)
 	return ids
 }
+
+// WithActor records who is making the request, for the audit trail.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, ctxActor, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, or "" if none.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(ctxActor).(string)
+	return actor
+}
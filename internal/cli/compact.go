@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"context"
 	"fmt"
 	"time"
 
@@ -11,56 +10,161 @@ import (
 )
 
 func newCompactCmd() *cobra.Command {
-	var age string
+	var (
+		age           string
+		dryRun        bool
+		projectID     string
+		excludeLabels []string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "compact",
 		Short: "Run semantic compaction on old closed issues",
-		Long:  "Summarizes old closed issues to save context window tokens. Level 0→1 after threshold, Level 1→2 after 2x threshold.",
+		Long: "Summarizes old closed issues to save context window tokens. Level 0→1 after threshold, Level 1→2 after 2x threshold.\n" +
+			"A project's compaction policy replaces the threshold and can exclude labels. Pinned issues are never compacted.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			dbURL := getDBURL()
 			if dbURL == "" {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
 			threshold, err := time.ParseDuration(age)
 			if err != nil {
 				return fmt.Errorf("invalid age duration %q: %w", age, err)
 			}
 
-			compactor := compact.New(pg)
-			results, err := compactor.CompactOld(ctx, threshold)
+			report, err := compact.New(st).Run(ctx, compact.Options{
+				Age:           threshold,
+				DryRun:        dryRun,
+				ProjectID:     projectID,
+				ExcludeLabels: excludeLabels,
+			})
 			if err != nil {
 				return fmt.Errorf("compacting: %w", err)
 			}
 
 			if jsonOutput {
-				outputJSON(results)
+				outputJSON(report)
 				return nil
 			}
 
-			if len(results) == 0 {
-				fmt.Println("No issues needed compaction.")
+			if len(report.Compacted) == 0 {
+				fmt.Printf("No issues needed compaction (%d closed, %d excluded).\n", report.Scanned, report.Excluded)
 				return nil
 			}
 
-			for _, r := range results {
-				fmt.Printf("  %s: level %d → %d\n", r.IssueID, r.OldLevel, r.NewLevel)
+			for _, r := range report.Compacted {
+				if report.DryRun {
+					fmt.Printf("  %s: level %d → %d  %s\n", r.IssueID, r.OldLevel, r.NewLevel, r.Title)
+					continue
+				}
+				fmt.Printf("  %s: level %d → %d (%s)\n", r.IssueID, r.OldLevel, r.NewLevel, r.Summarizer)
+				if r.SummarizerError != "" {
+					fmt.Printf("    summarizer failed, used heuristic: %s\n", r.SummarizerError)
+				}
 			}
-			printSuccess("Compacted %d issues", len(results))
+			if report.DryRun {
+				printSuccess("Would compact %d issues (%d excluded)", len(report.Compacted), report.Excluded)
+				return nil
+			}
+			printSuccess("Compacted %d issues (%d excluded)", len(report.Compacted), report.Excluded)
 
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&age, "age", "168h", "Compaction threshold (e.g. 168h for 7 days)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List what would be compacted without changing anything")
+	cmd.Flags().StringVar(&projectID, "project", "", "Only compact this project ID's issues")
+	cmd.Flags().StringSliceVar(&excludeLabels, "exclude-label", nil, "Skip issues with this label (repeatable)")
 
+	cmd.AddCommand(newCompactRestoreCmd(), newCompactSnapshotsCmd())
 	return cmd
 }
+
+func newCompactRestoreCmd() *cobra.Command {
+	var level int
+
+	cmd := &cobra.Command{
+		Use:   "restore <id>",
+		Short: "Restore a compacted issue's content from its snapshots",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbURL := getDBURL()
+			if dbURL == "" {
+				return fmt.Errorf("DATABASE_URL not set")
+			}
+
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer st.Close()
+
+			issue, err := store.UncompactIssue(ctx, st, args[0], level)
+			if err != nil {
+				return fmt.Errorf("restoring: %w", err)
+			}
+
+			if jsonOutput {
+				outputJSON(issue)
+				return nil
+			}
+
+			printSuccess("Restored %s to compaction level %d", issue.ID, issue.CompactionLevel)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&level, "level", 0, "Compaction level to restore to (0 is full detail)")
+	return cmd
+}
+
+func newCompactSnapshotsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "snapshots <id>",
+		Short: "List the snapshots compaction took of an issue",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbURL := getDBURL()
+			if dbURL == "" {
+				return fmt.Errorf("DATABASE_URL not set")
+			}
+
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer st.Close()
+
+			snaps, err := st.GetCompactionSnapshots(ctx, args[0])
+			if err != nil {
+				return fmt.Errorf("listing snapshots: %w", err)
+			}
+
+			if jsonOutput {
+				outputJSON(snaps)
+				return nil
+			}
+
+			if len(snaps) == 0 {
+				fmt.Printf("%s has never been compacted.\n", args[0])
+				return nil
+			}
+			for _, snap := range snaps {
+				fmt.Printf("  level %d → %d at %s: %s\n", snap.FromLevel, snap.Level,
+					snap.CreatedAt.Local().Format("2006-01-02 15:04"), snap.Summary)
+			}
+			return nil
+		},
+	}
+}
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"context"
 	"fmt"
 	"time"
 
@@ -23,6 +22,7 @@ func newCreateCmd() *cobra.Command {
 		notes       string
 		labels      []string
 		ephemeral   bool
+		estimate    int
 	)
 
 	cmd := &cobra.Command{
@@ -35,25 +35,25 @@ func newCreateCmd() *cobra.Command {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
 			// Generate ID
 			var id string
 			if parent != "" {
-				id, err = pg.NextChildID(ctx, parent)
+				id, err = st.NextChildID(ctx, parent)
 			} else {
-				id, err = pg.GenerateID(ctx, "")
+				id, err = st.GenerateID(ctx, "")
 			}
 			if err != nil {
 				return fmt.Errorf("generating ID: %w", err)
 			}
 
-			issue, err := pg.CreateIssue(ctx, store.CreateIssueInput{
+			input := store.CreateIssueInput{
 				ID:                 id,
 				Title:              args[0],
 				Description:        description,
@@ -68,7 +68,11 @@ func newCreateCmd() *cobra.Command {
 				ParentID:           parent,
 				Labels:             labels,
 				Ephemeral:          ephemeral,
-			})
+			}
+			if estimate > 0 {
+				input.EstimatedMinutes = &estimate
+			}
+			issue, err := st.CreateIssue(ctx, input)
 			if err != nil {
 				return fmt.Errorf("creating issue: %w", err)
 			}
@@ -97,6 +101,7 @@ func newCreateCmd() *cobra.Command {
 	cmd.Flags().StringVar(&notes, "notes", "", "Additional notes")
 	cmd.Flags().StringSliceVar(&labels, "label", nil, "Labels (repeatable)")
 	cmd.Flags().BoolVar(&ephemeral, "ephemeral", false, "Ephemeral (excluded from export)")
+	cmd.Flags().IntVarP(&estimate, "estimate", "e", 0, "Estimated minutes of work")
 
 	return cmd
 }
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"context"
 	"fmt"
 	"time"
 
@@ -36,14 +35,14 @@ func newDepAddCmd() *cobra.Command {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
-			dep, err := pg.AddDependency(ctx, store.AddDependencyInput{
+			dep, err := st.AddDependency(ctx, store.AddDependencyInput{
 				IssueID:     args[0],
 				DependsOnID: args[1],
 				Type:        model.DependencyType(depType),
@@ -78,14 +77,14 @@ func newDepRemoveCmd() *cobra.Command {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
-			if err := pg.RemoveDependency(ctx, args[0], args[1]); err != nil {
+			if err := st.RemoveDependency(ctx, args[0], args[1]); err != nil {
 				return fmt.Errorf("removing dependency: %w", err)
 			}
 
@@ -108,14 +107,14 @@ func newDepListCmd() *cobra.Command {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
-			deps, err := pg.ListDependencies(ctx, args[0], direction)
+			deps, err := st.ListDependencies(ctx, args[0], direction)
 			if err != nil {
 				return fmt.Errorf("listing dependencies: %w", err)
 			}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/gc"
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func newGCCmd() *cobra.Command {
+	var (
+		dryRun bool
+		report bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Delete ephemeral issues and wisps past their retention",
+		Long: "Permanently deletes ephemeral issues and wisps kept longer than the tenant's gc policy allows.\n" +
+			"Wisps count from when they were closed or last updated; other ephemeral issues only once closed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbURL := getDBURL()
+			if dbURL == "" {
+				return fmt.Errorf("DATABASE_URL not set")
+			}
+
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer st.Close()
+
+			opts := gc.Options{DryRun: dryRun}
+			if cmd.Flags().Changed("report") {
+				opts.Report = &report
+			}
+			result, err := gc.New(st).Run(ctx, time.Now().UTC(), opts)
+			if err != nil {
+				return fmt.Errorf("collecting: %w", err)
+			}
+
+			if jsonOutput {
+				outputJSON(result)
+				return nil
+			}
+
+			if len(result.Collected) == 0 {
+				fmt.Printf("Nothing to collect (%d ephemeral issues).\n", result.Scanned)
+				return nil
+			}
+			for _, c := range result.Collected {
+				kind := string(c.WispType)
+				if kind == "" {
+					kind = "ephemeral"
+				}
+				fmt.Printf("  %s [%s] idle %s  %s\n", c.IssueID, kind, c.Idle, c.Title)
+			}
+			if result.DryRun {
+				printSuccess("Would delete %d of %d ephemeral issues", len(result.Collected), result.Scanned)
+				return nil
+			}
+			printSuccess("Deleted %d of %d ephemeral issues", len(result.Collected), result.Scanned)
+			if result.ReportID != "" {
+				fmt.Printf("Report: %s\n", result.ReportID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List what would be deleted without changing anything")
+	cmd.Flags().BoolVar(&report, "report", false, "Write a gc_report wisp listing what was deleted (default: the tenant's policy)")
+	return cmd
+}
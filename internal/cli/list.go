@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"context"
 	"fmt"
 	"time"
 
@@ -30,12 +29,12 @@ func newListCmd() *cobra.Command {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
 			filter := model.IssueFilter{
 				Limit:  limit,
@@ -59,7 +58,7 @@ func newListCmd() *cobra.Command {
 				filter.ProjectID = &projectID
 			}
 
-			issues, err := pg.ListIssues(ctx, filter)
+			issues, err := st.ListIssues(ctx, filter)
 			if err != nil {
 				return fmt.Errorf("listing issues: %w", err)
 			}
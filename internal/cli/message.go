@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"context"
 	"fmt"
 	"time"
 
@@ -45,19 +44,19 @@ func newMsgSendCmd() *cobra.Command {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
-			id, err := pg.GenerateID(ctx, "msg")
+			id, err := st.GenerateID(ctx, "msg")
 			if err != nil {
 				return fmt.Errorf("generating ID: %w", err)
 			}
 
-			issue, err := pg.CreateIssue(ctx, store.CreateIssueInput{
+			issue, err := st.CreateIssue(ctx, store.CreateIssueInput{
 				ID:          id,
 				Title:       truncate(args[0], 80),
 				Description: args[0],
@@ -73,7 +72,7 @@ func newMsgSendCmd() *cobra.Command {
 
 			// Thread via replies-to dependency
 			if thread != "" {
-				_, err = pg.AddDependency(ctx, store.AddDependencyInput{
+				_, err = st.AddDependency(ctx, store.AddDependencyInput{
 					IssueID:     issue.ID,
 					DependsOnID: thread,
 					Type:        model.DepRepliesTo,
@@ -116,12 +115,12 @@ func newMsgListCmd() *cobra.Command {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
 			msgType := model.TypeMessage
 			filter := model.IssueFilter{
@@ -137,7 +136,7 @@ func newMsgListCmd() *cobra.Command {
 				filter.Status = &s
 			}
 
-			issues, err := pg.ListIssues(ctx, filter)
+			issues, err := st.ListIssues(ctx, filter)
 			if err != nil {
 				return fmt.Errorf("listing messages: %w", err)
 			}
@@ -185,15 +184,15 @@ func newMsgReadCmd() *cobra.Command {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
 			s := model.StatusClosed
-			_, err = pg.UpdateIssue(ctx, args[0], store.UpdateIssueInput{
+			_, err = st.UpdateIssue(ctx, args[0], store.UpdateIssueInput{
 				Status: &s,
 			})
 			if err != nil {
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func newPlanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan <epic>",
+		Short: "Show an epic's critical path and schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbURL := getDBURL()
+			if dbURL == "" {
+				return fmt.Errorf("DATABASE_URL not set")
+			}
+
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer st.Close()
+
+			sched, err := store.CriticalPath(ctx, st, args[0], time.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("planning: %w", err)
+			}
+
+			if jsonOutput {
+				outputJSON(sched)
+				return nil
+			}
+
+			if len(sched.Tasks) == 0 {
+				fmt.Printf("%s has no unfinished tasks.\n", sched.EpicID)
+				return nil
+			}
+
+			fmt.Printf("📅 %s: %s of work on the critical path, earliest finish %s\n",
+				sched.EpicID, formatMinutes(sched.TotalMinutes), sched.EarliestFinish.Local().Format("2006-01-02 15:04"))
+			if len(sched.CriticalPath) > 0 {
+				fmt.Printf("   %s\n", strings.Join(sched.CriticalPath, " → "))
+			}
+			fmt.Println()
+
+			for _, t := range sched.Tasks {
+				mark := " "
+				if t.Critical {
+					mark = "★"
+				}
+				fmt.Printf("%s %s [%s] %s: %s, starts +%s, finishes +%s, slack %s\n",
+					mark, t.ID, t.Status, t.Title, formatMinutes(t.EstimatedMinutes),
+					formatMinutes(t.EarliestStart), formatMinutes(t.EarliestFinish), formatMinutes(t.Slack))
+				if len(t.ExternalBlockers) > 0 {
+					fmt.Printf("    also blocked outside the epic by %s\n", strings.Join(t.ExternalBlockers, ", "))
+				}
+			}
+			fmt.Println("\n★ on the critical path")
+			if len(sched.Unestimated) > 0 {
+				fmt.Printf("⚠️  %d task(s) have no estimate and count as zero: %s\n",
+					len(sched.Unestimated), strings.Join(sched.Unestimated, ", "))
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// formatMinutes renders a duration in minutes as e.g. "2h30m" or "45m".
+func formatMinutes(m int) string {
+	switch {
+	case m == 0:
+		return "0m"
+	case m%60 == 0:
+		return fmt.Sprintf("%dh", m/60)
+	case m < 60:
+		return fmt.Sprintf("%dm", m)
+	default:
+		return fmt.Sprintf("%dh%02dm", m/60, m%60)
+	}
+}
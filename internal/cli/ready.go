@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"context"
 	"fmt"
 	"time"
 
@@ -25,18 +24,18 @@ func newReadyCmd() *cobra.Command {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
 			filter := model.IssueFilter{Limit: limit}
 			if projectID != "" {
 				filter.ProjectID = &projectID
 			}
-			issues, err := pg.ListReady(ctx, filter)
+			issues, err := st.ListReady(ctx, filter)
 			if err != nil {
 				return fmt.Errorf("listing ready issues: %w", err)
 			}
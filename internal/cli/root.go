@@ -1,10 +1,12 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 
+	"github.com/Actual-Outcomes/doit/internal/auth"
 	"github.com/Actual-Outcomes/doit/internal/version"
 	"github.com/spf13/cobra"
 )
@@ -25,17 +27,22 @@ func NewRootCmd() *cobra.Command {
 
 	root.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output in JSON format")
 	root.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Suppress non-essential output")
-	root.PersistentFlags().StringVar(&dbURL, "db", "", "Database URL (default: $DATABASE_URL)")
+	root.PersistentFlags().StringVar(&dbURL, "db", "", "Database URL: postgres://..., sqlite://path or mem:// (default: $DATABASE_URL)")
 
 	root.AddCommand(newVersionCmd())
 	root.AddCommand(newCreateCmd())
 	root.AddCommand(newShowCmd())
 	root.AddCommand(newListCmd())
+	root.AddCommand(newSearchCmd())
 	root.AddCommand(newReadyCmd())
+	root.AddCommand(newWhyCmd())
 	root.AddCommand(newUpdateCmd())
 	root.AddCommand(newDepCmd())
+	root.AddCommand(newPlanCmd())
 	root.AddCommand(newMessageCmd())
 	root.AddCommand(newCompactCmd())
+	root.AddCommand(newGCCmd())
+	root.AddCommand(newTemplateCmd())
 
 	return root
 }
@@ -50,6 +57,19 @@ func getDBURL() string {
 	return ""
 }
 
+// commandContext returns the context commands run in, attributing the
+// changes they make to $DOIT_ACTOR, else the OS user.
+func commandContext() context.Context {
+	actor := os.Getenv("DOIT_ACTOR")
+	if actor == "" {
+		actor = os.Getenv("USER")
+	}
+	if actor == "" {
+		actor = "cli"
+	}
+	return auth.WithActor(context.Background(), actor)
+}
+
 func newVersionCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func newSearchCmd() *cobra.Command {
+	var (
+		status    string
+		issueType string
+		projectID string
+		limit     int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Full-text search issues and comments",
+		Long: `Search issue titles, descriptions, design, acceptance criteria, notes and
+comments. Words are ANDed, "quoted phrases" match in order and -word excludes.
+Results are ordered by relevance.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbURL := getDBURL()
+			if dbURL == "" {
+				return fmt.Errorf("DATABASE_URL not set")
+			}
+
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer st.Close()
+
+			query := model.SearchQuery{
+				Query: strings.Join(args, " "),
+				Limit: limit,
+			}
+			if status != "" {
+				s := model.Status(status)
+				query.Status = &s
+			}
+			if issueType != "" {
+				t := model.IssueType(issueType)
+				query.IssueType = &t
+			}
+			if projectID != "" {
+				query.ProjectID = &projectID
+			}
+
+			results, err := st.SearchIssues(ctx, query)
+			if err != nil {
+				return fmt.Errorf("searching issues: %w", err)
+			}
+
+			if jsonOutput {
+				outputJSON(results)
+				return nil
+			}
+
+			if len(results) == 0 {
+				fmt.Println("No matching issues.")
+				return nil
+			}
+
+			for _, r := range results {
+				fmt.Printf("%s [%s] [%.3f] %s\n", r.Issue.ID, r.Issue.Status, r.Rank, r.Issue.Title)
+				if r.Snippet != "" {
+					fmt.Printf("    %s\n", r.Snippet)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&status, "status", "s", "", "Filter by status")
+	cmd.Flags().StringVarP(&issueType, "type", "t", "", "Filter by type")
+	cmd.Flags().StringVar(&projectID, "project", "", "Filter by project ID")
+	cmd.Flags().IntVarP(&limit, "limit", "l", 20, "Max results")
+
+	return cmd
+}
@@ -1,43 +1,90 @@
 package cli
 
 import (
-	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/Actual-Outcomes/doit/internal/model"
 	"github.com/Actual-Outcomes/doit/internal/store"
 	"github.com/spf13/cobra"
 )
 
 func newShowCmd() *cobra.Command {
+	var at, since string
+
 	cmd := &cobra.Command{
 		Use:   "show <id>",
 		Short: "Show issue details",
-		Args:  cobra.ExactArgs(1),
+		Long: `Show issue details.
+
+With --at, show the issue as it stood at a past time, rebuilt from its
+audit trail. With --since, list the fields that changed between that time
+and --at (or now) instead. Times are RFC 3339 or YYYY-MM-DD.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			dbURL := getDBURL()
 			if dbURL == "" {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
-			issue, err := pg.GetIssue(ctx, args[0])
-			if err != nil {
-				return fmt.Errorf("getting issue: %w", err)
+			when := time.Now().UTC()
+			if at != "" {
+				if when, err = model.ParseHistoryTime(at); err != nil {
+					return err
+				}
 			}
 
-			if jsonOutput {
-				outputJSON(issue)
+			if since != "" {
+				from, err := model.ParseHistoryTime(since)
+				if err != nil {
+					return err
+				}
+				diff, err := store.DiffIssue(ctx, st, args[0], from, when)
+				if err != nil {
+					return fmt.Errorf("diffing issue: %w", err)
+				}
+				if jsonOutput {
+					outputJSON(diff)
+					return nil
+				}
+				printIssueDiff(diff)
 				return nil
 			}
 
+			var issue *model.Issue
+			var deps []model.Dependency
+			if at != "" {
+				version, err := store.IssueAt(ctx, st, args[0], when)
+				if err != nil {
+					return fmt.Errorf("reconstructing issue: %w", err)
+				}
+				if jsonOutput {
+					outputJSON(version)
+					return nil
+				}
+				issue, deps = &version.Issue, version.Dependencies
+				fmt.Printf("As of %s (%d later changes undone)\n", when.Local().Format("2006-01-02 15:04"), version.Undone)
+			} else {
+				issue, err = st.GetIssue(ctx, args[0])
+				if err != nil {
+					return fmt.Errorf("getting issue: %w", err)
+				}
+				if jsonOutput {
+					outputJSON(issue)
+					return nil
+				}
+				// Errors just leave the dependency list out.
+				deps, _ = st.ListDependencies(ctx, args[0], "both")
+			}
+
 			// Pretty print
 			fmt.Printf("%s: %s\n", issue.ID, issue.Title)
 			fmt.Printf("  Status:   %s\n", issue.Status)
@@ -70,8 +117,7 @@ func newShowCmd() *cobra.Command {
 			fmt.Printf("  Updated: %s\n", issue.UpdatedAt.Format(time.RFC3339))
 
 			// Show dependencies
-			deps, err := pg.ListDependencies(ctx, args[0], "both")
-			if err == nil && len(deps) > 0 {
+			if len(deps) > 0 {
 				fmt.Println("\n  Dependencies:")
 				for _, d := range deps {
 					if d.IssueID == args[0] {
@@ -86,5 +132,24 @@ func newShowCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().StringVar(&at, "at", "", "Show the issue as it stood at this time")
+	cmd.Flags().StringVar(&since, "since", "", "List the fields changed since this time")
 	return cmd
 }
+
+func printIssueDiff(diff *model.IssueDiff) {
+	const layout = "2006-01-02 15:04"
+	if len(diff.Changes) == 0 {
+		fmt.Printf("%s: no changes between %s and %s\n", diff.IssueID, diff.From.Local().Format(layout), diff.To.Local().Format(layout))
+		return
+	}
+	fmt.Printf("%s: %d fields changed between %s and %s", diff.IssueID, len(diff.Changes),
+		diff.From.Local().Format(layout), diff.To.Local().Format(layout))
+	if len(diff.Actors) > 0 {
+		fmt.Printf(" by %s", strings.Join(diff.Actors, ", "))
+	}
+	fmt.Println()
+	for _, c := range diff.Changes {
+		fmt.Printf("  %s:\n    - %s\n    + %s\n", c.Field, c.From, c.To)
+	}
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/Actual-Outcomes/doit/internal/templates"
+	"github.com/spf13/cobra"
+)
+
+func newTemplateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Manage issue templates",
+		Long: "A template is an issue tree whose fields may hold {{variables}}. Template issues are never ready;\n" +
+			"applying a template copies the tree with the variables filled in.",
+	}
+
+	cmd.AddCommand(newTemplateMarkCmd())
+	cmd.AddCommand(newTemplateListCmd())
+	cmd.AddCommand(newTemplateApplyCmd())
+
+	return cmd
+}
+
+func newTemplateMarkCmd() *cobra.Command {
+	var unmark bool
+
+	cmd := &cobra.Command{
+		Use:   "mark <root-id>",
+		Short: "Make an issue and its descendants a template",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbURL := getDBURL()
+			if dbURL == "" {
+				return fmt.Errorf("DATABASE_URL not set")
+			}
+
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer st.Close()
+
+			tmpl, err := templates.New(st).Mark(ctx, args[0], !unmark)
+			if err != nil {
+				return fmt.Errorf("marking template: %w", err)
+			}
+
+			if jsonOutput {
+				outputJSON(tmpl)
+				return nil
+			}
+			if unmark {
+				printSuccess("Unmarked %s (%d issues)", tmpl.ID, tmpl.Issues)
+				return nil
+			}
+			printSuccess("Marked %s as a template (%d issues)", tmpl.ID, tmpl.Issues)
+			if len(tmpl.Variables) > 0 {
+				fmt.Printf("  Variables: %s\n", strings.Join(tmpl.Variables, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&unmark, "unmark", false, "Turn the template back into ordinary issues")
+
+	return cmd
+}
+
+func newTemplateListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List templates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbURL := getDBURL()
+			if dbURL == "" {
+				return fmt.Errorf("DATABASE_URL not set")
+			}
+
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer st.Close()
+
+			list, err := templates.New(st).List(ctx)
+			if err != nil {
+				return fmt.Errorf("listing templates: %w", err)
+			}
+
+			if jsonOutput {
+				outputJSON(list)
+				return nil
+			}
+			if len(list) == 0 {
+				fmt.Println("No templates.")
+				return nil
+			}
+			for _, t := range list {
+				fmt.Printf("  %s  %s (%d issues)", t.ID, t.Title, t.Issues)
+				if len(t.Variables) > 0 {
+					fmt.Printf("  {{%s}}", strings.Join(t.Variables, "}} {{"))
+				}
+				fmt.Println()
+			}
+			return nil
+		},
+	}
+}
+
+func newTemplateApplyCmd() *cobra.Command {
+	var (
+		vars    []string
+		project string
+		parent  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply <template-id>",
+		Short: "Copy a template with its variables filled in",
+		Long: "Deep-copies the template tree as open issues, with its labels and the blocking dependencies\n" +
+			"between its issues. Every variable the template uses must be given with --var, and only those.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			variables := make(map[string]string, len(vars))
+			for _, v := range vars {
+				name, value, ok := strings.Cut(v, "=")
+				if !ok || strings.TrimSpace(name) == "" {
+					return fmt.Errorf("invalid --var %q: want name=value", v)
+				}
+				variables[strings.TrimSpace(name)] = value
+			}
+
+			dbURL := getDBURL()
+			if dbURL == "" {
+				return fmt.Errorf("DATABASE_URL not set")
+			}
+
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer st.Close()
+
+			inst, err := templates.New(st).Instantiate(ctx, templates.InstantiateInput{
+				TemplateID: args[0],
+				Variables:  variables,
+				ProjectID:  project,
+				ParentID:   parent,
+			})
+			if err != nil {
+				return fmt.Errorf("applying template: %w", err)
+			}
+
+			if jsonOutput {
+				outputJSON(inst)
+				return nil
+			}
+			printSuccess("Created %d issues from template %s", len(inst.Issues), inst.TemplateID)
+			for _, c := range inst.Issues {
+				fmt.Printf("  %s  %s\n", c.ID, c.Title)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Template variable as name=value (repeatable)")
+	cmd.Flags().StringVar(&project, "project", "", "Project ID for the copy (default: the parent's, else the template's)")
+	cmd.Flags().StringVar(&parent, "parent", "", "Issue ID to create the copy under")
+
+	return cmd
+}
@@ -1,11 +1,11 @@
 package cli
 
 import (
-	"context"
 	"fmt"
 	"os/user"
 	"time"
 
+	"github.com/Actual-Outcomes/doit/internal/gates"
 	"github.com/Actual-Outcomes/doit/internal/model"
 	"github.com/Actual-Outcomes/doit/internal/store"
 	"github.com/spf13/cobra"
@@ -22,6 +22,9 @@ func newUpdateCmd() *cobra.Command {
 		claim       bool
 		pinned      bool
 		notes       string
+		estimate    int
+		ifHash      string
+		ifUpdatedAt string
 	)
 
 	cmd := &cobra.Command{
@@ -34,12 +37,12 @@ func newUpdateCmd() *cobra.Command {
 				return fmt.Errorf("DATABASE_URL not set")
 			}
 
-			ctx := context.Background()
-			pg, err := store.NewPgStore(ctx, dbURL, 10*time.Second, "")
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
-			defer pg.Close()
+			defer st.Close()
 
 			input := store.UpdateIssueInput{}
 
@@ -80,11 +83,29 @@ func newUpdateCmd() *cobra.Command {
 			if cmd.Flags().Changed("notes") {
 				input.Notes = &notes
 			}
+			if cmd.Flags().Changed("estimate") {
+				input.EstimatedMinutes = &estimate
+			}
+			if cmd.Flags().Changed("if-hash") {
+				input.ExpectedContentHash = &ifHash
+			}
+			if cmd.Flags().Changed("if-updated-at") {
+				t, err := time.Parse(time.RFC3339Nano, ifUpdatedAt)
+				if err != nil {
+					return fmt.Errorf("invalid --if-updated-at %q: want RFC 3339", ifUpdatedAt)
+				}
+				input.ExpectedUpdatedAt = &t
+			}
 
-			issue, err := pg.UpdateIssue(ctx, args[0], input)
+			issue, err := st.UpdateIssue(ctx, args[0], input)
 			if err != nil {
 				return fmt.Errorf("updating issue: %w", err)
 			}
+			if issue.Status == model.StatusClosed && input.Status != nil {
+				if _, err := gates.New(st).IssueClosed(ctx, issue.ID); err != nil {
+					return fmt.Errorf("%s was closed, but opening the gates waiting on it failed: %w", issue.ID, err)
+				}
+			}
 
 			if jsonOutput {
 				outputJSON(issue)
@@ -105,6 +126,9 @@ func newUpdateCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&claim, "claim", false, "Atomically claim (sets assignee + in_progress)")
 	cmd.Flags().BoolVar(&pinned, "pinned", false, "Pin/unpin issue")
 	cmd.Flags().StringVar(&notes, "notes", "", "Additional notes")
+	cmd.Flags().IntVarP(&estimate, "estimate", "e", 0, "Estimated minutes of work (0 clears)")
+	cmd.Flags().StringVar(&ifHash, "if-hash", "", "Only update if the issue's content hash still matches")
+	cmd.Flags().StringVar(&ifUpdatedAt, "if-updated-at", "", "Only update if the issue's updated_at (RFC 3339) still matches")
 
 	return cmd
 }
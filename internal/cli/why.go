@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func newWhyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "why <id>",
+		Short: "Explain why an issue is or is not ready",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbURL := getDBURL()
+			if dbURL == "" {
+				return fmt.Errorf("DATABASE_URL not set")
+			}
+
+			ctx := commandContext()
+			st, err := store.Open(ctx, dbURL, 10*time.Second, "")
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer st.Close()
+
+			exp, err := store.ExplainReady(ctx, st, args[0])
+			if err != nil {
+				return fmt.Errorf("explaining readiness: %w", err)
+			}
+
+			if jsonOutput {
+				outputJSON(exp)
+				return nil
+			}
+
+			if exp.Ready {
+				fmt.Printf("✅ %s is ready for work.\n", exp.IssueID)
+				return nil
+			}
+
+			fmt.Printf("⛔ %s is not ready:\n\n", exp.IssueID)
+			for _, r := range exp.Reasons {
+				fmt.Printf("- [%s] %s\n", r.Kind, r.Message)
+				printBlockers(r.Blockers, 1)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// printBlockers prints a blocker chain as an indented tree.
+func printBlockers(blockers []model.Blocker, depth int) {
+	indent := strings.Repeat("    ", depth)
+	for _, b := range blockers {
+		line := fmt.Sprintf("%s└─ %s [%s] %s", indent, b.ID, b.Status, b.Title)
+		if b.Assignee != "" {
+			line += " @" + b.Assignee
+		}
+		if b.Via != model.DepBlocks {
+			line += fmt.Sprintf(" (via %s)", b.Via)
+		}
+		if b.Repeated {
+			line += " (see above)"
+		}
+		fmt.Println(line)
+		printBlockers(b.BlockedBy, depth+1)
+	}
+}
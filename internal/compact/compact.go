@@ -5,18 +5,24 @@
 //   - 0: Full detail (original content preserved)
 //   - 1: Summarized — description/design/notes replaced with a short summary
 //   - 2: Minimal — only title, status, and key metadata retained
+//
+// Pinned issues are never compacted. Projects can set their own age and
+// labels to exclude with a model.CompactionPolicy.
 package compact
 
 import (
 	"context"
 	"fmt"
-	"strings"
+	"slices"
 	"time"
 
 	"github.com/Actual-Outcomes/doit/internal/model"
 	"github.com/Actual-Outcomes/doit/internal/store"
 )
 
+// pageSize is how many closed issues a run loads at a time.
+const pageSize = 200
+
 // Compactor manages the memory decay process.
 type Compactor struct {
 	store store.Store
@@ -26,122 +32,214 @@ func New(s store.Store) *Compactor {
 	return &Compactor{store: s}
 }
 
+// Options control a compaction run.
+type Options struct {
+	// Age is how long an issue stays closed before it is compacted to
+	// level 1; level 2 follows at twice that. Project policies override it.
+	Age           time.Duration
+	DryRun        bool     // report what would be compacted, changing nothing
+	ProjectID     string   // only compact this project's issues
+	ExcludeLabels []string // skip issues with any of these, besides those their project excludes
+}
+
+// Report is what a compaction run did or, on a dry run, would do.
+type Report struct {
+	DryRun    bool            `json:"dry_run"`
+	Scanned   int             `json:"scanned"`  // closed issues looked at
+	Excluded  int             `json:"excluded"` // due for compaction, but pinned or carrying an excluded label
+	Compacted []CompactResult `json:"compacted"`
+}
+
 // CompactResult reports what was done.
 type CompactResult struct {
-	IssueID string `json:"issue_id"`
-	OldLevel int   `json:"old_level"`
-	NewLevel int   `json:"new_level"`
+	IssueID    string `json:"issue_id"`
+	Title      string `json:"title"`
+	OldLevel   int    `json:"old_level"`
+	NewLevel   int    `json:"new_level"`
+	Summarizer string `json:"summarizer,omitempty"` // empty on a dry run
+	// SummarizerError is why the tenant's summarizer failed, when the
+	// heuristic one wrote the summary in its place.
+	SummarizerError string `json:"summarizer_error,omitempty"`
 }
 
-// CompactOld finds closed issues older than the threshold and bumps their compaction level.
-// Level 0 → 1 after closedAge; Level 1 → 2 after 2*closedAge.
-func (c *Compactor) CompactOld(ctx context.Context, closedAge time.Duration) ([]CompactResult, error) {
-	closedStatus := model.StatusClosed
-	issues, err := c.store.ListIssues(ctx, model.IssueFilter{
-		Status: &closedStatus,
-		Limit:  500,
-		SortBy: "oldest",
-	})
-	if err != nil {
-		return nil, fmt.Errorf("listing closed issues: %w", err)
+// Run pages through every closed issue of the tenant in ctx and compacts
+// those closed long enough: to level 1 after their age, to level 2 after
+// twice it. Each compaction replaces the issue's content with a summary
+// from the tenant's summarizer and, in the same transaction, saves a
+// snapshot, raises its compaction level and records a compacted event.
+func (c *Compactor) Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Age <= 0 {
+		return nil, fmt.Errorf("compaction age must be positive, got %s", opts.Age)
 	}
-
-	now := time.Now().UTC()
-	var results []CompactResult
-
-	for _, issue := range issues {
-		if issue.ClosedAt == nil {
-			continue
+	var (
+		settings   *model.CompactionSettings
+		summarizer Summarizer
+	)
+	if !opts.DryRun {
+		var err error
+		settings, err = c.store.GetCompactionSettings(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting compaction settings: %w", err)
 		}
-
-		age := now.Sub(*issue.ClosedAt)
-		targetLevel := 0
-		if age > 2*closedAge {
-			targetLevel = 2
-		} else if age > closedAge {
-			targetLevel = 1
-		}
-
-		if targetLevel <= issue.CompactionLevel {
-			continue
+		summarizer, err = NewSummarizer(settings)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		// Snapshot original before compacting
-		original := formatOriginal(&issue)
-		summary := generateSummary(&issue, targetLevel)
-
-		if err := c.store.SaveCompactionSnapshot(ctx, issue.ID, targetLevel, summary, original); err != nil {
-			return nil, fmt.Errorf("saving snapshot for %s: %w", issue.ID, err)
+	policies := make(map[string]*model.CompactionPolicy)
+	policyFor := func(projectID string) (*model.CompactionPolicy, error) {
+		if projectID == "" {
+			return nil, nil
 		}
-
-		// Update the issue with compacted content
-		input := store.UpdateIssueInput{}
-		desc := summary
-		input.Description = &desc
-		empty := ""
-		input.Design = &empty
-		input.Notes = &empty
-		if targetLevel == 2 {
-			input.AcceptanceCriteria = &empty
+		if p, ok := policies[projectID]; ok {
+			return p, nil
 		}
-
-		if _, err := c.store.UpdateIssue(ctx, issue.ID, input); err != nil {
-			return nil, fmt.Errorf("updating %s: %w", issue.ID, err)
+		p, err := c.store.GetCompactionPolicy(ctx, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("getting compaction policy of project %s: %w", projectID, err)
 		}
+		policies[projectID] = p
+		return p, nil
+	}
 
-		results = append(results, CompactResult{
-			IssueID:  issue.ID,
-			OldLevel: issue.CompactionLevel,
-			NewLevel: targetLevel,
-		})
+	closedStatus := model.StatusClosed
+	filter := model.IssueFilter{
+		Status: &closedStatus,
+		Limit:  pageSize,
+		SortBy: "oldest",
+	}
+	if opts.ProjectID != "" {
+		filter.ProjectID = &opts.ProjectID
 	}
 
-	return results, nil
-}
+	report := &Report{DryRun: opts.DryRun, Compacted: []CompactResult{}}
+	now := time.Now().UTC()
+	for {
+		issues, err := c.store.ListIssues(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("listing closed issues: %w", err)
+		}
+		for _, issue := range issues {
+			report.Scanned++
+			if issue.ClosedAt == nil {
+				continue
+			}
+			policy, err := policyFor(issue.ProjectID)
+			if err != nil {
+				return nil, err
+			}
+			targetLevel, err := dueLevel(now.Sub(*issue.ClosedAt), opts.Age, policy)
+			if err != nil {
+				return nil, err
+			}
+			if targetLevel <= issue.CompactionLevel {
+				continue
+			}
+			excluded, err := c.excluded(ctx, &issue, opts.ExcludeLabels, policy)
+			if err != nil {
+				return nil, err
+			}
+			if excluded {
+				report.Excluded++
+				continue
+			}
 
-// generateSummary creates a compact summary of the issue.
-func generateSummary(issue *model.Issue, level int) string {
-	var parts []string
-	parts = append(parts, fmt.Sprintf("[%s] %s", issue.IssueType, issue.Title))
-
-	if level == 1 {
-		// Keep a sentence or two from description
-		if issue.Description != "" {
-			lines := strings.SplitN(issue.Description, "\n", 3)
-			if len(lines) > 2 {
-				parts = append(parts, strings.Join(lines[:2], " "))
-			} else {
-				parts = append(parts, issue.Description)
+			result := CompactResult{
+				IssueID:  issue.ID,
+				Title:    issue.Title,
+				OldLevel: issue.CompactionLevel,
+				NewLevel: targetLevel,
 			}
+			if !opts.DryRun {
+				if err := c.compact(ctx, &issue, targetLevel, settings, summarizer, &result); err != nil {
+					return nil, err
+				}
+			}
+			report.Compacted = append(report.Compacted, result)
 		}
-		if issue.CloseReason != "" {
-			parts = append(parts, fmt.Sprintf("Closed: %s", issue.CloseReason))
-		}
-	} else {
-		// Level 2: minimal
-		if issue.CloseReason != "" {
-			parts = append(parts, fmt.Sprintf("Closed: %s", issue.CloseReason))
+		if len(issues) < pageSize {
+			return report, nil
 		}
+		filter.Cursor = store.IssueCursor(&issues[len(issues)-1], filter.SortBy)
 	}
+}
 
-	return strings.Join(parts, " | ")
+// dueLevel is the compaction level an issue closed for closedFor has
+// reached, under its project's policy or else the run's age.
+func dueLevel(closedFor, age time.Duration, policy *model.CompactionPolicy) (int, error) {
+	if policy != nil && policy.Age != "" {
+		var err error
+		if age, err = time.ParseDuration(policy.Age); err != nil {
+			return 0, fmt.Errorf("project %s has an invalid compaction age %q: %w", policy.ProjectID, policy.Age, err)
+		}
+	}
+	switch {
+	case closedFor > 2*age:
+		return 2, nil
+	case closedFor > age:
+		return 1, nil
+	}
+	return 0, nil
 }
 
-// formatOriginal captures the full issue content for snapshot preservation.
-func formatOriginal(issue *model.Issue) string {
-	var b strings.Builder
-	b.WriteString(fmt.Sprintf("Title: %s\n", issue.Title))
-	if issue.Description != "" {
-		b.WriteString(fmt.Sprintf("Description: %s\n", issue.Description))
+// excluded reports whether issue is kept at full detail: it is pinned, or
+// carries a label the run or its project's policy excludes.
+func (c *Compactor) excluded(ctx context.Context, issue *model.Issue, runLabels []string, policy *model.CompactionPolicy) (bool, error) {
+	if issue.Pinned {
+		return true, nil
+	}
+	excludedLabels := runLabels
+	if policy != nil {
+		excludedLabels = append(slices.Clip(excludedLabels), policy.ExcludeLabels...)
+	}
+	if len(excludedLabels) == 0 {
+		return false, nil
+	}
+	// Listed issues do not carry their labels with every backend.
+	labels, err := c.store.ListLabels(ctx, issue.ID)
+	if err != nil {
+		return false, fmt.Errorf("listing labels of %s: %w", issue.ID, err)
+	}
+	for _, l := range labels {
+		if slices.Contains(excludedLabels, l) {
+			return true, nil
+		}
 	}
-	if issue.Design != "" {
-		b.WriteString(fmt.Sprintf("Design: %s\n", issue.Design))
+	return false, nil
+}
+
+// compact snapshots issue and replaces its content with a summary at
+// targetLevel, filling in the summarizer that wrote it on result.
+func (c *Compactor) compact(ctx context.Context, issue *model.Issue, targetLevel int, settings *model.CompactionSettings, summarizer Summarizer, result *CompactResult) error {
+	result.Summarizer = settings.Summarizer
+
+	// Snapshot original before compacting
+	original := model.CompactionOriginal(issue)
+	budget := settings.Budgets.For(targetLevel)
+	summary, err := summarizer.Summarize(ctx, issue, targetLevel, budget)
+	if err != nil {
+		// A summarizer that calls out can be down; the heuristic
+		// one cannot fail, so compaction still goes ahead.
+		result.Summarizer, result.SummarizerError = model.SummarizerHeuristic, err.Error()
+		summary, _ = Heuristic{}.Summarize(ctx, issue, targetLevel, budget)
 	}
-	if issue.AcceptanceCriteria != "" {
-		b.WriteString(fmt.Sprintf("Acceptance Criteria: %s\n", issue.AcceptanceCriteria))
+	summary = truncate(summary, budget)
+
+	// The compacted content replaces the original in the same
+	// transaction that snapshots it.
+	input := store.UpdateIssueInput{}
+	desc := summary
+	input.Description = &desc
+	empty := ""
+	input.Design = &empty
+	input.Notes = &empty
+	if targetLevel == 2 {
+		input.AcceptanceCriteria = &empty
 	}
-	if issue.Notes != "" {
-		b.WriteString(fmt.Sprintf("Notes: %s\n", issue.Notes))
+
+	if err := c.store.SaveCompactionSnapshot(ctx, issue.ID, targetLevel, summary, original, input); err != nil {
+		return fmt.Errorf("compacting %s: %w", issue.ID, err)
 	}
-	return b.String()
+	return nil
 }
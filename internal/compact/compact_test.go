@@ -0,0 +1,151 @@
+package compact
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+func TestRun(t *testing.T) {
+	s := store.NewMemStore("doit")
+	tenant, err := s.CreateTenant(context.Background(), "acme", "acme")
+	if err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+	ctx := auth.WithTenant(context.Background(), tenant.ID)
+
+	project := func(slug string, policy *model.CompactionPolicy) string {
+		t.Helper()
+		p, err := s.CreateProject(ctx, slug, slug)
+		if err != nil {
+			t.Fatalf("CreateProject: %v", err)
+		}
+		if _, err := s.SetCompactionPolicy(ctx, p.ID.String(), policy); err != nil {
+			t.Fatalf("SetCompactionPolicy: %v", err)
+		}
+		return p.ID.String()
+	}
+	slow := project("slow", &model.CompactionPolicy{Age: "1h"})
+	legal := project("legal", &model.CompactionPolicy{ExcludeLabels: []string{"hold"}})
+
+	create := func(input store.CreateIssueInput, closed bool) string {
+		t.Helper()
+		id, err := s.GenerateID(ctx, "")
+		if err != nil {
+			t.Fatalf("GenerateID: %v", err)
+		}
+		input.ID, input.Status, input.IssueType = id, model.StatusOpen, model.TypeTask
+		if input.Title == "" {
+			input.Title = "done"
+		}
+		input.Description = "Long write-up of the work."
+		if _, err := s.CreateIssue(ctx, input); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+		if closed {
+			status := model.StatusClosed
+			if _, err := s.UpdateIssue(ctx, id, store.UpdateIssueInput{Status: &status}); err != nil {
+				t.Fatalf("closing: %v", err)
+			}
+		}
+		return id
+	}
+
+	// More than a page of plain closed issues.
+	var plain []string
+	for range pageSize + 5 {
+		plain = append(plain, create(store.CreateIssueInput{}, true))
+	}
+	pinned := create(store.CreateIssueInput{Title: "pinned"}, true)
+	pin := true
+	if _, err := s.UpdateIssue(ctx, pinned, store.UpdateIssueInput{Pinned: &pin}); err != nil {
+		t.Fatalf("pinning: %v", err)
+	}
+	notDue := create(store.CreateIssueInput{Title: "slow project", ProjectID: slow}, true)
+	held := create(store.CreateIssueInput{Title: "on hold", ProjectID: legal, Labels: []string{"hold"}}, true)
+	legalDone := create(store.CreateIssueInput{Title: "legal", ProjectID: legal}, true)
+	skipped := create(store.CreateIssueInput{Title: "run-excluded", Labels: []string{"keep"}}, true)
+	open := create(store.CreateIssueInput{Title: "open"}, false)
+	time.Sleep(time.Millisecond)
+
+	run := func(opts Options) *Report {
+		t.Helper()
+		opts.Age, opts.ExcludeLabels = time.Nanosecond, []string{"keep"}
+		report, err := New(s).Run(ctx, opts)
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return report
+	}
+	level := func(id string) int {
+		t.Helper()
+		issue, err := s.GetIssue(ctx, id)
+		if err != nil {
+			t.Fatalf("GetIssue: %v", err)
+		}
+		return issue.CompactionLevel
+	}
+	const closed = pageSize + 10 // every issue but the open one
+
+	report := run(Options{DryRun: true})
+	if !report.DryRun || report.Scanned != closed || report.Excluded != 3 || len(report.Compacted) != pageSize+6 {
+		t.Fatalf("dry run = scanned %d, excluded %d, compacted %d; want %d, 3, %d",
+			report.Scanned, report.Excluded, len(report.Compacted), closed, pageSize+6)
+	}
+	if r := report.Compacted[0]; r.NewLevel != 2 || r.Summarizer != "" {
+		t.Errorf("dry run result = %+v, want level 2 and no summarizer", r)
+	}
+	if level(plain[0]) != 0 {
+		t.Error("a dry run should not compact anything")
+	}
+	if snaps, _ := s.GetCompactionSnapshots(ctx, plain[0]); len(snaps) != 0 {
+		t.Errorf("a dry run saved %d snapshots", len(snaps))
+	}
+
+	report = run(Options{})
+	if report.DryRun || report.Scanned != closed || report.Excluded != 3 || len(report.Compacted) != pageSize+6 {
+		t.Fatalf("run = scanned %d, excluded %d, compacted %d; want %d, 3, %d",
+			report.Scanned, report.Excluded, len(report.Compacted), closed, pageSize+6)
+	}
+	for _, id := range []string{plain[0], plain[len(plain)-1], legalDone} {
+		issue, _ := s.GetIssue(ctx, id)
+		if issue.CompactionLevel != 2 || issue.CompactedAt == nil || issue.Description == "Long write-up of the work." {
+			t.Errorf("%s: level %d, compacted at %v, description %q; want compacted to 2", id, issue.CompactionLevel, issue.CompactedAt, issue.Description)
+		}
+	}
+	for _, id := range []string{pinned, notDue, held, skipped, open} {
+		if l := level(id); l != 0 {
+			t.Errorf("%s was compacted to level %d", id, l)
+		}
+	}
+	events, err := s.ListEvents(ctx, plain[0], 0)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	var compacted int
+	for _, e := range events {
+		if e.EventType == model.EventCompacted {
+			compacted++
+		}
+	}
+	if compacted != 1 {
+		t.Errorf("recorded %d compacted events, want 1", compacted)
+	}
+
+	// The level sticks, so a second run has nothing left to do.
+	if report := run(Options{}); len(report.Compacted) != 0 {
+		t.Errorf("second run compacted %d issues again", len(report.Compacted))
+	}
+	if snaps, _ := s.GetCompactionSnapshots(ctx, plain[0]); len(snaps) != 1 {
+		t.Errorf("%s has %d snapshots after two runs, want 1", plain[0], len(snaps))
+	}
+
+	// Scoped to one project, only its issues are looked at.
+	if report := run(Options{ProjectID: slow, DryRun: true}); report.Scanned != 1 || len(report.Compacted) != 0 {
+		t.Errorf("slow project run = %+v, want one issue scanned and none due", report)
+	}
+}
@@ -0,0 +1,351 @@
+package compact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// Summarizer writes the summary that replaces an issue's content when it is
+// compacted to level. Summaries longer than budget characters are cut to
+// fit by the compactor.
+type Summarizer interface {
+	Summarize(ctx context.Context, issue *model.Issue, level, budget int) (string, error)
+}
+
+// NewSummarizer returns the summarizer settings select.
+func NewSummarizer(settings *model.CompactionSettings) (Summarizer, error) {
+	switch settings.Summarizer {
+	case "", model.SummarizerHeuristic:
+		return Heuristic{}, nil
+	case model.SummarizerExtractive:
+		return Extractive{}, nil
+	case model.SummarizerEndpoint:
+		if settings.Endpoint == nil {
+			return nil, fmt.Errorf("the %s summarizer needs an endpoint", model.SummarizerEndpoint)
+		}
+		return NewEndpoint(*settings.Endpoint)
+	}
+	return nil, fmt.Errorf("unknown summarizer %q", settings.Summarizer)
+}
+
+// Heuristic keeps the type and title, at level 1 the first two lines of the
+// description, and the close reason.
+type Heuristic struct{}
+
+func (Heuristic) Summarize(_ context.Context, issue *model.Issue, level, budget int) (string, error) {
+	parts := []string{header(issue)}
+	if level == 1 && issue.Description != "" {
+		// Keep a sentence or two from description
+		lines := strings.SplitN(issue.Description, "\n", 3)
+		if len(lines) > 2 {
+			parts = append(parts, strings.Join(lines[:2], " "))
+		} else {
+			parts = append(parts, issue.Description)
+		}
+	}
+	if issue.CloseReason != "" {
+		parts = append(parts, fmt.Sprintf("Closed: %s", issue.CloseReason))
+	}
+	return truncate(strings.Join(parts, " | "), budget), nil
+}
+
+// Extractive keeps the sentences of the description (and, at level 1, the
+// design and notes) that say the most about the issue, plus which of its
+// acceptance criteria were met and the close reason. Sentences are scored
+// by how many title words they share, decision and outcome cue words and
+// position; those with none of these are dropped, and the rest kept in
+// their original order while the budget allows.
+type Extractive struct{}
+
+func (Extractive) Summarize(_ context.Context, issue *model.Issue, level, budget int) (string, error) {
+	fixed := []string{header(issue)}
+	if c := criteriaOutcome(issue.AcceptanceCriteria, level); c != "" {
+		fixed = append(fixed, c)
+	}
+	if issue.CloseReason != "" {
+		fixed = append(fixed, fmt.Sprintf("Closed: %s", issue.CloseReason))
+	}
+
+	fields := []string{issue.Description}
+	if level == 1 {
+		fields = append(fields, issue.Design, issue.Notes)
+	}
+	var sentences []scoredSentence
+	titleWords := words(issue.Title)
+	for _, f := range fields {
+		for i, s := range splitSentences(f) {
+			sentences = append(sentences, scoredSentence{
+				text:  s,
+				order: len(sentences),
+				score: scoreSentence(s, i, titleWords),
+			})
+		}
+	}
+
+	// Room left for sentences once the fixed parts and separators are in.
+	room := budget - utf8.RuneCountInString(strings.Join(fixed, " | ")) - len(" | ")
+	byScore := append([]scoredSentence(nil), sentences...)
+	sort.SliceStable(byScore, func(a, b int) bool { return byScore[a].score > byScore[b].score })
+	var kept []scoredSentence
+	for _, s := range byScore {
+		n := utf8.RuneCountInString(s.text) + 1
+		if s.score <= 0 || n > room {
+			continue
+		}
+		kept = append(kept, s)
+		room -= n
+	}
+	sort.Slice(kept, func(a, b int) bool { return kept[a].order < kept[b].order })
+
+	parts := fixed[:1:1]
+	if len(kept) > 0 {
+		texts := make([]string, len(kept))
+		for i, s := range kept {
+			texts[i] = s.text
+		}
+		parts = append(parts, strings.Join(texts, " "))
+	}
+	parts = append(parts, fixed[1:]...)
+	return truncate(strings.Join(parts, " | "), budget), nil
+}
+
+type scoredSentence struct {
+	text  string
+	order int
+	score int
+}
+
+// cueWords mark sentences that record a decision, a cause or an outcome.
+var cueWords = map[string]bool{
+	"because": true, "cause": true, "caused": true, "decided": true, "decision": true,
+	"fixed": true, "fixes": true, "instead": true, "must": true, "never": true,
+	"only": true, "resolved": true, "result": true, "root": true, "should": true,
+	"so": true, "therefore": true, "workaround": true,
+}
+
+func scoreSentence(s string, position int, titleWords map[string]bool) int {
+	ws := words(s)
+	if len(ws) < 3 {
+		return 0
+	}
+	score := 0
+	if position == 0 {
+		score += 2
+	}
+	for w := range ws {
+		if titleWords[w] {
+			score += 2
+		}
+		if cueWords[w] {
+			score++
+		}
+	}
+	return score
+}
+
+var (
+	sentenceEnd  = regexp.MustCompile(`([.!?])\s+`)
+	wordPattern  = regexp.MustCompile(`[\p{L}\p{N}]+`)
+	checklistRow = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])?\s*\[([ xX])\]\s*(.+)$`)
+	bulletPrefix = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s*`)
+)
+
+// splitSentences splits text into sentences at sentence punctuation and
+// line breaks, dropping list markers and headings' hashes.
+func splitSentences(text string) []string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimLeft(bulletPrefix.ReplaceAllString(line, ""), "# ")
+		for _, s := range strings.Split(sentenceEnd.ReplaceAllString(line, "$1\n"), "\n") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func words(s string) map[string]bool {
+	ws := make(map[string]bool)
+	for _, w := range wordPattern.FindAllString(strings.ToLower(s), -1) {
+		ws[w] = true
+	}
+	return ws
+}
+
+// criteriaOutcome reports which checklist items ("[x]" or "[ ]") of the
+// acceptance criteria were met: by name at level 1, as a count at level 2.
+// Criteria without checkboxes have no recorded outcome and are left out.
+func criteriaOutcome(criteria string, level int) string {
+	var met, unmet []string
+	for _, line := range strings.Split(criteria, "\n") {
+		m := checklistRow.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if m[1] == " " {
+			unmet = append(unmet, strings.TrimSpace(m[2]))
+		} else {
+			met = append(met, strings.TrimSpace(m[2]))
+		}
+	}
+	total := len(met) + len(unmet)
+	if total == 0 {
+		return ""
+	}
+	if level > 1 {
+		return fmt.Sprintf("Criteria: %d/%d met", len(met), total)
+	}
+	var parts []string
+	if len(met) > 0 {
+		parts = append(parts, "Met: "+strings.Join(met, "; "))
+	}
+	if len(unmet) > 0 {
+		parts = append(parts, "Not met: "+strings.Join(unmet, "; "))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// Endpoint asks an OpenAI-compatible chat completions API for the summary.
+type Endpoint struct {
+	URL    string // base URL; /chat/completions is appended
+	Model  string
+	APIKey string // sent as a bearer token when set
+	Client *http.Client
+}
+
+// NewEndpoint returns an Endpoint for cfg, reading its API key from the
+// environment variable cfg names. Only DOIT_SUMMARIZER_* variables are
+// read; any other name is an error rather than a secret sent to the
+// tenant's endpoint.
+func NewEndpoint(cfg model.CompactionEndpoint) (*Endpoint, error) {
+	e := &Endpoint{
+		URL:    cfg.URL,
+		Model:  cfg.Model,
+		Client: &http.Client{Timeout: 60 * time.Second},
+	}
+	if cfg.APIKeyEnv != "" {
+		if !model.ValidKeyEnv(cfg.APIKeyEnv) {
+			return nil, fmt.Errorf("summarizer key variable %q is not allowed: its name must start with %s", cfg.APIKeyEnv, model.SummarizerKeyEnvPrefix)
+		}
+		e.APIKey = os.Getenv(cfg.APIKeyEnv)
+	}
+	return e, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model     string        `json:"model"`
+	Messages  []chatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (e *Endpoint) Summarize(ctx context.Context, issue *model.Issue, level, budget int) (string, error) {
+	detail := "what was done, the outcome and anything a future reader must know"
+	if level > 1 {
+		detail = "only what was done and the outcome"
+	}
+	body, err := json.Marshal(chatRequest{
+		Model: e.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: fmt.Sprintf(
+				"You compact closed issues from an issue tracker. Summarize the issue in at most %d characters: %s. Reply with the summary only.",
+				budget, detail)},
+			{Role: "user", Content: issuePrompt(issue)},
+		},
+		// About four characters per token, with headroom for the model
+		// to finish its sentence; the summary is cut to budget anyway.
+		MaxTokens: budget/4 + 32,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding summarizer request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.URL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("building summarizer request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("calling summarizer endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("summarizer endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var out chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decoding summarizer response: %w", err)
+	}
+	if len(out.Choices) == 0 || strings.TrimSpace(out.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("summarizer endpoint returned no summary")
+	}
+	return truncate(strings.TrimSpace(out.Choices[0].Message.Content), budget), nil
+}
+
+// issuePrompt is the issue as the endpoint summarizer sees it.
+func issuePrompt(issue *model.Issue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Type: %s\n", issue.IssueType)
+	if issue.CloseReason != "" {
+		fmt.Fprintf(&b, "Close reason: %s\n", issue.CloseReason)
+	}
+	fmt.Fprintf(&b, "Title: %s\n", issue.Title)
+	for _, f := range []struct{ name, value string }{
+		{"Description", issue.Description},
+		{"Design", issue.Design},
+		{"Acceptance criteria", issue.AcceptanceCriteria},
+		{"Notes", issue.Notes},
+	} {
+		if f.value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", f.name, f.value)
+		}
+	}
+	return b.String()
+}
+
+func header(issue *model.Issue) string {
+	return fmt.Sprintf("[%s] %s", issue.IssueType, issue.Title)
+}
+
+// truncate cuts s to at most budget characters, ending it with an
+// ellipsis. A budget of zero or less leaves s alone.
+func truncate(s string, budget int) string {
+	if budget <= 0 || utf8.RuneCountInString(s) <= budget {
+		return s
+	}
+	r := []rune(s)
+	return strings.TrimSpace(string(r[:budget-1])) + "…"
+}
@@ -0,0 +1,223 @@
+package compact
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+func testIssue() *model.Issue {
+	return &model.Issue{
+		ID:        "doit-abc",
+		Title:     "Fix login redirect loop",
+		IssueType: model.TypeBug,
+		Description: "Users were bounced between /login and /home.\n" +
+			"It started last week.\n" +
+			"The login redirect loop happened because the session cookie lacked SameSite.\n" +
+			"Thanks to everyone who reported it.",
+		AcceptanceCriteria: "- [x] Login works in Safari\n- [x] Regression test\n- [ ] Docs updated",
+		CloseReason:        "fixed in 1.4.2",
+	}
+}
+
+func TestHeuristic(t *testing.T) {
+	ctx := context.Background()
+	got, err := Heuristic{}.Summarize(ctx, testIssue(), 1, 0)
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	want := "[bug] Fix login redirect loop | Users were bounced between /login and /home. It started last week. | Closed: fixed in 1.4.2"
+	if got != want {
+		t.Errorf("level 1 = %q, want %q", got, want)
+	}
+
+	got, _ = Heuristic{}.Summarize(ctx, testIssue(), 2, 0)
+	if want := "[bug] Fix login redirect loop | Closed: fixed in 1.4.2"; got != want {
+		t.Errorf("level 2 = %q, want %q", got, want)
+	}
+
+	got, _ = Heuristic{}.Summarize(ctx, testIssue(), 1, 20)
+	if utf8.RuneCountInString(got) != 20 || !strings.HasSuffix(got, "…") {
+		t.Errorf("budget 20 = %q, want 20 characters ending in an ellipsis", got)
+	}
+}
+
+func TestExtractive(t *testing.T) {
+	ctx := context.Background()
+	got, err := Extractive{}.Summarize(ctx, testIssue(), 1, 400)
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	for _, want := range []string{
+		"[bug] Fix login redirect loop",
+		"because the session cookie lacked SameSite",
+		"Met: Login works in Safari; Regression test",
+		"Not met: Docs updated",
+		"Closed: fixed in 1.4.2",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("level 1 summary %q lacks %q", got, want)
+		}
+	}
+
+	// A tight budget keeps the title, outcome and best sentence only.
+	got, _ = Extractive{}.Summarize(ctx, testIssue(), 2, 160)
+	if n := utf8.RuneCountInString(got); n > 160 {
+		t.Errorf("level 2 summary is %d characters, over its budget of 160: %q", n, got)
+	}
+	if !strings.Contains(got, "Criteria: 2/3 met") || !strings.Contains(got, "SameSite") {
+		t.Errorf("level 2 summary %q should count criteria and keep the cause", got)
+	}
+	if strings.Contains(got, "Thanks") || strings.Contains(got, "last week") {
+		t.Errorf("level 2 summary %q kept a low-value sentence", got)
+	}
+}
+
+// stubEndpoint serves chat completions, answering with reply, or with
+// status when it is not 200.
+func stubEndpoint(t *testing.T, status int, reply string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sekrit" {
+			t.Errorf("Authorization = %q, want the bearer token", got)
+		}
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if req.Model != "tiny" || len(req.Messages) != 2 || !strings.Contains(req.Messages[1].Content, "Title: Fix login redirect loop") {
+			t.Errorf("unexpected request %+v", req)
+		}
+		if status != http.StatusOK {
+			http.Error(w, "model overloaded", status)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]string{"role": "assistant", "content": reply}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEndpoint(t *testing.T) {
+	t.Setenv("DOIT_SUMMARIZER_TEST_KEY", "sekrit")
+	srv := stubEndpoint(t, http.StatusOK, "  Fixed the redirect loop by setting SameSite on the session cookie.\n")
+	e, err := NewEndpoint(model.CompactionEndpoint{URL: srv.URL + "/v1/", Model: "tiny", APIKeyEnv: "DOIT_SUMMARIZER_TEST_KEY"})
+	if err != nil {
+		t.Fatalf("NewEndpoint: %v", err)
+	}
+
+	got, err := e.Summarize(context.Background(), testIssue(), 1, 400)
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if want := "Fixed the redirect loop by setting SameSite on the session cookie."; got != want {
+		t.Errorf("Summarize = %q, want %q", got, want)
+	}
+	if got, _ := e.Summarize(context.Background(), testIssue(), 1, 10); utf8.RuneCountInString(got) != 10 {
+		t.Errorf("budget 10 = %q, want it cut to 10 characters", got)
+	}
+
+	failing, _ := NewEndpoint(model.CompactionEndpoint{URL: stubEndpoint(t, http.StatusServiceUnavailable, "").URL + "/v1", Model: "tiny", APIKeyEnv: "DOIT_SUMMARIZER_TEST_KEY"})
+	if _, err := failing.Summarize(context.Background(), testIssue(), 1, 400); err == nil || !strings.Contains(err.Error(), "model overloaded") {
+		t.Errorf("Summarize against a failing endpoint = %v, want its error", err)
+	}
+}
+
+func TestEndpointKeyEnv(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://doit:secret@db/doit")
+	for _, name := range []string{"DATABASE_URL", "DOIT_SUMMARIZER_", "doit_summarizer_key"} {
+		if _, err := NewEndpoint(model.CompactionEndpoint{URL: "http://localhost/v1", Model: "tiny", APIKeyEnv: name}); err == nil {
+			t.Errorf("NewEndpoint with key variable %q should fail", name)
+		}
+	}
+}
+
+func TestRunUsesTenantSettings(t *testing.T) {
+	t.Setenv("DOIT_SUMMARIZER_TEST_KEY", "sekrit")
+	s := store.NewMemStore("doit")
+	tenant, err := s.CreateTenant(context.Background(), "acme", "acme")
+	if err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+	ctx := auth.WithTenant(context.Background(), tenant.ID)
+
+	closeIssue := func(title string) string {
+		t.Helper()
+		src := testIssue()
+		id, err := s.GenerateID(ctx, "")
+		if err != nil {
+			t.Fatalf("GenerateID: %v", err)
+		}
+		issue, err := s.CreateIssue(ctx, store.CreateIssueInput{
+			ID: id, Title: title, IssueType: src.IssueType, Status: model.StatusOpen,
+			Description: src.Description, AcceptanceCriteria: src.AcceptanceCriteria,
+		})
+		if err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+		closed := model.StatusClosed
+		if _, err := s.UpdateIssue(ctx, issue.ID, store.UpdateIssueInput{Status: &closed, CloseReason: &src.CloseReason}); err != nil {
+			t.Fatalf("closing: %v", err)
+		}
+		return issue.ID
+	}
+	compactAll := func() []CompactResult {
+		t.Helper()
+		time.Sleep(time.Millisecond)
+		report, err := New(s).Run(ctx, Options{Age: time.Nanosecond})
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return report.Compacted
+	}
+
+	srv := stubEndpoint(t, http.StatusOK, "Redirect loop fixed.")
+	if _, err := s.SetCompactionSettings(ctx, &model.CompactionSettings{
+		Summarizer: model.SummarizerEndpoint,
+		Endpoint:   &model.CompactionEndpoint{URL: srv.URL + "/v1", Model: "tiny", APIKeyEnv: "DOIT_SUMMARIZER_TEST_KEY"},
+	}); err != nil {
+		t.Fatalf("SetCompactionSettings: %v", err)
+	}
+	id := closeIssue("Fix login redirect loop")
+	results := compactAll()
+	if len(results) != 1 || results[0].Summarizer != model.SummarizerEndpoint || results[0].SummarizerError != "" {
+		t.Fatalf("Run = %+v, want one issue summarized by the endpoint", results)
+	}
+	if got, _ := s.GetIssue(ctx, id); got.Description != "Redirect loop fixed." {
+		t.Errorf("description = %q, want the endpoint's summary", got.Description)
+	}
+
+	// An endpoint that fails falls back to the heuristic summary.
+	down := stubEndpoint(t, http.StatusBadGateway, "")
+	if _, err := s.SetCompactionSettings(ctx, &model.CompactionSettings{
+		Summarizer: model.SummarizerEndpoint,
+		Budgets:    model.CompactionBudgets{Level2: 40},
+		Endpoint:   &model.CompactionEndpoint{URL: down.URL + "/v1", Model: "tiny", APIKeyEnv: "DOIT_SUMMARIZER_TEST_KEY"},
+	}); err != nil {
+		t.Fatalf("SetCompactionSettings: %v", err)
+	}
+	id = closeIssue("Fix login redirect loop")
+	results = compactAll()
+	if len(results) != 1 || results[0].Summarizer != model.SummarizerHeuristic || results[0].SummarizerError == "" {
+		t.Fatalf("Run = %+v, want a heuristic fallback with the endpoint's error", results)
+	}
+	got, _ := s.GetIssue(ctx, id)
+	if !strings.HasPrefix(got.Description, "[bug] Fix login") || utf8.RuneCountInString(got.Description) > 40 {
+		t.Errorf("description = %q, want a heuristic summary within 40 characters", got.Description)
+	}
+}
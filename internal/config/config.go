@@ -19,6 +19,36 @@ type Config struct {
 	DBQueryTimeout  time.Duration
 	HTTPTimeout     time.Duration
 	MaxLimit        int
+
+	// ClaimLease is how long a claim lasts without a heartbeat before the
+	// reaper returns the issue to open; LeaseReapInterval is how often the
+	// reaper runs by default (0 leaves it off unless a tenant turns it on).
+	ClaimLease        time.Duration
+	LeaseReapInterval time.Duration
+
+	// TrashRetention is how long deleted issues stay restorable;
+	// TrashPurgeInterval is how often older ones are purged by default.
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	// CompactAge is how long issues stay closed before compaction
+	// summarizes them; CompactInterval is how often it runs by default.
+	CompactAge      time.Duration
+	CompactInterval time.Duration
+
+	// EphemeralGCInterval is how often ephemeral issues past their
+	// tenant's retention are deleted by default.
+	EphemeralGCInterval time.Duration
+
+	// AgentStuckAfter is how long an agent may go without reporting in
+	// before the monitor marks it stuck; AgentMonitorInterval is how often
+	// the monitor runs by default.
+	AgentStuckAfter      time.Duration
+	AgentMonitorInterval time.Duration
+
+	// GateCheckInterval is how often gates are checked by default: timers
+	// that ran out open, and gates past their timeout escalate.
+	GateCheckInterval time.Duration
 }
 
 func Load() (*Config, error) {
@@ -32,6 +62,16 @@ func Load() (*Config, error) {
 		DBQueryTimeout: envDuration("DB_QUERY_TIMEOUT", 10*time.Second),
 		HTTPTimeout:    envDuration("HTTP_TIMEOUT", 60*time.Second),
 		MaxLimit:       envInt("MAX_LIMIT", 200),
+		ClaimLease:        envDuration("CLAIM_LEASE", 30*time.Minute),
+		LeaseReapInterval: envDuration("LEASE_REAP_INTERVAL", time.Minute),
+		TrashRetention:     envDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval: envDuration("TRASH_PURGE_INTERVAL", time.Hour),
+		CompactAge:          envDuration("COMPACT_AGE", 7*24*time.Hour),
+		CompactInterval:     envDuration("COMPACT_INTERVAL", 24*time.Hour),
+		EphemeralGCInterval: envDuration("EPHEMERAL_GC_INTERVAL", time.Hour),
+		AgentStuckAfter:      envDuration("AGENT_STUCK_AFTER", 10*time.Minute),
+		AgentMonitorInterval: envDuration("AGENT_MONITOR_INTERVAL", time.Minute),
+		GateCheckInterval:    envDuration("GATE_CHECK_INTERVAL", time.Minute),
 	}
 
 	if cfg.DatabaseURL == "" {
@@ -0,0 +1,384 @@
+// Package gates resolves gates: issues of type gate that hold back the
+// issues they block until something happens.
+//
+// A gate's AwaitType says what it waits on: a timer that runs out Timeout
+// after the gate was created, another issue (AwaitID) closing, a flag
+// (AwaitID) being resolved, or a named signal (AwaitID) arriving through
+// Signal. A waiting gate is blocked, so it never shows up as ready work
+// itself. Resolving it closes it, and whatever it blocks becomes ready
+// unless something else still holds it back.
+//
+// A gate that waits on anything but a timer may also have a Timeout. If it
+// is still waiting that long after it was created, it escalates: it raises
+// a gate_timeout flag, once, and keeps waiting.
+package gates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+// keeperActor is who the gate checks' resolutions and flags are from.
+const keeperActor = "gate_keeper"
+
+// MaxBlocks caps the issues a gate is created blocking, which go into the
+// same batch as the gate.
+const MaxBlocks = store.MaxBatchOps - 1
+
+// Keeper creates gates and resolves them.
+type Keeper struct {
+	store store.Store
+}
+
+func New(s store.Store) *Keeper {
+	return &Keeper{store: s}
+}
+
+// CreateInput describes a gate to create.
+type CreateInput struct {
+	Title       string
+	Description string
+	AwaitType   string
+	// AwaitID is the issue, flag or signal waited on. A flag gate without
+	// one raises a human_decision flag on itself and waits for that: an
+	// approval.
+	AwaitID string
+	// Timeout is the timer of a timer gate; for the others it is how long
+	// to wait before escalating, and zero never escalates.
+	Timeout   time.Duration
+	Blocks    []string // issues the gate holds back
+	Priority  int
+	ProjectID string
+	CreatedBy string
+}
+
+// Resolved is a gate that opened.
+type Resolved struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// Escalated is a gate that outlived its timeout.
+type Escalated struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	FlagID string `json:"flag_id"`
+}
+
+// Report is what a Check did.
+type Report struct {
+	Resolved  []Resolved  `json:"resolved"`
+	Escalated []Escalated `json:"escalated"`
+}
+
+// Create makes the gate and its blocks dependencies in one batch. A gate
+// whose condition already holds, such as one waiting on a closed issue,
+// resolves straight away.
+func (k *Keeper) Create(ctx context.Context, input CreateInput) (*model.Issue, error) {
+	if strings.TrimSpace(input.Title) == "" {
+		return nil, fmt.Errorf("gate title is required")
+	}
+	if input.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative")
+	}
+	switch input.AwaitType {
+	case model.AwaitTimer:
+		if input.Timeout == 0 {
+			return nil, fmt.Errorf("a timer gate needs a timeout")
+		}
+		if input.AwaitID != "" {
+			return nil, fmt.Errorf("a timer gate waits on nothing but its timeout; drop await_id")
+		}
+	case model.AwaitIssue:
+		if input.AwaitID == "" {
+			return nil, fmt.Errorf("an issue gate needs the await_id of the issue to wait on")
+		}
+		if _, err := k.store.GetIssue(ctx, input.AwaitID); err != nil {
+			return nil, err
+		}
+	case model.AwaitFlag:
+		if input.AwaitID != "" {
+			if _, err := k.store.GetFlag(ctx, input.AwaitID); err != nil {
+				return nil, err
+			}
+		}
+	case model.AwaitSignal:
+		if input.AwaitID == "" {
+			return nil, fmt.Errorf("a signal gate needs the await_id of the signal to wait on")
+		}
+	default:
+		return nil, fmt.Errorf("invalid await_type %q: want %s, %s, %s or %s",
+			input.AwaitType, model.AwaitTimer, model.AwaitIssue, model.AwaitFlag, model.AwaitSignal)
+	}
+	if len(input.Blocks) > MaxBlocks {
+		return nil, fmt.Errorf("gate blocks %d issues; the limit is %d", len(input.Blocks), MaxBlocks)
+	}
+
+	ops := []store.BatchOp{{
+		Ref: "gate",
+		Create: &store.CreateIssueInput{
+			Title:       input.Title,
+			Description: input.Description,
+			Status:      model.StatusBlocked,
+			Priority:    input.Priority,
+			IssueType:   model.TypeGate,
+			CreatedBy:   input.CreatedBy,
+			ProjectID:   input.ProjectID,
+			AwaitType:   input.AwaitType,
+			AwaitID:     input.AwaitID,
+			Timeout:     input.Timeout,
+		},
+	}}
+	for _, id := range input.Blocks {
+		ops = append(ops, store.BatchOp{Dependency: &store.AddDependencyInput{
+			IssueID:     id,
+			DependsOnID: "$gate",
+			Type:        model.DepBlocks,
+			CreatedBy:   input.CreatedBy,
+		}})
+	}
+	results, err := k.store.ApplyBatch(ctx, ops)
+	if err != nil {
+		return nil, fmt.Errorf("creating gate: %w", err)
+	}
+	gate := results[0].Issue
+
+	if gate.AwaitType == model.AwaitFlag && gate.AwaitID == "" {
+		if gate, err = k.requestApproval(ctx, gate); err != nil {
+			return nil, err
+		}
+	}
+	reason, err := k.satisfied(ctx, gate, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		if _, err := k.resolve(ctx, gate, reason); err != nil {
+			return nil, err
+		}
+	}
+	return k.store.GetIssue(ctx, gate.ID)
+}
+
+// requestApproval raises the human_decision flag a flag gate without an
+// AwaitID waits on.
+func (k *Keeper) requestApproval(ctx context.Context, gate *model.Issue) (*model.Issue, error) {
+	flag, err := k.store.RaiseFlag(ctx, store.RaiseFlagInput{
+		IssueID:   gate.ID,
+		ProjectID: gate.ProjectID,
+		Type:      string(model.FlagHumanDecision),
+		Summary:   "Approval needed: " + gate.Title,
+		CreatedBy: gate.CreatedBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("raising the approval flag of gate %s: %w", gate.ID, err)
+	}
+	updated, err := k.store.UpdateIssue(ctx, gate.ID, store.UpdateIssueInput{AwaitID: &flag.ID})
+	if err != nil {
+		return nil, fmt.Errorf("pointing gate %s at its approval flag: %w", gate.ID, err)
+	}
+	return updated, nil
+}
+
+// Signal sends the named signal, resolving every gate waiting on it. note,
+// if given, is added to the gates' close reason.
+func (k *Keeper) Signal(ctx context.Context, name, note string) ([]Resolved, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("signal name is required")
+	}
+	reason := "signal " + name
+	if note != "" {
+		reason += ": " + note
+	}
+	return k.release(ctx, model.AwaitSignal, name, reason)
+}
+
+// IssueClosed resolves the gates waiting on the issue issueID, which has
+// just closed, rather than leaving them to the next Check.
+func (k *Keeper) IssueClosed(ctx context.Context, issueID string) ([]Resolved, error) {
+	return k.release(ctx, model.AwaitIssue, issueID, issueClosedReason(issueID))
+}
+
+// FlagResolved resolves the gates waiting on flag, which has just been
+// resolved, rather than leaving them to the next Check.
+func (k *Keeper) FlagResolved(ctx context.Context, flag *model.Flag) ([]Resolved, error) {
+	return k.release(ctx, model.AwaitFlag, flag.ID, flagResolvedReason(flag))
+}
+
+// release resolves the gates waiting on awaitType awaitID with reason.
+func (k *Keeper) release(ctx context.Context, awaitType, awaitID, reason string) ([]Resolved, error) {
+	waiting, err := k.waiting(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolved := []Resolved{}
+	for i := range waiting {
+		gate := &waiting[i]
+		if gate.AwaitType != awaitType || gate.AwaitID != awaitID {
+			continue
+		}
+		ok, err := k.resolve(ctx, gate, reason)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			resolved = append(resolved, Resolved{ID: gate.ID, Title: gate.Title, Reason: reason})
+		}
+	}
+	return resolved, nil
+}
+
+// Check resolves every waiting gate whose condition holds at now and
+// escalates those that have outlived their timeout. A gate that cannot be
+// checked, say because the issue it waits on was deleted, is skipped; its
+// error is returned, joined with the others, once the rest are done.
+func (k *Keeper) Check(ctx context.Context, now time.Time) (*Report, error) {
+	ctx = auth.WithActor(ctx, keeperActor)
+	waiting, err := k.waiting(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Resolved: []Resolved{}, Escalated: []Escalated{}}
+	var errs []error
+	for i := range waiting {
+		gate := &waiting[i]
+		reason, err := k.satisfied(ctx, gate, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("checking gate %s: %w", gate.ID, err))
+			continue
+		}
+		if reason != "" {
+			ok, err := k.resolve(ctx, gate, reason)
+			if err != nil {
+				errs = append(errs, err)
+			} else if ok {
+				report.Resolved = append(report.Resolved, Resolved{ID: gate.ID, Title: gate.Title, Reason: reason})
+			}
+			continue
+		}
+
+		if gate.AwaitType == model.AwaitTimer || gate.Timeout == 0 || now.Before(gate.CreatedAt.Add(gate.Timeout)) {
+			continue
+		}
+		flag, err := k.escalate(ctx, gate, now)
+		if err != nil {
+			errs = append(errs, err)
+		} else if flag != nil {
+			report.Escalated = append(report.Escalated, Escalated{ID: gate.ID, Title: gate.Title, FlagID: flag.ID})
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// satisfied returns why gate may open at now, or "" while it must wait.
+// Signal gates only open through Signal.
+func (k *Keeper) satisfied(ctx context.Context, gate *model.Issue, now time.Time) (string, error) {
+	switch gate.AwaitType {
+	case model.AwaitTimer:
+		if !now.Before(gate.CreatedAt.Add(gate.Timeout)) {
+			return fmt.Sprintf("timer of %s ran out", gate.Timeout), nil
+		}
+	case model.AwaitIssue:
+		issue, err := k.store.GetIssue(ctx, gate.AwaitID)
+		if err != nil {
+			return "", err
+		}
+		if issue.Status == model.StatusClosed {
+			return issueClosedReason(issue.ID), nil
+		}
+	case model.AwaitFlag:
+		flag, err := k.store.GetFlag(ctx, gate.AwaitID)
+		if err != nil {
+			return "", err
+		}
+		if flag.Status == model.FlagStatusResolved {
+			return flagResolvedReason(flag), nil
+		}
+	}
+	return "", nil
+}
+
+func issueClosedReason(id string) string {
+	return id + " closed"
+}
+
+func flagResolvedReason(flag *model.Flag) string {
+	return fmt.Sprintf("flag %s resolved: %s", flag.ID, flag.Resolution)
+}
+
+// resolve closes gate with reason. It reports false, and does nothing, if
+// the gate changed since it was read: someone else resolved or moved it.
+func (k *Keeper) resolve(ctx context.Context, gate *model.Issue, reason string) (bool, error) {
+	closed := model.StatusClosed
+	if _, err := k.store.UpdateIssue(ctx, gate.ID, store.UpdateIssueInput{
+		Status:            &closed,
+		CloseReason:       &reason,
+		ExpectedUpdatedAt: &gate.UpdatedAt,
+	}); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return false, nil
+		}
+		return false, fmt.Errorf("resolving gate %s: %w", gate.ID, err)
+	}
+	return true, nil
+}
+
+// escalate raises the gate_timeout flag of gate, unless it already has
+// one: a gate escalates once, even if the flag is resolved while the gate
+// keeps waiting.
+func (k *Keeper) escalate(ctx context.Context, gate *model.Issue, now time.Time) (*model.Flag, error) {
+	flags, err := k.store.ListFlags(ctx, model.FlagFilter{IssueID: &gate.ID})
+	if err != nil {
+		return nil, fmt.Errorf("listing flags of gate %s: %w", gate.ID, err)
+	}
+	for _, f := range flags {
+		if f.Type == model.FlagGateTimeout {
+			return nil, nil
+		}
+	}
+
+	waited := now.Sub(gate.CreatedAt).Round(time.Second)
+	details, err := json.Marshal(map[string]any{
+		"gate":       gate.ID,
+		"await_type": gate.AwaitType,
+		"await_id":   gate.AwaitID,
+		"timeout":    gate.Timeout.String(),
+		"waited":     waited.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	flag, err := k.store.RaiseFlag(ctx, store.RaiseFlagInput{
+		IssueID:   gate.ID,
+		ProjectID: gate.ProjectID,
+		Type:      string(model.FlagGateTimeout),
+		Severity:  2,
+		Summary:   fmt.Sprintf("Gate %q has waited %s for %s %s", gate.Title, waited, gate.AwaitType, gate.AwaitID),
+		Context:   details,
+		CreatedBy: keeperActor,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("flagging gate %s: %w", gate.ID, err)
+	}
+	return flag, nil
+}
+
+// waiting returns the tenant's gates that have not resolved.
+func (k *Keeper) waiting(ctx context.Context) ([]model.Issue, error) {
+	gateType, blocked := model.TypeGate, model.StatusBlocked
+	gates, err := k.store.ListIssues(ctx, model.IssueFilter{IssueType: &gateType, Status: &blocked})
+	if err != nil {
+		return nil, fmt.Errorf("listing gates: %w", err)
+	}
+	return gates, nil
+}
@@ -0,0 +1,200 @@
+package gates
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/Actual-Outcomes/doit/internal/store/storetest"
+)
+
+func TestGates(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	k := New(s)
+	deploy := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "deploy"}).ID
+	review := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "review"}).ID
+
+	timer, err := k.Create(ctx, CreateInput{Title: "soak", AwaitType: model.AwaitTimer, Timeout: time.Hour, Blocks: []string{deploy}})
+	if err != nil {
+		t.Fatalf("Create(timer): %v", err)
+	}
+	onReview, err := k.Create(ctx, CreateInput{Title: "reviewed", AwaitType: model.AwaitIssue, AwaitID: review, Blocks: []string{deploy}})
+	if err != nil {
+		t.Fatalf("Create(issue): %v", err)
+	}
+	signal, err := k.Create(ctx, CreateInput{Title: "go-ahead", AwaitType: model.AwaitSignal, AwaitID: "release-window", Blocks: []string{deploy}})
+	if err != nil {
+		t.Fatalf("Create(signal): %v", err)
+	}
+	if timer.IssueType != model.TypeGate || timer.Status != model.StatusBlocked {
+		t.Fatalf("gate = %s %s, want a blocked gate", timer.IssueType, timer.Status)
+	}
+	if got := strings.Join(storetest.ReadyIDs(t, ctx, s), ","); got != review {
+		t.Fatalf("ready = %s, want only %s: gates are not work and hold back deploy", got, review)
+	}
+
+	report, err := k.Check(ctx, time.Now())
+	if err != nil || len(report.Resolved) != 0 {
+		t.Fatalf("Check right away = %+v, %v; want nothing resolved", report, err)
+	}
+
+	closed := model.StatusClosed
+	if _, err := s.UpdateIssue(ctx, review, store.UpdateIssueInput{Status: &closed}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	report, err = k.Check(ctx, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(report.Resolved) != 2 {
+		t.Fatalf("resolved = %+v, want the timer and issue gates", report.Resolved)
+	}
+	g, _ := s.GetIssue(ctx, onReview.ID)
+	if g.Status != model.StatusClosed || g.CloseReason != review+" closed" {
+		t.Errorf("issue gate = %s %q, want closed because %s closed", g.Status, g.CloseReason, review)
+	}
+
+	if resolved, err := k.Signal(ctx, "other", ""); err != nil || len(resolved) != 0 {
+		t.Errorf("Signal(other) = %+v, %v; want nothing", resolved, err)
+	}
+	resolved, err := k.Signal(ctx, "release-window", "opened by ops")
+	if err != nil || len(resolved) != 1 || resolved[0].ID != signal.ID {
+		t.Fatalf("Signal = %+v, %v; want the signal gate", resolved, err)
+	}
+	if resolved[0].Reason != "signal release-window: opened by ops" {
+		t.Errorf("reason = %q", resolved[0].Reason)
+	}
+	if got := strings.Join(storetest.ReadyIDs(t, ctx, s), ","); got != deploy {
+		t.Errorf("ready = %s, want %s once every gate opened", got, deploy)
+	}
+
+	// A gate on an issue that is already closed opens straight away.
+	g, err = k.Create(ctx, CreateInput{Title: "late", AwaitType: model.AwaitIssue, AwaitID: review})
+	if err != nil || g.Status != model.StatusClosed {
+		t.Errorf("late gate = %+v, %v; want it closed at once", g, err)
+	}
+
+	for _, bad := range []CreateInput{
+		{Title: "t", AwaitType: model.AwaitTimer},
+		{Title: "t", AwaitType: model.AwaitTimer, Timeout: time.Minute, AwaitID: "x"},
+		{Title: "i", AwaitType: model.AwaitIssue, AwaitID: "doit-nope"},
+		{Title: "s", AwaitType: model.AwaitSignal},
+		{Title: "f", AwaitType: model.AwaitFlag, AwaitID: "flg-nope"},
+		{Title: "w", AwaitType: "weather"},
+		{AwaitType: model.AwaitSignal, AwaitID: "x"},
+	} {
+		if _, err := k.Create(ctx, bad); err == nil {
+			t.Errorf("Create(%+v) should fail", bad)
+		}
+	}
+}
+
+func TestApprovalAndEscalation(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	k := New(s)
+	ship := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "ship"}).ID
+
+	gate, err := k.Create(ctx, CreateInput{Title: "ship it?", AwaitType: model.AwaitFlag, Timeout: time.Hour, Blocks: []string{ship}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	approval, err := s.GetFlag(ctx, gate.AwaitID)
+	if err != nil {
+		t.Fatalf("GetFlag(%q): %v", gate.AwaitID, err)
+	}
+	if approval.Type != model.FlagHumanDecision || approval.IssueID != gate.ID {
+		t.Fatalf("approval = %+v, want a human_decision flag on the gate", approval)
+	}
+
+	later := time.Now().Add(2 * time.Hour)
+	report, err := k.Check(ctx, later)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(report.Escalated) != 1 || report.Escalated[0].ID != gate.ID {
+		t.Fatalf("escalated = %+v, want the gate", report.Escalated)
+	}
+	timeout, err := s.GetFlag(ctx, report.Escalated[0].FlagID)
+	if err != nil || timeout.Type != model.FlagGateTimeout || timeout.CreatedBy != keeperActor {
+		t.Fatalf("timeout flag = %+v, %v; want a gate_timeout flag from the keeper", timeout, err)
+	}
+	if _, err := s.ResolveFlag(ctx, timeout.ID, "chased", "ops"); err != nil {
+		t.Fatalf("ResolveFlag: %v", err)
+	}
+	if report, _ = k.Check(ctx, later); len(report.Escalated) != 0 || len(report.Resolved) != 0 {
+		t.Errorf("second check = %+v, want the gate to escalate once and keep waiting", report)
+	}
+
+	if approval, err = s.ResolveFlag(ctx, approval.ID, "approved", "lead"); err != nil {
+		t.Fatalf("ResolveFlag: %v", err)
+	}
+	resolved, err := k.FlagResolved(ctx, approval)
+	if err != nil || len(resolved) != 1 || resolved[0].Reason != "flag "+approval.ID+" resolved: approved" {
+		t.Fatalf("FlagResolved = %+v, %v; want the gate", resolved, err)
+	}
+	if got := strings.Join(storetest.ReadyIDs(t, ctx, s), ","); got != ship {
+		t.Errorf("ready = %s, want %s once approved", got, ship)
+	}
+}
+
+// TestEveryStore escalates a flag gate past its timeout and opens it once the flag is resolved on each store backend.
+func TestEveryStore(t *testing.T) {
+	storetest.EachStore(t, testEveryStore)
+}
+
+func testEveryStore(t *testing.T, s store.Store) {
+	ctx := storetest.NewTenant(t, s)
+	k := New(s)
+	blocked := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "ship"})
+
+	gate, err := k.Create(ctx, CreateInput{
+		Title:     "sign-off",
+		AwaitType: model.AwaitFlag,
+		Timeout:   time.Hour,
+		Blocks:    []string{blocked.ID},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, err := s.GetIssue(ctx, gate.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.IssueType != model.TypeGate || got.Status != model.StatusBlocked ||
+		got.AwaitType != model.AwaitFlag || got.AwaitID == "" || got.Timeout != time.Hour {
+		t.Fatalf("gate = %+v, want a blocked flag gate with a 1h timeout", got)
+	}
+	flag, err := s.GetFlag(ctx, got.AwaitID)
+	if err != nil {
+		t.Fatalf("GetFlag: %v", err)
+	}
+	if flag.IssueID != gate.ID || flag.Status != model.FlagStatusOpen {
+		t.Errorf("approval flag = %+v, want an open flag on %s", flag, gate.ID)
+	}
+	if _, err := s.GetFlag(storetest.NewTenant(t, s), flag.ID); err == nil {
+		t.Error("another tenant should not see the flag")
+	}
+	if ready := storetest.ReadyIDs(t, ctx, s); len(ready) != 0 {
+		t.Errorf("ready = %v, want the gate and what it blocks held back", ready)
+	}
+
+	report, err := k.Check(ctx, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(report.Escalated) != 1 {
+		t.Fatalf("escalated = %+v, want the gate", report.Escalated)
+	}
+
+	if _, err := s.ResolveFlag(ctx, flag.ID, "approved", "lead"); err != nil {
+		t.Fatalf("ResolveFlag: %v", err)
+	}
+	if report, err = k.Check(ctx, time.Now()); err != nil || len(report.Resolved) != 1 {
+		t.Fatalf("Check = %+v, %v; want the gate resolved", report, err)
+	}
+	if ready := storetest.ReadyIDs(t, ctx, s); len(ready) != 1 || ready[0] != blocked.ID {
+		t.Errorf("ready = %v, want %s", ready, blocked.ID)
+	}
+}
@@ -0,0 +1,259 @@
+// Package gc garbage-collects ephemeral issues: the throwaway issues and
+// wisps (heartbeats, pings, patrol reports and the like) agents create as
+// they work. Each tenant's model.GCPolicy says how long they are kept.
+//
+// Collected issues are deleted for good, not moved to the trash, so they
+// stop counting towards everything that scans the issues table. A run can
+// leave a single gc_report wisp behind recording what it deleted.
+package gc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+// pageSize is how many ephemeral issues a run loads, and purges, at a time.
+const pageSize = 200
+
+// reportListLimit caps how many collected IDs a gc_report lists.
+const reportListLimit = 100
+
+// Collector deletes ephemeral issues past their retention.
+type Collector struct {
+	store store.Store
+}
+
+func New(s store.Store) *Collector {
+	return &Collector{store: s}
+}
+
+// Options control a collection run.
+type Options struct {
+	DryRun bool // report what would be collected, changing nothing
+	// Report overrides the tenant policy on rolling the run into a
+	// gc_report wisp.
+	Report *bool
+}
+
+// Report is what a run collected or, on a dry run, would collect.
+type Report struct {
+	DryRun    bool           `json:"dry_run"`
+	Scanned   int            `json:"scanned"` // ephemeral issues looked at
+	Collected []Collected    `json:"collected"`
+	ByType    map[string]int `json:"by_type"`             // collected per wisp type; "ephemeral" for other issues
+	ReportID  string         `json:"report_id,omitempty"` // the gc_report wisp, when one was written
+}
+
+// Collected is an issue a run deleted.
+type Collected struct {
+	IssueID  string         `json:"issue_id"`
+	Title    string         `json:"title"`
+	WispType model.WispType `json:"wisp_type,omitempty"`
+	Status   model.Status   `json:"status"`
+	Idle     string         `json:"idle"` // how long since it was closed or last updated
+}
+
+// otherEphemeral is the ByType key of ephemeral issues that are not wisps.
+const otherEphemeral = "ephemeral"
+
+// Run pages through the ephemeral issues of the tenant in ctx and deletes
+// those kept longer than the tenant's policy allows. Wisps count from when
+// they were closed or, while open, last updated; other ephemeral issues
+// only once closed. Pinned issues, issues being worked on and the steps of
+// molecules still running are kept.
+func (c *Collector) Run(ctx context.Context, now time.Time, opts Options) (*Report, error) {
+	policy, err := c.store.GetGCPolicy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting gc policy: %w", err)
+	}
+	retention, err := retentions(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeral := true
+	filter := model.IssueFilter{
+		Ephemeral: &ephemeral,
+		Limit:     pageSize,
+		SortBy:    "oldest",
+	}
+	report := &Report{DryRun: opts.DryRun, Collected: []Collected{}, ByType: map[string]int{}}
+	var due []string
+	running, err := c.runningMolecules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		issues, err := c.store.ListIssues(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("listing ephemeral issues: %w", err)
+		}
+		for _, issue := range issues {
+			report.Scanned++
+			idle, ok := collectable(&issue, now, retention)
+			if !ok {
+				continue
+			}
+			if parent, ok := store.ImpliedParent(issue.ID); ok && running[parent] {
+				continue
+			}
+			due = append(due, issue.ID)
+			report.Collected = append(report.Collected, Collected{
+				IssueID:  issue.ID,
+				Title:    issue.Title,
+				WispType: issue.WispType,
+				Status:   issue.Status,
+				Idle:     idle.Round(time.Second).String(),
+			})
+			report.ByType[typeKey(&issue)]++
+		}
+		if len(issues) < pageSize {
+			break
+		}
+		filter.Cursor = store.IssueCursor(&issues[len(issues)-1], filter.SortBy)
+	}
+	if opts.DryRun || len(due) == 0 {
+		return report, nil
+	}
+
+	for start := 0; start < len(due); start += pageSize {
+		batch := due[start:min(start+pageSize, len(due))]
+		if _, err := c.store.PurgeIssues(ctx, batch); err != nil {
+			return nil, fmt.Errorf("purging ephemeral issues: %w", err)
+		}
+	}
+
+	writeReport := policy.Report
+	if opts.Report != nil {
+		writeReport = *opts.Report
+	}
+	if writeReport {
+		id, err := c.writeReport(ctx, report)
+		if err != nil {
+			return nil, err
+		}
+		report.ReportID = id
+	}
+	return report, nil
+}
+
+// retentions parses the policy's durations, keyed like Report.ByType.
+func retentions(policy *model.GCPolicy) (map[string]time.Duration, error) {
+	out := make(map[string]time.Duration, len(policy.Retention)+1)
+	for t, v := range policy.Retention {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("gc policy has an invalid %s retention %q: %w", t, v, err)
+		}
+		out[string(t)] = d
+	}
+	d, err := time.ParseDuration(policy.Ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("gc policy has an invalid ephemeral retention %q: %w", policy.Ephemeral, err)
+	}
+	out[otherEphemeral] = d
+	return out, nil
+}
+
+func typeKey(issue *model.Issue) string {
+	if issue.WispType != "" {
+		return string(issue.WispType)
+	}
+	return otherEphemeral
+}
+
+// collectable reports whether issue has outlived its retention, and how
+// long it has been idle.
+func collectable(issue *model.Issue, now time.Time, retention map[string]time.Duration) (time.Duration, bool) {
+	if issue.Pinned || issue.Status == model.StatusInProgress {
+		return 0, false
+	}
+	since := issue.UpdatedAt
+	switch {
+	case issue.ClosedAt != nil:
+		since = *issue.ClosedAt
+	case issue.WispType == "":
+		// An open ephemeral issue may be a message nobody has read yet.
+		return 0, false
+	}
+	keep, ok := retention[typeKey(issue)]
+	if !ok {
+		// A wisp type the policy does not know yet.
+		keep = retention[otherEphemeral]
+	}
+	idle := now.Sub(since)
+	return idle, idle > keep
+}
+
+// runningMolecules returns the IDs of the tenant's molecules that have not
+// closed yet. Their steps are kept, so a molecule that crystallizes still
+// has them when it does.
+func (c *Collector) runningMolecules(ctx context.Context) (map[string]bool, error) {
+	molecule := model.TypeMolecule
+	mols, err := c.store.ListIssues(ctx, model.IssueFilter{
+		IssueType: &molecule,
+		StatusNot: []model.Status{model.StatusClosed},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing running molecules: %w", err)
+	}
+	running := make(map[string]bool, len(mols))
+	for _, m := range mols {
+		running[m.ID] = true
+	}
+	return running, nil
+}
+
+// writeReport records the run as one closed gc_report wisp, itself
+// collected once its own retention runs out.
+func (c *Collector) writeReport(ctx context.Context, report *Report) (string, error) {
+	id, err := c.store.GenerateID(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("generating gc report ID: %w", err)
+	}
+	title := fmt.Sprintf("GC: collected %d ephemeral issues", len(report.Collected))
+	if _, err := c.store.CreateIssue(ctx, store.CreateIssueInput{
+		ID:          id,
+		Title:       title,
+		Description: reportDescription(report),
+		Status:      model.StatusOpen,
+		IssueType:   model.TypeEvent,
+		Ephemeral:   true,
+		WispType:    model.WispGCReport,
+	}); err != nil {
+		return "", fmt.Errorf("creating gc report: %w", err)
+	}
+	closed, reason := model.StatusClosed, "gc report"
+	if _, err := c.store.UpdateIssue(ctx, id, store.UpdateIssueInput{Status: &closed, CloseReason: &reason}); err != nil {
+		return "", fmt.Errorf("closing gc report: %w", err)
+	}
+	return id, nil
+}
+
+func reportDescription(report *Report) string {
+	var b strings.Builder
+	b.WriteString("Collected by type:\n")
+	for _, t := range append(model.WispTypes, otherEphemeral) {
+		if n := report.ByType[string(t)]; n > 0 {
+			fmt.Fprintf(&b, "- %s: %d\n", t, n)
+		}
+	}
+	b.WriteString("\nIssues:\n")
+	for i, c := range report.Collected {
+		if i == reportListLimit {
+			fmt.Fprintf(&b, "- and %d more\n", len(report.Collected)-i)
+			break
+		}
+		kind := c.WispType
+		if kind == "" {
+			kind = otherEphemeral
+		}
+		fmt.Fprintf(&b, "- %s [%s] %s\n", c.IssueID, kind, c.Title)
+	}
+	return b.String()
+}
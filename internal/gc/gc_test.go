@@ -0,0 +1,141 @@
+package gc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/Actual-Outcomes/doit/internal/store/storetest"
+)
+
+func TestRun(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+
+	create := func(title string, wisp model.WispType, ephemeral bool, status model.Status) string {
+		t.Helper()
+		id := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: title, Ephemeral: ephemeral, WispType: wisp}).ID
+		if status != model.StatusOpen {
+			if _, err := s.UpdateIssue(ctx, id, store.UpdateIssueInput{Status: &status}); err != nil {
+				t.Fatalf("UpdateIssue: %v", err)
+			}
+		}
+		return id
+	}
+	heartbeat := create("beat", model.WispHeartbeat, true, model.StatusOpen)
+	ping := create("ping", model.WispPing, true, model.StatusClosed)
+	message := create("unread message", "", true, model.StatusOpen)
+	done := create("scratch", "", true, model.StatusClosed)
+	working := create("patrol in progress", model.WispHeartbeat, true, model.StatusInProgress)
+	pinned := create("pinned beat", model.WispHeartbeat, true, model.StatusOpen)
+	pin := true
+	if _, err := s.UpdateIssue(ctx, pinned, store.UpdateIssueInput{Pinned: &pin}); err != nil {
+		t.Fatalf("pinning: %v", err)
+	}
+	durable := create("real work", "", false, model.StatusClosed)
+
+	if _, err := s.SetGCPolicy(ctx, &model.GCPolicy{Retention: map[model.WispType]string{model.WispPing: "3h"}}); err != nil {
+		t.Fatalf("SetGCPolicy: %v", err)
+	}
+	exists := func(id string) bool {
+		_, err := s.GetIssue(ctx, id)
+		return err == nil
+	}
+	run := func(after time.Duration, opts Options) *Report {
+		t.Helper()
+		report, err := New(s).Run(ctx, time.Now().Add(after), opts)
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return report
+	}
+
+	// Two hours on, only the heartbeat is past its retention.
+	report := run(2*time.Hour, Options{DryRun: true})
+	if !report.DryRun || report.Scanned != 6 || len(report.Collected) != 1 || report.Collected[0].IssueID != heartbeat {
+		t.Fatalf("dry run = %+v, want the heartbeat alone out of 6 ephemeral issues", report)
+	}
+	if !exists(heartbeat) {
+		t.Error("a dry run should not delete anything")
+	}
+	report = run(2*time.Hour, Options{})
+	if len(report.Collected) != 1 || report.ByType["heartbeat"] != 1 || report.ReportID != "" || exists(heartbeat) {
+		t.Fatalf("run = %+v, want the heartbeat deleted and no report", report)
+	}
+
+	// A day on, the ping (kept 3h by policy) and the closed scratch issue
+	// go too, rolled into a report.
+	yes := true
+	report = run(25*time.Hour, Options{Report: &yes})
+	if len(report.Collected) != 2 || report.ByType["ping"] != 1 || report.ByType["ephemeral"] != 1 || report.ReportID == "" {
+		t.Fatalf("run = %+v, want the ping and scratch issue collected with a report", report)
+	}
+	for _, id := range []string{ping, done} {
+		if exists(id) {
+			t.Errorf("%s should have been deleted", id)
+		}
+	}
+	for _, id := range []string{message, working, pinned, durable} {
+		if !exists(id) {
+			t.Errorf("%s should have been kept", id)
+		}
+	}
+	gcReport, err := s.GetIssue(ctx, report.ReportID)
+	if err != nil {
+		t.Fatalf("GetIssue(report): %v", err)
+	}
+	if gcReport.WispType != model.WispGCReport || !gcReport.Ephemeral || gcReport.Status != model.StatusClosed ||
+		!strings.Contains(gcReport.Description, ping) || !strings.Contains(gcReport.Description, "- ping: 1") {
+		t.Errorf("gc report = %+v, want a closed gc_report wisp listing the ping", gcReport)
+	}
+
+	// Reports are wisps too, and go once their own retention is up.
+	if report := run(31*24*time.Hour, Options{}); exists(report.Collected[0].IssueID) || !strings.Contains(report.Collected[0].Title, "GC:") {
+		t.Errorf("month-later run = %+v, want the old report collected", report)
+	}
+}
+
+// TestEveryStore collects an expired heartbeat on each store backend.
+func TestEveryStore(t *testing.T) {
+	storetest.EachStore(t, testEveryStore)
+}
+
+func testEveryStore(t *testing.T, s store.Store) {
+	ctx := storetest.NewTenant(t, s)
+	if _, err := s.SetGCPolicy(ctx, &model.GCPolicy{Report: true}); err != nil {
+		t.Fatalf("SetGCPolicy: %v", err)
+	}
+	beat := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{
+		Title: "beat", Ephemeral: true, WispType: model.WispHeartbeat, Labels: []string{"x"},
+	})
+	note := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "note", Ephemeral: true})
+	keep := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "keep"})
+	if _, err := s.AddDependency(ctx, store.AddDependencyInput{IssueID: keep.ID, DependsOnID: beat.ID, Type: model.DepBlocks}); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+
+	report, err := New(s).Run(ctx, time.Now().Add(2*time.Hour), Options{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Scanned != 2 || len(report.Collected) != 1 || report.Collected[0].IssueID != beat.ID || report.ReportID == "" {
+		t.Fatalf("Run = %+v, want the heartbeat of 2 ephemeral issues collected and a report", report)
+	}
+	if _, err := s.GetIssue(ctx, beat.ID); err == nil {
+		t.Error("collected heartbeat should be gone")
+	}
+	if _, err := s.GetIssue(ctx, note.ID); err != nil {
+		t.Errorf("open ephemeral issue should be kept: %v", err)
+	}
+	if trash, _ := s.ListTrash(ctx); len(trash) != 0 {
+		t.Errorf("collection should skip the trash, found %d issues", len(trash))
+	}
+	if ready := storetest.ReadyIDs(t, ctx, s); len(ready) != 1 || ready[0] != keep.ID {
+		t.Errorf("ready = %v, want %s, no longer blocked by the heartbeat", ready, keep.ID)
+	}
+	gcReport, err := s.GetIssue(ctx, report.ReportID)
+	if err != nil || gcReport.WispType != model.WispGCReport || !gcReport.Ephemeral {
+		t.Errorf("gc report = %+v, %v; want a gc_report wisp", gcReport, err)
+	}
+}
@@ -0,0 +1,229 @@
+// Package jobs runs periodic maintenance (lease reaping, trash purging,
+// compaction, garbage collection, agent monitoring, gate checks) for
+// every tenant on the server.
+//
+// Each job has a default interval that a tenant can override. A run is
+// recorded in the tenant's job history along with its result or error.
+// When several replicas share a store that implements store.JobLocker, only
+// one of them runs a given job for a given tenant at a time; the history
+// tells the others it was done.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+// Tick is how often the scheduler looks for jobs that are due.
+const Tick = 15 * time.Second
+
+// Job is a piece of periodic maintenance, run for one tenant at a time.
+type Job struct {
+	Name        string
+	Description string
+	// Interval is how often the job runs unless a tenant overrides it;
+	// 0 leaves it off.
+	Interval time.Duration
+	// Run does the work for the tenant in ctx. Its result is recorded in
+	// the job history as JSON.
+	Run func(ctx context.Context, st store.Store, now time.Time) (any, error)
+}
+
+// Scheduler runs jobs for every tenant when they are due.
+type Scheduler struct {
+	store  store.Store
+	jobs   []Job
+	runner string
+
+	mu      sync.Mutex
+	running map[string]bool // locks held by this process
+}
+
+// New returns a scheduler for jobs, which must have distinct names.
+func New(st store.Store, jobs ...Job) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		store:   st,
+		jobs:    jobs,
+		runner:  fmt.Sprintf("%s:%d", host, os.Getpid()),
+		running: make(map[string]bool),
+	}
+}
+
+// Jobs returns the scheduler's jobs.
+func (s *Scheduler) Jobs() []Job {
+	return s.jobs
+}
+
+// Job returns the job called name.
+func (s *Scheduler) Job(name string) (Job, bool) {
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return j, true
+		}
+	}
+	return Job{}, false
+}
+
+// Status is a job as it stands for one tenant.
+type Status struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Interval    string        `json:"interval"` // "0s" when off
+	Default     bool          `json:"default"`  // no tenant override
+	Enabled     bool          `json:"enabled"`
+	LastRun     *model.JobRun `json:"last_run,omitempty"`
+	NextRun     *time.Time    `json:"next_run,omitempty"`
+}
+
+// Statuses reports every job for the tenant in ctx.
+func (s *Scheduler) Statuses(ctx context.Context) ([]Status, error) {
+	overrides, err := s.store.GetJobIntervals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting job intervals: %w", err)
+	}
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		interval, overridden := overrides[j.Name]
+		if !overridden {
+			interval = j.Interval
+		}
+		st := Status{
+			Name:        j.Name,
+			Description: j.Description,
+			Interval:    interval.String(),
+			Default:     !overridden,
+			Enabled:     interval > 0,
+		}
+		runs, err := s.store.ListJobRuns(ctx, j.Name, 1)
+		if err != nil {
+			return nil, fmt.Errorf("listing runs of %s: %w", j.Name, err)
+		}
+		if len(runs) > 0 {
+			st.LastRun = &runs[0]
+			if st.Enabled {
+				next := runs[0].StartedAt.Add(interval)
+				st.NextRun = &next
+			}
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// Run checks for due jobs every Tick until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(Tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.runDue(ctx, now)
+		}
+	}
+}
+
+// runDue runs every job that is due, tenant by tenant.
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	tenants, err := s.store.ListTenants(ctx)
+	if err != nil {
+		slog.Error("jobs: listing tenants", "error", err)
+		return
+	}
+	for _, t := range tenants {
+		tctx := auth.WithTenant(ctx, t.ID)
+		overrides, err := s.store.GetJobIntervals(tctx)
+		if err != nil {
+			slog.Error("jobs: getting intervals", "tenant", t.Slug, "error", err)
+			continue
+		}
+		for _, j := range s.jobs {
+			interval, ok := overrides[j.Name]
+			if !ok {
+				interval = j.Interval
+			}
+			if interval <= 0 {
+				continue
+			}
+			if err := s.runIfDue(tctx, t, j, interval, now); err != nil {
+				slog.Error("jobs: scheduling", "job", j.Name, "tenant", t.Slug, "error", err)
+			}
+		}
+	}
+}
+
+// runIfDue runs j for tenant t unless it ran within interval or another
+// replica is running it now.
+func (s *Scheduler) runIfDue(ctx context.Context, t model.Tenant, j Job, interval time.Duration, now time.Time) error {
+	due, err := s.due(ctx, j.Name, interval, now)
+	if err != nil || !due {
+		return err
+	}
+	release, ok, err := s.lock(ctx, fmt.Sprintf("doit-job:%s:%s", j.Name, t.ID))
+	if err != nil || !ok {
+		return err
+	}
+	defer release()
+
+	// Another replica may have finished a run between the check above
+	// and taking the lock.
+	if due, err = s.due(ctx, j.Name, interval, now); err != nil || !due {
+		return err
+	}
+
+	run := &model.JobRun{Job: j.Name, StartedAt: time.Now().UTC(), Runner: s.runner}
+	result, err := j.Run(ctx, s.store, now)
+	run.FinishedAt = time.Now().UTC()
+	if err != nil {
+		run.Error = err.Error()
+		slog.Error("job failed", "job", j.Name, "tenant", t.Slug, "error", err)
+	} else {
+		run.Succeeded = true
+	}
+	if result != nil {
+		if run.Result, err = json.Marshal(result); err != nil {
+			return fmt.Errorf("encoding result: %w", err)
+		}
+	}
+	return s.store.RecordJobRun(ctx, run)
+}
+
+// due reports whether job last started at least interval before now.
+func (s *Scheduler) due(ctx context.Context, job string, interval time.Duration, now time.Time) (bool, error) {
+	runs, err := s.store.ListJobRuns(ctx, job, 1)
+	if err != nil {
+		return false, fmt.Errorf("listing runs: %w", err)
+	}
+	return len(runs) == 0 || !now.Before(runs[0].StartedAt.Add(interval)), nil
+}
+
+// lock takes key through the store when replicas share it, and within
+// this process otherwise.
+func (s *Scheduler) lock(ctx context.Context, key string) (release func(), ok bool, err error) {
+	if l, isLocker := s.store.(store.JobLocker); isLocker {
+		return l.TryJobLock(ctx, key)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[key] {
+		return nil, false, nil
+	}
+	s.running[key] = true
+	return func() {
+		s.mu.Lock()
+		delete(s.running, key)
+		s.mu.Unlock()
+	}, true, nil
+}
@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+// lockedStore is a store shared with a replica that holds every job lock.
+type lockedStore struct {
+	*store.MemStore
+}
+
+func (lockedStore) TryJobLock(context.Context, string) (func(), bool, error) {
+	return nil, false, nil
+}
+
+func TestScheduler(t *testing.T) {
+	s := store.NewMemStore("doit")
+	tenant, err := s.CreateTenant(context.Background(), "acme", "acme")
+	if err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+	ctx := auth.WithTenant(context.Background(), tenant.ID)
+
+	var counted, failed int
+	sched := New(s,
+		Job{Name: "count", Interval: time.Hour, Run: func(context.Context, store.Store, time.Time) (any, error) {
+			counted++
+			return map[string]int{"count": counted}, nil
+		}},
+		Job{Name: "fail", Interval: time.Hour, Run: func(context.Context, store.Store, time.Time) (any, error) {
+			failed++
+			return nil, errors.New("disk full")
+		}},
+		Job{Name: "off", Run: func(context.Context, store.Store, time.Time) (any, error) {
+			t.Error("a job without an interval ran")
+			return nil, nil
+		}},
+	)
+
+	now := time.Now()
+	sched.runDue(context.Background(), now)
+	sched.runDue(context.Background(), now.Add(30*time.Minute))
+	if counted != 1 || failed != 1 {
+		t.Fatalf("after two ticks within the hour: counted %d, failed %d; want 1 each", counted, failed)
+	}
+	runs, err := s.ListJobRuns(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("ListJobRuns: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("recorded %d runs, want 2", len(runs))
+	}
+	for _, r := range runs {
+		switch r.Job {
+		case "count":
+			if !r.Succeeded || string(r.Result) != `{"count":1}` || r.Runner == "" {
+				t.Errorf("count run = %+v, want a success with its result", r)
+			}
+		case "fail":
+			if r.Succeeded || r.Error != "disk full" {
+				t.Errorf("fail run = %+v, want its error", r)
+			}
+		}
+	}
+
+	// A tenant can run a job more often, or turn it off.
+	quarter, off := 15*time.Minute, time.Duration(0)
+	if err := s.SetJobInterval(ctx, "count", &quarter); err != nil {
+		t.Fatalf("SetJobInterval: %v", err)
+	}
+	if err := s.SetJobInterval(ctx, "fail", &off); err != nil {
+		t.Fatalf("SetJobInterval: %v", err)
+	}
+	sched.runDue(context.Background(), now.Add(30*time.Minute))
+	if counted != 2 || failed != 1 {
+		t.Errorf("after overriding intervals: counted %d, failed %d; want 2 and 1", counted, failed)
+	}
+
+	statuses, err := sched.Statuses(ctx)
+	if err != nil {
+		t.Fatalf("Statuses: %v", err)
+	}
+	if st := statuses[0]; st.Interval != "15m0s" || st.Default || st.LastRun == nil || st.NextRun == nil {
+		t.Errorf("count status = %+v, want the override, its last run and the next", st)
+	}
+	if st := statuses[1]; st.Enabled || st.NextRun != nil || st.LastRun.Error != "disk full" {
+		t.Errorf("fail status = %+v, want it off with its failed run", st)
+	}
+	if st := statuses[2]; st.Enabled || !st.Default || st.LastRun != nil {
+		t.Errorf("off status = %+v, want it off by default and never run", st)
+	}
+
+	// While another replica holds the lock, nothing runs here.
+	New(lockedStore{s}, sched.Jobs()...).runDue(context.Background(), now.Add(2*time.Hour))
+	if counted != 2 {
+		t.Errorf("ran %d times while the lock was held elsewhere, want 2", counted)
+	}
+}
+
+func TestJobHistoryLimit(t *testing.T) {
+	s := store.NewMemStore("doit")
+	tenant, _ := s.CreateTenant(context.Background(), "acme", "acme")
+	ctx := auth.WithTenant(context.Background(), tenant.ID)
+
+	sched := New(s, Job{Name: "tick", Interval: time.Minute, Run: func(context.Context, store.Store, time.Time) (any, error) {
+		return nil, nil
+	}})
+	now := time.Now()
+	for i := range store.JobHistoryLimit + 5 {
+		sched.runDue(context.Background(), now.Add(time.Duration(i)*time.Hour))
+	}
+	runs, _ := s.ListJobRuns(ctx, "tick", 0)
+	if len(runs) != store.JobHistoryLimit {
+		t.Errorf("kept %d runs, want %d", len(runs), store.JobHistoryLimit)
+	}
+}
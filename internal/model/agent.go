@@ -0,0 +1,48 @@
+package model
+
+import "fmt"
+
+// AgentStates lists every agent state, in lifecycle order.
+var AgentStates = []AgentState{
+	AgentSpawning, AgentRunning, AgentWorking, AgentIdle, AgentStuck, AgentDone, AgentStopped, AgentDead,
+}
+
+// Valid reports whether s is a known agent state.
+func (s AgentState) Valid() bool {
+	for _, known := range AgentStates {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Ended reports whether an agent in state s has finished for good, until
+// it is registered again.
+func (s AgentState) Ended() bool {
+	return s == AgentDone || s == AgentStopped || s == AgentDead
+}
+
+// Monitored reports whether an agent in state s is expected to heartbeat.
+// Idle agents are waiting for work and may stay quiet.
+func (s AgentState) Monitored() bool {
+	return s == AgentSpawning || s == AgentRunning || s == AgentWorking
+}
+
+// CheckAgentTransition reports whether an agent may move from one state to
+// another. Live agents move freely between live states and may end;
+// spawning is only entered by registering, which also restarts an agent
+// that has ended.
+func CheckAgentTransition(from, to AgentState) error {
+	switch {
+	case !to.Valid():
+		return fmt.Errorf("invalid agent state %q", to)
+	case from == to:
+		return nil
+	case from.Ended():
+		return fmt.Errorf("agent is %s; register it again to restart it", from)
+	case to == AgentSpawning:
+		return fmt.Errorf("an agent only enters spawning by registering")
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// CompactedContent is the content compaction replaces. A snapshot's
+// Original holds it as JSON, so restoring never has to guess where one
+// field ends and the next begins.
+type CompactedContent struct {
+	Title              string `json:"title"`
+	Description        string `json:"description,omitempty"`
+	Design             string `json:"design,omitempty"`
+	AcceptanceCriteria string `json:"acceptance_criteria,omitempty"`
+	Notes              string `json:"notes,omitempty"`
+}
+
+// CompactionOriginal captures the content compaction replaces, for a
+// CompactionSnapshot's Original.
+func CompactionOriginal(issue *Issue) string {
+	data, _ := json.Marshal(CompactedContent{
+		Title:              issue.Title,
+		Description:        issue.Description,
+		Design:             issue.Design,
+		AcceptanceCriteria: issue.AcceptanceCriteria,
+		Notes:              issue.Notes,
+	})
+	return string(data)
+}
+
+// ApplyOriginal restores the content recorded in s.Original onto issue.
+// Snapshots written before Original was JSON are read in their old
+// line-prefixed text format.
+func (s *CompactionSnapshot) ApplyOriginal(issue *Issue) {
+	var c CompactedContent
+	if err := json.Unmarshal([]byte(s.Original), &c); err != nil {
+		c = legacyOriginal(s.Original)
+	}
+	issue.Title = c.Title
+	issue.Description = c.Description
+	issue.Design = c.Design
+	issue.AcceptanceCriteria = c.AcceptanceCriteria
+	issue.Notes = c.Notes
+}
+
+// legacyOriginal parses the text format snapshots used to be written in: a
+// "Title: " line and, when set, "Description: ", "Design: ",
+// "Acceptance Criteria: " and "Notes: " lines, each continued by the lines
+// after it that start with no such prefix.
+func legacyOriginal(original string) CompactedContent {
+	var c CompactedContent
+	fields := []struct {
+		prefix string
+		value  *string
+	}{
+		{"Title: ", &c.Title},
+		{"Description: ", &c.Description},
+		{"Design: ", &c.Design},
+		{"Acceptance Criteria: ", &c.AcceptanceCriteria},
+		{"Notes: ", &c.Notes},
+	}
+	values := make([][]string, len(fields))
+	cur := -1
+	for _, line := range strings.Split(strings.TrimSuffix(original, "\n"), "\n") {
+		matched := false
+		for i, f := range fields {
+			if strings.HasPrefix(line, f.prefix) {
+				cur, matched = i, true
+				values[i] = []string{strings.TrimPrefix(line, f.prefix)}
+				break
+			}
+		}
+		if !matched && cur >= 0 {
+			values[cur] = append(values[cur], line)
+		}
+	}
+	for i, f := range fields {
+		*f.value = strings.Join(values[i], "\n")
+	}
+	return c
+}
+
+// Summarizers write the summary that replaces an issue's content when it is
+// compacted.
+const (
+	SummarizerHeuristic  = "heuristic"  // type, title, first lines and close reason
+	SummarizerExtractive = "extractive" // key sentences and acceptance-criteria outcomes
+	SummarizerEndpoint   = "endpoint"   // an OpenAI-compatible chat completions API
+)
+
+// Summarizers lists the valid CompactionSettings.Summarizer values.
+var Summarizers = []string{SummarizerHeuristic, SummarizerExtractive, SummarizerEndpoint}
+
+// CompactionSettings choose how a tenant's issues are summarized when they
+// are compacted.
+type CompactionSettings struct {
+	Summarizer string              `json:"summarizer"`
+	Budgets    CompactionBudgets   `json:"budgets"`
+	Endpoint   *CompactionEndpoint `json:"endpoint,omitempty"` // required by the endpoint summarizer
+	Default    bool                `json:"default"`            // no settings are stored; these are the defaults
+}
+
+// CompactionBudgets cap summary length, in characters, per compaction level.
+type CompactionBudgets struct {
+	Level1 int `json:"level1"`
+	Level2 int `json:"level2"`
+}
+
+// DefaultCompactionBudgets apply to tenants that set none.
+var DefaultCompactionBudgets = CompactionBudgets{Level1: 400, Level2: 120}
+
+// For returns the budget of compaction level; levels past 2 share level 2's.
+func (b CompactionBudgets) For(level int) int {
+	if level <= 1 {
+		return b.Level1
+	}
+	return b.Level2
+}
+
+// CompactionEndpoint is an OpenAI-compatible API the endpoint summarizer
+// calls. The bearer token is read from the server's environment, so it is
+// never stored or returned.
+type CompactionEndpoint struct {
+	URL       string `json:"url"` // base URL, e.g. http://localhost:11434/v1
+	Model     string `json:"model"`
+	APIKeyEnv string `json:"api_key_env,omitempty"` // environment variable holding the token
+}
+
+// SummarizerKeyEnvPrefix starts the name of every environment variable a
+// CompactionEndpoint may take its token from. Tenants choose the endpoint,
+// so they must not be able to have any other server secret sent to it.
+const SummarizerKeyEnvPrefix = "DOIT_SUMMARIZER_"
+
+// ValidKeyEnv reports whether name may hold a summarizer endpoint's token.
+func ValidKeyEnv(name string) bool {
+	return strings.HasPrefix(name, SummarizerKeyEnvPrefix) && len(name) > len(SummarizerKeyEnvPrefix)
+}
+
+// CompactionPolicy sets when a project's closed issues are compacted,
+// overriding what a compaction run is given. Pinned issues are never
+// compacted, whatever the policy.
+type CompactionPolicy struct {
+	ProjectID     string   `json:"project_id"`
+	Age           string   `json:"age,omitempty"`            // Go duration to level 1, twice it to level 2; empty keeps the run's
+	ExcludeLabels []string `json:"exclude_labels,omitempty"` // issues with any of these are never compacted
+	Default       bool     `json:"default"`                  // no policy is stored; the run's age applies
+}
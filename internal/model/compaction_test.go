@@ -0,0 +1,47 @@
+package model
+
+import "testing"
+
+func TestCompactionOriginalRoundTrip(t *testing.T) {
+	issue := &Issue{
+		Title:       "Fix login",
+		Description: "Users see a 500.\nSteps:\n1. log in",
+		Notes:       "Tracked in support.",
+	}
+	snap := CompactionSnapshot{Original: CompactionOriginal(issue)}
+
+	got := &Issue{Title: "t", Description: "summary", Design: "stale", AcceptanceCriteria: "stale"}
+	snap.ApplyOriginal(got)
+	if got.Title != issue.Title || got.Description != issue.Description || got.Notes != issue.Notes {
+		t.Errorf("restored %+v, want the content of %+v", got, issue)
+	}
+	if got.Design != "" || got.AcceptanceCriteria != "" {
+		t.Errorf("fields missing from the snapshot should be empty, got design %q, criteria %q", got.Design, got.AcceptanceCriteria)
+	}
+}
+
+func TestCompactionOriginalFieldPrefixes(t *testing.T) {
+	issue := &Issue{
+		Title:       "Title: looks like a prefix",
+		Description: "Reproduce with:\nNotes: not the notes field\nDesign: nor the design",
+		Design:      "Acceptance Criteria: still the design",
+	}
+	snap := CompactionSnapshot{Original: CompactionOriginal(issue)}
+
+	got := &Issue{Notes: "stale"}
+	snap.ApplyOriginal(got)
+	if got.Title != issue.Title || got.Description != issue.Description || got.Design != issue.Design ||
+		got.AcceptanceCriteria != "" || got.Notes != "" {
+		t.Errorf("restored %+v, want exactly the content of %+v", got, issue)
+	}
+}
+
+func TestApplyLegacyOriginal(t *testing.T) {
+	snap := CompactionSnapshot{Original: "Title: Fix login\nDescription: Users see a 500.\nSteps:\nNotes: Tracked in support.\n"}
+
+	got := &Issue{Design: "stale"}
+	snap.ApplyOriginal(got)
+	if got.Title != "Fix login" || got.Description != "Users see a 500.\nSteps:" || got.Notes != "Tracked in support." || got.Design != "" {
+		t.Errorf("restored %+v from a text snapshot", got)
+	}
+}
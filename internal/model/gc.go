@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// WispTypes lists every wisp type, in the order they are reported.
+var WispTypes = []WispType{
+	WispHeartbeat, WispPing, WispPatrol, WispGCReport, WispRecovery, WispError, WispEscalation,
+}
+
+// DefaultWispRetention is how long each type of wisp is kept unless the
+// tenant's GCPolicy says otherwise. Liveness chatter goes fast; records
+// someone may need to look back at stay longer.
+var DefaultWispRetention = map[WispType]time.Duration{
+	WispHeartbeat:  time.Hour,
+	WispPing:       time.Hour,
+	WispPatrol:     24 * time.Hour,
+	WispGCReport:   30 * 24 * time.Hour,
+	WispRecovery:   7 * 24 * time.Hour,
+	WispError:      7 * 24 * time.Hour,
+	WispEscalation: 30 * 24 * time.Hour,
+}
+
+// DefaultEphemeralRetention is how long closed ephemeral issues that are
+// not wisps are kept unless the tenant's GCPolicy says otherwise.
+const DefaultEphemeralRetention = 24 * time.Hour
+
+// GCPolicy is how long a tenant keeps ephemeral issues before garbage
+// collection deletes them for good. Durations are Go duration strings.
+type GCPolicy struct {
+	// Retention is how long wisps of each type are kept after they were
+	// closed or, while open, last updated.
+	Retention map[WispType]string `json:"retention"`
+	// Ephemeral is how long other ephemeral issues are kept after they
+	// were closed. Open ones, such as unread messages, are kept.
+	Ephemeral string `json:"ephemeral"`
+	// Report rolls each collection into one gc_report wisp listing what
+	// was deleted.
+	Report  bool `json:"report"`
+	Default bool `json:"default"` // true when the tenant has not set a policy
+}
@@ -0,0 +1,46 @@
+package model
+
+// Dependency families that must stay acyclic. Edges of other types (related,
+// duplicates, ...) are informational and may form cycles freely.
+const (
+	FamilyBlocking  = "blocking"  // blocks, conditional-blocks, waits-for
+	FamilyHierarchy = "hierarchy" // parent-child
+)
+
+// CycleFamily returns the acyclic family t belongs to, or "" if edges of
+// type t may form cycles.
+func (t DependencyType) CycleFamily() string {
+	switch t {
+	case DepBlocks, DepConditionalBlocks, DepWaitsFor:
+		return FamilyBlocking
+	case DepParentChild:
+		return FamilyHierarchy
+	}
+	return ""
+}
+
+// GraphReport is the outcome of validating one tenant's dependency graph.
+type GraphReport struct {
+	TenantID           string          `json:"tenant_id"`
+	Issues             int             `json:"issues"`
+	Dependencies       int             `json:"dependencies"`
+	Valid              bool            `json:"valid"`
+	Cycles             []GraphCycle    `json:"cycles"`
+	OrphanedChildren   []OrphanedChild `json:"orphaned_children"`
+	DanglingReferences []Dependency    `json:"dangling_references"`
+}
+
+// GraphCycle is a cycle among edges of one family. Path starts and ends
+// with the same issue, following each edge from issue to depends_on.
+type GraphCycle struct {
+	Family string   `json:"family"`
+	Path   []string `json:"path"`
+}
+
+// OrphanedChild is an issue with a hierarchical ID (parent.N) that has no
+// parent-child dependency, usually because its parent was deleted.
+type OrphanedChild struct {
+	IssueID      string `json:"issue_id"`
+	ParentID     string `json:"parent_id"` // the parent implied by the ID
+	ParentExists bool   `json:"parent_exists"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// IssueVersion is an issue as it stood at a past moment, rebuilt from its
+// audit trail and compaction snapshots. Dependencies are the issue's own
+// edges (those it depends on); fields the audit trail does not track, such
+// as the type and the lease, keep their current values.
+type IssueVersion struct {
+	At           time.Time    `json:"at"`
+	Issue        Issue        `json:"issue"`
+	Dependencies []Dependency `json:"dependencies"`
+	Undone       int          `json:"undone"` // events after At that were rolled back
+}
+
+// IssueDiff lists the fields of an issue that differ between two moments.
+type IssueDiff struct {
+	IssueID string        `json:"issue_id"`
+	From    time.Time     `json:"from"`
+	To      time.Time     `json:"to"`
+	Changes []FieldChange `json:"changes"`
+	Actors  []string      `json:"actors"` // who made changes in between, sorted
+}
+
+// FieldChange is one field's value at the start and end of an IssueDiff.
+// Labels and dependencies are rendered as sorted, comma-separated lists.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// ParseHistoryTime parses a point in an issue's history: an RFC 3339
+// timestamp or a bare date, meaning the start of that day in UTC.
+func ParseHistoryTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: want RFC 3339 or YYYY-MM-DD", s)
+}
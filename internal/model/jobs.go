@@ -0,0 +1,18 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobRun is one run of a background job for a tenant.
+type JobRun struct {
+	ID         int64           `json:"id"`
+	Job        string          `json:"job"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Succeeded  bool            `json:"succeeded"`
+	Result     json.RawMessage `json:"result,omitempty"` // what the job did; its shape depends on the job
+	Error      string          `json:"error,omitempty"`
+	Runner     string          `json:"runner"` // host and process that ran it
+}
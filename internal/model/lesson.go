@@ -70,4 +70,5 @@ type LessonFilter struct {
 	Component *string // filter by component (ANY match)
 	Severity  *int
 	Limit     int
+	Cursor    string // resume after this position (see store.LessonCursor)
 }
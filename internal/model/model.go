@@ -31,6 +31,8 @@ const (
 	TypeMessage  IssueType = "message"
 	TypeMolecule IssueType = "molecule"
 	TypeEvent    IssueType = "event"
+	TypeAgent    IssueType = "agent"
+	TypeGate     IssueType = "gate"
 )
 
 // DependencyType represents the kind of relationship between two issues.
@@ -102,6 +104,14 @@ const (
 	AgentDead     AgentState = "dead"
 )
 
+// Await types say what a gate issue (AwaitType) waits on.
+const (
+	AwaitTimer  = "timer"  // Timeout has passed since the gate was created
+	AwaitIssue  = "issue"  // the issue AwaitID is closed
+	AwaitFlag   = "flag"   // the flag AwaitID is resolved
+	AwaitSignal = "signal" // the signal named AwaitID arrives
+)
+
 // EventType categorizes audit trail entries.
 type EventType string
 
@@ -117,6 +127,9 @@ const (
 	EventLabelAdded        EventType = "label_added"
 	EventLabelRemoved      EventType = "label_removed"
 	EventCompacted         EventType = "compacted"
+	EventDeleted           EventType = "deleted"
+	EventRestored          EventType = "restored"
+	EventJoined            EventType = "joined" // an agent entered an open_competition molecule step
 )
 
 // Issue is the universal work item. Every task, bug, epic, message, molecule,
@@ -141,6 +154,15 @@ type Issue struct {
 	Assignee string `json:"assignee,omitempty" db:"assignee"`
 	Owner    string `json:"owner,omitempty" db:"owner"`
 
+	// Claim lease: an in_progress issue is released back to open once this
+	// passes without a heartbeat. Nil means the claim does not expire.
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty" db:"lease_expires_at"`
+
+	// Trash: a deleted issue is hidden from every query until it is
+	// restored or purged.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	DeletedBy string     `json:"deleted_by,omitempty" db:"deleted_by"`
+
 	// Time estimates
 	EstimatedMinutes *int `json:"estimated_minutes,omitempty" db:"estimated_minutes"`
 
@@ -282,6 +304,7 @@ type Event struct {
 	IssueID   string    `json:"issue_id" db:"issue_id"`
 	EventType EventType `json:"event_type" db:"event_type"`
 	Actor     string    `json:"actor" db:"actor"`
+	Field     string    `json:"field,omitempty" db:"field"` // issue field changed, e.g. "status", "priority" or "labels"
 	OldValue  string    `json:"old_value,omitempty" db:"old_value"`
 	NewValue  string    `json:"new_value,omitempty" db:"new_value"`
 	Comment   string    `json:"comment,omitempty" db:"comment"`
@@ -303,10 +326,13 @@ type TreeNode struct {
 }
 
 // CompactionSnapshot preserves original issue content before memory decay.
+// Original is the content at FromLevel, the level the issue was compacted
+// from, as CompactionOriginal writes it.
 type CompactionSnapshot struct {
 	ID        int64     `json:"id" db:"id"`
 	IssueID   string    `json:"issue_id" db:"issue_id"`
 	Level     int       `json:"level" db:"level"`
+	FromLevel int       `json:"from_level" db:"from_level"`
 	Summary   string    `json:"summary" db:"summary"`
 	Original  string    `json:"original" db:"original"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
@@ -321,6 +347,8 @@ const (
 	FlagRedFlag           FlagType = "red_flag"
 	FlagHumanDecision     FlagType = "human_decision"
 	FlagSecurityConcern   FlagType = "security_concern"
+	FlagAgentStuck        FlagType = "agent_stuck"  // raised by the agent monitor
+	FlagGateTimeout       FlagType = "gate_timeout" // raised when a gate outlives its timeout
 )
 
 // FlagStatus represents the lifecycle state of a flag.
@@ -357,6 +385,7 @@ type FlagFilter struct {
 	Severity  *int        `json:"severity,omitempty"`
 	IssueID   *string     `json:"issue_id,omitempty"`
 	Limit     int         `json:"limit,omitempty"`
+	Cursor    string      `json:"cursor,omitempty"` // resume after this position (see store.FlagCursor)
 }
 
 // IssueFilter provides comprehensive filtering for issue queries.
@@ -381,5 +410,6 @@ type IssueFilter struct {
 	Overdue      *bool      `json:"overdue,omitempty"`
 	Limit        int        `json:"limit,omitempty"`
 	Offset       int        `json:"offset,omitempty"`
+	Cursor       string     `json:"cursor,omitempty"` // resume after this position; takes precedence over Offset
 	SortBy       string     `json:"sort_by,omitempty"` // "priority", "oldest", "updated", "hybrid"
 }
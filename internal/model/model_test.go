@@ -73,3 +73,52 @@ func TestToCompactListEmpty(t *testing.T) {
 		t.Fatalf("len = %d, want 0", len(compacts))
 	}
 }
+
+func TestIsFailureCloseReason(t *testing.T) {
+	for reason, want := range map[string]bool{
+		"Failed: upstream rejected the patch": true,
+		"  rejected by review":                true,
+		"won't fix":                           true,
+		"Timed out after 3 attempts":          true,
+		"cancelled":                           true,
+		"fixed failing test":                  false,
+		"done":                                false,
+		"":                                    false,
+	} {
+		if got := IsFailureCloseReason(reason); got != want {
+			t.Errorf("IsFailureCloseReason(%q) = %v, want %v", reason, got, want)
+		}
+	}
+}
+
+func TestReadyPolicyApplies(t *testing.T) {
+	var none *ReadyPolicy
+	if !none.Applies(DepUntil) || none.Applies(DepRelated) {
+		t.Error("a nil policy should apply every ready gate and nothing else")
+	}
+	p := &ReadyPolicy{Gates: []DependencyType{DepBlocks}}
+	if !p.Applies(DepBlocks) || p.Applies(DepParentChild) {
+		t.Errorf("policy %v applies the wrong gates", p.Gates)
+	}
+}
+
+func TestCheckAgentTransition(t *testing.T) {
+	for _, c := range []struct {
+		from, to AgentState
+		ok       bool
+	}{
+		{AgentSpawning, AgentRunning, true},
+		{AgentRunning, AgentWorking, true},
+		{AgentStuck, AgentRunning, true},
+		{AgentWorking, AgentDead, true},
+		{AgentDone, AgentDone, true},
+		{AgentRunning, AgentSpawning, false},
+		{AgentDone, AgentRunning, false},
+		{AgentDead, AgentSpawning, false},
+		{AgentRunning, "asleep", false},
+	} {
+		if err := CheckAgentTransition(c.from, c.to); (err == nil) != c.ok {
+			t.Errorf("CheckAgentTransition(%s, %s) = %v, want ok=%v", c.from, c.to, err, c.ok)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// ReadyGates lists the dependency types that can hold an issue back from
+// the ready queue. For an edge issue → depends_on, the issue is gated by
+//
+//	blocks              until depends_on is closed
+//	conditional-blocks  until depends_on is closed with a failure reason
+//	                    (see IsFailureCloseReason); the issue is the fallback
+//	                    path, so a successful close keeps it gated for good
+//	waits-for           until depends_on and all of its children are closed
+//	until               once depends_on is closed; the issue is only worth
+//	                    doing while depends_on is still open
+//	parent-child        while any ancestor is blocked, deferred, or itself
+//	                    held back by one of its own gates
+//
+// A project's ReadyPolicy may switch individual gates off; by default all
+// of them apply.
+var ReadyGates = []DependencyType{
+	DepBlocks, DepConditionalBlocks, DepWaitsFor, DepUntil, DepParentChild,
+}
+
+// IsReadyGate reports whether edges of type t can hold an issue back from
+// the ready queue.
+func (t DependencyType) IsReadyGate() bool {
+	for _, g := range ReadyGates {
+		if t == g {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadyPolicy selects which ReadyGates apply to the issues of a project.
+// Issues without a project always use every gate.
+type ReadyPolicy struct {
+	ProjectID string           `json:"project_id"`
+	Gates     []DependencyType `json:"gates"`
+	Default   bool             `json:"default"` // no policy is set; Gates is ReadyGates
+}
+
+// Applies reports whether the policy applies gate t. A nil policy applies
+// every gate.
+func (p *ReadyPolicy) Applies(t DependencyType) bool {
+	if p == nil || p.Default {
+		return t.IsReadyGate()
+	}
+	for _, g := range p.Gates {
+		if t == g {
+			return true
+		}
+	}
+	return false
+}
+
+// failureCloseReasonPrefixes are the close_reason openings that mark an
+// issue as closed unsuccessfully. The SQL views match the same list.
+var failureCloseReasonPrefixes = []string{
+	"fail", "reject", "abort", "abandon", "cancel",
+	"wontfix", "won't fix", "timed out", "timeout",
+}
+
+// IsFailureCloseReason reports whether reason records an unsuccessful
+// close, e.g. "failed: flaky upstream" or "Rejected by review". Only the
+// start of the reason counts, so "fixed failing test" is a success.
+func IsFailureCloseReason(reason string) bool {
+	reason = strings.ToLower(strings.TrimSpace(reason))
+	for _, p := range failureCloseReasonPrefixes {
+		if strings.HasPrefix(reason, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Kinds of ReadyReason.
+const (
+	ReadyReasonStatus     = "status"     // the issue is not open
+	ReadyReasonEphemeral  = "ephemeral"  // wisps are never ready
+	ReadyReasonTemplate   = "template"   // templates are blueprints, never ready
+	ReadyReasonDeferred   = "deferred"   // defer_until is in the future
+	ReadyReasonFlag       = "flag"       // an open severity 1-2 flag
+	ReadyReasonDependency = "dependency" // one of the issue's own gates holds
+	ReadyReasonAncestor   = "ancestor"   // an ancestor is held back
+)
+
+// ReadyExplanation says why an issue is or is not in the ready queue.
+// Ready is true exactly when Reasons is empty.
+type ReadyExplanation struct {
+	IssueID string        `json:"issue_id"`
+	Status  Status        `json:"status"`
+	Ready   bool          `json:"ready"`
+	Reasons []ReadyReason `json:"reasons"`
+}
+
+// ReadyReason is one thing keeping an issue out of the ready queue.
+type ReadyReason struct {
+	Kind       string         `json:"kind"`
+	Message    string         `json:"message"`
+	Type       DependencyType `json:"type,omitempty"`     // dependency: the gating edge's type
+	IssueID    string         `json:"issue_id,omitempty"` // dependency: the edge's target; ancestor: the ancestor
+	FlagID     string         `json:"flag_id,omitempty"`
+	DeferUntil *time.Time     `json:"defer_until,omitempty"`
+	Blockers   []Blocker      `json:"blockers,omitempty"`
+}
+
+// Blocker is an unfinished issue holding another one back. BlockedBy
+// lists what holds the blocker itself back, and so on down the chain.
+type Blocker struct {
+	ID        string         `json:"id"`
+	Title     string         `json:"title"`
+	Status    Status         `json:"status"`
+	Assignee  string         `json:"assignee,omitempty"`
+	Via       DependencyType `json:"via"` // the edge type it holds back through
+	BlockedBy []Blocker      `json:"blocked_by,omitempty"`
+	Repeated  bool           `json:"repeated,omitempty"` // already expanded earlier in the explanation
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Schedule is a critical-path analysis of an epic's unfinished work,
+// assuming as many agents as there are parallel tasks. Times in
+// ScheduledTask are minutes after Start.
+type Schedule struct {
+	EpicID         string          `json:"epic_id"`
+	Start          time.Time       `json:"start"`
+	TotalMinutes   int             `json:"total_minutes"`   // length of the critical path
+	EarliestFinish time.Time       `json:"earliest_finish"` // Start + TotalMinutes
+	CriticalPath   []string        `json:"critical_path"`   // task IDs, first to last
+	Tasks          []ScheduledTask `json:"tasks"`           // by earliest start, then slack
+	Unestimated    []string        `json:"unestimated"`     // tasks counted as zero minutes
+}
+
+// ScheduledTask is one unfinished issue in a Schedule. Slack is how long
+// the task can slip without delaying the epic; critical tasks have none.
+type ScheduledTask struct {
+	ID               string   `json:"id"`
+	Title            string   `json:"title"`
+	Status           Status   `json:"status"`
+	Assignee         string   `json:"assignee,omitempty"`
+	EstimatedMinutes int      `json:"estimated_minutes"`
+	DependsOn        []string `json:"depends_on,omitempty"`        // predecessors within the epic
+	ExternalBlockers []string `json:"external_blockers,omitempty"` // open blockers outside the epic
+	EarliestStart    int      `json:"earliest_start"`
+	EarliestFinish   int      `json:"earliest_finish"`
+	LatestStart      int      `json:"latest_start"`
+	LatestFinish     int      `json:"latest_finish"`
+	Slack            int      `json:"slack"`
+	Critical         bool     `json:"critical"`
+}
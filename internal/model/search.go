@@ -0,0 +1,50 @@
+package model
+
+// SnippetMark brackets matched terms in SearchResult.Snippet, e.g.
+// "retry the **migration** after". Renderers may swap it for real markup.
+const SnippetMark = "**"
+
+// SearchQuery describes a full-text search over issues.
+//
+// Query uses web-search syntax: words are ANDed, "quoted phrases" match
+// in order, and a leading - excludes a word.
+type SearchQuery struct {
+	Query     string
+	Status    *Status
+	IssueType *IssueType
+	ProjectID *string
+	Limit     int
+}
+
+// SearchResult is an issue matched by full-text search, with its relevance
+// rank (higher is better) and a highlighted excerpt of the matching text.
+type SearchResult struct {
+	Issue   Issue   `json:"issue"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+// CompactSearchResult is a minimal representation of a SearchResult for list responses.
+type CompactSearchResult struct {
+	CompactIssue
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+// ToCompact converts a SearchResult to its compact form.
+func (r *SearchResult) ToCompact() CompactSearchResult {
+	return CompactSearchResult{
+		CompactIssue: r.Issue.ToCompact(),
+		Rank:         r.Rank,
+		Snippet:      r.Snippet,
+	}
+}
+
+// ToCompactSearchList converts a slice of SearchResults to CompactSearchResults.
+func ToCompactSearchList(results []SearchResult) []CompactSearchResult {
+	out := make([]CompactSearchResult, len(results))
+	for i := range results {
+		out[i] = results[i].ToCompact()
+	}
+	return out
+}
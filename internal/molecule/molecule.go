@@ -0,0 +1,489 @@
+// Package molecule runs molecules: issues of type molecule whose children
+// are the steps of a piece of work shared between agents.
+//
+// A molecule's MolType says how its steps run. Work steps run one after
+// another, each blocked by the one before; swarm and patrol steps all run
+// at once, and patrol steps are ephemeral patrol wisps unless asked
+// otherwise. Its WorkType says how agents share a step: under mutex one
+// agent claims it and holds it until it closes; under open_competition any
+// number join and the first to complete it wins.
+//
+// A molecule's status follows its steps: open until one is started, in
+// progress until all are closed, then closed. A molecule that crystallizes
+// turns its ephemeral steps into permanent issues when it closes, so the
+// record of the work outlives garbage collection.
+package molecule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+// MaxSteps caps the steps of one molecule, which is created in a single
+// batch along with a dependency per work step.
+const MaxSteps = (store.MaxBatchOps - 1) / 2
+
+// Engine creates molecules and moves their steps along.
+type Engine struct {
+	store store.Store
+}
+
+func New(s store.Store) *Engine {
+	return &Engine{store: s}
+}
+
+// Step describes a step to create.
+type Step struct {
+	Title              string
+	Description        string
+	AcceptanceCriteria string
+	EstimatedMinutes   *int
+}
+
+// CreateInput describes a molecule to create.
+type CreateInput struct {
+	Title        string
+	Description  string
+	MolType      model.MolType
+	WorkType     model.WorkType // mutex when empty
+	Crystallizes bool
+	// Ephemeral makes the steps ephemeral; nil leaves patrol steps
+	// ephemeral and the others not.
+	Ephemeral *bool
+	Priority  int
+	ProjectID string
+	Labels    []string // for the molecule itself
+	CreatedBy string
+	Steps     []Step
+}
+
+// Molecule is a molecule issue with its steps in order.
+type Molecule struct {
+	model.Issue
+	Steps    []StepStatus `json:"steps"`
+	Progress Progress     `json:"progress"`
+}
+
+// StepStatus is where one step stands.
+type StepStatus struct {
+	ID          string       `json:"id"`
+	Title       string       `json:"title"`
+	Status      model.Status `json:"status"`
+	Assignee    string       `json:"assignee,omitempty"`
+	Ephemeral   bool         `json:"ephemeral,omitempty"`
+	CloseReason string       `json:"close_reason,omitempty"`
+	// Competitors are the agents that joined an open_competition step, in
+	// the order they joined; Winner is the one that completed it.
+	Competitors []string `json:"competitors,omitempty"`
+	Winner      string   `json:"winner,omitempty"`
+}
+
+// Progress counts a molecule's steps by status.
+type Progress struct {
+	Total      int `json:"total"`
+	Open       int `json:"open"` // not started, including blocked and deferred steps
+	InProgress int `json:"in_progress"`
+	Closed     int `json:"closed"`
+}
+
+// Create makes the molecule and all its steps in one batch, so a molecule
+// never exists half-built.
+func (e *Engine) Create(ctx context.Context, input CreateInput) (*Molecule, error) {
+	if strings.TrimSpace(input.Title) == "" {
+		return nil, fmt.Errorf("molecule title is required")
+	}
+	switch input.MolType {
+	case model.MolSwarm, model.MolPatrol, model.MolWork:
+	default:
+		return nil, fmt.Errorf("invalid mol_type %q: want swarm, patrol or work", input.MolType)
+	}
+	workType := input.WorkType
+	switch workType {
+	case "":
+		workType = model.WorkMutex
+	case model.WorkMutex, model.WorkOpenCompetition:
+	default:
+		return nil, fmt.Errorf("invalid work_type %q: want mutex or open_competition", workType)
+	}
+	if len(input.Steps) == 0 {
+		return nil, fmt.Errorf("a molecule needs at least one step")
+	}
+	if len(input.Steps) > MaxSteps {
+		return nil, fmt.Errorf("molecule has %d steps; the limit is %d", len(input.Steps), MaxSteps)
+	}
+	ephemeral := input.MolType == model.MolPatrol
+	if input.Ephemeral != nil {
+		ephemeral = *input.Ephemeral
+	}
+	var wispType model.WispType
+	if ephemeral && input.MolType == model.MolPatrol {
+		wispType = model.WispPatrol
+	}
+
+	ops := []store.BatchOp{{
+		Ref: "mol",
+		Create: &store.CreateIssueInput{
+			Title:        input.Title,
+			Description:  input.Description,
+			Priority:     input.Priority,
+			IssueType:    model.TypeMolecule,
+			CreatedBy:    input.CreatedBy,
+			ProjectID:    input.ProjectID,
+			Labels:       input.Labels,
+			MolType:      input.MolType,
+			WorkType:     workType,
+			Crystallizes: input.Crystallizes,
+		},
+	}}
+	for i, step := range input.Steps {
+		if strings.TrimSpace(step.Title) == "" {
+			return nil, fmt.Errorf("step %d has no title", i+1)
+		}
+		ref := "step" + strconv.Itoa(i)
+		ops = append(ops, store.BatchOp{
+			Ref: ref,
+			Create: &store.CreateIssueInput{
+				Title:              step.Title,
+				Description:        step.Description,
+				AcceptanceCriteria: step.AcceptanceCriteria,
+				Priority:           input.Priority,
+				IssueType:          model.TypeTask,
+				CreatedBy:          input.CreatedBy,
+				ProjectID:          input.ProjectID,
+				ParentID:           "$mol",
+				EstimatedMinutes:   step.EstimatedMinutes,
+				Ephemeral:          ephemeral,
+				WispType:           wispType,
+			},
+		})
+		if input.MolType == model.MolWork && i > 0 {
+			ops = append(ops, store.BatchOp{Dependency: &store.AddDependencyInput{
+				IssueID:     "$" + ref,
+				DependsOnID: "$step" + strconv.Itoa(i-1),
+				Type:        model.DepBlocks,
+				CreatedBy:   input.CreatedBy,
+			}})
+		}
+	}
+
+	results, err := e.store.ApplyBatch(ctx, ops)
+	if err != nil {
+		return nil, fmt.Errorf("creating molecule: %w", err)
+	}
+	return e.Get(ctx, results[0].IssueID)
+}
+
+// Get returns the molecule with its steps.
+func (e *Engine) Get(ctx context.Context, id string) (*Molecule, error) {
+	mol, err := e.store.GetIssue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if mol.IssueType != model.TypeMolecule {
+		return nil, fmt.Errorf("%s is a %s, not a molecule", id, mol.IssueType)
+	}
+	steps, err := e.steps(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Molecule{Issue: *mol, Steps: make([]StepStatus, 0, len(steps))}
+	for _, s := range steps {
+		st := StepStatus{
+			ID:          s.ID,
+			Title:       s.Title,
+			Status:      s.Status,
+			Assignee:    s.Assignee,
+			Ephemeral:   s.Ephemeral,
+			CloseReason: s.CloseReason,
+		}
+		if mol.WorkType == model.WorkOpenCompetition {
+			if st.Competitors, err = e.competitors(ctx, s.ID); err != nil {
+				return nil, err
+			}
+			if s.Status == model.StatusClosed {
+				st.Winner = s.Assignee
+			}
+		}
+		m.Steps = append(m.Steps, st)
+
+		m.Progress.Total++
+		switch s.Status {
+		case model.StatusClosed:
+			m.Progress.Closed++
+		case model.StatusInProgress:
+			m.Progress.InProgress++
+		default:
+			m.Progress.Open++
+		}
+	}
+	return m, nil
+}
+
+// steps returns the molecule's children in the order they were created.
+func (e *Engine) steps(ctx context.Context, molID string) ([]model.Issue, error) {
+	steps, err := e.store.ListIssues(ctx, model.IssueFilter{ParentID: &molID})
+	if err != nil {
+		return nil, fmt.Errorf("listing steps of %s: %w", molID, err)
+	}
+	// Child IDs end in a counter, so "x.10" must sort after "x.9".
+	slices.SortFunc(steps, func(a, b model.Issue) int { return store.CompareChildIDs(a.ID, b.ID) })
+	return steps, nil
+}
+
+// competitors returns the agents that joined step, first to join first.
+func (e *Engine) competitors(ctx context.Context, stepID string) ([]string, error) {
+	events, err := e.store.ListEvents(ctx, stepID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("listing events of %s: %w", stepID, err)
+	}
+	var agents []string
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].EventType == model.EventJoined && !slices.Contains(agents, events[i].Actor) {
+			agents = append(agents, events[i].Actor)
+		}
+	}
+	return agents, nil
+}
+
+// step loads a step and the molecule it belongs to.
+func (e *Engine) step(ctx context.Context, id string) (*model.Issue, *model.Issue, error) {
+	step, err := e.store.GetIssue(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if step.ParentID != "" {
+		mol, err := e.store.GetIssue(ctx, step.ParentID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if mol.IssueType == model.TypeMolecule {
+			return step, mol, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%s is not a molecule step", id)
+}
+
+// Claim puts agent on a step. Under mutex the agent takes the step for
+// lease, failing if another agent holds it, and claiming again renews the
+// lease. Under open_competition the agent joins the step alongside any
+// others. Work steps can only be claimed once the steps before them close.
+func (e *Engine) Claim(ctx context.Context, stepID, agent string, lease time.Duration) (*Molecule, error) {
+	step, mol, err := e.step(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	if step.Status == model.StatusClosed {
+		return nil, closedError(step, mol)
+	}
+	if mol.MolType == model.MolWork {
+		if err := e.checkTurn(ctx, mol.ID, step.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	inProgress := model.StatusInProgress
+	if mol.WorkType == model.WorkOpenCompetition {
+		if err := e.join(ctx, step.ID, agent); err != nil {
+			return nil, err
+		}
+		if step.Status != model.StatusInProgress {
+			if _, err := e.store.UpdateIssue(ctx, step.ID, store.UpdateIssueInput{Status: &inProgress}); err != nil {
+				return nil, fmt.Errorf("starting step %s: %w", step.ID, err)
+			}
+		}
+		return e.Sync(ctx, mol.ID)
+	}
+
+	if holder := holder(step); holder != "" && holder != agent {
+		return nil, fmt.Errorf("step %s is held by %s", step.ID, holder)
+	}
+	_, err = e.store.UpdateIssue(ctx, step.ID, store.UpdateIssueInput{
+		Status:            &inProgress,
+		Assignee:          &agent,
+		Lease:             &lease,
+		ExpectedUpdatedAt: &step.UpdatedAt,
+	})
+	var conflict *store.ConflictError
+	if errors.As(err, &conflict) && conflict.Current != nil {
+		if holder := holder(conflict.Current); holder != "" && holder != agent {
+			return nil, fmt.Errorf("step %s was claimed by %s first", step.ID, holder)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claiming step %s: %w", step.ID, err)
+	}
+	return e.Sync(ctx, mol.ID)
+}
+
+// Complete closes a step for agent. Under mutex only the agent holding the
+// step may close it. Under open_competition the first agent to complete
+// the step wins it, and those after are told who did.
+func (e *Engine) Complete(ctx context.Context, stepID, agent, reason string) (*Molecule, error) {
+	step, mol, err := e.step(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	if step.Status == model.StatusClosed {
+		return nil, closedError(step, mol)
+	}
+	if mol.WorkType == model.WorkOpenCompetition {
+		if err := e.join(ctx, step.ID, agent); err != nil {
+			return nil, err
+		}
+	} else if holder := holder(step); holder != "" && holder != agent {
+		return nil, fmt.Errorf("step %s is held by %s", step.ID, holder)
+	}
+	if reason == "" {
+		reason = "completed"
+	}
+
+	closed, noLease := model.StatusClosed, time.Duration(0)
+	_, err = e.store.UpdateIssue(ctx, step.ID, store.UpdateIssueInput{
+		Status:            &closed,
+		Assignee:          &agent,
+		CloseReason:       &reason,
+		Lease:             &noLease,
+		ExpectedUpdatedAt: &step.UpdatedAt,
+	})
+	var conflict *store.ConflictError
+	if errors.As(err, &conflict) && conflict.Current != nil && conflict.Current.Status == model.StatusClosed {
+		return nil, closedError(conflict.Current, mol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("completing step %s: %w", step.ID, err)
+	}
+	return e.Sync(ctx, mol.ID)
+}
+
+// holder returns the agent holding a mutex step, if any.
+func holder(step *model.Issue) string {
+	if step.Status != model.StatusInProgress {
+		return ""
+	}
+	return step.Assignee
+}
+
+func closedError(step, mol *model.Issue) error {
+	if mol.WorkType == model.WorkOpenCompetition && step.Assignee != "" {
+		return fmt.Errorf("step %s was already won by %s", step.ID, step.Assignee)
+	}
+	return fmt.Errorf("step %s is already closed", step.ID)
+}
+
+// join records agent as a competitor for step, once.
+func (e *Engine) join(ctx context.Context, stepID, agent string) error {
+	joined, err := e.competitors(ctx, stepID)
+	if err != nil || slices.Contains(joined, agent) {
+		return err
+	}
+	if _, err := e.store.AddEvent(ctx, store.AddEventInput{
+		IssueID:   stepID,
+		EventType: model.EventJoined,
+		Actor:     agent,
+	}); err != nil {
+		return fmt.Errorf("joining step %s: %w", stepID, err)
+	}
+	return nil
+}
+
+// checkTurn fails unless every step of a work molecule before stepID is
+// closed.
+func (e *Engine) checkTurn(ctx context.Context, molID, stepID string) error {
+	steps, err := e.steps(ctx, molID)
+	if err != nil {
+		return err
+	}
+	for _, s := range steps {
+		if s.ID == stepID {
+			return nil
+		}
+		if s.Status != model.StatusClosed {
+			return fmt.Errorf("step %s waits on step %s", stepID, s.ID)
+		}
+	}
+	return nil
+}
+
+// Sync brings the molecule's status into line with its steps and, once it
+// closes, crystallizes it if it should. It returns the molecule as synced.
+func (e *Engine) Sync(ctx context.Context, id string) (*Molecule, error) {
+	m, err := e.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	want, ok := derivedStatus(m)
+	if ok && want != m.Status {
+		input := store.UpdateIssueInput{Status: &want}
+		if want == model.StatusClosed {
+			reason := fmt.Sprintf("all %d steps closed", m.Progress.Total)
+			input.CloseReason = &reason
+		}
+		if _, err := e.store.UpdateIssue(ctx, id, input); err != nil {
+			return nil, fmt.Errorf("updating molecule %s: %w", id, err)
+		}
+	}
+	if want == model.StatusClosed && m.Crystallizes {
+		if err := e.crystallize(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+	return e.Get(ctx, id)
+}
+
+// SyncParent syncs the molecule issueID is a step of, if it is one, and
+// returns it as synced; nil if issueID is not a step. Tools that change
+// any issue's status call it, so steps moved by hand still move their
+// molecule.
+func (e *Engine) SyncParent(ctx context.Context, issueID string) (*Molecule, error) {
+	issue, err := e.store.GetIssue(ctx, issueID)
+	if err != nil || issue.ParentID == "" {
+		return nil, err
+	}
+	parent, err := e.store.GetIssue(ctx, issue.ParentID)
+	if err != nil || parent.IssueType != model.TypeMolecule {
+		return nil, err
+	}
+	return e.Sync(ctx, parent.ID)
+}
+
+// derivedStatus is the status m's steps call for. A molecule without steps
+// keeps its own, and one blocked or deferred by hand stays so until a step
+// starts.
+func derivedStatus(m *Molecule) (model.Status, bool) {
+	p := m.Progress
+	switch {
+	case p.Total == 0:
+		return "", false
+	case p.Closed == p.Total:
+		return model.StatusClosed, true
+	case p.InProgress > 0 || p.Closed > 0:
+		return model.StatusInProgress, true
+	case m.Status == model.StatusInProgress || m.Status == model.StatusClosed:
+		return model.StatusOpen, true
+	default:
+		return "", false
+	}
+}
+
+// crystallize makes m's ephemeral steps permanent.
+func (e *Engine) crystallize(ctx context.Context, m *Molecule) error {
+	permanent := false
+	for _, s := range m.Steps {
+		if !s.Ephemeral {
+			continue
+		}
+		if _, err := e.store.UpdateIssue(ctx, s.ID, store.UpdateIssueInput{Ephemeral: &permanent}); err != nil {
+			return fmt.Errorf("crystallizing step %s: %w", s.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package molecule
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/gc"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/Actual-Outcomes/doit/internal/store/storetest"
+)
+
+func steps(titles ...string) []Step {
+	out := make([]Step, len(titles))
+	for i, title := range titles {
+		out[i] = Step{Title: title}
+	}
+	return out
+}
+
+func TestWorkMoleculeMutex(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	e := New(s)
+	m, err := e.Create(ctx, CreateInput{
+		Title:   "Ship release",
+		MolType: model.MolWork,
+		Steps:   steps("build", "test", "deploy"),
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if m.WorkType != model.WorkMutex || m.Status != model.StatusOpen || m.Progress.Total != 3 || m.Steps[0].Ephemeral {
+		t.Fatalf("created %+v, want an open mutex molecule with 3 permanent steps", m)
+	}
+	build, test := m.Steps[0].ID, m.Steps[1].ID
+	if test != m.ID+".2" {
+		t.Errorf("second step is %s, want %s.2", test, m.ID)
+	}
+	if _, err := e.Claim(ctx, test, "alice", time.Hour); err == nil || !strings.Contains(err.Error(), "waits on step "+build) {
+		t.Errorf("claiming a step out of turn: %v, want it to wait on %s", err, build)
+	}
+	deps, err := s.ListDependencies(ctx, test, "upstream")
+	if err != nil {
+		t.Fatalf("ListDependencies: %v", err)
+	}
+	if !slices.ContainsFunc(deps, func(d model.Dependency) bool { return d.Type == model.DepBlocks && d.DependsOnID == build }) {
+		t.Errorf("dependencies of %s = %+v, want it blocked by %s", test, deps, build)
+	}
+
+	m, err = e.Claim(ctx, build, "alice", time.Hour)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if m.Status != model.StatusInProgress || m.Steps[0].Assignee != "alice" {
+		t.Errorf("after claiming: %+v, want the molecule in progress and alice on the step", m)
+	}
+	if _, err := e.Claim(ctx, build, "bob", time.Hour); err == nil || !strings.Contains(err.Error(), "held by alice") {
+		t.Errorf("bob claiming alice's step: %v, want it held by alice", err)
+	}
+	if _, err := e.Complete(ctx, build, "bob", ""); err == nil || !strings.Contains(err.Error(), "held by alice") {
+		t.Errorf("bob completing alice's step: %v, want it held by alice", err)
+	}
+	if _, err := e.Claim(ctx, build, "alice", time.Hour); err != nil {
+		t.Errorf("alice claiming again should renew her lease: %v", err)
+	}
+	if _, err := e.Complete(ctx, build, "alice", ""); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if _, err := e.Complete(ctx, build, "alice", ""); err == nil || !strings.Contains(err.Error(), "already closed") {
+		t.Errorf("completing twice: %v, want already closed", err)
+	}
+
+	for _, id := range []string{test, m.Steps[2].ID} {
+		if _, err := e.Claim(ctx, id, "bob", time.Hour); err != nil {
+			t.Fatalf("Claim(%s): %v", id, err)
+		}
+		if m, err = e.Complete(ctx, id, "bob", "done"); err != nil {
+			t.Fatalf("Complete(%s): %v", id, err)
+		}
+	}
+	if m.Status != model.StatusClosed || m.Progress.Closed != 3 || m.CloseReason != "all 3 steps closed" {
+		t.Errorf("after the last step: %+v, want the molecule closed", m)
+	}
+
+	// Reopening a step by hand reopens the molecule.
+	open := model.StatusOpen
+	if _, err := s.UpdateIssue(ctx, test, store.UpdateIssueInput{Status: &open}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if m, err = e.SyncParent(ctx, test); err != nil {
+		t.Fatalf("SyncParent: %v", err)
+	}
+	if m.Status != model.StatusInProgress {
+		t.Errorf("molecule is %s after reopening a step, want in_progress", m.Status)
+	}
+}
+
+func TestOpenCompetition(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	e := New(s)
+	m, err := e.Create(ctx, CreateInput{
+		Title:    "Find the flake",
+		MolType:  model.MolSwarm,
+		WorkType: model.WorkOpenCompetition,
+		Steps:    steps("reproduce", "bisect"),
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	reproduce := m.Steps[0].ID
+
+	for _, agent := range []string{"alice", "bob", "alice"} {
+		if m, err = e.Claim(ctx, reproduce, agent, time.Hour); err != nil {
+			t.Fatalf("Claim(%s): %v", agent, err)
+		}
+	}
+	if st := m.Steps[0]; st.Status != model.StatusInProgress || st.Assignee != "" || !slices.Equal(st.Competitors, []string{"alice", "bob"}) {
+		t.Fatalf("step = %+v, want alice and bob competing for it", st)
+	}
+
+	if m, err = e.Complete(ctx, reproduce, "bob", ""); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if st := m.Steps[0]; st.Status != model.StatusClosed || st.Winner != "bob" {
+		t.Errorf("step = %+v, want it won by bob", st)
+	}
+	if _, err := e.Complete(ctx, reproduce, "alice", ""); err == nil || !strings.Contains(err.Error(), "already won by bob") {
+		t.Errorf("alice completing second: %v, want it already won by bob", err)
+	}
+	if _, err := e.Claim(ctx, reproduce, "carol", time.Hour); err == nil {
+		t.Error("joining a won step should fail")
+	}
+
+	// Swarm steps run at once: the second needs no wait for the first.
+	if m, err = e.Complete(ctx, m.Steps[1].ID, "carol", ""); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if m.Status != model.StatusClosed || !slices.Equal(m.Steps[1].Competitors, []string{"carol"}) {
+		t.Errorf("molecule = %+v, want it closed with carol the lone competitor", m)
+	}
+}
+
+func TestPatrolCrystallizes(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	e := New(s)
+	m, err := e.Create(ctx, CreateInput{
+		Title:        "Nightly patrol",
+		MolType:      model.MolPatrol,
+		Crystallizes: true,
+		Steps:        steps("check disks", "check certs"),
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !m.Crystallizes {
+		t.Error("the molecule should record that it crystallizes")
+	}
+	for _, st := range m.Steps {
+		issue, _ := s.GetIssue(ctx, st.ID)
+		if !issue.Ephemeral || issue.WispType != model.WispPatrol {
+			t.Fatalf("step %+v, want an ephemeral patrol wisp", issue)
+		}
+	}
+
+	// Garbage collection leaves the steps of a running molecule alone,
+	// even long past their retention.
+	disks := m.Steps[0].ID
+	if _, err := e.Complete(ctx, disks, "patroller", ""); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	report, err := gc.New(s).Run(ctx, time.Now().Add(30*24*time.Hour), gc.Options{})
+	if err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	if len(report.Collected) != 0 {
+		t.Fatalf("gc collected %+v from a running molecule", report.Collected)
+	}
+
+	if m, err = e.Complete(ctx, m.Steps[1].ID, "patroller", ""); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if m.Status != model.StatusClosed {
+		t.Fatalf("molecule is %s, want closed", m.Status)
+	}
+	for _, st := range m.Steps {
+		if st.Ephemeral {
+			t.Errorf("step %s is still ephemeral after the molecule crystallized", st.ID)
+		}
+	}
+	history, err := s.ListEvents(ctx, disks, 0)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if history[0].Field != "ephemeral" || history[0].NewValue != "false" {
+		t.Errorf("latest event = %+v, want crystallizing recorded", history[0])
+	}
+}
+
+func TestCreateValidates(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	e := New(s)
+	for name, input := range map[string]CreateInput{
+		"no title":      {MolType: model.MolWork, Steps: steps("a")},
+		"bad mol type":  {Title: "x", MolType: "convoy", Steps: steps("a")},
+		"bad work type": {Title: "x", MolType: model.MolWork, WorkType: "auction", Steps: steps("a")},
+		"no steps":      {Title: "x", MolType: model.MolSwarm},
+		"untitled step": {Title: "x", MolType: model.MolSwarm, Steps: steps("a", " ")},
+	} {
+		if _, err := e.Create(ctx, input); err == nil {
+			t.Errorf("%s: Create succeeded", name)
+		}
+	}
+
+	plain := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "plain"}).ID
+	if _, err := e.Claim(ctx, plain, "alice", time.Hour); err == nil || !strings.Contains(err.Error(), "not a molecule step") {
+		t.Errorf("claiming a plain issue: %v, want not a molecule step", err)
+	}
+	if m, err := e.SyncParent(ctx, plain); err != nil || m != nil {
+		t.Errorf("SyncParent on a plain issue = %+v, %v; want nil", m, err)
+	}
+}
+
+// TestEveryStore runs a crystallizing patrol to completion on each store backend.
+func TestEveryStore(t *testing.T) {
+	storetest.EachStore(t, testEveryStore)
+}
+
+func testEveryStore(t *testing.T, s store.Store) {
+	ctx := storetest.NewTenant(t, s)
+	e := New(s)
+
+	m, err := e.Create(ctx, CreateInput{
+		Title:        "patrol",
+		MolType:      model.MolPatrol,
+		WorkType:     model.WorkOpenCompetition,
+		Crystallizes: true,
+		Steps:        []Step{{Title: "disks"}, {Title: "certs"}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	mol, err := s.GetIssue(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if mol.IssueType != model.TypeMolecule || mol.MolType != model.MolPatrol ||
+		mol.WorkType != model.WorkOpenCompetition || !mol.Crystallizes {
+		t.Errorf("molecule = %+v, want a crystallizing open_competition patrol", mol)
+	}
+	if len(m.Steps) != 2 || !m.Steps[0].Ephemeral || m.Steps[1].ID != m.ID+".2" {
+		t.Fatalf("steps = %+v, want two ephemeral children in order", m.Steps)
+	}
+
+	if _, err := e.Claim(ctx, m.Steps[0].ID, "alice", time.Hour); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if _, err := e.Complete(ctx, m.Steps[0].ID, "bob", ""); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if _, err := e.Complete(ctx, m.Steps[0].ID, "alice", ""); err == nil {
+		t.Error("completing a step already won should fail")
+	}
+	if m, err = e.Complete(ctx, m.Steps[1].ID, "alice", ""); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if m.Status != model.StatusClosed || m.Steps[0].Winner != "bob" ||
+		!slices.Equal(m.Steps[0].Competitors, []string{"alice", "bob"}) {
+		t.Errorf("molecule = %+v, want it closed with bob beating alice to the first step", m)
+	}
+
+	ephemeral := true
+	left, err := s.ListIssues(ctx, model.IssueFilter{Ephemeral: &ephemeral})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(left) != 0 {
+		t.Errorf("ephemeral issues after crystallizing = %d, want none", len(left))
+	}
+}
@@ -0,0 +1,326 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// MaxBatchOps caps the number of operations in one ApplyBatch call.
+const MaxBatchOps = 500
+
+// Batch operation kinds, as reported in BatchResult.Op and BatchError.Op.
+const (
+	BatchOpCreate     = "create"
+	BatchOpUpdate     = "update"
+	BatchOpDependency = "dependency"
+	BatchOpLabel      = "label"
+	BatchOpComment    = "comment"
+)
+
+// BatchOp is one step of an ApplyBatch call. Exactly one of Create, Update,
+// Dependency, Label or Comment is set. Any issue ID in the op may instead be
+// a reference of the form "$name" to an issue created earlier in the batch.
+type BatchOp struct {
+	// Ref names the issue a Create op makes, so later ops can refer to it
+	// as "$name". Only valid on Create.
+	Ref string
+
+	Create     *CreateIssueInput // ID is generated when empty
+	Update     *BatchUpdate
+	Dependency *AddDependencyInput
+	Label      *BatchLabel
+	Comment    *BatchComment
+}
+
+// BatchUpdate applies an UpdateIssueInput to one issue.
+type BatchUpdate struct {
+	ID    string
+	Input UpdateIssueInput
+}
+
+// BatchLabel adds a label to an issue.
+type BatchLabel struct {
+	IssueID string
+	Label   string
+}
+
+// BatchComment adds a comment to an issue.
+type BatchComment struct {
+	IssueID string
+	Author  string
+	Text    string
+}
+
+// BatchResult is the outcome of one BatchOp, in the same position as the op.
+type BatchResult struct {
+	Op         string            `json:"op"`
+	Ref        string            `json:"ref,omitempty"`
+	IssueID    string            `json:"issue_id"`
+	Issue      *model.Issue      `json:"issue,omitempty"`
+	Dependency *model.Dependency `json:"dependency,omitempty"`
+	Comment    *model.Comment    `json:"comment,omitempty"`
+}
+
+// BatchError is returned by ApplyBatch when an op fails. Nothing in the batch
+// was applied. Err is the underlying error, so errors.Is(err, ErrConflict)
+// and friends still work.
+type BatchError struct {
+	Index int    // position of the failing op in the batch
+	Op    string // its kind, e.g. "create"
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch op %d (%s): %v", e.Index, e.Op, e.Err)
+}
+
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// kind returns which operation op carries, or an error unless exactly one
+// is set.
+func (op BatchOp) kind() (string, error) {
+	var kinds []string
+	if op.Create != nil {
+		kinds = append(kinds, BatchOpCreate)
+	}
+	if op.Update != nil {
+		kinds = append(kinds, BatchOpUpdate)
+	}
+	if op.Dependency != nil {
+		kinds = append(kinds, BatchOpDependency)
+	}
+	if op.Label != nil {
+		kinds = append(kinds, BatchOpLabel)
+	}
+	if op.Comment != nil {
+		kinds = append(kinds, BatchOpComment)
+	}
+	switch len(kinds) {
+	case 0:
+		return "", fmt.Errorf("no operation set")
+	case 1:
+		return kinds[0], nil
+	default:
+		return "", fmt.Errorf("more than one operation set: %s", strings.Join(kinds, ", "))
+	}
+}
+
+// batchRefName strips the optional leading "$" from a Create op's Ref.
+func batchRefName(ref string) string { return strings.TrimPrefix(ref, "$") }
+
+// validateBatch checks the shape of ops and that every "$name" reference
+// points at a Create earlier in the batch, so obviously broken batches fail
+// before a transaction is opened.
+func validateBatch(ops []BatchOp) error {
+	if len(ops) == 0 {
+		return fmt.Errorf("batch is empty")
+	}
+	if len(ops) > MaxBatchOps {
+		return fmt.Errorf("batch has %d operations; the limit is %d", len(ops), MaxBatchOps)
+	}
+
+	defined := make(map[string]bool)
+	for i, op := range ops {
+		kind, err := op.kind()
+		if err != nil {
+			return &BatchError{Index: i, Err: err}
+		}
+		fail := func(err error) error { return &BatchError{Index: i, Op: kind, Err: err} }
+
+		for _, id := range op.issueIDs() {
+			if name, ok := strings.CutPrefix(id, "$"); ok && !defined[name] {
+				return fail(fmt.Errorf("unknown reference %q", id))
+			}
+		}
+
+		if op.Ref == "" {
+			continue
+		}
+		if kind != BatchOpCreate {
+			return fail(fmt.Errorf("ref is only valid on create"))
+		}
+		name := batchRefName(op.Ref)
+		if name == "" {
+			return fail(fmt.Errorf("ref %q is empty", op.Ref))
+		}
+		if defined[name] {
+			return fail(fmt.Errorf("ref %q is defined twice", op.Ref))
+		}
+		defined[name] = true
+	}
+	return nil
+}
+
+// issueIDs returns the issue IDs op refers to, references included.
+func (op BatchOp) issueIDs() []string {
+	var ids []string
+	switch {
+	case op.Create != nil:
+		if op.Create.ParentID != "" {
+			ids = append(ids, op.Create.ParentID)
+		}
+	case op.Update != nil:
+		ids = append(ids, op.Update.ID)
+	case op.Dependency != nil:
+		ids = append(ids, op.Dependency.IssueID, op.Dependency.DependsOnID)
+	case op.Label != nil:
+		ids = append(ids, op.Label.IssueID)
+	case op.Comment != nil:
+		ids = append(ids, op.Comment.IssueID)
+	}
+	return ids
+}
+
+// batchTx is what a backend provides to applyBatch: the write operations,
+// all scoped to one open transaction (or, for MemStore, one critical
+// section) so each sees the writes of the ones before it.
+type batchTx interface {
+	checkOwned(issueID string) error
+	generateID() (string, error)
+	nextChildID(parentID string) (string, error)
+	createIssue(input CreateIssueInput) (*model.Issue, error)
+	updateIssue(id string, input UpdateIssueInput) (*model.Issue, error)
+	addDependency(input AddDependencyInput) (*model.Dependency, error)
+	addLabel(issueID, label string) error
+	addComment(issueID, author, text string) (*model.Comment, error)
+}
+
+// applyBatch runs validated ops in order against tx, resolving references
+// as issues are created. The caller rolls back on error.
+func applyBatch(ops []BatchOp, tx batchTx) ([]BatchResult, error) {
+	refs := make(map[string]string)
+	resolve := func(id string) (string, error) {
+		name, ok := strings.CutPrefix(id, "$")
+		if !ok {
+			return id, nil
+		}
+		if resolved, ok := refs[name]; ok {
+			return resolved, nil
+		}
+		return "", fmt.Errorf("unknown reference %q", id)
+	}
+
+	results := make([]BatchResult, 0, len(ops))
+	for i, op := range ops {
+		kind, err := op.kind()
+		if err != nil {
+			return nil, &BatchError{Index: i, Err: err}
+		}
+		res, err := applyBatchOp(kind, op, resolve, tx)
+		if err != nil {
+			return nil, &BatchError{Index: i, Op: kind, Err: err}
+		}
+		if op.Ref != "" {
+			refs[batchRefName(op.Ref)] = res.IssueID
+			res.Ref = op.Ref
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func applyBatchOp(kind string, op BatchOp, resolve func(string) (string, error), tx batchTx) (BatchResult, error) {
+	res := BatchResult{Op: kind}
+	var err error
+
+	switch kind {
+	case BatchOpCreate:
+		input := *op.Create
+		if input.ParentID != "" {
+			if input.ParentID, err = resolve(input.ParentID); err != nil {
+				return res, err
+			}
+			if err := tx.checkOwned(input.ParentID); err != nil {
+				return res, err
+			}
+		}
+		if input.ID == "" {
+			if input.ParentID != "" {
+				input.ID, err = tx.nextChildID(input.ParentID)
+			} else {
+				input.ID, err = tx.generateID()
+			}
+			if err != nil {
+				return res, err
+			}
+		}
+		if input.Status == "" {
+			input.Status = model.StatusOpen
+		}
+		if input.IssueType == "" {
+			input.IssueType = model.TypeTask
+		}
+		res.Issue, err = tx.createIssue(input)
+		if err != nil {
+			return res, err
+		}
+		res.IssueID = res.Issue.ID
+
+	case BatchOpUpdate:
+		id, err := resolve(op.Update.ID)
+		if err != nil {
+			return res, err
+		}
+		res.Issue, err = tx.updateIssue(id, op.Update.Input)
+		if err != nil {
+			return res, err
+		}
+		res.IssueID = id
+
+	case BatchOpDependency:
+		input := *op.Dependency
+		if input.IssueID, err = resolve(input.IssueID); err != nil {
+			return res, err
+		}
+		if input.DependsOnID, err = resolve(input.DependsOnID); err != nil {
+			return res, err
+		}
+		if input.Type == "" {
+			input.Type = model.DepBlocks
+		}
+		if err := tx.checkOwned(input.IssueID); err != nil {
+			return res, err
+		}
+		if err := tx.checkOwned(input.DependsOnID); err != nil {
+			return res, err
+		}
+		res.Dependency, err = tx.addDependency(input)
+		if err != nil {
+			return res, err
+		}
+		res.IssueID = input.IssueID
+
+	case BatchOpLabel:
+		id, err := resolve(op.Label.IssueID)
+		if err != nil {
+			return res, err
+		}
+		if op.Label.Label == "" {
+			return res, fmt.Errorf("label is required")
+		}
+		if err := tx.checkOwned(id); err != nil {
+			return res, err
+		}
+		if err := tx.addLabel(id, op.Label.Label); err != nil {
+			return res, fmt.Errorf("adding label: %w", err)
+		}
+		res.IssueID = id
+
+	case BatchOpComment:
+		id, err := resolve(op.Comment.IssueID)
+		if err != nil {
+			return res, err
+		}
+		if err := tx.checkOwned(id); err != nil {
+			return res, err
+		}
+		res.Comment, err = tx.addComment(id, op.Comment.Author, op.Comment.Text)
+		if err != nil {
+			return res, err
+		}
+		res.IssueID = id
+	}
+	return res, nil
+}
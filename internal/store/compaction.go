@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// UncompactIssue restores issue id to compaction level (0 for full detail)
+// from the snapshot taken when it was last compacted past that level. The
+// restored content replaces the summary through UpdateIssue, so each field
+// is recorded in the audit trail.
+func UncompactIssue(ctx context.Context, s Store, id string, level int) (*model.Issue, error) {
+	issue, err := s.GetIssue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if level < 0 {
+		return nil, fmt.Errorf("compaction level must be 0 or more, got %d", level)
+	}
+	if level >= issue.CompactionLevel {
+		return nil, fmt.Errorf("issue %s is at compaction level %d; nothing to restore to level %d", id, issue.CompactionLevel, level)
+	}
+	snaps, err := s.GetCompactionSnapshots(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("listing compaction snapshots of %s: %w", id, err)
+	}
+	snap := snapshotFor(snaps, level)
+	if snap == nil {
+		return nil, fmt.Errorf("issue %s has no compaction snapshot at level %d or below", id, level)
+	}
+	input := UpdateIssueInput{ExpectedContentHash: &issue.ContentHash}
+	uncompactInput(snap, &input)
+	return s.UpdateIssue(ctx, id, input)
+}
+
+// snapshotFor returns the newest snapshot holding content at level or
+// below: one taken when the issue was compacted from there to above it.
+func snapshotFor(snaps []model.CompactionSnapshot, level int) *model.CompactionSnapshot {
+	var found *model.CompactionSnapshot
+	for i := range snaps {
+		s := &snaps[i]
+		if s.FromLevel <= level && s.Level > level && (found == nil || s.ID > found.ID) {
+			found = s
+		}
+	}
+	return found
+}
+
+// uncompactInput sets input to put back the content in snap and lower the
+// compaction level to the one it was taken at. Fields input already sets
+// win over the snapshot.
+func uncompactInput(snap *model.CompactionSnapshot, input *UpdateIssueInput) {
+	var restored model.Issue
+	snap.ApplyOriginal(&restored)
+	for _, f := range []struct {
+		dst **string
+		val string
+	}{
+		{&input.Title, restored.Title},
+		{&input.Description, restored.Description},
+		{&input.Design, restored.Design},
+		{&input.AcceptanceCriteria, restored.AcceptanceCriteria},
+		{&input.Notes, restored.Notes},
+	} {
+		if *f.dst == nil {
+			v := f.val
+			*f.dst = &v
+		}
+	}
+	level := snap.FromLevel
+	input.CompactionLevel = &level
+}
+
+// reopensCompacted reports whether input takes a compacted issue out of
+// closed. Its full content comes back first, so whoever picks the work up
+// again does not start from a summary.
+func reopensCompacted(current *model.Issue, input UpdateIssueInput) bool {
+	return current.CompactionLevel > 0 && current.Status == model.StatusClosed &&
+		input.Status != nil && *input.Status != model.StatusClosed && input.CompactionLevel == nil
+}
+
+// defaultCompactionSettings are the settings of a tenant that stored none.
+func defaultCompactionSettings() *model.CompactionSettings {
+	return &model.CompactionSettings{
+		Summarizer: model.SummarizerHeuristic,
+		Budgets:    model.DefaultCompactionBudgets,
+		Default:    true,
+	}
+}
+
+// normalizeCompactionSettings validates settings, filling in the default
+// summarizer and budgets where they are left out.
+func normalizeCompactionSettings(in *model.CompactionSettings) (*model.CompactionSettings, error) {
+	out := *in
+	out.Default = false
+	if out.Summarizer == "" {
+		out.Summarizer = model.SummarizerHeuristic
+	}
+	if !slices.Contains(model.Summarizers, out.Summarizer) {
+		return nil, fmt.Errorf("invalid summarizer %q: want one of %s", out.Summarizer, strings.Join(model.Summarizers, ", "))
+	}
+	for _, b := range []struct {
+		level  int
+		budget *int
+		def    int
+	}{
+		{1, &out.Budgets.Level1, model.DefaultCompactionBudgets.Level1},
+		{2, &out.Budgets.Level2, model.DefaultCompactionBudgets.Level2},
+	} {
+		if *b.budget == 0 {
+			*b.budget = b.def
+		}
+		if *b.budget < 0 {
+			return nil, fmt.Errorf("level %d summary budget must be positive, got %d", b.level, *b.budget)
+		}
+	}
+	if out.Endpoint != nil {
+		ep := *out.Endpoint
+		u, err := url.Parse(ep.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid summarizer endpoint URL %q: want an http or https URL", ep.URL)
+		}
+		if ep.Model == "" {
+			return nil, fmt.Errorf("summarizer endpoint needs a model")
+		}
+		if ep.APIKeyEnv != "" && !model.ValidKeyEnv(ep.APIKeyEnv) {
+			return nil, fmt.Errorf("invalid summarizer key variable %q: its name must start with %s", ep.APIKeyEnv, model.SummarizerKeyEnvPrefix)
+		}
+		out.Endpoint = &ep
+	} else if out.Summarizer == model.SummarizerEndpoint {
+		return nil, fmt.Errorf("the %s summarizer needs an endpoint", model.SummarizerEndpoint)
+	}
+	return &out, nil
+}
+
+func defaultCompactionPolicy(projectID string) *model.CompactionPolicy {
+	return &model.CompactionPolicy{ProjectID: projectID, Default: true}
+}
+
+// normalizeCompactionPolicy validates policy's age, writing it in canonical
+// form, and trims, dedupes and sorts its excluded labels.
+func normalizeCompactionPolicy(projectID string, in *model.CompactionPolicy) (*model.CompactionPolicy, error) {
+	out := &model.CompactionPolicy{ProjectID: projectID, ExcludeLabels: []string{}}
+	if in.Age != "" {
+		age, err := time.ParseDuration(in.Age)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compaction age %q: %w", in.Age, err)
+		}
+		if age <= 0 {
+			return nil, fmt.Errorf("compaction age must be positive, got %s", in.Age)
+		}
+		out.Age = age.String()
+	}
+	for _, l := range in.ExcludeLabels {
+		if l = strings.TrimSpace(l); l != "" && !slices.Contains(out.ExcludeLabels, l) {
+			out.ExcludeLabels = append(out.ExcludeLabels, l)
+		}
+	}
+	slices.Sort(out.ExcludeLabels)
+	return out, nil
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// ErrConflict matches (via errors.Is) every *ConflictError.
+var ErrConflict = errors.New("issue was modified concurrently")
+
+// ConflictError is returned by UpdateIssue when the caller's expected
+// content hash or updated_at no longer matches the stored issue. Current is
+// the stored issue, so the caller can merge its change and retry.
+type ConflictError struct {
+	IssueID  string
+	Field    string // "content_hash" or "updated_at"
+	Expected string
+	Actual   string
+	Current  *model.Issue
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflict updating issue %s: expected %s %s but it is now %s",
+		e.IssueID, e.Field, e.Expected, e.Actual)
+}
+
+func (e *ConflictError) Unwrap() error { return ErrConflict }
+
+// checkExpectedVersion enforces the optimistic concurrency preconditions of
+// input against the issue as currently stored.
+func checkExpectedVersion(current *model.Issue, input UpdateIssueInput) error {
+	if input.ExpectedContentHash != nil && *input.ExpectedContentHash != current.ContentHash {
+		return &ConflictError{
+			IssueID:  current.ID,
+			Field:    "content_hash",
+			Expected: *input.ExpectedContentHash,
+			Actual:   current.ContentHash,
+			Current:  current,
+		}
+	}
+	if input.ExpectedUpdatedAt != nil && !input.ExpectedUpdatedAt.Equal(current.UpdatedAt) {
+		return &ConflictError{
+			IssueID:  current.ID,
+			Field:    "updated_at",
+			Expected: input.ExpectedUpdatedAt.UTC().Format(time.RFC3339Nano),
+			Actual:   current.UpdatedAt.UTC().Format(time.RFC3339Nano),
+			Current:  current,
+		}
+	}
+	return nil
+}
+
+// updatedContentHash returns the content hash current will have once the
+// text fields in input are applied.
+func updatedContentHash(current *model.Issue, input UpdateIssueInput) string {
+	next := *current
+	if input.Title != nil {
+		next.Title = *input.Title
+	}
+	if input.Description != nil {
+		next.Description = *input.Description
+	}
+	if input.Design != nil {
+		next.Design = *input.Design
+	}
+	if input.AcceptanceCriteria != nil {
+		next.AcceptanceCriteria = *input.AcceptanceCriteria
+	}
+	if input.Notes != nil {
+		next.Notes = *input.Notes
+	}
+	return contentHash(&next)
+}
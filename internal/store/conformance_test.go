@@ -0,0 +1,24 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/Actual-Outcomes/doit/internal/store/storetest"
+)
+
+func TestConformance_Memory(t *testing.T) {
+	storetest.Run(t, storetest.Memory)
+}
+
+func TestConformance_Sqlite(t *testing.T) {
+	storetest.Run(t, storetest.Sqlite)
+}
+
+// TestConformance_Postgres runs against a real database when
+// DOIT_TEST_DATABASE_URL is set, as CI does with a Postgres service
+// container; in CI a missing database is a failure, not a skip. The
+// database is migrated but not wiped; scenarios isolate themselves in
+// fresh tenants.
+func TestConformance_Postgres(t *testing.T) {
+	storetest.Run(t, storetest.Postgres(storetest.PostgresURL(t)))
+}
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// Page cursors are keyset positions: the sort key of the last row a caller
+// has seen, base64-encoded so clients treat them as opaque. Resuming with
+// "rows after this key" instead of an OFFSET keeps pages stable when rows are
+// inserted or deleted between calls.
+//
+// That holds for rows whose sort key does not change. The "updated" and
+// "hybrid" issue sorts key on updated_at, which every edit moves forward, so
+// an issue edited while a caller pages jumps ahead of the cursor: one not
+// yet returned is skipped, and one already returned does not come back.
+// Priority is mutable too; an issue whose priority changes between pages
+// can be skipped or returned twice. Callers that need every issue exactly
+// once page by "oldest", whose created_at and ID never change.
+
+// ReadySortBy is the fixed sort order of ListReady, for building its cursors.
+const ReadySortBy = "priority"
+
+// lessonFlagSort names the fixed order of ListLessons and ListFlags.
+const lessonFlagSort = "severity"
+
+type pageCursor struct {
+	Sort string    `json:"s"`
+	Rank int       `json:"r"` // priority or severity
+	Time time.Time `json:"t"`
+	ID   string    `json:"id"`
+}
+
+func (c pageCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s, sort string) (pageCursor, error) {
+	var c pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.ID == "" {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	if c.Sort != sort {
+		return c, fmt.Errorf("cursor was issued for sort %q, not %q", c.Sort, sort)
+	}
+	return c, nil
+}
+
+// keyField identifies which cursor value a sort column compares against.
+type keyField int
+
+const (
+	keyRank keyField = iota
+	keyTime
+	keyID
+)
+
+type keysetColumn struct {
+	name  string
+	field keyField
+	desc  bool
+}
+
+type keyset []keysetColumn
+
+// issueSortKey normalizes sortBy the way ListIssues does: anything
+// unrecognized falls back to "hybrid".
+func issueSortKey(sortBy string) string {
+	switch sortBy {
+	case "priority", "oldest", "updated":
+		return sortBy
+	default:
+		return "hybrid"
+	}
+}
+
+// issueKeyset returns the ORDER BY columns for an issue sort mode. Every
+// mode ends with the ID so the order is total.
+func issueKeyset(sortBy string) keyset {
+	switch issueSortKey(sortBy) {
+	case "priority":
+		return keyset{{"priority", keyRank, false}, {"created_at", keyTime, false}, {"id", keyID, false}}
+	case "oldest":
+		return keyset{{"created_at", keyTime, false}, {"id", keyID, false}}
+	case "updated":
+		return keyset{{"updated_at", keyTime, true}, {"id", keyID, false}}
+	default:
+		return keyset{{"priority", keyRank, false}, {"updated_at", keyTime, true}, {"id", keyID, false}}
+	}
+}
+
+// lessonFlagKeyset is the order shared by lessons and flags: most severe
+// first, then newest.
+var lessonFlagKeyset = keyset{{"severity", keyRank, false}, {"created_at", keyTime, true}, {"id", keyID, false}}
+
+func (k keyset) value(c pageCursor, f keyField) any {
+	switch f {
+	case keyRank:
+		return c.Rank
+	case keyTime:
+		return c.Time.UTC()
+	default:
+		return c.ID
+	}
+}
+
+// orderBy renders the ORDER BY clause.
+func (k keyset) orderBy() string {
+	parts := make([]string, len(k))
+	for i, col := range k {
+		parts[i] = col.name
+		if col.desc {
+			parts[i] += " DESC"
+		} else {
+			parts[i] += " ASC"
+		}
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// after renders a predicate selecting rows that sort after c. placeholder
+// formats a bind parameter number ("$%d" or "?%d"); values are appended to
+// args starting at argN+1.
+func (k keyset) after(c pageCursor, placeholder string, args []any, argN int) (string, []any, int) {
+	params := make([]string, len(k))
+	for i, col := range k {
+		argN++
+		params[i] = fmt.Sprintf(placeholder, argN)
+		args = append(args, k.value(c, col.field))
+	}
+
+	// (a > x) OR (a = x AND ((b < y) OR (b = y AND c > z))), innermost first.
+	var clause string
+	for i := len(k) - 1; i >= 0; i-- {
+		op := ">"
+		if k[i].desc {
+			op = "<"
+		}
+		cmp := fmt.Sprintf("%s %s %s", k[i].name, op, params[i])
+		if clause == "" {
+			clause = cmp
+		} else {
+			clause = fmt.Sprintf("(%s OR (%s = %s AND %s))", cmp, k[i].name, params[i], clause)
+		}
+	}
+	return clause, args, argN
+}
+
+// compare orders two cursors by the keyset, for the in-memory store.
+func (k keyset) compare(a, b pageCursor) int {
+	for _, col := range k {
+		var c int
+		switch col.field {
+		case keyRank:
+			c = a.Rank - b.Rank
+		case keyTime:
+			c = a.Time.Compare(b.Time)
+		default:
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if col.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func issuePosition(i *model.Issue, sortBy string) pageCursor {
+	c := pageCursor{Sort: issueSortKey(sortBy), Rank: i.Priority, Time: i.UpdatedAt, ID: i.ID}
+	if c.Sort == "priority" || c.Sort == "oldest" {
+		c.Time = i.CreatedAt
+	}
+	return c
+}
+
+// IssueCursor returns the cursor that resumes a ListIssues (or ListReady,
+// with ReadySortBy) page after issue i.
+func IssueCursor(i *model.Issue, sortBy string) string {
+	return issuePosition(i, sortBy).encode()
+}
+
+func lessonPosition(l *model.Lesson) pageCursor {
+	return pageCursor{Sort: lessonFlagSort, Rank: l.Severity, Time: l.CreatedAt, ID: l.ID}
+}
+
+func flagPosition(f *model.Flag) pageCursor {
+	return pageCursor{Sort: lessonFlagSort, Rank: f.Severity, Time: f.CreatedAt, ID: f.ID}
+}
+
+// LessonCursor returns the cursor that resumes a ListLessons page after l.
+func LessonCursor(l *model.Lesson) string {
+	return lessonPosition(l).encode()
+}
+
+// FlagCursor returns the cursor that resumes a ListFlags page after f.
+func FlagCursor(f *model.Flag) string {
+	return flagPosition(f).encode()
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// SystemActor is recorded for changes made with no actor in the context and
+// none given explicitly.
+const SystemActor = "system"
+
+// eventActor returns who a change is attributed to: the actor given
+// explicitly (created_by, claimant, author), else the caller set in ctx
+// with auth.WithActor, else SystemActor.
+func eventActor(ctx context.Context, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if actor := auth.ActorFromContext(ctx); actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+// trackedFields are the issue fields whose changes are recorded as updated
+// events, under their JSON names. Status has its own event types; lease
+// bookkeeping and agent activity (heartbeats) are not recorded. set parses
+// a recorded value back into the issue, for history replay.
+var trackedFields = []struct {
+	name  string
+	value func(*model.Issue) string
+	set   func(*model.Issue, string) error
+}{
+	{"title", func(i *model.Issue) string { return i.Title }, func(i *model.Issue, v string) error { i.Title = v; return nil }},
+	{"description", func(i *model.Issue) string { return i.Description }, func(i *model.Issue, v string) error { i.Description = v; return nil }},
+	{"design", func(i *model.Issue) string { return i.Design }, func(i *model.Issue, v string) error { i.Design = v; return nil }},
+	{"acceptance_criteria", func(i *model.Issue) string { return i.AcceptanceCriteria }, func(i *model.Issue, v string) error { i.AcceptanceCriteria = v; return nil }},
+	{"notes", func(i *model.Issue) string { return i.Notes }, func(i *model.Issue, v string) error { i.Notes = v; return nil }},
+	{"priority", func(i *model.Issue) string { return strconv.Itoa(i.Priority) }, func(i *model.Issue, v string) (err error) { i.Priority, err = strconv.Atoi(v); return err }},
+	{"assignee", func(i *model.Issue) string { return i.Assignee }, func(i *model.Issue, v string) error { i.Assignee = v; return nil }},
+	{"owner", func(i *model.Issue) string { return i.Owner }, func(i *model.Issue, v string) error { i.Owner = v; return nil }},
+	{"close_reason", func(i *model.Issue) string { return i.CloseReason }, func(i *model.Issue, v string) error { i.CloseReason = v; return nil }},
+	{"pinned", func(i *model.Issue) string { return strconv.FormatBool(i.Pinned) }, func(i *model.Issue, v string) (err error) { i.Pinned, err = strconv.ParseBool(v); return err }},
+	{"ephemeral", func(i *model.Issue) string { return strconv.FormatBool(i.Ephemeral) }, func(i *model.Issue, v string) (err error) { i.Ephemeral, err = strconv.ParseBool(v); return err }},
+	{"is_template", func(i *model.Issue) string { return strconv.FormatBool(i.IsTemplate) }, func(i *model.Issue, v string) (err error) { i.IsTemplate, err = strconv.ParseBool(v); return err }},
+	{"external_ref", func(i *model.Issue) string { return derefString(i.ExternalRef) }, func(i *model.Issue, v string) error { i.ExternalRef = optionalString(v); return nil }},
+	{"estimated_minutes", func(i *model.Issue) string { return formatOptionalInt(i.EstimatedMinutes) }, func(i *model.Issue, v string) (err error) { i.EstimatedMinutes, err = parseOptionalInt(v); return err }},
+	{"due_at", func(i *model.Issue) string { return formatOptionalTime(i.DueAt) }, func(i *model.Issue, v string) (err error) { i.DueAt, err = parseOptionalTime(v); return err }},
+	{"defer_until", func(i *model.Issue) string { return formatOptionalTime(i.DeferUntil) }, func(i *model.Issue, v string) (err error) { i.DeferUntil, err = parseOptionalTime(v); return err }},
+	{"agent_state", func(i *model.Issue) string { return string(i.AgentState) }, func(i *model.Issue, v string) error { i.AgentState = model.AgentState(v); return nil }},
+	{"role_type", func(i *model.Issue) string { return i.RoleType }, func(i *model.Issue, v string) error { i.RoleType = v; return nil }},
+	{"rig", func(i *model.Issue) string { return i.Rig }, func(i *model.Issue, v string) error { i.Rig = v; return nil }},
+	{"hook_bead", func(i *model.Issue) string { return i.HookBead }, func(i *model.Issue, v string) error { i.HookBead = v; return nil }},
+	{"role_bead", func(i *model.Issue) string { return i.RoleBead }, func(i *model.Issue, v string) error { i.RoleBead = v; return nil }},
+	{"await_id", func(i *model.Issue) string { return i.AwaitID }, func(i *model.Issue, v string) error { i.AwaitID = v; return nil }},
+}
+
+// issueEvents returns the events recording the change from before to after:
+// an updated event per changed field, a compacted event if the compaction
+// level moved, then a closed, reopened or status_changed event if the
+// status moved, so the status change is the newest.
+func issueEvents(before, after *model.Issue, actor string) []AddEventInput {
+	var events []AddEventInput
+	for _, f := range trackedFields {
+		old, cur := f.value(before), f.value(after)
+		if old == cur {
+			continue
+		}
+		events = append(events, AddEventInput{
+			IssueID:   after.ID,
+			EventType: model.EventUpdated,
+			Actor:     actor,
+			Field:     f.name,
+			OldValue:  old,
+			NewValue:  cur,
+		})
+	}
+	if before.CompactionLevel != after.CompactionLevel {
+		events = append(events, compactedEvent(after.ID, before.CompactionLevel, after.CompactionLevel, actor))
+	}
+	if before.Status != after.Status {
+		t := model.EventStatusChanged
+		switch {
+		case after.Status == model.StatusClosed:
+			t = model.EventClosed
+		case before.Status == model.StatusClosed:
+			t = model.EventReopened
+		}
+		events = append(events, AddEventInput{
+			IssueID:   after.ID,
+			EventType: t,
+			Actor:     actor,
+			Field:     "status",
+			OldValue:  string(before.Status),
+			NewValue:  string(after.Status),
+		})
+	}
+	return events
+}
+
+func labelEvent(issueID, label string, added bool, actor string) AddEventInput {
+	if added {
+		return AddEventInput{IssueID: issueID, EventType: model.EventLabelAdded, Actor: actor, Field: "labels", NewValue: label}
+	}
+	return AddEventInput{IssueID: issueID, EventType: model.EventLabelRemoved, Actor: actor, Field: "labels", OldValue: label}
+}
+
+// dependencyEvent records an edge on its dependent issue. The comment holds
+// the dependency type; re-adding an edge with a new type carries the old
+// one in OldValue.
+func dependencyEvent(dep model.Dependency, oldType model.DependencyType, added bool, actor string) AddEventInput {
+	e := AddEventInput{IssueID: dep.IssueID, Actor: actor, Comment: string(dep.Type)}
+	if added {
+		e.EventType = model.EventDependencyAdded
+		e.NewValue = dep.DependsOnID
+		if oldType != "" && oldType != dep.Type {
+			e.OldValue = string(oldType)
+		}
+	} else {
+		e.EventType = model.EventDependencyRemoved
+		e.OldValue = dep.DependsOnID
+	}
+	return e
+}
+
+func commentEvent(c *model.Comment, actor string) AddEventInput {
+	return AddEventInput{
+		IssueID:   c.IssueID,
+		EventType: model.EventCommented,
+		Actor:     actor,
+		NewValue:  strconv.FormatInt(c.ID, 10),
+		Comment:   c.Text,
+	}
+}
+
+func compactedEvent(issueID string, oldLevel, newLevel int, actor string) AddEventInput {
+	return AddEventInput{
+		IssueID:   issueID,
+		EventType: model.EventCompacted,
+		Actor:     actor,
+		Field:     "compaction_level",
+		OldValue:  strconv.Itoa(oldLevel),
+		NewValue:  strconv.Itoa(newLevel),
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatOptionalInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func parseOptionalInt(s string) (*int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func parseOptionalTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
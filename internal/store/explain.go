@@ -0,0 +1,322 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// ExplainReady reports every reason issue id is missing from ListReady,
+// following the rules of the ready_issues view (see model.ReadyGates). Each
+// dependency reason carries the chain of unfinished issues behind it. It is
+// built on the Store interface, so it works with any backend.
+func ExplainReady(ctx context.Context, s Store, id string) (*model.ReadyExplanation, error) {
+	issue, err := s.GetIssue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	e := &readyExplainer{
+		ctx:      ctx,
+		s:        s,
+		now:      time.Now().UTC(),
+		issues:   map[string]*model.Issue{issue.ID: issue},
+		policies: make(map[string]*model.ReadyPolicy),
+		expanded: map[string]bool{issue.ID: true},
+	}
+
+	exp := &model.ReadyExplanation{IssueID: issue.ID, Status: issue.Status, Reasons: []model.ReadyReason{}}
+	add := func(r model.ReadyReason) { exp.Reasons = append(exp.Reasons, r) }
+
+	if issue.Status != model.StatusOpen {
+		msg := fmt.Sprintf("status is %s; only open issues are ready", issue.Status)
+		if issue.Assignee != "" && issue.Status != model.StatusClosed {
+			msg += fmt.Sprintf(" (assigned to %s)", issue.Assignee)
+		}
+		add(model.ReadyReason{Kind: model.ReadyReasonStatus, Message: msg})
+	}
+	if issue.Ephemeral {
+		add(model.ReadyReason{Kind: model.ReadyReasonEphemeral, Message: "ephemeral issues are never ready"})
+	}
+	if issue.IsTemplate {
+		add(model.ReadyReason{Kind: model.ReadyReasonTemplate, Message: "template issues are never ready; instantiate the template instead"})
+	}
+	if issue.DeferUntil != nil && issue.DeferUntil.After(e.now) {
+		add(model.ReadyReason{
+			Kind:       model.ReadyReasonDeferred,
+			Message:    fmt.Sprintf("deferred until %s", issue.DeferUntil.UTC().Format(time.RFC3339)),
+			DeferUntil: issue.DeferUntil,
+		})
+	}
+
+	open := model.FlagStatusOpen
+	flags, err := s.ListFlags(ctx, model.FlagFilter{IssueID: &issue.ID, Status: &open})
+	if err != nil {
+		return nil, fmt.Errorf("listing flags: %w", err)
+	}
+	for _, f := range flags {
+		if f.Severity <= 2 {
+			add(model.ReadyReason{
+				Kind:    model.ReadyReasonFlag,
+				Message: fmt.Sprintf("open severity %d flag %s: %s", f.Severity, f.ID, f.Summary),
+				FlagID:  f.ID,
+			})
+		}
+	}
+
+	policy, err := e.policy(issue.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	gates, err := e.gates(issue, policy)
+	if err != nil {
+		return nil, err
+	}
+	exp.Reasons = append(exp.Reasons, gates...)
+
+	if policy.Applies(model.DepParentChild) {
+		held, err := e.heldAncestors(issue.ID)
+		if err != nil {
+			return nil, err
+		}
+		exp.Reasons = append(exp.Reasons, held...)
+	}
+
+	exp.Ready = len(exp.Reasons) == 0
+	return exp, nil
+}
+
+type readyExplainer struct {
+	ctx      context.Context
+	s        Store
+	now      time.Time
+	issues   map[string]*model.Issue
+	policies map[string]*model.ReadyPolicy // by project ID
+	expanded map[string]bool               // blockers already listed with their own blockers
+}
+
+func (e *readyExplainer) issue(id string) (*model.Issue, error) {
+	if i, ok := e.issues[id]; ok {
+		return i, nil
+	}
+	i, err := e.s.GetIssue(e.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	e.issues[id] = i
+	return i, nil
+}
+
+// policy returns a project's ready policy, nil for issues without one.
+func (e *readyExplainer) policy(projectID string) (*model.ReadyPolicy, error) {
+	if projectID == "" {
+		return nil, nil
+	}
+	if p, ok := e.policies[projectID]; ok {
+		return p, nil
+	}
+	p, err := e.s.GetReadyPolicy(e.ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("getting ready policy: %w", err)
+	}
+	e.policies[projectID] = p
+	return p, nil
+}
+
+// gates returns a dependency reason for each of i's own edges that holds
+// it back under policy.
+func (e *readyExplainer) gates(i *model.Issue, policy *model.ReadyPolicy) ([]model.ReadyReason, error) {
+	deps, err := e.s.ListDependencies(e.ctx, i.ID, "upstream")
+	if err != nil {
+		return nil, fmt.Errorf("listing dependencies of %s: %w", i.ID, err)
+	}
+	var reasons []model.ReadyReason
+	for _, d := range deps {
+		if d.Type == model.DepParentChild || !policy.Applies(d.Type) {
+			continue
+		}
+		target, err := e.issue(d.DependsOnID)
+		if err != nil {
+			return nil, fmt.Errorf("looking up dependency target: %w", err)
+		}
+		closed := target.Status == model.StatusClosed
+		r := model.ReadyReason{Kind: model.ReadyReasonDependency, Type: d.Type, IssueID: target.ID}
+
+		switch d.Type {
+		case model.DepBlocks:
+			if closed {
+				continue
+			}
+			r.Message = "blocked by " + describeIssue(target)
+
+		case model.DepConditionalBlocks:
+			switch {
+			case !closed:
+				r.Message = fmt.Sprintf("fallback for %s, which has not failed yet", describeIssue(target))
+			case model.IsFailureCloseReason(target.CloseReason):
+				continue
+			default:
+				r.Message = fmt.Sprintf("fallback for %s, which closed without failing (close reason %q)",
+					target.ID, target.CloseReason)
+			}
+
+		case model.DepWaitsFor:
+			children, err := e.openChildren(target.ID)
+			if err != nil {
+				return nil, err
+			}
+			if closed && len(children) == 0 {
+				continue
+			}
+			if closed {
+				r.Message = fmt.Sprintf("waits for the children of %s: %d still open", target.ID, len(children))
+			} else {
+				r.Message = fmt.Sprintf("waits for %s and its children (%d still open)",
+					describeIssue(target), len(children))
+			}
+			// The children are listed alongside the target rather than
+			// under it: they hold this issue back, not the target.
+			for _, c := range children {
+				b, err := e.blocker(c, d.Type)
+				if err != nil {
+					return nil, err
+				}
+				r.Blockers = append(r.Blockers, b)
+			}
+
+		case model.DepUntil:
+			if !closed {
+				continue
+			}
+			r.Message = fmt.Sprintf("only relevant until %s, which is closed", target.ID)
+
+		default:
+			continue
+		}
+
+		if !closed {
+			b, err := e.blocker(target, d.Type)
+			if err != nil {
+				return nil, err
+			}
+			r.Blockers = append([]model.Blocker{b}, r.Blockers...)
+		}
+		reasons = append(reasons, r)
+	}
+	return reasons, nil
+}
+
+// blocker describes an unfinished issue and, the first time it is seen,
+// what holds it back in turn.
+func (e *readyExplainer) blocker(i *model.Issue, via model.DependencyType) (model.Blocker, error) {
+	b := model.Blocker{ID: i.ID, Title: i.Title, Status: i.Status, Assignee: i.Assignee, Via: via}
+	if e.expanded[i.ID] {
+		b.Repeated = true
+		return b, nil
+	}
+	e.expanded[i.ID] = true
+
+	policy, err := e.policy(i.ProjectID)
+	if err != nil {
+		return b, err
+	}
+	reasons, err := e.gates(i, policy)
+	if err != nil {
+		return b, err
+	}
+	for _, r := range reasons {
+		b.BlockedBy = append(b.BlockedBy, r.Blockers...)
+	}
+	return b, nil
+}
+
+func (e *readyExplainer) openChildren(parentID string) ([]*model.Issue, error) {
+	deps, err := e.s.ListDependencies(e.ctx, parentID, "downstream")
+	if err != nil {
+		return nil, fmt.Errorf("listing children of %s: %w", parentID, err)
+	}
+	var open []*model.Issue
+	for _, d := range deps {
+		if d.Type != model.DepParentChild {
+			continue
+		}
+		child, err := e.issue(d.IssueID)
+		if err != nil {
+			return nil, fmt.Errorf("looking up child: %w", err)
+		}
+		if child.Status != model.StatusClosed {
+			open = append(open, child)
+		}
+	}
+	return open, nil
+}
+
+// heldAncestors returns an ancestor reason for each ancestor of id that is
+// blocked, deferred, or gated under its own project's policy, nearest first.
+func (e *readyExplainer) heldAncestors(id string) ([]model.ReadyReason, error) {
+	var reasons []model.ReadyReason
+	seen := map[string]bool{id: true}
+	for {
+		deps, err := e.s.ListDependencies(e.ctx, id, "upstream")
+		if err != nil {
+			return nil, fmt.Errorf("listing dependencies of %s: %w", id, err)
+		}
+		parentID := ""
+		for _, d := range deps {
+			if d.Type == model.DepParentChild && !seen[d.DependsOnID] {
+				parentID = d.DependsOnID
+				break
+			}
+		}
+		if parentID == "" {
+			return reasons, nil
+		}
+		seen[parentID] = true
+		id = parentID
+
+		parent, err := e.issue(parentID)
+		if err != nil {
+			return nil, fmt.Errorf("looking up parent: %w", err)
+		}
+		r := model.ReadyReason{Kind: model.ReadyReasonAncestor, IssueID: parent.ID}
+		switch {
+		case parent.Status == model.StatusBlocked, parent.Status == model.StatusDeferred:
+			r.Message = fmt.Sprintf("ancestor %s is %s", parent.ID, parent.Status)
+		case parent.Status == model.StatusClosed:
+			continue
+		case parent.DeferUntil != nil && parent.DeferUntil.After(e.now):
+			r.Message = fmt.Sprintf("ancestor %s is deferred until %s",
+				parent.ID, parent.DeferUntil.UTC().Format(time.RFC3339))
+			r.DeferUntil = parent.DeferUntil
+		default:
+			policy, err := e.policy(parent.ProjectID)
+			if err != nil {
+				return nil, err
+			}
+			gates, err := e.gates(parent, policy)
+			if err != nil {
+				return nil, err
+			}
+			if len(gates) == 0 {
+				continue
+			}
+			r.Message = fmt.Sprintf("ancestor %s is held back: %s", parent.ID, gates[0].Message)
+			if len(gates) > 1 {
+				r.Message += fmt.Sprintf(" (and %d more)", len(gates)-1)
+			}
+			for _, g := range gates {
+				r.Blockers = append(r.Blockers, g.Blockers...)
+			}
+		}
+		reasons = append(reasons, r)
+	}
+}
+
+// describeIssue renders "doit-abc (in_progress, assigned to bob)".
+func describeIssue(i *model.Issue) string {
+	if i.Assignee == "" {
+		return fmt.Sprintf("%s (%s)", i.ID, i.Status)
+	}
+	return fmt.Sprintf("%s (%s, assigned to %s)", i.ID, i.Status, i.Assignee)
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// defaultGCPolicy is the policy of a tenant that stored none.
+func defaultGCPolicy() *model.GCPolicy {
+	p := &model.GCPolicy{
+		Retention: make(map[model.WispType]string, len(model.DefaultWispRetention)),
+		Ephemeral: model.DefaultEphemeralRetention.String(),
+		Default:   true,
+	}
+	for t, d := range model.DefaultWispRetention {
+		p.Retention[t] = d.String()
+	}
+	return p
+}
+
+// normalizeGCPolicy validates policy, writing its durations in canonical
+// form and filling in the default retention of wisp types it leaves out.
+func normalizeGCPolicy(in *model.GCPolicy) (*model.GCPolicy, error) {
+	out := defaultGCPolicy()
+	out.Default = false
+	out.Report = in.Report
+	for t, v := range in.Retention {
+		if !slices.Contains(model.WispTypes, t) {
+			return nil, fmt.Errorf("unknown wisp type %q", t)
+		}
+		d, err := parseRetention(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s retention: %w", t, err)
+		}
+		out.Retention[t] = d.String()
+	}
+	if in.Ephemeral != "" {
+		d, err := parseRetention(in.Ephemeral)
+		if err != nil {
+			return nil, fmt.Errorf("ephemeral retention: %w", err)
+		}
+		out.Ephemeral = d.String()
+	}
+	return out, nil
+}
+
+func parseRetention(v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", v, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive, got %s", v)
+	}
+	return d, nil
+}
+
+func copyGCPolicy(p *model.GCPolicy) *model.GCPolicy {
+	out := *p
+	out.Retention = maps.Clone(p.Retention)
+	return &out
+}
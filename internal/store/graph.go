@@ -0,0 +1,307 @@
+package store
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// ErrCycle matches (via errors.Is) every *CycleError.
+var ErrCycle = errors.New("dependency cycle")
+
+// CycleError is returned by AddDependency when the new edge would close a
+// cycle among blocking or hierarchical dependencies. Path is the cycle the
+// edge would create: the new edge's issue, its depends_on, and the existing
+// edges leading back to the issue.
+type CycleError struct {
+	Type model.DependencyType
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("adding %s dependency %s → %s would create a cycle: %s",
+		e.Type, e.Path[0], e.Path[1], strings.Join(e.Path, " → "))
+}
+
+func (e *CycleError) Unwrap() error { return ErrCycle }
+
+// familyTypes lists the dependency types in an acyclic family.
+func familyTypes(family string) []string {
+	var types []string
+	for _, t := range []model.DependencyType{
+		model.DepBlocks, model.DepConditionalBlocks, model.DepWaitsFor, model.DepParentChild,
+	} {
+		if t.CycleFamily() == family {
+			types = append(types, string(t))
+		}
+	}
+	return types
+}
+
+// edgeLookup returns, for each issue in ids, the depends_on IDs of its
+// edges whose type is one of types.
+type edgeLookup func(ids []string, types []string) (map[string][]string, error)
+
+// checkCycle returns a *CycleError if adding input would close a cycle in
+// its type's family. It walks the existing edges breadth-first from
+// input.DependsOnID, so the reported path is a shortest one.
+func checkCycle(input AddDependencyInput, edgesFrom edgeLookup) error {
+	family := input.Type.CycleFamily()
+	if family == "" {
+		return nil
+	}
+	if input.IssueID == input.DependsOnID {
+		return &CycleError{Type: input.Type, Path: []string{input.IssueID, input.IssueID}}
+	}
+
+	types := familyTypes(family)
+	prev := map[string]string{input.DependsOnID: ""}
+	frontier := []string{input.DependsOnID}
+	for len(frontier) > 0 {
+		next, err := edgesFrom(frontier, types)
+		if err != nil {
+			return fmt.Errorf("checking for dependency cycles: %w", err)
+		}
+		var following []string
+		for _, from := range frontier {
+			targets := next[from]
+			sort.Strings(targets)
+			for _, to := range targets {
+				if _, seen := prev[to]; seen {
+					continue
+				}
+				prev[to] = from
+				if to == input.IssueID {
+					return &CycleError{Type: input.Type, Path: cyclePath(input, prev)}
+				}
+				following = append(following, to)
+			}
+		}
+		frontier = following
+	}
+	return nil
+}
+
+// cyclePath rebuilds issue → depends_on → … → issue from the BFS tree.
+func cyclePath(input AddDependencyInput, prev map[string]string) []string {
+	var back []string
+	for id := input.IssueID; id != ""; id = prev[id] {
+		back = append(back, id)
+	}
+	path := []string{input.IssueID}
+	for i := len(back) - 1; i >= 0; i-- {
+		path = append(path, back[i])
+	}
+	return path
+}
+
+// analyzeGraph builds a GraphReport from a tenant's issue IDs and every
+// dependency touching them.
+func analyzeGraph(tenantID string, issueIDs []string, deps []model.Dependency) *model.GraphReport {
+	report := &model.GraphReport{
+		TenantID:           tenantID,
+		Issues:             len(issueIDs),
+		Dependencies:       len(deps),
+		Cycles:             []model.GraphCycle{},
+		OrphanedChildren:   []model.OrphanedChild{},
+		DanglingReferences: []model.Dependency{},
+	}
+
+	owned := make(map[string]bool, len(issueIDs))
+	for _, id := range issueIDs {
+		owned[id] = true
+	}
+
+	adj := map[string]map[string][]string{} // family → issue → depends_on
+	hasParent := make(map[string]bool)
+	for _, d := range deps {
+		if !owned[d.IssueID] || !owned[d.DependsOnID] {
+			report.DanglingReferences = append(report.DanglingReferences, d)
+			continue
+		}
+		if d.Type == model.DepParentChild {
+			hasParent[d.IssueID] = true
+		}
+		if family := d.Type.CycleFamily(); family != "" {
+			if adj[family] == nil {
+				adj[family] = make(map[string][]string)
+			}
+			adj[family][d.IssueID] = append(adj[family][d.IssueID], d.DependsOnID)
+		}
+	}
+
+	for _, family := range []string{model.FamilyBlocking, model.FamilyHierarchy} {
+		for _, path := range findCycles(adj[family]) {
+			report.Cycles = append(report.Cycles, model.GraphCycle{Family: family, Path: path})
+		}
+	}
+
+	sorted := append([]string(nil), issueIDs...)
+	sort.Strings(sorted)
+	for _, id := range sorted {
+		parent, ok := ImpliedParent(id)
+		if !ok || hasParent[id] {
+			continue
+		}
+		report.OrphanedChildren = append(report.OrphanedChildren, model.OrphanedChild{
+			IssueID:      id,
+			ParentID:     parent,
+			ParentExists: owned[parent],
+		})
+	}
+
+	sort.Slice(report.DanglingReferences, func(a, b int) bool {
+		da, db := report.DanglingReferences[a], report.DanglingReferences[b]
+		if da.IssueID != db.IssueID {
+			return da.IssueID < db.IssueID
+		}
+		return da.DependsOnID < db.DependsOnID
+	})
+
+	report.Valid = len(report.Cycles) == 0 && len(report.OrphanedChildren) == 0 &&
+		len(report.DanglingReferences) == 0
+	return report
+}
+
+// ImpliedParent returns "doit-abc" for a hierarchical ID like "doit-abc.3",
+// and false for an ID with no numeric child suffix.
+func ImpliedParent(id string) (string, bool) {
+	i := strings.LastIndexByte(id, '.')
+	if i <= 0 || i == len(id)-1 {
+		return "", false
+	}
+	for _, c := range id[i+1:] {
+		if c < '0' || c > '9' {
+			return "", false
+		}
+	}
+	return id[:i], true
+}
+
+// ChildNumber returns 3 for a hierarchical ID like "doit-abc.3", and 0 for
+// an ID with no numeric child suffix.
+func ChildNumber(id string) int {
+	parent, ok := ImpliedParent(id)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.Atoi(id[len(parent)+1:])
+	return n
+}
+
+// CompareChildIDs orders sibling IDs by their child number, so "x.10" sorts
+// after "x.9", falling back to the IDs themselves.
+func CompareChildIDs(a, b string) int {
+	if c := cmp.Compare(ChildNumber(a), ChildNumber(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// findCycles returns one cycle per strongly connected component of adj
+// that contains a cycle, each starting at the component's smallest ID.
+func findCycles(adj map[string][]string) [][]string {
+	nodes := make([]string, 0, len(adj))
+	for id, targets := range adj {
+		nodes = append(nodes, id)
+		sort.Strings(targets)
+	}
+	sort.Strings(nodes)
+
+	// Tarjan's algorithm.
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+	var visit func(v string)
+	visit = func(v string) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range adj[v] {
+			if _, seen := index[w]; !seen {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var comp []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			comp = append(comp, w)
+			if w == v {
+				break
+			}
+		}
+		components = append(components, comp)
+	}
+	for _, v := range nodes {
+		if _, seen := index[v]; !seen {
+			visit(v)
+		}
+	}
+
+	var cycles [][]string
+	for _, comp := range components {
+		sort.Strings(comp)
+		start := comp[0]
+		if len(comp) == 1 && !containsString(adj[start], start) {
+			continue
+		}
+		in := make(map[string]bool, len(comp))
+		for _, id := range comp {
+			in[id] = true
+		}
+		cycles = append(cycles, cycleWithin(adj, in, start))
+	}
+	sort.Slice(cycles, func(a, b int) bool { return cycles[a][0] < cycles[b][0] })
+	return cycles
+}
+
+// cycleWithin finds a shortest cycle through start using only nodes in
+// the strongly connected component in.
+func cycleWithin(adj map[string][]string, in map[string]bool, start string) []string {
+	prev := map[string]string{}
+	frontier := []string{start}
+	for len(frontier) > 0 {
+		var following []string
+		for _, from := range frontier {
+			for _, to := range adj[from] {
+				if !in[to] {
+					continue
+				}
+				if to == start {
+					var back []string
+					for id := from; id != start; id = prev[id] {
+						back = append(back, id)
+					}
+					path := []string{start}
+					for i := len(back) - 1; i >= 0; i-- {
+						path = append(path, back[i])
+					}
+					return append(path, start)
+				}
+				if _, seen := prev[to]; seen {
+					continue
+				}
+				prev[to] = from
+				following = append(following, to)
+			}
+		}
+		frontier = following
+	}
+	return []string{start, start} // unreachable for a real component
+}
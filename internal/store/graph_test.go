@@ -0,0 +1,83 @@
+package store
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+func TestAnalyzeGraph(t *testing.T) {
+	dep := func(from, to string, typ model.DependencyType) model.Dependency {
+		return model.Dependency{IssueID: from, DependsOnID: to, Type: typ}
+	}
+	ids := []string{"a", "b", "c", "d", "p", "p.1", "p.2", "q.1"}
+	report := analyzeGraph("t1", ids, []model.Dependency{
+		dep("a", "b", model.DepBlocks),
+		dep("b", "c", model.DepWaitsFor),
+		dep("c", "a", model.DepConditionalBlocks),
+		dep("d", "d", model.DepBlocks),
+		dep("a", "d", model.DepRelated), // informational: may cycle
+		dep("d", "a", model.DepRelated),
+		dep("p.1", "p", model.DepParentChild),
+		dep("p", "p.1", model.DepParentChild),
+		dep("c", "elsewhere-1", model.DepBlocks),
+	})
+
+	want := []model.GraphCycle{
+		{Family: model.FamilyBlocking, Path: []string{"a", "b", "c", "a"}},
+		{Family: model.FamilyBlocking, Path: []string{"d", "d"}},
+		{Family: model.FamilyHierarchy, Path: []string{"p", "p.1", "p"}},
+	}
+	if !reflect.DeepEqual(report.Cycles, want) {
+		t.Errorf("cycles = %+v, want %+v", report.Cycles, want)
+	}
+	wantOrphans := []model.OrphanedChild{
+		{IssueID: "p.2", ParentID: "p", ParentExists: true},
+		{IssueID: "q.1", ParentID: "q", ParentExists: false},
+	}
+	if !reflect.DeepEqual(report.OrphanedChildren, wantOrphans) {
+		t.Errorf("orphans = %+v, want %+v", report.OrphanedChildren, wantOrphans)
+	}
+	if len(report.DanglingReferences) != 1 || report.DanglingReferences[0].DependsOnID != "elsewhere-1" {
+		t.Errorf("dangling = %+v", report.DanglingReferences)
+	}
+	if report.Valid || report.Issues != 8 || report.Dependencies != 9 {
+		t.Errorf("report summary = valid %v, %d issues, %d deps", report.Valid, report.Issues, report.Dependencies)
+	}
+
+	if clean := analyzeGraph("t1", []string{"a", "b"}, []model.Dependency{dep("a", "b", model.DepBlocks)}); !clean.Valid {
+		t.Errorf("acyclic graph should be valid: %+v", clean)
+	}
+}
+
+func TestImpliedParent(t *testing.T) {
+	for _, tc := range []struct {
+		id, parent string
+		ok         bool
+	}{
+		{"doit-abc.3", "doit-abc", true},
+		{"doit-abc.3.12", "doit-abc.3", true},
+		{"doit-abc", "", false},
+		{"doit-abc.", "", false},
+		{"doit-v1.x", "", false},
+	} {
+		parent, ok := ImpliedParent(tc.id)
+		if parent != tc.parent || ok != tc.ok {
+			t.Errorf("ImpliedParent(%q) = %q, %v; want %q, %v", tc.id, parent, ok, tc.parent, tc.ok)
+		}
+	}
+}
+
+func TestCompareChildIDs(t *testing.T) {
+	ids := []string{"doit-abc.10", "doit-abc.2", "doit-abc", "doit-abc.9", "doit-abc.1"}
+	slices.SortFunc(ids, CompareChildIDs)
+	want := []string{"doit-abc", "doit-abc.1", "doit-abc.2", "doit-abc.9", "doit-abc.10"}
+	if !slices.Equal(ids, want) {
+		t.Errorf("sorted = %v, want %v", ids, want)
+	}
+	if n := ChildNumber("doit-abc.3.12"); n != 12 {
+		t.Errorf("ChildNumber(doit-abc.3.12) = %d, want 12", n)
+	}
+}
@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// IssueAt rebuilds issue id as it stood at the given moment. Starting from
+// the issue as it is now, it undoes every recorded change made after that
+// moment, newest first: field updates and status changes, labels, the
+// issue's own dependency edges, compaction and trash moves. Compaction
+// snapshots restore the content each compaction replaced, so compactions
+// from before the audit trail existed are undone too. It is built on the
+// Store interface, so it works with any backend.
+func IssueAt(ctx context.Context, s Store, id string, at time.Time) (*model.IssueVersion, error) {
+	h, err := loadIssueHistory(ctx, s, id)
+	if err != nil {
+		return nil, err
+	}
+	if at.Before(h.issue.CreatedAt) {
+		return nil, fmt.Errorf("issue %s did not exist at %s; it was created at %s",
+			id, at.UTC().Format(time.RFC3339), h.issue.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	return h.at(at)
+}
+
+// DiffIssue lists the fields of issue id that changed between from and to,
+// and who changed them. An issue created after from is compared from its
+// creation.
+func DiffIssue(ctx context.Context, s Store, id string, from, to time.Time) (*model.IssueDiff, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("diff end %s is before its start %s", to.UTC().Format(time.RFC3339), from.UTC().Format(time.RFC3339))
+	}
+	h, err := loadIssueHistory(ctx, s, id)
+	if err != nil {
+		return nil, err
+	}
+	if to.Before(h.issue.CreatedAt) {
+		return nil, fmt.Errorf("issue %s did not exist at %s; it was created at %s",
+			id, to.UTC().Format(time.RFC3339), h.issue.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	if from.Before(h.issue.CreatedAt) {
+		from = h.issue.CreatedAt
+	}
+	before, err := h.at(from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := h.at(to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &model.IssueDiff{IssueID: id, From: from, To: to, Changes: []model.FieldChange{}, Actors: []string{}}
+	for _, f := range versionFields {
+		old, cur := f.value(before), f.value(after)
+		if old != cur {
+			diff.Changes = append(diff.Changes, model.FieldChange{Field: f.name, From: old, To: cur})
+		}
+	}
+	actors := make(map[string]bool)
+	for _, e := range h.events {
+		if e.CreatedAt.After(from) && !e.CreatedAt.After(to) {
+			actors[e.Actor] = true
+		}
+	}
+	for a := range actors {
+		diff.Actors = append(diff.Actors, a)
+	}
+	sort.Strings(diff.Actors)
+	return diff, nil
+}
+
+// versionFields are the parts of an IssueVersion DiffIssue compares: the
+// tracked fields, then the ones with their own event types.
+var versionFields = func() []struct {
+	name  string
+	value func(*model.IssueVersion) string
+} {
+	type field = struct {
+		name  string
+		value func(*model.IssueVersion) string
+	}
+	var fields []field
+	for _, f := range trackedFields {
+		fields = append(fields, field{f.name, func(v *model.IssueVersion) string { return f.value(&v.Issue) }})
+	}
+	return append(fields,
+		field{"status", func(v *model.IssueVersion) string { return string(v.Issue.Status) }},
+		field{"labels", func(v *model.IssueVersion) string { return strings.Join(v.Issue.Labels, ", ") }},
+		field{"dependencies", func(v *model.IssueVersion) string { return formatDependencies(v.Dependencies) }},
+		field{"compaction_level", func(v *model.IssueVersion) string { return strconv.Itoa(v.Issue.CompactionLevel) }},
+		field{"deleted_at", func(v *model.IssueVersion) string { return formatOptionalTime(v.Issue.DeletedAt) }},
+	)
+}()
+
+// issueHistory is everything IssueAt replays for one issue. events and
+// snapshots are newest first.
+type issueHistory struct {
+	issue     *model.Issue
+	deps      []model.Dependency
+	events    []model.Event
+	snapshots []model.CompactionSnapshot
+}
+
+func loadIssueHistory(ctx context.Context, s Store, id string) (*issueHistory, error) {
+	issue, err := s.GetIssue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := s.ListDependencies(ctx, id, "upstream")
+	if err != nil {
+		return nil, fmt.Errorf("listing dependencies of %s: %w", id, err)
+	}
+	events, err := s.ListEvents(ctx, id, 0)
+	if err != nil {
+		return nil, fmt.Errorf("listing events of %s: %w", id, err)
+	}
+	snaps, err := s.GetCompactionSnapshots(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("listing compaction snapshots of %s: %w", id, err)
+	}
+	sort.SliceStable(snaps, func(a, b int) bool { return snaps[a].CreatedAt.After(snaps[b].CreatedAt) })
+	return &issueHistory{issue: issue, deps: deps, events: events, snapshots: snaps}, nil
+}
+
+func (h *issueHistory) at(at time.Time) (*model.IssueVersion, error) {
+	issue := *h.issue
+	labels := make(map[string]bool, len(issue.Labels))
+	for _, l := range issue.Labels {
+		labels[l] = true
+	}
+	deps := make(map[string]model.Dependency, len(h.deps))
+	for _, d := range h.deps {
+		deps[d.DependsOnID] = d
+	}
+	v := &model.IssueVersion{At: at}
+
+	snaps := h.snapshots
+	undoSnapshots := func(after time.Time) {
+		for len(snaps) > 0 && snaps[0].CreatedAt.After(after) {
+			snaps[0].ApplyOriginal(&issue)
+			issue.CompactionLevel = snaps[0].FromLevel
+			snaps = snaps[1:]
+		}
+	}
+
+	var older []model.Event
+	for i, e := range h.events {
+		if !e.CreatedAt.After(at) {
+			older = h.events[i:]
+			break
+		}
+		undoSnapshots(e.CreatedAt)
+		undone, err := undoEvent(&issue, labels, deps, e)
+		if err != nil {
+			return nil, fmt.Errorf("replaying event %d (%s): %w", e.ID, e.EventType, err)
+		}
+		if undone {
+			v.Undone++
+		}
+	}
+	undoSnapshots(at)
+
+	// Timestamps and attributions that only older events record.
+	if issue.UpdatedAt.After(at) {
+		issue.UpdatedAt = issue.CreatedAt
+		if len(older) > 0 {
+			issue.UpdatedAt = older[0].CreatedAt
+		}
+	}
+	if issue.Status != model.StatusClosed {
+		issue.ClosedAt = nil
+	} else if c := newestEvent(older, model.EventClosed, ""); c != nil {
+		closedAt := c.CreatedAt
+		issue.ClosedAt = &closedAt
+	}
+	if issue.DeletedAt != nil {
+		if d := newestEvent(older, model.EventDeleted, ""); d != nil {
+			issue.DeletedBy = d.Actor
+		}
+	}
+
+	issue.Labels = []string{}
+	for l := range labels {
+		issue.Labels = append(issue.Labels, l)
+	}
+	sort.Strings(issue.Labels)
+
+	v.Dependencies = []model.Dependency{}
+	issue.ParentID = ""
+	for _, d := range deps {
+		if d.CreatedAt.IsZero() {
+			if added := newestEvent(older, model.EventDependencyAdded, d.DependsOnID); added != nil {
+				d.CreatedAt, d.CreatedBy = added.CreatedAt, added.Actor
+			}
+		}
+		if d.Type == model.DepParentChild {
+			issue.ParentID = d.DependsOnID
+		}
+		v.Dependencies = append(v.Dependencies, d)
+	}
+	sort.Slice(v.Dependencies, func(a, b int) bool { return v.Dependencies[a].DependsOnID < v.Dependencies[b].DependsOnID })
+
+	v.Issue = issue
+	return v, nil
+}
+
+// undoEvent rolls issue back to before e, reporting whether e changed
+// anything replay tracks. Comments and creation are left alone.
+func undoEvent(issue *model.Issue, labels map[string]bool, deps map[string]model.Dependency, e model.Event) (bool, error) {
+	switch e.EventType {
+	case model.EventUpdated:
+		for _, f := range trackedFields {
+			if f.name == e.Field {
+				return true, f.set(issue, e.OldValue)
+			}
+		}
+		return false, nil
+	case model.EventStatusChanged, model.EventClosed, model.EventReopened:
+		issue.Status = model.Status(e.OldValue)
+	case model.EventLabelAdded:
+		delete(labels, e.NewValue)
+	case model.EventLabelRemoved:
+		labels[e.OldValue] = true
+	case model.EventDependencyAdded:
+		if e.OldValue == "" {
+			delete(deps, e.NewValue)
+			break
+		}
+		d := deps[e.NewValue]
+		d.Type = model.DependencyType(e.OldValue)
+		deps[e.NewValue] = d
+	case model.EventDependencyRemoved:
+		deps[e.OldValue] = model.Dependency{IssueID: e.IssueID, DependsOnID: e.OldValue, Type: model.DependencyType(e.Comment)}
+	case model.EventCompacted:
+		level, err := strconv.Atoi(e.OldValue)
+		if err != nil {
+			return false, err
+		}
+		issue.CompactionLevel = level
+	case model.EventDeleted:
+		issue.DeletedAt, issue.DeletedBy = nil, ""
+	case model.EventRestored:
+		t, err := parseOptionalTime(e.OldValue)
+		if err != nil {
+			return false, err
+		}
+		issue.DeletedAt = t
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// newestEvent returns the first event of type t in events, newest first,
+// whose new value is newValue when one is given.
+func newestEvent(events []model.Event, t model.EventType, newValue string) *model.Event {
+	for i := range events {
+		if events[i].EventType == t && (newValue == "" || events[i].NewValue == newValue) {
+			return &events[i]
+		}
+	}
+	return nil
+}
+
+func formatDependencies(deps []model.Dependency) string {
+	parts := make([]string, len(deps))
+	for i, d := range deps {
+		parts[i] = fmt.Sprintf("%s %s", d.Type, d.DependsOnID)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// checkJobInterval rejects negative intervals; zero turns a job off.
+func checkJobInterval(job string, interval time.Duration) error {
+	if job == "" {
+		return fmt.Errorf("job is required")
+	}
+	if interval < 0 {
+		return fmt.Errorf("job interval must not be negative, got %s", interval)
+	}
+	return nil
+}
+
+// parseJobIntervals decodes the intervals stored per job.
+func parseJobIntervals(stored map[string]string) (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration, len(stored))
+	for job, v := range stored {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("job %s has an invalid interval %q: %w", job, v, err)
+		}
+		intervals[job] = d
+	}
+	return intervals, nil
+}
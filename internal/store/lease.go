@@ -0,0 +1,63 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// ReaperActor is the event actor and retry creator recorded when an expired
+// claim is released.
+const ReaperActor = "lease-reaper"
+
+// ErrLeaseLost matches (via errors.Is) RenewLease failures caused by the
+// caller no longer holding the claim: the issue was released, reassigned or
+// moved out of in_progress.
+var ErrLeaseLost = errors.New("claim lease lost")
+
+// leaseExpiry returns when a lease of the given length taken at now ends, or
+// nil for a lease that never expires.
+func leaseExpiry(now time.Time, lease time.Duration) *time.Time {
+	if lease <= 0 {
+		return nil
+	}
+	t := now.Add(lease)
+	return &t
+}
+
+// checkLeaseHolder verifies that claimant still holds the claim on issue.
+func checkLeaseHolder(issue *model.Issue, claimant string) error {
+	if issue.Status != model.StatusInProgress {
+		return fmt.Errorf("%w: issue %s is %s", ErrLeaseLost, issue.ID, issue.Status)
+	}
+	if issue.Assignee != claimant {
+		return fmt.Errorf("%w: issue %s is claimed by %q, not %q", ErrLeaseLost, issue.ID, issue.Assignee, claimant)
+	}
+	return nil
+}
+
+// leaseExpiredMessage is the retry error and event comment for a released claim.
+func leaseExpiredMessage(i *model.Issue) string {
+	msg := "claim lease expired"
+	if i.LeaseExpiresAt != nil {
+		msg += " at " + i.LeaseExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if i.Assignee != "" {
+		msg += " (claimed by " + i.Assignee + ")"
+	}
+	return msg
+}
+
+// releaseEvents records the reaper reopening expired as released, with msg
+// as the comment on the status change.
+func releaseEvents(expired, released *model.Issue, msg string) []AddEventInput {
+	events := issueEvents(expired, released, ReaperActor)
+	for i := range events {
+		if events[i].Field == "status" {
+			events[i].Comment = msg
+		}
+	}
+	return events
+}
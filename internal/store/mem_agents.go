@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// FindAgentBead returns the live agent bead titled name, or nil.
+func (s *MemStore) FindAgentBead(ctx context.Context, name string) (*model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bead := s.agentBead(tid.String(), name, "")
+	if bead == nil {
+		return nil, nil
+	}
+	out := *bead
+	return &out, nil
+}
+
+// agentBead returns the tenant's live agent bead titled name, other than
+// the issue except. Callers must hold s.mu.
+func (s *MemStore) agentBead(tenantID, name, except string) *model.Issue {
+	for id, i := range s.issues {
+		if id != except && i.TenantID == tenantID && i.IssueType == model.TypeAgent &&
+			i.Title == name && i.DeletedAt == nil {
+			return i
+		}
+	}
+	return nil
+}
+
+// checkAgentName enforces what the unique index on agent names does in
+// the SQL stores: at most one live agent bead per name in a tenant.
+// Callers must hold s.mu.
+func (s *MemStore) checkAgentName(issue *model.Issue) error {
+	if issue.IssueType != model.TypeAgent || issue.DeletedAt != nil {
+		return nil
+	}
+	if s.agentBead(issue.TenantID, issue.Title, issue.ID) != nil {
+		return fmt.Errorf("agent %q is already registered", issue.Title)
+	}
+	return nil
+}
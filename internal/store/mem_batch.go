@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// ApplyBatch runs ops in order under the store lock. On the first failure
+// the issue state is restored to what it was before the batch.
+func (s *MemStore) ApplyBatch(ctx context.Context, ops []BatchOp) ([]BatchResult, error) {
+	if err := validateBatch(ops); err != nil {
+		return nil, err
+	}
+	if _, err := requireTenant(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := s.saveIssueState()
+	results, err := applyBatch(ops, &memBatch{s: s, ctx: ctx})
+	if err != nil {
+		s.restoreIssueState(saved)
+		return nil, err
+	}
+	return results, nil
+}
+
+// memBatch runs batch operations with s.mu already held.
+type memBatch struct {
+	s   *MemStore
+	ctx context.Context
+}
+
+func (b *memBatch) checkOwned(issueID string) error {
+	_, err := b.s.ownedIssue(b.ctx, issueID)
+	return err
+}
+
+func (b *memBatch) generateID() (string, error) { return b.s.generateID(b.s.idPrefix) }
+
+func (b *memBatch) nextChildID(parentID string) (string, error) {
+	return b.s.nextChildID(b.ctx, parentID)
+}
+
+func (b *memBatch) createIssue(input CreateIssueInput) (*model.Issue, error) {
+	return b.s.createIssue(b.ctx, input)
+}
+
+func (b *memBatch) updateIssue(id string, input UpdateIssueInput) (*model.Issue, error) {
+	return b.s.updateIssue(b.ctx, id, input)
+}
+
+func (b *memBatch) addDependency(input AddDependencyInput) (*model.Dependency, error) {
+	return b.s.addDependency(b.ctx, input)
+}
+
+func (b *memBatch) addLabel(issueID, label string) error {
+	b.s.addLabelWithEvent(b.ctx, issueID, label)
+	return nil
+}
+
+func (b *memBatch) addComment(issueID, author, text string) (*model.Comment, error) {
+	return b.s.addComment(b.ctx, issueID, author, text)
+}
+
+// memIssueState is a copy of everything a batch can change.
+type memIssueState struct {
+	issues        map[string]model.Issue
+	deps          map[depKey]model.Dependency
+	labels        map[string][]string
+	childCounters map[string]int
+	comments      int
+	events        int
+	nextCommentID int64
+	nextEventID   int64
+}
+
+// saveIssueState copies the issue-related state. Comments and events are
+// append-only, so remembering their lengths is enough. Callers must hold s.mu.
+func (s *MemStore) saveIssueState() memIssueState {
+	st := memIssueState{
+		issues:        make(map[string]model.Issue, len(s.issues)),
+		deps:          make(map[depKey]model.Dependency, len(s.deps)),
+		labels:        make(map[string][]string, len(s.labels)),
+		childCounters: make(map[string]int, len(s.childCounters)),
+		comments:      len(s.comments),
+		events:        len(s.events),
+		nextCommentID: s.nextCommentID,
+		nextEventID:   s.nextEventID,
+	}
+	for id, i := range s.issues {
+		st.issues[id] = *i
+	}
+	for k, d := range s.deps {
+		st.deps[k] = *d
+	}
+	for id := range s.labels {
+		st.labels[id] = s.sortedLabels(id)
+	}
+	for id, n := range s.childCounters {
+		st.childCounters[id] = n
+	}
+	return st
+}
+
+// restoreIssueState puts back state taken by saveIssueState. Issues that
+// still exist are restored in place. Callers must hold s.mu.
+func (s *MemStore) restoreIssueState(st memIssueState) {
+	for id, i := range s.issues {
+		saved, ok := st.issues[id]
+		if !ok {
+			delete(s.issues, id)
+			continue
+		}
+		*i = saved
+	}
+
+	s.deps = make(map[depKey]*model.Dependency, len(st.deps))
+	for k, d := range st.deps {
+		d := d
+		s.deps[k] = &d
+	}
+
+	s.labels = make(map[string]map[string]bool, len(st.labels))
+	for id, labels := range st.labels {
+		for _, l := range labels {
+			s.addLabel(id, l)
+		}
+	}
+
+	s.childCounters = st.childCounters
+	s.comments = s.comments[:st.comments]
+	s.events = s.events[:st.events]
+	s.nextCommentID = st.nextCommentID
+	s.nextEventID = st.nextEventID
+}
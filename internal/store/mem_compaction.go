@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// GetCompactionSettings returns how the tenant's issues are summarized when
+// compacted.
+func (s *MemStore) GetCompactionSettings(ctx context.Context) (*model.CompactionSettings, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings, ok := s.compactionSettings[tenantID]
+	if !ok {
+		return defaultCompactionSettings(), nil
+	}
+	return copyCompactionSettings(settings), nil
+}
+
+// SetCompactionSettings sets how the tenant's issues are summarized when
+// compacted. Nil settings removes them, restoring the defaults.
+func (s *MemStore) SetCompactionSettings(ctx context.Context, settings *model.CompactionSettings) (*model.CompactionSettings, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if settings == nil {
+		delete(s.compactionSettings, tenantID)
+		return defaultCompactionSettings(), nil
+	}
+	settings, err = normalizeCompactionSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	s.compactionSettings[tenantID] = settings
+	return copyCompactionSettings(settings), nil
+}
+
+func copyCompactionSettings(settings *model.CompactionSettings) *model.CompactionSettings {
+	out := *settings
+	if out.Endpoint != nil {
+		ep := *out.Endpoint
+		out.Endpoint = &ep
+	}
+	return &out
+}
+
+// GetCompactionPolicy returns the compaction policy of a project within the
+// tenant.
+func (s *MemStore) GetCompactionPolicy(ctx context.Context, projectID string) (*model.CompactionPolicy, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ownsProject(tenantID, projectID) {
+		return nil, fmt.Errorf("project not found")
+	}
+	policy, ok := s.compactionPolicies[projectID]
+	if !ok {
+		return defaultCompactionPolicy(projectID), nil
+	}
+	return copyCompactionPolicy(policy), nil
+}
+
+// SetCompactionPolicy sets when a project's closed issues are compacted.
+// Nil policy removes it, restoring the default.
+func (s *MemStore) SetCompactionPolicy(ctx context.Context, projectID string, policy *model.CompactionPolicy) (*model.CompactionPolicy, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ownsProject(tenantID, projectID) {
+		return nil, fmt.Errorf("project not found")
+	}
+	if policy == nil {
+		delete(s.compactionPolicies, projectID)
+		return defaultCompactionPolicy(projectID), nil
+	}
+	policy, err = normalizeCompactionPolicy(projectID, policy)
+	if err != nil {
+		return nil, err
+	}
+	s.compactionPolicies[projectID] = policy
+	return copyCompactionPolicy(policy), nil
+}
+
+func copyCompactionPolicy(policy *model.CompactionPolicy) *model.CompactionPolicy {
+	out := *policy
+	out.ExcludeLabels = append([]string{}, policy.ExcludeLabels...)
+	return &out
+}
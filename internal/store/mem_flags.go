@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// GenerateFlagID creates a hash-based flag ID with adaptive length.
+func (s *MemStore) GenerateFlagID(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generateFlagID()
+}
+
+func (s *MemStore) generateFlagID() (string, error) {
+	id, ok := generateHashID("flg", len(s.flags), func(id string) bool {
+		_, exists := s.flags[id]
+		return exists
+	})
+	if !ok {
+		return "", fmt.Errorf("failed to generate unique flag ID after 30 attempts")
+	}
+	return id, nil
+}
+
+// RaiseFlag inserts a new flag tied to an issue.
+func (s *MemStore) RaiseFlag(ctx context.Context, input RaiseFlagInput) (*model.Flag, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if input.IssueID != "" {
+		if _, ok := s.issues[input.IssueID]; !ok {
+			return nil, fmt.Errorf("raising flag: issue %s does not exist", input.IssueID)
+		}
+	}
+
+	id, err := s.generateFlagID()
+	if err != nil {
+		return nil, err
+	}
+
+	severity := input.Severity
+	if severity == 0 {
+		severity = 2
+	}
+
+	ctxJSON := input.Context
+	if ctxJSON == nil {
+		ctxJSON = json.RawMessage(`{}`)
+	}
+
+	f := &model.Flag{
+		ID:        id,
+		TenantID:  tenantID.String(),
+		ProjectID: input.ProjectID,
+		IssueID:   input.IssueID,
+		Type:      model.FlagType(input.Type),
+		Severity:  severity,
+		Summary:   input.Summary,
+		Context:   ctxJSON,
+		Status:    model.FlagStatusOpen,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: input.CreatedBy,
+	}
+	s.flags[id] = f
+
+	out := *f
+	return &out, nil
+}
+
+// ListFlags returns flags matching the filter.
+func (s *MemStore) ListFlags(ctx context.Context, filter model.FlagFilter) ([]model.Flag, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flags := []model.Flag{}
+	for _, f := range s.flags {
+		if f.TenantID != tenantID.String() || !projectAllowed(ctx, f.ProjectID) {
+			continue
+		}
+		if filter.ProjectID != nil && f.ProjectID != *filter.ProjectID {
+			continue
+		}
+		if filter.Status != nil && f.Status != *filter.Status {
+			continue
+		}
+		if filter.Severity != nil && f.Severity != *filter.Severity {
+			continue
+		}
+		if filter.IssueID != nil && f.IssueID != *filter.IssueID {
+			continue
+		}
+		flags = append(flags, *f)
+	}
+
+	sort.Slice(flags, func(a, b int) bool {
+		x, y := flags[a], flags[b]
+		if x.Severity != y.Severity {
+			return x.Severity < y.Severity
+		}
+		if !x.CreatedAt.Equal(y.CreatedAt) {
+			return x.CreatedAt.After(y.CreatedAt)
+		}
+		return x.ID < y.ID
+	})
+
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, lessonFlagSort)
+		if err != nil {
+			return nil, err
+		}
+		flags = afterCursor(flags, lessonFlagKeyset, cur, flagPosition)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	return paginate(flags, limit, 0), nil
+}
+
+// GetFlag returns a flag by ID.
+func (s *MemStore) GetFlag(ctx context.Context, id string) (*model.Flag, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.flags[id]
+	if !ok || f.TenantID != tenantID.String() {
+		return nil, fmt.Errorf("flag %s not found", id)
+	}
+
+	out := *f
+	return &out, nil
+}
+
+// ResolveFlag marks a flag as resolved with a resolution message.
+func (s *MemStore) ResolveFlag(ctx context.Context, id string, resolution, resolvedBy string) (*model.Flag, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.flags[id]
+	if !ok || f.TenantID != tenantID.String() {
+		return nil, fmt.Errorf("flag %s not found", id)
+	}
+
+	now := time.Now().UTC()
+	f.Status = model.FlagStatusResolved
+	f.Resolution = resolution
+	f.ResolvedAt = &now
+	f.ResolvedBy = resolvedBy
+
+	out := *f
+	return &out, nil
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// GetGCPolicy returns how long the tenant keeps ephemeral issues.
+func (s *MemStore) GetGCPolicy(ctx context.Context) (*model.GCPolicy, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	policy, ok := s.gcPolicies[tenantID]
+	if !ok {
+		return defaultGCPolicy(), nil
+	}
+	return copyGCPolicy(policy), nil
+}
+
+// SetGCPolicy sets how long the tenant keeps ephemeral issues. Nil removes
+// the policy, restoring the defaults.
+func (s *MemStore) SetGCPolicy(ctx context.Context, policy *model.GCPolicy) (*model.GCPolicy, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if policy == nil {
+		delete(s.gcPolicies, tenantID)
+		return defaultGCPolicy(), nil
+	}
+	policy, err = normalizeGCPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	s.gcPolicies[tenantID] = policy
+	return copyGCPolicy(policy), nil
+}
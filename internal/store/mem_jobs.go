@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// RecordJobRun adds a run to the tenant's job history, dropping the oldest
+// runs of the job past JobHistoryLimit.
+func (s *MemStore) RecordJobRun(ctx context.Context, run *model.JobRun) error {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextJobRunID++
+	run.ID = s.nextJobRunID
+	stored := *run
+	stored.Result = append([]byte(nil), run.Result...)
+	runs := append(s.jobRuns[tenantID], stored)
+
+	kept, count := runs[:0], 0
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Job == run.Job {
+			count++
+			if count > JobHistoryLimit {
+				runs[i].ID = 0 // dropped below
+			}
+		}
+	}
+	for _, r := range runs {
+		if r.ID != 0 {
+			kept = append(kept, r)
+		}
+	}
+	s.jobRuns[tenantID] = kept
+	return nil
+}
+
+// ListJobRuns returns the tenant's runs of job, or of every job when job is
+// empty, newest first. A limit of 0 or less returns all that are kept.
+func (s *MemStore) ListJobRuns(ctx context.Context, job string, limit int) ([]model.JobRun, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := []model.JobRun{}
+	stored := s.jobRuns[tenantID]
+	for i := len(stored) - 1; i >= 0 && (limit <= 0 || len(runs) < limit); i-- {
+		if job == "" || stored[i].Job == job {
+			r := stored[i]
+			r.Result = append([]byte(nil), r.Result...)
+			runs = append(runs, r)
+		}
+	}
+	return runs, nil
+}
+
+// GetJobIntervals returns the tenant's overrides of how often jobs run.
+func (s *MemStore) GetJobIntervals(ctx context.Context) (map[string]time.Duration, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	intervals := make(map[string]time.Duration)
+	maps.Copy(intervals, s.jobIntervals[tenantID])
+	return intervals, nil
+}
+
+// SetJobInterval sets how often job runs for the tenant. Nil removes the
+// override, so the server's default applies again.
+func (s *MemStore) SetJobInterval(ctx context.Context, job string, interval *time.Duration) error {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if interval == nil {
+		delete(s.jobIntervals[tenantID], job)
+		return nil
+	}
+	if err := checkJobInterval(job, *interval); err != nil {
+		return err
+	}
+	if s.jobIntervals[tenantID] == nil {
+		s.jobIntervals[tenantID] = make(map[string]time.Duration)
+	}
+	s.jobIntervals[tenantID][job] = *interval
+	return nil
+}
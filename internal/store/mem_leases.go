@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// RenewLease extends the claimant's lease on an in_progress issue and records
+// the heartbeat as the issue's last activity.
+func (s *MemStore) RenewLease(ctx context.Context, issueID, claimant string, lease time.Duration) (*model.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, err := s.ownedIssue(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLeaseHolder(issue, claimant); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	issue.LastActivity = &now
+	issue.LeaseExpiresAt = leaseExpiry(now, lease)
+
+	out := *issue
+	return &out, nil
+}
+
+// ReleaseExpiredLeases reopens every in_progress issue whose lease expired
+// before now, recording an abandoned retry and a status_changed event for each.
+func (s *MemStore) ReleaseExpiredLeases(ctx context.Context, now time.Time) ([]model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now = now.UTC()
+	var expired []*model.Issue
+	for _, i := range s.issues {
+		if i.TenantID == tid.String() && i.DeletedAt == nil && i.Status == model.StatusInProgress &&
+			i.LeaseExpiresAt != nil && i.LeaseExpiresAt.Before(now) {
+			expired = append(expired, i)
+		}
+	}
+	sort.Slice(expired, func(a, b int) bool { return expired[a].LeaseExpiresAt.Before(*expired[b].LeaseExpiresAt) })
+
+	released := []model.Issue{}
+	for _, i := range expired {
+		msg := leaseExpiredMessage(i)
+		r, err := s.recordRetry(i.TenantID, RecordRetryInput{
+			IssueID:   i.ID,
+			ProjectID: i.ProjectID,
+			Status:    string(model.RetryAbandoned),
+			Error:     msg,
+			Agent:     i.Assignee,
+			CreatedBy: ReaperActor,
+		}, now)
+		if err != nil {
+			return released, err
+		}
+		ended := now
+		r.EndedAt = &ended
+
+		before := *i
+		i.Status = model.StatusOpen
+		i.Assignee = ""
+		i.LeaseExpiresAt = nil
+		i.UpdatedAt = now
+		s.addEvents(releaseEvents(&before, i, msg), now)
+		released = append(released, *i)
+	}
+	return released, nil
+}
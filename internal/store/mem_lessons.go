@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// GenerateLessonID creates a hash-based lesson ID with adaptive length.
+func (s *MemStore) GenerateLessonID(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generateLessonID()
+}
+
+func (s *MemStore) generateLessonID() (string, error) {
+	id, ok := generateHashID("lsn", len(s.lessons), func(id string) bool {
+		_, exists := s.lessons[id]
+		return exists
+	})
+	if !ok {
+		return "", fmt.Errorf("failed to generate unique lesson ID after 30 attempts")
+	}
+	return id, nil
+}
+
+// RecordLesson inserts a new lesson learned.
+func (s *MemStore) RecordLesson(ctx context.Context, input RecordLessonInput) (*model.Lesson, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if input.IssueID != "" {
+		if _, ok := s.issues[input.IssueID]; !ok {
+			return nil, fmt.Errorf("recording lesson: issue %s does not exist", input.IssueID)
+		}
+	}
+
+	id, err := s.generateLessonID()
+	if err != nil {
+		return nil, err
+	}
+
+	severity := input.Severity
+	if severity == 0 {
+		severity = 2
+	}
+
+	components := input.Components
+	if components == nil {
+		components = []string{}
+	}
+
+	l := &model.Lesson{
+		ID:         id,
+		TenantID:   tenantID,
+		ProjectID:  input.ProjectID,
+		IssueID:    input.IssueID,
+		Title:      input.Title,
+		Mistake:    input.Mistake,
+		Correction: input.Correction,
+		Expert:     input.Expert,
+		Components: append([]string(nil), components...),
+		Severity:   severity,
+		Status:     model.LessonOpen,
+		CreatedAt:  time.Now().UTC(),
+		CreatedBy:  input.CreatedBy,
+	}
+	s.lessons[id] = l
+
+	out := *l
+	return &out, nil
+}
+
+// ListLessons returns lessons matching the filter.
+func (s *MemStore) ListLessons(ctx context.Context, filter model.LessonFilter) ([]model.Lesson, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lessons := []model.Lesson{}
+	for _, l := range s.lessons {
+		if l.TenantID != tenantID || !projectAllowed(ctx, l.ProjectID) {
+			continue
+		}
+		if filter.ProjectID != nil && l.ProjectID != *filter.ProjectID {
+			continue
+		}
+		if filter.Status != nil && l.Status != *filter.Status {
+			continue
+		}
+		if filter.Expert != nil && l.Expert != *filter.Expert {
+			continue
+		}
+		if filter.Component != nil && !containsString(l.Components, *filter.Component) {
+			continue
+		}
+		if filter.Severity != nil && l.Severity != *filter.Severity {
+			continue
+		}
+		lessons = append(lessons, *l)
+	}
+
+	sort.Slice(lessons, func(a, b int) bool {
+		x, y := lessons[a], lessons[b]
+		if x.Severity != y.Severity {
+			return x.Severity < y.Severity
+		}
+		if !x.CreatedAt.Equal(y.CreatedAt) {
+			return x.CreatedAt.After(y.CreatedAt)
+		}
+		return x.ID < y.ID
+	})
+
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, lessonFlagSort)
+		if err != nil {
+			return nil, err
+		}
+		lessons = afterCursor(lessons, lessonFlagKeyset, cur, lessonPosition)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	return paginate(lessons, limit, 0), nil
+}
+
+// ResolveLesson marks a lesson as resolved.
+func (s *MemStore) ResolveLesson(ctx context.Context, id string, resolvedBy string) (*model.Lesson, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.lessons[id]
+	if !ok || l.TenantID != tenantID {
+		return nil, fmt.Errorf("lesson %s not found", id)
+	}
+
+	now := time.Now().UTC()
+	l.Status = model.LessonResolved
+	l.ResolvedAt = &now
+	l.ResolvedBy = resolvedBy
+
+	out := *l
+	return &out, nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+)
+
+// CreateProject creates a new project within the tenant from context.
+func (s *MemStore) CreateProject(ctx context.Context, name, slug string) (*model.Project, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tenants[tenantID]; !ok {
+		return nil, fmt.Errorf("creating project: tenant %s does not exist", tenantID)
+	}
+	if s.projectBySlug(tenantID, slug) != nil {
+		return nil, fmt.Errorf("creating project: slug %q already exists", slug)
+	}
+
+	p := &model.Project{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Name:      name,
+		Slug:      slug,
+		CreatedAt: time.Now().UTC(),
+	}
+	s.projects[p.ID] = p
+
+	out := *p
+	return &out, nil
+}
+
+// ListProjects returns projects for the tenant from context.
+func (s *MemStore) ListProjects(ctx context.Context) ([]model.Project, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var projects []model.Project
+	for _, p := range s.projects {
+		if p.TenantID == tenantID {
+			projects = append(projects, *p)
+		}
+	}
+	sortProjects(projects)
+	return projects, nil
+}
+
+// ListAllProjects returns all projects across all tenants. Admin use only.
+func (s *MemStore) ListAllProjects(ctx context.Context) ([]model.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var projects []model.Project
+	for _, p := range s.projects {
+		projects = append(projects, *p)
+	}
+	sortProjects(projects)
+	return projects, nil
+}
+
+// GetProjectBySlug returns a single project by its slug within the tenant.
+func (s *MemStore) GetProjectBySlug(ctx context.Context, slug string) (*model.Project, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.projectBySlug(tenantID, slug)
+	if p == nil {
+		return nil, fmt.Errorf("project not found for slug %q", slug)
+	}
+	out := *p
+	return &out, nil
+}
+
+// UpdateProject updates a project's name and/or slug by project ID within the tenant.
+func (s *MemStore) UpdateProject(ctx context.Context, projectID string, name, slug *string) (*model.Project, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == nil && slug == nil {
+		return nil, fmt.Errorf("nothing to update: provide name or slug")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pid, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("updating project: %w", err)
+	}
+	p, ok := s.projects[pid]
+	if !ok || p.TenantID != tenantID {
+		return nil, fmt.Errorf("updating project: project %s not found", projectID)
+	}
+	if slug != nil {
+		if other := s.projectBySlug(tenantID, *slug); other != nil && other.ID != pid {
+			return nil, fmt.Errorf("updating project: slug %q already exists", *slug)
+		}
+		p.Slug = *slug
+	}
+	if name != nil {
+		p.Name = *name
+	}
+
+	out := *p
+	return &out, nil
+}
+
+// DeleteProject deletes a project by ID within the tenant.
+// Rejects if any issues still reference the project.
+func (s *MemStore) DeleteProject(ctx context.Context, projectID string) error {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, i := range s.issues {
+		if i.ProjectID == projectID {
+			count++
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("cannot delete project: %d issues still reference it", count)
+	}
+
+	pid, err := uuid.Parse(projectID)
+	if err != nil {
+		return fmt.Errorf("deleting project: %w", err)
+	}
+	p, ok := s.projects[pid]
+	if !ok || p.TenantID != tenantID {
+		return fmt.Errorf("project not found")
+	}
+	delete(s.projects, pid)
+	delete(s.readyGates, projectID)
+	delete(s.compactionPolicies, projectID)
+	return nil
+}
+
+// GetReadyPolicy returns the ready policy of a project within the tenant.
+func (s *MemStore) GetReadyPolicy(ctx context.Context, projectID string) (*model.ReadyPolicy, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ownsProject(tenantID, projectID) {
+		return nil, fmt.Errorf("project not found")
+	}
+	gates, ok := s.readyGates[projectID]
+	if !ok {
+		return defaultReadyPolicy(projectID), nil
+	}
+	return &model.ReadyPolicy{ProjectID: projectID, Gates: append([]model.DependencyType{}, gates...)}, nil
+}
+
+// SetReadyPolicy sets which dependency types gate readiness for a
+// project's issues. Nil gates removes the policy, restoring the default.
+func (s *MemStore) SetReadyPolicy(ctx context.Context, projectID string, gates []model.DependencyType) (*model.ReadyPolicy, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ownsProject(tenantID, projectID) {
+		return nil, fmt.Errorf("project not found")
+	}
+	if gates == nil {
+		delete(s.readyGates, projectID)
+		return defaultReadyPolicy(projectID), nil
+	}
+	gates, err = normalizeReadyGates(gates)
+	if err != nil {
+		return nil, err
+	}
+	s.readyGates[projectID] = gates
+	return &model.ReadyPolicy{ProjectID: projectID, Gates: append([]model.DependencyType{}, gates...)}, nil
+}
+
+func (s *MemStore) ownsProject(tenantID uuid.UUID, projectID string) bool {
+	pid, err := uuid.Parse(projectID)
+	if err != nil {
+		return false
+	}
+	p, ok := s.projects[pid]
+	return ok && p.TenantID == tenantID
+}
+
+func (s *MemStore) projectBySlug(tenantID uuid.UUID, slug string) *model.Project {
+	for _, p := range s.projects {
+		if p.TenantID == tenantID && p.Slug == slug {
+			return p
+		}
+	}
+	return nil
+}
+
+func sortProjects(projects []model.Project) {
+	sort.Slice(projects, func(a, b int) bool {
+		if projects[a].Name != projects[b].Name {
+			return projects[a].Name < projects[b].Name
+		}
+		return projects[a].Slug < projects[b].Slug
+	})
+}
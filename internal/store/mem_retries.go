@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// GenerateRetryID creates a hash-based retry ID with adaptive length.
+func (s *MemStore) GenerateRetryID(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generateRetryID()
+}
+
+func (s *MemStore) generateRetryID() (string, error) {
+	id, ok := generateHashID("rty", len(s.retries), func(id string) bool {
+		_, exists := s.retries[id]
+		return exists
+	})
+	if !ok {
+		return "", fmt.Errorf("failed to generate unique retry ID after 30 attempts")
+	}
+	return id, nil
+}
+
+// RecordRetry inserts a new retry attempt for an issue.
+func (s *MemStore) RecordRetry(ctx context.Context, input RecordRetryInput) (*model.Retry, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.issues[input.IssueID]; !ok {
+		return nil, fmt.Errorf("recording retry: issue %s does not exist", input.IssueID)
+	}
+
+	r, err := s.recordRetry(tenantID.String(), input, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	out := *r
+	return &out, nil
+}
+
+// recordRetry stores a retry with the next attempt number. Callers hold s.mu.
+func (s *MemStore) recordRetry(tenantID string, input RecordRetryInput, now time.Time) (*model.Retry, error) {
+	id, err := s.generateRetryID()
+	if err != nil {
+		return nil, err
+	}
+
+	// Auto-compute attempt number for this issue
+	attempt := 1
+	for _, r := range s.retries {
+		if r.IssueID == input.IssueID && r.TenantID == tenantID && r.Attempt >= attempt {
+			attempt = r.Attempt + 1
+		}
+	}
+
+	status := input.Status
+	if status == "" {
+		status = string(model.RetryFailed)
+	}
+
+	r := &model.Retry{
+		ID:        id,
+		TenantID:  tenantID,
+		ProjectID: input.ProjectID,
+		IssueID:   input.IssueID,
+		Attempt:   attempt,
+		Status:    model.RetryStatus(status),
+		Error:     input.Error,
+		Agent:     input.Agent,
+		StartedAt: now,
+		CreatedBy: input.CreatedBy,
+	}
+	s.retries[id] = r
+	return r, nil
+}
+
+// ListRetries returns retry attempts for an issue.
+func (s *MemStore) ListRetries(ctx context.Context, issueID string, filter model.RetryFilter) ([]model.Retry, error) {
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	retries := []model.Retry{}
+	for _, r := range s.retries {
+		if r.TenantID != tenantID.String() || r.IssueID != issueID {
+			continue
+		}
+		if filter.Status != nil && r.Status != *filter.Status {
+			continue
+		}
+		retries = append(retries, *r)
+	}
+
+	sort.Slice(retries, func(a, b int) bool { return retries[a].Attempt < retries[b].Attempt })
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	return paginate(retries, limit, 0), nil
+}
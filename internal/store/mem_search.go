@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// SearchIssues matches issues and their comments against a web-search style query.
+func (s *MemStore) SearchIssues(ctx context.Context, query model.SearchQuery) ([]model.SearchResult, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(query.Query) == "" {
+		return nil, fmt.Errorf("search query is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tq := parseTextQuery(query.Query)
+	results := []model.SearchResult{}
+	if tq.empty() {
+		return results, nil
+	}
+
+	comments := make(map[string][]string)
+	for _, c := range s.comments {
+		comments[c.IssueID] = append(comments[c.IssueID], c.Text)
+	}
+
+	for _, i := range s.issues {
+		if i.TenantID != tid.String() || i.DeletedAt != nil || !projectAllowed(ctx, i.ProjectID) {
+			continue
+		}
+		if query.Status != nil && i.Status != *query.Status {
+			continue
+		}
+		if query.IssueType != nil && i.IssueType != *query.IssueType {
+			continue
+		}
+		if query.ProjectID != nil && i.ProjectID != *query.ProjectID {
+			continue
+		}
+		if r, ok := tq.matchIssue(i, comments[i.ID]); ok {
+			results = append(results, r)
+		}
+	}
+
+	sortSearchResults(results)
+	return paginate(results, searchLimit(query.Limit), 0), nil
+}
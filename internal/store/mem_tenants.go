@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+)
+
+// ResolveAPIKey looks up an active API key by its SHA-256 hash and returns the tenant ID.
+func (s *MemStore) ResolveAPIKey(ctx context.Context, keyHash string) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.apiKeys {
+		if k.keyHash != keyHash || k.info.RevokedAt != nil {
+			continue
+		}
+		if _, ok := s.tenants[k.info.TenantID]; ok {
+			return k.info.TenantID, nil
+		}
+	}
+	return uuid.Nil, fmt.Errorf("resolving API key: key not found")
+}
+
+// CreateTenant creates a new tenant.
+func (s *MemStore) CreateTenant(ctx context.Context, name, slug string) (*model.Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tenantBySlug(slug) != nil {
+		return nil, fmt.Errorf("creating tenant: slug %q already exists", slug)
+	}
+
+	t := &model.Tenant{
+		ID:        uuid.New(),
+		Name:      name,
+		Slug:      slug,
+		CreatedAt: time.Now().UTC(),
+	}
+	s.tenants[t.ID] = t
+
+	out := *t
+	return &out, nil
+}
+
+// UpdateTenant updates a tenant's name and/or slug by tenant ID.
+func (s *MemStore) UpdateTenant(ctx context.Context, tenantID string, name, slug *string) (*model.Tenant, error) {
+	if name == nil && slug == nil {
+		return nil, fmt.Errorf("nothing to update: provide name or slug")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tid, err := uuid.Parse(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("updating tenant: %w", err)
+	}
+	t, ok := s.tenants[tid]
+	if !ok {
+		return nil, fmt.Errorf("updating tenant: tenant %s not found", tenantID)
+	}
+	if slug != nil {
+		if other := s.tenantBySlug(*slug); other != nil && other.ID != tid {
+			return nil, fmt.Errorf("updating tenant: slug %q already exists", *slug)
+		}
+		t.Slug = *slug
+	}
+	if name != nil {
+		t.Name = *name
+	}
+
+	out := *t
+	return &out, nil
+}
+
+// ListTenants returns all tenants.
+func (s *MemStore) ListTenants(ctx context.Context) ([]model.Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tenants []model.Tenant
+	for _, t := range s.tenants {
+		tenants = append(tenants, *t)
+	}
+	sort.Slice(tenants, func(a, b int) bool {
+		if !tenants[a].CreatedAt.Equal(tenants[b].CreatedAt) {
+			return tenants[a].CreatedAt.Before(tenants[b].CreatedAt)
+		}
+		return tenants[a].Slug < tenants[b].Slug
+	})
+	return tenants, nil
+}
+
+// DeleteTenant deletes a tenant by ID. Rejects if any projects still exist.
+// Cascades to API keys.
+func (s *MemStore) DeleteTenant(ctx context.Context, tenantID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tid, err := uuid.Parse(tenantID)
+	if err != nil {
+		return fmt.Errorf("deleting tenant: %w", err)
+	}
+
+	count := 0
+	for _, p := range s.projects {
+		if p.TenantID == tid {
+			count++
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("cannot delete tenant: %d projects still exist (delete them first)", count)
+	}
+
+	if _, ok := s.tenants[tid]; !ok {
+		return fmt.Errorf("tenant not found")
+	}
+
+	keys := s.apiKeys[:0]
+	for _, k := range s.apiKeys {
+		if k.info.TenantID != tid {
+			keys = append(keys, k)
+		}
+	}
+	s.apiKeys = keys
+	delete(s.compactionSettings, tid)
+	delete(s.jobRuns, tid)
+	delete(s.jobIntervals, tid)
+	delete(s.gcPolicies, tid)
+	delete(s.tenants, tid)
+	return nil
+}
+
+// CreateAPIKey creates a new API key for a tenant. Returns the key info (not the raw key).
+func (s *MemStore) CreateAPIKey(ctx context.Context, tenantSlug, label, keyHash, prefix string) (*model.APIKeyInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tenantBySlug(tenantSlug)
+	if t == nil {
+		return nil, fmt.Errorf("tenant %q not found", tenantSlug)
+	}
+	for _, k := range s.apiKeys {
+		if k.keyHash == keyHash {
+			return nil, fmt.Errorf("creating API key: key hash already exists")
+		}
+	}
+
+	k := &memAPIKey{
+		info: model.APIKeyInfo{
+			ID:        uuid.New(),
+			TenantID:  t.ID,
+			Prefix:    prefix,
+			Label:     label,
+			CreatedAt: time.Now().UTC(),
+		},
+		keyHash: keyHash,
+	}
+	s.apiKeys = append(s.apiKeys, k)
+
+	out := k.info
+	return &out, nil
+}
+
+// RevokeAPIKey revokes an API key by prefix.
+func (s *MemStore) RevokeAPIKey(ctx context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	revoked := 0
+	for _, k := range s.apiKeys {
+		if k.info.Prefix == prefix && k.info.RevokedAt == nil {
+			k.info.RevokedAt = &now
+			revoked++
+		}
+	}
+	if revoked == 0 {
+		return fmt.Errorf("API key with prefix %q not found or already revoked", prefix)
+	}
+	return nil
+}
+
+// ListAPIKeys lists API keys for a tenant.
+func (s *MemStore) ListAPIKeys(ctx context.Context, tenantSlug string) ([]model.APIKeyInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tenantBySlug(tenantSlug)
+	if t == nil {
+		return nil, nil
+	}
+
+	var keys []model.APIKeyInfo
+	for _, k := range s.apiKeys {
+		if k.info.TenantID == t.ID {
+			keys = append(keys, k.info)
+		}
+	}
+	return keys, nil
+}
+
+// GetConfig retrieves a value from the config map by key.
+func (s *MemStore) GetConfig(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.config[key]
+	if !ok {
+		return "", fmt.Errorf("config key %q: not found", key)
+	}
+	return value, nil
+}
+
+// SetConfig upserts a value in the config map.
+func (s *MemStore) SetConfig(ctx context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.config[key] = value
+	return nil
+}
+
+func (s *MemStore) tenantBySlug(slug string) *model.Tenant {
+	for _, t := range s.tenants {
+		if t.Slug == slug {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// DeleteIssue moves an issue to the trash. Its dependencies, labels,
+// comments and events are kept until the trash is purged.
+func (s *MemStore) DeleteIssue(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, err := s.ownedIssue(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	actor := eventActor(ctx, "")
+	issue.DeletedAt = &now
+	issue.DeletedBy = actor
+	s.addEvents([]AddEventInput{trashEvent(id, now, true, actor)}, now)
+	return nil
+}
+
+// RestoreIssue takes an issue out of the trash as it was when deleted.
+func (s *MemStore) RestoreIssue(ctx context.Context, id string) (*model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, ok := s.issues[id]
+	if !ok || issue.TenantID != tid.String() {
+		return nil, fmt.Errorf("issue %s not found", id)
+	}
+	if issue.DeletedAt == nil {
+		return nil, fmt.Errorf("issue %s is not in the trash", id)
+	}
+	restored := *issue
+	restored.DeletedAt = nil
+	if err := s.checkAgentName(&restored); err != nil {
+		return nil, fmt.Errorf("restoring issue %s: %w", id, err)
+	}
+	deletedAt := *issue.DeletedAt
+	issue.DeletedAt = nil
+	issue.DeletedBy = ""
+	s.addEvents([]AddEventInput{trashEvent(id, deletedAt, false, eventActor(ctx, ""))}, time.Now().UTC())
+
+	out := *issue
+	out.Labels = s.sortedLabels(id)
+	for k, d := range s.deps {
+		if k.issueID == id && d.Type == model.DepParentChild && s.liveEdge(k) {
+			out.ParentID = d.DependsOnID
+			break
+		}
+	}
+	return &out, nil
+}
+
+// ListTrash returns the tenant's deleted issues, most recently deleted
+// first.
+func (s *MemStore) ListTrash(ctx context.Context) ([]model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issues := []model.Issue{}
+	for _, i := range s.issues {
+		if i.TenantID == tid.String() && i.DeletedAt != nil && projectAllowed(ctx, i.ProjectID) {
+			issues = append(issues, *i)
+		}
+	}
+	sort.Slice(issues, func(a, b int) bool {
+		if !issues[a].DeletedAt.Equal(*issues[b].DeletedAt) {
+			return issues[a].DeletedAt.After(*issues[b].DeletedAt)
+		}
+		return issues[a].ID < issues[b].ID
+	})
+	return issues, nil
+}
+
+// PurgeTrash permanently deletes issues trashed before deletedBefore,
+// cascading to their dependencies, labels, comments and events, and
+// returns their IDs.
+func (s *MemStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := []string{}
+	for id, i := range s.issues {
+		if i.TenantID == tid.String() && i.DeletedAt != nil && i.DeletedAt.Before(deletedBefore) {
+			purged = append(purged, id)
+		}
+	}
+	sort.Strings(purged)
+	for _, id := range purged {
+		s.purgeIssue(id)
+	}
+	return purged, nil
+}
+
+// PurgeIssues permanently deletes issues ids, trashed or not, cascading
+// like PurgeTrash, and returns the IDs of those found.
+func (s *MemStore) PurgeIssues(ctx context.Context, ids []string) ([]string, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := []string{}
+	for _, id := range ids {
+		if i, ok := s.issues[id]; ok && i.TenantID == tid.String() {
+			s.purgeIssue(id)
+			purged = append(purged, id)
+		}
+	}
+	return purged, nil
+}
+
+// purgeIssue removes an issue and everything recorded against it. Lessons
+// and flags outlive it, detached. Callers must hold s.mu.
+func (s *MemStore) purgeIssue(id string) {
+	delete(s.issues, id)
+	delete(s.labels, id)
+	for k := range s.deps {
+		if k.issueID == id || k.dependsOnID == id {
+			delete(s.deps, k)
+		}
+	}
+	s.comments = filterByIssue(s.comments, id, func(c model.Comment) string { return c.IssueID })
+	s.events = filterByIssue(s.events, id, func(e model.Event) string { return e.IssueID })
+	s.snapshots = filterByIssue(s.snapshots, id, func(c model.CompactionSnapshot) string { return c.IssueID })
+	for rid, r := range s.retries {
+		if r.IssueID == id {
+			delete(s.retries, rid)
+		}
+	}
+	for _, l := range s.lessons {
+		if l.IssueID == id {
+			l.IssueID = ""
+		}
+	}
+	for _, f := range s.flags {
+		if f.IssueID == id {
+			f.IssueID = ""
+		}
+	}
+}
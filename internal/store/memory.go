@@ -0,0 +1,1236 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+)
+
+// MemoryURL is the DATABASE_URL that selects the in-memory store.
+const MemoryURL = "mem://"
+
+// MemStore implements Store in process memory. It follows the same tenant
+// scoping and ready rules as PgStore and is meant for tests and throwaway
+// runs; nothing survives a restart.
+type MemStore struct {
+	mu       sync.Mutex
+	idPrefix string
+
+	issues        map[string]*model.Issue
+	deps          map[depKey]*model.Dependency
+	labels        map[string]map[string]bool
+	comments      []model.Comment
+	events        []model.Event
+	snapshots     []model.CompactionSnapshot
+	childCounters map[string]int
+
+	lessons  map[string]*model.Lesson
+	flags    map[string]*model.Flag
+	retries  map[string]*model.Retry
+	projects map[uuid.UUID]*model.Project
+	tenants  map[uuid.UUID]*model.Tenant
+	apiKeys  []*memAPIKey
+	config   map[string]string
+
+	readyGates         map[string][]model.DependencyType  // project ID → policy gates
+	compactionPolicies map[string]*model.CompactionPolicy // project ID → policy
+	compactionSettings map[uuid.UUID]*model.CompactionSettings
+	jobRuns            map[uuid.UUID][]model.JobRun // oldest first
+	jobIntervals       map[uuid.UUID]map[string]time.Duration
+	gcPolicies         map[uuid.UUID]*model.GCPolicy
+
+	nextCommentID  int64
+	nextEventID    int64
+	nextSnapshotID int64
+	nextJobRunID   int64
+}
+
+type depKey struct {
+	issueID     string
+	dependsOnID string
+}
+
+type memAPIKey struct {
+	info    model.APIKeyInfo
+	keyHash string
+}
+
+// NewMemStore returns an empty in-memory store.
+func NewMemStore(idPrefix string) *MemStore {
+	if idPrefix == "" {
+		idPrefix = "doit"
+	}
+	return &MemStore{
+		idPrefix:           idPrefix,
+		issues:             make(map[string]*model.Issue),
+		deps:               make(map[depKey]*model.Dependency),
+		labels:             make(map[string]map[string]bool),
+		childCounters:      make(map[string]int),
+		lessons:            make(map[string]*model.Lesson),
+		flags:              make(map[string]*model.Flag),
+		retries:            make(map[string]*model.Retry),
+		projects:           make(map[uuid.UUID]*model.Project),
+		tenants:            make(map[uuid.UUID]*model.Tenant),
+		config:             make(map[string]string),
+		readyGates:         make(map[string][]model.DependencyType),
+		compactionPolicies: make(map[string]*model.CompactionPolicy),
+		compactionSettings: make(map[uuid.UUID]*model.CompactionSettings),
+		jobRuns:            make(map[uuid.UUID][]model.JobRun),
+		jobIntervals:       make(map[uuid.UUID]map[string]time.Duration),
+		gcPolicies:         make(map[uuid.UUID]*model.GCPolicy),
+	}
+}
+
+func (s *MemStore) Close() {}
+
+// generateHashID mirrors the adaptive-length hash IDs used by PgStore:
+// the length grows with the number of existing rows and on repeated collisions.
+func generateHashID(prefix string, count int, exists func(id string) bool) (string, bool) {
+	hashLen := 3
+	switch {
+	case count > 1500:
+		hashLen = 6
+	case count > 500:
+		hashLen = 5
+	case count > 100:
+		hashLen = 4
+	}
+
+	for attempt := 0; attempt < 30; attempt++ {
+		seed := fmt.Sprintf("%d-%d-%d", time.Now().UnixNano(), rand.Int63(), attempt)
+		hash := sha256.Sum256([]byte(seed))
+		hexHash := hex.EncodeToString(hash[:])
+		id := fmt.Sprintf("%s-%s", prefix, hexHash[:hashLen])
+		if !exists(id) {
+			return id, true
+		}
+		if attempt%10 == 9 && hashLen < 8 {
+			hashLen++
+		}
+	}
+	return "", false
+}
+
+// GenerateID creates a hash-based issue ID with adaptive length.
+func (s *MemStore) GenerateID(ctx context.Context, prefix string) (string, error) {
+	if prefix == "" {
+		prefix = s.idPrefix
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generateID(prefix)
+}
+
+func (s *MemStore) generateID(prefix string) (string, error) {
+	id, ok := generateHashID(prefix, len(s.issues), func(id string) bool {
+		_, exists := s.issues[id]
+		return exists
+	})
+	if !ok {
+		return "", fmt.Errorf("failed to generate unique ID after 30 attempts")
+	}
+	return id, nil
+}
+
+// NextChildID returns the next hierarchical child ID for a parent.
+func (s *MemStore) NextChildID(ctx context.Context, parentID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextChildID(ctx, parentID)
+}
+
+func (s *MemStore) nextChildID(ctx context.Context, parentID string) (string, error) {
+	if _, err := s.ownedIssue(ctx, parentID); err != nil {
+		return "", err
+	}
+
+	s.childCounters[parentID]++
+	return fmt.Sprintf("%s.%d", parentID, s.childCounters[parentID]), nil
+}
+
+// CreateIssue inserts a new issue and optionally creates a parent-child dependency.
+func (s *MemStore) CreateIssue(ctx context.Context, input CreateIssueInput) (*model.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createIssue(ctx, input)
+}
+
+func (s *MemStore) createIssue(ctx context.Context, input CreateIssueInput) (*model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, exists := s.issues[input.ID]; exists {
+		return nil, fmt.Errorf("inserting issue: duplicate issue id %s", input.ID)
+	}
+	if input.ParentID != "" {
+		if _, exists := s.issues[input.ParentID]; !exists {
+			return nil, fmt.Errorf("creating parent-child dependency: issue %s does not exist", input.ParentID)
+		}
+	}
+
+	now := time.Now().UTC()
+	issue := &model.Issue{
+		ID:                 input.ID,
+		Title:              input.Title,
+		Description:        input.Description,
+		Design:             input.Design,
+		AcceptanceCriteria: input.AcceptanceCriteria,
+		Notes:              input.Notes,
+		Status:             input.Status,
+		Priority:           input.Priority,
+		IssueType:          input.IssueType,
+		Assignee:           input.Assignee,
+		Owner:              input.Owner,
+		CreatedAt:          now,
+		CreatedBy:          input.CreatedBy,
+		UpdatedAt:          now,
+		Ephemeral:          input.Ephemeral,
+		MolType:            input.MolType,
+		WorkType:           input.WorkType,
+		Crystallizes:       input.Crystallizes,
+		WispType:           input.WispType,
+		AgentState:         input.AgentState,
+		LastActivity:       input.LastActivity,
+		RoleType:           input.RoleType,
+		Rig:                input.Rig,
+		RoleBead:           input.RoleBead,
+		AwaitType:          input.AwaitType,
+		AwaitID:            input.AwaitID,
+		Timeout:            input.Timeout,
+		IsTemplate:         input.IsTemplate,
+		TenantID:           tid.String(),
+		ProjectID:          input.ProjectID,
+		EstimatedMinutes:   positiveOrNil(input.EstimatedMinutes),
+	}
+	issue.ContentHash = contentHash(issue)
+	if err := s.checkAgentName(issue); err != nil {
+		return nil, fmt.Errorf("inserting issue: %w", err)
+	}
+	s.issues[issue.ID] = issue
+
+	if input.ParentID != "" {
+		s.deps[depKey{input.ID, input.ParentID}] = &model.Dependency{
+			IssueID:     input.ID,
+			DependsOnID: input.ParentID,
+			Type:        model.DepParentChild,
+			CreatedAt:   now,
+			CreatedBy:   input.CreatedBy,
+		}
+	}
+
+	for _, label := range input.Labels {
+		s.addLabel(input.ID, label)
+	}
+
+	s.addEvent(model.Event{
+		IssueID:   issue.ID,
+		EventType: model.EventCreated,
+		Actor:     eventActor(ctx, input.CreatedBy),
+		NewValue:  issue.Title,
+		CreatedAt: now,
+	})
+
+	out := *issue
+	out.Labels = input.Labels
+	out.ParentID = input.ParentID
+	return &out, nil
+}
+
+// GetIssue retrieves an issue by ID with labels and parent.
+func (s *MemStore) GetIssue(ctx context.Context, id string) (*model.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, err := s.ownedIssue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := *issue
+	out.Labels = s.sortedLabels(id)
+	for k, d := range s.deps {
+		if k.issueID == id && d.Type == model.DepParentChild && s.liveEdge(k) {
+			out.ParentID = d.DependsOnID
+			break
+		}
+	}
+	return &out, nil
+}
+
+// UpdateIssue applies partial updates to an issue.
+func (s *MemStore) UpdateIssue(ctx context.Context, id string, input UpdateIssueInput) (*model.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.updateIssue(ctx, id, input)
+}
+
+func (s *MemStore) updateIssue(ctx context.Context, id string, input UpdateIssueInput) (*model.Issue, error) {
+	issue, err := s.ownedIssue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	current := *issue
+	if err := checkExpectedVersion(&current, input); err != nil {
+		return nil, err
+	}
+	if reopensCompacted(&current, input) {
+		if snap := snapshotFor(s.issueSnapshots(id), 0); snap != nil {
+			uncompactInput(snap, &input)
+		}
+	}
+
+	updated := *issue
+	now := time.Now().UTC()
+	updated.UpdatedAt = now
+	updated.ContentHash = updatedContentHash(issue, input)
+
+	if input.Title != nil {
+		updated.Title = *input.Title
+	}
+	if input.Description != nil {
+		updated.Description = *input.Description
+	}
+	if input.Design != nil {
+		updated.Design = *input.Design
+	}
+	if input.AcceptanceCriteria != nil {
+		updated.AcceptanceCriteria = *input.AcceptanceCriteria
+	}
+	if input.Notes != nil {
+		updated.Notes = *input.Notes
+	}
+	if input.Status != nil {
+		updated.Status = *input.Status
+		if *input.Status == model.StatusClosed {
+			updated.ClosedAt = &now
+		}
+	}
+	if input.Priority != nil {
+		updated.Priority = *input.Priority
+	}
+	if input.Assignee != nil {
+		updated.Assignee = emptyIfNull(*input.Assignee)
+	}
+	if input.Owner != nil {
+		updated.Owner = emptyIfNull(*input.Owner)
+	}
+	if input.Pinned != nil {
+		updated.Pinned = *input.Pinned
+	}
+	if input.Ephemeral != nil {
+		updated.Ephemeral = *input.Ephemeral
+	}
+	if input.AgentState != nil {
+		updated.AgentState = *input.AgentState
+	}
+	if input.LastActivity != nil {
+		t := input.LastActivity.UTC()
+		updated.LastActivity = &t
+	}
+	if input.RoleType != nil {
+		updated.RoleType = *input.RoleType
+	}
+	if input.Rig != nil {
+		updated.Rig = *input.Rig
+	}
+	if input.HookBead != nil {
+		updated.HookBead = *input.HookBead
+	}
+	if input.RoleBead != nil {
+		updated.RoleBead = *input.RoleBead
+	}
+	if input.AwaitID != nil {
+		updated.AwaitID = *input.AwaitID
+	}
+	if input.IsTemplate != nil {
+		updated.IsTemplate = *input.IsTemplate
+	}
+	if input.ExternalRef != nil {
+		updated.ExternalRef = nullEmpty(*input.ExternalRef)
+	}
+	if input.EstimatedMinutes != nil {
+		updated.EstimatedMinutes = positiveOrNil(input.EstimatedMinutes)
+	}
+	if input.CloseReason != nil {
+		updated.CloseReason = *input.CloseReason
+	}
+	if input.CompactionLevel != nil {
+		updated.CompactionLevel = *input.CompactionLevel
+		if updated.CompactionLevel == 0 {
+			updated.CompactedAt = nil
+		}
+	}
+	if input.Lease != nil {
+		updated.LeaseExpiresAt = leaseExpiry(now, *input.Lease)
+		updated.LastActivity = &now
+	} else if input.Status != nil && *input.Status != model.StatusInProgress {
+		updated.LeaseExpiresAt = nil
+	}
+
+	if err := s.checkAgentName(&updated); err != nil {
+		return nil, fmt.Errorf("updating issue %s: %w", id, err)
+	}
+	s.addEvents(issueEvents(issue, &updated, eventActor(ctx, "")), now)
+	*issue = updated
+	out := updated
+	return &out, nil
+}
+
+// ListIssues returns issues matching the filter.
+func (s *MemStore) ListIssues(ctx context.Context, filter model.IssueFilter) ([]model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issues := []model.Issue{}
+	for _, i := range s.issues {
+		if i.TenantID != tid.String() || i.DeletedAt != nil || !projectAllowed(ctx, i.ProjectID) {
+			continue
+		}
+		if filter.Status != nil && i.Status != *filter.Status {
+			continue
+		}
+		if len(filter.StatusNot) > 0 && containsStatus(filter.StatusNot, i.Status) {
+			continue
+		}
+		if filter.Priority != nil && i.Priority != *filter.Priority {
+			continue
+		}
+		if filter.IssueType != nil && i.IssueType != *filter.IssueType {
+			continue
+		}
+		if filter.Assignee != nil && i.Assignee != *filter.Assignee {
+			continue
+		}
+		if filter.Owner != nil && i.Owner != *filter.Owner {
+			continue
+		}
+		if filter.Ephemeral != nil && i.Ephemeral != *filter.Ephemeral {
+			continue
+		}
+		if filter.Pinned != nil && i.Pinned != *filter.Pinned {
+			continue
+		}
+		if filter.Search != nil {
+			q := strings.ToLower(*filter.Search)
+			if !strings.Contains(strings.ToLower(i.Title), q) && !strings.Contains(strings.ToLower(i.Description), q) {
+				continue
+			}
+		}
+		if filter.ParentID != nil {
+			d, ok := s.deps[depKey{i.ID, *filter.ParentID}]
+			if !ok || d.Type != model.DepParentChild {
+				continue
+			}
+		}
+		if filter.ProjectID != nil && i.ProjectID != *filter.ProjectID {
+			continue
+		}
+		issues = append(issues, *i)
+	}
+
+	sortIssues(issues, filter.SortBy)
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, issueSortKey(filter.SortBy))
+		if err != nil {
+			return nil, err
+		}
+		issues = afterCursor(issues, issueKeyset(filter.SortBy), cur, func(i *model.Issue) pageCursor {
+			return issuePosition(i, filter.SortBy)
+		})
+		return paginate(issues, filter.Limit, 0), nil
+	}
+	return paginate(issues, filter.Limit, filter.Offset), nil
+}
+
+// ListReady returns issues that satisfy the ready_issues view rules.
+func (s *MemStore) ListReady(ctx context.Context, filter model.IssueFilter) ([]model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	issues := []model.Issue{}
+	for _, i := range s.issues {
+		if i.TenantID != tid.String() || !projectAllowed(ctx, i.ProjectID) {
+			continue
+		}
+		if !s.isReady(i, now) {
+			continue
+		}
+		if filter.IssueType != nil && i.IssueType != *filter.IssueType {
+			continue
+		}
+		if filter.Priority != nil && i.Priority != *filter.Priority {
+			continue
+		}
+		if filter.Assignee != nil && i.Assignee != *filter.Assignee {
+			continue
+		}
+		if filter.ProjectID != nil && i.ProjectID != *filter.ProjectID {
+			continue
+		}
+		issues = append(issues, *i)
+	}
+
+	sortIssues(issues, ReadySortBy)
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, ReadySortBy)
+		if err != nil {
+			return nil, err
+		}
+		issues = afterCursor(issues, issueKeyset(ReadySortBy), cur, func(i *model.Issue) pageCursor {
+			return issuePosition(i, ReadySortBy)
+		})
+	}
+	return paginate(issues, filter.Limit, 0), nil
+}
+
+// ClaimNextReady assigns the next ready issue to the claimant under the
+// store lock, so concurrent claims never return the same issue.
+func (s *MemStore) ClaimNextReady(ctx context.Context, input ClaimNextReadyInput) (*model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if input.Claimant == "" {
+		return nil, fmt.Errorf("claimant is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	candidates := []model.Issue{}
+	for _, i := range s.issues {
+		if i.TenantID != tid.String() || !projectAllowed(ctx, i.ProjectID) || !s.isReady(i, now) {
+			continue
+		}
+		if input.IssueType != nil && i.IssueType != *input.IssueType {
+			continue
+		}
+		if input.ProjectID != nil && i.ProjectID != *input.ProjectID {
+			continue
+		}
+		if !s.hasLabels(i.ID, input.Labels) {
+			continue
+		}
+		candidates = append(candidates, *i)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	sortIssues(candidates, ReadySortBy)
+
+	issue := s.issues[candidates[0].ID]
+	before := *issue
+	issue.Status = model.StatusInProgress
+	issue.Assignee = input.Claimant
+	issue.UpdatedAt = now
+	issue.LastActivity = &now
+	issue.LeaseExpiresAt = leaseExpiry(now, input.Lease)
+	s.addEvents(issueEvents(&before, issue, eventActor(ctx, input.Claimant)), now)
+
+	out := *issue
+	out.Labels = s.sortedLabels(issue.ID)
+	return &out, nil
+}
+
+// hasLabels reports whether the issue carries every one of labels.
+func (s *MemStore) hasLabels(issueID string, labels []string) bool {
+	for _, l := range labels {
+		if !s.labels[issueID][l] {
+			return false
+		}
+	}
+	return true
+}
+
+// isReady reports whether an issue would appear in the ready_issues view.
+func (s *MemStore) isReady(i *model.Issue, now time.Time) bool {
+	if i.Status != model.StatusOpen || i.Ephemeral || i.IsTemplate || i.DeletedAt != nil {
+		return false
+	}
+	if i.DeferUntil != nil && i.DeferUntil.After(now) {
+		return false
+	}
+	policy := s.readyPolicy(i.ProjectID)
+	if s.gated(i, policy) {
+		return false
+	}
+	if policy.Applies(model.DepParentChild) && s.underHeldAncestor(i.ID, now) {
+		return false
+	}
+	for _, f := range s.flags {
+		if f.IssueID == i.ID && f.Status == model.FlagStatusOpen && f.Severity <= 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// readyPolicy returns the policy for a project ID, nil meaning every gate.
+func (s *MemStore) readyPolicy(projectID string) *model.ReadyPolicy {
+	gates, ok := s.readyGates[projectID]
+	if projectID == "" || !ok {
+		return nil
+	}
+	return &model.ReadyPolicy{ProjectID: projectID, Gates: gates}
+}
+
+// gated reports whether one of i's own edges holds it back under policy.
+// See model.ReadyGates for what each type means.
+func (s *MemStore) gated(i *model.Issue, policy *model.ReadyPolicy) bool {
+	for k, d := range s.deps {
+		if k.issueID != i.ID || !policy.Applies(d.Type) {
+			continue
+		}
+		target, ok := s.live(k.dependsOnID)
+		if !ok {
+			continue
+		}
+		closed := target.Status == model.StatusClosed
+		switch d.Type {
+		case model.DepBlocks:
+			if !closed {
+				return true
+			}
+		case model.DepConditionalBlocks:
+			if !closed || !model.IsFailureCloseReason(target.CloseReason) {
+				return true
+			}
+		case model.DepWaitsFor:
+			if !closed || s.hasOpenChild(target.ID) {
+				return true
+			}
+		case model.DepUntil:
+			if closed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *MemStore) hasOpenChild(parentID string) bool {
+	for k, d := range s.deps {
+		if k.dependsOnID != parentID || d.Type != model.DepParentChild {
+			continue
+		}
+		if child, ok := s.live(k.issueID); ok && child.Status != model.StatusClosed {
+			return true
+		}
+	}
+	return false
+}
+
+// underHeldAncestor reports whether any ancestor of id is blocked,
+// deferred, or gated under its own project's policy.
+func (s *MemStore) underHeldAncestor(id string, now time.Time) bool {
+	seen := map[string]bool{id: true}
+	frontier := []string{id}
+	for len(frontier) > 0 {
+		var parents []string
+		for k, d := range s.deps {
+			if d.Type != model.DepParentChild || seen[k.dependsOnID] || !containsString(frontier, k.issueID) || !s.liveEdge(k) {
+				continue
+			}
+			seen[k.dependsOnID] = true
+			parents = append(parents, k.dependsOnID)
+		}
+		for _, pid := range parents {
+			p, ok := s.issues[pid]
+			if !ok {
+				continue
+			}
+			switch {
+			case p.Status == model.StatusBlocked, p.Status == model.StatusDeferred:
+				return true
+			case p.Status == model.StatusClosed:
+			case p.DeferUntil != nil && p.DeferUntil.After(now):
+				return true
+			case s.gated(p, s.readyPolicy(p.ProjectID)):
+				return true
+			}
+		}
+		frontier = parents
+	}
+	return false
+}
+
+// --- Dependencies ---
+
+func (s *MemStore) AddDependency(ctx context.Context, input AddDependencyInput) (*model.Dependency, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addDependency(ctx, input)
+}
+
+func (s *MemStore) addDependency(ctx context.Context, input AddDependencyInput) (*model.Dependency, error) {
+	if _, err := s.ownedIssue(ctx, input.IssueID); err != nil {
+		return nil, err
+	}
+	if _, err := s.ownedIssue(ctx, input.DependsOnID); err != nil {
+		return nil, err
+	}
+	if err := checkCycle(input, s.edges); err != nil {
+		return nil, err
+	}
+
+	dep := &model.Dependency{
+		IssueID:     input.IssueID,
+		DependsOnID: input.DependsOnID,
+		Type:        input.Type,
+		CreatedAt:   time.Now().UTC(),
+		CreatedBy:   input.CreatedBy,
+		ThreadID:    input.ThreadID,
+	}
+
+	// Same as ON CONFLICT (issue_id, depends_on_id) DO UPDATE SET type.
+	var oldType model.DependencyType
+	key := depKey{input.IssueID, input.DependsOnID}
+	if existing, ok := s.deps[key]; ok {
+		oldType = existing.Type
+		existing.Type = input.Type
+	} else {
+		stored := *dep
+		s.deps[key] = &stored
+	}
+	if oldType != dep.Type {
+		s.addEvents([]AddEventInput{dependencyEvent(*dep, oldType, true, eventActor(ctx, dep.CreatedBy))}, dep.CreatedAt)
+	}
+
+	return dep, nil
+}
+
+// edges is the MemStore edgeLookup. Callers must hold s.mu.
+func (s *MemStore) edges(ids, types []string) (map[string][]string, error) {
+	from := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		from[id] = true
+	}
+	edges := make(map[string][]string)
+	for k, d := range s.deps {
+		if from[k.issueID] && containsString(types, string(d.Type)) && s.liveEdge(k) {
+			edges[k.issueID] = append(edges[k.issueID], k.dependsOnID)
+		}
+	}
+	return edges, nil
+}
+
+// ValidateGraph reports cycles, orphaned children and dangling references
+// in the tenant's dependency graph.
+func (s *MemStore) ValidateGraph(ctx context.Context) (*model.GraphReport, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	owned := make(map[string]bool)
+	for id, i := range s.issues {
+		if i.TenantID == tid.String() && i.DeletedAt == nil {
+			ids = append(ids, id)
+			owned[id] = true
+		}
+	}
+	var deps []model.Dependency
+	for k, d := range s.deps {
+		if (owned[k.issueID] || owned[k.dependsOnID]) && s.liveEdge(k) {
+			deps = append(deps, *d)
+		}
+	}
+	return analyzeGraph(tid.String(), ids, deps), nil
+}
+
+func (s *MemStore) RemoveDependency(ctx context.Context, issueID, dependsOnID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.ownedIssue(ctx, issueID); err != nil {
+		return err
+	}
+	key := depKey{issueID, dependsOnID}
+	if d, ok := s.deps[key]; ok {
+		delete(s.deps, key)
+		s.addEvents([]AddEventInput{dependencyEvent(*d, "", false, eventActor(ctx, ""))}, time.Now().UTC())
+	}
+	return nil
+}
+
+func (s *MemStore) ListDependencies(ctx context.Context, issueID string, direction string) ([]model.Dependency, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.ownedIssue(ctx, issueID); err != nil {
+		return nil, err
+	}
+
+	var deps []model.Dependency
+	for k, d := range s.deps {
+		var match bool
+		switch direction {
+		case "upstream":
+			match = k.issueID == issueID
+		case "downstream":
+			match = k.dependsOnID == issueID
+		default: // both
+			match = k.issueID == issueID || k.dependsOnID == issueID
+		}
+		if match && s.liveEdge(k) {
+			deps = append(deps, *d)
+		}
+	}
+	sort.Slice(deps, func(a, b int) bool {
+		if !deps[a].CreatedAt.Equal(deps[b].CreatedAt) {
+			return deps[a].CreatedAt.Before(deps[b].CreatedAt)
+		}
+		if deps[a].IssueID != deps[b].IssueID {
+			return deps[a].IssueID < deps[b].IssueID
+		}
+		return deps[a].DependsOnID < deps[b].DependsOnID
+	})
+	return deps, nil
+}
+
+func (s *MemStore) GetDependencyTree(ctx context.Context, rootID string, maxDepth int) ([]model.TreeNode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	root, err := s.ownedIssue(ctx, rootID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []model.TreeNode{{Issue: *root, Depth: 0}}
+	frontier := []string{rootID}
+	for depth := 1; depth <= maxDepth && len(frontier) > 0; depth++ {
+		var level []model.Issue
+		for _, parentID := range frontier {
+			for k, d := range s.deps {
+				if k.dependsOnID != parentID || d.Type != model.DepParentChild {
+					continue
+				}
+				if child, ok := s.live(k.issueID); ok && child.TenantID == root.TenantID {
+					level = append(level, *child)
+				}
+			}
+		}
+		sortIssues(level, "priority")
+		frontier = frontier[:0]
+		for _, child := range level {
+			nodes = append(nodes, model.TreeNode{Issue: child, Depth: depth})
+			frontier = append(frontier, child.ID)
+		}
+	}
+	return nodes, nil
+}
+
+// --- Labels ---
+
+func (s *MemStore) AddLabel(ctx context.Context, issueID, label string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.ownedIssue(ctx, issueID); err != nil {
+		return err
+	}
+	s.addLabelWithEvent(ctx, issueID, label)
+	return nil
+}
+
+func (s *MemStore) RemoveLabel(ctx context.Context, issueID, label string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.ownedIssue(ctx, issueID); err != nil {
+		return err
+	}
+	if s.labels[issueID][label] {
+		delete(s.labels[issueID], label)
+		s.addEvents([]AddEventInput{labelEvent(issueID, label, false, eventActor(ctx, ""))}, time.Now().UTC())
+	}
+	return nil
+}
+
+func (s *MemStore) ListLabels(ctx context.Context, issueID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.ownedIssue(ctx, issueID); err != nil {
+		return nil, err
+	}
+	return s.sortedLabels(issueID), nil
+}
+
+// --- Comments ---
+
+func (s *MemStore) AddComment(ctx context.Context, issueID, author, text string) (*model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addComment(ctx, issueID, author, text)
+}
+
+func (s *MemStore) addComment(ctx context.Context, issueID, author, text string) (*model.Comment, error) {
+	if _, err := s.ownedIssue(ctx, issueID); err != nil {
+		return nil, err
+	}
+
+	s.nextCommentID++
+	c := model.Comment{
+		ID:        s.nextCommentID,
+		IssueID:   issueID,
+		Author:    author,
+		Text:      text,
+		CreatedAt: time.Now().UTC(),
+	}
+	s.comments = append(s.comments, c)
+	s.addEvents([]AddEventInput{commentEvent(&c, eventActor(ctx, author))}, c.CreatedAt)
+	return &c, nil
+}
+
+func (s *MemStore) ListComments(ctx context.Context, issueID string) ([]model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.ownedIssue(ctx, issueID); err != nil {
+		return nil, err
+	}
+
+	var comments []model.Comment
+	for _, c := range s.comments {
+		if c.IssueID == issueID {
+			comments = append(comments, c)
+		}
+	}
+	return comments, nil
+}
+
+// --- Events ---
+
+func (s *MemStore) AddEvent(ctx context.Context, input AddEventInput) (*model.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.ownedIssue(ctx, input.IssueID); err != nil {
+		return nil, err
+	}
+
+	e := s.addEvent(model.Event{
+		IssueID:   input.IssueID,
+		EventType: input.EventType,
+		Actor:     eventActor(ctx, input.Actor),
+		Field:     emptyIfNull(input.Field),
+		OldValue:  emptyIfNull(input.OldValue),
+		NewValue:  emptyIfNull(input.NewValue),
+		Comment:   emptyIfNull(input.Comment),
+		CreatedAt: time.Now().UTC(),
+	})
+	return &e, nil
+}
+
+func (s *MemStore) ListEvents(ctx context.Context, issueID string, limit int) ([]model.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.ownedIssue(ctx, issueID); err != nil {
+		return nil, err
+	}
+
+	var events []model.Event
+	for i := len(s.events) - 1; i >= 0; i-- {
+		if s.events[i].IssueID != issueID {
+			continue
+		}
+		events = append(events, s.events[i])
+		if limit > 0 && len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+// --- Compaction ---
+
+func (s *MemStore) SaveCompactionSnapshot(ctx context.Context, issueID string, level int, summary, original string, content UpdateIssueInput) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, err := s.ownedIssue(ctx, issueID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.updateIssue(ctx, issueID, content); err != nil {
+		return err
+	}
+
+	oldLevel := issue.CompactionLevel
+	now := time.Now().UTC()
+	s.nextSnapshotID++
+	s.snapshots = append(s.snapshots, model.CompactionSnapshot{
+		ID:        s.nextSnapshotID,
+		IssueID:   issueID,
+		Level:     level,
+		FromLevel: oldLevel,
+		Summary:   summary,
+		Original:  original,
+		CreatedAt: now,
+	})
+	issue.CompactionLevel = level
+	issue.CompactedAt = &now
+	s.addEvents([]AddEventInput{compactedEvent(issueID, oldLevel, level, eventActor(ctx, ""))}, now)
+	return nil
+}
+
+func (s *MemStore) GetCompactionSnapshots(ctx context.Context, issueID string) ([]model.CompactionSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.ownedIssue(ctx, issueID); err != nil {
+		return nil, err
+	}
+
+	return s.issueSnapshots(issueID), nil
+}
+
+// issueSnapshots returns the compaction snapshots of issueID by level.
+// Callers must hold s.mu.
+func (s *MemStore) issueSnapshots(issueID string) []model.CompactionSnapshot {
+	var snaps []model.CompactionSnapshot
+	for _, snap := range s.snapshots {
+		if snap.IssueID == issueID {
+			snaps = append(snaps, snap)
+		}
+	}
+	sort.SliceStable(snaps, func(a, b int) bool { return snaps[a].Level < snaps[b].Level })
+	return snaps
+}
+
+// --- Aggregation ---
+
+func (s *MemStore) CountIssuesByStatus(ctx context.Context) (map[string]int, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, i := range s.issues {
+		if i.TenantID == tid.String() && i.DeletedAt == nil && projectAllowed(ctx, i.ProjectID) {
+			counts[string(i.Status)]++
+		}
+	}
+	return counts, nil
+}
+
+// --- Helpers ---
+
+// ownedIssue returns the stored issue if it belongs to the tenant in context.
+// Returns "not found" (not "access denied") to avoid leaking existence.
+// Callers must hold s.mu.
+func (s *MemStore) ownedIssue(ctx context.Context, issueID string) (*model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	issue, ok := s.live(issueID)
+	if !ok || issue.TenantID != tid.String() {
+		return nil, fmt.Errorf("issue %s not found", issueID)
+	}
+	return issue, nil
+}
+
+// live returns the stored issue unless it is missing or in the trash.
+// Callers must hold s.mu.
+func (s *MemStore) live(id string) (*model.Issue, bool) {
+	i, ok := s.issues[id]
+	if !ok || i.DeletedAt != nil {
+		return nil, false
+	}
+	return i, true
+}
+
+// liveEdge reports whether neither end of an edge is in the trash. Edges
+// touching a trashed issue are kept for its restore but otherwise ignored.
+// Callers must hold s.mu.
+func (s *MemStore) liveEdge(k depKey) bool {
+	for _, id := range []string{k.issueID, k.dependsOnID} {
+		if i, ok := s.issues[id]; ok && i.DeletedAt != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *MemStore) addLabel(issueID, label string) {
+	if s.labels[issueID] == nil {
+		s.labels[issueID] = make(map[string]bool)
+	}
+	s.labels[issueID][label] = true
+}
+
+func (s *MemStore) sortedLabels(issueID string) []string {
+	var labels []string
+	for l := range s.labels[issueID] {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func (s *MemStore) addEvent(e model.Event) model.Event {
+	s.nextEventID++
+	e.ID = s.nextEventID
+	s.events = append(s.events, e)
+	return e
+}
+
+func (s *MemStore) addEvents(events []AddEventInput, now time.Time) {
+	for _, e := range events {
+		s.addEvent(model.Event{
+			IssueID:   e.IssueID,
+			EventType: e.EventType,
+			Actor:     e.Actor,
+			Field:     e.Field,
+			OldValue:  e.OldValue,
+			NewValue:  e.NewValue,
+			Comment:   e.Comment,
+			CreatedAt: now,
+		})
+	}
+}
+
+// addLabelWithEvent adds a label, recording a label_added event if the
+// issue did not already carry it.
+func (s *MemStore) addLabelWithEvent(ctx context.Context, issueID, label string) {
+	if s.labels[issueID][label] {
+		return
+	}
+	s.addLabel(issueID, label)
+	s.addEvents([]AddEventInput{labelEvent(issueID, label, true, eventActor(ctx, ""))}, time.Now().UTC())
+}
+
+// projectAllowed applies the same allowed-projects restriction as addProjectFilter.
+func projectAllowed(ctx context.Context, projectID string) bool {
+	allowed := auth.AllowedProjectsFromContext(ctx)
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, p := range allowed {
+		if p == projectID {
+			return true
+		}
+	}
+	return false
+}
+
+func containsStatus(list []model.Status, status model.Status) bool {
+	for _, s := range list {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// sortIssues orders issues like the ORDER BY clauses in PgStore, with the ID
+// as a final tie-breaker so results are deterministic.
+func sortIssues(issues []model.Issue, sortBy string) {
+	sort.Slice(issues, func(a, b int) bool {
+		x, y := issues[a], issues[b]
+		switch sortBy {
+		case "priority":
+			if x.Priority != y.Priority {
+				return x.Priority < y.Priority
+			}
+			if !x.CreatedAt.Equal(y.CreatedAt) {
+				return x.CreatedAt.Before(y.CreatedAt)
+			}
+		case "oldest":
+			if !x.CreatedAt.Equal(y.CreatedAt) {
+				return x.CreatedAt.Before(y.CreatedAt)
+			}
+		case "updated":
+			if !x.UpdatedAt.Equal(y.UpdatedAt) {
+				return x.UpdatedAt.After(y.UpdatedAt)
+			}
+		default: // "hybrid"
+			if x.Priority != y.Priority {
+				return x.Priority < y.Priority
+			}
+			if !x.UpdatedAt.Equal(y.UpdatedAt) {
+				return x.UpdatedAt.After(y.UpdatedAt)
+			}
+		}
+		return x.ID < y.ID
+	})
+}
+
+// afterCursor drops the leading items (already sorted by keys) that do not
+// sort strictly after cur.
+func afterCursor[T any](items []T, keys keyset, cur pageCursor, pos func(*T) pageCursor) []T {
+	for n := range items {
+		if keys.compare(pos(&items[n]), cur) > 0 {
+			return items[n:]
+		}
+	}
+	return items[:0]
+}
+
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(items) {
+			return items[:0]
+		}
+		items = items[offset:]
+	}
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+func filterByIssue[T any](items []T, issueID string, key func(T) string) []T {
+	out := items[:0]
+	for _, item := range items {
+		if key(item) != issueID {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// emptyIfNull mirrors nullEmpty for fields held as plain strings:
+// "" and the literal "null" both mean unset.
+func emptyIfNull(s string) string {
+	if s == "null" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+)
+
+func memTenantCtx(t *testing.T, s *MemStore, slug string) context.Context {
+	t.Helper()
+	tenant, err := s.CreateTenant(context.Background(), slug, slug)
+	if err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+	return auth.WithTenant(context.Background(), tenant.ID)
+}
+
+func memCreate(t *testing.T, ctx context.Context, s *MemStore, input CreateIssueInput) *model.Issue {
+	t.Helper()
+	if input.ID == "" {
+		id, err := s.GenerateID(ctx, "")
+		if err != nil {
+			t.Fatalf("GenerateID: %v", err)
+		}
+		input.ID = id
+	}
+	if input.Status == "" {
+		input.Status = model.StatusOpen
+	}
+	issue, err := s.CreateIssue(ctx, input)
+	if err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	return issue
+}
+
+func readyIDs(t *testing.T, ctx context.Context, s *MemStore) map[string]bool {
+	t.Helper()
+	ready, err := s.ListReady(ctx, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListReady: %v", err)
+	}
+	ids := make(map[string]bool)
+	for _, i := range ready {
+		ids[i.ID] = true
+	}
+	return ids
+}
+
+func TestMemStore_RequiresTenant(t *testing.T) {
+	s := NewMemStore("")
+	_, err := s.CreateIssue(context.Background(), CreateIssueInput{ID: "doit-x", Title: "x"})
+	if err == nil || !strings.Contains(err.Error(), "no tenant") {
+		t.Fatalf("expected no tenant error, got %v", err)
+	}
+}
+
+func TestMemStore_GenerateID(t *testing.T) {
+	s := NewMemStore("tst")
+	ctx := memTenantCtx(t, s, "acme")
+
+	id, err := s.GenerateID(ctx, "")
+	if err != nil {
+		t.Fatalf("GenerateID: %v", err)
+	}
+	if !strings.HasPrefix(id, "tst-") || len(id) != len("tst-")+3 {
+		t.Errorf("id = %q, want tst- prefix with 3-char hash", id)
+	}
+
+	lid, _ := s.GenerateLessonID(ctx)
+	fid, _ := s.GenerateFlagID(ctx)
+	rid, _ := s.GenerateRetryID(ctx)
+	if !strings.HasPrefix(lid, "lsn-") || !strings.HasPrefix(fid, "flg-") || !strings.HasPrefix(rid, "rty-") {
+		t.Errorf("unexpected prefixes: %s %s %s", lid, fid, rid)
+	}
+}
+
+func TestMemStore_NextChildID(t *testing.T) {
+	s := NewMemStore("")
+	ctx := memTenantCtx(t, s, "acme")
+	parent := memCreate(t, ctx, s, CreateIssueInput{Title: "Epic", IssueType: model.TypeEpic})
+
+	for want := 1; want <= 2; want++ {
+		id, err := s.NextChildID(ctx, parent.ID)
+		if err != nil {
+			t.Fatalf("NextChildID: %v", err)
+		}
+		memCreate(t, ctx, s, CreateIssueInput{ID: id, Title: "child", ParentID: parent.ID})
+		if id != fmt.Sprintf("%s.%d", parent.ID, want) {
+			t.Errorf("child id = %q, want %s.%d", id, parent.ID, want)
+		}
+	}
+
+	child, err := s.GetIssue(ctx, parent.ID+".1")
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if child.ParentID != parent.ID {
+		t.Errorf("ParentID = %q, want %q", child.ParentID, parent.ID)
+	}
+}
+
+func TestMemStore_ReadyRules(t *testing.T) {
+	s := NewMemStore("")
+	ctx := memTenantCtx(t, s, "acme")
+
+	plain := memCreate(t, ctx, s, CreateIssueInput{Title: "plain"})
+	blocker := memCreate(t, ctx, s, CreateIssueInput{Title: "blocker"})
+	blocked := memCreate(t, ctx, s, CreateIssueInput{Title: "blocked"})
+	ephemeral := memCreate(t, ctx, s, CreateIssueInput{Title: "wisp", Ephemeral: true})
+	flagged := memCreate(t, ctx, s, CreateIssueInput{Title: "flagged"})
+	related := memCreate(t, ctx, s, CreateIssueInput{Title: "related"})
+
+	if _, err := s.AddDependency(ctx, AddDependencyInput{IssueID: blocked.ID, DependsOnID: blocker.ID, Type: model.DepBlocks}); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	if _, err := s.AddDependency(ctx, AddDependencyInput{IssueID: related.ID, DependsOnID: blocker.ID, Type: model.DepRelated}); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	if _, err := s.RaiseFlag(ctx, RaiseFlagInput{IssueID: flagged.ID, Type: "red_flag", Severity: 1, Summary: "stop"}); err != nil {
+		t.Fatalf("RaiseFlag: %v", err)
+	}
+
+	ready := readyIDs(t, ctx, s)
+	for _, id := range []string{plain.ID, blocker.ID, related.ID} {
+		if !ready[id] {
+			t.Errorf("%s should be ready", id)
+		}
+	}
+	for _, id := range []string{blocked.ID, ephemeral.ID, flagged.ID} {
+		if ready[id] {
+			t.Errorf("%s should not be ready", id)
+		}
+	}
+
+	closed := model.StatusClosed
+	if _, err := s.UpdateIssue(ctx, blocker.ID, UpdateIssueInput{Status: &closed}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if !readyIDs(t, ctx, s)[blocked.ID] {
+		t.Error("blocked issue should be ready once its blocker is closed")
+	}
+}
+
+func TestMemStore_DeferUntil(t *testing.T) {
+	s := NewMemStore("")
+	ctx := memTenantCtx(t, s, "acme")
+	deferred := memCreate(t, ctx, s, CreateIssueInput{Title: "later"})
+
+	future := time.Now().Add(time.Hour)
+	s.issues[deferred.ID].DeferUntil = &future
+	if readyIDs(t, ctx, s)[deferred.ID] {
+		t.Error("deferred issue should not be ready")
+	}
+
+	past := time.Now().Add(-time.Hour)
+	s.issues[deferred.ID].DeferUntil = &past
+	if !readyIDs(t, ctx, s)[deferred.ID] {
+		t.Error("issue whose defer_until has passed should be ready")
+	}
+}
+
+func TestMemStore_AddDependencyUpsert(t *testing.T) {
+	s := NewMemStore("")
+	ctx := memTenantCtx(t, s, "acme")
+	a := memCreate(t, ctx, s, CreateIssueInput{Title: "a"})
+	b := memCreate(t, ctx, s, CreateIssueInput{Title: "b"})
+
+	for _, typ := range []model.DependencyType{model.DepRelated, model.DepBlocks} {
+		if _, err := s.AddDependency(ctx, AddDependencyInput{IssueID: a.ID, DependsOnID: b.ID, Type: typ}); err != nil {
+			t.Fatalf("AddDependency: %v", err)
+		}
+	}
+
+	deps, err := s.ListDependencies(ctx, a.ID, "upstream")
+	if err != nil {
+		t.Fatalf("ListDependencies: %v", err)
+	}
+	if len(deps) != 1 || deps[0].Type != model.DepBlocks {
+		t.Errorf("deps = %+v, want one blocks edge", deps)
+	}
+}
+
+func TestMemStore_TenantIsolation(t *testing.T) {
+	s := NewMemStore("")
+	ctxA := memTenantCtx(t, s, "a")
+	ctxB := memTenantCtx(t, s, "b")
+	issue := memCreate(t, ctxA, s, CreateIssueInput{Title: "secret"})
+	other := memCreate(t, ctxB, s, CreateIssueInput{Title: "mine"})
+
+	if _, err := s.GetIssue(ctxB, issue.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("cross-tenant GetIssue err = %v, want not found", err)
+	}
+	if _, err := s.AddDependency(ctxB, AddDependencyInput{IssueID: other.ID, DependsOnID: issue.ID, Type: model.DepBlocks}); err == nil {
+		t.Error("cross-tenant AddDependency should fail")
+	}
+	if err := s.DeleteIssue(ctxB, issue.ID); err == nil {
+		t.Error("cross-tenant DeleteIssue should fail")
+	}
+
+	list, err := s.ListIssues(ctxB, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != other.ID {
+		t.Errorf("tenant b sees %d issues, want only its own", len(list))
+	}
+}
+
+func TestMemStore_AllowedProjects(t *testing.T) {
+	s := NewMemStore("")
+	ctx := memTenantCtx(t, s, "acme")
+	p1, _ := s.CreateProject(ctx, "One", "one")
+	p2, _ := s.CreateProject(ctx, "Two", "two")
+	in1 := memCreate(t, ctx, s, CreateIssueInput{Title: "in one", ProjectID: p1.ID.String()})
+	memCreate(t, ctx, s, CreateIssueInput{Title: "in two", ProjectID: p2.ID.String()})
+	memCreate(t, ctx, s, CreateIssueInput{Title: "no project"})
+
+	scoped := auth.WithAllowedProjects(ctx, []string{p1.ID.String()})
+
+	list, _ := s.ListIssues(scoped, model.IssueFilter{})
+	if len(list) != 1 || list[0].ID != in1.ID {
+		t.Errorf("ListIssues returned %d issues, want only %s", len(list), in1.ID)
+	}
+	ready, _ := s.ListReady(scoped, model.IssueFilter{})
+	if len(ready) != 1 || ready[0].ID != in1.ID {
+		t.Errorf("ListReady returned %d issues, want only %s", len(ready), in1.ID)
+	}
+	counts, _ := s.CountIssuesByStatus(scoped)
+	if counts["open"] != 1 {
+		t.Errorf("open count = %d, want 1", counts["open"])
+	}
+}
+
+func TestMemStore_APIKeys(t *testing.T) {
+	s := NewMemStore("")
+	ctx := context.Background()
+	tenant, _ := s.CreateTenant(ctx, "Acme", "acme")
+
+	if _, err := s.CreateAPIKey(ctx, "acme", "ci", "hash-1", "doit_abc"); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	got, err := s.ResolveAPIKey(ctx, "hash-1")
+	if err != nil || got != tenant.ID {
+		t.Fatalf("ResolveAPIKey = %v, %v; want %v", got, err, tenant.ID)
+	}
+	if err := s.RevokeAPIKey(ctx, "doit_abc"); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if got, err := s.ResolveAPIKey(ctx, "hash-1"); err == nil || got != uuid.Nil {
+		t.Error("revoked key should not resolve")
+	}
+}
@@ -13,8 +13,24 @@ import (
 //go:embed migrations/*.sql
 var migrations embed.FS
 
+//go:embed sqlite_migrations/*.sql
+var sqliteMigrations embed.FS
+
 // RunMigrations applies all pending database migrations.
+// The in-memory store has no schema, so mem:// URLs are a no-op.
 func RunMigrations(databaseURL string) error {
+	if IsMemoryURL(databaseURL) {
+		return nil
+	}
+	if IsSqliteURL(databaseURL) {
+		db, err := openSqlite(SqlitePath(databaseURL))
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return migrateSqlite(db)
+	}
+
 	db, err := sql.Open("pgx", databaseURL)
 	if err != nil {
 		return fmt.Errorf("opening db for migrations: %w", err)
@@ -50,3 +66,18 @@ func RunMigrations(databaseURL string) error {
 
 	return nil
 }
+
+// migrateSqlite applies the embedded SQLite migrations.
+func migrateSqlite(db *sql.DB) error {
+	goose.SetBaseFS(sqliteMigrations)
+	goose.SetLogger(goose.NopLogger())
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		return fmt.Errorf("setting goose dialect: %w", err)
+	}
+
+	if err := goose.Up(db, "sqlite_migrations"); err != nil {
+		return fmt.Errorf("running migrations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+-- +goose Up
+
+-- Weighted search document: title (A) > description (B) > design, acceptance
+-- criteria and notes (C). Comments are indexed separately and ranked as D.
+ALTER TABLE issues ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(design, '') || ' ' || coalesce(acceptance_criteria, '') || ' ' || coalesce(notes, '')), 'C')
+) STORED;
+
+CREATE INDEX idx_issues_search ON issues USING GIN (search_vector);
+
+ALTER TABLE comments ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(text, '')), 'D')
+) STORED;
+
+CREATE INDEX idx_comments_search ON comments USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_comments_search;
+ALTER TABLE comments DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS idx_issues_search;
+ALTER TABLE issues DROP COLUMN IF EXISTS search_vector;
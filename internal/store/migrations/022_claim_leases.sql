@@ -0,0 +1,53 @@
+-- +goose Up
+
+-- A claim lease: an in_progress issue whose lease has expired is returned to
+-- open by the server's reaper. NULL means the claim never expires.
+ALTER TABLE issues ADD COLUMN lease_expires_at TIMESTAMPTZ;
+
+CREATE INDEX idx_issues_lease_expires_at ON issues (lease_expires_at)
+    WHERE lease_expires_at IS NOT NULL;
+
+-- Views expand i.* when created, so recreate ready_issues to pick up the
+-- new column.
+DROP VIEW IF EXISTS ready_issues;
+CREATE VIEW ready_issues AS
+SELECT i.*
+FROM issues i
+WHERE i.status = 'open'
+  AND i.ephemeral = FALSE
+  AND (i.defer_until IS NULL OR i.defer_until <= NOW())
+  AND NOT EXISTS (
+      SELECT 1 FROM dependencies d
+      JOIN issues blocker ON blocker.id = d.depends_on_id
+      WHERE d.issue_id = i.id AND d.type = 'blocks'
+        AND blocker.status != 'closed'
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM flags f
+      WHERE f.issue_id = i.id
+        AND f.status = 'open'
+        AND f.severity <= 2
+  );
+
+-- +goose Down
+DROP VIEW IF EXISTS ready_issues;
+DROP INDEX IF EXISTS idx_issues_lease_expires_at;
+ALTER TABLE issues DROP COLUMN IF EXISTS lease_expires_at;
+CREATE VIEW ready_issues AS
+SELECT i.*
+FROM issues i
+WHERE i.status = 'open'
+  AND i.ephemeral = FALSE
+  AND (i.defer_until IS NULL OR i.defer_until <= NOW())
+  AND NOT EXISTS (
+      SELECT 1 FROM dependencies d
+      JOIN issues blocker ON blocker.id = d.depends_on_id
+      WHERE d.issue_id = i.id AND d.type = 'blocks'
+        AND blocker.status != 'closed'
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM flags f
+      WHERE f.issue_id = i.id
+        AND f.status = 'open'
+        AND f.severity <= 2
+  );
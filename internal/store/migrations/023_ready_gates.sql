@@ -0,0 +1,104 @@
+-- +goose Up
+
+-- Which dependency types gate readiness for a project's issues. A project
+-- without a row uses all of them; see model.ReadyGates for the semantics.
+CREATE TABLE project_ready_policy (
+    project_id  UUID PRIMARY KEY REFERENCES project(id) ON DELETE CASCADE,
+    gates       TEXT[] NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- ready_issues now honors every gating dependency type, not just blocks.
+--
+-- gated: issues held back by one of their own edges, under their project's
+--        policy.
+-- held:  issues that hold back their descendants: blocked, deferred or
+--        gated.
+-- under_held: descendants of held issues, via parent-child edges.
+DROP VIEW IF EXISTS ready_issues;
+CREATE VIEW ready_issues AS
+WITH RECURSIVE gated AS (
+    SELECT d.issue_id AS id
+    FROM dependencies d
+    JOIN issues x ON x.id = d.issue_id
+    JOIN issues y ON y.id = d.depends_on_id
+    LEFT JOIN project_ready_policy rp ON rp.project_id = x.project_id
+    WHERE x.status != 'closed'
+      AND (rp.gates IS NULL OR d.type = ANY(rp.gates))
+      AND (
+          (d.type = 'blocks' AND y.status != 'closed')
+          OR (d.type = 'conditional-blocks' AND (
+              y.status != 'closed'
+              OR y.close_reason IS NULL
+              OR y.close_reason !~* '^\s*(fail|reject|abort|abandon|cancel|wontfix|won''t fix|timed out|timeout)'
+          ))
+          OR (d.type = 'waits-for' AND (
+              y.status != 'closed'
+              OR EXISTS (
+                  SELECT 1 FROM dependencies c
+                  JOIN issues child ON child.id = c.issue_id
+                  WHERE c.depends_on_id = y.id AND c.type = 'parent-child'
+                    AND child.status != 'closed'
+              )
+          ))
+          OR (d.type = 'until' AND y.status = 'closed')
+      )
+), held AS (
+    SELECT id FROM gated
+    UNION
+    SELECT id FROM issues
+    WHERE status IN ('blocked', 'deferred')
+       OR (status != 'closed' AND defer_until > NOW())
+), under_held(id) AS (
+    SELECT d.issue_id
+    FROM dependencies d
+    JOIN held h ON h.id = d.depends_on_id
+    WHERE d.type = 'parent-child'
+    UNION
+    SELECT d.issue_id
+    FROM dependencies d
+    JOIN under_held u ON u.id = d.depends_on_id
+    WHERE d.type = 'parent-child'
+)
+SELECT i.*
+FROM issues i
+WHERE i.status = 'open'
+  AND i.ephemeral = FALSE
+  AND (i.defer_until IS NULL OR i.defer_until <= NOW())
+  AND i.id NOT IN (SELECT id FROM gated)
+  AND (
+      i.id NOT IN (SELECT id FROM under_held)
+      OR EXISTS (
+          SELECT 1 FROM project_ready_policy rp
+          WHERE rp.project_id = i.project_id
+            AND NOT ('parent-child' = ANY(rp.gates))
+      )
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM flags f
+      WHERE f.issue_id = i.id
+        AND f.status = 'open'
+        AND f.severity <= 2
+  );
+
+-- +goose Down
+DROP VIEW IF EXISTS ready_issues;
+DROP TABLE IF EXISTS project_ready_policy;
+CREATE VIEW ready_issues AS
+SELECT i.*
+FROM issues i
+WHERE i.status = 'open'
+  AND i.ephemeral = FALSE
+  AND (i.defer_until IS NULL OR i.defer_until <= NOW())
+  AND NOT EXISTS (
+      SELECT 1 FROM dependencies d
+      JOIN issues blocker ON blocker.id = d.depends_on_id
+      WHERE d.issue_id = i.id AND d.type = 'blocks'
+        AND blocker.status != 'closed'
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM flags f
+      WHERE f.issue_id = i.id
+        AND f.status = 'open'
+        AND f.severity <= 2
+  );
@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- The issue field an updated event changed (e.g. "priority"), so the audit
+-- trail can be replayed field by field.
+ALTER TABLE events ADD COLUMN field VARCHAR(64);
+
+CREATE INDEX idx_events_issue_created ON events (issue_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_events_issue_created;
+ALTER TABLE events DROP COLUMN IF EXISTS field;
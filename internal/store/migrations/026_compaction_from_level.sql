@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- Snapshots record the level they were compacted from, so an issue can be
+-- restored to any earlier level. Earlier snapshots were all taken with the
+-- issue at level 0, since the level was never stored on the issue; it is
+-- now, starting from the snapshots already taken.
+ALTER TABLE compaction_snapshots ADD COLUMN from_level INT NOT NULL DEFAULT 0;
+
+UPDATE issues i
+SET compaction_level = s.level, compacted_at = s.created_at
+FROM (
+    SELECT issue_id, MAX(level) AS level, MAX(created_at) AS created_at
+    FROM compaction_snapshots
+    GROUP BY issue_id
+) s
+WHERE s.issue_id = i.id AND i.compaction_level = 0;
+
+-- +goose Down
+ALTER TABLE compaction_snapshots DROP COLUMN IF EXISTS from_level;
@@ -0,0 +1,14 @@
+-- +goose Up
+
+-- How each tenant's issues are summarized when compacted: the summarizer,
+-- per-level length budgets and, for the endpoint summarizer, the API to
+-- call. A tenant without a row uses the heuristic summarizer and default
+-- budgets; see model.CompactionSettings.
+CREATE TABLE compaction_settings (
+    tenant_id   UUID PRIMARY KEY REFERENCES tenant(id) ON DELETE CASCADE,
+    settings    JSONB NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS compaction_settings;
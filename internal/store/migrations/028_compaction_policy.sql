@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- When a project's closed issues are compacted: the age before they are,
+-- and labels that keep them from ever being. A project without a row uses
+-- the age each compaction run is given; see model.CompactionPolicy.
+CREATE TABLE project_compaction_policy (
+    project_id  UUID PRIMARY KEY REFERENCES project(id) ON DELETE CASCADE,
+    policy      JSONB NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS project_compaction_policy;
@@ -0,0 +1,31 @@
+-- +goose Up
+
+-- History of the background jobs doit-server runs for each tenant. Only
+-- the newest runs of each job are kept; see store.JobHistoryLimit.
+CREATE TABLE job_runs (
+    id           BIGSERIAL PRIMARY KEY,
+    tenant_id    UUID NOT NULL REFERENCES tenant(id) ON DELETE CASCADE,
+    job          TEXT NOT NULL,
+    started_at   TIMESTAMPTZ NOT NULL,
+    finished_at  TIMESTAMPTZ NOT NULL,
+    succeeded    BOOLEAN NOT NULL,
+    result       JSONB,
+    error        TEXT NOT NULL DEFAULT '',
+    runner       TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_job_runs_tenant_job ON job_runs(tenant_id, job, id DESC);
+
+-- How often each job runs for a tenant, where it differs from the server's
+-- default. An interval of 0s turns the job off for the tenant.
+CREATE TABLE job_intervals (
+    tenant_id   UUID NOT NULL REFERENCES tenant(id) ON DELETE CASCADE,
+    job         TEXT NOT NULL,
+    every       TEXT NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, job)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS job_intervals;
+DROP TABLE IF EXISTS job_runs;
@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- How long each tenant keeps ephemeral issues and each type of wisp before
+-- garbage collection deletes them. A tenant without a row uses the
+-- defaults; see model.GCPolicy.
+CREATE TABLE gc_policy (
+    tenant_id   UUID PRIMARY KEY REFERENCES tenant(id) ON DELETE CASCADE,
+    policy      JSONB NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS gc_policy;
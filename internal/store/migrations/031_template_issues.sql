@@ -0,0 +1,146 @@
+-- +goose Up
+
+-- Template issues are blueprints copied by doit_instantiate_template, not
+-- work: they are never ready. They still hold back what they block, like
+-- any other open issue, which only matters inside the template itself.
+DROP VIEW IF EXISTS ready_issues;
+CREATE VIEW ready_issues AS
+WITH RECURSIVE live AS NOT MATERIALIZED (
+    SELECT * FROM issues WHERE deleted_at IS NULL
+), gated AS (
+    SELECT d.issue_id AS id
+    FROM dependencies d
+    JOIN live x ON x.id = d.issue_id
+    JOIN live y ON y.id = d.depends_on_id
+    LEFT JOIN project_ready_policy rp ON rp.project_id = x.project_id
+    WHERE x.status != 'closed'
+      AND (rp.gates IS NULL OR d.type = ANY(rp.gates))
+      AND (
+          (d.type = 'blocks' AND y.status != 'closed')
+          OR (d.type = 'conditional-blocks' AND (
+              y.status != 'closed'
+              OR y.close_reason IS NULL
+              OR y.close_reason !~* '^\s*(fail|reject|abort|abandon|cancel|wontfix|won''t fix|timed out|timeout)'
+          ))
+          OR (d.type = 'waits-for' AND (
+              y.status != 'closed'
+              OR EXISTS (
+                  SELECT 1 FROM dependencies c
+                  JOIN live child ON child.id = c.issue_id
+                  WHERE c.depends_on_id = y.id AND c.type = 'parent-child'
+                    AND child.status != 'closed'
+              )
+          ))
+          OR (d.type = 'until' AND y.status = 'closed')
+      )
+), held AS (
+    SELECT id FROM gated
+    UNION
+    SELECT id FROM live
+    WHERE status IN ('blocked', 'deferred')
+       OR (status != 'closed' AND defer_until > NOW())
+), under_held(id) AS (
+    SELECT d.issue_id
+    FROM dependencies d
+    JOIN held h ON h.id = d.depends_on_id
+    JOIN live c ON c.id = d.issue_id
+    WHERE d.type = 'parent-child'
+    UNION
+    SELECT d.issue_id
+    FROM dependencies d
+    JOIN under_held u ON u.id = d.depends_on_id
+    JOIN live c ON c.id = d.issue_id
+    WHERE d.type = 'parent-child'
+)
+SELECT i.*
+FROM live i
+WHERE i.status = 'open'
+  AND i.ephemeral = FALSE
+  AND i.is_template = FALSE
+  AND (i.defer_until IS NULL OR i.defer_until <= NOW())
+  AND i.id NOT IN (SELECT id FROM gated)
+  AND (
+      i.id NOT IN (SELECT id FROM under_held)
+      OR EXISTS (
+          SELECT 1 FROM project_ready_policy rp
+          WHERE rp.project_id = i.project_id
+            AND NOT ('parent-child' = ANY(rp.gates))
+      )
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM flags f
+      WHERE f.issue_id = i.id
+        AND f.status = 'open'
+        AND f.severity <= 2
+  );
+
+-- +goose Down
+DROP VIEW IF EXISTS ready_issues;
+CREATE VIEW ready_issues AS
+WITH RECURSIVE live AS NOT MATERIALIZED (
+    SELECT * FROM issues WHERE deleted_at IS NULL
+), gated AS (
+    SELECT d.issue_id AS id
+    FROM dependencies d
+    JOIN live x ON x.id = d.issue_id
+    JOIN live y ON y.id = d.depends_on_id
+    LEFT JOIN project_ready_policy rp ON rp.project_id = x.project_id
+    WHERE x.status != 'closed'
+      AND (rp.gates IS NULL OR d.type = ANY(rp.gates))
+      AND (
+          (d.type = 'blocks' AND y.status != 'closed')
+          OR (d.type = 'conditional-blocks' AND (
+              y.status != 'closed'
+              OR y.close_reason IS NULL
+              OR y.close_reason !~* '^\s*(fail|reject|abort|abandon|cancel|wontfix|won''t fix|timed out|timeout)'
+          ))
+          OR (d.type = 'waits-for' AND (
+              y.status != 'closed'
+              OR EXISTS (
+                  SELECT 1 FROM dependencies c
+                  JOIN live child ON child.id = c.issue_id
+                  WHERE c.depends_on_id = y.id AND c.type = 'parent-child'
+                    AND child.status != 'closed'
+              )
+          ))
+          OR (d.type = 'until' AND y.status = 'closed')
+      )
+), held AS (
+    SELECT id FROM gated
+    UNION
+    SELECT id FROM live
+    WHERE status IN ('blocked', 'deferred')
+       OR (status != 'closed' AND defer_until > NOW())
+), under_held(id) AS (
+    SELECT d.issue_id
+    FROM dependencies d
+    JOIN held h ON h.id = d.depends_on_id
+    JOIN live c ON c.id = d.issue_id
+    WHERE d.type = 'parent-child'
+    UNION
+    SELECT d.issue_id
+    FROM dependencies d
+    JOIN under_held u ON u.id = d.depends_on_id
+    JOIN live c ON c.id = d.issue_id
+    WHERE d.type = 'parent-child'
+)
+SELECT i.*
+FROM live i
+WHERE i.status = 'open'
+  AND i.ephemeral = FALSE
+  AND (i.defer_until IS NULL OR i.defer_until <= NOW())
+  AND i.id NOT IN (SELECT id FROM gated)
+  AND (
+      i.id NOT IN (SELECT id FROM under_held)
+      OR EXISTS (
+          SELECT 1 FROM project_ready_policy rp
+          WHERE rp.project_id = i.project_id
+            AND NOT ('parent-child' = ANY(rp.gates))
+      )
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM flags f
+      WHERE f.issue_id = i.id
+        AND f.status = 'open'
+        AND f.severity <= 2
+  );
@@ -0,0 +1,24 @@
+-- +goose Up
+
+-- An agent's bead is the tenant's one live issue of type agent titled with
+-- the agent's name, so registering a name twice at once cannot create two.
+-- Duplicates left by such races keep the most recently updated bead under
+-- the name; the others are renamed after their IDs.
+UPDATE issues i
+SET title = i.title || ' (' || i.id || ')'
+WHERE i.issue_type = 'agent'
+  AND i.deleted_at IS NULL
+  AND EXISTS (
+      SELECT 1 FROM issues o
+      WHERE o.tenant_id = i.tenant_id
+        AND o.title = i.title
+        AND o.issue_type = 'agent'
+        AND o.deleted_at IS NULL
+        AND (o.updated_at, o.id) > (i.updated_at, i.id)
+  );
+
+CREATE UNIQUE INDEX idx_issues_agent_name ON issues (tenant_id, title)
+    WHERE issue_type = 'agent' AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_issues_agent_name;
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Open returns the Store backend selected by the database URL scheme.
+// "mem://" selects the in-memory store and "sqlite://path" the SQLite store;
+// anything else is treated as a PostgreSQL connection string.
+func Open(ctx context.Context, databaseURL string, queryTimeout time.Duration, idPrefix string) (Store, error) {
+	if IsMemoryURL(databaseURL) {
+		return NewMemStore(idPrefix), nil
+	}
+	if IsSqliteURL(databaseURL) {
+		return NewSqliteStore(ctx, SqlitePath(databaseURL), queryTimeout, idPrefix)
+	}
+	return NewPgStore(ctx, databaseURL, queryTimeout, idPrefix)
+}
+
+// IsMemoryURL reports whether the URL selects the in-memory store.
+func IsMemoryURL(databaseURL string) bool {
+	return strings.HasPrefix(databaseURL, MemoryURL)
+}
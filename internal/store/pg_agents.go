@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// FindAgentBead returns the live agent bead titled name, or nil.
+func (s *PgStore) FindAgentBead(ctx context.Context, name string) (*model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	bead, err := s.scanIssue(ctx, s.pool,
+		"SELECT "+issueColumns+" FROM issues WHERE tenant_id = $1 AND issue_type = $2 AND title = $3 AND deleted_at IS NULL",
+		tid, model.TypeAgent, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding agent %s: %w", name, err)
+	}
+	return bead, nil
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ApplyBatch runs ops in order inside one transaction. Either every op is
+// applied or, on the first failure, none are.
+func (s *PgStore) ApplyBatch(ctx context.Context, ops []BatchOp) ([]BatchResult, error) {
+	if err := validateBatch(ops); err != nil {
+		return nil, err
+	}
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	results, err := applyBatch(ops, &pgBatch{s: s, ctx: ctx, tx: tx, tid: tid})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return results, nil
+}
+
+// pgBatch runs batch operations on an open transaction.
+type pgBatch struct {
+	s   *PgStore
+	ctx context.Context
+	tx  pgx.Tx
+	tid uuid.UUID
+}
+
+func (b *pgBatch) checkOwned(issueID string) error {
+	return checkIssueOwned(b.ctx, b.tx, b.tid, issueID)
+}
+
+func (b *pgBatch) generateID() (string, error) {
+	return generateIssueID(b.ctx, b.tx, b.s.idPrefix)
+}
+
+func (b *pgBatch) nextChildID(parentID string) (string, error) {
+	return nextChildID(b.ctx, b.tx, parentID)
+}
+
+func (b *pgBatch) createIssue(input CreateIssueInput) (*model.Issue, error) {
+	return insertIssue(b.ctx, b.tx, b.tid, input)
+}
+
+func (b *pgBatch) updateIssue(id string, input UpdateIssueInput) (*model.Issue, error) {
+	return b.s.updateIssue(b.ctx, b.tx, b.tid, id, input)
+}
+
+func (b *pgBatch) addDependency(input AddDependencyInput) (*model.Dependency, error) {
+	return addCheckedDependency(b.ctx, b.tx, b.tid, input)
+}
+
+func (b *pgBatch) addLabel(issueID, label string) error {
+	return insertLabel(b.ctx, b.tx, issueID, label)
+}
+
+func (b *pgBatch) addComment(issueID, author, text string) (*model.Comment, error) {
+	return insertComment(b.ctx, b.tx, issueID, author, text)
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetCompactionSettings returns how the tenant's issues are summarized when
+// compacted.
+func (s *PgStore) GetCompactionSettings(ctx context.Context) (*model.CompactionSettings, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var raw []byte
+	err = s.pool.QueryRow(ctx,
+		"SELECT settings FROM compaction_settings WHERE tenant_id = $1", tid).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defaultCompactionSettings(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting compaction settings: %w", err)
+	}
+	var settings model.CompactionSettings
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return nil, fmt.Errorf("decoding compaction settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SetCompactionSettings sets how the tenant's issues are summarized when
+// compacted. Nil settings removes them, restoring the defaults.
+func (s *PgStore) SetCompactionSettings(ctx context.Context, settings *model.CompactionSettings) (*model.CompactionSettings, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if settings == nil {
+		if _, err := s.pool.Exec(ctx,
+			"DELETE FROM compaction_settings WHERE tenant_id = $1", tid); err != nil {
+			return nil, fmt.Errorf("resetting compaction settings: %w", err)
+		}
+		return defaultCompactionSettings(), nil
+	}
+
+	settings, err = normalizeCompactionSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("encoding compaction settings: %w", err)
+	}
+	if _, err := s.pool.Exec(ctx,
+		`INSERT INTO compaction_settings (tenant_id, settings) VALUES ($1, $2)
+		 ON CONFLICT (tenant_id) DO UPDATE SET settings = EXCLUDED.settings, updated_at = NOW()`,
+		tid, raw); err != nil {
+		return nil, fmt.Errorf("setting compaction settings: %w", err)
+	}
+	return settings, nil
+}
+
+// GetCompactionPolicy returns the compaction policy of a project within the
+// tenant.
+func (s *PgStore) GetCompactionPolicy(ctx context.Context, projectID string) (*model.CompactionPolicy, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var raw []byte
+	err = s.pool.QueryRow(ctx,
+		`SELECT cp.policy FROM project p
+		 LEFT JOIN project_compaction_policy cp ON cp.project_id = p.id
+		 WHERE p.id = $1 AND p.tenant_id = $2`,
+		projectID, tid).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("project not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting compaction policy: %w", err)
+	}
+	if raw == nil {
+		return defaultCompactionPolicy(projectID), nil
+	}
+	var policy model.CompactionPolicy
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return nil, fmt.Errorf("decoding compaction policy: %w", err)
+	}
+	policy.ProjectID = projectID
+	return &policy, nil
+}
+
+// SetCompactionPolicy sets when a project's closed issues are compacted.
+// Nil policy removes it, restoring the default.
+func (s *PgStore) SetCompactionPolicy(ctx context.Context, projectID string, policy *model.CompactionPolicy) (*model.CompactionPolicy, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var owned bool
+	err = s.pool.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM project WHERE id = $1 AND tenant_id = $2)",
+		projectID, tid).Scan(&owned)
+	if err != nil {
+		return nil, fmt.Errorf("checking project: %w", err)
+	}
+	if !owned {
+		return nil, fmt.Errorf("project not found")
+	}
+
+	if policy == nil {
+		if _, err := s.pool.Exec(ctx,
+			"DELETE FROM project_compaction_policy WHERE project_id = $1", projectID); err != nil {
+			return nil, fmt.Errorf("resetting compaction policy: %w", err)
+		}
+		return defaultCompactionPolicy(projectID), nil
+	}
+
+	policy, err = normalizeCompactionPolicy(projectID, policy)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("encoding compaction policy: %w", err)
+	}
+	if _, err := s.pool.Exec(ctx,
+		`INSERT INTO project_compaction_policy (project_id, policy) VALUES ($1, $2)
+		 ON CONFLICT (project_id) DO UPDATE SET policy = EXCLUDED.policy, updated_at = NOW()`,
+		projectID, raw); err != nil {
+		return nil, fmt.Errorf("setting compaction policy: %w", err)
+	}
+	return policy, nil
+}
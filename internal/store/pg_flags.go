@@ -149,7 +149,16 @@ func (s *PgStore) ListFlags(ctx context.Context, filter model.FlagFilter) ([]mod
 		args = append(args, projectIDs)
 	}
 
-	query += " ORDER BY severity ASC, created_at DESC"
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, lessonFlagSort)
+		if err != nil {
+			return nil, err
+		}
+		var after string
+		after, args, argN = lessonFlagKeyset.after(cur, "$%d", args, argN)
+		query += " AND " + after
+	}
+	query += lessonFlagKeyset.orderBy()
 
 	limit := filter.Limit
 	if limit <= 0 {
@@ -176,6 +185,36 @@ func (s *PgStore) ListFlags(ctx context.Context, filter model.FlagFilter) ([]mod
 	return flags, rows.Err()
 }
 
+// GetFlag returns a flag by ID.
+func (s *PgStore) GetFlag(ctx context.Context, id string) (*model.Flag, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tenantID, ok := auth.TenantFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no tenant in context")
+	}
+
+	f := &model.Flag{}
+	err := s.pool.QueryRow(ctx,
+		`SELECT id, tenant_id, project_id, issue_id, type, severity, summary,
+		 context, status, resolution, resolved_by, resolved_at, created_at, created_by
+		 FROM flags WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID).
+		Scan(&f.ID, &f.TenantID, &ns{&f.ProjectID}, &ns{&f.IssueID},
+			&f.Type, &f.Severity, &f.Summary,
+			&f.Context, &f.Status, &ns{&f.Resolution}, &ns{&f.ResolvedBy},
+			&f.ResolvedAt, &f.CreatedAt, &ns{&f.CreatedBy})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("flag %s not found", id)
+		}
+		return nil, fmt.Errorf("getting flag: %w", err)
+	}
+
+	return f, nil
+}
+
 // ResolveFlag marks a flag as resolved with a resolution message.
 func (s *PgStore) ResolveFlag(ctx context.Context, id string, resolution, resolvedBy string) (*model.Flag, error) {
 	ctx, cancel := s.withTimeout(ctx)
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetGCPolicy returns how long the tenant keeps ephemeral issues.
+func (s *PgStore) GetGCPolicy(ctx context.Context) (*model.GCPolicy, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var raw []byte
+	err = s.pool.QueryRow(ctx, "SELECT policy FROM gc_policy WHERE tenant_id = $1", tid).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defaultGCPolicy(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting gc policy: %w", err)
+	}
+	var policy model.GCPolicy
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return nil, fmt.Errorf("decoding gc policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// SetGCPolicy sets how long the tenant keeps ephemeral issues. Nil removes
+// the policy, restoring the defaults.
+func (s *PgStore) SetGCPolicy(ctx context.Context, policy *model.GCPolicy) (*model.GCPolicy, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if policy == nil {
+		if _, err := s.pool.Exec(ctx, "DELETE FROM gc_policy WHERE tenant_id = $1", tid); err != nil {
+			return nil, fmt.Errorf("resetting gc policy: %w", err)
+		}
+		return defaultGCPolicy(), nil
+	}
+
+	policy, err = normalizeGCPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("encoding gc policy: %w", err)
+	}
+	if _, err := s.pool.Exec(ctx,
+		`INSERT INTO gc_policy (tenant_id, policy) VALUES ($1, $2)
+		 ON CONFLICT (tenant_id) DO UPDATE SET policy = EXCLUDED.policy, updated_at = NOW()`,
+		tid, raw); err != nil {
+		return nil, fmt.Errorf("setting gc policy: %w", err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// RecordJobRun adds a run to the tenant's job history, dropping the oldest
+// runs of the job past JobHistoryLimit.
+func (s *PgStore) RecordJobRun(ctx context.Context, run *model.JobRun) error {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var result []byte
+	if len(run.Result) > 0 {
+		result = run.Result
+	}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO job_runs (tenant_id, job, started_at, finished_at, succeeded, result, error, runner)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		tid, run.Job, run.StartedAt, run.FinishedAt, run.Succeeded, result, run.Error, run.Runner).Scan(&run.ID)
+	if err != nil {
+		return fmt.Errorf("recording job run: %w", err)
+	}
+	if _, err := s.pool.Exec(ctx,
+		`DELETE FROM job_runs WHERE tenant_id = $1 AND job = $2 AND id < (
+		     SELECT MIN(id) FROM (
+		         SELECT id FROM job_runs WHERE tenant_id = $1 AND job = $2 ORDER BY id DESC LIMIT $3
+		     ) newest)`,
+		tid, run.Job, JobHistoryLimit); err != nil {
+		return fmt.Errorf("pruning job history: %w", err)
+	}
+	return nil
+}
+
+// ListJobRuns returns the tenant's runs of job, or of every job when job is
+// empty, newest first. A limit of 0 or less returns all that are kept.
+func (s *PgStore) ListJobRuns(ctx context.Context, job string, limit int) ([]model.JobRun, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, job, started_at, finished_at, succeeded, result, error, runner
+		 FROM job_runs WHERE tenant_id = $1 AND ($2 = '' OR job = $2) ORDER BY id DESC`
+	args := []any{tid, job}
+	if limit > 0 {
+		query += " LIMIT $3"
+		args = append(args, limit)
+	}
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []model.JobRun{}
+	for rows.Next() {
+		var r model.JobRun
+		var result []byte
+		if err := rows.Scan(&r.ID, &r.Job, &r.StartedAt, &r.FinishedAt, &r.Succeeded, &result, &r.Error, &r.Runner); err != nil {
+			return nil, fmt.Errorf("scanning job run: %w", err)
+		}
+		r.Result = result
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// GetJobIntervals returns the tenant's overrides of how often jobs run.
+func (s *PgStore) GetJobIntervals(ctx context.Context) (map[string]time.Duration, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, "SELECT job, every FROM job_intervals WHERE tenant_id = $1", tid)
+	if err != nil {
+		return nil, fmt.Errorf("getting job intervals: %w", err)
+	}
+	defer rows.Close()
+
+	stored := make(map[string]string)
+	for rows.Next() {
+		var job, interval string
+		if err := rows.Scan(&job, &interval); err != nil {
+			return nil, fmt.Errorf("scanning job interval: %w", err)
+		}
+		stored[job] = interval
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return parseJobIntervals(stored)
+}
+
+// SetJobInterval sets how often job runs for the tenant. Nil removes the
+// override, so the server's default applies again.
+func (s *PgStore) SetJobInterval(ctx context.Context, job string, interval *time.Duration) error {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if interval == nil {
+		if _, err := s.pool.Exec(ctx,
+			"DELETE FROM job_intervals WHERE tenant_id = $1 AND job = $2", tid, job); err != nil {
+			return fmt.Errorf("resetting job interval: %w", err)
+		}
+		return nil
+	}
+	if err := checkJobInterval(job, *interval); err != nil {
+		return err
+	}
+	if _, err := s.pool.Exec(ctx,
+		`INSERT INTO job_intervals (tenant_id, job, every) VALUES ($1, $2, $3)
+		 ON CONFLICT (tenant_id, job) DO UPDATE SET every = EXCLUDED.every, updated_at = NOW()`,
+		tid, job, interval.String()); err != nil {
+		return fmt.Errorf("setting job interval: %w", err)
+	}
+	return nil
+}
+
+// TryJobLock takes a session advisory lock on a connection of its own,
+// which it holds until release, so only one replica runs a job at a time.
+func (s *PgStore) TryJobLock(ctx context.Context, key string) (func(), bool, error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("acquiring connection for job lock: %w", err)
+	}
+	var ok bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock(hashtextextended($1, 0))", key).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("taking job lock: %w", err)
+	}
+	if !ok {
+		conn.Release()
+		return nil, false, nil
+	}
+	return func() {
+		// The job's context may be done by now.
+		ctx, cancel := s.withTimeout(context.Background())
+		defer cancel()
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock(hashtextextended($1, 0))", key); err != nil {
+			// Session locks go with the session.
+			conn.Conn().Close(ctx)
+		}
+		conn.Release()
+	}, true, nil
+}
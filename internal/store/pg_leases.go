@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// RenewLease extends the claimant's lease on an in_progress issue and records
+// the heartbeat as the issue's last activity.
+func (s *PgStore) RenewLease(ctx context.Context, issueID, claimant string, lease time.Duration) (*model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := s.scanIssue(ctx, tx,
+		"SELECT "+issueColumns+" FROM issues WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL FOR UPDATE", issueID, tid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("issue %s not found", issueID)
+		}
+		return nil, fmt.Errorf("loading issue %s: %w", issueID, err)
+	}
+	if err := checkLeaseHolder(current, claimant); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	issue, err := s.scanIssue(ctx, tx,
+		`UPDATE issues SET last_activity = $1, lease_expires_at = $2
+		 WHERE id = $3 AND tenant_id = $4 RETURNING `+issueColumns,
+		now, leaseExpiry(now, lease), issueID, tid)
+	if err != nil {
+		return nil, fmt.Errorf("renewing lease on %s: %w", issueID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return issue, nil
+}
+
+// ReleaseExpiredLeases reopens every in_progress issue whose lease expired
+// before now, recording an abandoned retry and a status_changed event for
+// each. Each issue is released in its own transaction, guarded on the lease
+// still being expired, so a heartbeat that lands first wins and concurrent
+// reapers never release the same claim twice.
+func (s *PgStore) ReleaseExpiredLeases(ctx context.Context, now time.Time) ([]model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	expired, err := s.scanIssues(ctx, s.pool,
+		`SELECT `+issueColumns+` FROM issues
+		 WHERE tenant_id = $1 AND status = $2 AND lease_expires_at < $3 AND deleted_at IS NULL
+		 ORDER BY lease_expires_at`,
+		tid, string(model.StatusInProgress), now)
+	if err != nil {
+		return nil, fmt.Errorf("finding expired leases: %w", err)
+	}
+
+	released := []model.Issue{}
+	for _, candidate := range expired {
+		issue, err := s.releaseLease(ctx, tid, &candidate, now)
+		if err != nil {
+			return released, err
+		}
+		if issue != nil {
+			released = append(released, *issue)
+		}
+	}
+	return released, nil
+}
+
+func (s *PgStore) releaseLease(ctx context.Context, tid uuid.UUID, expired *model.Issue, now time.Time) (*model.Issue, error) {
+	retryID, err := s.GenerateRetryID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	issue, err := s.scanIssue(ctx, tx,
+		`UPDATE issues SET status = $1, assignee = NULL, lease_expires_at = NULL, updated_at = $2
+		 WHERE id = $3 AND tenant_id = $4 AND status = $5 AND lease_expires_at < $2 AND deleted_at IS NULL
+		 RETURNING `+issueColumns,
+		string(model.StatusOpen), now, expired.ID, tid, string(model.StatusInProgress))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // renewed or released since we looked
+		}
+		return nil, fmt.Errorf("releasing issue %s: %w", expired.ID, err)
+	}
+
+	msg := leaseExpiredMessage(expired)
+	_, err = tx.Exec(ctx,
+		`INSERT INTO retries (id, tenant_id, project_id, issue_id, attempt, status, error, agent, started_at, ended_at, created_by)
+		 VALUES ($1, $2, $3, $4,
+		   (SELECT COALESCE(MAX(attempt), 0) + 1 FROM retries WHERE issue_id = $4 AND tenant_id = $2),
+		   $5, $6, $7, $8, $8, $9)`,
+		retryID, tid, nullEmpty(expired.ProjectID), expired.ID,
+		string(model.RetryAbandoned), msg, nullEmpty(expired.Assignee), now, ReaperActor)
+	if err != nil {
+		return nil, fmt.Errorf("recording abandoned retry: %w", err)
+	}
+
+	if err := insertEvents(ctx, tx, releaseEvents(expired, issue, msg)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return issue, nil
+}
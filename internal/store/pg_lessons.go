@@ -152,7 +152,16 @@ func (s *PgStore) ListLessons(ctx context.Context, filter model.LessonFilter) ([
 		args = append(args, projectIDs)
 	}
 
-	query += " ORDER BY severity ASC, created_at DESC"
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, lessonFlagSort)
+		if err != nil {
+			return nil, err
+		}
+		var after string
+		after, args, argN = lessonFlagKeyset.after(cur, "$%d", args, argN)
+		query += " AND " + after
+	}
+	query += lessonFlagKeyset.orderBy()
 
 	limit := filter.Limit
 	if limit <= 0 {
@@ -2,11 +2,13 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/Actual-Outcomes/doit/internal/auth"
 	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/jackc/pgx/v5"
 )
 
 // CreateProject creates a new project within the tenant from context.
@@ -198,3 +200,82 @@ func addProjectFilter(ctx context.Context, query string, args []any, argN int, c
 	args = append(args, projectIDs)
 	return query, args, argN
 }
+
+// GetReadyPolicy returns the ready policy of a project within the tenant.
+func (s *PgStore) GetReadyPolicy(ctx context.Context, projectID string) (*model.ReadyPolicy, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tenantID, ok := auth.TenantFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no tenant in context")
+	}
+
+	var gates []string
+	err := s.pool.QueryRow(ctx,
+		`SELECT rp.gates FROM project p
+		 LEFT JOIN project_ready_policy rp ON rp.project_id = p.id
+		 WHERE p.id = $1 AND p.tenant_id = $2`,
+		projectID, tenantID).Scan(&gates)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("project not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting ready policy: %w", err)
+	}
+	if gates == nil {
+		return defaultReadyPolicy(projectID), nil
+	}
+	policy := &model.ReadyPolicy{ProjectID: projectID, Gates: []model.DependencyType{}}
+	for _, g := range gates {
+		policy.Gates = append(policy.Gates, model.DependencyType(g))
+	}
+	return policy, nil
+}
+
+// SetReadyPolicy sets which dependency types gate readiness for a
+// project's issues. Nil gates removes the policy, restoring the default.
+func (s *PgStore) SetReadyPolicy(ctx context.Context, projectID string, gates []model.DependencyType) (*model.ReadyPolicy, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tenantID, ok := auth.TenantFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no tenant in context")
+	}
+
+	var owned bool
+	err := s.pool.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM project WHERE id = $1 AND tenant_id = $2)",
+		projectID, tenantID).Scan(&owned)
+	if err != nil {
+		return nil, fmt.Errorf("checking project: %w", err)
+	}
+	if !owned {
+		return nil, fmt.Errorf("project not found")
+	}
+
+	if gates == nil {
+		if _, err := s.pool.Exec(ctx,
+			"DELETE FROM project_ready_policy WHERE project_id = $1", projectID); err != nil {
+			return nil, fmt.Errorf("resetting ready policy: %w", err)
+		}
+		return defaultReadyPolicy(projectID), nil
+	}
+
+	gates, err = normalizeReadyGates(gates)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(gates))
+	for i, g := range gates {
+		names[i] = string(g)
+	}
+	if _, err := s.pool.Exec(ctx,
+		`INSERT INTO project_ready_policy (project_id, gates) VALUES ($1, $2)
+		 ON CONFLICT (project_id) DO UPDATE SET gates = EXCLUDED.gates, updated_at = NOW()`,
+		projectID, names); err != nil {
+		return nil, fmt.Errorf("setting ready policy: %w", err)
+	}
+	return &model.ReadyPolicy{ProjectID: projectID, Gates: gates}, nil
+}
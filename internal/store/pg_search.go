@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// searchHeadlineOptions configures ts_headline to mark matches with
+// model.SnippetMark and keep snippets to a couple of short fragments.
+const searchHeadlineOptions = `StartSel=**, StopSel=**, MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=" … "`
+
+// SearchIssues runs a ranked full-text search over issue text and comments.
+// An issue matches when its own fields match the query or any single
+// comment on it does.
+func (s *PgStore) SearchIssues(ctx context.Context, query model.SearchQuery) ([]model.SearchResult, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(query.Query) == "" {
+		return nil, fmt.Errorf("search query is empty")
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	sql := `WITH q AS (SELECT websearch_to_tsquery('english', $2) AS query)
+		SELECT (ts_rank(i.search_vector, q.query) + COALESCE(cm.comment_rank, 0))::float8 AS rank,
+			ts_headline('english', concat_ws(' … ', NULLIF(i.description, ''), NULLIF(i.design, ''),
+				NULLIF(i.acceptance_criteria, ''), NULLIF(i.notes, ''), cm.body, i.title),
+				q.query, '` + searchHeadlineOptions + `') AS snippet,
+			` + issueColumns + `
+		FROM issues i CROSS JOIN q
+		LEFT JOIN LATERAL (
+			SELECT MAX(ts_rank(c.search_vector, q.query)) AS comment_rank,
+				string_agg(c.text, ' ' ORDER BY c.id) AS body
+			FROM comments c
+			WHERE c.issue_id = i.id AND c.search_vector @@ q.query
+		) cm ON true
+		WHERE i.tenant_id = $1 AND i.deleted_at IS NULL
+			AND (i.search_vector @@ q.query
+				OR EXISTS (SELECT 1 FROM comments c WHERE c.issue_id = i.id AND c.search_vector @@ q.query))`
+	args := []any{tid, query.Query}
+	argN := 2
+
+	if query.Status != nil {
+		argN++
+		sql += fmt.Sprintf(" AND i.status = $%d", argN)
+		args = append(args, string(*query.Status))
+	}
+	if query.IssueType != nil {
+		argN++
+		sql += fmt.Sprintf(" AND i.issue_type = $%d", argN)
+		args = append(args, string(*query.IssueType))
+	}
+	if query.ProjectID != nil {
+		argN++
+		sql += fmt.Sprintf(" AND i.project_id = $%d::uuid", argN)
+		args = append(args, *query.ProjectID)
+	}
+	sql, args, argN = addProjectFilter(ctx, sql, args, argN, "i.project_id")
+
+	argN++
+	sql += fmt.Sprintf(" ORDER BY rank DESC, i.updated_at DESC LIMIT $%d", argN)
+	args = append(args, searchLimit(query.Limit))
+
+	rows, err := s.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("searching issues: %w", err)
+	}
+	defer rows.Close()
+
+	results := []model.SearchResult{}
+	for rows.Next() {
+		var r model.SearchResult
+		issue, err := scanIssueFromRow(rows, &r.Rank, &r.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		r.Issue = *issue
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
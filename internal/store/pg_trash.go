@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// DeleteIssue moves an issue to the trash. Its dependencies, labels,
+// comments and events are kept until the trash is purged.
+func (s *PgStore) DeleteIssue(ctx context.Context, id string) error {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return err
+	}
+	actor := eventActor(ctx, "")
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deletedAt time.Time
+	err = tx.QueryRow(ctx,
+		`UPDATE issues SET deleted_at = NOW(), deleted_by = $3
+		 WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+		 RETURNING deleted_at`, id, tid, actor).Scan(&deletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("issue %s not found", id)
+	}
+	if err != nil {
+		return fmt.Errorf("deleting issue: %w", err)
+	}
+	if err := insertEvents(ctx, tx, []AddEventInput{trashEvent(id, deletedAt, true, actor)}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RestoreIssue takes an issue out of the trash as it was when deleted.
+func (s *PgStore) RestoreIssue(ctx context.Context, id string) (*model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	actor := eventActor(ctx, "")
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deletedAt *time.Time
+	err = tx.QueryRow(ctx,
+		"SELECT deleted_at FROM issues WHERE id = $1 AND tenant_id = $2 FOR UPDATE", id, tid).Scan(&deletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("issue %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading issue %s: %w", id, err)
+	}
+	if deletedAt == nil {
+		return nil, fmt.Errorf("issue %s is not in the trash", id)
+	}
+	if _, err := tx.Exec(ctx,
+		"UPDATE issues SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND tenant_id = $2", id, tid); err != nil {
+		return nil, fmt.Errorf("restoring issue: %w", err)
+	}
+	if err := insertEvents(ctx, tx, []AddEventInput{trashEvent(id, *deletedAt, false, actor)}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetIssue(ctx, id)
+}
+
+// ListTrash returns the tenant's deleted issues, most recently deleted
+// first.
+func (s *PgStore) ListTrash(ctx context.Context) ([]model.Issue, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + issueColumns + " FROM issues WHERE tenant_id = $1 AND deleted_at IS NOT NULL"
+	query, args, _ := addProjectFilter(ctx, query, []any{tid}, 1, "project_id")
+	query += " ORDER BY deleted_at DESC, id"
+	return s.scanIssues(ctx, s.pool, query, args...)
+}
+
+// PurgeTrash permanently deletes issues trashed before deletedBefore,
+// cascading to their dependencies, labels, comments and events, and
+// returns their IDs.
+func (s *PgStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		`DELETE FROM issues WHERE tenant_id = $1 AND deleted_at < $2 RETURNING id`, tid, deletedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("purging trash: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("purging trash: %w", err)
+	}
+	return ids, nil
+}
+
+// PurgeIssues permanently deletes issues ids, trashed or not, cascading
+// like PurgeTrash, and returns the IDs of those found.
+func (s *PgStore) PurgeIssues(ctx context.Context, ids []string) ([]string, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		`DELETE FROM issues WHERE tenant_id = $1 AND id = ANY($2) RETURNING id`, tid, ids)
+	if err != nil {
+		return nil, fmt.Errorf("purging issues: %w", err)
+	}
+	purged, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("purging issues: %w", err)
+	}
+	return purged, nil
+}
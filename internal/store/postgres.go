@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"github.com/Actual-Outcomes/doit/internal/model"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -60,10 +62,15 @@ func (s *PgStore) GenerateID(ctx context.Context, prefix string) (string, error)
 	}
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
+	return generateIssueID(ctx, s.pool, prefix)
+}
 
+// generateIssueID is GenerateID against q, so a batch can mint IDs inside
+// its transaction.
+func generateIssueID(ctx context.Context, q querier, prefix string) (string, error) {
 	// Count existing issues to determine hash length
 	var count int
-	err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM issues").Scan(&count)
+	err := q.QueryRow(ctx, "SELECT COUNT(*) FROM issues").Scan(&count)
 	if err != nil {
 		return "", fmt.Errorf("counting issues: %w", err)
 	}
@@ -87,7 +94,7 @@ func (s *PgStore) GenerateID(ctx context.Context, prefix string) (string, error)
 
 		// Check uniqueness
 		var exists bool
-		err := s.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM issues WHERE id = $1)", id).Scan(&exists)
+		err := q.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM issues WHERE id = $1)", id).Scan(&exists)
 		if err != nil {
 			return "", fmt.Errorf("checking ID uniqueness: %w", err)
 		}
@@ -114,14 +121,12 @@ func (s *PgStore) NextChildID(ctx context.Context, parentID string) (string, err
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	tx, err := s.pool.Begin(ctx)
-	if err != nil {
-		return "", fmt.Errorf("beginning transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
+	return nextChildID(ctx, s.pool, parentID)
+}
 
+func nextChildID(ctx context.Context, q querier, parentID string) (string, error) {
 	var lastChild int
-	err = tx.QueryRow(ctx,
+	err := q.QueryRow(ctx,
 		`INSERT INTO child_counters (parent_id, last_child)
 		 VALUES ($1, 1)
 		 ON CONFLICT (parent_id) DO UPDATE SET last_child = child_counters.last_child + 1
@@ -129,11 +134,6 @@ func (s *PgStore) NextChildID(ctx context.Context, parentID string) (string, err
 	if err != nil {
 		return "", fmt.Errorf("incrementing child counter: %w", err)
 	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return "", fmt.Errorf("committing: %w", err)
-	}
-
 	return fmt.Sprintf("%s.%d", parentID, lastChild), nil
 }
 
@@ -147,14 +147,29 @@ func (s *PgStore) CreateIssue(ctx context.Context, input CreateIssueInput) (*mod
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	now := time.Now().UTC()
-
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	issue, err := insertIssue(ctx, tx, tid, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+
+	return issue, nil
+}
+
+// insertIssue writes a new issue with its parent-child dependency, labels and
+// creation event using q, which is normally a transaction.
+func insertIssue(ctx context.Context, q querier, tid uuid.UUID, input CreateIssueInput) (*model.Issue, error) {
+	now := time.Now().UTC()
+
 	issue := &model.Issue{
 		ID:        input.ID,
 		Title:     input.Title,
@@ -173,33 +188,50 @@ func (s *PgStore) CreateIssue(ctx context.Context, input CreateIssueInput) (*mod
 		Ephemeral: input.Ephemeral,
 		MolType:   input.MolType,
 		WorkType:  input.WorkType,
+		Crystallizes: input.Crystallizes,
 		WispType:  input.WispType,
+		AgentState: input.AgentState,
+		LastActivity: input.LastActivity,
+		RoleType:  input.RoleType,
+		Rig:       input.Rig,
+		RoleBead:  input.RoleBead,
+		AwaitType: input.AwaitType,
+		AwaitID:   input.AwaitID,
+		Timeout:   input.Timeout,
+		IsTemplate: input.IsTemplate,
 		TenantID:  tid.String(),
 		ProjectID: input.ProjectID,
+		EstimatedMinutes: positiveOrNil(input.EstimatedMinutes),
 	}
 
 	// Compute content hash
 	issue.ContentHash = contentHash(issue)
 
-	_, err = tx.Exec(ctx,
+	_, err := q.Exec(ctx,
 		`INSERT INTO issues (id, content_hash, title, description, design, acceptance_criteria,
 		 notes, status, priority, issue_type, assignee, owner, created_at, created_by,
-		 updated_at, ephemeral, mol_type, work_type, wisp_type, tenant_id, project_id)
-		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21)`,
+		 updated_at, ephemeral, mol_type, work_type, crystallizes, wisp_type, tenant_id,
+		 project_id, estimated_minutes, agent_state, last_activity, role_type, rig, role_bead,
+		 await_type, await_id, timeout_ns, is_template)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29,$30,$31,$32)`,
 		issue.ID, issue.ContentHash, issue.Title, issue.Description, issue.Design,
 		issue.AcceptanceCriteria, issue.Notes, issue.Status, issue.Priority,
 		issue.IssueType, nullEmpty(issue.Assignee), nullEmpty(issue.Owner),
 		issue.CreatedAt, nullEmpty(issue.CreatedBy), issue.UpdatedAt,
 		issue.Ephemeral, nullEmpty(string(issue.MolType)),
-		nullEmpty(string(issue.WorkType)), nullEmpty(string(issue.WispType)),
-		tid, nullEmpty(input.ProjectID))
+		nullEmpty(string(issue.WorkType)), issue.Crystallizes, nullEmpty(string(issue.WispType)),
+		tid, nullEmpty(input.ProjectID), issue.EstimatedMinutes,
+		nullEmpty(string(issue.AgentState)), issue.LastActivity, nullEmpty(issue.RoleType),
+		nullEmpty(issue.Rig), nullEmpty(issue.RoleBead),
+		nullEmpty(issue.AwaitType), nullEmpty(issue.AwaitID), nullDuration(issue.Timeout),
+		issue.IsTemplate)
 	if err != nil {
 		return nil, fmt.Errorf("inserting issue: %w", err)
 	}
 
 	// Create parent-child dependency if parent specified
 	if input.ParentID != "" {
-		_, err = tx.Exec(ctx,
+		_, err = q.Exec(ctx,
 			`INSERT INTO dependencies (issue_id, depends_on_id, type, created_at, created_by)
 			 VALUES ($1, $2, 'parent-child', $3, $4)`,
 			input.ID, input.ParentID, now, nullEmpty(input.CreatedBy))
@@ -211,7 +243,7 @@ func (s *PgStore) CreateIssue(ctx context.Context, input CreateIssueInput) (*mod
 
 	// Add labels
 	for _, label := range input.Labels {
-		_, err = tx.Exec(ctx,
+		_, err = q.Exec(ctx,
 			`INSERT INTO labels (issue_id, label) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
 			input.ID, label)
 		if err != nil {
@@ -221,18 +253,14 @@ func (s *PgStore) CreateIssue(ctx context.Context, input CreateIssueInput) (*mod
 	issue.Labels = input.Labels
 
 	// Record creation event
-	_, err = tx.Exec(ctx,
+	_, err = q.Exec(ctx,
 		`INSERT INTO events (issue_id, event_type, actor, new_value, created_at)
 		 VALUES ($1, 'created', $2, $3, $4)`,
-		issue.ID, nullEmpty(input.CreatedBy), issue.Title, now)
+		issue.ID, eventActor(ctx, input.CreatedBy), issue.Title, now)
 	if err != nil {
 		return nil, fmt.Errorf("recording creation event: %w", err)
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("committing: %w", err)
-	}
-
 	return issue, nil
 }
 
@@ -247,7 +275,7 @@ func (s *PgStore) GetIssue(ctx context.Context, id string) (*model.Issue, error)
 	defer cancel()
 
 	issue, err := s.scanIssue(ctx, s.pool,
-		`SELECT `+issueColumns+` FROM issues WHERE id = $1 AND tenant_id = $2`, id, tid)
+		`SELECT `+issueColumns+` FROM issues WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`, id, tid)
 	if err != nil {
 		return nil, fmt.Errorf("getting issue %s: %w", id, err)
 	}
@@ -261,7 +289,9 @@ func (s *PgStore) GetIssue(ctx context.Context, id string) (*model.Issue, error)
 	// Load parent ID
 	var parentID *string
 	err = s.pool.QueryRow(ctx,
-		`SELECT depends_on_id FROM dependencies WHERE issue_id = $1 AND type = 'parent-child'`, id).
+		`SELECT d.depends_on_id FROM dependencies d
+		 JOIN issues p ON p.id = d.depends_on_id AND p.deleted_at IS NULL
+		 WHERE d.issue_id = $1 AND d.type = 'parent-child'`, id).
 		Scan(&parentID)
 	if err == nil && parentID != nil {
 		issue.ParentID = *parentID
@@ -286,10 +316,46 @@ func (s *PgStore) UpdateIssue(ctx context.Context, id string, input UpdateIssueI
 	}
 	defer tx.Rollback(ctx)
 
+	issue, err := s.updateIssue(ctx, tx, tid, id, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+
+	return issue, nil
+}
+
+// updateIssue locks the issue row, checks the expected version and applies
+// input using q, which must be a transaction for the lock to hold.
+func (s *PgStore) updateIssue(ctx context.Context, q querier, tid uuid.UUID, id string, input UpdateIssueInput) (*model.Issue, error) {
+	current, err := s.scanIssue(ctx, q,
+		"SELECT "+issueColumns+" FROM issues WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL FOR UPDATE", id, tid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("issue %s not found", id)
+		}
+		return nil, fmt.Errorf("loading issue %s: %w", id, err)
+	}
+	if err := checkExpectedVersion(current, input); err != nil {
+		return nil, err
+	}
+	if reopensCompacted(current, input) {
+		snaps, err := querySnapshots(ctx, q, id)
+		if err != nil {
+			return nil, err
+		}
+		if snap := snapshotFor(snaps, 0); snap != nil {
+			uncompactInput(snap, &input)
+		}
+	}
+
 	// Build dynamic SET clause
-	sets := []string{"updated_at = NOW()"}
-	args := []any{}
-	argN := 0
+	sets := []string{"updated_at = NOW()", "content_hash = $1"}
+	args := []any{updatedContentHash(current, input)}
+	argN := 1
 
 	addSet := func(col string, val any) {
 		argN++
@@ -330,12 +396,56 @@ func (s *PgStore) UpdateIssue(ctx context.Context, id string, input UpdateIssueI
 	if input.Pinned != nil {
 		addSet("pinned", *input.Pinned)
 	}
+	if input.Ephemeral != nil {
+		addSet("ephemeral", *input.Ephemeral)
+	}
+	if input.AgentState != nil {
+		addSet("agent_state", nullEmpty(string(*input.AgentState)))
+	}
+	if input.LastActivity != nil && input.Lease == nil { // a lease sets it to now below
+		addSet("last_activity", *input.LastActivity)
+	}
+	if input.RoleType != nil {
+		addSet("role_type", nullEmpty(*input.RoleType))
+	}
+	if input.Rig != nil {
+		addSet("rig", nullEmpty(*input.Rig))
+	}
+	if input.HookBead != nil {
+		addSet("hook_bead", nullEmpty(*input.HookBead))
+	}
+	if input.RoleBead != nil {
+		addSet("role_bead", nullEmpty(*input.RoleBead))
+	}
+	if input.AwaitID != nil {
+		addSet("await_id", nullEmpty(*input.AwaitID))
+	}
+	if input.IsTemplate != nil {
+		addSet("is_template", *input.IsTemplate)
+	}
 	if input.ExternalRef != nil {
 		addSet("external_ref", nullEmpty(*input.ExternalRef))
 	}
+	if input.EstimatedMinutes != nil {
+		addSet("estimated_minutes", positiveOrNil(input.EstimatedMinutes))
+	}
 	if input.CloseReason != nil {
 		addSet("close_reason", *input.CloseReason)
 	}
+	if input.CompactionLevel != nil {
+		addSet("compaction_level", *input.CompactionLevel)
+		if *input.CompactionLevel == 0 {
+			sets = append(sets, "compacted_at = NULL")
+		}
+	}
+	if input.Lease != nil {
+		now := time.Now().UTC()
+		addSet("lease_expires_at", leaseExpiry(now, *input.Lease))
+		addSet("last_activity", now)
+	} else if input.Status != nil && *input.Status != model.StatusInProgress {
+		// Leaving in_progress ends the claim.
+		sets = append(sets, "lease_expires_at = NULL")
+	}
 
 	argN++
 	args = append(args, id)
@@ -345,15 +455,13 @@ func (s *PgStore) UpdateIssue(ctx context.Context, id string, input UpdateIssueI
 	query := fmt.Sprintf("UPDATE issues SET %s WHERE id = $%d AND tenant_id = $%d RETURNING %s",
 		strings.Join(sets, ", "), idArg, argN, issueColumns)
 
-	issue, err := s.scanIssue(ctx, tx, query, args...)
+	issue, err := s.scanIssue(ctx, q, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("updating issue %s: %w", id, err)
 	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("committing: %w", err)
+	if err := insertEvents(ctx, q, issueEvents(current, issue, eventActor(ctx, ""))); err != nil {
+		return nil, err
 	}
-
 	return issue, nil
 }
 
@@ -367,7 +475,7 @@ func (s *PgStore) ListIssues(ctx context.Context, filter model.IssueFilter) ([]m
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	query := "SELECT " + issueColumns + " FROM issues WHERE tenant_id = $1"
+	query := "SELECT " + issueColumns + " FROM issues WHERE tenant_id = $1 AND deleted_at IS NULL"
 	args := []any{tid}
 	argN := 1
 
@@ -406,8 +514,7 @@ func (s *PgStore) ListIssues(ctx context.Context, filter model.IssueFilter) ([]m
 		addWhere("pinned = $%d", *filter.Pinned)
 	}
 	if filter.Search != nil {
-		addWhere("(title ILIKE '%%' || $%d || '%%' OR description ILIKE '%%' || $%d || '%%')", *filter.Search)
-		// Note: search uses same arg twice, need to fix arg counting
+		addWhere("(title ILIKE '%%' || $%[1]d || '%%' OR description ILIKE '%%' || $%[1]d || '%%')", *filter.Search)
 	}
 	if filter.ParentID != nil {
 		argN++
@@ -421,17 +528,18 @@ func (s *PgStore) ListIssues(ctx context.Context, filter model.IssueFilter) ([]m
 	// Project filter (context-based auto-filter for allowed projects)
 	query, args, argN = addProjectFilter(ctx, query, args, argN, "project_id")
 
-	// Sort
-	switch filter.SortBy {
-	case "priority":
-		query += " ORDER BY priority ASC, created_at ASC"
-	case "oldest":
-		query += " ORDER BY created_at ASC"
-	case "updated":
-		query += " ORDER BY updated_at DESC"
-	default: // "hybrid"
-		query += " ORDER BY priority ASC, updated_at DESC"
+	// Sort, resuming after the cursor if one was given
+	keys := issueKeyset(filter.SortBy)
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, issueSortKey(filter.SortBy))
+		if err != nil {
+			return nil, err
+		}
+		var after string
+		after, args, argN = keys.after(cur, "$%d", args, argN)
+		query += " AND " + after
 	}
+	query += keys.orderBy()
 
 	// Limit/offset
 	if filter.Limit > 0 {
@@ -439,7 +547,7 @@ func (s *PgStore) ListIssues(ctx context.Context, filter model.IssueFilter) ([]m
 		query += fmt.Sprintf(" LIMIT $%d", argN)
 		args = append(args, filter.Limit)
 	}
-	if filter.Offset > 0 {
+	if filter.Offset > 0 && filter.Cursor == "" {
 		argN++
 		query += fmt.Sprintf(" OFFSET $%d", argN)
 		args = append(args, filter.Offset)
@@ -448,7 +556,8 @@ func (s *PgStore) ListIssues(ctx context.Context, filter model.IssueFilter) ([]m
 	return s.scanIssues(ctx, s.pool, query, args...)
 }
 
-// ListReady returns issues from the ready_issues view.
+// ListReady returns the tenant's issues from ready_issues_for, the
+// ready_issues view's rules applied to one tenant.
 func (s *PgStore) ListReady(ctx context.Context, filter model.IssueFilter) ([]model.Issue, error) {
 	tid, err := requireTenant(ctx)
 	if err != nil {
@@ -458,11 +567,11 @@ func (s *PgStore) ListReady(ctx context.Context, filter model.IssueFilter) ([]mo
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	query := "SELECT " + issueColumns + " FROM ready_issues"
+	query := "SELECT " + issueColumns + " FROM ready_issues_for($1)"
 	args := []any{tid}
 	argN := 1
 
-	where := []string{"tenant_id = $1"}
+	var where []string
 	if filter.IssueType != nil {
 		argN++
 		where = append(where, fmt.Sprintf("issue_type = $%d", argN))
@@ -492,11 +601,22 @@ func (s *PgStore) ListReady(ctx context.Context, filter model.IssueFilter) ([]mo
 		args = append(args, projectIDs)
 	}
 
+	keys := issueKeyset(ReadySortBy)
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, ReadySortBy)
+		if err != nil {
+			return nil, err
+		}
+		var after string
+		after, args, argN = keys.after(cur, "$%d", args, argN)
+		where = append(where, after)
+	}
+
 	if len(where) > 0 {
 		query += " WHERE " + strings.Join(where, " AND ")
 	}
 
-	query += " ORDER BY priority ASC, created_at ASC"
+	query += keys.orderBy()
 
 	if filter.Limit > 0 {
 		argN++
@@ -507,24 +627,118 @@ func (s *PgStore) ListReady(ctx context.Context, filter model.IssueFilter) ([]mo
 	return s.scanIssues(ctx, s.pool, query, args...)
 }
 
-// DeleteIssue removes an issue and cascades to dependencies, labels, etc.
-func (s *PgStore) DeleteIssue(ctx context.Context, id string) error {
+// claimAttempts bounds how often ClaimNextReady retries after losing a
+// race for the issue it selected.
+const claimAttempts = 5
+
+// ClaimNextReady atomically assigns the next ready issue to the claimant.
+// Rows locked by a concurrent claim are skipped rather than waited on, so
+// competing agents never receive the same issue. It returns nil when no
+// ready issue matches.
+func (s *PgStore) ClaimNextReady(ctx context.Context, input ClaimNextReadyInput) (*model.Issue, error) {
 	tid, err := requireTenant(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if input.Claimant == "" {
+		return nil, fmt.Errorf("claimant is required")
 	}
 
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	tag, err := s.pool.Exec(ctx, "DELETE FROM issues WHERE id = $1 AND tenant_id = $2", id, tid)
+	query := "SELECT id FROM ready_issues_for($1) AS ready_issues"
+	args := []any{tid}
+	argN := 1
+
+	var where []string
+	if input.IssueType != nil {
+		argN++
+		where = append(where, fmt.Sprintf("issue_type = $%d", argN))
+		args = append(args, string(*input.IssueType))
+	}
+	if input.ProjectID != nil {
+		argN++
+		where = append(where, fmt.Sprintf("project_id = $%d::uuid", argN))
+		args = append(args, *input.ProjectID)
+	}
+	for _, label := range input.Labels {
+		argN++
+		where = append(where, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM labels l WHERE l.issue_id = ready_issues.id AND l.label = $%d)", argN))
+		args = append(args, label)
+	}
+	projectIDs := auth.AllowedProjectsFromContext(ctx)
+	if len(projectIDs) > 0 {
+		argN++
+		where = append(where, fmt.Sprintf("project_id = ANY($%d::uuid[])", argN))
+		args = append(args, projectIDs)
+	}
+
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	// Lock the issues row itself: ready_issues_for is too complex a query
+	// to take row locks through. A claim that committed after this
+	// statement's snapshot is only visible on the locked row, not in the
+	// function's result, so the status is checked again there.
+	query = "SELECT " + issueColumns + " FROM issues WHERE status = 'open' AND id IN (" + query + ")"
+	query += issueKeyset(ReadySortBy).orderBy()
+	query += " LIMIT 1 FOR UPDATE SKIP LOCKED"
+
+	for attempt := 0; attempt < claimAttempts; attempt++ {
+		issue, raced, err := s.claimReady(ctx, tid, input, query, args)
+		if err != nil || !raced {
+			return issue, err
+		}
+	}
+	return nil, nil
+}
+
+// claimReady claims the issue query selects in one transaction. raced
+// reports that another claim took the issue first, leaving nothing claimed.
+func (s *PgStore) claimReady(ctx context.Context, tid uuid.UUID, input ClaimNextReadyInput, query string, args []any) (issue *model.Issue, raced bool, err error) {
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("deleting issue: %w", err)
+		return nil, false, fmt.Errorf("beginning transaction: %w", err)
 	}
-	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("issue %s not found", id)
+	defer tx.Rollback(ctx)
+
+	current, err := s.scanIssue(ctx, tx, query, args...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("selecting ready issue: %w", err)
 	}
-	return nil
+	id := current.ID
+
+	now := time.Now().UTC()
+	issue, err = s.scanIssue(ctx, tx,
+		`UPDATE issues SET status = $1, assignee = $2, updated_at = NOW(), last_activity = $5, lease_expires_at = $6
+		 WHERE id = $3 AND tenant_id = $4 AND status = 'open' RETURNING `+issueColumns,
+		string(model.StatusInProgress), input.Claimant, id, tid, now, leaseExpiry(now, input.Lease))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, true, nil
+		}
+		return nil, false, fmt.Errorf("claiming issue %s: %w", id, err)
+	}
+
+	if err := insertEvents(ctx, tx, issueEvents(current, issue, eventActor(ctx, input.Claimant))); err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("committing: %w", err)
+	}
+
+	issue.Labels, err = s.queryLabels(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	return issue, false, nil
 }
 
 // --- Dependencies ---
@@ -537,9 +751,124 @@ func (s *PgStore) AddDependency(ctx context.Context, input AddDependencyInput) (
 		return nil, err
 	}
 
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	dep, err := addCheckedDependency(ctx, tx, tid, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return dep, nil
+}
+
+// addCheckedDependency upserts input unless it would close a blocking or
+// hierarchical cycle. q must be a transaction: the tenant's graph lock is
+// held until it ends, so two concurrent edges cannot each pass the check
+// and together close a cycle.
+func addCheckedDependency(ctx context.Context, q querier, tid uuid.UUID, input AddDependencyInput) (*model.Dependency, error) {
+	if input.Type.CycleFamily() != "" {
+		_, err := q.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "doit.dependencies:"+tid.String())
+		if err != nil {
+			return nil, fmt.Errorf("locking dependency graph: %w", err)
+		}
+		err = checkCycle(input, func(ids, types []string) (map[string][]string, error) {
+			return queryEdges(ctx, q, ids, types)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return insertDependency(ctx, q, input)
+}
+
+// queryEdges returns the depends_on IDs of the given issues' edges of the
+// given types, keyed by issue.
+func queryEdges(ctx context.Context, q querier, ids, types []string) (map[string][]string, error) {
+	rows, err := q.Query(ctx,
+		"SELECT issue_id, depends_on_id FROM dependencies WHERE issue_id = ANY($1) AND type = ANY($2) AND "+liveEdge,
+		ids, types)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edges := make(map[string][]string)
+	for rows.Next() {
+		var from, to string
+		if err := rows.Scan(&from, &to); err != nil {
+			return nil, err
+		}
+		edges[from] = append(edges[from], to)
+	}
+	return edges, rows.Err()
+}
+
+// ValidateGraph reports cycles, orphaned children and dangling references
+// in the tenant's dependency graph.
+func (s *PgStore) ValidateGraph(ctx context.Context) (*model.GraphReport, error) {
+	tid, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
+	rows, err := s.pool.Query(ctx, "SELECT id FROM issues WHERE tenant_id = $1 AND deleted_at IS NULL", tid)
+	if err != nil {
+		return nil, fmt.Errorf("listing issues: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("listing issues: %w", err)
+	}
+
+	rows, err = s.pool.Query(ctx,
+		`SELECT issue_id, depends_on_id, type, created_at, created_by, metadata, thread_id
+		 FROM dependencies
+		 WHERE (issue_id IN (SELECT id FROM issues WHERE tenant_id = $1)
+		    OR depends_on_id IN (SELECT id FROM issues WHERE tenant_id = $1))
+		   AND `+liveEdge, tid)
+	if err != nil {
+		return nil, fmt.Errorf("listing dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	var deps []model.Dependency
+	for rows.Next() {
+		var d model.Dependency
+		var metadata []byte
+		err := rows.Scan(&d.IssueID, &d.DependsOnID, &d.Type, &d.CreatedAt,
+			&ns{&d.CreatedBy}, &metadata, &ns{&d.ThreadID})
+		if err != nil {
+			return nil, fmt.Errorf("scanning dependency: %w", err)
+		}
+		d.Metadata = metadata
+		deps = append(deps, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return analyzeGraph(tid.String(), ids, deps), nil
+}
+
+func insertDependency(ctx context.Context, q querier, input AddDependencyInput) (*model.Dependency, error) {
 	now := time.Now().UTC()
 	dep := &model.Dependency{
 		IssueID:     input.IssueID,
@@ -550,7 +879,18 @@ func (s *PgStore) AddDependency(ctx context.Context, input AddDependencyInput) (
 		ThreadID:    input.ThreadID,
 	}
 
-	_, err := s.pool.Exec(ctx,
+	var oldType model.DependencyType
+	err := q.QueryRow(ctx,
+		"SELECT type FROM dependencies WHERE issue_id = $1 AND depends_on_id = $2 FOR UPDATE",
+		dep.IssueID, dep.DependsOnID).Scan(&oldType)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("adding dependency: %w", err)
+	}
+	if oldType == dep.Type {
+		return dep, nil
+	}
+
+	_, err = q.Exec(ctx,
 		`INSERT INTO dependencies (issue_id, depends_on_id, type, created_at, created_by, thread_id)
 		 VALUES ($1, $2, $3, $4, $5, $6)
 		 ON CONFLICT (issue_id, depends_on_id) DO UPDATE SET type = $3`,
@@ -560,6 +900,10 @@ func (s *PgStore) AddDependency(ctx context.Context, input AddDependencyInput) (
 		return nil, fmt.Errorf("adding dependency: %w", err)
 	}
 
+	e := dependencyEvent(*dep, oldType, true, eventActor(ctx, dep.CreatedBy))
+	if err := insertEvents(ctx, q, []AddEventInput{e}); err != nil {
+		return nil, err
+	}
 	return dep, nil
 }
 
@@ -571,10 +915,26 @@ func (s *PgStore) RemoveDependency(ctx context.Context, issueID, dependsOnID str
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	_, err := s.pool.Exec(ctx,
-		"DELETE FROM dependencies WHERE issue_id = $1 AND depends_on_id = $2",
-		issueID, dependsOnID)
-	return err
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	dep := model.Dependency{IssueID: issueID, DependsOnID: dependsOnID}
+	err = tx.QueryRow(ctx,
+		"DELETE FROM dependencies WHERE issue_id = $1 AND depends_on_id = $2 RETURNING type",
+		issueID, dependsOnID).Scan(&dep.Type)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("removing dependency: %w", err)
+	}
+	if err := insertEvents(ctx, tx, []AddEventInput{dependencyEvent(dep, "", false, eventActor(ctx, ""))}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (s *PgStore) ListDependencies(ctx context.Context, issueID string, direction string) ([]model.Dependency, error) {
@@ -595,8 +955,9 @@ func (s *PgStore) ListDependencies(ctx context.Context, issueID string, directio
 				 FROM dependencies WHERE depends_on_id = $1`
 	default: // both
 		query = `SELECT issue_id, depends_on_id, type, created_at, created_by, metadata, thread_id
-				 FROM dependencies WHERE issue_id = $1 OR depends_on_id = $1`
+				 FROM dependencies WHERE (issue_id = $1 OR depends_on_id = $1)`
 	}
+	query += " AND " + liveEdge
 
 	rows, err := s.pool.Query(ctx, query, issueID)
 	if err != nil {
@@ -639,7 +1000,7 @@ func (s *PgStore) GetDependencyTree(ctx context.Context, rootID string, maxDepth
 			FROM issues i
 			JOIN dependencies d ON d.issue_id = i.id AND d.type = 'parent-child'
 			JOIN tree t ON d.depends_on_id = t.issue_id
-			WHERE t.depth < $2 AND i.tenant_id = $3
+			WHERE t.depth < $2 AND i.tenant_id = $3 AND i.deleted_at IS NULL
 		)
 		SELECT t.depth, `+issueColumns+`
 		FROM tree t
@@ -674,10 +1035,27 @@ func (s *PgStore) AddLabel(ctx context.Context, issueID, label string) error {
 	}
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
-	_, err := s.pool.Exec(ctx,
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertLabel(ctx, tx, issueID, label); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func insertLabel(ctx context.Context, q querier, issueID, label string) error {
+	tag, err := q.Exec(ctx,
 		"INSERT INTO labels (issue_id, label) VALUES ($1, $2) ON CONFLICT DO NOTHING",
 		issueID, label)
-	return err
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+	return insertEvents(ctx, q, []AddEventInput{labelEvent(issueID, label, true, eventActor(ctx, ""))})
 }
 
 func (s *PgStore) RemoveLabel(ctx context.Context, issueID, label string) error {
@@ -686,9 +1064,22 @@ func (s *PgStore) RemoveLabel(ctx context.Context, issueID, label string) error
 	}
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
-	_, err := s.pool.Exec(ctx,
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
 		"DELETE FROM labels WHERE issue_id = $1 AND label = $2", issueID, label)
-	return err
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+	if err := insertEvents(ctx, tx, []AddEventInput{labelEvent(issueID, label, false, eventActor(ctx, ""))}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (s *PgStore) ListLabels(ctx context.Context, issueID string) ([]string, error) {
@@ -708,8 +1099,25 @@ func (s *PgStore) AddComment(ctx context.Context, issueID, author, text string)
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	c, err := insertComment(ctx, tx, issueID, author, text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return c, nil
+}
+
+func insertComment(ctx context.Context, q querier, issueID, author, text string) (*model.Comment, error) {
 	c := &model.Comment{}
-	err := s.pool.QueryRow(ctx,
+	err := q.QueryRow(ctx,
 		`INSERT INTO comments (issue_id, author, text) VALUES ($1, $2, $3)
 		 RETURNING id, issue_id, author, text, created_at`,
 		issueID, author, text).
@@ -717,6 +1125,9 @@ func (s *PgStore) AddComment(ctx context.Context, issueID, author, text string)
 	if err != nil {
 		return nil, fmt.Errorf("adding comment: %w", err)
 	}
+	if err := insertEvents(ctx, q, []AddEventInput{commentEvent(c, eventActor(ctx, author))}); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
@@ -759,12 +1170,12 @@ func (s *PgStore) AddEvent(ctx context.Context, input AddEventInput) (*model.Eve
 
 	e := &model.Event{}
 	err := s.pool.QueryRow(ctx,
-		`INSERT INTO events (issue_id, event_type, actor, old_value, new_value, comment)
-		 VALUES ($1, $2, $3, $4, $5, $6)
-		 RETURNING id, issue_id, event_type, actor, old_value, new_value, comment, created_at`,
-		input.IssueID, input.EventType, input.Actor,
+		`INSERT INTO events (issue_id, event_type, actor, field, old_value, new_value, comment)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING `+eventColumns,
+		input.IssueID, input.EventType, eventActor(ctx, input.Actor), nullEmpty(input.Field),
 		nullEmpty(input.OldValue), nullEmpty(input.NewValue), nullEmpty(input.Comment)).
-		Scan(&e.ID, &e.IssueID, &e.EventType, &e.Actor, &e.OldValue, &e.NewValue, &e.Comment, &e.CreatedAt)
+		Scan(&e.ID, &e.IssueID, &e.EventType, &e.Actor, &ns{&e.Field}, &ns{&e.OldValue}, &ns{&e.NewValue}, &ns{&e.Comment}, &e.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("adding event: %w", err)
 	}
@@ -779,7 +1190,7 @@ func (s *PgStore) ListEvents(ctx context.Context, issueID string, limit int) ([]
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	query := "SELECT id, issue_id, event_type, actor, old_value, new_value, comment, created_at FROM events WHERE issue_id = $1 ORDER BY created_at DESC"
+	query := "SELECT " + eventColumns + " FROM events WHERE issue_id = $1 ORDER BY created_at DESC, id DESC"
 	args := []any{issueID}
 	if limit > 0 {
 		query += " LIMIT $2"
@@ -795,7 +1206,7 @@ func (s *PgStore) ListEvents(ctx context.Context, issueID string, limit int) ([]
 	var events []model.Event
 	for rows.Next() {
 		var e model.Event
-		if err := rows.Scan(&e.ID, &e.IssueID, &e.EventType, &e.Actor, &e.OldValue, &e.NewValue, &e.Comment, &e.CreatedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.IssueID, &e.EventType, &e.Actor, &ns{&e.Field}, &ns{&e.OldValue}, &ns{&e.NewValue}, &ns{&e.Comment}, &e.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scanning event: %w", err)
 		}
 		events = append(events, e)
@@ -803,20 +1214,65 @@ func (s *PgStore) ListEvents(ctx context.Context, issueID string, limit int) ([]
 	return events, rows.Err()
 }
 
+const eventColumns = "id, issue_id, event_type, actor, field, old_value, new_value, comment, created_at"
+
+// insertEvents records events using q, normally the transaction that made
+// the change they describe.
+func insertEvents(ctx context.Context, q querier, events []AddEventInput) error {
+	for _, e := range events {
+		_, err := q.Exec(ctx,
+			`INSERT INTO events (issue_id, event_type, actor, field, old_value, new_value, comment)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			e.IssueID, e.EventType, e.Actor, nullEmpty(e.Field),
+			nullEmpty(e.OldValue), nullEmpty(e.NewValue), nullEmpty(e.Comment))
+		if err != nil {
+			return fmt.Errorf("recording %s event: %w", e.EventType, err)
+		}
+	}
+	return nil
+}
+
 // --- Compaction ---
 
-func (s *PgStore) SaveCompactionSnapshot(ctx context.Context, issueID string, level int, summary, original string) error {
-	if err := s.validateIssueOwnership(ctx, issueID); err != nil {
+func (s *PgStore) SaveCompactionSnapshot(ctx context.Context, issueID string, level int, summary, original string, content UpdateIssueInput) error {
+	tid, err := requireTenant(ctx)
+	if err != nil {
 		return err
 	}
 
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	_, err := s.pool.Exec(ctx,
-		`INSERT INTO compaction_snapshots (issue_id, level, summary, original) VALUES ($1, $2, $3, $4)`,
-		issueID, level, summary, original)
-	return err
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := s.updateIssue(ctx, tx, tid, issueID, content); err != nil {
+		return err
+	}
+	var oldLevel int
+	err = tx.QueryRow(ctx,
+		"SELECT compaction_level FROM issues WHERE id = $1 FOR UPDATE", issueID).Scan(&oldLevel)
+	if err != nil {
+		return fmt.Errorf("reading compaction level: %w", err)
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO compaction_snapshots (issue_id, level, from_level, summary, original) VALUES ($1, $2, $3, $4, $5)`,
+		issueID, level, oldLevel, summary, original)
+	if err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	_, err = tx.Exec(ctx,
+		"UPDATE issues SET compaction_level = $2, compacted_at = NOW() WHERE id = $1", issueID, level)
+	if err != nil {
+		return fmt.Errorf("recording compaction level: %w", err)
+	}
+	if err := insertEvents(ctx, tx, []AddEventInput{compactedEvent(issueID, oldLevel, level, eventActor(ctx, ""))}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (s *PgStore) GetCompactionSnapshots(ctx context.Context, issueID string) ([]model.CompactionSnapshot, error) {
@@ -827,8 +1283,12 @@ func (s *PgStore) GetCompactionSnapshots(ctx context.Context, issueID string) ([
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, issue_id, level, summary, original, created_at FROM compaction_snapshots WHERE issue_id = $1 ORDER BY level",
+	return querySnapshots(ctx, s.pool, issueID)
+}
+
+func querySnapshots(ctx context.Context, q querier, issueID string) ([]model.CompactionSnapshot, error) {
+	rows, err := q.Query(ctx,
+		"SELECT id, issue_id, level, from_level, summary, original, created_at FROM compaction_snapshots WHERE issue_id = $1 ORDER BY level, id",
 		issueID)
 	if err != nil {
 		return nil, fmt.Errorf("listing snapshots: %w", err)
@@ -838,7 +1298,7 @@ func (s *PgStore) GetCompactionSnapshots(ctx context.Context, issueID string) ([
 	var snaps []model.CompactionSnapshot
 	for rows.Next() {
 		var snap model.CompactionSnapshot
-		if err := rows.Scan(&snap.ID, &snap.IssueID, &snap.Level, &snap.Summary, &snap.Original, &snap.CreatedAt); err != nil {
+		if err := rows.Scan(&snap.ID, &snap.IssueID, &snap.Level, &snap.FromLevel, &snap.Summary, &snap.Original, &snap.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scanning snapshot: %w", err)
 		}
 		snaps = append(snaps, snap)
@@ -857,7 +1317,7 @@ func (s *PgStore) CountIssuesByStatus(ctx context.Context) (map[string]int, erro
 	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	query := "SELECT status, COUNT(*) FROM issues WHERE tenant_id = $1"
+	query := "SELECT status, COUNT(*) FROM issues WHERE tenant_id = $1 AND deleted_at IS NULL"
 	args := []any{tid}
 	argN := 1
 	query, args, _ = addProjectFilter(ctx, query, args, argN, "project_id")
@@ -899,9 +1359,13 @@ func (s *PgStore) validateIssueOwnership(ctx context.Context, issueID string) er
 	if err != nil {
 		return err
 	}
+	return checkIssueOwned(ctx, s.pool, tid, issueID)
+}
+
+func checkIssueOwned(ctx context.Context, q querier, tid uuid.UUID, issueID string) error {
 	var exists bool
-	err = s.pool.QueryRow(ctx,
-		"SELECT EXISTS(SELECT 1 FROM issues WHERE id = $1 AND tenant_id = $2)",
+	err := q.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM issues WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL)",
 		issueID, tid).Scan(&exists)
 	if err != nil {
 		return fmt.Errorf("checking issue ownership: %w", err)
@@ -922,11 +1386,18 @@ const issueColumns = `id, content_hash, title, description, design, acceptance_c
 	sender, ephemeral, mol_type, work_type, crystallizes, wisp_type,
 	pinned, is_template, quality_score, event_kind, actor, target, payload,
 	await_type, await_id, timeout_ns, agent_state, last_activity, role_type, rig,
-	hook_bead, role_bead, tenant_id, project_id`
+	hook_bead, role_bead, tenant_id, project_id, lease_expires_at, deleted_at, deleted_by`
 
+// liveEdge is a dependencies condition excluding edges that touch a
+// trashed issue; they come back if it is restored.
+const liveEdge = `NOT EXISTS (SELECT 1 FROM issues x
+	WHERE x.id IN (dependencies.issue_id, dependencies.depends_on_id) AND x.deleted_at IS NOT NULL)`
+
+// querier is satisfied by both the pool and a pgx.Tx.
 type querier interface {
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
 }
 
 func (s *PgStore) scanIssue(ctx context.Context, q querier, query string, args ...any) (*model.Issue, error) {
@@ -947,7 +1418,8 @@ func scanIssueFromSingleRow(row pgx.Row) (*model.Issue, error) {
 		&ns{&i.Sender}, &i.Ephemeral, &ns{(*string)(&i.MolType)}, &ns{(*string)(&i.WorkType)}, &i.Crystallizes, &ns{(*string)(&i.WispType)},
 		&i.Pinned, &i.IsTemplate, &i.QualityScore, &ns{&i.EventKind}, &ns{&i.Actor}, &ns{&i.Target}, &ns{&i.Payload},
 		&ns{&i.AwaitType}, &ns{&i.AwaitID}, &ni64{(*int64)(&i.Timeout)}, &ns{(*string)(&i.AgentState)}, &i.LastActivity, &ns{&i.RoleType}, &ns{&i.Rig},
-		&ns{&i.HookBead}, &ns{&i.RoleBead}, &ns{&i.TenantID}, &ns{&i.ProjectID},
+		&ns{&i.HookBead}, &ns{&i.RoleBead}, &ns{&i.TenantID}, &ns{&i.ProjectID}, &i.LeaseExpiresAt,
+		&i.DeletedAt, &ns{&i.DeletedBy},
 	)
 	if err != nil {
 		return nil, err
@@ -960,7 +1432,7 @@ func scanIssueFromRow(rows pgx.Rows, extraFields ...any) (*model.Issue, error) {
 	var i model.Issue
 	var metadata []byte
 
-	scanArgs := make([]any, 0, len(extraFields)+54)
+	scanArgs := make([]any, 0, len(extraFields)+59)
 	scanArgs = append(scanArgs, extraFields...)
 	scanArgs = append(scanArgs,
 		&i.ID, &i.ContentHash, &i.Title, &i.Description, &i.Design,
@@ -972,7 +1444,8 @@ func scanIssueFromRow(rows pgx.Rows, extraFields ...any) (*model.Issue, error) {
 		&ns{&i.Sender}, &i.Ephemeral, &ns{(*string)(&i.MolType)}, &ns{(*string)(&i.WorkType)}, &i.Crystallizes, &ns{(*string)(&i.WispType)},
 		&i.Pinned, &i.IsTemplate, &i.QualityScore, &ns{&i.EventKind}, &ns{&i.Actor}, &ns{&i.Target}, &ns{&i.Payload},
 		&ns{&i.AwaitType}, &ns{&i.AwaitID}, &ni64{(*int64)(&i.Timeout)}, &ns{(*string)(&i.AgentState)}, &i.LastActivity, &ns{&i.RoleType}, &ns{&i.Rig},
-		&ns{&i.HookBead}, &ns{&i.RoleBead}, &ns{&i.TenantID}, &ns{&i.ProjectID},
+		&ns{&i.HookBead}, &ns{&i.RoleBead}, &ns{&i.TenantID}, &ns{&i.ProjectID}, &i.LeaseExpiresAt,
+		&i.DeletedAt, &ns{&i.DeletedBy},
 	)
 
 	if err := rows.Scan(scanArgs...); err != nil {
@@ -1029,6 +1502,24 @@ func contentHash(i *model.Issue) string {
 	return hex.EncodeToString(h.Sum(nil))[:16]
 }
 
+// positiveOrNil maps a missing or non-positive estimate to NULL.
+func positiveOrNil(n *int) *int {
+	if n == nil || *n <= 0 {
+		return nil
+	}
+	v := *n
+	return &v
+}
+
+// nullDuration maps a zero duration to NULL.
+func nullDuration(d time.Duration) *int64 {
+	if d == 0 {
+		return nil
+	}
+	v := int64(d)
+	return &v
+}
+
 func nullEmpty(s string) *string {
 	if s == "" || s == "null" {
 		return nil
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// normalizeReadyGates checks that every type in gates is a ready gate and
+// returns them deduplicated, in model.ReadyGates order. The result is never
+// nil, so an empty policy (no dependency gates) stays distinct from none.
+func normalizeReadyGates(gates []model.DependencyType) ([]model.DependencyType, error) {
+	want := make(map[model.DependencyType]bool, len(gates))
+	for _, g := range gates {
+		if !g.IsReadyGate() {
+			return nil, fmt.Errorf("%q is not a ready gate: want one of %s", g, joinDependencyTypes(model.ReadyGates, ", "))
+		}
+		want[g] = true
+	}
+	out := []model.DependencyType{}
+	for _, g := range model.ReadyGates {
+		if want[g] {
+			out = append(out, g)
+		}
+	}
+	return out, nil
+}
+
+// defaultReadyPolicy is the policy of a project that has none set.
+func defaultReadyPolicy(projectID string) *model.ReadyPolicy {
+	return &model.ReadyPolicy{
+		ProjectID: projectID,
+		Gates:     append([]model.DependencyType(nil), model.ReadyGates...),
+		Default:   true,
+	}
+}
+
+func joinDependencyTypes(types []model.DependencyType, sep string) string {
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = string(t)
+	}
+	return strings.Join(parts, sep)
+}
+
+// splitDependencyTypes parses the comma-separated gates column SQLite uses.
+func splitDependencyTypes(s string) []model.DependencyType {
+	out := []model.DependencyType{}
+	for _, part := range strings.Split(s, ",") {
+		if part != "" {
+			out = append(out, model.DependencyType(part))
+		}
+	}
+	return out
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// maxScheduleDepth bounds how far below an epic CriticalPath looks for
+// tasks.
+const maxScheduleDepth = 20
+
+// CriticalPath schedules the unfinished descendants of epicID from now.
+// A task starts once every predecessor inside the epic has finished:
+// targets of its blocks, conditional-blocks and waits-for edges, and its
+// own children, since a parent is done only when they are. Closed tasks
+// are done and drop out. Missing estimates count as zero and are listed in
+// Unestimated.
+func CriticalPath(ctx context.Context, s Store, epicID string, now time.Time) (*model.Schedule, error) {
+	nodes, err := s.GetDependencyTree(ctx, epicID, maxScheduleDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []*model.Issue
+	inEpic := make(map[string]bool)
+	for _, n := range nodes[1:] {
+		if n.Issue.Status == model.StatusClosed {
+			continue
+		}
+		issue := n.Issue
+		tasks = append(tasks, &issue)
+		inEpic[issue.ID] = true
+	}
+
+	preds := make(map[string][]string)
+	external := make(map[string][]string)
+	for _, t := range tasks {
+		deps, err := s.ListDependencies(ctx, t.ID, "both")
+		if err != nil {
+			return nil, fmt.Errorf("listing dependencies of %s: %w", t.ID, err)
+		}
+		for _, d := range deps {
+			if d.Type == model.DepParentChild && d.DependsOnID == t.ID {
+				if inEpic[d.IssueID] {
+					preds[t.ID] = append(preds[t.ID], d.IssueID)
+				}
+				continue
+			}
+			if d.IssueID != t.ID || d.Type.CycleFamily() != model.FamilyBlocking {
+				continue
+			}
+			if inEpic[d.DependsOnID] {
+				preds[t.ID] = append(preds[t.ID], d.DependsOnID)
+				continue
+			}
+			target, err := s.GetIssue(ctx, d.DependsOnID)
+			if err != nil {
+				return nil, fmt.Errorf("looking up dependency target: %w", err)
+			}
+			if target.Status != model.StatusClosed && target.ID != epicID {
+				external[t.ID] = append(external[t.ID], target.ID)
+			}
+		}
+	}
+	return buildSchedule(epicID, now, tasks, preds, external)
+}
+
+// buildSchedule runs the critical path method over tasks, where preds maps
+// a task to the tasks that must finish before it starts.
+func buildSchedule(epicID string, start time.Time, tasks []*model.Issue, preds, external map[string][]string) (*model.Schedule, error) {
+	sched := &model.Schedule{
+		EpicID:       epicID,
+		Start:        start,
+		CriticalPath: []string{},
+		Tasks:        []model.ScheduledTask{},
+		Unestimated:  []string{},
+	}
+
+	byID := make(map[string]*model.ScheduledTask, len(tasks))
+	succs := make(map[string][]string)
+	indegree := make(map[string]int, len(tasks))
+	all := make([]*model.ScheduledTask, 0, len(tasks))
+	for _, t := range tasks {
+		st := &model.ScheduledTask{
+			ID:               t.ID,
+			Title:            t.Title,
+			Status:           t.Status,
+			Assignee:         t.Assignee,
+			DependsOn:        dedupeSorted(preds[t.ID]),
+			ExternalBlockers: dedupeSorted(external[t.ID]),
+		}
+		if t.EstimatedMinutes != nil {
+			st.EstimatedMinutes = *t.EstimatedMinutes
+		} else {
+			sched.Unestimated = append(sched.Unestimated, t.ID)
+		}
+		byID[t.ID] = st
+		all = append(all, st)
+	}
+	for _, st := range all {
+		indegree[st.ID] = len(st.DependsOn)
+		for _, p := range st.DependsOn {
+			succs[p] = append(succs[p], st.ID)
+		}
+	}
+	sort.Strings(sched.Unestimated)
+
+	// Forward pass in topological order (Kahn's algorithm, by ID for
+	// determinism).
+	var queue, order []string
+	for _, st := range all {
+		if indegree[st.ID] == 0 {
+			queue = append(queue, st.ID)
+		}
+	}
+	sort.Strings(queue)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		order = append(order, id)
+		st := byID[id]
+		for _, p := range st.DependsOn {
+			st.EarliestStart = max(st.EarliestStart, byID[p].EarliestFinish)
+		}
+		st.EarliestFinish = st.EarliestStart + st.EstimatedMinutes
+		sched.TotalMinutes = max(sched.TotalMinutes, st.EarliestFinish)
+
+		next := succs[id]
+		sort.Strings(next)
+		for _, s := range next {
+			if indegree[s]--; indegree[s] == 0 {
+				queue = append(queue, s)
+			}
+		}
+	}
+	if len(order) != len(all) {
+		var stuck []string
+		for _, st := range all {
+			if indegree[st.ID] > 0 {
+				stuck = append(stuck, st.ID)
+			}
+		}
+		sort.Strings(stuck)
+		return nil, fmt.Errorf("cannot schedule %s: dependency cycle among %s", epicID, strings.Join(stuck, ", "))
+	}
+
+	// Backward pass.
+	for i := len(order) - 1; i >= 0; i-- {
+		st := byID[order[i]]
+		st.LatestFinish = sched.TotalMinutes
+		for _, s := range succs[st.ID] {
+			st.LatestFinish = min(st.LatestFinish, byID[s].LatestStart)
+		}
+		st.LatestStart = st.LatestFinish - st.EstimatedMinutes
+		st.Slack = st.LatestStart - st.EarliestStart
+		st.Critical = st.Slack == 0
+	}
+
+	// Walk the critical path back from the task that finishes last. Only
+	// tasks nothing waits on qualify, so zero-minute tasks at the end of a
+	// chain stay on it.
+	var end *model.ScheduledTask
+	for _, id := range order {
+		st := byID[id]
+		if st.Critical && len(succs[id]) == 0 && st.EarliestFinish == sched.TotalMinutes && (end == nil || st.ID < end.ID) {
+			end = st
+		}
+	}
+	for cur := end; cur != nil; {
+		sched.CriticalPath = append(sched.CriticalPath, cur.ID)
+		var prev *model.ScheduledTask
+		for _, p := range cur.DependsOn {
+			if pt := byID[p]; pt.Critical && pt.EarliestFinish == cur.EarliestStart {
+				prev = pt
+				break
+			}
+		}
+		cur = prev
+	}
+	for i, j := 0, len(sched.CriticalPath)-1; i < j; i, j = i+1, j-1 {
+		sched.CriticalPath[i], sched.CriticalPath[j] = sched.CriticalPath[j], sched.CriticalPath[i]
+	}
+
+	sort.Slice(all, func(a, b int) bool {
+		if all[a].EarliestStart != all[b].EarliestStart {
+			return all[a].EarliestStart < all[b].EarliestStart
+		}
+		if all[a].Slack != all[b].Slack {
+			return all[a].Slack < all[b].Slack
+		}
+		return all[a].ID < all[b].ID
+	})
+	for _, st := range all {
+		sched.Tasks = append(sched.Tasks, *st)
+	}
+	sched.EarliestFinish = start.Add(time.Duration(sched.TotalMinutes) * time.Minute)
+	return sched, nil
+}
+
+func dedupeSorted(ids []string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	out := append([]string(nil), ids...)
+	sort.Strings(out)
+	n := 1
+	for _, id := range out[1:] {
+		if id != out[n-1] {
+			out[n] = id
+			n++
+		}
+	}
+	return out[:n]
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+func TestBuildSchedule(t *testing.T) {
+	task := func(id string, minutes int) *model.Issue {
+		i := &model.Issue{ID: id, Title: id, Status: model.StatusOpen}
+		if minutes > 0 {
+			i.EstimatedMinutes = &minutes
+		}
+		return i
+	}
+	// design(60) → api(120) → ui(30) → launch(0, unestimated)
+	//            ↘ docs(45) ─────────↗
+	tasks := []*model.Issue{
+		task("design", 60), task("api", 120), task("ui", 30), task("docs", 45), task("launch", 0),
+	}
+	preds := map[string][]string{
+		"api":    {"design"},
+		"ui":     {"api"},
+		"docs":   {"design", "design"},
+		"launch": {"ui", "docs"},
+	}
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	sched, err := buildSchedule("epic", start, tasks, preds, map[string][]string{"docs": {"ext-1"}})
+	if err != nil {
+		t.Fatalf("buildSchedule: %v", err)
+	}
+
+	if sched.TotalMinutes != 210 || !sched.EarliestFinish.Equal(start.Add(210*time.Minute)) {
+		t.Errorf("total = %d, finish = %v; want 210 minutes", sched.TotalMinutes, sched.EarliestFinish)
+	}
+	if want := []string{"design", "api", "ui", "launch"}; !reflect.DeepEqual(sched.CriticalPath, want) {
+		t.Errorf("critical path = %v, want %v", sched.CriticalPath, want)
+	}
+	if want := []string{"launch"}; !reflect.DeepEqual(sched.Unestimated, want) {
+		t.Errorf("unestimated = %v, want %v", sched.Unestimated, want)
+	}
+
+	byID := make(map[string]model.ScheduledTask)
+	for _, st := range sched.Tasks {
+		byID[st.ID] = st
+	}
+	docs := byID["docs"]
+	if docs.EarliestStart != 60 || docs.LatestStart != 165 || docs.Slack != 105 || docs.Critical {
+		t.Errorf("docs = %+v, want start 60, latest start 165, slack 105", docs)
+	}
+	if !reflect.DeepEqual(docs.DependsOn, []string{"design"}) || !reflect.DeepEqual(docs.ExternalBlockers, []string{"ext-1"}) {
+		t.Errorf("docs predecessors = %v, external = %v", docs.DependsOn, docs.ExternalBlockers)
+	}
+	if ui := byID["ui"]; ui.EarliestStart != 180 || ui.Slack != 0 || !ui.Critical {
+		t.Errorf("ui = %+v, want a critical task starting at 180", ui)
+	}
+	if sched.Tasks[0].ID != "design" {
+		t.Errorf("tasks should be in earliest-start order, got %s first", sched.Tasks[0].ID)
+	}
+
+	if _, err := buildSchedule("epic", start, []*model.Issue{task("a", 1), task("b", 1)},
+		map[string][]string{"a": {"b"}, "b": {"a"}}, nil); err == nil {
+		t.Error("a cycle should be reported")
+	}
+
+	empty, err := buildSchedule("epic", start, nil, nil, nil)
+	if err != nil || empty.TotalMinutes != 0 || len(empty.CriticalPath) != 0 {
+		t.Errorf("empty schedule = %+v, %v", empty, err)
+	}
+}
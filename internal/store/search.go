@@ -0,0 +1,293 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+const defaultSearchLimit = 50
+
+func searchLimit(limit int) int {
+	if limit <= 0 {
+		return defaultSearchLimit
+	}
+	return limit
+}
+
+// The memory and SQLite stores have no tsvector support, so they match and
+// rank in Go. The rules approximate PostgreSQL's websearch_to_tsquery and
+// ts_rank closely enough that the stores agree on which issues match and on
+// the relative order of obvious cases (title hits beat description hits).
+
+// Field weights follow ts_rank's defaults for the A-D labels in the pg index.
+const (
+	searchWeightTitle   = 1.0 // A
+	searchWeightDesc    = 0.4 // B
+	searchWeightDetail  = 0.2 // C: design, acceptance criteria, notes
+	searchWeightComment = 0.1 // D
+)
+
+// searchStopWords are dropped from queries, as the english text search
+// configuration does.
+var searchStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"so": true, "that": true, "the": true, "their": true, "then": true,
+	"there": true, "these": true, "this": true, "to": true, "was": true,
+	"will": true, "with": true,
+}
+
+// textQuery is a parsed web-search style query.
+type textQuery struct {
+	terms    []string   // every term must appear
+	phrases  [][]string // every phrase must appear as consecutive words
+	excluded []string   // none of these may appear
+}
+
+// searchField is one weighted piece of text in a search document.
+type searchField struct {
+	text   string
+	weight float64
+}
+
+func parseTextQuery(q string) textQuery {
+	var tq textQuery
+	for len(q) > 0 {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			break
+		}
+		if q[0] == '"' {
+			end := strings.IndexByte(q[1:], '"')
+			var phrase string
+			if end < 0 {
+				phrase, q = q[1:], ""
+			} else {
+				phrase, q = q[1:end+1], q[end+2:]
+			}
+			if words := searchTokens(phrase); len(words) == 1 {
+				tq.terms = append(tq.terms, words[0])
+			} else if len(words) > 1 {
+				tq.phrases = append(tq.phrases, words)
+			}
+			continue
+		}
+
+		word := q
+		if i := strings.IndexFunc(q, unicode.IsSpace); i >= 0 {
+			word, q = q[:i], q[i:]
+		} else {
+			q = ""
+		}
+		negate := strings.HasPrefix(word, "-")
+		for _, tok := range searchTokens(strings.TrimPrefix(word, "-")) {
+			if searchStopWords[tok] {
+				continue
+			}
+			if negate {
+				tq.excluded = append(tq.excluded, tok)
+			} else {
+				tq.terms = append(tq.terms, tok)
+			}
+		}
+	}
+	return tq
+}
+
+func (tq textQuery) empty() bool {
+	return len(tq.terms) == 0 && len(tq.phrases) == 0
+}
+
+// searchTokens lowercases s and splits it into words.
+func searchTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchStem strips common English suffixes so "migrations" matches
+// "migration" and "failing" matches "failed".
+func searchStem(w string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if len(w) > len(suffix)+2 && strings.HasSuffix(w, suffix) {
+			return strings.TrimSuffix(w, suffix)
+		}
+	}
+	return w
+}
+
+// tokenMatches reports whether tok is term or a close inflection of it.
+func tokenMatches(tok, term string) bool {
+	return tok == term || inflectionOf(tok, searchStem(term)) || inflectionOf(term, searchStem(tok))
+}
+
+// inflectionOf reports whether w is stem plus at most a short suffix.
+func inflectionOf(w, stem string) bool {
+	return strings.HasPrefix(w, stem) && len(w)-len(stem) <= 3
+}
+
+// hits counts occurrences of the query's terms and phrases in tokens.
+func (tq textQuery) hits(tokens []string) (total int, found map[int]bool) {
+	found = make(map[int]bool)
+	for ti, term := range tq.terms {
+		for _, tok := range tokens {
+			if tokenMatches(tok, term) {
+				total++
+				found[ti] = true
+			}
+		}
+	}
+	for pi, phrase := range tq.phrases {
+		for start := 0; start+len(phrase) <= len(tokens); start++ {
+			ok := true
+			for k, w := range phrase {
+				if !tokenMatches(tokens[start+k], w) {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				total += len(phrase)
+				found[len(tq.terms)+pi] = true
+			}
+		}
+	}
+	return total, found
+}
+
+func (tq textQuery) excludes(tokens []string) bool {
+	for _, ex := range tq.excluded {
+		for _, tok := range tokens {
+			if tokenMatches(tok, ex) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchDocument reports whether fields together satisfy the query and, if
+// so, their weighted rank.
+func (tq textQuery) matchDocument(fields []searchField) (float64, bool) {
+	found := make(map[int]bool)
+	var rank float64
+	for _, f := range fields {
+		tokens := searchTokens(f.text)
+		if tq.excludes(tokens) {
+			return 0, false
+		}
+		n, fieldFound := tq.hits(tokens)
+		for k := range fieldFound {
+			found[k] = true
+		}
+		rank += f.weight * float64(n)
+	}
+	if len(found) < len(tq.terms)+len(tq.phrases) {
+		return 0, false
+	}
+	return rank, true
+}
+
+// matchIssue scores an issue and its comments against the query. Like the
+// PostgreSQL store, the issue matches when its own fields match or when any
+// single comment does.
+func (tq textQuery) matchIssue(i *model.Issue, comments []string) (model.SearchResult, bool) {
+	fields := []searchField{
+		{i.Title, searchWeightTitle},
+		{i.Description, searchWeightDesc},
+		{i.Design, searchWeightDetail},
+		{i.AcceptanceCriteria, searchWeightDetail},
+		{i.Notes, searchWeightDetail},
+	}
+	rank, ok := tq.matchDocument(fields)
+
+	var matched []string
+	for _, c := range comments {
+		if r, cok := tq.matchDocument([]searchField{{c, searchWeightComment}}); cok {
+			matched = append(matched, c)
+			rank += r
+			ok = true
+		}
+	}
+	if !ok {
+		return model.SearchResult{}, false
+	}
+
+	var snippetFrom string
+	for _, f := range append(fields[1:len(fields):len(fields)], searchField{text: strings.Join(matched, " ")}) {
+		if n, _ := tq.hits(searchTokens(f.text)); n > 0 {
+			snippetFrom = f.text
+			break
+		}
+	}
+	if snippetFrom == "" {
+		snippetFrom = i.Title
+	}
+
+	return model.SearchResult{Issue: *i, Rank: rank, Snippet: tq.snippet(snippetFrom)}, true
+}
+
+// snippet returns a short window of text around the first match, with
+// matched words wrapped in model.SnippetMark.
+func (tq textQuery) snippet(text string) string {
+	const before, width = 8, 20
+
+	words := strings.Fields(text)
+	first := -1
+	marked := make([]string, len(words))
+	for k, w := range words {
+		marked[k] = w
+		if tq.wordMatches(w) {
+			marked[k] = model.SnippetMark + w + model.SnippetMark
+			if first < 0 {
+				first = k
+			}
+		}
+	}
+	if first < 0 {
+		first = 0
+	}
+
+	start := max(first-before, 0)
+	end := min(start+width, len(words))
+	out := strings.Join(marked[start:end], " ")
+	if start > 0 {
+		out = "… " + out
+	}
+	if end < len(words) {
+		out += " …"
+	}
+	return out
+}
+
+func (tq textQuery) wordMatches(word string) bool {
+	for _, tok := range searchTokens(word) {
+		for _, term := range tq.terms {
+			if tokenMatches(tok, term) {
+				return true
+			}
+		}
+		for _, phrase := range tq.phrases {
+			for _, w := range phrase {
+				if tokenMatches(tok, w) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// sortSearchResults orders results by rank, most recently updated first on ties.
+func sortSearchResults(results []model.SearchResult) {
+	sort.SliceStable(results, func(a, b int) bool {
+		if results[a].Rank != results[b].Rank {
+			return results[a].Rank > results[b].Rank
+		}
+		return results[a].Issue.UpdatedAt.After(results[b].Issue.UpdatedAt)
+	})
+}
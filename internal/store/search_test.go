@@ -0,0 +1,53 @@
+package store
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTextQuery(t *testing.T) {
+	tq := parseTextQuery(`Fix the "login timeout" -flaky`)
+	if !reflect.DeepEqual(tq.terms, []string{"fix"}) {
+		t.Errorf("terms = %v, want [fix] (stop words dropped)", tq.terms)
+	}
+	if !reflect.DeepEqual(tq.phrases, [][]string{{"login", "timeout"}}) {
+		t.Errorf("phrases = %v", tq.phrases)
+	}
+	if !reflect.DeepEqual(tq.excluded, []string{"flaky"}) {
+		t.Errorf("excluded = %v", tq.excluded)
+	}
+	if !parseTextQuery("the and of").empty() {
+		t.Error("a query of only stop words should be empty")
+	}
+}
+
+func TestTokenMatches(t *testing.T) {
+	for _, tc := range []struct {
+		tok, term string
+		want      bool
+	}{
+		{"migrations", "migration", true},
+		{"issue", "issues", true},
+		{"failed", "failing", true},
+		{"category", "cat", false},
+	} {
+		if got := tokenMatches(tc.tok, tc.term); got != tc.want {
+			t.Errorf("tokenMatches(%q, %q) = %v, want %v", tc.tok, tc.term, got, tc.want)
+		}
+	}
+}
+
+func TestSnippetWindow(t *testing.T) {
+	text := strings.Repeat("filler ", 30) + "the migration broke " + strings.Repeat("tail ", 30)
+	got := parseTextQuery("migration").snippet(text)
+	if !strings.HasPrefix(got, "… ") || !strings.HasSuffix(got, " …") {
+		t.Errorf("snippet should be elided on both sides: %q", got)
+	}
+	if !strings.Contains(got, "**migration**") {
+		t.Errorf("snippet should highlight the match: %q", got)
+	}
+	if n := len(strings.Fields(got)); n > 22 {
+		t.Errorf("snippet has %d words, want a short window", n)
+	}
+}
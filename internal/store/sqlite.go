@@ -0,0 +1,1416 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SqliteURLPrefix is the DATABASE_URL scheme that selects the SQLite store,
+// e.g. sqlite://doit.db or sqlite:///var/lib/doit/doit.db.
+const SqliteURLPrefix = "sqlite://"
+
+// ImplicitTenantID is the tenant seeded by the SQLite schema. SqliteStore
+// falls back to it when no tenant is in context, so a local database works
+// without any tenant or API key setup.
+var ImplicitTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// SqliteStore implements Store against a single SQLite database file.
+type SqliteStore struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+	idPrefix     string
+}
+
+// NewSqliteStore opens (creating if needed) the SQLite database at path and
+// applies any pending migrations.
+func NewSqliteStore(ctx context.Context, path string, queryTimeout time.Duration, idPrefix string) (*SqliteStore, error) {
+	db, err := openSqlite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("pinging database: %w", err)
+	}
+
+	if err := migrateSqlite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if idPrefix == "" {
+		idPrefix = "doit"
+	}
+
+	return &SqliteStore{db: db, queryTimeout: queryTimeout, idPrefix: idPrefix}, nil
+}
+
+// openSqlite opens the database with foreign keys on. SQLite allows a single
+// writer, so the pool is limited to one connection; this also keeps
+// ":memory:" databases alive across calls.
+func openSqlite(path string) (*sql.DB, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sqlite database path is empty")
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite3", path+sep+"_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// IsSqliteURL reports whether the URL selects the SQLite store.
+func IsSqliteURL(databaseURL string) bool {
+	return strings.HasPrefix(databaseURL, SqliteURLPrefix)
+}
+
+// SqlitePath extracts the file path from a sqlite:// URL.
+func SqlitePath(databaseURL string) string {
+	return strings.TrimPrefix(databaseURL, SqliteURLPrefix)
+}
+
+func (s *SqliteStore) Close() { s.db.Close() }
+
+func (s *SqliteStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// tenant returns the tenant from context, or the implicit tenant when none is set.
+func (s *SqliteStore) tenant(ctx context.Context) uuid.UUID {
+	if tid, ok := auth.TenantFromContext(ctx); ok {
+		return tid
+	}
+	return ImplicitTenantID
+}
+
+// GenerateID creates a hash-based issue ID with adaptive length.
+func (s *SqliteStore) GenerateID(ctx context.Context, prefix string) (string, error) {
+	if prefix == "" {
+		prefix = s.idPrefix
+	}
+	return s.generateID(ctx, "issues", prefix, "ID")
+}
+
+// generateID implements the shared hash-ID scheme against the given table.
+func (s *SqliteStore) generateID(ctx context.Context, table, prefix, what string) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return generateSqliteID(ctx, s.db, table, prefix, what)
+}
+
+// generateSqliteID is generateID against q. Callers holding a transaction
+// must pass it: the pool's only connection is busy until it ends.
+func generateSqliteID(ctx context.Context, q sqliteQuerier, table, prefix, what string) (string, error) {
+	var count int
+	if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+		return "", fmt.Errorf("counting %s: %w", table, err)
+	}
+
+	var lookupErr error
+	id, ok := generateHashID(prefix, count, func(id string) bool {
+		var exists bool
+		err := q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM "+table+" WHERE id = ?1)", id).Scan(&exists)
+		if err != nil {
+			lookupErr = err
+			return false
+		}
+		return exists
+	})
+	if lookupErr != nil {
+		return "", fmt.Errorf("checking %s uniqueness: %w", what, lookupErr)
+	}
+	if !ok {
+		return "", fmt.Errorf("failed to generate unique %s after 30 attempts", what)
+	}
+	return id, nil
+}
+
+// NextChildID returns the next hierarchical child ID for a parent.
+func (s *SqliteStore) NextChildID(ctx context.Context, parentID string) (string, error) {
+	if err := s.validateIssueOwnership(ctx, parentID); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return nextSqliteChildID(ctx, s.db, parentID)
+}
+
+func nextSqliteChildID(ctx context.Context, q sqliteQuerier, parentID string) (string, error) {
+	var lastChild int
+	err := q.QueryRowContext(ctx,
+		`INSERT INTO child_counters (parent_id, last_child)
+		 VALUES (?1, 1)
+		 ON CONFLICT (parent_id) DO UPDATE SET last_child = child_counters.last_child + 1
+		 RETURNING last_child`, parentID).Scan(&lastChild)
+	if err != nil {
+		return "", fmt.Errorf("incrementing child counter: %w", err)
+	}
+
+	return fmt.Sprintf("%s.%d", parentID, lastChild), nil
+}
+
+// CreateIssue inserts a new issue and optionally creates a parent-child dependency.
+func (s *SqliteStore) CreateIssue(ctx context.Context, input CreateIssueInput) (*model.Issue, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	issue, err := insertSqliteIssue(ctx, tx, tid, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+
+	return issue, nil
+}
+
+// insertSqliteIssue writes a new issue with its parent-child dependency,
+// labels and creation event using q, which is normally a transaction.
+func insertSqliteIssue(ctx context.Context, q sqliteQuerier, tid uuid.UUID, input CreateIssueInput) (*model.Issue, error) {
+	now := time.Now().UTC()
+
+	issue := &model.Issue{
+		ID:                 input.ID,
+		Title:              input.Title,
+		Description:        input.Description,
+		Design:             input.Design,
+		AcceptanceCriteria: input.AcceptanceCriteria,
+		Notes:              input.Notes,
+		Status:             input.Status,
+		Priority:           input.Priority,
+		IssueType:          input.IssueType,
+		Assignee:           input.Assignee,
+		Owner:              input.Owner,
+		CreatedAt:          now,
+		CreatedBy:          input.CreatedBy,
+		UpdatedAt:          now,
+		Ephemeral:          input.Ephemeral,
+		MolType:            input.MolType,
+		WorkType:           input.WorkType,
+		Crystallizes:       input.Crystallizes,
+		WispType:           input.WispType,
+		AgentState:         input.AgentState,
+		LastActivity:       input.LastActivity,
+		RoleType:           input.RoleType,
+		Rig:                input.Rig,
+		RoleBead:           input.RoleBead,
+		AwaitType:          input.AwaitType,
+		AwaitID:            input.AwaitID,
+		Timeout:            input.Timeout,
+		IsTemplate:         input.IsTemplate,
+		TenantID:           tid.String(),
+		ProjectID:          input.ProjectID,
+		EstimatedMinutes:   positiveOrNil(input.EstimatedMinutes),
+	}
+	issue.ContentHash = contentHash(issue)
+
+	_, err := q.ExecContext(ctx,
+		`INSERT INTO issues (id, content_hash, title, description, design, acceptance_criteria,
+		 notes, status, priority, issue_type, assignee, owner, created_at, created_by,
+		 updated_at, ephemeral, mol_type, work_type, crystallizes, wisp_type, tenant_id,
+		 project_id, estimated_minutes, agent_state, last_activity, role_type, rig, role_bead,
+		 await_type, await_id, timeout_ns, is_template)
+		 VALUES (?1,?2,?3,?4,?5,?6,?7,?8,?9,?10,?11,?12,?13,?14,?15,?16,?17,?18,?19,?20,?21,?22,?23,?24,?25,?26,?27,?28,?29,?30,?31,?32)`,
+		issue.ID, issue.ContentHash, issue.Title, issue.Description, issue.Design,
+		issue.AcceptanceCriteria, issue.Notes, issue.Status, issue.Priority,
+		issue.IssueType, nullEmpty(issue.Assignee), nullEmpty(issue.Owner),
+		issue.CreatedAt, nullEmpty(issue.CreatedBy), issue.UpdatedAt,
+		issue.Ephemeral, nullEmpty(string(issue.MolType)),
+		nullEmpty(string(issue.WorkType)), issue.Crystallizes, nullEmpty(string(issue.WispType)),
+		tid, nullEmpty(input.ProjectID), issue.EstimatedMinutes,
+		nullEmpty(string(issue.AgentState)), issue.LastActivity, nullEmpty(issue.RoleType),
+		nullEmpty(issue.Rig), nullEmpty(issue.RoleBead),
+		nullEmpty(issue.AwaitType), nullEmpty(issue.AwaitID), nullDuration(issue.Timeout),
+		issue.IsTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("inserting issue: %w", err)
+	}
+
+	if input.ParentID != "" {
+		_, err = q.ExecContext(ctx,
+			`INSERT INTO dependencies (issue_id, depends_on_id, type, created_at, created_by)
+			 VALUES (?1, ?2, 'parent-child', ?3, ?4)`,
+			input.ID, input.ParentID, now, nullEmpty(input.CreatedBy))
+		if err != nil {
+			return nil, fmt.Errorf("creating parent-child dependency: %w", err)
+		}
+		issue.ParentID = input.ParentID
+	}
+
+	for _, label := range input.Labels {
+		_, err = q.ExecContext(ctx,
+			`INSERT INTO labels (issue_id, label) VALUES (?1, ?2) ON CONFLICT DO NOTHING`,
+			input.ID, label)
+		if err != nil {
+			return nil, fmt.Errorf("adding label: %w", err)
+		}
+	}
+	issue.Labels = input.Labels
+
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO events (issue_id, event_type, actor, new_value, created_at)
+		 VALUES (?1, 'created', ?2, ?3, ?4)`,
+		issue.ID, eventActor(ctx, input.CreatedBy), issue.Title, now)
+	if err != nil {
+		return nil, fmt.Errorf("recording creation event: %w", err)
+	}
+
+	return issue, nil
+}
+
+// GetIssue retrieves an issue by ID with labels and parent.
+func (s *SqliteStore) GetIssue(ctx context.Context, id string) (*model.Issue, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	issue, err := scanSqliteIssue(s.db.QueryRowContext(ctx,
+		`SELECT `+issueColumns+` FROM issues WHERE id = ?1 AND tenant_id = ?2 AND deleted_at IS NULL`, id, tid))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("issue %s not found", id)
+		}
+		return nil, fmt.Errorf("getting issue %s: %w", id, err)
+	}
+
+	issue.Labels, err = s.queryLabels(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var parentID sql.NullString
+	err = s.db.QueryRowContext(ctx,
+		`SELECT d.depends_on_id FROM dependencies d
+		 JOIN issues p ON p.id = d.depends_on_id AND p.deleted_at IS NULL
+		 WHERE d.issue_id = ?1 AND d.type = 'parent-child'`, id).
+		Scan(&parentID)
+	if err == nil && parentID.Valid {
+		issue.ParentID = parentID.String
+	}
+
+	return issue, nil
+}
+
+// UpdateIssue applies partial updates to an issue.
+func (s *SqliteStore) UpdateIssue(ctx context.Context, id string, input UpdateIssueInput) (*model.Issue, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	issue, err := updateSqliteIssue(ctx, tx, tid, id, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+
+	return issue, nil
+}
+
+// updateSqliteIssue checks the expected version and applies input using q,
+// which must be a transaction for the check to be atomic with the write.
+func updateSqliteIssue(ctx context.Context, q sqliteQuerier, tid uuid.UUID, id string, input UpdateIssueInput) (*model.Issue, error) {
+	current, err := scanSqliteIssue(q.QueryRowContext(ctx,
+		"SELECT "+issueColumns+" FROM issues WHERE id = ?1 AND tenant_id = ?2 AND deleted_at IS NULL", id, tid))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("issue %s not found", id)
+		}
+		return nil, fmt.Errorf("loading issue %s: %w", id, err)
+	}
+	if err := checkExpectedVersion(current, input); err != nil {
+		return nil, err
+	}
+	if reopensCompacted(current, input) {
+		snaps, err := querySqliteSnapshots(ctx, q, id)
+		if err != nil {
+			return nil, err
+		}
+		if snap := snapshotFor(snaps, 0); snap != nil {
+			uncompactInput(snap, &input)
+		}
+	}
+
+	args := []any{time.Now().UTC(), updatedContentHash(current, input)}
+	argN := 2
+	sets := []string{"updated_at = ?1", "content_hash = ?2"}
+
+	addSet := func(col string, val any) {
+		argN++
+		sets = append(sets, fmt.Sprintf("%s = ?%d", col, argN))
+		args = append(args, val)
+	}
+
+	if input.Title != nil {
+		addSet("title", *input.Title)
+	}
+	if input.Description != nil {
+		addSet("description", *input.Description)
+	}
+	if input.Design != nil {
+		addSet("design", *input.Design)
+	}
+	if input.AcceptanceCriteria != nil {
+		addSet("acceptance_criteria", *input.AcceptanceCriteria)
+	}
+	if input.Notes != nil {
+		addSet("notes", *input.Notes)
+	}
+	if input.Status != nil {
+		addSet("status", string(*input.Status))
+		if *input.Status == model.StatusClosed {
+			addSet("closed_at", time.Now().UTC())
+		}
+	}
+	if input.Priority != nil {
+		addSet("priority", *input.Priority)
+	}
+	if input.Assignee != nil {
+		addSet("assignee", nullEmpty(*input.Assignee))
+	}
+	if input.Owner != nil {
+		addSet("owner", nullEmpty(*input.Owner))
+	}
+	if input.Pinned != nil {
+		addSet("pinned", *input.Pinned)
+	}
+	if input.Ephemeral != nil {
+		addSet("ephemeral", *input.Ephemeral)
+	}
+	if input.AgentState != nil {
+		addSet("agent_state", nullEmpty(string(*input.AgentState)))
+	}
+	if input.LastActivity != nil && input.Lease == nil { // a lease sets it to now below
+		addSet("last_activity", *input.LastActivity)
+	}
+	if input.RoleType != nil {
+		addSet("role_type", nullEmpty(*input.RoleType))
+	}
+	if input.Rig != nil {
+		addSet("rig", nullEmpty(*input.Rig))
+	}
+	if input.HookBead != nil {
+		addSet("hook_bead", nullEmpty(*input.HookBead))
+	}
+	if input.RoleBead != nil {
+		addSet("role_bead", nullEmpty(*input.RoleBead))
+	}
+	if input.AwaitID != nil {
+		addSet("await_id", nullEmpty(*input.AwaitID))
+	}
+	if input.IsTemplate != nil {
+		addSet("is_template", *input.IsTemplate)
+	}
+	if input.ExternalRef != nil {
+		addSet("external_ref", nullEmpty(*input.ExternalRef))
+	}
+	if input.EstimatedMinutes != nil {
+		addSet("estimated_minutes", positiveOrNil(input.EstimatedMinutes))
+	}
+	if input.CloseReason != nil {
+		addSet("close_reason", *input.CloseReason)
+	}
+	if input.CompactionLevel != nil {
+		addSet("compaction_level", *input.CompactionLevel)
+		if *input.CompactionLevel == 0 {
+			sets = append(sets, "compacted_at = NULL")
+		}
+	}
+	if input.Lease != nil {
+		now := time.Now().UTC()
+		addSet("lease_expires_at", leaseExpiry(now, *input.Lease))
+		addSet("last_activity", now)
+	} else if input.Status != nil && *input.Status != model.StatusInProgress {
+		// Leaving in_progress ends the claim.
+		sets = append(sets, "lease_expires_at = NULL")
+	}
+
+	argN++
+	args = append(args, id)
+	idArg := argN
+	argN++
+	args = append(args, tid)
+	query := fmt.Sprintf("UPDATE issues SET %s WHERE id = ?%d AND tenant_id = ?%d RETURNING %s",
+		strings.Join(sets, ", "), idArg, argN, issueColumns)
+
+	issue, err := scanSqliteIssue(q.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return nil, fmt.Errorf("updating issue %s: %w", id, err)
+	}
+	if err := insertSqliteEvents(ctx, q, issueEvents(current, issue, eventActor(ctx, ""))); err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+// ListIssues returns issues matching the filter.
+func (s *SqliteStore) ListIssues(ctx context.Context, filter model.IssueFilter) ([]model.Issue, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + issueColumns + " FROM issues WHERE tenant_id = ?1 AND deleted_at IS NULL"
+	args := []any{tid}
+	argN := 1
+
+	addWhere := func(clause string, val any) {
+		argN++
+		query += " AND " + strings.ReplaceAll(clause, "?N", fmt.Sprintf("?%d", argN))
+		args = append(args, val)
+	}
+
+	if filter.Status != nil {
+		addWhere("status = ?N", string(*filter.Status))
+	}
+	if len(filter.StatusNot) > 0 {
+		strs := make([]string, len(filter.StatusNot))
+		for i, st := range filter.StatusNot {
+			strs[i] = string(st)
+		}
+		var in string
+		in, args, argN = sqliteInList(args, argN, strs)
+		query += " AND status NOT IN (" + in + ")"
+	}
+	if filter.Priority != nil {
+		addWhere("priority = ?N", *filter.Priority)
+	}
+	if filter.IssueType != nil {
+		addWhere("issue_type = ?N", string(*filter.IssueType))
+	}
+	if filter.Assignee != nil {
+		addWhere("assignee = ?N", *filter.Assignee)
+	}
+	if filter.Owner != nil {
+		addWhere("owner = ?N", *filter.Owner)
+	}
+	if filter.Ephemeral != nil {
+		addWhere("ephemeral = ?N", *filter.Ephemeral)
+	}
+	if filter.Pinned != nil {
+		addWhere("pinned = ?N", *filter.Pinned)
+	}
+	if filter.Search != nil {
+		addWhere("(title LIKE '%' || ?N || '%' OR description LIKE '%' || ?N || '%')", *filter.Search)
+	}
+	if filter.ParentID != nil {
+		addWhere("id IN (SELECT issue_id FROM dependencies WHERE depends_on_id = ?N AND type = 'parent-child')", *filter.ParentID)
+	}
+	if filter.ProjectID != nil {
+		addWhere("project_id = ?N", *filter.ProjectID)
+	}
+
+	query, args, argN = addSqliteProjectFilter(ctx, query, args, argN, "project_id")
+
+	keys := issueKeyset(filter.SortBy)
+	offset := filter.Offset
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, issueSortKey(filter.SortBy))
+		if err != nil {
+			return nil, err
+		}
+		var after string
+		after, args, argN = keys.after(cur, "?%d", args, argN)
+		query += " AND " + after
+		offset = 0
+	}
+	query += keys.orderBy()
+
+	if filter.Limit > 0 || offset > 0 {
+		limit := filter.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		argN++
+		query += fmt.Sprintf(" LIMIT ?%d", argN)
+		args = append(args, limit)
+		argN++
+		query += fmt.Sprintf(" OFFSET ?%d", argN)
+		args = append(args, offset)
+	}
+
+	return s.scanIssues(ctx, query, args...)
+}
+
+// ListReady returns the tenant's issues from sqliteReadyIssues.
+func (s *SqliteStore) ListReady(ctx context.Context, filter model.IssueFilter) ([]model.Issue, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := sqliteReadyIssues + "SELECT " + issueColumns + " FROM ready_issues WHERE tenant_id = ?1"
+	args := []any{tid}
+	argN := 1
+
+	if filter.IssueType != nil {
+		argN++
+		query += fmt.Sprintf(" AND issue_type = ?%d", argN)
+		args = append(args, string(*filter.IssueType))
+	}
+	if filter.Priority != nil {
+		argN++
+		query += fmt.Sprintf(" AND priority = ?%d", argN)
+		args = append(args, *filter.Priority)
+	}
+	if filter.Assignee != nil {
+		argN++
+		query += fmt.Sprintf(" AND assignee = ?%d", argN)
+		args = append(args, *filter.Assignee)
+	}
+	if filter.ProjectID != nil {
+		argN++
+		query += fmt.Sprintf(" AND project_id = ?%d", argN)
+		args = append(args, *filter.ProjectID)
+	}
+
+	query, args, argN = addSqliteProjectFilter(ctx, query, args, argN, "project_id")
+
+	keys := issueKeyset(ReadySortBy)
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, ReadySortBy)
+		if err != nil {
+			return nil, err
+		}
+		var after string
+		after, args, argN = keys.after(cur, "?%d", args, argN)
+		query += " AND " + after
+	}
+	query += keys.orderBy()
+
+	if filter.Limit > 0 {
+		argN++
+		query += fmt.Sprintf(" LIMIT ?%d", argN)
+		args = append(args, filter.Limit)
+	}
+
+	return s.scanIssues(ctx, query, args...)
+}
+
+// ClaimNextReady atomically assigns the next ready issue to the claimant.
+// SQLite serializes writers, so selecting and updating in one statement is
+// enough to keep two claims from returning the same issue.
+func (s *SqliteStore) ClaimNextReady(ctx context.Context, input ClaimNextReadyInput) (*model.Issue, error) {
+	tid := s.tenant(ctx)
+	if input.Claimant == "" {
+		return nil, fmt.Errorf("claimant is required")
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	args := []any{tid}
+	argN := 1
+
+	sub := sqliteReadyIssues + "SELECT id FROM ready_issues WHERE tenant_id = ?1"
+	if input.IssueType != nil {
+		argN++
+		sub += fmt.Sprintf(" AND issue_type = ?%d", argN)
+		args = append(args, string(*input.IssueType))
+	}
+	if input.ProjectID != nil {
+		argN++
+		sub += fmt.Sprintf(" AND project_id = ?%d", argN)
+		args = append(args, *input.ProjectID)
+	}
+	for _, label := range input.Labels {
+		argN++
+		sub += fmt.Sprintf(
+			" AND EXISTS (SELECT 1 FROM labels l WHERE l.issue_id = ready_issues.id AND l.label = ?%d)", argN)
+		args = append(args, label)
+	}
+	sub, args, _ = addSqliteProjectFilter(ctx, sub, args, argN, "project_id")
+	sub += issueKeyset(ReadySortBy).orderBy() + " LIMIT 1"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := scanSqliteIssue(tx.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT %s FROM issues WHERE id = (%s)", issueColumns, sub), args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("selecting ready issue: %w", err)
+	}
+
+	now := time.Now().UTC()
+	issue, err := scanSqliteIssue(tx.QueryRowContext(ctx,
+		`UPDATE issues SET status = ?1, assignee = ?2, updated_at = ?3, last_activity = ?3, lease_expires_at = ?4
+		 WHERE id = ?5 RETURNING `+issueColumns,
+		string(model.StatusInProgress), input.Claimant, now, leaseExpiry(now, input.Lease), current.ID))
+	if err != nil {
+		return nil, fmt.Errorf("claiming issue %s: %w", current.ID, err)
+	}
+
+	if err := insertSqliteEvents(ctx, tx, issueEvents(current, issue, eventActor(ctx, input.Claimant))); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+
+	issue.Labels, err = s.queryLabels(ctx, issue.ID)
+	if err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+// --- Dependencies ---
+
+func (s *SqliteStore) AddDependency(ctx context.Context, input AddDependencyInput) (*model.Dependency, error) {
+	if err := s.validateIssueOwnership(ctx, input.IssueID); err != nil {
+		return nil, err
+	}
+	if err := s.validateIssueOwnership(ctx, input.DependsOnID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	dep, err := addCheckedSqliteDependency(ctx, tx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return dep, nil
+}
+
+// addCheckedSqliteDependency upserts input unless it would close a blocking
+// or hierarchical cycle. The pool's single connection serializes writers,
+// so running the check and insert on one transaction makes them atomic.
+func addCheckedSqliteDependency(ctx context.Context, q sqliteQuerier, input AddDependencyInput) (*model.Dependency, error) {
+	err := checkCycle(input, func(ids, types []string) (map[string][]string, error) {
+		return querySqliteEdges(ctx, q, ids, types)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return insertSqliteDependency(ctx, q, input)
+}
+
+// querySqliteEdges is the SQLite counterpart of queryEdges.
+func querySqliteEdges(ctx context.Context, q sqliteQuerier, ids, types []string) (map[string][]string, error) {
+	idList, args, argN := sqliteInList(nil, 0, ids)
+	typeList, args, _ := sqliteInList(args, argN, types)
+	rows, err := q.QueryContext(ctx,
+		"SELECT issue_id, depends_on_id FROM dependencies WHERE issue_id IN ("+idList+") AND type IN ("+typeList+") AND "+liveEdge,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edges := make(map[string][]string)
+	for rows.Next() {
+		var from, to string
+		if err := rows.Scan(&from, &to); err != nil {
+			return nil, err
+		}
+		edges[from] = append(edges[from], to)
+	}
+	return edges, rows.Err()
+}
+
+// ValidateGraph reports cycles, orphaned children and dangling references
+// in the tenant's dependency graph.
+func (s *SqliteStore) ValidateGraph(ctx context.Context) (*model.GraphReport, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM issues WHERE tenant_id = ?1 AND deleted_at IS NULL", tid)
+	if err != nil {
+		return nil, fmt.Errorf("listing issues: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning issue id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx,
+		`SELECT issue_id, depends_on_id, type, created_at, created_by, metadata, thread_id
+		 FROM dependencies
+		 WHERE (issue_id IN (SELECT id FROM issues WHERE tenant_id = ?1)
+		    OR depends_on_id IN (SELECT id FROM issues WHERE tenant_id = ?1))
+		   AND `+liveEdge, tid)
+	if err != nil {
+		return nil, fmt.Errorf("listing dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	var deps []model.Dependency
+	for rows.Next() {
+		var d model.Dependency
+		var metadata []byte
+		err := rows.Scan(&d.IssueID, &d.DependsOnID, &d.Type, &d.CreatedAt, &ns{&d.CreatedBy}, &metadata, &ns{&d.ThreadID})
+		if err != nil {
+			return nil, fmt.Errorf("scanning dependency: %w", err)
+		}
+		d.Metadata = metadata
+		deps = append(deps, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return analyzeGraph(tid.String(), ids, deps), nil
+}
+
+func insertSqliteDependency(ctx context.Context, q sqliteQuerier, input AddDependencyInput) (*model.Dependency, error) {
+	dep := &model.Dependency{
+		IssueID:     input.IssueID,
+		DependsOnID: input.DependsOnID,
+		Type:        input.Type,
+		CreatedAt:   time.Now().UTC(),
+		CreatedBy:   input.CreatedBy,
+		ThreadID:    input.ThreadID,
+	}
+
+	var oldType model.DependencyType
+	err := q.QueryRowContext(ctx,
+		"SELECT type FROM dependencies WHERE issue_id = ?1 AND depends_on_id = ?2",
+		dep.IssueID, dep.DependsOnID).Scan(&oldType)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("adding dependency: %w", err)
+	}
+	if oldType == dep.Type {
+		return dep, nil
+	}
+
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO dependencies (issue_id, depends_on_id, type, created_at, created_by, thread_id)
+		 VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+		 ON CONFLICT (issue_id, depends_on_id) DO UPDATE SET type = ?3`,
+		dep.IssueID, dep.DependsOnID, dep.Type, dep.CreatedAt,
+		nullEmpty(dep.CreatedBy), nullEmpty(dep.ThreadID))
+	if err != nil {
+		return nil, fmt.Errorf("adding dependency: %w", err)
+	}
+
+	e := dependencyEvent(*dep, oldType, true, eventActor(ctx, dep.CreatedBy))
+	if err := insertSqliteEvents(ctx, q, []AddEventInput{e}); err != nil {
+		return nil, err
+	}
+	return dep, nil
+}
+
+func (s *SqliteStore) RemoveDependency(ctx context.Context, issueID, dependsOnID string) error {
+	if err := s.validateIssueOwnership(ctx, issueID); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	dep := model.Dependency{IssueID: issueID, DependsOnID: dependsOnID}
+	err = tx.QueryRowContext(ctx,
+		"DELETE FROM dependencies WHERE issue_id = ?1 AND depends_on_id = ?2 RETURNING type",
+		issueID, dependsOnID).Scan(&dep.Type)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("removing dependency: %w", err)
+	}
+	if err := insertSqliteEvents(ctx, tx, []AddEventInput{dependencyEvent(dep, "", false, eventActor(ctx, ""))}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SqliteStore) ListDependencies(ctx context.Context, issueID string, direction string) ([]model.Dependency, error) {
+	if err := s.validateIssueOwnership(ctx, issueID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT issue_id, depends_on_id, type, created_at, created_by, metadata, thread_id FROM dependencies `
+	switch direction {
+	case "upstream":
+		query += "WHERE issue_id = ?1"
+	case "downstream":
+		query += "WHERE depends_on_id = ?1"
+	default: // both
+		query += "WHERE (issue_id = ?1 OR depends_on_id = ?1)"
+	}
+	query += " AND " + liveEdge
+
+	rows, err := s.db.QueryContext(ctx, query, issueID)
+	if err != nil {
+		return nil, fmt.Errorf("listing dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	var deps []model.Dependency
+	for rows.Next() {
+		var d model.Dependency
+		var metadata []byte
+		err := rows.Scan(&d.IssueID, &d.DependsOnID, &d.Type, &d.CreatedAt, &ns{&d.CreatedBy}, &metadata, &ns{&d.ThreadID})
+		if err != nil {
+			return nil, fmt.Errorf("scanning dependency: %w", err)
+		}
+		d.Metadata = metadata
+		deps = append(deps, d)
+	}
+
+	return deps, rows.Err()
+}
+
+func (s *SqliteStore) GetDependencyTree(ctx context.Context, rootID string, maxDepth int) ([]model.TreeNode, error) {
+	if err := s.validateIssueOwnership(ctx, rootID); err != nil {
+		return nil, err
+	}
+
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT id AS issue_id, 0 AS depth
+			FROM issues WHERE id = ?1 AND tenant_id = ?3
+			UNION ALL
+			SELECT i.id, t.depth + 1
+			FROM issues i
+			JOIN dependencies d ON d.issue_id = i.id AND d.type = 'parent-child'
+			JOIN tree t ON d.depends_on_id = t.issue_id
+			WHERE t.depth < ?2 AND i.tenant_id = ?3 AND i.deleted_at IS NULL
+		)
+		SELECT t.depth, `+prefixedIssueColumns("i")+`
+		FROM tree t
+		JOIN issues i ON i.id = t.issue_id
+		ORDER BY t.depth, i.priority, i.created_at`, rootID, maxDepth, tid)
+	if err != nil {
+		return nil, fmt.Errorf("walking dependency tree: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []model.TreeNode
+	for rows.Next() {
+		var depth int
+		issue, err := scanSqliteIssue(rows, &depth)
+		if err != nil {
+			return nil, fmt.Errorf("scanning issue: %w", err)
+		}
+		nodes = append(nodes, model.TreeNode{
+			Issue: *issue,
+			Depth: depth,
+		})
+	}
+
+	return nodes, rows.Err()
+}
+
+// --- Labels ---
+
+func (s *SqliteStore) AddLabel(ctx context.Context, issueID, label string) error {
+	if err := s.validateIssueOwnership(ctx, issueID); err != nil {
+		return err
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertSqliteLabel(ctx, tx, issueID, label); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertSqliteLabel(ctx context.Context, q sqliteQuerier, issueID, label string) error {
+	res, err := q.ExecContext(ctx,
+		"INSERT INTO labels (issue_id, label) VALUES (?1, ?2) ON CONFLICT DO NOTHING",
+		issueID, label)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	return insertSqliteEvents(ctx, q, []AddEventInput{labelEvent(issueID, label, true, eventActor(ctx, ""))})
+}
+
+func (s *SqliteStore) RemoveLabel(ctx context.Context, issueID, label string) error {
+	if err := s.validateIssueOwnership(ctx, issueID); err != nil {
+		return err
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"DELETE FROM labels WHERE issue_id = ?1 AND label = ?2", issueID, label)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if err := insertSqliteEvents(ctx, tx, []AddEventInput{labelEvent(issueID, label, false, eventActor(ctx, ""))}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SqliteStore) ListLabels(ctx context.Context, issueID string) ([]string, error) {
+	if err := s.validateIssueOwnership(ctx, issueID); err != nil {
+		return nil, err
+	}
+	return s.queryLabels(ctx, issueID)
+}
+
+// --- Comments ---
+
+func (s *SqliteStore) AddComment(ctx context.Context, issueID, author, text string) (*model.Comment, error) {
+	if err := s.validateIssueOwnership(ctx, issueID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	c, err := insertSqliteComment(ctx, tx, issueID, author, text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return c, nil
+}
+
+func insertSqliteComment(ctx context.Context, q sqliteQuerier, issueID, author, text string) (*model.Comment, error) {
+	c := &model.Comment{}
+	err := q.QueryRowContext(ctx,
+		`INSERT INTO comments (issue_id, author, text, created_at) VALUES (?1, ?2, ?3, ?4)
+		 RETURNING id, issue_id, author, text, created_at`,
+		issueID, author, text, time.Now().UTC()).
+		Scan(&c.ID, &c.IssueID, &c.Author, &c.Text, &c.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("adding comment: %w", err)
+	}
+	if err := insertSqliteEvents(ctx, q, []AddEventInput{commentEvent(c, eventActor(ctx, author))}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *SqliteStore) ListComments(ctx context.Context, issueID string) ([]model.Comment, error) {
+	if err := s.validateIssueOwnership(ctx, issueID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, issue_id, author, text, created_at FROM comments WHERE issue_id = ?1 ORDER BY created_at, id",
+		issueID)
+	if err != nil {
+		return nil, fmt.Errorf("listing comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []model.Comment
+	for rows.Next() {
+		var c model.Comment
+		if err := rows.Scan(&c.ID, &c.IssueID, &c.Author, &c.Text, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// --- Events ---
+
+func (s *SqliteStore) AddEvent(ctx context.Context, input AddEventInput) (*model.Event, error) {
+	if err := s.validateIssueOwnership(ctx, input.IssueID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	e := &model.Event{}
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO events (issue_id, event_type, actor, field, old_value, new_value, comment, created_at)
+		 VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+		 RETURNING `+eventColumns,
+		input.IssueID, input.EventType, eventActor(ctx, input.Actor), nullEmpty(input.Field),
+		nullEmpty(input.OldValue), nullEmpty(input.NewValue), nullEmpty(input.Comment), time.Now().UTC()).
+		Scan(&e.ID, &e.IssueID, &e.EventType, &e.Actor, &ns{&e.Field}, &ns{&e.OldValue}, &ns{&e.NewValue}, &ns{&e.Comment}, &e.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("adding event: %w", err)
+	}
+	return e, nil
+}
+
+func (s *SqliteStore) ListEvents(ctx context.Context, issueID string, limit int) ([]model.Event, error) {
+	if err := s.validateIssueOwnership(ctx, issueID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + eventColumns + " FROM events WHERE issue_id = ?1 ORDER BY created_at DESC, id DESC"
+	args := []any{issueID}
+	if limit > 0 {
+		query += " LIMIT ?2"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []model.Event
+	for rows.Next() {
+		var e model.Event
+		if err := rows.Scan(&e.ID, &e.IssueID, &e.EventType, &e.Actor, &ns{&e.Field}, &ns{&e.OldValue}, &ns{&e.NewValue}, &ns{&e.Comment}, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// insertSqliteEvents is the SQLite counterpart of insertEvents.
+func insertSqliteEvents(ctx context.Context, q sqliteQuerier, events []AddEventInput) error {
+	now := time.Now().UTC()
+	for _, e := range events {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO events (issue_id, event_type, actor, field, old_value, new_value, comment, created_at)
+			 VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)`,
+			e.IssueID, e.EventType, e.Actor, nullEmpty(e.Field),
+			nullEmpty(e.OldValue), nullEmpty(e.NewValue), nullEmpty(e.Comment), now)
+		if err != nil {
+			return fmt.Errorf("recording %s event: %w", e.EventType, err)
+		}
+	}
+	return nil
+}
+
+// --- Compaction ---
+
+func (s *SqliteStore) SaveCompactionSnapshot(ctx context.Context, issueID string, level int, summary, original string, content UpdateIssueInput) error {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := updateSqliteIssue(ctx, tx, tid, issueID, content); err != nil {
+		return err
+	}
+	var oldLevel int
+	err = tx.QueryRowContext(ctx,
+		"SELECT compaction_level FROM issues WHERE id = ?1", issueID).Scan(&oldLevel)
+	if err != nil {
+		return fmt.Errorf("reading compaction level: %w", err)
+	}
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO compaction_snapshots (issue_id, level, from_level, summary, original, created_at) VALUES (?1, ?2, ?3, ?4, ?5, ?6)`,
+		issueID, level, oldLevel, summary, original, now)
+	if err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE issues SET compaction_level = ?2, compacted_at = ?3 WHERE id = ?1", issueID, level, now)
+	if err != nil {
+		return fmt.Errorf("recording compaction level: %w", err)
+	}
+	if err := insertSqliteEvents(ctx, tx, []AddEventInput{compactedEvent(issueID, oldLevel, level, eventActor(ctx, ""))}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SqliteStore) GetCompactionSnapshots(ctx context.Context, issueID string) ([]model.CompactionSnapshot, error) {
+	if err := s.validateIssueOwnership(ctx, issueID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return querySqliteSnapshots(ctx, s.db, issueID)
+}
+
+func querySqliteSnapshots(ctx context.Context, q sqliteQuerier, issueID string) ([]model.CompactionSnapshot, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT id, issue_id, level, from_level, summary, original, created_at FROM compaction_snapshots WHERE issue_id = ?1 ORDER BY level, id",
+		issueID)
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snaps []model.CompactionSnapshot
+	for rows.Next() {
+		var snap model.CompactionSnapshot
+		if err := rows.Scan(&snap.ID, &snap.IssueID, &snap.Level, &snap.FromLevel, &snap.Summary, &snap.Original, &snap.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning snapshot: %w", err)
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, rows.Err()
+}
+
+// --- Aggregation ---
+
+func (s *SqliteStore) CountIssuesByStatus(ctx context.Context) (map[string]int, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT status, COUNT(*) FROM issues WHERE tenant_id = ?1 AND deleted_at IS NULL"
+	args := []any{tid}
+	query, args, _ = addSqliteProjectFilter(ctx, query, args, 1, "project_id")
+	query += " GROUP BY status"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("counting issues by status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("scanning count: %w", err)
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// --- Helpers ---
+
+// validateIssueOwnership checks that the issue belongs to the tenant in context.
+// Returns "not found" (not "access denied") to avoid leaking existence.
+func (s *SqliteStore) validateIssueOwnership(ctx context.Context, issueID string) error {
+	return checkSqliteIssueOwned(ctx, s.db, s.tenant(ctx), issueID)
+}
+
+func checkSqliteIssueOwned(ctx context.Context, q sqliteQuerier, tid uuid.UUID, issueID string) error {
+	var exists bool
+	err := q.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM issues WHERE id = ?1 AND tenant_id = ?2 AND deleted_at IS NULL)",
+		issueID, tid).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("checking issue ownership: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("issue %s not found", issueID)
+	}
+	return nil
+}
+
+// sqliteQuerier is satisfied by both *sql.DB and *sql.Tx.
+type sqliteQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqliteRow is satisfied by both *sql.Row and *sql.Rows.
+type sqliteRow interface {
+	Scan(dest ...any) error
+}
+
+func scanSqliteIssue(row sqliteRow, extraFields ...any) (*model.Issue, error) {
+	var i model.Issue
+	var metadata []byte
+
+	scanArgs := make([]any, 0, len(extraFields)+59)
+	scanArgs = append(scanArgs, extraFields...)
+	scanArgs = append(scanArgs,
+		&i.ID, &ns{&i.ContentHash}, &i.Title, &i.Description, &i.Design,
+		&i.AcceptanceCriteria, &i.Notes, &ns{(*string)(&i.SpecID)}, &i.Status, &i.Priority,
+		&ns{(*string)(&i.IssueType)}, &ns{&i.Assignee}, &ns{&i.Owner}, &i.EstimatedMinutes,
+		&i.CreatedAt, &ns{&i.CreatedBy}, &i.UpdatedAt, &i.ClosedAt, &i.DueAt, &i.DeferUntil,
+		&ns{&i.CloseReason}, &ns{&i.ClosedBySession}, &i.ExternalRef, &ns{&i.SourceSystem}, &ns{&i.SourceRepo},
+		&metadata, &i.CompactionLevel, &i.CompactedAt, &i.CompactedAtCommit, &i.OriginalSize,
+		&ns{&i.Sender}, &i.Ephemeral, &ns{(*string)(&i.MolType)}, &ns{(*string)(&i.WorkType)}, &i.Crystallizes, &ns{(*string)(&i.WispType)},
+		&i.Pinned, &i.IsTemplate, &i.QualityScore, &ns{&i.EventKind}, &ns{&i.Actor}, &ns{&i.Target}, &ns{&i.Payload},
+		&ns{&i.AwaitType}, &ns{&i.AwaitID}, &ni64{(*int64)(&i.Timeout)}, &ns{(*string)(&i.AgentState)}, &i.LastActivity, &ns{&i.RoleType}, &ns{&i.Rig},
+		&ns{&i.HookBead}, &ns{&i.RoleBead}, &ns{&i.TenantID}, &ns{&i.ProjectID}, &i.LeaseExpiresAt,
+		&i.DeletedAt, &ns{&i.DeletedBy},
+	)
+
+	if err := row.Scan(scanArgs...); err != nil {
+		return nil, err
+	}
+	i.Metadata = metadata
+	return &i, nil
+}
+
+func (s *SqliteStore) scanIssues(ctx context.Context, query string, args ...any) ([]model.Issue, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying issues: %w", err)
+	}
+	defer rows.Close()
+
+	issues := []model.Issue{}
+	for rows.Next() {
+		issue, err := scanSqliteIssue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning issue: %w", err)
+		}
+		issues = append(issues, *issue)
+	}
+	return issues, rows.Err()
+}
+
+func (s *SqliteStore) queryLabels(ctx context.Context, issueID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT label FROM labels WHERE issue_id = ?1 ORDER BY label", issueID)
+	if err != nil {
+		return nil, fmt.Errorf("querying labels: %w", err)
+	}
+	defer rows.Close()
+
+	var labels []string
+	for rows.Next() {
+		var l string
+		if err := rows.Scan(&l); err != nil {
+			return nil, err
+		}
+		labels = append(labels, l)
+	}
+	return labels, rows.Err()
+}
+
+// prefixedIssueColumns qualifies issueColumns with a table alias.
+func prefixedIssueColumns(alias string) string {
+	cols := strings.Split(issueColumns, ",")
+	for i, c := range cols {
+		cols[i] = alias + "." + strings.TrimSpace(c)
+	}
+	return strings.Join(cols, ", ")
+}
+
+// sqliteInList appends values as numbered parameters and returns the
+// comma-separated placeholder list for an IN (...) clause.
+func sqliteInList(args []any, argN int, values []string) (string, []any, int) {
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		argN++
+		placeholders[i] = fmt.Sprintf("?%d", argN)
+		args = append(args, v)
+	}
+	return strings.Join(placeholders, ", "), args, argN
+}
+
+// addSqliteProjectFilter is the SQLite counterpart of addProjectFilter.
+func addSqliteProjectFilter(ctx context.Context, query string, args []any, argN int, column string) (string, []any, int) {
+	projectIDs := auth.AllowedProjectsFromContext(ctx)
+	if len(projectIDs) == 0 {
+		return query, args, argN
+	}
+	var in string
+	in, args, argN = sqliteInList(args, argN, projectIDs)
+	query += fmt.Sprintf(" AND %s IN (%s)", column, in)
+	return query, args, argN
+}
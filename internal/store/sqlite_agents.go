@@ -0,0 +1,29 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// FindAgentBead returns the live agent bead titled name, or nil.
+func (s *SqliteStore) FindAgentBead(ctx context.Context, name string) (*model.Issue, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	bead, err := scanSqliteIssue(s.db.QueryRowContext(ctx,
+		"SELECT "+issueColumns+" FROM issues WHERE tenant_id = ?1 AND issue_type = ?2 AND title = ?3 AND deleted_at IS NULL",
+		tid, model.TypeAgent, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding agent %s: %w", name, err)
+	}
+	return bead, nil
+}
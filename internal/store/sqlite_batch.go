@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+)
+
+// ApplyBatch runs ops in order inside one transaction. Either every op is
+// applied or, on the first failure, none are.
+func (s *SqliteStore) ApplyBatch(ctx context.Context, ops []BatchOp) ([]BatchResult, error) {
+	if err := validateBatch(ops); err != nil {
+		return nil, err
+	}
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results, err := applyBatch(ops, &sqliteBatch{s: s, ctx: ctx, tx: tx, tid: tid})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return results, nil
+}
+
+// sqliteBatch runs batch operations on an open transaction. Everything,
+// ID generation included, must go through tx: it holds the pool's only
+// connection.
+type sqliteBatch struct {
+	s   *SqliteStore
+	ctx context.Context
+	tx  *sql.Tx
+	tid uuid.UUID
+}
+
+func (b *sqliteBatch) checkOwned(issueID string) error {
+	return checkSqliteIssueOwned(b.ctx, b.tx, b.tid, issueID)
+}
+
+func (b *sqliteBatch) generateID() (string, error) {
+	return generateSqliteID(b.ctx, b.tx, "issues", b.s.idPrefix, "ID")
+}
+
+func (b *sqliteBatch) nextChildID(parentID string) (string, error) {
+	return nextSqliteChildID(b.ctx, b.tx, parentID)
+}
+
+func (b *sqliteBatch) createIssue(input CreateIssueInput) (*model.Issue, error) {
+	return insertSqliteIssue(b.ctx, b.tx, b.tid, input)
+}
+
+func (b *sqliteBatch) updateIssue(id string, input UpdateIssueInput) (*model.Issue, error) {
+	return updateSqliteIssue(b.ctx, b.tx, b.tid, id, input)
+}
+
+func (b *sqliteBatch) addDependency(input AddDependencyInput) (*model.Dependency, error) {
+	return addCheckedSqliteDependency(b.ctx, b.tx, input)
+}
+
+func (b *sqliteBatch) addLabel(issueID, label string) error {
+	return insertSqliteLabel(b.ctx, b.tx, issueID, label)
+}
+
+func (b *sqliteBatch) addComment(issueID, author, text string) (*model.Comment, error) {
+	return insertSqliteComment(b.ctx, b.tx, issueID, author, text)
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// GetCompactionSettings returns how the tenant's issues are summarized when
+// compacted.
+func (s *SqliteStore) GetCompactionSettings(ctx context.Context) (*model.CompactionSettings, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var raw string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT settings FROM compaction_settings WHERE tenant_id = ?1", tenantID).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return defaultCompactionSettings(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting compaction settings: %w", err)
+	}
+	var settings model.CompactionSettings
+	if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+		return nil, fmt.Errorf("decoding compaction settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SetCompactionSettings sets how the tenant's issues are summarized when
+// compacted. Nil settings removes them, restoring the defaults.
+func (s *SqliteStore) SetCompactionSettings(ctx context.Context, settings *model.CompactionSettings) (*model.CompactionSettings, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if settings == nil {
+		if _, err := s.db.ExecContext(ctx,
+			"DELETE FROM compaction_settings WHERE tenant_id = ?1", tenantID); err != nil {
+			return nil, fmt.Errorf("resetting compaction settings: %w", err)
+		}
+		return defaultCompactionSettings(), nil
+	}
+
+	settings, err := normalizeCompactionSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("encoding compaction settings: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO compaction_settings (tenant_id, settings, updated_at) VALUES (?1, ?2, ?3)
+		 ON CONFLICT (tenant_id) DO UPDATE SET settings = excluded.settings, updated_at = excluded.updated_at`,
+		tenantID, string(raw), time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("setting compaction settings: %w", err)
+	}
+	return settings, nil
+}
+
+// GetCompactionPolicy returns the compaction policy of a project within the
+// tenant.
+func (s *SqliteStore) GetCompactionPolicy(ctx context.Context, projectID string) (*model.CompactionPolicy, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var raw sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		`SELECT cp.policy FROM project p
+		 LEFT JOIN project_compaction_policy cp ON cp.project_id = p.id
+		 WHERE p.id = ?1 AND p.tenant_id = ?2`,
+		projectID, tenantID).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("project not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting compaction policy: %w", err)
+	}
+	if !raw.Valid {
+		return defaultCompactionPolicy(projectID), nil
+	}
+	var policy model.CompactionPolicy
+	if err := json.Unmarshal([]byte(raw.String), &policy); err != nil {
+		return nil, fmt.Errorf("decoding compaction policy: %w", err)
+	}
+	policy.ProjectID = projectID
+	return &policy, nil
+}
+
+// SetCompactionPolicy sets when a project's closed issues are compacted.
+// Nil policy removes it, restoring the default.
+func (s *SqliteStore) SetCompactionPolicy(ctx context.Context, projectID string, policy *model.CompactionPolicy) (*model.CompactionPolicy, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var owned int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM project WHERE id = ?1 AND tenant_id = ?2", projectID, tenantID).Scan(&owned)
+	if err != nil {
+		return nil, fmt.Errorf("checking project: %w", err)
+	}
+	if owned == 0 {
+		return nil, fmt.Errorf("project not found")
+	}
+
+	if policy == nil {
+		if _, err := s.db.ExecContext(ctx,
+			"DELETE FROM project_compaction_policy WHERE project_id = ?1", projectID); err != nil {
+			return nil, fmt.Errorf("resetting compaction policy: %w", err)
+		}
+		return defaultCompactionPolicy(projectID), nil
+	}
+
+	policy, err = normalizeCompactionPolicy(projectID, policy)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("encoding compaction policy: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO project_compaction_policy (project_id, policy, updated_at) VALUES (?1, ?2, ?3)
+		 ON CONFLICT (project_id) DO UPDATE SET policy = excluded.policy, updated_at = excluded.updated_at`,
+		projectID, string(raw), time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("setting compaction policy: %w", err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+const sqliteFlagColumns = `id, tenant_id, project_id, issue_id, type, severity, summary,
+	context, status, resolution, resolved_by, resolved_at, created_at, created_by`
+
+// GenerateFlagID creates a hash-based flag ID with adaptive length.
+func (s *SqliteStore) GenerateFlagID(ctx context.Context) (string, error) {
+	return s.generateID(ctx, "flags", "flg", "flag ID")
+}
+
+// RaiseFlag inserts a new flag tied to an issue.
+func (s *SqliteStore) RaiseFlag(ctx context.Context, input RaiseFlagInput) (*model.Flag, error) {
+	tenantID := s.tenant(ctx)
+
+	id, err := s.GenerateFlagID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	severity := input.Severity
+	if severity == 0 {
+		severity = 2
+	}
+
+	ctxJSON := input.Context
+	if ctxJSON == nil {
+		ctxJSON = json.RawMessage(`{}`)
+	}
+
+	f, err := scanSqliteFlag(s.db.QueryRowContext(ctx,
+		`INSERT INTO flags (id, tenant_id, project_id, issue_id, type, severity, summary,
+		 context, status, created_at, created_by)
+		 VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, 'open', ?9, ?10)
+		 RETURNING `+sqliteFlagColumns,
+		id, tenantID, nullEmpty(input.ProjectID), nullEmpty(input.IssueID),
+		input.Type, severity, input.Summary,
+		string(ctxJSON), time.Now().UTC(), nullEmpty(input.CreatedBy)))
+	if err != nil {
+		return nil, fmt.Errorf("raising flag: %w", err)
+	}
+
+	return f, nil
+}
+
+// ListFlags returns flags matching the filter.
+func (s *SqliteStore) ListFlags(ctx context.Context, filter model.FlagFilter) ([]model.Flag, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + sqliteFlagColumns + ` FROM flags WHERE tenant_id = ?1`
+	args := []any{tenantID}
+	argN := 1
+
+	if filter.ProjectID != nil {
+		argN++
+		query += fmt.Sprintf(" AND project_id = ?%d", argN)
+		args = append(args, *filter.ProjectID)
+	}
+	if filter.Status != nil {
+		argN++
+		query += fmt.Sprintf(" AND status = ?%d", argN)
+		args = append(args, string(*filter.Status))
+	}
+	if filter.Severity != nil {
+		argN++
+		query += fmt.Sprintf(" AND severity = ?%d", argN)
+		args = append(args, *filter.Severity)
+	}
+	if filter.IssueID != nil {
+		argN++
+		query += fmt.Sprintf(" AND issue_id = ?%d", argN)
+		args = append(args, *filter.IssueID)
+	}
+
+	query, args, argN = addSqliteProjectFilter(ctx, query, args, argN, "project_id")
+
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, lessonFlagSort)
+		if err != nil {
+			return nil, err
+		}
+		var after string
+		after, args, argN = lessonFlagKeyset.after(cur, "?%d", args, argN)
+		query += " AND " + after
+	}
+	query += lessonFlagKeyset.orderBy()
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	argN++
+	query += fmt.Sprintf(" LIMIT ?%d", argN)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []model.Flag{}
+	for rows.Next() {
+		f, err := scanSqliteFlag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning flag: %w", err)
+		}
+		flags = append(flags, *f)
+	}
+	return flags, rows.Err()
+}
+
+// GetFlag returns a flag by ID.
+func (s *SqliteStore) GetFlag(ctx context.Context, id string) (*model.Flag, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	f, err := scanSqliteFlag(s.db.QueryRowContext(ctx,
+		`SELECT `+sqliteFlagColumns+` FROM flags WHERE id = ?1 AND tenant_id = ?2`,
+		id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("flag %s not found", id)
+		}
+		return nil, fmt.Errorf("getting flag: %w", err)
+	}
+
+	return f, nil
+}
+
+// ResolveFlag marks a flag as resolved with a resolution message.
+func (s *SqliteStore) ResolveFlag(ctx context.Context, id string, resolution, resolvedBy string) (*model.Flag, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	f, err := scanSqliteFlag(s.db.QueryRowContext(ctx,
+		`UPDATE flags SET status = 'resolved', resolution = ?1, resolved_at = ?2, resolved_by = ?3
+		 WHERE id = ?4 AND tenant_id = ?5
+		 RETURNING `+sqliteFlagColumns,
+		resolution, time.Now().UTC(), nullEmpty(resolvedBy), id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("flag %s not found", id)
+		}
+		return nil, fmt.Errorf("resolving flag: %w", err)
+	}
+
+	return f, nil
+}
+
+func scanSqliteFlag(row sqliteRow) (*model.Flag, error) {
+	var f model.Flag
+	err := row.Scan(&f.ID, &f.TenantID, &ns{&f.ProjectID}, &ns{&f.IssueID},
+		&f.Type, &f.Severity, &f.Summary,
+		(*[]byte)(&f.Context), &f.Status, &ns{&f.Resolution}, &ns{&f.ResolvedBy},
+		&f.ResolvedAt, &f.CreatedAt, &ns{&f.CreatedBy})
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// GetGCPolicy returns how long the tenant keeps ephemeral issues.
+func (s *SqliteStore) GetGCPolicy(ctx context.Context) (*model.GCPolicy, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var raw string
+	err := s.db.QueryRowContext(ctx, "SELECT policy FROM gc_policy WHERE tenant_id = ?1", tenantID).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return defaultGCPolicy(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting gc policy: %w", err)
+	}
+	var policy model.GCPolicy
+	if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+		return nil, fmt.Errorf("decoding gc policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// SetGCPolicy sets how long the tenant keeps ephemeral issues. Nil removes
+// the policy, restoring the defaults.
+func (s *SqliteStore) SetGCPolicy(ctx context.Context, policy *model.GCPolicy) (*model.GCPolicy, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if policy == nil {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM gc_policy WHERE tenant_id = ?1", tenantID); err != nil {
+			return nil, fmt.Errorf("resetting gc policy: %w", err)
+		}
+		return defaultGCPolicy(), nil
+	}
+
+	policy, err := normalizeGCPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("encoding gc policy: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO gc_policy (tenant_id, policy, updated_at) VALUES (?1, ?2, ?3)
+		 ON CONFLICT (tenant_id) DO UPDATE SET policy = excluded.policy, updated_at = excluded.updated_at`,
+		tenantID, string(raw), time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("setting gc policy: %w", err)
+	}
+	return policy, nil
+}
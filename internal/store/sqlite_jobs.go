@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// RecordJobRun adds a run to the tenant's job history, dropping the oldest
+// runs of the job past JobHistoryLimit.
+func (s *SqliteStore) RecordJobRun(ctx context.Context, run *model.JobRun) error {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var result sql.NullString
+	if len(run.Result) > 0 {
+		result = sql.NullString{String: string(run.Result), Valid: true}
+	}
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO job_runs (tenant_id, job, started_at, finished_at, succeeded, result, error, runner)
+		 VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8) RETURNING id`,
+		tid, run.Job, run.StartedAt.UTC(), run.FinishedAt.UTC(), run.Succeeded, result, run.Error, run.Runner).Scan(&run.ID)
+	if err != nil {
+		return fmt.Errorf("recording job run: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`DELETE FROM job_runs WHERE tenant_id = ?1 AND job = ?2 AND id < (
+		     SELECT MIN(id) FROM (
+		         SELECT id FROM job_runs WHERE tenant_id = ?1 AND job = ?2 ORDER BY id DESC LIMIT ?3
+		     ))`,
+		tid, run.Job, JobHistoryLimit); err != nil {
+		return fmt.Errorf("pruning job history: %w", err)
+	}
+	return nil
+}
+
+// ListJobRuns returns the tenant's runs of job, or of every job when job is
+// empty, newest first. A limit of 0 or less returns all that are kept.
+func (s *SqliteStore) ListJobRuns(ctx context.Context, job string, limit int) ([]model.JobRun, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, job, started_at, finished_at, succeeded, result, error, runner
+		 FROM job_runs WHERE tenant_id = ?1 AND (?2 = '' OR job = ?2) ORDER BY id DESC`
+	args := []any{tid, job}
+	if limit > 0 {
+		query += " LIMIT ?3"
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []model.JobRun{}
+	for rows.Next() {
+		var r model.JobRun
+		var result sql.NullString
+		if err := rows.Scan(&r.ID, &r.Job, &r.StartedAt, &r.FinishedAt, &r.Succeeded, &result, &r.Error, &r.Runner); err != nil {
+			return nil, fmt.Errorf("scanning job run: %w", err)
+		}
+		if result.Valid {
+			r.Result = []byte(result.String)
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// GetJobIntervals returns the tenant's overrides of how often jobs run.
+func (s *SqliteStore) GetJobIntervals(ctx context.Context) (map[string]time.Duration, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT job, every FROM job_intervals WHERE tenant_id = ?1", tid)
+	if err != nil {
+		return nil, fmt.Errorf("getting job intervals: %w", err)
+	}
+	defer rows.Close()
+
+	stored := make(map[string]string)
+	for rows.Next() {
+		var job, interval string
+		if err := rows.Scan(&job, &interval); err != nil {
+			return nil, fmt.Errorf("scanning job interval: %w", err)
+		}
+		stored[job] = interval
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return parseJobIntervals(stored)
+}
+
+// SetJobInterval sets how often job runs for the tenant. Nil removes the
+// override, so the server's default applies again.
+func (s *SqliteStore) SetJobInterval(ctx context.Context, job string, interval *time.Duration) error {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if interval == nil {
+		if _, err := s.db.ExecContext(ctx,
+			"DELETE FROM job_intervals WHERE tenant_id = ?1 AND job = ?2", tid, job); err != nil {
+			return fmt.Errorf("resetting job interval: %w", err)
+		}
+		return nil
+	}
+	if err := checkJobInterval(job, *interval); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO job_intervals (tenant_id, job, every, updated_at) VALUES (?1, ?2, ?3, ?4)
+		 ON CONFLICT (tenant_id, job) DO UPDATE SET every = excluded.every, updated_at = excluded.updated_at`,
+		tid, job, interval.String(), time.Now().UTC()); err != nil {
+		return fmt.Errorf("setting job interval: %w", err)
+	}
+	return nil
+}
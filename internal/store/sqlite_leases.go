@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+)
+
+// RenewLease extends the claimant's lease on an in_progress issue and records
+// the heartbeat as the issue's last activity.
+func (s *SqliteStore) RenewLease(ctx context.Context, issueID, claimant string, lease time.Duration) (*model.Issue, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := scanSqliteIssue(tx.QueryRowContext(ctx,
+		"SELECT "+issueColumns+" FROM issues WHERE id = ?1 AND tenant_id = ?2 AND deleted_at IS NULL", issueID, tid))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("issue %s not found", issueID)
+		}
+		return nil, fmt.Errorf("loading issue %s: %w", issueID, err)
+	}
+	if err := checkLeaseHolder(current, claimant); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	issue, err := scanSqliteIssue(tx.QueryRowContext(ctx,
+		`UPDATE issues SET last_activity = ?1, lease_expires_at = ?2
+		 WHERE id = ?3 AND tenant_id = ?4 RETURNING `+issueColumns,
+		now, leaseExpiry(now, lease), issueID, tid))
+	if err != nil {
+		return nil, fmt.Errorf("renewing lease on %s: %w", issueID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return issue, nil
+}
+
+// ReleaseExpiredLeases reopens every in_progress issue whose lease expired
+// before now, recording an abandoned retry and a status_changed event for
+// each. See PgStore.ReleaseExpiredLeases.
+func (s *SqliteStore) ReleaseExpiredLeases(ctx context.Context, now time.Time) ([]model.Issue, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	now = now.UTC()
+	expired, err := s.scanIssues(ctx,
+		`SELECT `+issueColumns+` FROM issues
+		 WHERE tenant_id = ?1 AND status = ?2 AND lease_expires_at < ?3 AND deleted_at IS NULL
+		 ORDER BY lease_expires_at`,
+		tid, string(model.StatusInProgress), now)
+	if err != nil {
+		return nil, fmt.Errorf("finding expired leases: %w", err)
+	}
+
+	released := []model.Issue{}
+	for _, candidate := range expired {
+		issue, err := s.releaseLease(ctx, tid, &candidate, now)
+		if err != nil {
+			return released, err
+		}
+		if issue != nil {
+			released = append(released, *issue)
+		}
+	}
+	return released, nil
+}
+
+func (s *SqliteStore) releaseLease(ctx context.Context, tid uuid.UUID, expired *model.Issue, now time.Time) (*model.Issue, error) {
+	// The pool has a single connection, so the ID must be generated before
+	// the transaction takes it.
+	retryID, err := s.GenerateRetryID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	issue, err := scanSqliteIssue(tx.QueryRowContext(ctx,
+		`UPDATE issues SET status = ?1, assignee = NULL, lease_expires_at = NULL, updated_at = ?2
+		 WHERE id = ?3 AND tenant_id = ?4 AND status = ?5 AND lease_expires_at < ?2 AND deleted_at IS NULL
+		 RETURNING `+issueColumns,
+		string(model.StatusOpen), now, expired.ID, tid, string(model.StatusInProgress)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // renewed or released since we looked
+		}
+		return nil, fmt.Errorf("releasing issue %s: %w", expired.ID, err)
+	}
+
+	msg := leaseExpiredMessage(expired)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO retries (id, tenant_id, project_id, issue_id, attempt, status, error, agent, started_at, ended_at, created_by)
+		 VALUES (?1, ?2, ?3, ?4,
+		   (SELECT COALESCE(MAX(attempt), 0) + 1 FROM retries WHERE issue_id = ?4 AND tenant_id = ?2),
+		   ?5, ?6, ?7, ?8, ?8, ?9)`,
+		retryID, tid, nullEmpty(expired.ProjectID), expired.ID,
+		string(model.RetryAbandoned), msg, nullEmpty(expired.Assignee), now, ReaperActor)
+	if err != nil {
+		return nil, fmt.Errorf("recording abandoned retry: %w", err)
+	}
+
+	if err := insertSqliteEvents(ctx, tx, releaseEvents(expired, issue, msg)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return issue, nil
+}
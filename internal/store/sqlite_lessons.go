@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+const sqliteLessonColumns = `id, tenant_id, project_id, issue_id, title, mistake, correction,
+	expert, components, severity, status, created_at, created_by, resolved_at, resolved_by`
+
+// GenerateLessonID creates a hash-based lesson ID with adaptive length.
+func (s *SqliteStore) GenerateLessonID(ctx context.Context) (string, error) {
+	return s.generateID(ctx, "lessons", "lsn", "lesson ID")
+}
+
+// RecordLesson inserts a new lesson learned.
+func (s *SqliteStore) RecordLesson(ctx context.Context, input RecordLessonInput) (*model.Lesson, error) {
+	tenantID := s.tenant(ctx)
+
+	id, err := s.GenerateLessonID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	severity := input.Severity
+	if severity == 0 {
+		severity = 2
+	}
+
+	components := input.Components
+	if components == nil {
+		components = []string{}
+	}
+	componentsJSON, err := json.Marshal(components)
+	if err != nil {
+		return nil, fmt.Errorf("encoding components: %w", err)
+	}
+
+	l, err := scanSqliteLesson(s.db.QueryRowContext(ctx,
+		`INSERT INTO lessons (id, tenant_id, project_id, issue_id, title, mistake, correction,
+		 expert, components, severity, status, created_at, created_by)
+		 VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, 'open', ?11, ?12)
+		 RETURNING `+sqliteLessonColumns,
+		id, tenantID, nullEmpty(input.ProjectID), nullEmpty(input.IssueID),
+		input.Title, input.Mistake, input.Correction,
+		nullEmpty(input.Expert), string(componentsJSON), severity, time.Now().UTC(), nullEmpty(input.CreatedBy)))
+	if err != nil {
+		return nil, fmt.Errorf("recording lesson: %w", err)
+	}
+
+	return l, nil
+}
+
+// ListLessons returns lessons matching the filter.
+func (s *SqliteStore) ListLessons(ctx context.Context, filter model.LessonFilter) ([]model.Lesson, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + sqliteLessonColumns + ` FROM lessons WHERE tenant_id = ?1`
+	args := []any{tenantID}
+	argN := 1
+
+	if filter.ProjectID != nil {
+		argN++
+		query += fmt.Sprintf(" AND project_id = ?%d", argN)
+		args = append(args, *filter.ProjectID)
+	}
+	if filter.Status != nil {
+		argN++
+		query += fmt.Sprintf(" AND status = ?%d", argN)
+		args = append(args, string(*filter.Status))
+	}
+	if filter.Expert != nil {
+		argN++
+		query += fmt.Sprintf(" AND expert = ?%d", argN)
+		args = append(args, *filter.Expert)
+	}
+	if filter.Component != nil {
+		argN++
+		query += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM json_each(components) WHERE value = ?%d)", argN)
+		args = append(args, *filter.Component)
+	}
+	if filter.Severity != nil {
+		argN++
+		query += fmt.Sprintf(" AND severity = ?%d", argN)
+		args = append(args, *filter.Severity)
+	}
+
+	query, args, argN = addSqliteProjectFilter(ctx, query, args, argN, "project_id")
+
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, lessonFlagSort)
+		if err != nil {
+			return nil, err
+		}
+		var after string
+		after, args, argN = lessonFlagKeyset.after(cur, "?%d", args, argN)
+		query += " AND " + after
+	}
+	query += lessonFlagKeyset.orderBy()
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	argN++
+	query += fmt.Sprintf(" LIMIT ?%d", argN)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing lessons: %w", err)
+	}
+	defer rows.Close()
+
+	lessons := []model.Lesson{}
+	for rows.Next() {
+		l, err := scanSqliteLesson(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning lesson: %w", err)
+		}
+		lessons = append(lessons, *l)
+	}
+	return lessons, rows.Err()
+}
+
+// ResolveLesson marks a lesson as resolved.
+func (s *SqliteStore) ResolveLesson(ctx context.Context, id string, resolvedBy string) (*model.Lesson, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	l, err := scanSqliteLesson(s.db.QueryRowContext(ctx,
+		`UPDATE lessons SET status = 'resolved', resolved_at = ?1, resolved_by = ?2
+		 WHERE id = ?3 AND tenant_id = ?4
+		 RETURNING `+sqliteLessonColumns,
+		time.Now().UTC(), nullEmpty(resolvedBy), id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lesson %s not found", id)
+		}
+		return nil, fmt.Errorf("resolving lesson: %w", err)
+	}
+
+	return l, nil
+}
+
+func scanSqliteLesson(row sqliteRow) (*model.Lesson, error) {
+	var l model.Lesson
+	var components string
+	err := row.Scan(&l.ID, &l.TenantID, &ns{&l.ProjectID}, &ns{&l.IssueID},
+		&l.Title, &l.Mistake, &l.Correction,
+		&ns{&l.Expert}, &components, &l.Severity, &l.Status,
+		&l.CreatedAt, &ns{&l.CreatedBy}, &l.ResolvedAt, &ns{&l.ResolvedBy})
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(components), &l.Components); err != nil {
+		return nil, fmt.Errorf("decoding components: %w", err)
+	}
+	return &l, nil
+}
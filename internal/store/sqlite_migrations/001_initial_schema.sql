@@ -0,0 +1,263 @@
+-- +goose Up
+
+-- SQLite equivalent of the PostgreSQL schema through migration 020.
+-- UUIDs are stored as TEXT, JSONB as TEXT and TEXT[] as a JSON array.
+
+CREATE TABLE tenant (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    slug       TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The implicit tenant used when no tenant is in context (local CLI use).
+INSERT INTO tenant (id, name, slug) VALUES ('00000000-0000-0000-0000-000000000001', 'Default', 'default');
+
+CREATE TABLE api_key (
+    id         TEXT PRIMARY KEY,
+    tenant_id  TEXT NOT NULL REFERENCES tenant(id),
+    key_hash   TEXT NOT NULL UNIQUE,
+    prefix     TEXT NOT NULL,
+    label      TEXT NOT NULL DEFAULT '',
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_api_key_tenant ON api_key(tenant_id);
+
+CREATE TABLE project (
+    id         TEXT PRIMARY KEY,
+    tenant_id  TEXT NOT NULL REFERENCES tenant(id),
+    name       TEXT NOT NULL,
+    slug       TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, slug)
+);
+
+CREATE TABLE issues (
+    id                  TEXT PRIMARY KEY,
+    content_hash        TEXT,
+    title               TEXT NOT NULL,
+    description         TEXT NOT NULL DEFAULT '',
+    design              TEXT NOT NULL DEFAULT '',
+    acceptance_criteria TEXT NOT NULL DEFAULT '',
+    notes               TEXT NOT NULL DEFAULT '',
+    spec_id             TEXT,
+    status              TEXT NOT NULL DEFAULT 'open',
+    priority            INTEGER NOT NULL DEFAULT 2,
+    issue_type          TEXT NOT NULL DEFAULT 'task',
+    assignee            TEXT,
+    owner               TEXT,
+    estimated_minutes   INTEGER,
+    created_at          TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by          TEXT,
+    updated_at          TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_at           TIMESTAMP,
+    due_at              TIMESTAMP,
+    defer_until         TIMESTAMP,
+    close_reason        TEXT,
+    closed_by_session   TEXT,
+    external_ref        TEXT,
+    source_system       TEXT,
+    source_repo         TEXT,
+    metadata            TEXT,
+    compaction_level    INTEGER NOT NULL DEFAULT 0,
+    compacted_at        TIMESTAMP,
+    compacted_at_commit TEXT,
+    original_size       INTEGER NOT NULL DEFAULT 0,
+    sender              TEXT,
+    ephemeral           BOOLEAN NOT NULL DEFAULT 0,
+    mol_type            TEXT,
+    work_type           TEXT DEFAULT 'mutex',
+    crystallizes        BOOLEAN NOT NULL DEFAULT 0,
+    wisp_type           TEXT,
+    pinned              BOOLEAN NOT NULL DEFAULT 0,
+    is_template         BOOLEAN NOT NULL DEFAULT 0,
+    quality_score       REAL,
+    event_kind          TEXT,
+    actor               TEXT,
+    target              TEXT,
+    payload             TEXT,
+    await_type          TEXT,
+    await_id            TEXT,
+    timeout_ns          INTEGER,
+    agent_state         TEXT,
+    last_activity       TIMESTAMP,
+    role_type           TEXT,
+    rig                 TEXT,
+    hook_bead           TEXT,
+    role_bead           TEXT,
+    tenant_id           TEXT NOT NULL REFERENCES tenant(id),
+    project_id          TEXT REFERENCES project(id)
+);
+
+CREATE INDEX idx_issues_status ON issues (status);
+CREATE INDEX idx_issues_priority ON issues (priority);
+CREATE INDEX idx_issues_created_at ON issues (created_at);
+CREATE INDEX idx_issues_updated_at ON issues (updated_at);
+CREATE INDEX idx_issues_tenant ON issues (tenant_id);
+CREATE INDEX idx_issues_project ON issues (project_id);
+
+CREATE TABLE dependencies (
+    issue_id      TEXT NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+    depends_on_id TEXT NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+    type          TEXT NOT NULL DEFAULT 'blocks',
+    created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by    TEXT,
+    metadata      TEXT,
+    thread_id     TEXT,
+    PRIMARY KEY (issue_id, depends_on_id)
+);
+
+CREATE INDEX idx_deps_depends_on ON dependencies (depends_on_id);
+
+CREATE TABLE labels (
+    issue_id TEXT NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+    label    TEXT NOT NULL,
+    PRIMARY KEY (issue_id, label)
+);
+
+CREATE TABLE comments (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    issue_id   TEXT NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+    author     TEXT NOT NULL,
+    text       TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_comments_issue ON comments (issue_id);
+
+CREATE TABLE events (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    issue_id   TEXT NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    actor      TEXT NOT NULL DEFAULT '',
+    old_value  TEXT,
+    new_value  TEXT,
+    comment    TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_events_issue ON events (issue_id);
+
+CREATE TABLE child_counters (
+    parent_id  TEXT PRIMARY KEY,
+    last_child INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE compaction_snapshots (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    issue_id   TEXT NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+    level      INTEGER NOT NULL,
+    summary    TEXT NOT NULL,
+    original   TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_compaction_issue ON compaction_snapshots (issue_id);
+
+CREATE TABLE config (
+    key   TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+CREATE TABLE metadata (
+    key   TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+CREATE TABLE lessons (
+    id          TEXT PRIMARY KEY,
+    tenant_id   TEXT NOT NULL REFERENCES tenant(id),
+    project_id  TEXT REFERENCES project(id),
+    issue_id    TEXT REFERENCES issues(id) ON DELETE SET NULL,
+    title       TEXT NOT NULL,
+    mistake     TEXT NOT NULL,
+    correction  TEXT NOT NULL,
+    expert      TEXT,
+    components  TEXT NOT NULL DEFAULT '[]',
+    severity    INTEGER NOT NULL DEFAULT 2,
+    status      TEXT NOT NULL DEFAULT 'open',
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by  TEXT,
+    resolved_at TIMESTAMP,
+    resolved_by TEXT
+);
+
+CREATE INDEX idx_lessons_tenant ON lessons(tenant_id);
+CREATE INDEX idx_lessons_issue ON lessons(issue_id);
+
+CREATE TABLE flags (
+    id          TEXT PRIMARY KEY,
+    tenant_id   TEXT NOT NULL REFERENCES tenant(id),
+    project_id  TEXT REFERENCES project(id),
+    issue_id    TEXT REFERENCES issues(id) ON DELETE SET NULL,
+    type        TEXT NOT NULL,
+    severity    INTEGER NOT NULL DEFAULT 2,
+    summary     TEXT NOT NULL,
+    context     TEXT NOT NULL DEFAULT '{}',
+    status      TEXT NOT NULL DEFAULT 'open',
+    resolution  TEXT,
+    resolved_by TEXT,
+    resolved_at TIMESTAMP,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by  TEXT
+);
+
+CREATE INDEX idx_flags_tenant ON flags(tenant_id);
+CREATE INDEX idx_flags_issue ON flags(issue_id);
+
+CREATE TABLE retries (
+    id         TEXT PRIMARY KEY,
+    tenant_id  TEXT NOT NULL REFERENCES tenant(id),
+    project_id TEXT REFERENCES project(id),
+    issue_id   TEXT NOT NULL REFERENCES issues(id) ON DELETE CASCADE,
+    attempt    INTEGER NOT NULL DEFAULT 1,
+    status     TEXT NOT NULL DEFAULT 'failed',
+    error      TEXT NOT NULL DEFAULT '',
+    agent      TEXT,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ended_at   TIMESTAMP,
+    created_by TEXT
+);
+
+CREATE INDEX idx_retries_issue ON retries(issue_id);
+
+-- Timestamps are written by the store as UTC text, so they compare
+-- correctly against the current UTC time as text.
+CREATE VIEW ready_issues AS
+SELECT i.*
+FROM issues i
+WHERE i.status = 'open'
+  AND i.ephemeral = 0
+  AND (i.defer_until IS NULL OR i.defer_until <= strftime('%Y-%m-%d %H:%M:%f', 'now'))
+  AND NOT EXISTS (
+      SELECT 1 FROM dependencies d
+      JOIN issues blocker ON blocker.id = d.depends_on_id
+      WHERE d.issue_id = i.id AND d.type = 'blocks'
+        AND blocker.status != 'closed'
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM flags f
+      WHERE f.issue_id = i.id
+        AND f.status = 'open'
+        AND f.severity <= 2
+  );
+
+-- +goose Down
+DROP VIEW IF EXISTS ready_issues;
+DROP TABLE IF EXISTS retries;
+DROP TABLE IF EXISTS flags;
+DROP TABLE IF EXISTS lessons;
+DROP TABLE IF EXISTS metadata;
+DROP TABLE IF EXISTS config;
+DROP TABLE IF EXISTS compaction_snapshots;
+DROP TABLE IF EXISTS child_counters;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS labels;
+DROP TABLE IF EXISTS dependencies;
+DROP TABLE IF EXISTS issues;
+DROP TABLE IF EXISTS project;
+DROP TABLE IF EXISTS api_key;
+DROP TABLE IF EXISTS tenant;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- See migrations/022_claim_leases.sql. SQLite expands the ready_issues
+-- view's i.* at query time, so the view needs no change.
+ALTER TABLE issues ADD COLUMN lease_expires_at TIMESTAMP;
+
+CREATE INDEX idx_issues_lease_expires_at ON issues (lease_expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_issues_lease_expires_at;
+ALTER TABLE issues DROP COLUMN lease_expires_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+
+-- See migrations/024_event_fields.sql.
+ALTER TABLE events ADD COLUMN field TEXT;
+
+CREATE INDEX idx_events_issue_created ON events (issue_id, created_at, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_events_issue_created;
+ALTER TABLE events DROP COLUMN field;
@@ -0,0 +1,167 @@
+-- +goose Up
+
+-- See migrations/025_soft_delete.sql.
+ALTER TABLE issues ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE issues ADD COLUMN deleted_by TEXT;
+
+CREATE INDEX idx_issues_deleted_at ON issues (tenant_id, deleted_at) WHERE deleted_at IS NOT NULL;
+
+DROP VIEW IF EXISTS ready_issues;
+CREATE VIEW ready_issues AS
+WITH RECURSIVE live AS (
+    SELECT * FROM issues WHERE deleted_at IS NULL
+), gated AS (
+    SELECT d.issue_id AS id
+    FROM dependencies d
+    JOIN live x ON x.id = d.issue_id
+    JOIN live y ON y.id = d.depends_on_id
+    LEFT JOIN project_ready_policy rp ON rp.project_id = x.project_id
+    WHERE x.status != 'closed'
+      AND (rp.gates IS NULL OR ',' || rp.gates || ',' LIKE '%,' || d.type || ',%')
+      AND (
+          (d.type = 'blocks' AND y.status != 'closed')
+          OR (d.type = 'conditional-blocks' AND (
+              y.status != 'closed'
+              OR y.close_reason IS NULL
+              OR NOT (
+                  lower(trim(y.close_reason)) LIKE 'fail%'
+                  OR lower(trim(y.close_reason)) LIKE 'reject%'
+                  OR lower(trim(y.close_reason)) LIKE 'abort%'
+                  OR lower(trim(y.close_reason)) LIKE 'abandon%'
+                  OR lower(trim(y.close_reason)) LIKE 'cancel%'
+                  OR lower(trim(y.close_reason)) LIKE 'wontfix%'
+                  OR lower(trim(y.close_reason)) LIKE 'won''t fix%'
+                  OR lower(trim(y.close_reason)) LIKE 'timed out%'
+                  OR lower(trim(y.close_reason)) LIKE 'timeout%'
+              )
+          ))
+          OR (d.type = 'waits-for' AND (
+              y.status != 'closed'
+              OR EXISTS (
+                  SELECT 1 FROM dependencies c
+                  JOIN live child ON child.id = c.issue_id
+                  WHERE c.depends_on_id = y.id AND c.type = 'parent-child'
+                    AND child.status != 'closed'
+              )
+          ))
+          OR (d.type = 'until' AND y.status = 'closed')
+      )
+), held(id) AS (
+    SELECT id FROM gated
+    UNION
+    SELECT id FROM live
+    WHERE status IN ('blocked', 'deferred')
+       OR (status != 'closed' AND defer_until > strftime('%Y-%m-%d %H:%M:%f', 'now'))
+), under_held(id) AS (
+    SELECT d.issue_id
+    FROM dependencies d
+    JOIN held h ON h.id = d.depends_on_id
+    JOIN live c ON c.id = d.issue_id
+    WHERE d.type = 'parent-child'
+    UNION
+    SELECT d.issue_id
+    FROM dependencies d
+    JOIN under_held u ON u.id = d.depends_on_id
+    JOIN live c ON c.id = d.issue_id
+    WHERE d.type = 'parent-child'
+)
+SELECT i.*
+FROM live i
+WHERE i.status = 'open'
+  AND i.ephemeral = 0
+  AND (i.defer_until IS NULL OR i.defer_until <= strftime('%Y-%m-%d %H:%M:%f', 'now'))
+  AND i.id NOT IN (SELECT id FROM gated)
+  AND (
+      i.id NOT IN (SELECT id FROM under_held)
+      OR EXISTS (
+          SELECT 1 FROM project_ready_policy rp
+          WHERE rp.project_id = i.project_id
+            AND ',' || rp.gates || ',' NOT LIKE '%,parent-child,%'
+      )
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM flags f
+      WHERE f.issue_id = i.id
+        AND f.status = 'open'
+        AND f.severity <= 2
+  );
+
+-- +goose Down
+DROP VIEW IF EXISTS ready_issues;
+DROP INDEX IF EXISTS idx_issues_deleted_at;
+ALTER TABLE issues DROP COLUMN deleted_by;
+ALTER TABLE issues DROP COLUMN deleted_at;
+CREATE VIEW ready_issues AS
+WITH RECURSIVE gated AS (
+    SELECT d.issue_id AS id
+    FROM dependencies d
+    JOIN issues x ON x.id = d.issue_id
+    JOIN issues y ON y.id = d.depends_on_id
+    LEFT JOIN project_ready_policy rp ON rp.project_id = x.project_id
+    WHERE x.status != 'closed'
+      AND (rp.gates IS NULL OR ',' || rp.gates || ',' LIKE '%,' || d.type || ',%')
+      AND (
+          (d.type = 'blocks' AND y.status != 'closed')
+          OR (d.type = 'conditional-blocks' AND (
+              y.status != 'closed'
+              OR y.close_reason IS NULL
+              OR NOT (
+                  lower(trim(y.close_reason)) LIKE 'fail%'
+                  OR lower(trim(y.close_reason)) LIKE 'reject%'
+                  OR lower(trim(y.close_reason)) LIKE 'abort%'
+                  OR lower(trim(y.close_reason)) LIKE 'abandon%'
+                  OR lower(trim(y.close_reason)) LIKE 'cancel%'
+                  OR lower(trim(y.close_reason)) LIKE 'wontfix%'
+                  OR lower(trim(y.close_reason)) LIKE 'won''t fix%'
+                  OR lower(trim(y.close_reason)) LIKE 'timed out%'
+                  OR lower(trim(y.close_reason)) LIKE 'timeout%'
+              )
+          ))
+          OR (d.type = 'waits-for' AND (
+              y.status != 'closed'
+              OR EXISTS (
+                  SELECT 1 FROM dependencies c
+                  JOIN issues child ON child.id = c.issue_id
+                  WHERE c.depends_on_id = y.id AND c.type = 'parent-child'
+                    AND child.status != 'closed'
+              )
+          ))
+          OR (d.type = 'until' AND y.status = 'closed')
+      )
+), held(id) AS (
+    SELECT id FROM gated
+    UNION
+    SELECT id FROM issues
+    WHERE status IN ('blocked', 'deferred')
+       OR (status != 'closed' AND defer_until > strftime('%Y-%m-%d %H:%M:%f', 'now'))
+), under_held(id) AS (
+    SELECT d.issue_id
+    FROM dependencies d
+    JOIN held h ON h.id = d.depends_on_id
+    WHERE d.type = 'parent-child'
+    UNION
+    SELECT d.issue_id
+    FROM dependencies d
+    JOIN under_held u ON u.id = d.depends_on_id
+    WHERE d.type = 'parent-child'
+)
+SELECT i.*
+FROM issues i
+WHERE i.status = 'open'
+  AND i.ephemeral = 0
+  AND (i.defer_until IS NULL OR i.defer_until <= strftime('%Y-%m-%d %H:%M:%f', 'now'))
+  AND i.id NOT IN (SELECT id FROM gated)
+  AND (
+      i.id NOT IN (SELECT id FROM under_held)
+      OR EXISTS (
+          SELECT 1 FROM project_ready_policy rp
+          WHERE rp.project_id = i.project_id
+            AND ',' || rp.gates || ',' NOT LIKE '%,parent-child,%'
+      )
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM flags f
+      WHERE f.issue_id = i.id
+        AND f.status = 'open'
+        AND f.severity <= 2
+  );
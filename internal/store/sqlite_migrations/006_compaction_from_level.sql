@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- See migrations/026_compaction_from_level.sql.
+ALTER TABLE compaction_snapshots ADD COLUMN from_level INTEGER NOT NULL DEFAULT 0;
+
+UPDATE issues
+SET compaction_level = (SELECT MAX(level) FROM compaction_snapshots s WHERE s.issue_id = issues.id),
+    compacted_at = (SELECT MAX(created_at) FROM compaction_snapshots s WHERE s.issue_id = issues.id)
+WHERE compaction_level = 0
+  AND EXISTS (SELECT 1 FROM compaction_snapshots s WHERE s.issue_id = issues.id);
+
+-- +goose Down
+ALTER TABLE compaction_snapshots DROP COLUMN from_level;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- See migrations/027_compaction_settings.sql.
+CREATE TABLE compaction_settings (
+    tenant_id   TEXT PRIMARY KEY REFERENCES tenant(id) ON DELETE CASCADE,
+    settings    TEXT NOT NULL,
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS compaction_settings;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- See migrations/028_compaction_policy.sql.
+CREATE TABLE project_compaction_policy (
+    project_id  TEXT PRIMARY KEY REFERENCES project(id) ON DELETE CASCADE,
+    policy      TEXT NOT NULL,
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS project_compaction_policy;
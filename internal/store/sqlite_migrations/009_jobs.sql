@@ -0,0 +1,28 @@
+-- +goose Up
+
+-- See migrations/029_jobs.sql.
+CREATE TABLE job_runs (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id    TEXT NOT NULL REFERENCES tenant(id) ON DELETE CASCADE,
+    job          TEXT NOT NULL,
+    started_at   TIMESTAMP NOT NULL,
+    finished_at  TIMESTAMP NOT NULL,
+    succeeded    BOOLEAN NOT NULL,
+    result       TEXT,
+    error        TEXT NOT NULL DEFAULT '',
+    runner       TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_job_runs_tenant_job ON job_runs(tenant_id, job, id DESC);
+
+CREATE TABLE job_intervals (
+    tenant_id   TEXT NOT NULL REFERENCES tenant(id) ON DELETE CASCADE,
+    job         TEXT NOT NULL,
+    every       TEXT NOT NULL,
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, job)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS job_intervals;
+DROP TABLE IF EXISTS job_runs;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- See migrations/030_gc_policy.sql.
+CREATE TABLE gc_policy (
+    tenant_id   TEXT PRIMARY KEY REFERENCES tenant(id) ON DELETE CASCADE,
+    policy      TEXT NOT NULL,
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS gc_policy;
@@ -0,0 +1,21 @@
+-- +goose Up
+
+-- See migrations/032_agent_names.sql.
+UPDATE issues
+SET title = title || ' (' || id || ')'
+WHERE issue_type = 'agent'
+  AND deleted_at IS NULL
+  AND EXISTS (
+      SELECT 1 FROM issues o
+      WHERE o.tenant_id = issues.tenant_id
+        AND o.title = issues.title
+        AND o.issue_type = 'agent'
+        AND o.deleted_at IS NULL
+        AND (o.updated_at, o.id) > (issues.updated_at, issues.id)
+  );
+
+CREATE UNIQUE INDEX idx_issues_agent_name ON issues (tenant_id, title)
+    WHERE issue_type = 'agent' AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_issues_agent_name;
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+)
+
+// CreateProject creates a new project within the tenant from context.
+func (s *SqliteStore) CreateProject(ctx context.Context, name, slug string) (*model.Project, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	p := &model.Project{}
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO project (id, tenant_id, name, slug, created_at) VALUES (?1, ?2, ?3, ?4, ?5)
+		 RETURNING id, tenant_id, name, slug, created_at`,
+		uuid.New(), tenantID, name, slug, time.Now().UTC()).
+		Scan(&p.ID, &p.TenantID, &p.Name, &p.Slug, &p.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("creating project: %w", err)
+	}
+	return p, nil
+}
+
+// ListProjects returns projects for the tenant from context.
+func (s *SqliteStore) ListProjects(ctx context.Context) ([]model.Project, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.queryProjects(ctx,
+		"SELECT id, tenant_id, name, slug, created_at FROM project WHERE tenant_id = ?1 ORDER BY name",
+		tenantID)
+}
+
+// ListAllProjects returns all projects across all tenants. Admin use only.
+func (s *SqliteStore) ListAllProjects(ctx context.Context) ([]model.Project, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.queryProjects(ctx,
+		"SELECT id, tenant_id, name, slug, created_at FROM project ORDER BY name")
+}
+
+// GetProjectBySlug returns a single project by its slug within the tenant.
+func (s *SqliteStore) GetProjectBySlug(ctx context.Context, slug string) (*model.Project, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	p := &model.Project{}
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, tenant_id, name, slug, created_at FROM project WHERE tenant_id = ?1 AND slug = ?2",
+		tenantID, slug).
+		Scan(&p.ID, &p.TenantID, &p.Name, &p.Slug, &p.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("project not found for slug %q: %w", slug, err)
+	}
+	return p, nil
+}
+
+// UpdateProject updates a project's name and/or slug by project ID within the tenant.
+func (s *SqliteStore) UpdateProject(ctx context.Context, projectID string, name, slug *string) (*model.Project, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if name == nil && slug == nil {
+		return nil, fmt.Errorf("nothing to update: provide name or slug")
+	}
+
+	sets := []string{}
+	args := []any{}
+	argN := 0
+
+	if name != nil {
+		argN++
+		sets = append(sets, fmt.Sprintf("name = ?%d", argN))
+		args = append(args, *name)
+	}
+	if slug != nil {
+		argN++
+		sets = append(sets, fmt.Sprintf("slug = ?%d", argN))
+		args = append(args, *slug)
+	}
+
+	argN++
+	args = append(args, tenantID)
+	argN++
+	args = append(args, projectID)
+
+	query := fmt.Sprintf(
+		"UPDATE project SET %s WHERE tenant_id = ?%d AND id = ?%d RETURNING id, tenant_id, name, slug, created_at",
+		strings.Join(sets, ", "), argN-1, argN,
+	)
+
+	p := &model.Project{}
+	err := s.db.QueryRowContext(ctx, query, args...).
+		Scan(&p.ID, &p.TenantID, &p.Name, &p.Slug, &p.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("updating project: %w", err)
+	}
+	return p, nil
+}
+
+// DeleteProject deletes a project by ID within the tenant.
+// Rejects if any issues still reference the project.
+func (s *SqliteStore) DeleteProject(ctx context.Context, projectID string) error {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM issues WHERE project_id = ?1", projectID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("checking project issues: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("cannot delete project: %d issues still reference it", count)
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"DELETE FROM project WHERE id = ?1 AND tenant_id = ?2", projectID, tenantID)
+	if err != nil {
+		return fmt.Errorf("deleting project: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("project not found")
+	}
+	return nil
+}
+
+func (s *SqliteStore) queryProjects(ctx context.Context, query string, args ...any) ([]model.Project, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []model.Project
+	for rows.Next() {
+		var p model.Project
+		if err := rows.Scan(&p.ID, &p.TenantID, &p.Name, &p.Slug, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning project: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// GetReadyPolicy returns the ready policy of a project within the tenant.
+func (s *SqliteStore) GetReadyPolicy(ctx context.Context, projectID string) (*model.ReadyPolicy, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var gates sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		`SELECT rp.gates FROM project p
+		 LEFT JOIN project_ready_policy rp ON rp.project_id = p.id
+		 WHERE p.id = ?1 AND p.tenant_id = ?2`,
+		projectID, tenantID).Scan(&gates)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("project not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting ready policy: %w", err)
+	}
+	if !gates.Valid {
+		return defaultReadyPolicy(projectID), nil
+	}
+	return &model.ReadyPolicy{ProjectID: projectID, Gates: splitDependencyTypes(gates.String)}, nil
+}
+
+// SetReadyPolicy sets which dependency types gate readiness for a
+// project's issues. Nil gates removes the policy, restoring the default.
+func (s *SqliteStore) SetReadyPolicy(ctx context.Context, projectID string, gates []model.DependencyType) (*model.ReadyPolicy, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var owned int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM project WHERE id = ?1 AND tenant_id = ?2", projectID, tenantID).Scan(&owned)
+	if err != nil {
+		return nil, fmt.Errorf("checking project: %w", err)
+	}
+	if owned == 0 {
+		return nil, fmt.Errorf("project not found")
+	}
+
+	if gates == nil {
+		if _, err := s.db.ExecContext(ctx,
+			"DELETE FROM project_ready_policy WHERE project_id = ?1", projectID); err != nil {
+			return nil, fmt.Errorf("resetting ready policy: %w", err)
+		}
+		return defaultReadyPolicy(projectID), nil
+	}
+
+	gates, err = normalizeReadyGates(gates)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO project_ready_policy (project_id, gates, updated_at) VALUES (?1, ?2, ?3)
+		 ON CONFLICT (project_id) DO UPDATE SET gates = excluded.gates, updated_at = excluded.updated_at`,
+		projectID, joinDependencyTypes(gates, ","), time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("setting ready policy: %w", err)
+	}
+	return &model.ReadyPolicy{ProjectID: projectID, Gates: gates}, nil
+}
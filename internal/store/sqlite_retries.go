@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+const sqliteRetryColumns = `id, tenant_id, project_id, issue_id, attempt, status, error, agent, started_at, ended_at, created_by`
+
+// GenerateRetryID creates a hash-based retry ID with adaptive length.
+func (s *SqliteStore) GenerateRetryID(ctx context.Context) (string, error) {
+	return s.generateID(ctx, "retries", "rty", "retry ID")
+}
+
+// RecordRetry inserts a new retry attempt for an issue.
+func (s *SqliteStore) RecordRetry(ctx context.Context, input RecordRetryInput) (*model.Retry, error) {
+	tenantID := s.tenant(ctx)
+
+	id, err := s.GenerateRetryID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	status := input.Status
+	if status == "" {
+		status = string(model.RetryFailed)
+	}
+
+	// The attempt number is computed in the INSERT so concurrent writers
+	// serialize on SQLite's write lock rather than racing on MAX(attempt).
+	r, err := scanSqliteRetry(s.db.QueryRowContext(ctx,
+		`INSERT INTO retries (id, tenant_id, project_id, issue_id, attempt, status, error, agent, started_at, created_by)
+		 VALUES (?1, ?2, ?3, ?4,
+		   (SELECT COALESCE(MAX(attempt), 0) + 1 FROM retries WHERE issue_id = ?4 AND tenant_id = ?2),
+		   ?5, ?6, ?7, ?8, ?9)
+		 RETURNING `+sqliteRetryColumns,
+		id, tenantID, nullEmpty(input.ProjectID), input.IssueID,
+		status, input.Error, nullEmpty(input.Agent), time.Now().UTC(), nullEmpty(input.CreatedBy)))
+	if err != nil {
+		return nil, fmt.Errorf("recording retry: %w", err)
+	}
+
+	return r, nil
+}
+
+// ListRetries returns retry attempts for an issue.
+func (s *SqliteStore) ListRetries(ctx context.Context, issueID string, filter model.RetryFilter) ([]model.Retry, error) {
+	tenantID := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + sqliteRetryColumns + ` FROM retries WHERE tenant_id = ?1 AND issue_id = ?2`
+	args := []any{tenantID, issueID}
+	argN := 2
+
+	if filter.Status != nil {
+		argN++
+		query += fmt.Sprintf(" AND status = ?%d", argN)
+		args = append(args, string(*filter.Status))
+	}
+
+	query += " ORDER BY attempt ASC"
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	argN++
+	query += fmt.Sprintf(" LIMIT ?%d", argN)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing retries: %w", err)
+	}
+	defer rows.Close()
+
+	retries := []model.Retry{}
+	for rows.Next() {
+		r, err := scanSqliteRetry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning retry: %w", err)
+		}
+		retries = append(retries, *r)
+	}
+	return retries, rows.Err()
+}
+
+func scanSqliteRetry(row sqliteRow) (*model.Retry, error) {
+	var r model.Retry
+	err := row.Scan(&r.ID, &ns{&r.TenantID}, &ns{&r.ProjectID}, &r.IssueID,
+		&r.Attempt, &r.Status, &r.Error, &ns{&r.Agent},
+		&r.StartedAt, &r.EndedAt, &ns{&r.CreatedBy})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// SearchIssues narrows candidates with LIKE on each query word, then matches
+// and ranks them in Go with the same rules as the in-memory store.
+func (s *SqliteStore) SearchIssues(ctx context.Context, query model.SearchQuery) ([]model.SearchResult, error) {
+	tid := s.tenant(ctx)
+	if strings.TrimSpace(query.Query) == "" {
+		return nil, fmt.Errorf("search query is empty")
+	}
+
+	tq := parseTextQuery(query.Query)
+	results := []model.SearchResult{}
+	if tq.empty() {
+		return results, nil
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	sql := "SELECT " + issueColumns + " FROM issues WHERE tenant_id = ?1 AND deleted_at IS NULL"
+	args := []any{tid}
+	argN := 1
+
+	words := append([]string{}, tq.terms...)
+	for _, phrase := range tq.phrases {
+		words = append(words, phrase...)
+	}
+	for _, w := range words {
+		argN++
+		sql += fmt.Sprintf(` AND ((title || ' ' || description || ' ' || design || ' ' || acceptance_criteria || ' ' || notes) LIKE '%%' || ?%[1]d || '%%'
+			OR EXISTS (SELECT 1 FROM comments c WHERE c.issue_id = issues.id AND c.text LIKE '%%' || ?%[1]d || '%%'))`, argN)
+		args = append(args, searchStem(w))
+	}
+	if query.Status != nil {
+		argN++
+		sql += fmt.Sprintf(" AND status = ?%d", argN)
+		args = append(args, string(*query.Status))
+	}
+	if query.IssueType != nil {
+		argN++
+		sql += fmt.Sprintf(" AND issue_type = ?%d", argN)
+		args = append(args, string(*query.IssueType))
+	}
+	if query.ProjectID != nil {
+		argN++
+		sql += fmt.Sprintf(" AND project_id = ?%d", argN)
+		args = append(args, *query.ProjectID)
+	}
+	sql, args, _ = addSqliteProjectFilter(ctx, sql, args, argN, "project_id")
+
+	candidates, err := s.scanIssues(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("searching issues: %w", err)
+	}
+	if len(candidates) == 0 {
+		return results, nil
+	}
+
+	ids := make([]string, len(candidates))
+	for k := range candidates {
+		ids[k] = candidates[k].ID
+	}
+	in, cargs, _ := sqliteInList(nil, 0, ids)
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT issue_id, text FROM comments WHERE issue_id IN ("+in+") ORDER BY id", cargs...)
+	if err != nil {
+		return nil, fmt.Errorf("loading comments for search: %w", err)
+	}
+	defer rows.Close()
+
+	comments := make(map[string][]string)
+	for rows.Next() {
+		var issueID, text string
+		if err := rows.Scan(&issueID, &text); err != nil {
+			return nil, fmt.Errorf("scanning comment: %w", err)
+		}
+		comments[issueID] = append(comments[issueID], text)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for k := range candidates {
+		if r, ok := tq.matchIssue(&candidates[k], comments[candidates[k].ID]); ok {
+			results = append(results, r)
+		}
+	}
+
+	sortSearchResults(results)
+	return paginate(results, searchLimit(query.Limit), 0), nil
+}
@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/google/uuid"
+)
+
+// ResolveAPIKey looks up an active API key by its SHA-256 hash and returns the tenant ID.
+func (s *SqliteStore) ResolveAPIKey(ctx context.Context, keyHash string) (uuid.UUID, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var tenantID uuid.UUID
+	err := s.db.QueryRowContext(ctx,
+		`SELECT ak.tenant_id FROM api_key ak
+		 JOIN tenant t ON t.id = ak.tenant_id
+		 WHERE ak.key_hash = ?1 AND ak.revoked_at IS NULL`, keyHash).Scan(&tenantID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("resolving API key: %w", err)
+	}
+	return tenantID, nil
+}
+
+// CreateTenant creates a new tenant.
+func (s *SqliteStore) CreateTenant(ctx context.Context, name, slug string) (*model.Tenant, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	t := &model.Tenant{}
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO tenant (id, name, slug, created_at) VALUES (?1, ?2, ?3, ?4)
+		 RETURNING id, name, slug, created_at`, uuid.New(), name, slug, time.Now().UTC()).
+		Scan(&t.ID, &t.Name, &t.Slug, &t.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("creating tenant: %w", err)
+	}
+	return t, nil
+}
+
+// UpdateTenant updates a tenant's name and/or slug by tenant ID.
+func (s *SqliteStore) UpdateTenant(ctx context.Context, tenantID string, name, slug *string) (*model.Tenant, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if name == nil && slug == nil {
+		return nil, fmt.Errorf("nothing to update: provide name or slug")
+	}
+
+	sets := []string{}
+	args := []any{}
+	argN := 0
+
+	if name != nil {
+		argN++
+		sets = append(sets, fmt.Sprintf("name = ?%d", argN))
+		args = append(args, *name)
+	}
+	if slug != nil {
+		argN++
+		sets = append(sets, fmt.Sprintf("slug = ?%d", argN))
+		args = append(args, *slug)
+	}
+
+	argN++
+	args = append(args, tenantID)
+
+	query := fmt.Sprintf(
+		"UPDATE tenant SET %s WHERE id = ?%d RETURNING id, name, slug, created_at",
+		strings.Join(sets, ", "), argN,
+	)
+
+	t := &model.Tenant{}
+	err := s.db.QueryRowContext(ctx, query, args...).
+		Scan(&t.ID, &t.Name, &t.Slug, &t.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("updating tenant: %w", err)
+	}
+	return t, nil
+}
+
+// ListTenants returns all tenants.
+func (s *SqliteStore) ListTenants(ctx context.Context) ([]model.Tenant, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, name, slug, created_at FROM tenant ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("listing tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []model.Tenant
+	for rows.Next() {
+		var t model.Tenant
+		if err := rows.Scan(&t.ID, &t.Name, &t.Slug, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning tenant: %w", err)
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, rows.Err()
+}
+
+// DeleteTenant deletes a tenant by ID. Rejects if any projects still exist.
+// Cascades to API keys.
+func (s *SqliteStore) DeleteTenant(ctx context.Context, tenantID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM project WHERE tenant_id = ?1", tenantID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("checking tenant projects: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("cannot delete tenant: %d projects still exist (delete them first)", count)
+	}
+
+	_, err = s.db.ExecContext(ctx, "DELETE FROM api_key WHERE tenant_id = ?1", tenantID)
+	if err != nil {
+		return fmt.Errorf("deleting tenant API keys: %w", err)
+	}
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM tenant WHERE id = ?1", tenantID)
+	if err != nil {
+		return fmt.Errorf("deleting tenant: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("tenant not found")
+	}
+	return nil
+}
+
+// CreateAPIKey creates a new API key for a tenant. Returns the key info (not the raw key).
+func (s *SqliteStore) CreateAPIKey(ctx context.Context, tenantSlug, label, keyHash, prefix string) (*model.APIKeyInfo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var tenantID uuid.UUID
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM tenant WHERE slug = ?1", tenantSlug).Scan(&tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %q not found: %w", tenantSlug, err)
+	}
+
+	k := &model.APIKeyInfo{}
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO api_key (id, tenant_id, key_hash, prefix, label, created_at)
+		 VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+		 RETURNING id, tenant_id, prefix, label, created_at`,
+		uuid.New(), tenantID, keyHash, prefix, label, time.Now().UTC()).
+		Scan(&k.ID, &k.TenantID, &k.Prefix, &k.Label, &k.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("creating API key: %w", err)
+	}
+	return k, nil
+}
+
+// RevokeAPIKey revokes an API key by prefix.
+func (s *SqliteStore) RevokeAPIKey(ctx context.Context, prefix string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE api_key SET revoked_at = ?1 WHERE prefix = ?2 AND revoked_at IS NULL", time.Now().UTC(), prefix)
+	if err != nil {
+		return fmt.Errorf("revoking API key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("API key with prefix %q not found or already revoked", prefix)
+	}
+	return nil
+}
+
+// ListAPIKeys lists API keys for a tenant.
+func (s *SqliteStore) ListAPIKeys(ctx context.Context, tenantSlug string) ([]model.APIKeyInfo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT ak.id, ak.tenant_id, ak.prefix, ak.label, ak.created_at, ak.revoked_at
+		 FROM api_key ak
+		 JOIN tenant t ON t.id = ak.tenant_id
+		 WHERE t.slug = ?1
+		 ORDER BY ak.created_at`, tenantSlug)
+	if err != nil {
+		return nil, fmt.Errorf("listing API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []model.APIKeyInfo
+	for rows.Next() {
+		var k model.APIKeyInfo
+		if err := rows.Scan(&k.ID, &k.TenantID, &k.Prefix, &k.Label, &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, fmt.Errorf("scanning API key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// GetConfig retrieves a value from the config table by key.
+func (s *SqliteStore) GetConfig(ctx context.Context, key string) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var value string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM config WHERE key = ?1", key).Scan(&value)
+	if err != nil {
+		return "", fmt.Errorf("config key %q: %w", key, err)
+	}
+	return value, nil
+}
+
+// SetConfig upserts a value in the config table.
+func (s *SqliteStore) SetConfig(ctx context.Context, key, value string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO config (key, value) VALUES (?1, ?2)
+		 ON CONFLICT (key) DO UPDATE SET value = excluded.value`,
+		key, value)
+	if err != nil {
+		return fmt.Errorf("setting config %q: %w", key, err)
+	}
+	return nil
+}
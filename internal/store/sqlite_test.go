@@ -0,0 +1,320 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+func newTestSqliteStore(t *testing.T) *SqliteStore {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "doit.db")
+	s, err := NewSqliteStore(context.Background(), path, 5*time.Second, "")
+	if err != nil {
+		t.Fatalf("NewSqliteStore: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func sqliteCreate(t *testing.T, ctx context.Context, s *SqliteStore, input CreateIssueInput) *model.Issue {
+	t.Helper()
+	if input.ID == "" {
+		id, err := s.GenerateID(ctx, "")
+		if err != nil {
+			t.Fatalf("GenerateID: %v", err)
+		}
+		input.ID = id
+	}
+	if input.Status == "" {
+		input.Status = model.StatusOpen
+	}
+	issue, err := s.CreateIssue(ctx, input)
+	if err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	return issue
+}
+
+func TestRunMigrations_Sqlite(t *testing.T) {
+	url := SqliteURLPrefix + filepath.Join(t.TempDir(), "doit.db")
+	for i := 0; i < 2; i++ {
+		if err := RunMigrations(url); err != nil {
+			t.Fatalf("RunMigrations (pass %d): %v", i+1, err)
+		}
+	}
+
+	st, err := Open(context.Background(), url, 5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer st.Close()
+	if _, ok := st.(*SqliteStore); !ok {
+		t.Fatalf("Open returned %T, want *SqliteStore", st)
+	}
+}
+
+func TestSqliteStore_ImplicitTenant(t *testing.T) {
+	s := newTestSqliteStore(t)
+	ctx := context.Background()
+
+	issue := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "local", Labels: []string{"cli"}})
+	if issue.TenantID != ImplicitTenantID.String() {
+		t.Errorf("TenantID = %q, want implicit tenant", issue.TenantID)
+	}
+
+	got, err := s.GetIssue(ctx, issue.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.Title != "local" || len(got.Labels) != 1 || got.Labels[0] != "cli" {
+		t.Errorf("GetIssue = %+v", got)
+	}
+	if got.CreatedAt.IsZero() || got.CreatedAt.Location() != time.UTC {
+		t.Errorf("CreatedAt = %v, want UTC timestamp", got.CreatedAt)
+	}
+
+	explicit := auth.WithTenant(ctx, ImplicitTenantID)
+	if _, err := s.GetIssue(explicit, issue.ID); err != nil {
+		t.Errorf("implicit tenant issue not visible with explicit tenant: %v", err)
+	}
+}
+
+func TestSqliteStore_UpdateAndList(t *testing.T) {
+	s := newTestSqliteStore(t)
+	ctx := context.Background()
+	a := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "find the needle", Priority: 1})
+	sqliteCreate(t, ctx, s, CreateIssueInput{Title: "haystack", Priority: 3})
+
+	closed := model.StatusClosed
+	reason := "done"
+	updated, err := s.UpdateIssue(ctx, a.ID, UpdateIssueInput{Status: &closed, CloseReason: &reason})
+	if err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if updated.Status != model.StatusClosed || updated.ClosedAt == nil || updated.CloseReason != "done" {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	search := "needle"
+	list, err := s.ListIssues(ctx, model.IssueFilter{Search: &search})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != a.ID {
+		t.Errorf("search returned %d issues, want %s", len(list), a.ID)
+	}
+
+	list, err = s.ListIssues(ctx, model.IssueFilter{StatusNot: []model.Status{model.StatusClosed}, SortBy: "priority"})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(list) != 1 || list[0].Title != "haystack" {
+		t.Errorf("StatusNot filter returned %+v", list)
+	}
+
+	if _, err := s.UpdateIssue(ctx, "doit-nope", UpdateIssueInput{Status: &closed}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("UpdateIssue on missing issue err = %v, want not found", err)
+	}
+}
+
+func TestSqliteStore_ReadyRules(t *testing.T) {
+	s := newTestSqliteStore(t)
+	ctx := context.Background()
+
+	plain := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "plain"})
+	blocker := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "blocker"})
+	blocked := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "blocked"})
+	ephemeral := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "wisp", Ephemeral: true})
+	flagged := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "flagged"})
+	deferred := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "later"})
+
+	if _, err := s.AddDependency(ctx, AddDependencyInput{IssueID: blocked.ID, DependsOnID: blocker.ID, Type: model.DepBlocks}); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	if _, err := s.RaiseFlag(ctx, RaiseFlagInput{IssueID: flagged.ID, Type: "red_flag", Severity: 1, Summary: "stop"}); err != nil {
+		t.Fatalf("RaiseFlag: %v", err)
+	}
+	if _, err := s.db.Exec("UPDATE issues SET defer_until = ?1 WHERE id = ?2", time.Now().UTC().Add(time.Hour), deferred.ID); err != nil {
+		t.Fatalf("deferring issue: %v", err)
+	}
+
+	readyIDs := func() map[string]bool {
+		ready, err := s.ListReady(ctx, model.IssueFilter{})
+		if err != nil {
+			t.Fatalf("ListReady: %v", err)
+		}
+		ids := make(map[string]bool)
+		for _, i := range ready {
+			ids[i.ID] = true
+		}
+		return ids
+	}
+
+	ready := readyIDs()
+	for _, id := range []string{plain.ID, blocker.ID} {
+		if !ready[id] {
+			t.Errorf("%s should be ready", id)
+		}
+	}
+	for _, id := range []string{blocked.ID, ephemeral.ID, flagged.ID, deferred.ID} {
+		if ready[id] {
+			t.Errorf("%s should not be ready", id)
+		}
+	}
+
+	closed := model.StatusClosed
+	if _, err := s.UpdateIssue(ctx, blocker.ID, UpdateIssueInput{Status: &closed}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if _, err := s.db.Exec("UPDATE issues SET defer_until = ?1 WHERE id = ?2", time.Now().UTC().Add(-time.Hour), deferred.ID); err != nil {
+		t.Fatalf("undeferring issue: %v", err)
+	}
+	ready = readyIDs()
+	if !ready[blocked.ID] {
+		t.Error("blocked issue should be ready once its blocker is closed")
+	}
+	if !ready[deferred.ID] {
+		t.Error("issue whose defer_until has passed should be ready")
+	}
+}
+
+func TestSqliteStore_TreeAndCascade(t *testing.T) {
+	s := newTestSqliteStore(t)
+	ctx := context.Background()
+	epic := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "Epic", IssueType: model.TypeEpic})
+
+	childID, err := s.NextChildID(ctx, epic.ID)
+	if err != nil {
+		t.Fatalf("NextChildID: %v", err)
+	}
+	if childID != epic.ID+".1" {
+		t.Errorf("child id = %q, want %s.1", childID, epic.ID)
+	}
+	sqliteCreate(t, ctx, s, CreateIssueInput{ID: childID, Title: "child", ParentID: epic.ID})
+
+	nodes, err := s.GetDependencyTree(ctx, epic.ID, 5)
+	if err != nil {
+		t.Fatalf("GetDependencyTree: %v", err)
+	}
+	if len(nodes) != 2 || nodes[1].Issue.ID != childID || nodes[1].Depth != 1 {
+		t.Errorf("tree = %+v", nodes)
+	}
+
+	if _, err := s.AddComment(ctx, childID, "me", "note"); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if err := s.DeleteIssue(ctx, childID); err != nil {
+		t.Fatalf("DeleteIssue: %v", err)
+	}
+	deps, err := s.ListDependencies(ctx, epic.ID, "both")
+	if err != nil {
+		t.Fatalf("ListDependencies: %v", err)
+	}
+	if len(deps) != 0 {
+		t.Errorf("dependencies should cascade on delete, got %+v", deps)
+	}
+}
+
+func TestSqliteStore_TenantIsolation(t *testing.T) {
+	s := newTestSqliteStore(t)
+	bg := context.Background()
+	tenantA, _ := s.CreateTenant(bg, "A", "a")
+	tenantB, _ := s.CreateTenant(bg, "B", "b")
+	ctxA := auth.WithTenant(bg, tenantA.ID)
+	ctxB := auth.WithTenant(bg, tenantB.ID)
+
+	issue := sqliteCreate(t, ctxA, s, CreateIssueInput{Title: "secret"})
+	other := sqliteCreate(t, ctxB, s, CreateIssueInput{Title: "mine"})
+
+	if _, err := s.GetIssue(ctxB, issue.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("cross-tenant GetIssue err = %v, want not found", err)
+	}
+	if _, err := s.GetIssue(bg, issue.ID); err == nil {
+		t.Error("implicit tenant should not see other tenants' issues")
+	}
+	if _, err := s.AddDependency(ctxB, AddDependencyInput{IssueID: other.ID, DependsOnID: issue.ID, Type: model.DepBlocks}); err == nil {
+		t.Error("cross-tenant AddDependency should fail")
+	}
+	if err := s.DeleteIssue(ctxB, issue.ID); err == nil {
+		t.Error("cross-tenant DeleteIssue should fail")
+	}
+}
+
+func TestSqliteStore_AllowedProjects(t *testing.T) {
+	s := newTestSqliteStore(t)
+	ctx := context.Background()
+	p1, err := s.CreateProject(ctx, "One", "one")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	p2, _ := s.CreateProject(ctx, "Two", "two")
+	in1 := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "in one", ProjectID: p1.ID.String()})
+	sqliteCreate(t, ctx, s, CreateIssueInput{Title: "in two", ProjectID: p2.ID.String()})
+
+	scoped := auth.WithAllowedProjects(ctx, []string{p1.ID.String()})
+	list, err := s.ListIssues(scoped, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != in1.ID {
+		t.Errorf("ListIssues returned %d issues, want only %s", len(list), in1.ID)
+	}
+	counts, _ := s.CountIssuesByStatus(scoped)
+	if counts["open"] != 1 {
+		t.Errorf("open count = %d, want 1", counts["open"])
+	}
+
+	if err := s.DeleteProject(ctx, p1.ID.String()); err == nil {
+		t.Error("DeleteProject should refuse while issues reference it")
+	}
+}
+
+func TestSqliteStore_LessonsFlagsRetries(t *testing.T) {
+	s := newTestSqliteStore(t)
+	ctx := context.Background()
+	issue := sqliteCreate(t, ctx, s, CreateIssueInput{Title: "work"})
+
+	if _, err := s.RecordLesson(ctx, RecordLessonInput{Title: "t", Mistake: "m", Correction: "c", Components: []string{"api", "db"}}); err != nil {
+		t.Fatalf("RecordLesson: %v", err)
+	}
+	component := "db"
+	lessons, err := s.ListLessons(ctx, model.LessonFilter{Component: &component})
+	if err != nil {
+		t.Fatalf("ListLessons: %v", err)
+	}
+	if len(lessons) != 1 || len(lessons[0].Components) != 2 {
+		t.Errorf("lessons = %+v", lessons)
+	}
+
+	f, err := s.RaiseFlag(ctx, RaiseFlagInput{IssueID: issue.ID, Type: "question", Summary: "?", Context: json.RawMessage(`{"k":"v"}`)})
+	if err != nil {
+		t.Fatalf("RaiseFlag: %v", err)
+	}
+	if f.Severity != 2 || string(f.Context) != `{"k":"v"}` {
+		t.Errorf("flag = %+v", f)
+	}
+	resolved, err := s.ResolveFlag(ctx, f.ID, "answered", "me")
+	if err != nil {
+		t.Fatalf("ResolveFlag: %v", err)
+	}
+	if resolved.Status != model.FlagStatusResolved || resolved.ResolvedAt == nil {
+		t.Errorf("resolved flag = %+v", resolved)
+	}
+
+	for want := 1; want <= 2; want++ {
+		r, err := s.RecordRetry(ctx, RecordRetryInput{IssueID: issue.ID, Error: "boom"})
+		if err != nil {
+			t.Fatalf("RecordRetry: %v", err)
+		}
+		if r.Attempt != want {
+			t.Errorf("attempt = %d, want %d", r.Attempt, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// DeleteIssue moves an issue to the trash. See PgStore.DeleteIssue.
+func (s *SqliteStore) DeleteIssue(ctx context.Context, id string) error {
+	tid := s.tenant(ctx)
+	actor := eventActor(ctx, "")
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	res, err := tx.ExecContext(ctx,
+		`UPDATE issues SET deleted_at = ?3, deleted_by = ?4
+		 WHERE id = ?1 AND tenant_id = ?2 AND deleted_at IS NULL`, id, tid, now, actor)
+	if err != nil {
+		return fmt.Errorf("deleting issue: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("issue %s not found", id)
+	}
+	if err := insertSqliteEvents(ctx, tx, []AddEventInput{trashEvent(id, now, true, actor)}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RestoreIssue takes an issue out of the trash as it was when deleted.
+func (s *SqliteStore) RestoreIssue(ctx context.Context, id string) (*model.Issue, error) {
+	tid := s.tenant(ctx)
+	actor := eventActor(ctx, "")
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt *time.Time
+	err = tx.QueryRowContext(ctx,
+		"SELECT deleted_at FROM issues WHERE id = ?1 AND tenant_id = ?2", id, tid).Scan(&deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("issue %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading issue %s: %w", id, err)
+	}
+	if deletedAt == nil {
+		return nil, fmt.Errorf("issue %s is not in the trash", id)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE issues SET deleted_at = NULL, deleted_by = NULL WHERE id = ?1 AND tenant_id = ?2", id, tid); err != nil {
+		return nil, fmt.Errorf("restoring issue: %w", err)
+	}
+	if err := insertSqliteEvents(ctx, tx, []AddEventInput{trashEvent(id, *deletedAt, false, actor)}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return s.GetIssue(ctx, id)
+}
+
+// ListTrash returns the tenant's deleted issues, most recently deleted
+// first.
+func (s *SqliteStore) ListTrash(ctx context.Context) ([]model.Issue, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + issueColumns + " FROM issues WHERE tenant_id = ?1 AND deleted_at IS NOT NULL"
+	query, args, _ := addSqliteProjectFilter(ctx, query, []any{tid}, 1, "project_id")
+	query += " ORDER BY deleted_at DESC, id"
+	return s.scanIssues(ctx, query, args...)
+}
+
+// PurgeTrash permanently deletes issues trashed before deletedBefore. See
+// PgStore.PurgeTrash.
+func (s *SqliteStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	tid := s.tenant(ctx)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		`DELETE FROM issues WHERE tenant_id = ?1 AND deleted_at < ?2 RETURNING id`, tid, deletedBefore.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("purging trash: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("purging trash: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// PurgeIssues permanently deletes issues ids, trashed or not. See
+// PgStore.PurgeIssues.
+func (s *SqliteStore) PurgeIssues(ctx context.Context, ids []string) ([]string, error) {
+	tid := s.tenant(ctx)
+	purged := []string{}
+	if len(ids) == 0 {
+		return purged, nil
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	in, args, _ := sqliteInList([]any{tid}, 1, ids)
+	rows, err := s.db.QueryContext(ctx,
+		"DELETE FROM issues WHERE tenant_id = ?1 AND id IN ("+in+") RETURNING id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("purging issues: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("purging issues: %w", err)
+		}
+		purged = append(purged, id)
+	}
+	return purged, rows.Err()
+}
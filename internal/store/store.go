@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/Actual-Outcomes/doit/internal/model"
 	"github.com/google/uuid"
@@ -17,14 +18,38 @@ type Store interface {
 	ListIssues(ctx context.Context, filter model.IssueFilter) ([]model.Issue, error)
 	DeleteIssue(ctx context.Context, id string) error
 
+	// Trash: DeleteIssue moves an issue here, keeping its dependencies,
+	// labels, comments and events. Trashed issues are hidden from every
+	// other query until restored or purged.
+	RestoreIssue(ctx context.Context, id string) (*model.Issue, error)
+	ListTrash(ctx context.Context) ([]model.Issue, error)
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	// PurgeIssues permanently deletes the given issues, trashed or not, the
+	// same way, and returns the IDs of those it found.
+	PurgeIssues(ctx context.Context, ids []string) ([]string, error)
+
+	// Agents: an agent's bead is the one live issue of type agent titled
+	// with its name, which the store keeps unique within the tenant.
+	// FindAgentBead returns nil when no agent has the name.
+	FindAgentBead(ctx context.Context, name string) (*model.Issue, error)
+
+	// Search
+	SearchIssues(ctx context.Context, query model.SearchQuery) ([]model.SearchResult, error)
+
 	// Ready detection
 	ListReady(ctx context.Context, filter model.IssueFilter) ([]model.Issue, error)
+	ClaimNextReady(ctx context.Context, input ClaimNextReadyInput) (*model.Issue, error)
+
+	// Claim leases
+	RenewLease(ctx context.Context, issueID, claimant string, lease time.Duration) (*model.Issue, error)
+	ReleaseExpiredLeases(ctx context.Context, now time.Time) ([]model.Issue, error)
 
 	// Dependencies
 	AddDependency(ctx context.Context, input AddDependencyInput) (*model.Dependency, error)
 	RemoveDependency(ctx context.Context, issueID, dependsOnID string) error
 	ListDependencies(ctx context.Context, issueID string, direction string) ([]model.Dependency, error)
 	GetDependencyTree(ctx context.Context, rootID string, maxDepth int) ([]model.TreeNode, error)
+	ValidateGraph(ctx context.Context) (*model.GraphReport, error)
 
 	// Hierarchical IDs
 	NextChildID(ctx context.Context, parentID string) (string, error)
@@ -38,14 +63,35 @@ type Store interface {
 	AddComment(ctx context.Context, issueID, author, text string) (*model.Comment, error)
 	ListComments(ctx context.Context, issueID string) ([]model.Comment, error)
 
+	// Batches: ops run in order in one transaction, all or nothing. Later
+	// ops may refer to issues created earlier as "$ref". A failing op is
+	// reported as *BatchError.
+	ApplyBatch(ctx context.Context, ops []BatchOp) ([]BatchResult, error)
+
 	// Events (audit trail)
 	AddEvent(ctx context.Context, input AddEventInput) (*model.Event, error)
 	ListEvents(ctx context.Context, issueID string, limit int) ([]model.Event, error)
 
-	// Compaction
-	SaveCompactionSnapshot(ctx context.Context, issueID string, level int, summary, original string) error
+	// Compaction: SaveCompactionSnapshot records original and applies
+	// content, the compacted fields, in one transaction.
+	SaveCompactionSnapshot(ctx context.Context, issueID string, level int, summary, original string, content UpdateIssueInput) error
 	GetCompactionSnapshots(ctx context.Context, issueID string) ([]model.CompactionSnapshot, error)
 
+	// Compaction settings: how the tenant's issues are summarized when they
+	// are compacted. SetCompactionSettings with nil restores the defaults.
+	GetCompactionSettings(ctx context.Context) (*model.CompactionSettings, error)
+	SetCompactionSettings(ctx context.Context, settings *model.CompactionSettings) (*model.CompactionSettings, error)
+
+	// Compaction policy: when a project's closed issues are compacted.
+	// SetCompactionPolicy with nil restores the default (the run's age).
+	GetCompactionPolicy(ctx context.Context, projectID string) (*model.CompactionPolicy, error)
+	SetCompactionPolicy(ctx context.Context, projectID string, policy *model.CompactionPolicy) (*model.CompactionPolicy, error)
+
+	// GC policy: how long the tenant keeps ephemeral issues and wisps.
+	// SetGCPolicy with nil restores the defaults.
+	GetGCPolicy(ctx context.Context) (*model.GCPolicy, error)
+	SetGCPolicy(ctx context.Context, policy *model.GCPolicy) (*model.GCPolicy, error)
+
 	// Aggregation
 	CountIssuesByStatus(ctx context.Context) (map[string]int, error)
 
@@ -61,6 +107,7 @@ type Store interface {
 	// Flags
 	RaiseFlag(ctx context.Context, input RaiseFlagInput) (*model.Flag, error)
 	ListFlags(ctx context.Context, filter model.FlagFilter) ([]model.Flag, error)
+	GetFlag(ctx context.Context, id string) (*model.Flag, error)
 	ResolveFlag(ctx context.Context, id string, resolution, resolvedBy string) (*model.Flag, error)
 	GenerateFlagID(ctx context.Context) (string, error)
 
@@ -77,6 +124,11 @@ type Store interface {
 	UpdateProject(ctx context.Context, projectID string, name, slug *string) (*model.Project, error)
 	DeleteProject(ctx context.Context, projectID string) error
 
+	// Ready policy: which dependency types gate readiness for a project's
+	// issues. SetReadyPolicy with nil gates restores the default (all).
+	GetReadyPolicy(ctx context.Context, projectID string) (*model.ReadyPolicy, error)
+	SetReadyPolicy(ctx context.Context, projectID string, gates []model.DependencyType) (*model.ReadyPolicy, error)
+
 	// Tenants
 	CreateTenant(ctx context.Context, name, slug string) (*model.Tenant, error)
 	UpdateTenant(ctx context.Context, tenantID string, name, slug *string) (*model.Tenant, error)
@@ -91,9 +143,28 @@ type Store interface {
 	GetConfig(ctx context.Context, key string) (string, error)
 	SetConfig(ctx context.Context, key, value string) error
 
+	// Background jobs: the tenant's run history, newest first, and its
+	// overrides of how often each job runs. RecordJobRun keeps the newest
+	// JobHistoryLimit runs of each job; a nil interval removes an override.
+	RecordJobRun(ctx context.Context, run *model.JobRun) error
+	ListJobRuns(ctx context.Context, job string, limit int) ([]model.JobRun, error)
+	GetJobIntervals(ctx context.Context) (map[string]time.Duration, error)
+	SetJobInterval(ctx context.Context, job string, interval *time.Duration) error
+
 	Close()
 }
 
+// JobHistoryLimit is how many runs of each job a tenant's history keeps.
+const JobHistoryLimit = 100
+
+// JobLocker is implemented by stores that several server replicas share,
+// so each background job runs on only one replica at a time. TryJobLock
+// reports false, without waiting, when another holder has key; otherwise
+// release gives the lock back.
+type JobLocker interface {
+	TryJobLock(ctx context.Context, key string) (release func(), ok bool, err error)
+}
+
 // CreateIssueInput holds the fields for creating a new issue.
 type CreateIssueInput struct {
 	ID                 string              // pre-generated ID (hash-based or child)
@@ -111,10 +182,21 @@ type CreateIssueInput struct {
 	ProjectID          string // project to assign the issue to
 	ParentID           string // if set, creates parent-child dependency
 	Labels             []string
+	EstimatedMinutes   *int // nil or <= 0 leaves the issue unestimated
 	Ephemeral          bool
 	MolType            model.MolType
 	WorkType           model.WorkType
+	Crystallizes       bool
 	WispType           model.WispType
+	AgentState         model.AgentState
+	LastActivity       *time.Time
+	RoleType           string
+	Rig                string
+	RoleBead           string
+	AwaitType          string        // gates: what the gate waits on
+	AwaitID            string        // gates: the issue, flag or signal waited on
+	Timeout            time.Duration // gates: the timer, or how long to wait before escalating
+	IsTemplate         bool
 }
 
 // UpdateIssueInput holds optional fields for updating an issue.
@@ -133,7 +215,34 @@ type UpdateIssueInput struct {
 	DeferUntil         *string
 	CloseReason        *string
 	Pinned             *bool
+	Ephemeral          *bool // false makes an ephemeral issue permanent
 	ExternalRef        *string
+	EstimatedMinutes   *int           // <= 0 clears the estimate
+	Lease              *time.Duration // restart the claim lease from now, and LastActivity with it; zero clears it
+	CompactionLevel    *int           // lowered when restoring from a snapshot; compaction itself saves one
+	AgentState         *model.AgentState
+	LastActivity       *time.Time
+	RoleType           *string
+	Rig                *string
+	HookBead           *string // the issue an agent is working on; "" clears it
+	RoleBead           *string
+	AwaitID            *string // gates: the issue, flag or signal waited on
+	IsTemplate         *bool
+
+	// Optimistic concurrency: when set, the update fails with a
+	// *ConflictError unless the stored issue still has this value.
+	ExpectedContentHash *string
+	ExpectedUpdatedAt   *time.Time
+}
+
+// ClaimNextReadyInput selects the ready issue to claim. The highest-priority,
+// oldest matching issue is assigned to Claimant and moved to in_progress.
+type ClaimNextReadyInput struct {
+	Claimant  string
+	ProjectID *string
+	IssueType *model.IssueType
+	Labels    []string      // issue must carry every label
+	Lease     time.Duration // claim expires unless renewed within this; zero never expires
 }
 
 // AddDependencyInput holds the fields for creating a dependency.
@@ -184,6 +293,7 @@ type AddEventInput struct {
 	IssueID   string
 	EventType model.EventType
 	Actor     string
+	Field     string
 	OldValue  string
 	NewValue  string
 	Comment   string
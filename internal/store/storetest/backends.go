@@ -0,0 +1,99 @@
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+// Memory returns a fresh in-memory store.
+func Memory(t *testing.T) store.Store {
+	return store.NewMemStore("")
+}
+
+// Sqlite returns a store on a fresh SQLite database in t's temp dir.
+func Sqlite(t *testing.T) store.Store {
+	t.Helper()
+	s, err := store.NewSqliteStore(context.Background(), filepath.Join(t.TempDir(), "doit.db"), 5*time.Second, "")
+	if err != nil {
+		t.Fatalf("NewSqliteStore: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// PostgresURL returns the database the Postgres tests run against,
+// DOIT_TEST_DATABASE_URL, migrated. It skips t when the variable is not
+// set, except in CI, where a missing database is a failure.
+func PostgresURL(t *testing.T) string {
+	t.Helper()
+	url := os.Getenv("DOIT_TEST_DATABASE_URL")
+	if url == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("DOIT_TEST_DATABASE_URL must be set in CI")
+		}
+		t.Skip("DOIT_TEST_DATABASE_URL not set")
+	}
+	migrateOnce.Do(func() { migrateErr = migratePostgres(url) })
+	if migrateErr != nil {
+		t.Fatalf("migrating %s: %v", url, migrateErr)
+	}
+	return url
+}
+
+var (
+	migrateOnce sync.Once
+	migrateErr  error
+)
+
+// migratePostgres runs the migrations under an advisory lock: go test runs
+// packages in parallel, and each migrates the shared database.
+func migratePostgres(url string) error {
+	ctx := context.Background()
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext('doit.storetest.migrations'))"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtext('doit.storetest.migrations'))")
+	return store.RunMigrations(url)
+}
+
+// Postgres returns a factory for stores on the database at url, which
+// PostgresURL provides. The database is shared and not wiped; tests keep
+// apart by working in fresh tenants.
+func Postgres(url string) func(t *testing.T) store.Store {
+	return func(t *testing.T) store.Store {
+		t.Helper()
+		s, err := store.NewPgStore(context.Background(), url, 10*time.Second, "")
+		if err != nil {
+			t.Fatalf("NewPgStore: %v", err)
+		}
+		t.Cleanup(s.Close)
+		return s
+	}
+}
+
+// EachStore runs fn against every backend: memory, SQLite and, when
+// PostgresURL provides one, Postgres. Packages built on store.Store use it
+// to check their behavior holds on each, without the suite depending on
+// them.
+func EachStore(t *testing.T, fn func(t *testing.T, s store.Store)) {
+	t.Run("Memory", func(t *testing.T) { fn(t, Memory(t)) })
+	t.Run("Sqlite", func(t *testing.T) { fn(t, Sqlite(t)) })
+	t.Run("Postgres", func(t *testing.T) { fn(t, Postgres(PostgresURL(t))(t)) })
+}
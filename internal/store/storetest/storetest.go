@@ -0,0 +1,2912 @@
+// Package storetest is a conformance suite for store.Store implementations.
+//
+// Every backend runs the same scenarios, so the semantics that used to be
+// implicit in PgStore (ready detection, parent-child creation, dependency
+// upserts, cycle rejection, atomic claims, batches, audit events, tenant
+// isolation, allowed-project filtering, search and cursor pagination) are
+// pinned down once and checked everywhere:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store { return newBackend(t) })
+//	}
+//
+// Scenarios create their own tenants with unique slugs, so a factory may hand
+// out a shared database (e.g. one PostgreSQL instance) as long as it is migrated.
+//
+// The suite covers the store.Store contract alone. Packages built on a
+// store (molecules, agents, gates, gc, templates) check themselves against
+// every backend from their own tests, with EachStore and the fixtures here.
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/auth"
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/google/uuid"
+)
+
+// Factory returns a ready-to-use Store for a single scenario. It should
+// register any cleanup with t.Cleanup.
+type Factory func(t *testing.T) store.Store
+
+// Run executes every conformance scenario against stores built by newStore.
+func Run(t *testing.T, newStore Factory) {
+	scenarios := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"UpdateIssue", testUpdateIssue},
+		{"OptimisticConcurrency", testOptimisticConcurrency},
+		{"ParentChild", testParentChild},
+		{"Dependencies", testDependencies},
+		{"AddDependencyUpsert", testAddDependencyUpsert},
+		{"DependencyCycles", testDependencyCycles},
+		{"ValidateGraph", testValidateGraph},
+		{"ReadyDetection", testReadyDetection},
+		{"ReadyFlags", testReadyFlags},
+		{"ReadyGates", testReadyGates},
+		{"ReadyPolicy", testReadyPolicy},
+		{"ExplainReady", testExplainReady},
+		{"CriticalPath", testCriticalPath},
+		{"ClaimNextReady", testClaimNextReady},
+		{"ClaimNextReadyConcurrent", testClaimNextReadyConcurrent},
+		{"ClaimNextReadyContention", testClaimNextReadyContention},
+		{"ClaimLeases", testClaimLeases},
+		{"ApplyBatch", testApplyBatch},
+		{"ApplyBatchRollback", testApplyBatchRollback},
+		{"Events", testEvents},
+		{"IssueHistory", testIssueHistory},
+		{"Compaction", testCompaction},
+		{"CompactionSettings", testCompactionSettings},
+		{"CompactionPolicy", testCompactionPolicy},
+		{"JobHistory", testJobHistory},
+		{"GarbageCollection", testGarbageCollection},
+		{"Templates", testTemplates},
+		{"AgentNames", testAgentNames},
+		{"DeleteCascades", testDeleteCascades},
+		{"Trash", testTrash},
+		{"TenantIsolation", testTenantIsolation},
+		{"CrossTenantAccess", testCrossTenantAccess},
+		{"AllowedProjects", testAllowedProjects},
+		{"Search", testSearch},
+		{"ListIssuesSearchFilter", testListIssuesSearchFilter},
+		{"IssueCursors", testIssueCursors},
+		{"ReadyCursor", testReadyCursor},
+		{"LessonFlagCursors", testLessonFlagCursors},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			sc.fn(t, newStore(t))
+		})
+	}
+}
+
+// --- Helpers ---
+
+// NewTenant creates a uniquely named tenant in s and returns a context
+// scoped to it.
+func NewTenant(t *testing.T, s store.Store) context.Context {
+	t.Helper()
+	slug := "st-" + uuid.NewString()[:8]
+	tenant, err := s.CreateTenant(context.Background(), slug, slug)
+	if err != nil {
+		t.Fatalf("CreateTenant: %v", err)
+	}
+	return auth.WithTenant(context.Background(), tenant.ID)
+}
+
+// MemTenant returns a fresh in-memory store handing out doit- IDs and a
+// context scoped to a new tenant in it: the fixture for unit tests of the
+// packages built on a store.
+func MemTenant(t *testing.T) (*store.MemStore, context.Context) {
+	t.Helper()
+	s := store.NewMemStore("doit")
+	return s, NewTenant(t, s)
+}
+
+// CreateIssue creates input, generating its ID and defaulting it to an open
+// P2 task.
+func CreateIssue(t *testing.T, ctx context.Context, s store.Store, input store.CreateIssueInput) *model.Issue {
+	t.Helper()
+	if input.ID == "" {
+		id, err := s.GenerateID(ctx, "")
+		if err != nil {
+			t.Fatalf("GenerateID: %v", err)
+		}
+		input.ID = id
+	}
+	if input.Status == "" {
+		input.Status = model.StatusOpen
+	}
+	if input.IssueType == "" {
+		input.IssueType = model.TypeTask
+	}
+	if input.Priority == 0 {
+		input.Priority = 2 // callers wanting P0 set it via UpdateIssue
+	}
+	issue, err := s.CreateIssue(ctx, input)
+	if err != nil {
+		t.Fatalf("CreateIssue(%q): %v", input.Title, err)
+	}
+	return issue
+}
+
+func addDep(t *testing.T, ctx context.Context, s store.Store, issueID, dependsOnID string, typ model.DependencyType) {
+	t.Helper()
+	if _, err := s.AddDependency(ctx, store.AddDependencyInput{IssueID: issueID, DependsOnID: dependsOnID, Type: typ}); err != nil {
+		t.Fatalf("AddDependency(%s -> %s, %s): %v", issueID, dependsOnID, typ, err)
+	}
+}
+
+func setStatus(t *testing.T, ctx context.Context, s store.Store, id string, status model.Status) {
+	t.Helper()
+	if _, err := s.UpdateIssue(ctx, id, store.UpdateIssueInput{Status: &status}); err != nil {
+		t.Fatalf("UpdateIssue(%s, %s): %v", id, status, err)
+	}
+}
+
+func readySet(t *testing.T, ctx context.Context, s store.Store) map[string]bool {
+	t.Helper()
+	ready, err := s.ListReady(ctx, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListReady: %v", err)
+	}
+	ids := make(map[string]bool, len(ready))
+	for _, i := range ready {
+		ids[i.ID] = true
+	}
+	return ids
+}
+
+// ReadyIDs returns the IDs of the ready issues, sorted.
+func ReadyIDs(t *testing.T, ctx context.Context, s store.Store) []string {
+	t.Helper()
+	ready, err := s.ListReady(ctx, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListReady: %v", err)
+	}
+	return issueIDs(ready)
+}
+
+func issueIDs(issues []model.Issue) []string {
+	ids := make([]string, len(issues))
+	for i, issue := range issues {
+		ids[i] = issue.ID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func sameIDs(got []string, want ...string) bool {
+	sort.Strings(want)
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// --- Scenarios ---
+
+func testCreateAndGet(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+
+	created := CreateIssue(t, ctx, s, store.CreateIssueInput{
+		Title:       "Write the suite",
+		Description: "desc",
+		Priority:    1,
+		IssueType:   model.TypeFeature,
+		Assignee:    "alice",
+		CreatedBy:   "bob",
+		Labels:      []string{"b", "a"},
+	})
+	if created.ContentHash == "" {
+		t.Error("CreateIssue should compute a content hash")
+	}
+
+	got, err := s.GetIssue(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.Title != "Write the suite" || got.Description != "desc" || got.Priority != 1 ||
+		got.IssueType != model.TypeFeature || got.Assignee != "alice" || got.CreatedBy != "bob" {
+		t.Errorf("GetIssue returned %+v", got)
+	}
+	if got.Status != model.StatusOpen {
+		t.Errorf("Status = %q, want open", got.Status)
+	}
+	if !sameIDs(got.Labels, "a", "b") {
+		t.Errorf("Labels = %v, want [a b] sorted", got.Labels)
+	}
+	if got.ContentHash != created.ContentHash {
+		t.Errorf("ContentHash = %q, want %q", got.ContentHash, created.ContentHash)
+	}
+
+	events, err := s.ListEvents(ctx, created.ID, 0)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) == 0 || events[0].EventType != "created" {
+		t.Errorf("events = %+v, want a single created event", events)
+	}
+
+	if _, err := s.GetIssue(ctx, "doit-missing"); err == nil {
+		t.Error("GetIssue on a missing ID should fail")
+	}
+}
+
+func testUpdateIssue(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	issue := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "before"})
+
+	title := "after"
+	priority := 0
+	updated, err := s.UpdateIssue(ctx, issue.ID, store.UpdateIssueInput{Title: &title, Priority: &priority})
+	if err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if updated.Title != "after" || updated.Priority != 0 {
+		t.Errorf("updated = %+v", updated)
+	}
+	if updated.ClosedAt != nil {
+		t.Error("ClosedAt should stay nil until the issue is closed")
+	}
+
+	setStatus(t, ctx, s, issue.ID, model.StatusClosed)
+	closed, err := s.GetIssue(ctx, issue.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if closed.Status != model.StatusClosed || closed.ClosedAt == nil {
+		t.Errorf("closing should set status and closed_at, got %+v", closed)
+	}
+
+	if _, err := s.UpdateIssue(ctx, "doit-missing", store.UpdateIssueInput{Title: &title}); err == nil {
+		t.Error("UpdateIssue on a missing ID should fail")
+	}
+}
+
+func testOptimisticConcurrency(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	issue := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "original"})
+	if issue.ContentHash == "" {
+		t.Fatal("CreateIssue should set a content hash")
+	}
+
+	title := "edited"
+	edited, err := s.UpdateIssue(ctx, issue.ID, store.UpdateIssueInput{
+		Title:               &title,
+		ExpectedContentHash: &issue.ContentHash,
+	})
+	if err != nil {
+		t.Fatalf("UpdateIssue with the current hash: %v", err)
+	}
+	if edited.ContentHash == issue.ContentHash {
+		t.Error("changing the title should recompute the content hash")
+	}
+	got, err := s.GetIssue(ctx, issue.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.ContentHash != edited.ContentHash {
+		t.Errorf("stored hash = %s, UpdateIssue returned %s", got.ContentHash, edited.ContentHash)
+	}
+
+	// A writer still holding the original hash loses.
+	stale := "stale"
+	_, err = s.UpdateIssue(ctx, issue.ID, store.UpdateIssueInput{
+		Title:               &stale,
+		ExpectedContentHash: &issue.ContentHash,
+	})
+	if !errors.Is(err, store.ErrConflict) {
+		t.Fatalf("stale content hash: err = %v, want ErrConflict", err)
+	}
+	var conflict *store.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("stale content hash: err = %T, want *store.ConflictError", err)
+	}
+	if conflict.Field != "content_hash" || conflict.Expected != issue.ContentHash || conflict.Actual != edited.ContentHash {
+		t.Errorf("conflict = %+v", conflict)
+	}
+	if conflict.Current == nil || conflict.Current.Title != "edited" {
+		t.Errorf("conflict should carry the current issue, got %+v", conflict.Current)
+	}
+	if got, _ := s.GetIssue(ctx, issue.ID); got.Title != "edited" {
+		t.Errorf("a conflicting update must not be applied, title = %q", got.Title)
+	}
+
+	// updated_at works the same way, and non-text changes still bump it.
+	priority := 0
+	bumped, err := s.UpdateIssue(ctx, issue.ID, store.UpdateIssueInput{
+		Priority:          &priority,
+		ExpectedUpdatedAt: &edited.UpdatedAt,
+	})
+	if err != nil {
+		t.Fatalf("UpdateIssue with the current updated_at: %v", err)
+	}
+	if bumped.ContentHash != edited.ContentHash {
+		t.Error("a priority change should not alter the content hash")
+	}
+	if !bumped.UpdatedAt.After(edited.UpdatedAt) {
+		t.Errorf("updated_at should advance: %v then %v", edited.UpdatedAt, bumped.UpdatedAt)
+	}
+	_, err = s.UpdateIssue(ctx, issue.ID, store.UpdateIssueInput{
+		Priority:          &priority,
+		ExpectedUpdatedAt: &edited.UpdatedAt,
+	})
+	if !errors.As(err, &conflict) || conflict.Field != "updated_at" {
+		t.Fatalf("stale updated_at: err = %v, want an updated_at conflict", err)
+	}
+
+	// A missing issue is not found, not a conflict.
+	_, err = s.UpdateIssue(ctx, "doit-missing", store.UpdateIssueInput{
+		Title:               &title,
+		ExpectedContentHash: &issue.ContentHash,
+	})
+	if err == nil || errors.Is(err, store.ErrConflict) {
+		t.Errorf("missing issue: err = %v, want not found", err)
+	}
+}
+
+func testParentChild(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	epic := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Epic", IssueType: model.TypeEpic})
+
+	var children []string
+	for n := 1; n <= 2; n++ {
+		id, err := s.NextChildID(ctx, epic.ID)
+		if err != nil {
+			t.Fatalf("NextChildID: %v", err)
+		}
+		if want := fmt.Sprintf("%s.%d", epic.ID, n); id != want {
+			t.Errorf("NextChildID = %q, want %q", id, want)
+		}
+		child := CreateIssue(t, ctx, s, store.CreateIssueInput{ID: id, Title: "child", ParentID: epic.ID})
+		if child.ParentID != epic.ID {
+			t.Errorf("CreateIssue ParentID = %q, want %q", child.ParentID, epic.ID)
+		}
+		children = append(children, id)
+	}
+
+	got, err := s.GetIssue(ctx, children[0])
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.ParentID != epic.ID {
+		t.Errorf("GetIssue ParentID = %q, want %q", got.ParentID, epic.ID)
+	}
+
+	deps, err := s.ListDependencies(ctx, children[0], "upstream")
+	if err != nil {
+		t.Fatalf("ListDependencies: %v", err)
+	}
+	if len(deps) != 1 || deps[0].DependsOnID != epic.ID || deps[0].Type != model.DepParentChild {
+		t.Errorf("child deps = %+v, want one parent-child edge to %s", deps, epic.ID)
+	}
+
+	parentID := epic.ID
+	listed, err := s.ListIssues(ctx, model.IssueFilter{ParentID: &parentID})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if !sameIDs(issueIDs(listed), children...) {
+		t.Errorf("ListIssues(ParentID) = %v, want %v", issueIDs(listed), children)
+	}
+
+	tree, err := s.GetDependencyTree(ctx, epic.ID, 3)
+	if err != nil {
+		t.Fatalf("GetDependencyTree: %v", err)
+	}
+	if len(tree) != 3 || tree[0].Issue.ID != epic.ID || tree[0].Depth != 0 {
+		t.Fatalf("tree = %+v, want root plus two children", tree)
+	}
+	for _, node := range tree[1:] {
+		if node.Depth != 1 {
+			t.Errorf("child %s depth = %d, want 1", node.Issue.ID, node.Depth)
+		}
+	}
+
+	// A parent-child edge does not block readiness on its own.
+	ready := readySet(t, ctx, s)
+	if !ready[epic.ID] || !ready[children[0]] {
+		t.Error("parent and children should all be ready")
+	}
+}
+
+func testDependencies(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	a := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "a"})
+	b := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "b"})
+	c := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "c"})
+
+	addDep(t, ctx, s, a.ID, b.ID, model.DepBlocks)
+	addDep(t, ctx, s, c.ID, a.ID, model.DepRelated)
+
+	for _, tc := range []struct {
+		direction string
+		want      int
+	}{
+		{"upstream", 1},
+		{"downstream", 1},
+		{"both", 2},
+	} {
+		deps, err := s.ListDependencies(ctx, a.ID, tc.direction)
+		if err != nil {
+			t.Fatalf("ListDependencies(%s): %v", tc.direction, err)
+		}
+		if len(deps) != tc.want {
+			t.Errorf("ListDependencies(%s) = %d edges, want %d", tc.direction, len(deps), tc.want)
+		}
+	}
+
+	if err := s.RemoveDependency(ctx, a.ID, b.ID); err != nil {
+		t.Fatalf("RemoveDependency: %v", err)
+	}
+	deps, err := s.ListDependencies(ctx, a.ID, "upstream")
+	if err != nil {
+		t.Fatalf("ListDependencies: %v", err)
+	}
+	if len(deps) != 0 {
+		t.Errorf("upstream deps after removal = %+v, want none", deps)
+	}
+	if !readySet(t, ctx, s)[a.ID] {
+		t.Error("issue should be ready once its blocks edge is removed")
+	}
+}
+
+func testAddDependencyUpsert(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	a := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "a"})
+	b := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "b"})
+
+	// Re-adding an existing edge updates its type instead of failing or duplicating.
+	addDep(t, ctx, s, a.ID, b.ID, model.DepRelated)
+	if !readySet(t, ctx, s)[a.ID] {
+		t.Error("a related edge should not block")
+	}
+
+	addDep(t, ctx, s, a.ID, b.ID, model.DepBlocks)
+	deps, err := s.ListDependencies(ctx, a.ID, "upstream")
+	if err != nil {
+		t.Fatalf("ListDependencies: %v", err)
+	}
+	if len(deps) != 1 || deps[0].Type != model.DepBlocks {
+		t.Fatalf("deps = %+v, want a single blocks edge", deps)
+	}
+	if readySet(t, ctx, s)[a.ID] {
+		t.Error("upgrading the edge to blocks should make the issue not ready")
+	}
+
+	addDep(t, ctx, s, a.ID, b.ID, model.DepRelated)
+	deps, _ = s.ListDependencies(ctx, a.ID, "upstream")
+	if len(deps) != 1 || deps[0].Type != model.DepRelated {
+		t.Errorf("deps = %+v, want a single related edge", deps)
+	}
+}
+
+func testDependencyCycles(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	a := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "a"})
+	b := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "b"})
+	c := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "c"})
+	addDep(t, ctx, s, a.ID, b.ID, model.DepBlocks)
+	addDep(t, ctx, s, b.ID, c.ID, model.DepWaitsFor)
+
+	_, err := s.AddDependency(ctx, store.AddDependencyInput{IssueID: c.ID, DependsOnID: a.ID, Type: model.DepBlocks})
+	var cycle *store.CycleError
+	if !errors.As(err, &cycle) || !errors.Is(err, store.ErrCycle) {
+		t.Fatalf("closing a blocking cycle should fail with *CycleError, got %v", err)
+	}
+	if got, want := strings.Join(cycle.Path, ","), strings.Join([]string{c.ID, a.ID, b.ID, c.ID}, ","); got != want {
+		t.Errorf("cycle path = %v, want %s → %s → %s → %s", cycle.Path, c.ID, a.ID, b.ID, c.ID)
+	}
+	if !strings.Contains(err.Error(), c.ID+" → "+a.ID+" → "+b.ID+" → "+c.ID) {
+		t.Errorf("error should show the path, got %q", err)
+	}
+
+	if _, err := s.AddDependency(ctx, store.AddDependencyInput{IssueID: a.ID, DependsOnID: a.ID, Type: model.DepBlocks}); !errors.Is(err, store.ErrCycle) {
+		t.Errorf("self-dependency should be rejected, got %v", err)
+	}
+	// Informational edges may point back; they never gate work.
+	addDep(t, ctx, s, c.ID, a.ID, model.DepRelated)
+	// Upgrading that edge to a blocking type would close the cycle.
+	if _, err := s.AddDependency(ctx, store.AddDependencyInput{IssueID: c.ID, DependsOnID: a.ID, Type: model.DepConditionalBlocks}); !errors.Is(err, store.ErrCycle) {
+		t.Errorf("upgrading an edge into a cycle should be rejected, got %v", err)
+	}
+
+	parent := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "parent"})
+	child := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "child", ParentID: parent.ID})
+	_, err = s.AddDependency(ctx, store.AddDependencyInput{IssueID: parent.ID, DependsOnID: child.ID, Type: model.DepParentChild})
+	if !errors.As(err, &cycle) || strings.Join(cycle.Path, ",") != parent.ID+","+child.ID+","+parent.ID {
+		t.Errorf("parent-child cycle should be rejected with its path, got %v", err)
+	}
+	// Families are checked separately: a parent may wait on its child.
+	addDep(t, ctx, s, parent.ID, child.ID, model.DepBlocks)
+
+	if _, err := s.ApplyBatch(ctx, []store.BatchOp{
+		{Ref: "d", Create: &store.CreateIssueInput{Title: "d"}},
+		{Dependency: &store.AddDependencyInput{IssueID: "$d", DependsOnID: a.ID, Type: model.DepBlocks}},
+		{Dependency: &store.AddDependencyInput{IssueID: c.ID, DependsOnID: "$d", Type: model.DepBlocks}},
+	}); !errors.Is(err, store.ErrCycle) {
+		t.Errorf("batch closing a cycle should fail, got %v", err)
+	}
+
+	report, err := s.ValidateGraph(ctx)
+	if err != nil {
+		t.Fatalf("ValidateGraph: %v", err)
+	}
+	if len(report.Cycles) != 0 {
+		t.Errorf("rejected edges should leave no cycles, got %+v", report.Cycles)
+	}
+}
+
+func testValidateGraph(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	report, err := s.ValidateGraph(ctx)
+	if err != nil {
+		t.Fatalf("ValidateGraph: %v", err)
+	}
+	if !report.Valid || report.Issues != 0 {
+		t.Errorf("empty tenant should be valid, got %+v", report)
+	}
+
+	epic := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "epic"})
+	childID, err := s.NextChildID(ctx, epic.ID)
+	if err != nil {
+		t.Fatalf("NextChildID: %v", err)
+	}
+	child := CreateIssue(t, ctx, s, store.CreateIssueInput{ID: childID, Title: "child", ParentID: epic.ID})
+	other := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "other"})
+	addDep(t, ctx, s, other.ID, child.ID, model.DepBlocks)
+
+	report, err = s.ValidateGraph(ctx)
+	if err != nil {
+		t.Fatalf("ValidateGraph: %v", err)
+	}
+	if !report.Valid || report.Issues != 3 || report.Dependencies != 2 {
+		t.Errorf("healthy graph should be valid with 3 issues and 2 edges, got %+v", report)
+	}
+
+	if err := s.DeleteIssue(ctx, epic.ID); err != nil {
+		t.Fatalf("DeleteIssue: %v", err)
+	}
+	report, err = s.ValidateGraph(ctx)
+	if err != nil {
+		t.Fatalf("ValidateGraph: %v", err)
+	}
+	want := model.OrphanedChild{IssueID: child.ID, ParentID: epic.ID, ParentExists: false}
+	if report.Valid || len(report.OrphanedChildren) != 1 || report.OrphanedChildren[0] != want {
+		t.Errorf("deleting the parent should orphan %s, got %+v", child.ID, report)
+	}
+
+	// Other tenants' graphs are not included.
+	if report, err := s.ValidateGraph(NewTenant(t, s)); err != nil || report.Issues != 0 {
+		t.Errorf("fresh tenant should see an empty graph, got %+v (%v)", report, err)
+	}
+}
+
+func testReadyDetection(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+
+	plain := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "plain", Priority: 3})
+	urgent := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "urgent", Priority: 1})
+	blocker := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "blocker"})
+	blocked := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "blocked"})
+	wisp := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "wisp", Ephemeral: true})
+	working := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "working"})
+	related := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "related"})
+
+	addDep(t, ctx, s, blocked.ID, blocker.ID, model.DepBlocks)
+	addDep(t, ctx, s, related.ID, blocker.ID, model.DepRelated)
+	setStatus(t, ctx, s, working.ID, model.StatusInProgress)
+
+	ready := readySet(t, ctx, s)
+	for _, id := range []string{plain.ID, urgent.ID, blocker.ID, related.ID} {
+		if !ready[id] {
+			t.Errorf("%s should be ready", id)
+		}
+	}
+	for _, id := range []string{blocked.ID, wisp.ID, working.ID} {
+		if ready[id] {
+			t.Errorf("%s should not be ready", id)
+		}
+	}
+
+	list, err := s.ListReady(ctx, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListReady: %v", err)
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Priority > list[i].Priority {
+			t.Errorf("ListReady not ordered by priority: %d before %d", list[i-1].Priority, list[i].Priority)
+		}
+	}
+	limited, err := s.ListReady(ctx, model.IssueFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListReady: %v", err)
+	}
+	if len(limited) != 1 || limited[0].ID != urgent.ID {
+		t.Errorf("ListReady(limit 1) = %v, want [%s]", issueIDs(limited), urgent.ID)
+	}
+
+	// Blocks edges only hold while the blocker is open.
+	setStatus(t, ctx, s, blocker.ID, model.StatusInProgress)
+	if readySet(t, ctx, s)[blocked.ID] {
+		t.Error("an in-progress blocker should still block")
+	}
+	setStatus(t, ctx, s, blocker.ID, model.StatusClosed)
+	if !readySet(t, ctx, s)[blocked.ID] {
+		t.Error("closing the blocker should make the blocked issue ready")
+	}
+}
+
+func testReadyFlags(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	severe := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "severe"})
+	minor := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "minor"})
+
+	flag, err := s.RaiseFlag(ctx, store.RaiseFlagInput{IssueID: severe.ID, Type: "red_flag", Severity: 1, Summary: "stop"})
+	if err != nil {
+		t.Fatalf("RaiseFlag: %v", err)
+	}
+	if _, err := s.RaiseFlag(ctx, store.RaiseFlagInput{IssueID: minor.ID, Type: "question", Severity: 3, Summary: "fyi"}); err != nil {
+		t.Fatalf("RaiseFlag: %v", err)
+	}
+
+	ready := readySet(t, ctx, s)
+	if ready[severe.ID] {
+		t.Error("an open flag with severity <= 2 should block readiness")
+	}
+	if !ready[minor.ID] {
+		t.Error("a severity 3 flag should not block readiness")
+	}
+
+	if _, err := s.ResolveFlag(ctx, flag.ID, "handled", "tester"); err != nil {
+		t.Fatalf("ResolveFlag: %v", err)
+	}
+	if !readySet(t, ctx, s)[severe.ID] {
+		t.Error("resolving the flag should make the issue ready")
+	}
+}
+
+func createChild(t *testing.T, ctx context.Context, s store.Store, parentID, title string) *model.Issue {
+	t.Helper()
+	id, err := s.NextChildID(ctx, parentID)
+	if err != nil {
+		t.Fatalf("NextChildID: %v", err)
+	}
+	return CreateIssue(t, ctx, s, store.CreateIssueInput{ID: id, Title: title, ParentID: parentID})
+}
+
+func closeWithReason(t *testing.T, ctx context.Context, s store.Store, id, reason string) {
+	t.Helper()
+	closed := model.StatusClosed
+	if _, err := s.UpdateIssue(ctx, id, store.UpdateIssueInput{Status: &closed, CloseReason: &reason}); err != nil {
+		t.Fatalf("UpdateIssue(%s, closed): %v", id, err)
+	}
+}
+
+func testReadyGates(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+
+	// conditional-blocks: the fallback runs only if its target fails.
+	attempt := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "attempt"})
+	fallback := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "fallback"})
+	addDep(t, ctx, s, fallback.ID, attempt.ID, model.DepConditionalBlocks)
+	passed := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "passed"})
+	unneeded := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "unneeded"})
+	addDep(t, ctx, s, unneeded.ID, passed.ID, model.DepConditionalBlocks)
+
+	// waits-for: fan-in on a parent and all of its children.
+	epic := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "epic", IssueType: model.TypeEpic})
+	part := createChild(t, ctx, s, epic.ID, "part")
+	join := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "join"})
+	addDep(t, ctx, s, join.ID, epic.ID, model.DepWaitsFor)
+
+	// until: only worth doing while the target is open.
+	release := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "release"})
+	hotfix := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "hotfix"})
+	addDep(t, ctx, s, hotfix.ID, release.ID, model.DepUntil)
+
+	ready := readySet(t, ctx, s)
+	for _, id := range []string{attempt.ID, passed.ID, epic.ID, part.ID, release.ID, hotfix.ID} {
+		if !ready[id] {
+			t.Errorf("%s should be ready", id)
+		}
+	}
+	for _, id := range []string{fallback.ID, unneeded.ID, join.ID} {
+		if ready[id] {
+			t.Errorf("%s should not be ready", id)
+		}
+	}
+
+	closeWithReason(t, ctx, s, attempt.ID, "Failed: upstream rejected the patch")
+	closeWithReason(t, ctx, s, passed.ID, "fixed failing test")
+	setStatus(t, ctx, s, epic.ID, model.StatusClosed)
+	setStatus(t, ctx, s, release.ID, model.StatusClosed)
+
+	ready = readySet(t, ctx, s)
+	if !ready[fallback.ID] {
+		t.Error("a conditional-blocks fallback should be ready once its target fails")
+	}
+	if ready[unneeded.ID] {
+		t.Error("a conditional-blocks fallback should stay gated when its target succeeds")
+	}
+	if ready[join.ID] {
+		t.Error("waits-for should hold while a child of the target is open")
+	}
+	if ready[hotfix.ID] {
+		t.Error("an until edge should gate once its target closes")
+	}
+
+	checkExplainAgrees(t, ctx, s, fallback.ID, unneeded.ID, join.ID, hotfix.ID, part.ID)
+
+	setStatus(t, ctx, s, part.ID, model.StatusClosed)
+	if !readySet(t, ctx, s)[join.ID] {
+		t.Error("waits-for should release once the target and its children are closed")
+	}
+
+	// parent-child: a held-back ancestor holds back its whole subtree.
+	roadmap := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "roadmap", IssueType: model.TypeEpic})
+	feature := createChild(t, ctx, s, roadmap.ID, "feature")
+	step := createChild(t, ctx, s, feature.ID, "step")
+	blocker := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "blocker"})
+
+	for _, tc := range []struct {
+		name string
+		hold func()
+		free func()
+	}{
+		{"deferred", func() { setStatus(t, ctx, s, roadmap.ID, model.StatusDeferred) },
+			func() { setStatus(t, ctx, s, roadmap.ID, model.StatusOpen) }},
+		{"blocked", func() { setStatus(t, ctx, s, roadmap.ID, model.StatusBlocked) },
+			func() { setStatus(t, ctx, s, roadmap.ID, model.StatusOpen) }},
+		{"gated", func() { addDep(t, ctx, s, roadmap.ID, blocker.ID, model.DepBlocks) },
+			func() { setStatus(t, ctx, s, blocker.ID, model.StatusClosed) }},
+	} {
+		tc.hold()
+		ready = readySet(t, ctx, s)
+		if ready[feature.ID] || ready[step.ID] {
+			t.Errorf("%s ancestor: descendants should not be ready", tc.name)
+		}
+		checkExplainAgrees(t, ctx, s, roadmap.ID, feature.ID, step.ID)
+		tc.free()
+		ready = readySet(t, ctx, s)
+		if !ready[feature.ID] || !ready[step.ID] {
+			t.Errorf("%s ancestor released: descendants should be ready", tc.name)
+		}
+	}
+
+	// Claiming honors the same gates.
+	claimCtx := NewTenant(t, s)
+	parent := CreateIssue(t, claimCtx, s, store.CreateIssueInput{Title: "parent", Priority: 3})
+	createChild(t, claimCtx, s, parent.ID, "child")
+	setStatus(t, claimCtx, s, parent.ID, model.StatusDeferred)
+	got, err := s.ClaimNextReady(claimCtx, store.ClaimNextReadyInput{Claimant: "agent"})
+	if err != nil {
+		t.Fatalf("ClaimNextReady: %v", err)
+	}
+	if got != nil {
+		t.Errorf("ClaimNextReady claimed %s under a deferred parent", got.ID)
+	}
+}
+
+func testReadyPolicy(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	project, err := s.CreateProject(ctx, "Gates", "gates")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	projectID := project.ID.String()
+
+	policy, err := s.GetReadyPolicy(ctx, projectID)
+	if err != nil {
+		t.Fatalf("GetReadyPolicy: %v", err)
+	}
+	if !policy.Default || len(policy.Gates) != len(model.ReadyGates) {
+		t.Errorf("new project policy = %+v, want the default", policy)
+	}
+
+	blocker := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "blocker", ProjectID: projectID})
+	blocked := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "blocked", ProjectID: projectID})
+	addDep(t, ctx, s, blocked.ID, blocker.ID, model.DepBlocks)
+	epic := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "epic", IssueType: model.TypeEpic, ProjectID: projectID})
+	child := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "child", ParentID: epic.ID, ProjectID: projectID})
+	setStatus(t, ctx, s, epic.ID, model.StatusDeferred)
+
+	// The same shape outside the project keeps the default gates.
+	otherBlocker := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "other blocker"})
+	otherBlocked := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "other blocked"})
+	addDep(t, ctx, s, otherBlocked.ID, otherBlocker.ID, model.DepBlocks)
+
+	ready := readySet(t, ctx, s)
+	if ready[blocked.ID] || ready[child.ID] || ready[otherBlocked.ID] {
+		t.Fatal("default policy should gate blocks and parent-child")
+	}
+
+	policy, err = s.SetReadyPolicy(ctx, projectID, []model.DependencyType{model.DepParentChild, model.DepParentChild})
+	if err != nil {
+		t.Fatalf("SetReadyPolicy: %v", err)
+	}
+	if policy.Default || len(policy.Gates) != 1 || policy.Gates[0] != model.DepParentChild {
+		t.Errorf("SetReadyPolicy = %+v, want only parent-child", policy)
+	}
+	ready = readySet(t, ctx, s)
+	if !ready[blocked.ID] {
+		t.Error("blocks is off for the project, so blocked should be ready")
+	}
+	if ready[child.ID] {
+		t.Error("parent-child is still on, so the deferred epic's child should not be ready")
+	}
+	if ready[otherBlocked.ID] {
+		t.Error("the policy should not apply outside its project")
+	}
+
+	if _, err := s.SetReadyPolicy(ctx, projectID, []model.DependencyType{}); err != nil {
+		t.Fatalf("SetReadyPolicy(empty): %v", err)
+	}
+	if ready = readySet(t, ctx, s); !ready[blocked.ID] || !ready[child.ID] {
+		t.Error("an empty policy should gate nothing")
+	}
+	if got, err := s.GetReadyPolicy(ctx, projectID); err != nil || got.Default || len(got.Gates) != 0 {
+		t.Errorf("GetReadyPolicy after emptying = %+v, %v", got, err)
+	}
+
+	if _, err := s.SetReadyPolicy(ctx, projectID, []model.DependencyType{model.DepRelated}); err == nil {
+		t.Error("related is not a ready gate and should be rejected")
+	}
+
+	if policy, err = s.SetReadyPolicy(ctx, projectID, nil); err != nil || !policy.Default {
+		t.Fatalf("SetReadyPolicy(nil) = %+v, %v; want the default", policy, err)
+	}
+	if ready = readySet(t, ctx, s); ready[blocked.ID] || ready[child.ID] {
+		t.Error("resetting the policy should restore every gate")
+	}
+
+	other := NewTenant(t, s)
+	if _, err := s.GetReadyPolicy(other, projectID); err == nil {
+		t.Error("another tenant should not see the project's policy")
+	}
+	if _, err := s.SetReadyPolicy(other, projectID, nil); err == nil {
+		t.Error("another tenant should not change the project's policy")
+	}
+}
+
+// checkExplainAgrees fails the test if ExplainReady disagrees with
+// ListReady about any of ids.
+func checkExplainAgrees(t *testing.T, ctx context.Context, s store.Store, ids ...string) {
+	t.Helper()
+	ready := readySet(t, ctx, s)
+	for _, id := range ids {
+		exp, err := store.ExplainReady(ctx, s, id)
+		if err != nil {
+			t.Fatalf("ExplainReady(%s): %v", id, err)
+		}
+		if exp.Ready != ready[id] {
+			t.Errorf("ExplainReady(%s).Ready = %v, but ListReady says %v (%+v)", id, exp.Ready, ready[id], exp.Reasons)
+		}
+	}
+}
+
+func testExplainReady(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+
+	// chain: target ← mid ← root, where root is claimed by bob.
+	root := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "root", Assignee: "bob"})
+	setStatus(t, ctx, s, root.ID, model.StatusInProgress)
+	mid := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "mid"})
+	addDep(t, ctx, s, mid.ID, root.ID, model.DepBlocks)
+	target := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "target"})
+	addDep(t, ctx, s, target.ID, mid.ID, model.DepBlocks)
+
+	epic := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "epic", IssueType: model.TypeEpic})
+	child := createChild(t, ctx, s, epic.ID, "child")
+	setStatus(t, ctx, s, epic.ID, model.StatusDeferred)
+
+	flagged := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "flagged", Ephemeral: true})
+	if _, err := s.RaiseFlag(ctx, store.RaiseFlagInput{IssueID: flagged.ID, Type: "red_flag", Severity: 2, Summary: "stop"}); err != nil {
+		t.Fatalf("RaiseFlag: %v", err)
+	}
+	free := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "free"})
+
+	explain := func(id string) *model.ReadyExplanation {
+		t.Helper()
+		exp, err := store.ExplainReady(ctx, s, id)
+		if err != nil {
+			t.Fatalf("ExplainReady(%s): %v", id, err)
+		}
+		return exp
+	}
+	kinds := func(exp *model.ReadyExplanation) string {
+		var k []string
+		for _, r := range exp.Reasons {
+			k = append(k, r.Kind)
+		}
+		return strings.Join(k, ",")
+	}
+
+	checkExplainAgrees(t, ctx, s, root.ID, mid.ID, target.ID, epic.ID, child.ID, flagged.ID, free.ID)
+
+	exp := explain(target.ID)
+	if kinds(exp) != "dependency" || len(exp.Reasons[0].Blockers) != 1 {
+		t.Fatalf("target reasons = %+v, want one dependency reason", exp.Reasons)
+	}
+	b := exp.Reasons[0].Blockers[0]
+	if b.ID != mid.ID || b.Status != model.StatusOpen || len(b.BlockedBy) != 1 {
+		t.Fatalf("first blocker = %+v, want %s blocked by %s", b, mid.ID, root.ID)
+	}
+	if r := b.BlockedBy[0]; r.ID != root.ID || r.Status != model.StatusInProgress || r.Assignee != "bob" {
+		t.Errorf("transitive blocker = %+v, want %s in progress with bob", r, root.ID)
+	}
+
+	if got := kinds(explain(root.ID)); got != "status" {
+		t.Errorf("in-progress root reasons = %s, want status", got)
+	}
+	if exp := explain(child.ID); kinds(exp) != "ancestor" || exp.Reasons[0].IssueID != epic.ID {
+		t.Errorf("child of a deferred epic reasons = %+v, want ancestor %s", exp.Reasons, epic.ID)
+	}
+	if got := kinds(explain(flagged.ID)); got != "ephemeral,flag" {
+		t.Errorf("flagged wisp reasons = %s, want ephemeral,flag", got)
+	}
+	if exp := explain(free.ID); !exp.Ready || len(exp.Reasons) != 0 {
+		t.Errorf("free issue explanation = %+v, want ready", exp)
+	}
+
+	if _, err := store.ExplainReady(ctx, s, "doit-missing"); err == nil {
+		t.Error("explaining a missing issue should fail")
+	}
+}
+
+func testCriticalPath(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	minutes := func(n int) *int { return &n }
+
+	epic := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "epic", IssueType: model.TypeEpic})
+	task := func(parentID, title string, estimate int) *model.Issue {
+		t.Helper()
+		id, err := s.NextChildID(ctx, parentID)
+		if err != nil {
+			t.Fatalf("NextChildID: %v", err)
+		}
+		return CreateIssue(t, ctx, s, store.CreateIssueInput{
+			ID: id, Title: title, ParentID: parentID, EstimatedMinutes: minutes(estimate),
+		})
+	}
+	done := task(epic.ID, "done", 500)
+	design := task(epic.ID, "design", 60)
+	backend := task(epic.ID, "backend", 0) // a container: finishes with its children
+	api := task(backend.ID, "api", 120)
+	docs := task(epic.ID, "docs", 30)
+	outside := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "outside"})
+
+	if design.EstimatedMinutes == nil || *design.EstimatedMinutes != 60 {
+		t.Fatalf("CreateIssue estimate = %v, want 60", design.EstimatedMinutes)
+	}
+	if backend.EstimatedMinutes != nil {
+		t.Errorf("a zero estimate should be stored as none, got %d", *backend.EstimatedMinutes)
+	}
+
+	addDep(t, ctx, s, api.ID, design.ID, model.DepBlocks)
+	addDep(t, ctx, s, docs.ID, design.ID, model.DepBlocks)
+	addDep(t, ctx, s, docs.ID, done.ID, model.DepBlocks)
+	addDep(t, ctx, s, docs.ID, outside.ID, model.DepBlocks)
+	setStatus(t, ctx, s, done.ID, model.StatusClosed)
+
+	start := time.Now().UTC()
+	sched, err := store.CriticalPath(ctx, s, epic.ID, start)
+	if err != nil {
+		t.Fatalf("CriticalPath: %v", err)
+	}
+	if sched.TotalMinutes != 180 || !sched.EarliestFinish.Equal(start.Add(180*time.Minute)) {
+		t.Errorf("total = %d minutes, want 180", sched.TotalMinutes)
+	}
+	if want := []string{design.ID, api.ID, backend.ID}; strings.Join(sched.CriticalPath, ",") != strings.Join(want, ",") {
+		t.Errorf("critical path = %v, want %v", sched.CriticalPath, want)
+	}
+	if strings.Join(sched.Unestimated, ",") != backend.ID {
+		t.Errorf("unestimated = %v, want [%s]", sched.Unestimated, backend.ID)
+	}
+	for _, st := range sched.Tasks {
+		switch st.ID {
+		case done.ID:
+			t.Error("closed tasks should not be scheduled")
+		case docs.ID:
+			if st.Slack != 90 || st.Critical || strings.Join(st.ExternalBlockers, ",") != outside.ID {
+				t.Errorf("docs = %+v, want slack 90 and external blocker %s", st, outside.ID)
+			}
+		}
+	}
+
+	if _, err := s.UpdateIssue(ctx, docs.ID, store.UpdateIssueInput{EstimatedMinutes: minutes(400)}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if sched, err = store.CriticalPath(ctx, s, epic.ID, start); err != nil {
+		t.Fatalf("CriticalPath: %v", err)
+	}
+	if sched.TotalMinutes != 460 || strings.Join(sched.CriticalPath, ",") != design.ID+","+docs.ID {
+		t.Errorf("after re-estimating docs: total %d, path %v", sched.TotalMinutes, sched.CriticalPath)
+	}
+
+	cleared, err := s.UpdateIssue(ctx, docs.ID, store.UpdateIssueInput{EstimatedMinutes: minutes(0)})
+	if err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if cleared.EstimatedMinutes != nil {
+		t.Errorf("an estimate of 0 should clear it, got %d", *cleared.EstimatedMinutes)
+	}
+}
+
+func testClaimNextReady(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	project, err := s.CreateProject(ctx, "Claims", "claims")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	projectID := project.ID.String()
+
+	low := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "low", Priority: 3})
+	high := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "high", Priority: 1})
+	blocker := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "blocker", Priority: 4})
+	blocked := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "blocked", Priority: 1})
+	addDep(t, ctx, s, blocked.ID, blocker.ID, model.DepBlocks)
+	bug := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "bug", IssueType: model.TypeBug, Priority: 4})
+	labeled := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "labeled", Priority: 4, Labels: []string{"db", "urgent"}})
+	scoped := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "scoped", Priority: 4, ProjectID: projectID})
+
+	claim := func(input store.ClaimNextReadyInput) *model.Issue {
+		t.Helper()
+		if input.Claimant == "" {
+			input.Claimant = "worker"
+		}
+		issue, err := s.ClaimNextReady(ctx, input)
+		if err != nil {
+			t.Fatalf("ClaimNextReady(%+v): %v", input, err)
+		}
+		return issue
+	}
+
+	bugType := model.TypeBug
+	if got := claim(store.ClaimNextReadyInput{IssueType: &bugType}); got == nil || got.ID != bug.ID {
+		t.Errorf("type filter claimed %v, want %s", got, bug.ID)
+	}
+	if got := claim(store.ClaimNextReadyInput{Labels: []string{"db", "missing"}}); got != nil {
+		t.Errorf("every label must match, claimed %s", got.ID)
+	}
+	if got := claim(store.ClaimNextReadyInput{Labels: []string{"urgent", "db"}}); got == nil || got.ID != labeled.ID {
+		t.Errorf("label filter claimed %v, want %s", got, labeled.ID)
+	}
+	if got := claim(store.ClaimNextReadyInput{ProjectID: &projectID}); got == nil || got.ID != scoped.ID {
+		t.Errorf("project filter claimed %v, want %s", got, scoped.ID)
+	}
+
+	// Unfiltered claims go by priority and never hand out blocked issues.
+	got := claim(store.ClaimNextReadyInput{Claimant: "alice"})
+	if got == nil || got.ID != high.ID {
+		t.Fatalf("claimed %v, want the P1 issue %s", got, high.ID)
+	}
+	if got.Assignee != "alice" || got.Status != model.StatusInProgress {
+		t.Errorf("claimed issue = assignee %q status %s, want alice in_progress", got.Assignee, got.Status)
+	}
+	if ready := readySet(t, ctx, s); ready[high.ID] {
+		t.Error("a claimed issue should leave the ready set")
+	}
+	events, err := s.ListEvents(ctx, high.ID, 0)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) == 0 || events[0].EventType != model.EventStatusChanged || events[0].Actor != "alice" ||
+		events[0].OldValue != string(model.StatusOpen) || events[0].NewValue != string(model.StatusInProgress) {
+		t.Errorf("claim events = %+v, want the newest to be an open -> in_progress status_changed by alice", events)
+	}
+
+	if got := claim(store.ClaimNextReadyInput{}); got == nil || got.ID != low.ID {
+		t.Errorf("claimed %v, want %s", got, low.ID)
+	}
+	if got := claim(store.ClaimNextReadyInput{}); got == nil || got.ID != blocker.ID {
+		t.Errorf("claimed %v, want %s", got, blocker.ID)
+	}
+	if got := claim(store.ClaimNextReadyInput{}); got != nil {
+		t.Errorf("only a blocked issue is left, but claimed %s", got.ID)
+	}
+
+	if _, err := s.ClaimNextReady(ctx, store.ClaimNextReadyInput{}); err == nil {
+		t.Error("ClaimNextReady without a claimant should fail")
+	}
+}
+
+func testClaimNextReadyConcurrent(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	const issues, workers = 12, 4
+	for i := 0; i < issues; i++ {
+		CreateIssue(t, ctx, s, store.CreateIssueInput{Title: fmt.Sprintf("job %d", i)})
+	}
+
+	var mu sync.Mutex
+	claimedBy := map[string]string{}
+	var wg sync.WaitGroup
+	errs := make(chan error, issues+workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			for {
+				issue, err := s.ClaimNextReady(ctx, store.ClaimNextReadyInput{Claimant: name})
+				if err != nil {
+					errs <- err
+					return
+				}
+				if issue == nil {
+					return
+				}
+				mu.Lock()
+				if prev, dup := claimedBy[issue.ID]; dup {
+					errs <- fmt.Errorf("%s claimed by both %s and %s", issue.ID, prev, name)
+				}
+				claimedBy[issue.ID] = name
+				mu.Unlock()
+			}
+		}(fmt.Sprintf("worker-%d", w))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if len(claimedBy) != issues {
+		t.Errorf("claimed %d issues, want %d", len(claimedBy), issues)
+	}
+}
+
+// testClaimNextReadyContention releases many claimants at once on a single
+// ready issue, round after round: exactly one may win it, and the winner's
+// assignment must stick.
+func testClaimNextReadyContention(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	const rounds, workers = 20, 8
+	for r := 0; r < rounds; r++ {
+		issue := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: fmt.Sprintf("round %d", r)})
+
+		start := make(chan struct{})
+		winners := make(chan string, workers)
+		errs := make(chan error, workers)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				<-start
+				claimed, err := s.ClaimNextReady(ctx, store.ClaimNextReadyInput{Claimant: name})
+				if err != nil {
+					errs <- err
+					return
+				}
+				if claimed != nil {
+					winners <- name
+				}
+			}(fmt.Sprintf("worker-%d", w))
+		}
+		close(start)
+		wg.Wait()
+		close(winners)
+		close(errs)
+		for err := range errs {
+			t.Fatalf("round %d: %v", r, err)
+		}
+
+		var won []string
+		for name := range winners {
+			won = append(won, name)
+		}
+		if len(won) != 1 {
+			t.Fatalf("round %d: claimed by %v, want exactly one claimant", r, won)
+		}
+		got, err := s.GetIssue(ctx, issue.ID)
+		if err != nil {
+			t.Fatalf("GetIssue: %v", err)
+		}
+		if got.Status != model.StatusInProgress || got.Assignee != won[0] {
+			t.Fatalf("round %d: issue = %s assigned to %q, want in_progress for %s", r, got.Status, got.Assignee, won[0])
+		}
+	}
+}
+
+func testClaimLeases(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	leased := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "leased", Priority: 1})
+	forever := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "no lease", Priority: 2})
+	finished := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "finished", Priority: 3})
+
+	claimed, err := s.ClaimNextReady(ctx, store.ClaimNextReadyInput{Claimant: "alice", Lease: time.Hour})
+	if err != nil || claimed == nil || claimed.ID != leased.ID {
+		t.Fatalf("ClaimNextReady = %v, %v; want %s", claimed, err, leased.ID)
+	}
+	if claimed.LeaseExpiresAt == nil || claimed.LastActivity == nil {
+		t.Fatalf("a leased claim should set lease_expires_at and last_activity, got %v / %v",
+			claimed.LeaseExpiresAt, claimed.LastActivity)
+	}
+	if d := claimed.LeaseExpiresAt.Sub(*claimed.LastActivity); d < 59*time.Minute || d > 61*time.Minute {
+		t.Errorf("lease length = %v, want an hour", d)
+	}
+	if c, err := s.ClaimNextReady(ctx, store.ClaimNextReadyInput{Claimant: "bob"}); err != nil || c == nil || c.ID != forever.ID {
+		t.Fatalf("ClaimNextReady = %v, %v; want %s", c, err, forever.ID)
+	} else if c.LeaseExpiresAt != nil {
+		t.Errorf("a claim without a lease should not expire, got %v", c.LeaseExpiresAt)
+	}
+
+	// Only the holder can renew.
+	if _, err := s.RenewLease(ctx, leased.ID, "bob", time.Hour); !errors.Is(err, store.ErrLeaseLost) {
+		t.Errorf("RenewLease by another agent: err = %v, want ErrLeaseLost", err)
+	}
+	renewed, err := s.RenewLease(ctx, leased.ID, "alice", 2*time.Hour)
+	if err != nil {
+		t.Fatalf("RenewLease: %v", err)
+	}
+	if renewed.LeaseExpiresAt == nil || !renewed.LeaseExpiresAt.After(*claimed.LeaseExpiresAt) {
+		t.Errorf("renewal should push the lease out: %v then %v", claimed.LeaseExpiresAt, renewed.LeaseExpiresAt)
+	}
+	if _, err := s.RenewLease(ctx, "doit-missing", "alice", time.Hour); err == nil || errors.Is(err, store.ErrLeaseLost) {
+		t.Errorf("RenewLease on a missing issue: err = %v, want not found", err)
+	}
+
+	// Claiming through UpdateIssue leases too, and leaving in_progress ends the lease.
+	// The lease restarts last_activity, overriding an explicit one.
+	assignee, inProgress, lease, stale := "carol", model.StatusInProgress, time.Minute, time.Now().Add(-time.Hour)
+	claim, err := s.UpdateIssue(ctx, finished.ID, store.UpdateIssueInput{
+		Assignee: &assignee, Status: &inProgress, Lease: &lease, LastActivity: &stale,
+	})
+	if err != nil {
+		t.Fatalf("UpdateIssue claim: %v", err)
+	}
+	if claim.LastActivity == nil || time.Since(*claim.LastActivity) > time.Minute {
+		t.Errorf("last_activity = %v, want the lease's start", claim.LastActivity)
+	}
+	setStatus(t, ctx, s, finished.ID, model.StatusClosed)
+	if got, _ := s.GetIssue(ctx, finished.ID); got.LeaseExpiresAt != nil {
+		t.Errorf("closing should clear the lease, got %v", got.LeaseExpiresAt)
+	}
+
+	released, err := s.ReleaseExpiredLeases(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("ReleaseExpiredLeases: %v", err)
+	}
+	if len(released) != 0 {
+		t.Errorf("nothing has expired yet, released %v", issueIDs(released))
+	}
+
+	// Another tenant's reaper pass leaves this tenant alone.
+	if released, err := s.ReleaseExpiredLeases(NewTenant(t, s), time.Now().Add(3*time.Hour)); err != nil || len(released) != 0 {
+		t.Errorf("other tenant released %v, %v", released, err)
+	}
+
+	released, err = s.ReleaseExpiredLeases(ctx, time.Now().Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("ReleaseExpiredLeases: %v", err)
+	}
+	if !sameIDs(issueIDs(released), leased.ID) {
+		t.Fatalf("released %v, want only %s", issueIDs(released), leased.ID)
+	}
+	got, err := s.GetIssue(ctx, leased.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.Status != model.StatusOpen || got.Assignee != "" || got.LeaseExpiresAt != nil {
+		t.Errorf("released issue = status %s assignee %q lease %v, want open, unassigned, no lease",
+			got.Status, got.Assignee, got.LeaseExpiresAt)
+	}
+	if !readySet(t, ctx, s)[leased.ID] {
+		t.Error("a released issue should be ready again")
+	}
+
+	abandoned := model.RetryAbandoned
+	retries, err := s.ListRetries(ctx, leased.ID, model.RetryFilter{Status: &abandoned})
+	if err != nil {
+		t.Fatalf("ListRetries: %v", err)
+	}
+	if len(retries) != 1 || retries[0].Agent != "alice" || retries[0].CreatedBy != store.ReaperActor {
+		t.Errorf("retries = %+v, want one abandoned attempt by alice", retries)
+	}
+	events, err := s.ListEvents(ctx, leased.ID, 0)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) == 0 || events[0].EventType != model.EventStatusChanged || events[0].Actor != store.ReaperActor ||
+		events[0].NewValue != string(model.StatusOpen) {
+		t.Errorf("newest event = %+v, want the reaper reopening the issue", events)
+	}
+
+	if _, err := s.RenewLease(ctx, leased.ID, "alice", time.Hour); !errors.Is(err, store.ErrLeaseLost) {
+		t.Errorf("RenewLease after release: err = %v, want ErrLeaseLost", err)
+	}
+	if released, _ := s.ReleaseExpiredLeases(ctx, time.Now().Add(3*time.Hour)); len(released) != 0 {
+		t.Errorf("a second pass released %v again", issueIDs(released))
+	}
+}
+
+func testApplyBatch(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	existing := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "existing"})
+
+	results, err := s.ApplyBatch(ctx, []store.BatchOp{
+		{Ref: "epic", Create: &store.CreateIssueInput{Title: "epic", IssueType: model.TypeEpic, Labels: []string{"plan"}}},
+		{Ref: "$a", Create: &store.CreateIssueInput{Title: "a", ParentID: "$epic"}},
+		{Ref: "b", Create: &store.CreateIssueInput{Title: "b", ParentID: "$epic"}},
+		{Dependency: &store.AddDependencyInput{IssueID: "$b", DependsOnID: "$a", Type: model.DepBlocks}},
+		{Dependency: &store.AddDependencyInput{IssueID: existing.ID, DependsOnID: "$b", Type: model.DepBlocks}},
+		{Label: &store.BatchLabel{IssueID: "$a", Label: "backend"}},
+		{Comment: &store.BatchComment{IssueID: "$epic", Author: "planner", Text: "laid out"}},
+		{Update: &store.BatchUpdate{ID: "$a", Input: store.UpdateIssueInput{Priority: ptr(0)}}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	if len(results) != 8 {
+		t.Fatalf("expected one result per op, got %d", len(results))
+	}
+	epic, a, b := results[0].IssueID, results[1].IssueID, results[2].IssueID
+	if a != epic+".1" || b != epic+".2" {
+		t.Errorf("children of $epic should be numbered under it, got %s and %s", a, b)
+	}
+	if results[1].Ref != "$a" || results[2].Ref != "b" || results[3].Ref != "" {
+		t.Errorf("results should echo refs, got %q %q %q", results[1].Ref, results[2].Ref, results[3].Ref)
+	}
+	for i, want := range []string{"create", "create", "create", "dependency", "dependency", "label", "comment", "update"} {
+		if results[i].Op != want {
+			t.Errorf("result %d op = %q, want %q", i, results[i].Op, want)
+		}
+	}
+	if results[6].Comment == nil || results[6].Comment.IssueID != epic {
+		t.Errorf("comment should land on the epic, got %+v", results[6].Comment)
+	}
+	if results[7].Issue == nil || results[7].Issue.Priority != 0 {
+		t.Errorf("update should apply to $a, got %+v", results[7].Issue)
+	}
+
+	got, err := s.GetIssue(ctx, a)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.ParentID != epic || !sameIDs(got.Labels, "backend") || got.Status != model.StatusOpen {
+		t.Errorf("created child not as expected: %+v", got)
+	}
+	if ready := readySet(t, ctx, s); !ready[a] || ready[b] || ready[existing.ID] {
+		t.Errorf("dependencies from the batch should gate ready work, got %v", ready)
+	}
+	comments, err := s.ListComments(ctx, epic)
+	if err != nil || len(comments) != 1 {
+		t.Errorf("expected the batch comment on the epic, got %+v (%v)", comments, err)
+	}
+}
+
+func testApplyBatchRollback(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	epic := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "epic"})
+	before, err := s.ListIssues(ctx, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+
+	_, err = s.ApplyBatch(ctx, []store.BatchOp{
+		{Update: &store.BatchUpdate{ID: epic.ID, Input: store.UpdateIssueInput{Title: ptr("renamed")}}},
+		{Ref: "child", Create: &store.CreateIssueInput{Title: "child", ParentID: epic.ID, Labels: []string{"x"}}},
+		{Comment: &store.BatchComment{IssueID: epic.ID, Author: "me", Text: "hi"}},
+		{Dependency: &store.AddDependencyInput{IssueID: "$child", DependsOnID: "doit-missing", Type: model.DepBlocks}},
+	})
+	var batchErr *store.BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 3 || batchErr.Op != store.BatchOpDependency {
+		t.Fatalf("expected *BatchError at op 3, got %v", err)
+	}
+
+	after, err := s.ListIssues(ctx, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("failed batch left issues behind: %v", issueIDs(after))
+	}
+	got, err := s.GetIssue(ctx, epic.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.Title != "epic" {
+		t.Errorf("update from a failed batch should be rolled back, title is %q", got.Title)
+	}
+	if comments, _ := s.ListComments(ctx, epic.ID); len(comments) != 0 {
+		t.Errorf("comment from a failed batch should be rolled back, got %+v", comments)
+	}
+	if id, err := s.NextChildID(ctx, epic.ID); err != nil || id != epic.ID+".1" {
+		t.Errorf("child numbering should be rolled back, next child is %s (%v)", id, err)
+	}
+
+	// Errors from the failing op stay matchable through the BatchError.
+	_, err = s.ApplyBatch(ctx, []store.BatchOp{
+		{Update: &store.BatchUpdate{ID: epic.ID, Input: store.UpdateIssueInput{ExpectedContentHash: ptr("stale")}}},
+	})
+	if !errors.Is(err, store.ErrConflict) {
+		t.Errorf("expected a conflict through the batch error, got %v", err)
+	}
+
+	// References must point at an earlier create, and other tenants' issues
+	// are invisible.
+	if _, err := s.ApplyBatch(ctx, []store.BatchOp{
+		{Label: &store.BatchLabel{IssueID: "$later", Label: "x"}},
+		{Ref: "later", Create: &store.CreateIssueInput{Title: "later"}},
+	}); err == nil || !strings.Contains(err.Error(), "unknown reference") {
+		t.Errorf("forward reference should be rejected, got %v", err)
+	}
+	other := NewTenant(t, s)
+	if _, err := s.ApplyBatch(other, []store.BatchOp{
+		{Comment: &store.BatchComment{IssueID: epic.ID, Author: "spy", Text: "hi"}},
+	}); err == nil {
+		t.Error("batch should not reach another tenant's issue")
+	}
+}
+
+func testEvents(t *testing.T, s store.Store) {
+	ctx := auth.WithActor(NewTenant(t, s), "alice")
+	issue := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "audited", Priority: 2, CreatedBy: "bob"})
+	other := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "other"})
+
+	// events returns issue's events oldest first.
+	events := func() []model.Event {
+		t.Helper()
+		got, err := s.ListEvents(ctx, issue.ID, 0)
+		if err != nil {
+			t.Fatalf("ListEvents: %v", err)
+		}
+		for i, j := 0, len(got)-1; i < j; i, j = i+1, j-1 {
+			got[i], got[j] = got[j], got[i]
+		}
+		return got
+	}
+	since := func(n int) []model.Event {
+		t.Helper()
+		return events()[n:]
+	}
+
+	all := events()
+	if len(all) != 1 || all[0].EventType != model.EventCreated || all[0].Actor != "bob" {
+		t.Fatalf("after create = %+v, want one created event by the explicit creator", all)
+	}
+	if got, _ := s.ListEvents(ctx, other.ID, 0); len(got) != 1 || got[0].Actor != "alice" {
+		t.Errorf("events of an issue created without created_by = %+v, want the context actor", got)
+	}
+	n := len(all)
+
+	title, priority := "renamed", 1
+	closed := model.StatusClosed
+	if _, err := s.UpdateIssue(ctx, issue.ID, store.UpdateIssueInput{Title: &title, Priority: &priority, Status: &closed}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	got := since(n)
+	n += len(got)
+	// The close also sets closed_at, which is not tracked.
+	want := []struct {
+		typ           model.EventType
+		field, oldVal string
+		newVal        string
+	}{
+		{model.EventUpdated, "title", "audited", "renamed"},
+		{model.EventUpdated, "priority", "2", "1"},
+		{model.EventClosed, "status", "open", "closed"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("update events = %+v, want %d", got, len(want))
+	}
+	for i, w := range want {
+		e := got[i]
+		if e.EventType != w.typ || e.Field != w.field || e.OldValue != w.oldVal || e.NewValue != w.newVal || e.Actor != "alice" {
+			t.Errorf("event %d = %s %s %q -> %q by %s, want %s %s %q -> %q by alice",
+				i, e.EventType, e.Field, e.OldValue, e.NewValue, e.Actor, w.typ, w.field, w.oldVal, w.newVal)
+		}
+	}
+
+	setStatus(t, ctx, s, issue.ID, model.StatusOpen)
+	if got := since(n); len(got) != 1 || got[0].EventType != model.EventReopened {
+		t.Fatalf("reopen events = %+v, want one reopened", got)
+	}
+	n++
+
+	// Writing the same values again records nothing.
+	if _, err := s.UpdateIssue(ctx, issue.ID, store.UpdateIssueInput{Title: &title}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if got := since(n); len(got) != 0 {
+		t.Fatalf("no-op update recorded %+v", got)
+	}
+
+	if err := s.AddLabel(ctx, issue.ID, "db"); err != nil {
+		t.Fatalf("AddLabel: %v", err)
+	}
+	if err := s.AddLabel(ctx, issue.ID, "db"); err != nil {
+		t.Fatalf("AddLabel again: %v", err)
+	}
+	if err := s.RemoveLabel(ctx, issue.ID, "db"); err != nil {
+		t.Fatalf("RemoveLabel: %v", err)
+	}
+	addDep(t, ctx, s, issue.ID, other.ID, model.DepBlocks)
+	addDep(t, ctx, s, issue.ID, other.ID, model.DepBlocks)
+	addDep(t, ctx, s, issue.ID, other.ID, model.DepRelated)
+	if err := s.RemoveDependency(ctx, issue.ID, other.ID); err != nil {
+		t.Fatalf("RemoveDependency: %v", err)
+	}
+	comment, err := s.AddComment(ctx, issue.ID, "carol", "looks good")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if err := s.SaveCompactionSnapshot(ctx, issue.ID, 1, "summary", "original", store.UpdateIssueInput{}); err != nil {
+		t.Fatalf("SaveCompactionSnapshot: %v", err)
+	}
+
+	got = since(n)
+	wantTypes := []struct {
+		typ            model.EventType
+		oldVal, newVal string
+	}{
+		{model.EventLabelAdded, "", "db"},
+		{model.EventLabelRemoved, "db", ""},
+		{model.EventDependencyAdded, "", other.ID},
+		{model.EventDependencyAdded, string(model.DepBlocks), other.ID},
+		{model.EventDependencyRemoved, other.ID, ""},
+		{model.EventCommented, "", fmt.Sprint(comment.ID)},
+		{model.EventCompacted, "0", "1"},
+	}
+	if len(got) != len(wantTypes) {
+		t.Fatalf("events = %+v, want %d (duplicate label and dependency adds record nothing)", got, len(wantTypes))
+	}
+	for i, w := range wantTypes {
+		e := got[i]
+		if e.EventType != w.typ || e.OldValue != w.oldVal || e.NewValue != w.newVal {
+			t.Errorf("event %d = %s %q -> %q, want %s %q -> %q", i, e.EventType, e.OldValue, e.NewValue, w.typ, w.oldVal, w.newVal)
+		}
+		// The comment names its author; the rest fall back to the context.
+		wantActor := "alice"
+		if w.typ == model.EventCommented {
+			wantActor = "carol"
+		}
+		if e.Actor != wantActor {
+			t.Errorf("event %d actor = %q, want %s", i, e.Actor, wantActor)
+		}
+	}
+	if e := got[3]; e.Comment != string(model.DepRelated) {
+		t.Errorf("retype comment = %q, want the new type", e.Comment)
+	}
+	if e := got[5]; e.Comment != "looks good" {
+		t.Errorf("comment event text = %q", e.Comment)
+	}
+
+	// Without an actor in the context, the explicit author is used, then
+	// the system.
+	tenantID, _ := auth.TenantFromContext(ctx)
+	plain := auth.WithTenant(context.Background(), tenantID)
+	if _, err := s.AddComment(plain, issue.ID, "carol", "again"); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if err := s.AddLabel(plain, issue.ID, "ops"); err != nil {
+		t.Fatalf("AddLabel: %v", err)
+	}
+	latest, err := s.ListEvents(ctx, issue.ID, 2)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(latest) != 2 || latest[0].Actor != store.SystemActor || latest[1].Actor != "carol" {
+		t.Errorf("fallback actors = %+v, want system then carol", latest)
+	}
+}
+
+func testIssueHistory(t *testing.T, s store.Store) {
+	alice := auth.WithActor(NewTenant(t, s), "alice")
+	bob := auth.WithActor(alice, "bob")
+	tick := func() time.Time {
+		time.Sleep(5 * time.Millisecond)
+		now := time.Now()
+		time.Sleep(5 * time.Millisecond)
+		return now
+	}
+
+	beforeCreate := tick()
+	a := CreateIssue(t, alice, s, store.CreateIssueInput{Title: "orig", Description: "first draft", Labels: []string{"x"}})
+	b := CreateIssue(t, alice, s, store.CreateIssueInput{Title: "b"})
+	t0 := tick()
+
+	title, desc, priority, reason := "renamed", "second draft", 1, "done"
+	closed := model.StatusClosed
+	if _, err := s.UpdateIssue(alice, a.ID, store.UpdateIssueInput{Title: &title, Description: &desc, Priority: &priority}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if err := s.AddLabel(alice, a.ID, "y"); err != nil {
+		t.Fatalf("AddLabel: %v", err)
+	}
+	if err := s.RemoveLabel(alice, a.ID, "x"); err != nil {
+		t.Fatalf("RemoveLabel: %v", err)
+	}
+	addDep(t, alice, s, a.ID, b.ID, model.DepBlocks)
+	if _, err := s.UpdateIssue(alice, a.ID, store.UpdateIssueInput{Status: &closed, CloseReason: &reason}); err != nil {
+		t.Fatalf("closing: %v", err)
+	}
+	t1 := tick()
+
+	// Bob compacts it the way the compactor does, snapshotting and
+	// replacing the content. He also drops the dependency.
+	summary := "summary"
+	if err := s.SaveCompactionSnapshot(bob, a.ID, 1, summary, "Title: renamed\nDescription: second draft\n", store.UpdateIssueInput{Description: &summary}); err != nil {
+		t.Fatalf("SaveCompactionSnapshot: %v", err)
+	}
+	if err := s.RemoveDependency(bob, a.ID, b.ID); err != nil {
+		t.Fatalf("RemoveDependency: %v", err)
+	}
+
+	if _, err := store.IssueAt(alice, s, a.ID, beforeCreate); err == nil {
+		t.Error("IssueAt before creation should fail")
+	}
+
+	v0, err := store.IssueAt(alice, s, a.ID, t0)
+	if err != nil {
+		t.Fatalf("IssueAt(t0): %v", err)
+	}
+	i0 := v0.Issue
+	if i0.Title != "orig" || i0.Description != "first draft" || i0.Priority != 2 || i0.Status != model.StatusOpen ||
+		i0.ClosedAt != nil || i0.CloseReason != "" || i0.CompactionLevel != 0 {
+		t.Errorf("at t0 = %+v", i0)
+	}
+	if !sameIDs(i0.Labels, "x") || len(v0.Dependencies) != 0 {
+		t.Errorf("at t0 labels = %v, dependencies = %+v", i0.Labels, v0.Dependencies)
+	}
+
+	v1, err := store.IssueAt(alice, s, a.ID, t1)
+	if err != nil {
+		t.Fatalf("IssueAt(t1): %v", err)
+	}
+	i1 := v1.Issue
+	if i1.Title != "renamed" || i1.Description != "second draft" || i1.Status != model.StatusClosed ||
+		i1.ClosedAt == nil || i1.CloseReason != "done" || i1.CompactionLevel != 0 {
+		t.Errorf("at t1 = %+v", i1)
+	}
+	if !sameIDs(i1.Labels, "y") {
+		t.Errorf("at t1 labels = %v, want [y]", i1.Labels)
+	}
+	if len(v1.Dependencies) != 1 || v1.Dependencies[0].DependsOnID != b.ID || v1.Dependencies[0].Type != model.DepBlocks ||
+		v1.Dependencies[0].CreatedBy != "alice" {
+		t.Errorf("at t1 dependencies = %+v, want alice's blocks edge to %s", v1.Dependencies, b.ID)
+	}
+	if v1.Undone != 3 {
+		t.Errorf("at t1 undone = %d, want 3 (compaction, description, dependency)", v1.Undone)
+	}
+
+	now, err := store.IssueAt(alice, s, a.ID, time.Now())
+	if err != nil {
+		t.Fatalf("IssueAt(now): %v", err)
+	}
+	if now.Issue.Description != "summary" || now.Issue.CompactionLevel != 1 || now.Undone != 0 {
+		t.Errorf("at now = %+v", now.Issue)
+	}
+
+	diff, err := store.DiffIssue(alice, s, a.ID, t0, t1)
+	if err != nil {
+		t.Fatalf("DiffIssue: %v", err)
+	}
+	changed := make(map[string]model.FieldChange)
+	for _, c := range diff.Changes {
+		changed[c.Field] = c
+	}
+	for _, f := range []string{"title", "description", "priority", "close_reason", "status", "labels", "dependencies"} {
+		if _, ok := changed[f]; !ok {
+			t.Errorf("diff t0..t1 missing %s: %+v", f, diff.Changes)
+		}
+	}
+	if len(diff.Changes) != 7 || changed["title"].From != "orig" || changed["title"].To != "renamed" {
+		t.Errorf("diff t0..t1 = %+v", diff.Changes)
+	}
+	if !sameIDs(diff.Actors, "alice") {
+		t.Errorf("diff t0..t1 actors = %v, want [alice]", diff.Actors)
+	}
+
+	diff, err = store.DiffIssue(alice, s, a.ID, t1, time.Now())
+	if err != nil {
+		t.Fatalf("DiffIssue: %v", err)
+	}
+	var fields []string
+	for _, c := range diff.Changes {
+		fields = append(fields, c.Field)
+	}
+	if strings.Join(fields, ",") != "description,dependencies,compaction_level" || !sameIDs(diff.Actors, "bob") {
+		t.Errorf("diff t1..now = %+v by %v", diff.Changes, diff.Actors)
+	}
+	if _, err := store.DiffIssue(alice, s, a.ID, t1, t0); err == nil {
+		t.Error("DiffIssue with to before from should fail")
+	}
+}
+
+func testCompaction(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	issue := CreateIssue(t, ctx, s, store.CreateIssueInput{
+		Title: "Fix login", Description: "Users see a 500.\nOnly on Safari.", Notes: "See ticket 42.",
+	})
+	setStatus(t, ctx, s, issue.ID, model.StatusClosed)
+
+	// compactTo does what the compactor does: snapshot and summarize.
+	compactTo := func(level int, summary string) {
+		t.Helper()
+		current, err := s.GetIssue(ctx, issue.ID)
+		if err != nil {
+			t.Fatalf("GetIssue: %v", err)
+		}
+		empty := ""
+		content := store.UpdateIssueInput{Description: &summary, Notes: &empty}
+		if err := s.SaveCompactionSnapshot(ctx, issue.ID, level, summary, model.CompactionOriginal(current), content); err != nil {
+			t.Fatalf("SaveCompactionSnapshot: %v", err)
+		}
+	}
+
+	// The snapshot and the content stand or fall together.
+	stale, summary := "stale", "lost"
+	if err := s.SaveCompactionSnapshot(ctx, issue.ID, 1, summary, "original", store.UpdateIssueInput{Description: &summary, ExpectedContentHash: &stale}); err == nil {
+		t.Fatal("SaveCompactionSnapshot with a failing content update should fail")
+	}
+	got, err := s.GetIssue(ctx, issue.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.CompactionLevel != 0 || got.Description != issue.Description {
+		t.Errorf("after a failed compaction = %+v, want it untouched", got)
+	}
+	if snaps, err := s.GetCompactionSnapshots(ctx, issue.ID); err != nil || len(snaps) != 0 {
+		t.Errorf("snapshots after a failed compaction = %+v, %v; want none", snaps, err)
+	}
+
+	compactTo(1, "Users see a 500.")
+	compactTo(2, "[task] Fix login")
+
+	got, err = s.GetIssue(ctx, issue.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.CompactionLevel != 2 || got.CompactedAt == nil {
+		t.Fatalf("after compaction level = %d, compacted_at = %v; want 2 and set", got.CompactionLevel, got.CompactedAt)
+	}
+	snaps, err := s.GetCompactionSnapshots(ctx, issue.ID)
+	if err != nil {
+		t.Fatalf("GetCompactionSnapshots: %v", err)
+	}
+	if len(snaps) != 2 || snaps[0].FromLevel != 0 || snaps[1].FromLevel != 1 {
+		t.Fatalf("snapshots = %+v, want levels 0→1 and 1→2", snaps)
+	}
+
+	if _, err := store.UncompactIssue(ctx, s, issue.ID, 2); err == nil {
+		t.Error("uncompacting to the current level should fail")
+	}
+	got, err = store.UncompactIssue(ctx, s, issue.ID, 1)
+	if err != nil {
+		t.Fatalf("UncompactIssue(1): %v", err)
+	}
+	if got.CompactionLevel != 1 || got.Description != "Users see a 500." || got.Notes != "" {
+		t.Errorf("at level 1 = %+v", got)
+	}
+	got, err = store.UncompactIssue(ctx, s, issue.ID, 0)
+	if err != nil {
+		t.Fatalf("UncompactIssue(0): %v", err)
+	}
+	if got.CompactionLevel != 0 || got.CompactedAt != nil || got.Description != issue.Description || got.Notes != issue.Notes {
+		t.Errorf("at level 0 = %+v", got)
+	}
+	events, err := s.ListEvents(ctx, issue.ID, 0)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if events[0].EventType != model.EventCompacted || events[0].OldValue != "1" || events[0].NewValue != "0" {
+		t.Errorf("newest event = %+v, want compacted 1 → 0", events[0])
+	}
+
+	// Reopening a compacted issue brings its full content back.
+	compactTo(2, "[task] Fix login")
+	setStatus(t, ctx, s, issue.ID, model.StatusOpen)
+	got, err = s.GetIssue(ctx, issue.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.CompactionLevel != 0 || got.Description != issue.Description || got.Notes != issue.Notes {
+		t.Errorf("reopened issue = %+v, want its full content back", got)
+	}
+	if _, err := store.UncompactIssue(ctx, s, issue.ID, 0); err == nil {
+		t.Error("uncompacting an uncompacted issue should fail")
+	}
+}
+
+func testCompactionSettings(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	other := NewTenant(t, s)
+
+	settings, err := s.GetCompactionSettings(ctx)
+	if err != nil {
+		t.Fatalf("GetCompactionSettings: %v", err)
+	}
+	if !settings.Default || settings.Summarizer != model.SummarizerHeuristic || settings.Budgets != model.DefaultCompactionBudgets {
+		t.Errorf("new tenant settings = %+v, want the defaults", settings)
+	}
+
+	for _, bad := range []*model.CompactionSettings{
+		{Summarizer: "llm"},
+		{Summarizer: model.SummarizerEndpoint},
+		{Summarizer: model.SummarizerEndpoint, Endpoint: &model.CompactionEndpoint{URL: "ftp://host", Model: "m"}},
+		{Summarizer: model.SummarizerEndpoint, Endpoint: &model.CompactionEndpoint{URL: "http://localhost:8080/v1"}},
+		{Summarizer: model.SummarizerEndpoint, Endpoint: &model.CompactionEndpoint{URL: "http://localhost:8080/v1", Model: "m", APIKeyEnv: "DATABASE_URL"}},
+		{Budgets: model.CompactionBudgets{Level1: -1}},
+	} {
+		if _, err := s.SetCompactionSettings(ctx, bad); err == nil {
+			t.Errorf("SetCompactionSettings(%+v) should fail", bad)
+		}
+	}
+
+	settings, err = s.SetCompactionSettings(ctx, &model.CompactionSettings{
+		Summarizer: model.SummarizerEndpoint,
+		Budgets:    model.CompactionBudgets{Level1: 200},
+		Endpoint:   &model.CompactionEndpoint{URL: "http://localhost:8080/v1", Model: "small", APIKeyEnv: "DOIT_SUMMARIZER_KEY"},
+	})
+	if err != nil {
+		t.Fatalf("SetCompactionSettings: %v", err)
+	}
+	if settings.Default || settings.Budgets.Level1 != 200 || settings.Budgets.Level2 != model.DefaultCompactionBudgets.Level2 {
+		t.Errorf("SetCompactionSettings = %+v, want level 1 budget 200 and the default level 2 one", settings)
+	}
+	got, err := s.GetCompactionSettings(ctx)
+	if err != nil {
+		t.Fatalf("GetCompactionSettings: %v", err)
+	}
+	if got.Summarizer != model.SummarizerEndpoint || got.Budgets != settings.Budgets ||
+		got.Endpoint == nil || *got.Endpoint != *settings.Endpoint {
+		t.Errorf("GetCompactionSettings = %+v, want %+v", got, settings)
+	}
+
+	if theirs, err := s.GetCompactionSettings(other); err != nil || !theirs.Default {
+		t.Errorf("another tenant's settings = %+v, %v; want the defaults", theirs, err)
+	}
+
+	settings, err = s.SetCompactionSettings(ctx, nil)
+	if err != nil {
+		t.Fatalf("SetCompactionSettings(nil): %v", err)
+	}
+	if !settings.Default {
+		t.Errorf("reset settings = %+v, want the defaults", settings)
+	}
+	if got, err := s.GetCompactionSettings(ctx); err != nil || !got.Default || got.Endpoint != nil {
+		t.Errorf("settings after reset = %+v, %v; want the defaults", got, err)
+	}
+}
+
+func testCompactionPolicy(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	project, err := s.CreateProject(ctx, "Archive", "archive")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	projectID := project.ID.String()
+
+	policy, err := s.GetCompactionPolicy(ctx, projectID)
+	if err != nil {
+		t.Fatalf("GetCompactionPolicy: %v", err)
+	}
+	if !policy.Default || policy.Age != "" || len(policy.ExcludeLabels) != 0 {
+		t.Errorf("new project policy = %+v, want the default", policy)
+	}
+
+	for _, bad := range []string{"soon", "-1h", "0s"} {
+		if _, err := s.SetCompactionPolicy(ctx, projectID, &model.CompactionPolicy{Age: bad}); err == nil {
+			t.Errorf("SetCompactionPolicy(age %q) should fail", bad)
+		}
+	}
+
+	policy, err = s.SetCompactionPolicy(ctx, projectID, &model.CompactionPolicy{
+		Age: "720h0m0s", ExcludeLabels: []string{"legal", " keep ", "legal", ""},
+	})
+	if err != nil {
+		t.Fatalf("SetCompactionPolicy: %v", err)
+	}
+	if policy.Default || policy.Age != "720h0m0s" || strings.Join(policy.ExcludeLabels, ",") != "keep,legal" {
+		t.Errorf("SetCompactionPolicy = %+v, want 720h excluding keep and legal", policy)
+	}
+	got, err := s.GetCompactionPolicy(ctx, projectID)
+	if err != nil {
+		t.Fatalf("GetCompactionPolicy: %v", err)
+	}
+	if got.Default || got.ProjectID != projectID || got.Age != policy.Age ||
+		strings.Join(got.ExcludeLabels, ",") != "keep,legal" {
+		t.Errorf("GetCompactionPolicy = %+v, want %+v", got, policy)
+	}
+
+	other := NewTenant(t, s)
+	if _, err := s.GetCompactionPolicy(other, projectID); err == nil {
+		t.Error("another tenant should not see the project's policy")
+	}
+	if _, err := s.SetCompactionPolicy(other, projectID, &model.CompactionPolicy{Age: "1h"}); err == nil {
+		t.Error("another tenant should not set the project's policy")
+	}
+
+	if policy, err = s.SetCompactionPolicy(ctx, projectID, nil); err != nil || !policy.Default {
+		t.Errorf("SetCompactionPolicy(nil) = %+v, %v; want the default", policy, err)
+	}
+	if got, err := s.GetCompactionPolicy(ctx, projectID); err != nil || !got.Default {
+		t.Errorf("policy after reset = %+v, %v; want the default", got, err)
+	}
+}
+
+func testJobHistory(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	other := NewTenant(t, s)
+
+	start := time.Now().UTC().Truncate(time.Second)
+	record := func(job string, i int, err string) {
+		t.Helper()
+		run := &model.JobRun{
+			Job: job, StartedAt: start.Add(time.Duration(i) * time.Minute),
+			FinishedAt: start.Add(time.Duration(i)*time.Minute + time.Second),
+			Succeeded:  err == "", Error: err, Runner: "host:1",
+		}
+		if err == "" {
+			run.Result = json.RawMessage(fmt.Sprintf(`{"n": %d}`, i))
+		}
+		if err := s.RecordJobRun(ctx, run); err != nil {
+			t.Fatalf("RecordJobRun: %v", err)
+		}
+		if run.ID == 0 {
+			t.Error("RecordJobRun should set the run's ID")
+		}
+	}
+	for i := range store.JobHistoryLimit + 3 {
+		record("compact", i, "")
+	}
+	record("purge_trash", 0, "disk full")
+
+	runs, err := s.ListJobRuns(ctx, "compact", 0)
+	if err != nil {
+		t.Fatalf("ListJobRuns: %v", err)
+	}
+	if len(runs) != store.JobHistoryLimit {
+		t.Fatalf("kept %d compact runs, want %d", len(runs), store.JobHistoryLimit)
+	}
+	newest := runs[0]
+	var result map[string]int
+	if err := json.Unmarshal(newest.Result, &result); err != nil || result["n"] != store.JobHistoryLimit+2 {
+		t.Errorf("newest run result = %s, %v; want n=%d", newest.Result, err, store.JobHistoryLimit+2)
+	}
+	if !newest.Succeeded || newest.Runner != "host:1" || !newest.StartedAt.Equal(start.Add(time.Duration(store.JobHistoryLimit+2)*time.Minute)) {
+		t.Errorf("newest run = %+v", newest)
+	}
+	if runs[len(runs)-1].Result == nil || runs[0].ID <= runs[1].ID {
+		t.Error("runs should be newest first")
+	}
+
+	all, err := s.ListJobRuns(ctx, "", 2)
+	if err != nil {
+		t.Fatalf("ListJobRuns: %v", err)
+	}
+	if len(all) != 2 || all[0].Job != "purge_trash" || all[0].Succeeded || all[0].Error != "disk full" || all[0].Result != nil {
+		t.Errorf("ListJobRuns(all, 2) = %+v, want the failed purge first", all)
+	}
+	if runs, _ := s.ListJobRuns(other, "", 0); len(runs) != 0 {
+		t.Errorf("another tenant sees %d job runs", len(runs))
+	}
+
+	intervals, err := s.GetJobIntervals(ctx)
+	if err != nil || len(intervals) != 0 {
+		t.Errorf("GetJobIntervals on a new tenant = %v, %v; want none", intervals, err)
+	}
+	six, off, negative := 6*time.Hour, time.Duration(0), -time.Minute
+	if err := s.SetJobInterval(ctx, "compact", &negative); err == nil {
+		t.Error("SetJobInterval should reject a negative interval")
+	}
+	for job, d := range map[string]*time.Duration{"compact": &six, "purge_trash": &off} {
+		if err := s.SetJobInterval(ctx, job, d); err != nil {
+			t.Fatalf("SetJobInterval(%s): %v", job, err)
+		}
+	}
+	// Setting twice replaces the override.
+	if err := s.SetJobInterval(ctx, "compact", &six); err != nil {
+		t.Fatalf("SetJobInterval: %v", err)
+	}
+	intervals, err = s.GetJobIntervals(ctx)
+	if err != nil || len(intervals) != 2 || intervals["compact"] != six || intervals["purge_trash"] != 0 {
+		t.Errorf("GetJobIntervals = %v, %v; want compact 6h and purge_trash off", intervals, err)
+	}
+	if intervals, _ := s.GetJobIntervals(other); len(intervals) != 0 {
+		t.Errorf("another tenant sees intervals %v", intervals)
+	}
+	if err := s.SetJobInterval(ctx, "compact", nil); err != nil {
+		t.Fatalf("SetJobInterval(nil): %v", err)
+	}
+	if intervals, _ := s.GetJobIntervals(ctx); len(intervals) != 1 {
+		t.Errorf("intervals after reset = %v, want only purge_trash", intervals)
+	}
+}
+
+func testDeleteCascades(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	keep := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "keep"})
+	gone := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "gone", Labels: []string{"x"}})
+
+	addDep(t, ctx, s, keep.ID, gone.ID, model.DepBlocks)
+	if _, err := s.AddComment(ctx, gone.ID, "me", "bye"); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	if err := s.DeleteIssue(ctx, gone.ID); err != nil {
+		t.Fatalf("DeleteIssue: %v", err)
+	}
+	if _, err := s.GetIssue(ctx, gone.ID); err == nil {
+		t.Error("deleted issue should not be retrievable")
+	}
+	deps, err := s.ListDependencies(ctx, keep.ID, "both")
+	if err != nil {
+		t.Fatalf("ListDependencies: %v", err)
+	}
+	if len(deps) != 0 {
+		t.Errorf("dependencies should cascade on delete, got %+v", deps)
+	}
+	if !readySet(t, ctx, s)[keep.ID] {
+		t.Error("issue blocked by a deleted issue should be ready")
+	}
+	if err := s.DeleteIssue(ctx, gone.ID); err == nil {
+		t.Error("deleting twice should fail")
+	}
+}
+
+func testTrash(t *testing.T, s store.Store) {
+	ctx := auth.WithActor(NewTenant(t, s), "alice")
+	keep := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "keep"})
+	gone := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "gone trash", Labels: []string{"x"}})
+	addDep(t, ctx, s, keep.ID, gone.ID, model.DepBlocks)
+	if _, err := s.AddComment(ctx, gone.ID, "me", "bye"); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	before := time.Now().Add(-time.Minute)
+	if err := s.DeleteIssue(ctx, gone.ID); err != nil {
+		t.Fatalf("DeleteIssue: %v", err)
+	}
+
+	list, err := s.ListIssues(ctx, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if !sameIDs(issueIDs(list), keep.ID) {
+		t.Errorf("ListIssues = %v, want only %s", issueIDs(list), keep.ID)
+	}
+	results, err := s.SearchIssues(ctx, model.SearchQuery{Query: "trash"})
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("SearchIssues found deleted issue: %v", searchIDs(results))
+	}
+	counts, err := s.CountIssuesByStatus(ctx)
+	if err != nil {
+		t.Fatalf("CountIssuesByStatus: %v", err)
+	}
+	if counts["open"] != 1 {
+		t.Errorf("counts = %v, want one open", counts)
+	}
+
+	trash, err := s.ListTrash(ctx)
+	if err != nil {
+		t.Fatalf("ListTrash: %v", err)
+	}
+	if len(trash) != 1 || trash[0].ID != gone.ID {
+		t.Fatalf("ListTrash = %v, want %s", issueIDs(trash), gone.ID)
+	}
+	if trash[0].DeletedAt == nil || trash[0].DeletedAt.Before(before) {
+		t.Errorf("DeletedAt = %v", trash[0].DeletedAt)
+	}
+	if trash[0].DeletedBy != "alice" {
+		t.Errorf("DeletedBy = %q, want alice", trash[0].DeletedBy)
+	}
+
+	if _, err := s.RestoreIssue(ctx, keep.ID); err == nil {
+		t.Error("restoring an issue that is not in the trash should fail")
+	}
+	restored, err := s.RestoreIssue(ctx, gone.ID)
+	if err != nil {
+		t.Fatalf("RestoreIssue: %v", err)
+	}
+	if restored.DeletedAt != nil || restored.DeletedBy != "" {
+		t.Errorf("restored issue still marked deleted: %v by %q", restored.DeletedAt, restored.DeletedBy)
+	}
+	if len(restored.Labels) != 1 || restored.Labels[0] != "x" {
+		t.Errorf("restored labels = %v, want [x]", restored.Labels)
+	}
+	deps, err := s.ListDependencies(ctx, keep.ID, "upstream")
+	if err != nil {
+		t.Fatalf("ListDependencies: %v", err)
+	}
+	if len(deps) != 1 || deps[0].DependsOnID != gone.ID {
+		t.Errorf("restore should bring back dependencies, got %+v", deps)
+	}
+	if readySet(t, ctx, s)[keep.ID] {
+		t.Error("issue blocked by a restored issue should not be ready")
+	}
+	comments, err := s.ListComments(ctx, gone.ID)
+	if err != nil {
+		t.Fatalf("ListComments: %v", err)
+	}
+	if len(comments) != 1 {
+		t.Errorf("restore should bring back comments, got %d", len(comments))
+	}
+	events, err := s.ListEvents(ctx, gone.ID, 0)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	var sawDeleted, sawRestored bool
+	for _, e := range events {
+		switch e.EventType {
+		case model.EventDeleted:
+			sawDeleted = e.Actor == "alice"
+		case model.EventRestored:
+			sawRestored = e.Actor == "alice"
+		}
+	}
+	if !sawDeleted || !sawRestored {
+		t.Errorf("want deleted and restored events by alice, got %+v", events)
+	}
+
+	// Purging only removes issues deleted before the cutoff, for good.
+	if err := s.DeleteIssue(ctx, gone.ID); err != nil {
+		t.Fatalf("DeleteIssue: %v", err)
+	}
+	other := NewTenant(t, s)
+	if purged, err := s.PurgeTrash(other, time.Now().Add(time.Minute)); err != nil || len(purged) != 0 {
+		t.Errorf("PurgeTrash in another tenant = %v, %v; want nothing", purged, err)
+	}
+	if purged, err := s.PurgeTrash(ctx, before); err != nil || len(purged) != 0 {
+		t.Errorf("PurgeTrash(before deletion) = %v, %v; want nothing", purged, err)
+	}
+	purged, err := s.PurgeTrash(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	}
+	if !sameIDs(purged, gone.ID) {
+		t.Errorf("PurgeTrash = %v, want %s", purged, gone.ID)
+	}
+	if _, err := s.RestoreIssue(ctx, gone.ID); err == nil {
+		t.Error("restoring a purged issue should fail")
+	}
+	if trash, err := s.ListTrash(ctx); err != nil || len(trash) != 0 {
+		t.Errorf("ListTrash after purge = %v, %v", issueIDs(trash), err)
+	}
+	if !readySet(t, ctx, s)[keep.ID] {
+		t.Error("issue blocked by a purged issue should be ready")
+	}
+}
+
+func testGarbageCollection(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	other := NewTenant(t, s)
+
+	policy, err := s.GetGCPolicy(ctx)
+	if err != nil {
+		t.Fatalf("GetGCPolicy: %v", err)
+	}
+	if !policy.Default || policy.Retention[model.WispHeartbeat] != "1h0m0s" || policy.Ephemeral != "24h0m0s" || policy.Report {
+		t.Errorf("new tenant gc policy = %+v, want the defaults", policy)
+	}
+	for _, bad := range []*model.GCPolicy{
+		{Retention: map[model.WispType]string{"chatter": "1h"}},
+		{Retention: map[model.WispType]string{model.WispPing: "0s"}},
+		{Ephemeral: "soon"},
+	} {
+		if _, err := s.SetGCPolicy(ctx, bad); err == nil {
+			t.Errorf("SetGCPolicy(%+v) should fail", bad)
+		}
+	}
+	policy, err = s.SetGCPolicy(ctx, &model.GCPolicy{
+		Retention: map[model.WispType]string{model.WispHeartbeat: "90m"}, Report: true,
+	})
+	if err != nil {
+		t.Fatalf("SetGCPolicy: %v", err)
+	}
+	got, err := s.GetGCPolicy(ctx)
+	if err != nil {
+		t.Fatalf("GetGCPolicy: %v", err)
+	}
+	if got.Default || !got.Report || got.Retention[model.WispHeartbeat] != "1h30m0s" ||
+		got.Retention[model.WispEscalation] != "720h0m0s" || got.Ephemeral != "24h0m0s" {
+		t.Errorf("GetGCPolicy = %+v, want 90m heartbeats, reports on and the other defaults", got)
+	}
+	if p, _ := s.GetGCPolicy(other); !p.Default {
+		t.Error("another tenant should keep the default gc policy")
+	}
+
+	beat := CreateIssue(t, ctx, s, store.CreateIssueInput{
+		Title: "beat", Ephemeral: true, WispType: model.WispHeartbeat, Labels: []string{"x"},
+	})
+	note := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "note", Ephemeral: true})
+	keep := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "keep"})
+	addDep(t, ctx, s, keep.ID, beat.ID, model.DepBlocks)
+
+	// Purging skips the trash and drops the purged issues' edges.
+	if purged, err := s.PurgeIssues(ctx, []string{beat.ID}); err != nil || !sameIDs(purged, beat.ID) {
+		t.Fatalf("PurgeIssues = %v, %v; want %s", purged, err, beat.ID)
+	}
+	if _, err := s.GetIssue(ctx, beat.ID); err == nil {
+		t.Error("purged heartbeat should be gone")
+	}
+	if trash, _ := s.ListTrash(ctx); len(trash) != 0 {
+		t.Errorf("purging should skip the trash, found %v", issueIDs(trash))
+	}
+	if !readySet(t, ctx, s)[keep.ID] {
+		t.Error("issue blocked by a purged wisp should be ready")
+	}
+
+	// PurgeIssues takes live and trashed issues alike, but only the tenant's.
+	trashed := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "trashed"})
+	if err := s.DeleteIssue(ctx, trashed.ID); err != nil {
+		t.Fatalf("DeleteIssue: %v", err)
+	}
+	if purged, err := s.PurgeIssues(other, []string{note.ID}); err != nil || len(purged) != 0 {
+		t.Errorf("PurgeIssues in another tenant = %v, %v; want nothing", purged, err)
+	}
+	purged, err := s.PurgeIssues(ctx, []string{note.ID, trashed.ID, "doit-nope"})
+	if err != nil {
+		t.Fatalf("PurgeIssues: %v", err)
+	}
+	sort.Strings(purged)
+	if !sameIDs(purged, note.ID, trashed.ID) {
+		t.Errorf("PurgeIssues = %v, want %s and %s", purged, note.ID, trashed.ID)
+	}
+	if _, err := s.RestoreIssue(ctx, trashed.ID); err == nil {
+		t.Error("restoring a purged issue should fail")
+	}
+
+	if policy, err = s.SetGCPolicy(ctx, nil); err != nil || !policy.Default {
+		t.Errorf("SetGCPolicy(nil) = %+v, %v; want the defaults", policy, err)
+	}
+}
+
+func testTenantIsolation(t *testing.T, s store.Store) {
+	ctxA := NewTenant(t, s)
+	ctxB := NewTenant(t, s)
+
+	a := CreateIssue(t, ctxA, s, store.CreateIssueInput{Title: "a"})
+	b := CreateIssue(t, ctxB, s, store.CreateIssueInput{Title: "b"})
+	setStatus(t, ctxB, s, b.ID, model.StatusInProgress)
+
+	listA, err := s.ListIssues(ctxA, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if !sameIDs(issueIDs(listA), a.ID) {
+		t.Errorf("tenant A lists %v, want only %s", issueIDs(listA), a.ID)
+	}
+	if ready := readySet(t, ctxB, s); len(ready) != 0 {
+		t.Errorf("tenant B ready = %v, want none", ready)
+	}
+	counts, err := s.CountIssuesByStatus(ctxA)
+	if err != nil {
+		t.Fatalf("CountIssuesByStatus: %v", err)
+	}
+	if counts["open"] != 1 || counts["in_progress"] != 0 {
+		t.Errorf("tenant A counts = %v, want one open", counts)
+	}
+
+	if _, err := s.CreateProject(ctxA, "Shared", "shared"); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	if _, err := s.CreateProject(ctxB, "Shared", "shared"); err != nil {
+		t.Errorf("project slugs should be unique per tenant, not globally: %v", err)
+	}
+	projects, err := s.ListProjects(ctxB)
+	if err != nil {
+		t.Fatalf("ListProjects: %v", err)
+	}
+	if len(projects) != 1 {
+		t.Errorf("tenant B sees %d projects, want 1", len(projects))
+	}
+
+	if _, err := s.RecordLesson(ctxA, store.RecordLessonInput{Title: "t", Mistake: "m", Correction: "c"}); err != nil {
+		t.Fatalf("RecordLesson: %v", err)
+	}
+	lessons, err := s.ListLessons(ctxB, model.LessonFilter{})
+	if err != nil {
+		t.Fatalf("ListLessons: %v", err)
+	}
+	if len(lessons) != 0 {
+		t.Errorf("tenant B sees %d lessons from tenant A", len(lessons))
+	}
+
+	flag, err := s.RaiseFlag(ctxA, store.RaiseFlagInput{IssueID: a.ID, Type: "question", Severity: 3, Summary: "?"})
+	if err != nil {
+		t.Fatalf("RaiseFlag: %v", err)
+	}
+	flags, err := s.ListFlags(ctxB, model.FlagFilter{})
+	if err != nil {
+		t.Fatalf("ListFlags: %v", err)
+	}
+	if len(flags) != 0 {
+		t.Errorf("tenant B sees %d flags from tenant A", len(flags))
+	}
+	if _, err := s.ResolveFlag(ctxB, flag.ID, "hijacked", "b"); err == nil {
+		t.Error("tenant B should not resolve tenant A's flag")
+	}
+}
+
+func testCrossTenantAccess(t *testing.T, s store.Store) {
+	ctxA := NewTenant(t, s)
+	ctxB := NewTenant(t, s)
+
+	victim := CreateIssue(t, ctxA, s, store.CreateIssueInput{Title: "secret", Labels: []string{"private"}})
+	attacker := CreateIssue(t, ctxB, s, store.CreateIssueInput{Title: "mine"})
+
+	title := "pwned"
+	closed := model.StatusClosed
+	attempts := map[string]func() error{
+		"GetIssue": func() error { _, err := s.GetIssue(ctxB, victim.ID); return err },
+		"UpdateIssue": func() error {
+			_, err := s.UpdateIssue(ctxB, victim.ID, store.UpdateIssueInput{Title: &title, Status: &closed})
+			return err
+		},
+		"DeleteIssue":  func() error { return s.DeleteIssue(ctxB, victim.ID) },
+		"RestoreIssue": func() error { _, err := s.RestoreIssue(ctxB, victim.ID); return err },
+		"NextChildID":  func() error { _, err := s.NextChildID(ctxB, victim.ID); return err },
+		"AddDependency(from)": func() error {
+			_, err := s.AddDependency(ctxB, store.AddDependencyInput{IssueID: victim.ID, DependsOnID: attacker.ID, Type: model.DepBlocks})
+			return err
+		},
+		"AddDependency(to)": func() error {
+			_, err := s.AddDependency(ctxB, store.AddDependencyInput{IssueID: attacker.ID, DependsOnID: victim.ID, Type: model.DepBlocks})
+			return err
+		},
+		"RemoveDependency":  func() error { return s.RemoveDependency(ctxB, victim.ID, attacker.ID) },
+		"ListDependencies":  func() error { _, err := s.ListDependencies(ctxB, victim.ID, "both"); return err },
+		"GetDependencyTree": func() error { _, err := s.GetDependencyTree(ctxB, victim.ID, 3); return err },
+		"AddLabel":          func() error { return s.AddLabel(ctxB, victim.ID, "x") },
+		"RemoveLabel":       func() error { return s.RemoveLabel(ctxB, victim.ID, "private") },
+		"ListLabels":        func() error { _, err := s.ListLabels(ctxB, victim.ID); return err },
+		"AddComment":        func() error { _, err := s.AddComment(ctxB, victim.ID, "b", "hi"); return err },
+		"ListComments":      func() error { _, err := s.ListComments(ctxB, victim.ID); return err },
+		"AddEvent": func() error {
+			_, err := s.AddEvent(ctxB, store.AddEventInput{IssueID: victim.ID, EventType: "x"})
+			return err
+		},
+		"ListEvents":     func() error { _, err := s.ListEvents(ctxB, victim.ID, 0); return err },
+		"SaveCompaction": func() error { return s.SaveCompactionSnapshot(ctxB, victim.ID, 1, "s", "o", store.UpdateIssueInput{}) },
+		"GetCompaction":  func() error { _, err := s.GetCompactionSnapshots(ctxB, victim.ID); return err },
+	}
+	for name, attempt := range attempts {
+		err := attempt()
+		if err == nil {
+			t.Errorf("%s across tenants should fail", name)
+			continue
+		}
+		if strings.Contains(err.Error(), "denied") || strings.Contains(err.Error(), "forbidden") {
+			t.Errorf("%s error %q should not confirm the issue exists", name, err)
+		}
+	}
+
+	// Nothing above may have touched tenant A's data.
+	got, err := s.GetIssue(ctxA, victim.ID)
+	if err != nil {
+		t.Fatalf("victim issue disappeared: %v", err)
+	}
+	if got.Title != "secret" || got.Status != model.StatusOpen || !sameIDs(got.Labels, "private") {
+		t.Errorf("victim issue was modified: %+v", got)
+	}
+	comments, _ := s.ListComments(ctxA, victim.ID)
+	deps, _ := s.ListDependencies(ctxA, victim.ID, "both")
+	if len(comments) != 0 || len(deps) != 0 {
+		t.Errorf("victim gained comments %v or dependencies %v", comments, deps)
+	}
+}
+
+func testAllowedProjects(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	p1, err := s.CreateProject(ctx, "One", "one")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	p2, err := s.CreateProject(ctx, "Two", "two")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	in1 := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "in one", ProjectID: p1.ID.String()})
+	in2 := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "in two", ProjectID: p2.ID.String()})
+	loose := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "no project"})
+
+	for _, in := range []struct{ issue, project string }{{in1.ID, p1.ID.String()}, {in2.ID, p2.ID.String()}} {
+		if _, err := s.RecordLesson(ctx, store.RecordLessonInput{Title: "l", Mistake: "m", Correction: "c", ProjectID: in.project, IssueID: in.issue}); err != nil {
+			t.Fatalf("RecordLesson: %v", err)
+		}
+		if _, err := s.RaiseFlag(ctx, store.RaiseFlagInput{IssueID: in.issue, ProjectID: in.project, Type: "question", Severity: 3, Summary: "?"}); err != nil {
+			t.Fatalf("RaiseFlag: %v", err)
+		}
+	}
+
+	// Without a restriction everything in the tenant is visible.
+	all, err := s.ListIssues(ctx, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if !sameIDs(issueIDs(all), in1.ID, in2.ID, loose.ID) {
+		t.Errorf("unscoped ListIssues = %v", issueIDs(all))
+	}
+
+	scoped := auth.WithAllowedProjects(ctx, []string{p1.ID.String()})
+
+	list, err := s.ListIssues(scoped, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if !sameIDs(issueIDs(list), in1.ID) {
+		t.Errorf("scoped ListIssues = %v, want [%s]", issueIDs(list), in1.ID)
+	}
+	ready, err := s.ListReady(scoped, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListReady: %v", err)
+	}
+	if !sameIDs(issueIDs(ready), in1.ID) {
+		t.Errorf("scoped ListReady = %v, want [%s]", issueIDs(ready), in1.ID)
+	}
+	counts, err := s.CountIssuesByStatus(scoped)
+	if err != nil {
+		t.Fatalf("CountIssuesByStatus: %v", err)
+	}
+	if counts["open"] != 1 {
+		t.Errorf("scoped open count = %d, want 1", counts["open"])
+	}
+	lessons, err := s.ListLessons(scoped, model.LessonFilter{})
+	if err != nil {
+		t.Fatalf("ListLessons: %v", err)
+	}
+	if len(lessons) != 1 || lessons[0].ProjectID != p1.ID.String() {
+		t.Errorf("scoped ListLessons = %+v", lessons)
+	}
+	flags, err := s.ListFlags(scoped, model.FlagFilter{})
+	if err != nil {
+		t.Fatalf("ListFlags: %v", err)
+	}
+	if len(flags) != 1 || flags[0].ProjectID != p1.ID.String() {
+		t.Errorf("scoped ListFlags = %+v", flags)
+	}
+
+	// An explicit project filter outside the allowed set yields nothing.
+	other := p2.ID.String()
+	list, err = s.ListIssues(scoped, model.IssueFilter{ProjectID: &other})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("filtering by a disallowed project returned %v", issueIDs(list))
+	}
+}
+
+func searchIDs(results []model.SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Issue.ID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func testSearch(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	proj, err := s.CreateProject(ctx, "Search", "search")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	inTitle := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Database migration fails on startup", ProjectID: proj.ID.String()})
+	inDesc := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Startup is slow", Description: "The migration runner takes a long time to finish."})
+	inNotes := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Release checklist", Notes: "Remember to run the migration before tagging."})
+	inComment := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Flaky test"})
+	CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Unrelated work", Description: "Nothing to see here."})
+
+	if _, err := s.AddComment(ctx, inComment.ID, "dev", "Looks like the migration lock is held by another runner."); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	results, err := s.SearchIssues(ctx, model.SearchQuery{Query: "migration"})
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if !sameIDs(searchIDs(results), inTitle.ID, inDesc.ID, inNotes.ID, inComment.ID) {
+		t.Fatalf("SearchIssues(migration) = %v", searchIDs(results))
+	}
+	if results[0].Issue.ID != inTitle.ID {
+		t.Errorf("title match should rank first, got %s", results[0].Issue.ID)
+	}
+	for _, r := range results {
+		if r.Rank <= 0 {
+			t.Errorf("%s: rank = %v, want > 0", r.Issue.ID, r.Rank)
+		}
+		if !strings.Contains(r.Snippet, model.SnippetMark) {
+			t.Errorf("%s: snippet %q has no highlighted match", r.Issue.ID, r.Snippet)
+		}
+	}
+
+	// Words are ANDed and - excludes.
+	results, err = s.SearchIssues(ctx, model.SearchQuery{Query: "migration startup"})
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if !sameIDs(searchIDs(results), inTitle.ID, inDesc.ID) {
+		t.Errorf("SearchIssues(migration startup) = %v", searchIDs(results))
+	}
+	results, err = s.SearchIssues(ctx, model.SearchQuery{Query: "migration -startup"})
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if !sameIDs(searchIDs(results), inNotes.ID, inComment.ID) {
+		t.Errorf("SearchIssues(migration -startup) = %v", searchIDs(results))
+	}
+
+	// Quoted phrases match words in order.
+	results, err = s.SearchIssues(ctx, model.SearchQuery{Query: `"migration lock"`})
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if !sameIDs(searchIDs(results), inComment.ID) {
+		t.Errorf(`SearchIssues("migration lock") = %v`, searchIDs(results))
+	}
+
+	// Filters and limit.
+	results, err = s.SearchIssues(ctx, model.SearchQuery{Query: "migration", ProjectID: ptr(proj.ID.String())})
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if !sameIDs(searchIDs(results), inTitle.ID) {
+		t.Errorf("project-filtered search = %v", searchIDs(results))
+	}
+	setStatus(t, ctx, s, inDesc.ID, model.StatusClosed)
+	results, err = s.SearchIssues(ctx, model.SearchQuery{Query: "migration", Status: ptr(model.StatusClosed)})
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if !sameIDs(searchIDs(results), inDesc.ID) {
+		t.Errorf("status-filtered search = %v", searchIDs(results))
+	}
+	results, err = s.SearchIssues(ctx, model.SearchQuery{Query: "migration", Limit: 2})
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("limited search returned %d results, want 2", len(results))
+	}
+
+	// Scoping: other tenants and disallowed projects see nothing.
+	other := NewTenant(t, s)
+	results, err = s.SearchIssues(other, model.SearchQuery{Query: "migration"})
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("other tenant found %v", searchIDs(results))
+	}
+	scoped := auth.WithAllowedProjects(ctx, []string{proj.ID.String()})
+	results, err = s.SearchIssues(scoped, model.SearchQuery{Query: "migration"})
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if !sameIDs(searchIDs(results), inTitle.ID) {
+		t.Errorf("scoped search = %v, want [%s]", searchIDs(results), inTitle.ID)
+	}
+
+	if _, err := s.SearchIssues(ctx, model.SearchQuery{Query: "  "}); err == nil {
+		t.Error("empty query should be rejected")
+	}
+}
+
+func testListIssuesSearchFilter(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	byTitle := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Rotate API keys"})
+	byDesc := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Security chores", Description: "rotate api keys quarterly"})
+	CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Other"})
+
+	search := "API keys"
+	list, err := s.ListIssues(ctx, model.IssueFilter{Search: &search, Status: ptr(model.StatusOpen)})
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if !sameIDs(issueIDs(list), byTitle.ID, byDesc.ID) {
+		t.Errorf("ListIssues(search=%q) = %v", search, issueIDs(list))
+	}
+}
+
+// pageIssues walks ListIssues with cursors, calling between after each page,
+// and returns every ID seen in order.
+func pageIssues(t *testing.T, ctx context.Context, s store.Store, sortBy string, pageSize int, between func()) []string {
+	t.Helper()
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 50 {
+			t.Fatalf("sort %q: pagination did not terminate", sortBy)
+		}
+		page, err := s.ListIssues(ctx, model.IssueFilter{SortBy: sortBy, Limit: pageSize + 1, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListIssues(sort=%q): %v", sortBy, err)
+		}
+		if len(page) <= pageSize {
+			for _, i := range page {
+				seen = append(seen, i.ID)
+			}
+			return seen
+		}
+		page = page[:pageSize]
+		for _, i := range page {
+			seen = append(seen, i.ID)
+		}
+		cursor = store.IssueCursor(&page[pageSize-1], sortBy)
+		between()
+	}
+}
+
+func testIssueCursors(t *testing.T, s store.Store) {
+	for _, sortBy := range []string{"priority", "oldest", "updated", "hybrid", ""} {
+		t.Run("sort="+sortBy, func(t *testing.T) {
+			ctx := NewTenant(t, s)
+			var want []string
+			for n := 0; n < 7; n++ {
+				i := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: fmt.Sprintf("issue %d", n), Priority: 1 + n%3})
+				want = append(want, i.ID)
+			}
+
+			// The unpaged order is the reference.
+			all, err := s.ListIssues(ctx, model.IssueFilter{SortBy: sortBy})
+			if err != nil {
+				t.Fatalf("ListIssues: %v", err)
+			}
+			var reference []string
+			for _, i := range all {
+				reference = append(reference, i.ID)
+			}
+			if got := pageIssues(t, ctx, s, sortBy, 3, func() {}); strings.Join(got, ",") != strings.Join(reference, ",") {
+				t.Fatalf("paged order %v, want %v", got, reference)
+			}
+
+			// Inserting between pages must not duplicate or skip existing rows.
+			inserted := 0
+			got := pageIssues(t, ctx, s, sortBy, 2, func() {
+				inserted++
+				CreateIssue(t, ctx, s, store.CreateIssueInput{Title: fmt.Sprintf("late %d", inserted), Priority: 1 + inserted%3})
+			})
+			counts := map[string]int{}
+			for _, id := range got {
+				counts[id]++
+			}
+			for _, id := range want {
+				if counts[id] != 1 {
+					t.Errorf("%s seen %d times across pages", id, counts[id])
+				}
+			}
+			for id, n := range counts {
+				if n > 1 {
+					t.Errorf("%s duplicated across pages", id)
+				}
+			}
+		})
+	}
+
+	ctx := NewTenant(t, s)
+	a := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "a"})
+	if _, err := s.ListIssues(ctx, model.IssueFilter{Cursor: "not-a-cursor"}); err == nil {
+		t.Error("garbage cursor should be rejected")
+	}
+	if _, err := s.ListIssues(ctx, model.IssueFilter{SortBy: "oldest", Cursor: store.IssueCursor(a, "updated")}); err == nil {
+		t.Error("cursor from another sort order should be rejected")
+	}
+}
+
+func testReadyCursor(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	for n := 0; n < 5; n++ {
+		CreateIssue(t, ctx, s, store.CreateIssueInput{Title: fmt.Sprintf("ready %d", n), Priority: 1 + n%2})
+	}
+	all, err := s.ListReady(ctx, model.IssueFilter{})
+	if err != nil {
+		t.Fatalf("ListReady: %v", err)
+	}
+
+	first, err := s.ListReady(ctx, model.IssueFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListReady: %v", err)
+	}
+	rest, err := s.ListReady(ctx, model.IssueFilter{Cursor: store.IssueCursor(&first[1], store.ReadySortBy)})
+	if err != nil {
+		t.Fatalf("ListReady: %v", err)
+	}
+	got := append(first, rest...)
+	if len(got) != len(all) {
+		t.Fatalf("paged ready returned %d issues, want %d", len(got), len(all))
+	}
+	for n := range all {
+		if got[n].ID != all[n].ID {
+			t.Errorf("position %d: %s, want %s", n, got[n].ID, all[n].ID)
+		}
+	}
+}
+
+func testLessonFlagCursors(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	issue := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "flagged"})
+	for n := 0; n < 5; n++ {
+		if _, err := s.RecordLesson(ctx, store.RecordLessonInput{Title: fmt.Sprintf("l%d", n), Mistake: "m", Correction: "c", Severity: 1 + n%2}); err != nil {
+			t.Fatalf("RecordLesson: %v", err)
+		}
+		if _, err := s.RaiseFlag(ctx, store.RaiseFlagInput{IssueID: issue.ID, Type: "question", Severity: 1 + n%2, Summary: fmt.Sprintf("f%d", n)}); err != nil {
+			t.Fatalf("RaiseFlag: %v", err)
+		}
+	}
+
+	var lessons []string
+	cursor := ""
+	for {
+		page, err := s.ListLessons(ctx, model.LessonFilter{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListLessons: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, l := range page {
+			lessons = append(lessons, l.ID)
+		}
+		cursor = store.LessonCursor(&page[len(page)-1])
+	}
+	allLessons, err := s.ListLessons(ctx, model.LessonFilter{})
+	if err != nil {
+		t.Fatalf("ListLessons: %v", err)
+	}
+	if len(lessons) != len(allLessons) {
+		t.Fatalf("paged lessons = %v, want %d", lessons, len(allLessons))
+	}
+	for n := range allLessons {
+		if lessons[n] != allLessons[n].ID {
+			t.Errorf("lesson %d: %s, want %s", n, lessons[n], allLessons[n].ID)
+		}
+	}
+
+	var flags []string
+	cursor = ""
+	for {
+		page, err := s.ListFlags(ctx, model.FlagFilter{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListFlags: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, f := range page {
+			flags = append(flags, f.ID)
+		}
+		cursor = store.FlagCursor(&page[len(page)-1])
+	}
+	allFlags, err := s.ListFlags(ctx, model.FlagFilter{})
+	if err != nil {
+		t.Fatalf("ListFlags: %v", err)
+	}
+	if len(flags) != len(allFlags) {
+		t.Fatalf("paged flags = %v, want %d", flags, len(allFlags))
+	}
+	for n := range allFlags {
+		if flags[n] != allFlags[n].ID {
+			t.Errorf("flag %d: %s, want %s", n, flags[n], allFlags[n].ID)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func testTemplates(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	root := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Onboard {{name}}", IsTemplate: true})
+	got, err := s.GetIssue(ctx, root.ID)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if !got.IsTemplate {
+		t.Fatal("is_template should persist on create")
+	}
+	if ready := readySet(t, ctx, s); ready[root.ID] {
+		t.Error("a template issue should never be ready")
+	}
+	explanation, err := store.ExplainReady(ctx, s, root.ID)
+	if err != nil {
+		t.Fatalf("ExplainReady: %v", err)
+	}
+	if explanation.Ready || !slices.ContainsFunc(explanation.Reasons, func(r model.ReadyReason) bool { return r.Kind == model.ReadyReasonTemplate }) {
+		t.Errorf("explanation = %+v, want not ready because it is a template", explanation)
+	}
+
+	child := CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Order a laptop for {{name}}", ParentID: root.ID})
+	if ready := readySet(t, ctx, s); !ready[child.ID] {
+		t.Fatal("an ordinary issue under a template root should be ready until marked")
+	}
+	on, off := true, false
+	if _, err := s.UpdateIssue(ctx, child.ID, store.UpdateIssueInput{IsTemplate: &on}); err != nil {
+		t.Fatalf("UpdateIssue(is_template): %v", err)
+	}
+	if got, _ := s.GetIssue(ctx, child.ID); !got.IsTemplate || readySet(t, ctx, s)[child.ID] {
+		t.Error("marking an issue a template should persist and take it out of ready")
+	}
+	if _, err := s.UpdateIssue(ctx, child.ID, store.UpdateIssueInput{IsTemplate: &off}); err != nil {
+		t.Fatalf("UpdateIssue(is_template): %v", err)
+	}
+	if !readySet(t, ctx, s)[child.ID] {
+		t.Error("unmarking a template issue should make it ready again")
+	}
+}
+
+// testAgentNames checks that a tenant has at most one live agent bead per
+// name, and that FindAgentBead looks it up.
+func testAgentNames(t *testing.T, s store.Store) {
+	ctx := NewTenant(t, s)
+	other := NewTenant(t, s)
+	agent := func(ctx context.Context, name string) (*model.Issue, error) {
+		t.Helper()
+		id, err := s.GenerateID(ctx, "agent")
+		if err != nil {
+			t.Fatalf("GenerateID: %v", err)
+		}
+		return s.CreateIssue(ctx, store.CreateIssueInput{ID: id, Title: name, Status: model.StatusInProgress, Priority: 2, IssueType: model.TypeAgent})
+	}
+
+	bead, err := agent(ctx, "builder-1")
+	if err != nil {
+		t.Fatalf("creating agent bead: %v", err)
+	}
+	if _, err := agent(ctx, "builder-1"); err == nil {
+		t.Error("a second live bead for the same agent name should be rejected")
+	}
+	if _, err := agent(other, "builder-1"); err != nil {
+		t.Errorf("another tenant's agent of the same name: %v", err)
+	}
+	CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "builder-1"})
+
+	found, err := s.FindAgentBead(ctx, "builder-1")
+	if err != nil {
+		t.Fatalf("FindAgentBead: %v", err)
+	}
+	if found == nil || found.ID != bead.ID {
+		t.Fatalf("FindAgentBead = %+v, want %s", found, bead.ID)
+	}
+	if found, err := s.FindAgentBead(ctx, "builder-2"); err != nil || found != nil {
+		t.Errorf("FindAgentBead(unregistered) = %+v, %v; want nil", found, err)
+	}
+
+	second, err := agent(ctx, "builder-2")
+	if err != nil {
+		t.Fatalf("creating agent bead: %v", err)
+	}
+	taken := "builder-1"
+	if _, err := s.UpdateIssue(ctx, second.ID, store.UpdateIssueInput{Title: &taken}); err == nil {
+		t.Error("renaming an agent bead to a registered name should be rejected")
+	}
+
+	// A trashed bead frees its name, and cannot come back while the
+	// name is taken again.
+	if err := s.DeleteIssue(ctx, bead.ID); err != nil {
+		t.Fatalf("DeleteIssue: %v", err)
+	}
+	if found, err := s.FindAgentBead(ctx, "builder-1"); err != nil || found != nil {
+		t.Errorf("FindAgentBead after trashing = %+v, %v; want nil", found, err)
+	}
+	if _, err := agent(ctx, "builder-1"); err != nil {
+		t.Fatalf("re-registering a trashed agent's name: %v", err)
+	}
+	if _, err := s.RestoreIssue(ctx, bead.ID); err == nil {
+		t.Error("restoring an agent bead whose name is taken should be rejected")
+	}
+}
@@ -0,0 +1,17 @@
+package store
+
+import (
+	"time"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+)
+
+// trashEvent records issueID moving into the trash at deletedAt, or back
+// out of it.
+func trashEvent(issueID string, deletedAt time.Time, deleted bool, actor string) AddEventInput {
+	at := deletedAt.UTC().Format(time.RFC3339Nano)
+	if deleted {
+		return AddEventInput{IssueID: issueID, EventType: model.EventDeleted, Actor: actor, Field: "deleted_at", NewValue: at}
+	}
+	return AddEventInput{IssueID: issueID, EventType: model.EventRestored, Actor: actor, Field: "deleted_at", OldValue: at}
+}
@@ -0,0 +1,365 @@
+// Package templates turns issue trees into reusable templates and copies
+// them out again.
+//
+// A template is an issue tree (an issue and its parent-child descendants)
+// with IsTemplate set on every issue in it. Template issues are blueprints,
+// not work: they never show up as ready. Their text fields and labels may
+// hold {{variables}}, which are filled in when the template is
+// instantiated: the tree is deep-copied in one batch, with its labels and
+// the ready-gating dependencies between its own issues, under a target
+// project or parent.
+package templates
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+)
+
+// variablePattern matches a {{variable}}; names may use letters, digits,
+// underscores, dots and dashes.
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// Library marks templates and instantiates them.
+type Library struct {
+	store store.Store
+}
+
+func New(s store.Store) *Library {
+	return &Library{store: s}
+}
+
+// Template describes a template tree.
+type Template struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	ProjectID string   `json:"project_id,omitempty"`
+	Issues    int      `json:"issues"`    // in the tree, including the root
+	Variables []string `json:"variables"` // sorted
+}
+
+// InstantiateInput says which template to copy, where to and with what.
+type InstantiateInput struct {
+	TemplateID string
+	Variables  map[string]string
+	// ProjectID is the project of the copy. When empty it is the parent's,
+	// else the template's.
+	ProjectID string
+	ParentID  string // makes the copy a child of this issue
+	CreatedBy string
+}
+
+// Instance is a copy of a template.
+type Instance struct {
+	TemplateID   string `json:"template_id"`
+	RootID       string `json:"root_id"`
+	Issues       []Copy `json:"issues"` // the root first, parents before children
+	Dependencies int    `json:"dependencies"`
+}
+
+// Copy pairs a template issue with its copy.
+type Copy struct {
+	From  string `json:"from"`
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Mark makes the tree under rootID a template, or, with on false, turns it
+// back into ordinary issues. The whole tree changes in one batch.
+func (l *Library) Mark(ctx context.Context, rootID string, on bool) (*Template, error) {
+	tree, err := l.tree(ctx, rootID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tree) > store.MaxBatchOps {
+		return nil, fmt.Errorf("tree under %s has %d issues; the limit is %d", rootID, len(tree), store.MaxBatchOps)
+	}
+	ops := make([]store.BatchOp, 0, len(tree))
+	for _, issue := range tree {
+		if issue.IsTemplate == on {
+			continue
+		}
+		ops = append(ops, store.BatchOp{Update: &store.BatchUpdate{
+			ID:    issue.ID,
+			Input: store.UpdateIssueInput{IsTemplate: &on},
+		}})
+	}
+	if len(ops) > 0 {
+		if _, err := l.store.ApplyBatch(ctx, ops); err != nil {
+			return nil, fmt.Errorf("marking %s: %w", rootID, err)
+		}
+	}
+	return describe(tree), nil
+}
+
+// Get describes the template rooted at id.
+func (l *Library) Get(ctx context.Context, id string) (*Template, error) {
+	tree, err := l.template(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return describe(tree), nil
+}
+
+// List describes every template of the tenant, by title. Only roots are
+// listed, not the template issues under them.
+func (l *Library) List(ctx context.Context) ([]Template, error) {
+	all, err := l.store.ListIssues(ctx, model.IssueFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("listing issues: %w", err)
+	}
+	isTemplate := make(map[string]bool)
+	for _, issue := range all {
+		if issue.IsTemplate {
+			isTemplate[issue.ID] = true
+		}
+	}
+
+	list := []Template{}
+	for id := range isTemplate {
+		issue, err := l.store.GetIssue(ctx, id) // ListIssues leaves ParentID unset
+		if err != nil {
+			return nil, err
+		}
+		if isTemplate[issue.ParentID] {
+			continue
+		}
+		t, err := l.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *t)
+	}
+	sort.Slice(list, func(a, b int) bool {
+		if list[a].Title != list[b].Title {
+			return list[a].Title < list[b].Title
+		}
+		return list[a].ID < list[b].ID
+	})
+	return list, nil
+}
+
+// Instantiate deep-copies the template, filling in its variables. Every
+// variable the template uses must be given, and only those.
+func (l *Library) Instantiate(ctx context.Context, input InstantiateInput) (*Instance, error) {
+	tree, err := l.template(ctx, input.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVariables(variables(tree), input.Variables); err != nil {
+		return nil, err
+	}
+	deps, err := l.internalDependencies(ctx, tree)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(tree) + len(deps); n > store.MaxBatchOps {
+		return nil, fmt.Errorf("template %s needs %d batch ops; the limit is %d", input.TemplateID, n, store.MaxBatchOps)
+	}
+
+	projectID := input.ProjectID
+	if projectID == "" && input.ParentID != "" {
+		parent, err := l.store.GetIssue(ctx, input.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		projectID = parent.ProjectID
+	}
+	if projectID == "" {
+		projectID = tree[0].ProjectID
+	}
+
+	fill := func(s string) string {
+		return variablePattern.ReplaceAllStringFunc(s, func(m string) string {
+			return input.Variables[variablePattern.FindStringSubmatch(m)[1]]
+		})
+	}
+	refs := make(map[string]string, len(tree)) // template issue → "$ref" of its copy
+	ops := make([]store.BatchOp, 0, len(tree)+len(deps))
+	for i, issue := range tree {
+		ref := "n" + strconv.Itoa(i)
+		refs[issue.ID] = "$" + ref
+		parentID := input.ParentID
+		if i > 0 {
+			parentID = refs[issue.ParentID]
+		}
+		labels := make([]string, len(issue.Labels))
+		for j, label := range issue.Labels {
+			labels[j] = fill(label)
+		}
+		ops = append(ops, store.BatchOp{
+			Ref: ref,
+			Create: &store.CreateIssueInput{
+				Title:              fill(issue.Title),
+				Description:        fill(issue.Description),
+				Design:             fill(issue.Design),
+				AcceptanceCriteria: fill(issue.AcceptanceCriteria),
+				Notes:              fill(issue.Notes),
+				Status:             model.StatusOpen,
+				Priority:           issue.Priority,
+				IssueType:          issue.IssueType,
+				Assignee:           fill(issue.Assignee),
+				Owner:              fill(issue.Owner),
+				CreatedBy:          input.CreatedBy,
+				ProjectID:          projectID,
+				ParentID:           parentID,
+				Labels:             labels,
+				EstimatedMinutes:   issue.EstimatedMinutes,
+			},
+		})
+	}
+	for _, d := range deps {
+		ops = append(ops, store.BatchOp{Dependency: &store.AddDependencyInput{
+			IssueID:     refs[d.IssueID],
+			DependsOnID: refs[d.DependsOnID],
+			Type:        d.Type,
+			CreatedBy:   input.CreatedBy,
+		}})
+	}
+
+	results, err := l.store.ApplyBatch(ctx, ops)
+	if err != nil {
+		return nil, fmt.Errorf("instantiating template %s: %w", input.TemplateID, err)
+	}
+	inst := &Instance{
+		TemplateID:   input.TemplateID,
+		RootID:       results[0].IssueID,
+		Issues:       make([]Copy, len(tree)),
+		Dependencies: len(deps),
+	}
+	for i, issue := range tree {
+		inst.Issues[i] = Copy{From: issue.ID, ID: results[i].IssueID, Title: results[i].Issue.Title}
+	}
+	return inst, nil
+}
+
+// template returns the tree under id, which must be a template.
+func (l *Library) template(ctx context.Context, id string) ([]model.Issue, error) {
+	tree, err := l.tree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !tree[0].IsTemplate {
+		return nil, fmt.Errorf("%s is not a template; mark it as one first", id)
+	}
+	return tree, nil
+}
+
+// tree returns the issue rootID and its descendants, with labels and
+// parents, root first and every parent before its children.
+func (l *Library) tree(ctx context.Context, rootID string) ([]model.Issue, error) {
+	root, err := l.store.GetIssue(ctx, rootID)
+	if err != nil {
+		return nil, err
+	}
+	tree := []model.Issue{*root}
+	for i := 0; i < len(tree); i++ {
+		parentID := tree[i].ID
+		children, err := l.store.ListIssues(ctx, model.IssueFilter{ParentID: &parentID})
+		if err != nil {
+			return nil, fmt.Errorf("listing children of %s: %w", parentID, err)
+		}
+		// The copies get sequential child IDs in this order, so "x.10" must
+		// sort after "x.9" for the steps to keep their numbers.
+		slices.SortFunc(children, func(a, b model.Issue) int { return store.CompareChildIDs(a.ID, b.ID) })
+		for _, c := range children {
+			child, err := l.store.GetIssue(ctx, c.ID) // for its labels
+			if err != nil {
+				return nil, err
+			}
+			child.ParentID = parentID
+			tree = append(tree, *child)
+		}
+	}
+	return tree, nil
+}
+
+// internalDependencies returns the ready-gating dependencies between issues
+// of tree. Parent-child links are left out: the copy rebuilds those itself.
+func (l *Library) internalDependencies(ctx context.Context, tree []model.Issue) ([]model.Dependency, error) {
+	inTree := make(map[string]bool, len(tree))
+	for _, issue := range tree {
+		inTree[issue.ID] = true
+	}
+	var deps []model.Dependency
+	for _, issue := range tree {
+		upstream, err := l.store.ListDependencies(ctx, issue.ID, "upstream")
+		if err != nil {
+			return nil, fmt.Errorf("listing dependencies of %s: %w", issue.ID, err)
+		}
+		for _, d := range upstream {
+			if d.Type != model.DepParentChild && d.Type.IsReadyGate() && inTree[d.DependsOnID] {
+				deps = append(deps, d)
+			}
+		}
+	}
+	return deps, nil
+}
+
+func describe(tree []model.Issue) *Template {
+	return &Template{
+		ID:        tree[0].ID,
+		Title:     tree[0].Title,
+		ProjectID: tree[0].ProjectID,
+		Issues:    len(tree),
+		Variables: variables(tree),
+	}
+}
+
+// variables returns the sorted names of the variables tree uses.
+func variables(tree []model.Issue) []string {
+	seen := make(map[string]bool)
+	for _, issue := range tree {
+		fields := append([]string{issue.Title, issue.Description, issue.Design, issue.AcceptanceCriteria,
+			issue.Notes, issue.Assignee, issue.Owner}, issue.Labels...)
+		for _, f := range fields {
+			for _, m := range variablePattern.FindAllStringSubmatch(f, -1) {
+				seen[m[1]] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkVariables reports variables the template uses that are not given,
+// and given ones it does not use, which are most likely typos.
+func checkVariables(used []string, given map[string]string) error {
+	var missing, unknown []string
+	for _, name := range used {
+		if _, ok := given[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	for name := range given {
+		if !slices.Contains(used, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	switch {
+	case len(missing) > 0:
+		return fmt.Errorf("missing template variables: %s", strings.Join(missing, ", "))
+	case len(unknown) > 0:
+		return fmt.Errorf("the template does not use %s; it uses %s", strings.Join(unknown, ", "), describeNames(used))
+	}
+	return nil
+}
+
+func describeNames(names []string) string {
+	if len(names) == 0 {
+		return "no variables"
+	}
+	return strings.Join(names, ", ")
+}
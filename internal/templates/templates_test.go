@@ -0,0 +1,209 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Actual-Outcomes/doit/internal/model"
+	"github.com/Actual-Outcomes/doit/internal/store"
+	"github.com/Actual-Outcomes/doit/internal/store/storetest"
+)
+
+// releaseChecklist builds a release template: a root with build and ship
+// steps, ship blocked by build.
+func releaseChecklist(t *testing.T, ctx context.Context, s store.Store) (root, build, ship string) {
+	t.Helper()
+	create := func(title, parent string, labels ...string) string {
+		t.Helper()
+		return storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{
+			Title: title, Priority: 1, ParentID: parent, Labels: labels,
+			Description: "Part of the {{version}} release.",
+		}).ID
+	}
+	root = create("Release {{version}}", "", "release")
+	build = create("Build {{version}}", root, "{{team}}")
+	ship = create("Ship to {{env}}", root)
+	if _, err := s.AddDependency(ctx, store.AddDependencyInput{IssueID: ship, DependsOnID: build, Type: model.DepBlocks}); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	return root, build, ship
+}
+
+func TestMarkAndList(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	l := New(s)
+	root, build, _ := releaseChecklist(t, ctx, s)
+
+	if _, err := l.Get(ctx, root); err == nil {
+		t.Error("an unmarked tree should not be a template")
+	}
+	tmpl, err := l.Mark(ctx, root, true)
+	if err != nil {
+		t.Fatalf("Mark: %v", err)
+	}
+	if tmpl.Issues != 3 || strings.Join(tmpl.Variables, ",") != "env,team,version" {
+		t.Errorf("template = %+v, want 3 issues using env, team and version", tmpl)
+	}
+	if issue, _ := s.GetIssue(ctx, build); !issue.IsTemplate {
+		t.Error("marking the root should mark the whole tree")
+	}
+	if ready, _ := s.ListReady(ctx, model.IssueFilter{}); len(ready) != 0 {
+		t.Errorf("ready = %d issues, want templates left out", len(ready))
+	}
+
+	list, err := l.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != root {
+		t.Errorf("List = %+v, want the root alone", list)
+	}
+
+	if _, err := l.Mark(ctx, root, false); err != nil {
+		t.Fatalf("Mark(false): %v", err)
+	}
+	if list, _ = l.List(ctx); len(list) != 0 {
+		t.Errorf("List after unmarking = %+v, want none", list)
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	l := New(s)
+	root, build, ship := releaseChecklist(t, ctx, s)
+	if _, err := l.Mark(ctx, root, true); err != nil {
+		t.Fatalf("Mark: %v", err)
+	}
+	epicID := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Q3", IssueType: model.TypeEpic}).ID
+
+	vars := map[string]string{"version": "2.1", "team": "platform", "env": "prod"}
+	inst, err := l.Instantiate(ctx, InstantiateInput{TemplateID: root, Variables: vars, ParentID: epicID})
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	if len(inst.Issues) != 3 || inst.Dependencies != 1 || inst.Issues[0].From != root {
+		t.Fatalf("instance = %+v, want 3 copies and 1 dependency", inst)
+	}
+
+	copyOf := make(map[string]string)
+	for _, c := range inst.Issues {
+		copyOf[c.From] = c.ID
+	}
+	got, _ := s.GetIssue(ctx, inst.RootID)
+	if got.Title != "Release 2.1" || got.ParentID != epicID || got.IsTemplate || got.Priority != 1 ||
+		got.Description != "Part of the 2.1 release." || !slices.Equal(got.Labels, []string{"release"}) {
+		t.Errorf("root copy = %+v", got)
+	}
+	got, _ = s.GetIssue(ctx, copyOf[build])
+	if got.Title != "Build 2.1" || got.ParentID != inst.RootID || !slices.Equal(got.Labels, []string{"platform"}) {
+		t.Errorf("build copy = %+v, want it under the new root, labelled platform", got)
+	}
+	deps, _ := s.ListDependencies(ctx, copyOf[ship], "upstream")
+	var blockedBy []string
+	for _, d := range deps {
+		if d.Type == model.DepBlocks {
+			blockedBy = append(blockedBy, d.DependsOnID)
+		}
+	}
+	if !slices.Equal(blockedBy, []string{copyOf[build]}) {
+		t.Errorf("ship copy blocked by %v, want the build copy", blockedBy)
+	}
+	ready := storetest.ReadyIDs(t, ctx, s)
+	if slices.Contains(ready, copyOf[ship]) || !slices.Contains(ready, copyOf[build]) {
+		t.Errorf("ready = %v, want the build copy ready and ship held back", ready)
+	}
+
+	for _, bad := range []map[string]string{
+		{"version": "2.2", "team": "platform"},
+		{"version": "2.2", "team": "platform", "env": "prod", "region": "eu"},
+	} {
+		if _, err := l.Instantiate(ctx, InstantiateInput{TemplateID: root, Variables: bad}); err == nil {
+			t.Errorf("Instantiate with %v should fail", bad)
+		}
+	}
+	if _, err := l.Instantiate(ctx, InstantiateInput{TemplateID: inst.RootID, Variables: vars}); err == nil {
+		t.Error("instantiating a copy, which is no template, should fail")
+	}
+}
+
+// TestInstantiateManySteps checks that a template with ten or more steps
+// keeps their order and numbers in the copy.
+func TestInstantiateManySteps(t *testing.T) {
+	s, ctx := storetest.MemTenant(t)
+	l := New(s)
+	root := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Checklist"}).ID
+	for i := 1; i <= 12; i++ {
+		id, err := s.NextChildID(ctx, root)
+		if err != nil {
+			t.Fatalf("NextChildID: %v", err)
+		}
+		storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{ID: id, Title: fmt.Sprintf("Step %d", i), ParentID: root})
+	}
+	if _, err := l.Mark(ctx, root, true); err != nil {
+		t.Fatalf("Mark: %v", err)
+	}
+
+	inst, err := l.Instantiate(ctx, InstantiateInput{TemplateID: root})
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	if len(inst.Issues) != 13 {
+		t.Fatalf("instance = %+v, want 13 copies", inst)
+	}
+	for i, c := range inst.Issues[1:] {
+		want := fmt.Sprintf("%s.%d", root, i+1)
+		got, _ := s.GetIssue(ctx, c.ID)
+		if c.From != want || c.ID != fmt.Sprintf("%s.%d", inst.RootID, i+1) || got.Title != fmt.Sprintf("Step %d", i+1) {
+			t.Errorf("copy %d = %+v titled %q, want a copy of %s keeping its number", i+1, c, got.Title, want)
+		}
+	}
+}
+
+// TestEveryStore marks and instantiates a template on each store backend.
+func TestEveryStore(t *testing.T) {
+	storetest.EachStore(t, testEveryStore)
+}
+
+func testEveryStore(t *testing.T, s store.Store) {
+	ctx := storetest.NewTenant(t, s)
+	l := New(s)
+	root := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Onboard {{name}}"})
+	laptop := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Order a laptop for {{name}}", ParentID: root.ID, Labels: []string{"it"}})
+	access := storetest.CreateIssue(t, ctx, s, store.CreateIssueInput{Title: "Grant {{name}} access", ParentID: root.ID})
+	if _, err := s.AddDependency(ctx, store.AddDependencyInput{IssueID: access.ID, DependsOnID: laptop.ID, Type: model.DepBlocks}); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	if _, err := l.Mark(ctx, root.ID, true); err != nil {
+		t.Fatalf("Mark: %v", err)
+	}
+	if ready := storetest.ReadyIDs(t, ctx, s); len(ready) != 0 {
+		t.Errorf("ready = %v, want the whole template left out", ready)
+	}
+
+	inst, err := l.Instantiate(ctx, InstantiateInput{TemplateID: root.ID, Variables: map[string]string{"name": "Ada"}})
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	if len(inst.Issues) != 3 || inst.Dependencies != 1 {
+		t.Fatalf("instance = %+v, want 3 copies and 1 dependency", inst)
+	}
+	copyOf := make(map[string]string)
+	for _, c := range inst.Issues {
+		copyOf[c.From] = c.ID
+	}
+	laptopCopy, err := s.GetIssue(ctx, copyOf[laptop.ID])
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if laptopCopy.Title != "Order a laptop for Ada" || laptopCopy.IsTemplate ||
+		laptopCopy.ParentID != inst.RootID || !slices.Equal(laptopCopy.Labels, []string{"it"}) {
+		t.Errorf("laptop copy = %+v", laptopCopy)
+	}
+	ready := storetest.ReadyIDs(t, ctx, s)
+	if !slices.Contains(ready, copyOf[laptop.ID]) || slices.Contains(ready, copyOf[access.ID]) {
+		t.Errorf("ready = %v, want the laptop copy ready and the access copy blocked", ready)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/Actual-Outcomes/doit/internal/agents"
 	"github.com/Actual-Outcomes/doit/internal/auth"
 	"github.com/Actual-Outcomes/doit/internal/model"
 	"github.com/Actual-Outcomes/doit/internal/store"
@@ -43,6 +44,8 @@ func NewUIHandlers(s store.Store, signingKey, adminKey string, adminTenantID *uu
 		"issues":         issuesPage,
 		"issueDetail":    issueDetailPage,
 		"ready":          readyPage,
+		"agents":         agentsPage,
+		"search":         searchPage,
 		"error":          errorPage,
 		"adminDashboard": adminDashboardPage,
 		"adminTenants":   adminTenantsPage,
@@ -297,7 +300,8 @@ func (h *UIHandlers) IssueList(w http.ResponseWriter, r *http.Request) {
 	h.render(w, "issues", data)
 }
 
-// IssueDetail shows a single issue with labels, deps, and comments.
+// IssueDetail shows a single issue with labels, deps, comments and its
+// event timeline.
 func (h *UIHandlers) IssueDetail(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	issue, err := h.store.GetIssue(r.Context(), id)
@@ -318,6 +322,12 @@ func (h *UIHandlers) IssueDetail(w http.ResponseWriter, r *http.Request) {
 		deps = nil
 	}
 
+	events, err := h.store.ListEvents(r.Context(), id, timelineLimit)
+	if err != nil {
+		slog.Error("issue detail: events query failed", "error", err)
+		events = nil
+	}
+
 	data := map[string]any{
 		"Title":        issue.Title,
 		"ShowNav":      true,
@@ -325,11 +335,51 @@ func (h *UIHandlers) IssueDetail(w http.ResponseWriter, r *http.Request) {
 		"Issue":        issue,
 		"Comments":     comments,
 		"Dependencies": deps,
+		"Events":       events,
+		"MoreEvents":   len(events) == timelineLimit,
 	}
 	h.addProjectData(r, data)
 	h.render(w, "issueDetail", data)
 }
 
+// Search shows ranked full-text search results for the q parameter.
+func (h *UIHandlers) Search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+
+	data := map[string]any{
+		"Title":        "Search",
+		"ShowNav":      true,
+		"NavActive":    "search",
+		"Query":        query,
+		"FilterStatus": q.Get("status"),
+		"FilterType":   q.Get("type"),
+	}
+
+	if query != "" {
+		sq := model.SearchQuery{Query: query, Limit: 50}
+		if s := q.Get("status"); s != "" {
+			status := model.Status(s)
+			sq.Status = &status
+		}
+		if t := q.Get("type"); t != "" {
+			issueType := model.IssueType(t)
+			sq.IssueType = &issueType
+		}
+
+		results, err := h.store.SearchIssues(r.Context(), sq)
+		if err != nil {
+			slog.Error("search: query failed", "error", err)
+			h.renderError(w, http.StatusInternalServerError, "Search failed.")
+			return
+		}
+		data["Results"] = results
+	}
+
+	h.addProjectData(r, data)
+	h.render(w, "search", data)
+}
+
 // ReadyWork shows issues ready for work.
 func (h *UIHandlers) ReadyWork(w http.ResponseWriter, r *http.Request) {
 	ready, err := h.store.ListReady(r.Context(), model.IssueFilter{Limit: 50})
@@ -349,6 +399,25 @@ func (h *UIHandlers) ReadyWork(w http.ResponseWriter, r *http.Request) {
 	h.render(w, "ready", data)
 }
 
+// Agents shows the live agents and the work each one holds.
+func (h *UIHandlers) Agents(w http.ResponseWriter, r *http.Request) {
+	list, err := agents.New(h.store).List(r.Context(), false)
+	if err != nil {
+		slog.Error("agents: query failed", "error", err)
+		h.renderError(w, http.StatusInternalServerError, "Failed to load agents.")
+		return
+	}
+
+	data := map[string]any{
+		"Title":     "Agents",
+		"ShowNav":   true,
+		"NavActive": "agents",
+		"Agents":    list,
+	}
+	h.addProjectData(r, data)
+	h.render(w, "agents", data)
+}
+
 // priorityLabel returns a human-readable priority label.
 func priorityLabel(p int) string {
 	switch p {
@@ -399,6 +468,91 @@ func typeClass(t model.IssueType) string {
 	}
 }
 
+// agentStateClass returns a CSS class suffix for an agent state.
+func agentStateClass(s model.AgentState) string {
+	switch s {
+	case model.AgentSpawning, model.AgentRunning:
+		return "open"
+	case model.AgentWorking:
+		return "progress"
+	case model.AgentIdle:
+		return "deferred"
+	case model.AgentStuck, model.AgentDead:
+		return "blocked"
+	case model.AgentDone, model.AgentStopped:
+		return "closed"
+	default:
+		return "default"
+	}
+}
+
+// timelineLimit caps the events shown on an issue page, newest first.
+const timelineLimit = 200
+
+// describeEvent renders an audit event as a short sentence for the timeline.
+func describeEvent(e model.Event) string {
+	switch e.EventType {
+	case model.EventCreated:
+		return fmt.Sprintf("created %q", e.NewValue)
+	case model.EventClosed:
+		return "closed it"
+	case model.EventReopened:
+		return "reopened it as " + e.NewValue
+	case model.EventStatusChanged:
+		return fmt.Sprintf("moved it from %s to %s", e.OldValue, e.NewValue)
+	case model.EventUpdated:
+		switch {
+		case e.OldValue == "":
+			return fmt.Sprintf("set %s to %q", e.Field, truncate(e.NewValue, 80))
+		case e.NewValue == "":
+			return fmt.Sprintf("cleared %s (was %q)", e.Field, truncate(e.OldValue, 80))
+		default:
+			return fmt.Sprintf("changed %s from %q to %q", e.Field, truncate(e.OldValue, 80), truncate(e.NewValue, 80))
+		}
+	case model.EventLabelAdded:
+		return "added label " + e.NewValue
+	case model.EventLabelRemoved:
+		return "removed label " + e.OldValue
+	case model.EventDependencyAdded:
+		return fmt.Sprintf("added a %s dependency on %s", e.Comment, e.NewValue)
+	case model.EventDependencyRemoved:
+		return fmt.Sprintf("removed the %s dependency on %s", e.Comment, e.OldValue)
+	case model.EventCommented:
+		return "commented"
+	case model.EventCompacted:
+		if e.NewValue < e.OldValue {
+			return fmt.Sprintf("restored it from compaction level %s to %s", e.OldValue, e.NewValue)
+		}
+		return fmt.Sprintf("compacted it from level %s to %s", e.OldValue, e.NewValue)
+	case model.EventDeleted:
+		return "moved it to the trash"
+	case model.EventRestored:
+		return "restored it from the trash"
+	case model.EventJoined:
+		return "joined the competition for it"
+	default:
+		return string(e.EventType)
+	}
+}
+
+// highlight escapes a search snippet and turns its model.SnippetMark pairs
+// into <mark> elements.
+func highlight(snippet string) template.HTML {
+	parts := strings.Split(template.HTMLEscapeString(snippet), model.SnippetMark)
+	var b strings.Builder
+	for i, part := range parts {
+		if i%2 == 1 && i < len(parts)-1 {
+			b.WriteString("<mark>" + part + "</mark>")
+			continue
+		}
+		if i%2 == 1 {
+			b.WriteString(model.SnippetMark)
+		}
+		b.WriteString(part)
+	}
+	return template.HTML(b.String())
+}
+
 // truncate shortens a string to n characters.
 func truncate(s string, n int) string {
 	if len(s) <= n {
@@ -705,11 +859,14 @@ func (h *UIHandlers) AdminUpdateProject(w http.ResponseWriter, r *http.Request)
 }
 
 var templateFuncs = template.FuncMap{
-	"priorityLabel": priorityLabel,
-	"statusClass":   statusClass,
-	"typeClass":     typeClass,
-	"truncate":      truncate,
-	"upper":         strings.ToUpper,
-	"replace":       strings.ReplaceAll,
-	"string":        func(v any) string { return fmt.Sprintf("%s", v) },
+	"priorityLabel":   priorityLabel,
+	"statusClass":     statusClass,
+	"typeClass":       typeClass,
+	"agentStateClass": agentStateClass,
+	"truncate":        truncate,
+	"highlight":       highlight,
+	"describeEvent":   describeEvent,
+	"upper":           strings.ToUpper,
+	"replace":         strings.ReplaceAll,
+	"string":          func(v any) string { return fmt.Sprintf("%s", v) },
 }
@@ -25,6 +25,8 @@ func RegisterUIRoutes(r chi.Router, s store.Store, adminKey string, adminTenantI
 			protected.Get("/issues", h.IssueList)
 			protected.Get("/issues/{id}", h.IssueDetail)
 			protected.Get("/ready", h.ReadyWork)
+			protected.Get("/agents", h.Agents)
+			protected.Get("/search", h.Search)
 		})
 
 		// Admin routes — require admin session
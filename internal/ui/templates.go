@@ -56,6 +56,18 @@ const baseLayout = `<!DOCTYPE html>
       cursor: pointer;
     }
     .nav-project select:hover { border-color: #64748b; }
+    .nav-search { margin-left: auto; }
+    .nav-search input {
+      background: #334155;
+      color: #e2e8f0;
+      border: 1px solid #475569;
+      padding: 0.25rem 0.5rem;
+      border-radius: 4px;
+      font-size: 0.85rem;
+      width: 14rem;
+    }
+    .nav-search input::placeholder { color: #94a3b8; }
+    .nav-search + .nav-project { margin-left: 0.75rem; }
     .nav-right { margin-left: 0.75rem; }
     .nav-right form { display: inline; }
     .nav-right button {
@@ -210,6 +222,12 @@ const baseLayout = `<!DOCTYPE html>
     .comment-meta { font-size: 0.8rem; color: #64748b; margin-bottom: 0.25rem; }
     .comment-text { font-size: 0.9rem; }
 
+    /* Timeline */
+    .timeline { list-style: none; border-left: 3px solid #e2e8f0; padding-left: 1rem; }
+    .timeline li { font-size: 0.9rem; margin-bottom: 0.5rem; }
+    .timeline .event-meta { font-size: 0.8rem; color: #64748b; }
+    .timeline .event-comment { font-size: 0.85rem; color: #475569; }
+
     /* Login */
     .login-box {
       max-width: 400px;
@@ -252,6 +270,14 @@ const baseLayout = `<!DOCTYPE html>
     .error-box .code { font-size: 4rem; font-weight: 700; color: #cbd5e1; }
     .error-box .message { font-size: 1.1rem; color: #64748b; margin-top: 0.5rem; }
 
+    /* Search */
+    .search-results { list-style: none; }
+    .search-results li { background: #fff; border-radius: 8px; padding: 1rem 1.25rem; margin-bottom: 0.75rem; box-shadow: 0 1px 3px rgba(0,0,0,0.08); }
+    .search-results .result-title { font-weight: 600; }
+    .search-results .result-meta { margin: 0.25rem 0; font-size: 0.85rem; color: #64748b; }
+    .search-results .snippet { color: #334155; font-size: 0.9rem; }
+    mark { background: #fef08a; color: inherit; padding: 0 0.1rem; border-radius: 2px; }
+
     /* Empty state */
     .empty { text-align: center; padding: 3rem; color: #94a3b8; font-size: 1rem; }
 
@@ -267,8 +293,12 @@ const baseLayout = `<!DOCTYPE html>
     <a href="/ui/" {{if eq .NavActive "dashboard"}}class="active"{{end}}>Dashboard</a>
     <a href="/ui/issues" {{if eq .NavActive "issues"}}class="active"{{end}}>Issues</a>
     <a href="/ui/ready" {{if eq .NavActive "ready"}}class="active"{{end}}>Ready</a>
+    <a href="/ui/agents" {{if eq .NavActive "agents"}}class="active"{{end}}>Agents</a>
     {{if .IsAdmin}}<a href="/ui/admin/" {{if eq .NavActive "admin"}}class="active"{{end}} style="color:#f59e0b">Admin</a>{{end}}
   </div>
+  <form class="nav-search" method="GET" action="/ui/search">
+    <input type="search" name="q" placeholder="Search issues…" value="{{.Query}}">
+  </form>
   {{if .Projects}}
   <div class="nav-project">
     <form method="POST" action="/ui/project">
@@ -424,6 +454,55 @@ const issuesPage = `{{define "page"}}
 {{end}}
 {{end}}`
 
+const searchPage = `{{define "page"}}
+<h1>Search</h1>
+
+<form class="filters" method="GET" action="/ui/search">
+  <input type="search" name="q" placeholder="words, &quot;exact phrase&quot;, -exclude" value="{{.Query}}" style="flex:1;min-width:16rem">
+  <select name="status">
+    <option value="">All Statuses</option>
+    <option value="open" {{if eq .FilterStatus "open"}}selected{{end}}>Open</option>
+    <option value="in_progress" {{if eq .FilterStatus "in_progress"}}selected{{end}}>In Progress</option>
+    <option value="blocked" {{if eq .FilterStatus "blocked"}}selected{{end}}>Blocked</option>
+    <option value="deferred" {{if eq .FilterStatus "deferred"}}selected{{end}}>Deferred</option>
+    <option value="closed" {{if eq .FilterStatus "closed"}}selected{{end}}>Closed</option>
+  </select>
+  <select name="type">
+    <option value="">All Types</option>
+    <option value="task" {{if eq .FilterType "task"}}selected{{end}}>Task</option>
+    <option value="bug" {{if eq .FilterType "bug"}}selected{{end}}>Bug</option>
+    <option value="feature" {{if eq .FilterType "feature"}}selected{{end}}>Feature</option>
+    <option value="epic" {{if eq .FilterType "epic"}}selected{{end}}>Epic</option>
+    <option value="chore" {{if eq .FilterType "chore"}}selected{{end}}>Chore</option>
+  </select>
+  <button type="submit">Search</button>
+</form>
+
+{{if .Results}}
+<ul class="search-results">
+  {{range .Results}}
+  <li>
+    <div class="result-title"><a href="/ui/issues/{{.Issue.ID}}">{{.Issue.Title}}</a></div>
+    <div class="result-meta">
+      <code>{{.Issue.ID}}</code>
+      &middot;
+      <span class="badge badge-{{statusClass .Issue.Status}}">{{replace (upper (string .Issue.Status)) "_" " "}}</span>
+      &middot;
+      <span class="badge badge-{{typeClass .Issue.IssueType}}">{{.Issue.IssueType}}</span>
+      &middot;
+      <span class="badge badge-p{{.Issue.Priority}}">P{{.Issue.Priority}}</span>
+    </div>
+    {{if .Snippet}}<div class="snippet">{{highlight .Snippet}}</div>{{end}}
+  </li>
+  {{end}}
+</ul>
+{{else if .Query}}
+<div class="empty">No issues match &ldquo;{{.Query}}&rdquo;.</div>
+{{else}}
+<div class="empty">Search titles, descriptions, notes and comments.</div>
+{{end}}
+{{end}}`
+
 const issueDetailPage = `{{define "page"}}
 <div class="detail-header">
   <h1>{{.Issue.Title}}</h1>
@@ -518,6 +597,22 @@ const issueDetailPage = `{{define "page"}}
 </div>
 {{end}}
 {{end}}
+
+{{if .Events}}
+<h2>Timeline</h2>
+<ul class="timeline">
+  {{range .Events}}
+  <li>
+    <span class="event-meta">{{.CreatedAt.Format "Jan 2 15:04:05"}}</span>
+    <strong>{{.Actor}}</strong> {{describeEvent .}}
+    {{if and .Comment (ne .EventType "dependency_added") (ne .EventType "dependency_removed")}}
+    <div class="event-comment">{{truncate .Comment 200}}</div>
+    {{end}}
+  </li>
+  {{end}}
+</ul>
+{{if .MoreEvents}}<p class="event-meta">Showing the latest {{len .Events}} events.</p>{{end}}
+{{end}}
 {{end}}`
 
 const readyPage = `{{define "page"}}
@@ -545,6 +640,31 @@ const readyPage = `{{define "page"}}
 {{end}}
 {{end}}`
 
+const agentsPage = `{{define "page"}}
+<h1>Agents</h1>
+<p style="color:#64748b;margin-bottom:1.5rem">Live agents, the issue each is hooked to, and the issues they hold.</p>
+
+{{if .Agents}}
+<table>
+  <thead><tr><th>Agent</th><th>State</th><th>Role</th><th>Hooked</th><th>Holding</th><th>Last Activity</th></tr></thead>
+  <tbody>
+  {{range .Agents}}
+  <tr>
+    <td><a href="/ui/issues/{{.ID}}">{{.Name}}</a></td>
+    <td><span class="badge badge-{{agentStateClass .State}}">{{.State}}</span></td>
+    <td>{{if .Role}}{{.Role}}{{if .Rig}} <span style="color:#64748b">on {{.Rig}}</span>{{end}}{{else}}<span style="color:#94a3b8">—</span>{{end}}</td>
+    <td>{{if .HookBead}}<a href="/ui/issues/{{.HookBead}}"><code>{{.HookBead}}</code></a> {{truncate .HookTitle 40}}{{else}}<span style="color:#94a3b8">—</span>{{end}}</td>
+    <td>{{range $i, $issue := .Holding}}{{if $i}}, {{end}}<a href="/ui/issues/{{$issue.ID}}"><code>{{$issue.ID}}</code></a>{{else}}<span style="color:#94a3b8">—</span>{{end}}</td>
+    <td style="color:#64748b;font-size:0.85rem">{{if .LastActivity}}{{.LastActivity.Format "Jan 2 15:04"}}{{else}}—{{end}}</td>
+  </tr>
+  {{end}}
+  </tbody>
+</table>
+{{else}}
+<div class="empty">No agents are registered right now.</div>
+{{end}}
+{{end}}`
+
 const errorPage = `{{define "page"}}
 <div class="error-box">
   <div class="code">{{.Code}}</div>